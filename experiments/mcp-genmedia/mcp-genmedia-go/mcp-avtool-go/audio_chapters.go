@@ -0,0 +1,292 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxChapterSegments caps the number of segments of a 'concat_audio_with_chapters' call.
+const maxChapterSegments = 200
+
+// chapterOutputFormats are the output formats that can carry chapter markers.
+var chapterOutputFormats = []string{"m4a", "mp3"}
+
+// audioChapter is one segment of a chaptered audio file and the chapter it becomes.
+type audioChapter struct {
+	Title        string  `json:"title"`
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+}
+
+// parseChapterSegments reads the 'segments' argument: each item is a URI, or an object with
+// 'uri' and an optional 'title'. Segments without a title are named "Chapter N".
+func parseChapterSegments(raw []interface{}) (uris, titles []string, err error) {
+	if len(raw) == 0 {
+		return nil, nil, fmt.Errorf("at least one segment is required")
+	}
+	if len(raw) > maxChapterSegments {
+		return nil, nil, fmt.Errorf("at most %d segments are supported, got %d", maxChapterSegments, len(raw))
+	}
+	for i, item := range raw {
+		var uri, title string
+		switch v := item.(type) {
+		case string:
+			uri = v
+		case map[string]interface{}:
+			uri, _ = v["uri"].(string)
+			title, _ = v["title"].(string)
+		default:
+			return nil, nil, fmt.Errorf("segment %d must be a URI or an object with 'uri' and 'title'", i+1)
+		}
+		uri, title = strings.TrimSpace(uri), strings.TrimSpace(title)
+		if uri == "" {
+			return nil, nil, fmt.Errorf("segment %d has no 'uri'", i+1)
+		}
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		uris = append(uris, uri)
+		titles = append(titles, title)
+	}
+	return uris, titles, nil
+}
+
+// buildChapters places the chapters back to back, one per segment, starting at 0.
+func buildChapters(titles []string, durations []float64) ([]audioChapter, error) {
+	if len(titles) != len(durations) {
+		return nil, fmt.Errorf("got %d titles for %d segments", len(titles), len(durations))
+	}
+	chapters := make([]audioChapter, len(titles))
+	start := 0.0
+	for i, title := range titles {
+		if durations[i] <= 0 {
+			return nil, fmt.Errorf("the duration of segment %d ('%s') is unknown", i+1, title)
+		}
+		chapters[i] = audioChapter{Title: title, StartSeconds: start, EndSeconds: start + durations[i]}
+		start += durations[i]
+	}
+	return chapters, nil
+}
+
+// escapeFFMetadataValue escapes the characters that are special in an FFMETADATA file.
+func escapeFFMetadataValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n").Replace(value)
+}
+
+// buildFFMetadata returns an FFMETADATA file with one chapter per entry, in milliseconds, and
+// the optional album title.
+func buildFFMetadata(title string, chapters []audioChapter) string {
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+	if title != "" {
+		fmt.Fprintf(&sb, "title=%s\nalbum=%s\n", escapeFFMetadataValue(title), escapeFFMetadataValue(title))
+	}
+	for _, chapter := range chapters {
+		fmt.Fprintf(&sb, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(math.Round(chapter.StartSeconds*1000)), int64(math.Round(chapter.EndSeconds*1000)), escapeFFMetadataValue(chapter.Title))
+	}
+	return sb.String()
+}
+
+// buildChapterConcatFilter joins the audio of n inputs, converted to a common sample format,
+// into the [a] output.
+func buildChapterConcatFilter(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "[%d:a]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[a%d];", i, i)
+	}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "[a%d]", i)
+	}
+	fmt.Fprintf(&sb, "concat=n=%d:v=0:a=1[a]", n)
+	return sb.String()
+}
+
+// chapterCodecArgs returns the encoder arguments of a chaptered output format. MP3 chapters are
+// written as ID3v2 CHAP frames.
+func chapterCodecArgs(format string) []string {
+	if format == "mp3" {
+		return []string{"-c:a", "libmp3lame", "-b:a", "192k", "-id3v2_version", "3"}
+	}
+	return []string{"-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart"}
+}
+
+// executeConcatAudioWithChapters joins the inputs and writes the chapters of the metadata file
+// into the output container.
+func executeConcatAudioWithChapters(ctx context.Context, localInputs []string, metadataFile, format, tempOutputFile string) (string, error) {
+	args := []string{"-y"}
+	for _, input := range localInputs {
+		args = append(args, "-i", input)
+	}
+	metadataIndex := len(localInputs)
+	args = append(args, "-f", "ffmetadata", "-i", metadataFile,
+		"-filter_complex", buildChapterConcatFilter(len(localInputs)),
+		"-map", "[a]",
+		"-map_metadata", fmt.Sprint(metadataIndex),
+		"-map_chapters", fmt.Sprint(metadataIndex))
+	args = append(args, chapterCodecArgs(format)...)
+	args = append(args, tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+// audioChaptersDetails is the tool-specific part of the 'concat_audio_with_chapters' result.
+type audioChaptersDetails struct {
+	Chapters []audioChapter `json:"chapters"`
+}
+
+// addConcatAudioWithChaptersTool defines and registers the 'concat_audio_with_chapters' tool.
+func addConcatAudioWithChaptersTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("concat_audio_with_chapters",
+		mcp.WithDescription("Joins audio segments, such as audiobook chapters, into one M4A or MP3 file with a navigable chapter marker at the start of each segment. Each chapter's title and timestamps are written into the output container, and returned in the result."),
+		mcp.WithArray("segments", mcp.Required(),
+			mcp.Description(fmt.Sprintf("The audio segments in playback order (1 to %d). Each item is a URI (local path or gs://) or an object with 'uri' and an optional chapter 'title' (default 'Chapter N').", maxChapterSegments)),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"uri":   map[string]any{"type": "string"},
+					"title": map[string]any{"type": "string"},
+				},
+				"required": []string{"uri"},
+			}),
+		),
+		mcp.WithString("title", mcp.Description("Optional. The title of the whole file, written as its title and album tags.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output file. The extension selects the format: '.m4a' (default) or '.mp3'.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return concatAudioWithChaptersHandler(ctx, request, cfg)
+	})
+}
+
+// concatAudioWithChaptersHandler is the handler for the 'concat_audio_with_chapters' tool.
+// It measures each segment to place the chapters, and joins the segments with the chapters
+// written into the output.
+func concatAudioWithChaptersHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "concat_audio_with_chapters")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "concat_audio_with_chapters", argsMap)
+
+	segmentsRaw, _ := argsMap["segments"].([]interface{})
+	uris, titles, err := parseChapterSegments(segmentsRaw)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid segments: %v", err)), nil
+	}
+	title, _ := argsMap["title"].(string)
+	title = strings.TrimSpace(title)
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler concat_audio_with_chapters: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	format := "m4a"
+	if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFileName), ".")); ext != "" {
+		format = ext
+	}
+	if format != "m4a" && format != "mp3" {
+		return mcp.NewToolResultError(fmt.Sprintf("Unsupported output format '%s'. Chapters can be written to: %s.", format, strings.Join(chapterOutputFormats, ", "))), nil
+	}
+
+	span.SetAttributes(
+		attribute.StringSlice("segment_uris", uris),
+		attribute.String("format", format),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputs, inputCleanup, errPrep := prepareInputFiles(ctx, uris, "chapter_input", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare segment %v", errPrep)), nil
+	}
+	defer inputCleanup()
+	var durations []float64
+	for i, localPath := range localInputs {
+		input := probeOutput(ctx, localPath)
+		if input.AudioCodec == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("Segment %s has no audio stream.", uris[i])), nil
+		}
+		durations = append(durations, input.DurationSeconds)
+	}
+
+	chapters, err := buildChapters(titles, durations)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to place chapters: %v", err)), nil
+	}
+
+	workDir, err := os.MkdirTemp("", "chapters_")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create a temporary directory: %v", err)), nil
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	metadataFile := filepath.Join(workDir, "chapters.txt")
+	if err := os.WriteFile(metadataFile, []byte(buildFFMetadata(title, chapters)), 0644); err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to write chapter metadata: %v", err)), nil
+	}
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, format)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeConcatAudioWithChapters(ctx, localInputs, metadataFile, format, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg chapter concatenation failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	totalSeconds := chapters[len(chapters)-1].EndSeconds
+	messageParts := []string{fmt.Sprintf("Joined %d segment(s) into a %.2fs %s file with %d chapter(s) in %v.", len(chapters), totalSeconds, strings.ToUpper(format), len(chapters), duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "concat_audio_with_chapters", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, audioChaptersDetails{Chapters: chapters}), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseChapterSegments(t *testing.T) {
+	uris, titles, err := parseChapterSegments([]interface{}{
+		map[string]interface{}{"uri": " gs://bucket/intro.mp3 ", "title": " Introduction "},
+		"part2.wav",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if !slices.Equal(uris, []string{"gs://bucket/intro.mp3", "part2.wav"}) || !slices.Equal(titles, []string{"Introduction", "Chapter 2"}) {
+		t.Errorf("unexpected segments: %v %v", uris, titles)
+	}
+
+	tests := map[string][]interface{}{
+		"no segments":   {},
+		"missing uri":   {map[string]interface{}{"title": "Intro"}},
+		"invalid item":  {42.0},
+		"too many":      make([]interface{}, maxChapterSegments+1),
+	}
+	for name, input := range tests {
+		if _, _, err := parseChapterSegments(input); err == nil {
+			t.Errorf("%s: expected an error, but got nil", name)
+		}
+	}
+}
+
+func TestBuildChapters(t *testing.T) {
+	chapters, err := buildChapters([]string{"One", "Two", "Three"}, []float64{61.5, 30, 12.25})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	want := []audioChapter{{"One", 0, 61.5}, {"Two", 61.5, 91.5}, {"Three", 91.5, 103.75}}
+	if !slices.Equal(chapters, want) {
+		t.Errorf("expected %v, but got %v", want, chapters)
+	}
+	if _, err := buildChapters([]string{"One"}, []float64{0}); err == nil {
+		t.Errorf("expected an error for a segment of unknown duration")
+	}
+}
+
+func TestBuildFFMetadata(t *testing.T) {
+	got := buildFFMetadata("My Book", []audioChapter{{"Part 1; the = start", 0, 61.5}, {"#2", 61.5, 91.5}})
+	want := ";FFMETADATA1\ntitle=My Book\nalbum=My Book\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=61500\ntitle=Part 1\\; the \\= start\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=61500\nEND=91500\ntitle=\\#2\n"
+	if got != want {
+		t.Errorf("expected:\n%s\nbut got:\n%s", want, got)
+	}
+	if got := buildFFMetadata("", nil); got != ";FFMETADATA1\n" {
+		t.Errorf("expected only the header, but got %q", got)
+	}
+}
+
+func TestBuildChapterConcatFilter(t *testing.T) {
+	got := buildChapterConcatFilter(2)
+	want := "[0:a]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[a0];[1:a]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[a1];[a0][a1]concat=n=2:v=0:a=1[a]"
+	if got != want {
+		t.Errorf("expected %q, but got %q", want, got)
+	}
+	if args := strings.Join(chapterCodecArgs("mp3"), " "); !strings.Contains(args, "libmp3lame") || !strings.Contains(args, "-id3v2_version 3") {
+		t.Errorf("expected MP3 output with ID3v2.3 chapters, but got %q", args)
+	}
+	if args := strings.Join(chapterCodecArgs("m4a"), " "); !strings.Contains(args, "-c:a aac") {
+		t.Errorf("expected AAC output, but got %q", args)
+	}
+}
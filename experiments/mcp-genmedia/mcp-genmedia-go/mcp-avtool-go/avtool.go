@@ -42,25 +42,25 @@ func init() {
 // It logs the selection process for clarity.
 func determinePort(transport string, portFlag int) int {
 	if portFlag != 0 {
-		log.Printf("Using port %d from --port/-p flag.", portFlag)
+		common.LogInfof("Using port %d from --port/-p flag.", portFlag)
 		return portFlag
 	}
 
 	envPortStr := common.GetEnv("PORT", "")
 	if envPortStr != "" {
 		if envPort, err := strconv.Atoi(envPortStr); err == nil {
-			log.Printf("Using port %d from PORT environment variable.", envPort)
+			common.LogInfof("Using port %d from PORT environment variable.", envPort)
 			return envPort
 		}
-		log.Printf("Warning: Could not parse PORT environment variable '%s'. Falling back to default.", envPortStr)
+		common.LogWarnf("Warning: Could not parse PORT environment variable '%s'. Falling back to default.", envPortStr)
 	}
 
 	if transport == "http" {
-		log.Println("Using default port 8080 for http transport.")
+		common.LogInfof("Using default port 8080 for http transport.")
 		return 8080
 	}
 	if transport == "sse" {
-		log.Println("Using default port 8081 for sse transport.")
+		common.LogInfof("Using default port 8081 for sse transport.")
 		return 8081
 	}
 	return 0 // Should not happen for http/sse
@@ -77,6 +77,11 @@ func main() {
 	var cleanup func()
 	cfg, cleanup := common.Init(serviceName, version)
 	defer cleanup()
+	faststartOutputs = cfg.MP4Faststart
+	// Read after common.Init, which loads .env.
+	inputDownloadConcurrency = getDownloadConcurrency()
+	avtoolJobs = newJobRegistry(getJobTTL())
+	asyncJobTimeout = getJobTimeout()
 
 	s := server.NewMCPServer(
 		"AV Compositing Tool", // More general name
@@ -93,18 +98,43 @@ func main() {
 	addLayerAudioTool(s, cfg)
 	addCreateGifTool(s, cfg)
 	addGetMediaInfoTool(s, cfg)
+	addCompareImagesTool(s, cfg)
+	addScaleVideoTool(s, cfg)
+	addMixAudioTool(s, cfg)
+	addSidechainDuckTool(s, cfg)
+	addChromaKeyTool(s, cfg)
+	addInterpolateFPSTool(s, cfg)
+	addTrimToSceneTool(s, cfg)
+	addRemixChannelsTool(s, cfg)
+	addImagesToVideoTool(s, cfg)
+	addSRTFromAudioTool(s, cfg)
+	addSpeedRampTool(s, cfg)
+	addRenderTimelineTool(s, cfg)
+	addConcatAudioWithChaptersTool(s, cfg)
+	addSplitBySilenceTool(s, cfg)
+	addVideoGridTool(s, cfg)
+	addGetJobTool(s, cfg)
+	addListCapabilitiesTool(s, cfg)
+	addValidateGCSAccessTool(s, cfg)
+	addResizeImageTool(s, cfg)
+	addCropImageTool(s, cfg)
+	addRotateImageTool(s, cfg)
+	addConvertImageTool(s, cfg)
+
+	common.AddIntrospectTool(s, cfg)
+	common.AddServerInfoResource(s, serviceName, version, transport)
 
 	switch transport {
 	case "sse":
 		ssePort := determinePort("sse", port)
-		log.Printf("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
+		common.LogInfof("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := determinePort("http", port)
-		log.Printf("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
+		common.LogInfof("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
 		mcpHTTPHandler := server.NewStreamableHTTPServer(s) // Base path /mcp
 		c := cors.New(cors.Options{
 			AllowedOrigins:   []string{"*"}, // Consider making this configurable
@@ -116,16 +146,17 @@ func main() {
 		})
 		handlerWithCORS := c.Handler(mcpHTTPHandler)
 		listenAddr := fmt.Sprintf(":%d", httpPort)
-		if err := http.ListenAndServe(listenAddr, handlerWithCORS); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		httpServer := common.NewHTTPServer(listenAddr, handlerWithCORS)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: stdio)", version)
+		common.LogInfof("Starting AV Compositing Tool (avtool) MCP Server (Version: %s, Transport: stdio)", version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type '%s' specified. Please use 'stdio', 'http', or 'sse'.", transport)
+		common.LogFatalf("Unsupported transport type '%s' specified. Please use 'stdio', 'http', or 'sse'.", transport)
 	}
-	log.Println("AV Compositing Tool (avtool) Server has stopped.")
+	common.LogInfof("AV Compositing Tool (avtool) Server has stopped.")
 }
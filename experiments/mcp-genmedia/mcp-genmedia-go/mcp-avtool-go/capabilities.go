@@ -0,0 +1,229 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+)
+
+// ffmpegCapability is an FFMpeg encoder or filter that avtool tools depend on.
+type ffmpegCapability struct {
+	Name            string   `json:"name"`
+	Available       bool     `json:"available"`
+	Required        bool     `json:"required"`                    // False for capabilities that only some builds provide.
+	MinMajorVersion int      `json:"min_major_version,omitempty"` // Oldest FFMpeg major version with the options the tools use; 0 for any.
+	UsedBy          []string `json:"used_by,omitempty"`
+}
+
+// ffmpegVersionPattern matches the major version in the first line of 'ffmpeg -version', e.g.
+// "ffmpeg version 6.1.1-3ubuntu5". Builds from git ("ffmpeg version N-...") have no version.
+var ffmpegVersionPattern = regexp.MustCompile(`^ffmpeg version n?(\d+)\.`)
+
+// avtoolEncoders lists the encoders used by the avtool tools.
+var avtoolEncoders = []ffmpegCapability{
+	{Name: "libx264", Required: true, UsedBy: []string{"ffmpeg_scale_video", "ffmpeg_concatenate_media_files", "ffmpeg_trim_to_scene", "ffmpeg_interpolate_fps", "ffmpeg_images_to_video", "srt_from_audio", "ffmpeg_speed_ramp", "render_timeline", "video_grid"}},
+	{Name: "aac", Required: true, UsedBy: []string{"ffmpeg_combine_audio_and_video", "ffmpeg_concatenate_media_files", "ffmpeg_layer_audio_files", "ffmpeg_trim_to_scene", "ffmpeg_images_to_video", "srt_from_audio", "ffmpeg_speed_ramp", "render_timeline", "concat_audio_with_chapters", "video_grid"}},
+	{Name: "libmp3lame", Required: true, UsedBy: []string{"ffmpeg_convert_audio_wav_to_mp3", "ffmpeg_concatenate_media_files", "ffmpeg_mix_audio", "ffmpeg_sidechain_duck", "ffmpeg_adjust_volume", "concat_audio_with_chapters"}},
+	{Name: "pcm_s16le", Required: true, UsedBy: []string{"ffmpeg_layer_audio_files", "ffmpeg_mix_audio"}},
+	{Name: "flac", Required: true, UsedBy: []string{"srt_from_audio"}},
+	{Name: "gif", Required: true, UsedBy: []string{"ffmpeg_video_to_gif"}},
+	{Name: "png", Required: true, UsedBy: []string{"ffmpeg_video_to_gif"}},
+	{Name: "libx265"}, // Optional; reported so clients can tell whether HEVC output is possible.
+	// Optional; only needed for transparent output from ffmpeg_chroma_key.
+	{Name: "libvpx-vp9", UsedBy: []string{"ffmpeg_chroma_key"}},
+	{Name: "libopus", UsedBy: []string{"ffmpeg_chroma_key"}},
+	{Name: "prores_ks", UsedBy: []string{"ffmpeg_chroma_key"}},
+}
+
+// avtoolFilters lists the filters used by the avtool tools.
+var avtoolFilters = []ffmpegCapability{
+	{Name: "scale", Required: true, UsedBy: []string{"ffmpeg_scale_video", "ffmpeg_video_to_gif", "ffmpeg_concatenate_media_files", "ffmpeg_chroma_key", "ffmpeg_images_to_video", "render_timeline", "video_grid"}},
+	{Name: "palettegen", Required: true, UsedBy: []string{"ffmpeg_video_to_gif"}},
+	{Name: "paletteuse", Required: true, UsedBy: []string{"ffmpeg_video_to_gif"}},
+	{Name: "overlay", Required: true, UsedBy: []string{"ffmpeg_overlay_image_on_video", "ffmpeg_chroma_key", "render_timeline"}},
+	{Name: "volume", Required: true, UsedBy: []string{"ffmpeg_adjust_volume", "ffmpeg_combine_audio_and_video", "ffmpeg_mix_audio", "ffmpeg_sidechain_duck", "render_timeline"}},
+	{Name: "amix", Required: true, UsedBy: []string{"ffmpeg_layer_audio_files", "ffmpeg_mix_audio", "ffmpeg_sidechain_duck", "render_timeline"}},
+	{Name: "adelay", Required: true, UsedBy: []string{"ffmpeg_mix_audio", "render_timeline"}},
+	{Name: "alimiter", Required: true, UsedBy: []string{"ffmpeg_mix_audio", "ffmpeg_sidechain_duck"}},
+	{Name: "asplit", Required: true, UsedBy: []string{"ffmpeg_sidechain_duck", "ffmpeg_speed_ramp"}},
+	{Name: "apad", Required: true, UsedBy: []string{"ffmpeg_sidechain_duck", "ffmpeg_images_to_video"}},
+	{Name: "sidechaincompress", Required: true, UsedBy: []string{"ffmpeg_sidechain_duck"}},
+	{Name: "concat", Required: true, UsedBy: []string{"ffmpeg_concatenate_media_files", "ffmpeg_images_to_video", "ffmpeg_speed_ramp", "render_timeline", "concat_audio_with_chapters"}},
+	{Name: "select", Required: true, UsedBy: []string{"ffmpeg_trim_to_scene"}},
+	{Name: "showinfo", Required: true, UsedBy: []string{"ffmpeg_trim_to_scene"}},
+	{Name: "chromakey", Required: true, UsedBy: []string{"ffmpeg_chroma_key"}},
+	{Name: "colorkey", Required: true, UsedBy: []string{"ffmpeg_chroma_key"}},
+	{Name: "crop", Required: true, UsedBy: []string{"ffmpeg_chroma_key"}},
+	{Name: "minterpolate", Required: true, UsedBy: []string{"ffmpeg_interpolate_fps"}},
+	{Name: "acrossfade", Required: true, UsedBy: []string{"ffmpeg_concatenate_media_files", "render_timeline"}},
+	{Name: "trim", Required: true, UsedBy: []string{"ffmpeg_concatenate_media_files", "render_timeline"}},
+	{Name: "pan", Required: true, UsedBy: []string{"ffmpeg_remix_channels"}},
+	{Name: "xfade", Required: true, UsedBy: []string{"ffmpeg_images_to_video", "render_timeline"}},
+	{Name: "pad", Required: true, UsedBy: []string{"ffmpeg_images_to_video", "render_timeline", "video_grid"}},
+	{Name: "setpts", Required: true, UsedBy: []string{"ffmpeg_speed_ramp", "render_timeline", "video_grid"}},
+	{Name: "atrim", Required: true, UsedBy: []string{"ffmpeg_speed_ramp", "render_timeline"}},
+	{Name: "asetpts", Required: true, UsedBy: []string{"ffmpeg_speed_ramp", "render_timeline"}},
+	{Name: "atempo", Required: true, UsedBy: []string{"ffmpeg_speed_ramp"}},
+	{Name: "aformat", Required: true, UsedBy: []string{"render_timeline", "concat_audio_with_chapters"}},
+	{Name: "anullsrc", Required: true, UsedBy: []string{"render_timeline"}},
+	{Name: "silencedetect", Required: true, UsedBy: []string{"split_by_silence"}},
+	{Name: "xstack", Required: true, MinMajorVersion: 5, UsedBy: []string{"video_grid"}}, // The fill option was added in FFMpeg 5.
+	{Name: "fps", Required: true, UsedBy: []string{"video_grid"}},
+	{Name: "format", Required: true, UsedBy: []string{"video_grid"}},
+	{Name: "setsar", Required: true, UsedBy: []string{"video_grid"}},
+	// Optional; only builds with libvidstab provide video stabilization.
+	{Name: "vidstabdetect"},
+	{Name: "vidstabtransform"},
+	// Optional; only builds with libass can burn captions into video.
+	{Name: "subtitles", UsedBy: []string{"srt_from_audio"}},
+}
+
+// avtoolCapabilities is the JSON result of the 'list_avtool_capabilities' tool.
+type avtoolCapabilities struct {
+	FFmpegVersion string             `json:"ffmpeg_version"`
+	Encoders      []ffmpegCapability `json:"encoders"`
+	Filters       []ffmpegCapability `json:"filters"`
+	Missing       []string           `json:"missing"`         // Required capabilities that are unavailable.
+	Unavailable   []string           `json:"unavailable"`     // Optional capabilities that are unavailable.
+	Error         string             `json:"error,omitempty"` // Set if FFMpeg could not be queried.
+}
+
+// parseFFmpegEncoders extracts encoder names from the output of 'ffmpeg -encoders'.
+// The list follows a legend terminated by a "------" line; each entry is "<flags> <name> <description>".
+func parseFFmpegEncoders(output string) map[string]bool {
+	encoders := map[string]bool{}
+	inList := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "------") {
+			inList = true
+			continue
+		}
+		if fields := strings.Fields(line); inList && len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// parseFFmpegFilters extracts filter names from the output of 'ffmpeg -filters'.
+// Each entry is "<flags> <name> <inputs>-><outputs> <description>"; legend lines have no "->" column.
+func parseFFmpegFilters(output string) map[string]bool {
+	filters := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			filters[fields[1]] = true
+		}
+	}
+	return filters
+}
+
+// parseFFmpegMajorVersion returns the major version from the first line of 'ffmpeg -version', or
+// 0 if it is unknown.
+func parseFFmpegMajorVersion(versionLine string) int {
+	match := ffmpegVersionPattern.FindStringSubmatch(versionLine)
+	if match == nil {
+		return 0
+	}
+	major, _ := strconv.Atoi(match[1])
+	return major
+}
+
+// checkCapabilities marks each capability as available or not and returns the names of the
+// missing required and optional capabilities. A capability that needs a newer FFMpeg than
+// majorVersion is unavailable; if majorVersion is 0 (unknown), versions are not checked.
+func checkCapabilities(capabilities []ffmpegCapability, available map[string]bool, majorVersion int) (checked []ffmpegCapability, missing, unavailable []string) {
+	for _, c := range capabilities {
+		c.Available = available[c.Name] && (majorVersion == 0 || majorVersion >= c.MinMajorVersion)
+		checked = append(checked, c)
+		switch {
+		case c.Available:
+		case c.Required:
+			missing = append(missing, c.Name)
+		default:
+			unavailable = append(unavailable, c.Name)
+		}
+	}
+	return checked, missing, unavailable
+}
+
+// queryAVToolCapabilities runs FFMpeg to list its encoders and filters and checks them against
+// those the avtool tools depend on.
+func queryAVToolCapabilities(ctx context.Context) avtoolCapabilities {
+	result := avtoolCapabilities{Missing: []string{}, Unavailable: []string{}}
+
+	versionOutput, err := runFFmpegCommand(ctx, "-hide_banner", "-version")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to run ffmpeg: %v", err)
+		return result
+	}
+	result.FFmpegVersion = strings.TrimSpace(strings.SplitN(versionOutput, "\n", 2)[0])
+
+	encodersOutput, err := runFFmpegCommand(ctx, "-hide_banner", "-encoders")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to list ffmpeg encoders: %v", err)
+		return result
+	}
+	filtersOutput, err := runFFmpegCommand(ctx, "-hide_banner", "-filters")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to list ffmpeg filters: %v", err)
+		return result
+	}
+
+	var missing, unavailable []string
+	majorVersion := parseFFmpegMajorVersion(result.FFmpegVersion)
+	result.Encoders, missing, unavailable = checkCapabilities(avtoolEncoders, parseFFmpegEncoders(encodersOutput), majorVersion)
+	result.Missing = append(result.Missing, missing...)
+	result.Unavailable = append(result.Unavailable, unavailable...)
+	result.Filters, missing, unavailable = checkCapabilities(avtoolFilters, parseFFmpegFilters(filtersOutput), majorVersion)
+	result.Missing = append(result.Missing, missing...)
+	result.Unavailable = append(result.Unavailable, unavailable...)
+	return result
+}
+
+// addListCapabilitiesTool defines and registers the 'list_avtool_capabilities' tool.
+func addListCapabilitiesTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("list_avtool_capabilities",
+		mcp.WithDescription("Reports which FFMpeg encoders and filters the avtool tools depend on are available in this server's FFMpeg build (e.g. libx264, libmp3lame, vidstab), and which tools use them. Required capabilities that are unavailable are listed under 'missing'."),
+	)
+	common.AddTool(s, cfg, tool, listCapabilitiesHandler)
+}
+
+// listCapabilitiesHandler is the handler for the 'list_avtool_capabilities' tool.
+func listCapabilitiesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "list_avtool_capabilities")
+	defer span.End()
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	capabilities := queryAVToolCapabilities(queryCtx)
+	if capabilities.Error != "" {
+		common.LogWarnf("list_avtool_capabilities: %s", capabilities.Error)
+	} else if len(capabilities.Missing) > 0 {
+		common.LogWarnf("list_avtool_capabilities: FFMpeg build is missing required capabilities: %s", strings.Join(capabilities.Missing, ", "))
+	}
+
+	out, err := json.MarshalIndent(capabilities, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode capabilities: %v", err)), nil
+	}
+	if capabilities.Error != "" {
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(out))}, IsError: true}, nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+const testEncodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D gif                  GIF (Graphics Interchange Format)
+ V....D png                  PNG (Portable Network Graphics) image
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libvpx-vp9           libvpx VP9 (codec vp9)
+ VF...D prores_ks            Apple ProRes (iCodec Pro) (codec prores)
+ A....D flac                 FLAC (Free Lossless Audio Codec)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A....D pcm_s16le            PCM signed 16-bit little-endian
+`
+
+const testFiltersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  A = Audio input/output
+ ... acrossfade        AA->A      Cross fade two input audio streams.
+ ... adelay            A->A       Delay one or more audio channels.
+ ... aformat           A->A       Convert the input audio to one of the specified formats.
+ T.. alimiter          A->A       Audio lookahead limiter.
+ ... amix              N->A       Audio mixing.
+ ... anullsrc          |->A       Null audio source, return empty audio frames.
+ ... apad              A->A       Pad audio with silence.
+ ... asetpts           A->A       Set PTS for the output audio frame.
+ ... asplit            A->N       Pass on the audio input to N audio outputs.
+ ... atempo            A->A       Adjust audio tempo.
+ ... atrim             A->A       Pick one continuous section from the input, drop the rest.
+ ... sidechaincompress AA->A      Sidechain compressor.
+ ... silencedetect     A->A       Detect silence.
+ T.C volume            A->A       Change input volume.
+ ... chromakey         V->V       Turns a certain color into transparency. Operates on YUV colors.
+ ... colorkey          V->V       Turns a certain color into transparency. Operates on RGB colors.
+ ... concat            N->N       Concatenate audio and video streams.
+ ... crop              V->V       Crop the input video.
+ ... minterpolate      V->V       Frame rate conversion using Motion Interpolation.
+ ... pan               A->A       Remix channels with coefficients (panning).
+ ... pad               V->V       Pad the input video.
+ ... palettegen        V->V       Find the optimal palette for a given stream.
+ ... paletteuse        VV->V      Use a palette to downsample an input video stream.
+ TSC overlay           VV->V      Overlay a video source on top of the input.
+ ..C scale             V->V       Scale the input video size and/or convert the image format.
+ ... setpts            V->V       Set PTS for the output video frame.
+ T.. select            V->V       Select video frames to pass in output.
+ ... showinfo          V->V       Show textual information for each video frame.
+ ... trim              V->V       Pick one continuous section from the input, drop the rest.
+ ... xfade             VV->V      Cross fade one video with another video.
+ ... xstack            N->V       Stack video inputs into custom layout.
+ ... fps               V->V       Force constant framerate.
+ ... format            V->V       Convert the input video to one of the specified pixel formats.
+ ..C setsar            V->V       Set the pixel sample aspect ratio.
+ ... subtitles         V->V       Render text subtitles onto input video using the libass library.
+ ... vidstabdetect     V->V       Extract relative transformations.
+`
+
+func TestParseFFmpegCapabilities(t *testing.T) {
+	encoders := parseFFmpegEncoders(testEncodersOutput)
+	filters := parseFFmpegFilters(testFiltersOutput)
+
+	for name, want := range map[string]bool{"libx264": true, "aac": true, "libmp3lame": false, "=": false} {
+		if encoders[name] != want {
+			t.Errorf("expected encoder %s available to be %t, but got %t", name, want, encoders[name])
+		}
+	}
+	for name, want := range map[string]bool{"scale": true, "vidstabdetect": true, "vidstabtransform": false, "=": false} {
+		if filters[name] != want {
+			t.Errorf("expected filter %s available to be %t, but got %t", name, want, filters[name])
+		}
+	}
+
+	checkedEncoders, missing, unavailable := checkCapabilities(avtoolEncoders, encoders, 6)
+	if !slices.Equal(missing, []string{"libmp3lame"}) {
+		t.Errorf("expected only libmp3lame to be missing, but got %v", missing)
+	}
+	if !slices.Equal(unavailable, []string{"libx265", "libopus"}) {
+		t.Errorf("expected libx265 and libopus to be reported as unavailable, but got %v", unavailable)
+	}
+	for _, c := range checkedEncoders {
+		if c.Name == "libx264" && !c.Available {
+			t.Errorf("expected libx264 to be available")
+		}
+	}
+
+	_, missing, unavailable = checkCapabilities(avtoolFilters, filters, 6)
+	if len(missing) != 0 {
+		t.Errorf("expected no missing filters, but got %v", missing)
+	}
+	if !slices.Equal(unavailable, []string{"vidstabtransform"}) {
+		t.Errorf("expected vidstabtransform to be reported as unavailable, but got %v", unavailable)
+	}
+}
+
+func TestParseFFmpegMajorVersion(t *testing.T) {
+	tests := map[string]int{
+		"ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers":    6,
+		"ffmpeg version n4.4.2 Copyright (c) 2000-2021 the FFmpeg developers":            4,
+		"ffmpeg version N-113372-g2b0ad5a Copyright (c) 2000-2024 the FFmpeg developers": 0,
+		"": 0,
+	}
+	for line, want := range tests {
+		if got := parseFFmpegMajorVersion(line); got != want {
+			t.Errorf("parseFFmpegMajorVersion(%q): expected %d, but got %d", line, want, got)
+		}
+	}
+}
+
+func TestCheckCapabilitiesMinMajorVersion(t *testing.T) {
+	capabilities := []ffmpegCapability{{Name: "xstack", Required: true, MinMajorVersion: 5}}
+	available := map[string]bool{"xstack": true}
+	for _, tt := range []struct {
+		version     int
+		wantMissing bool
+	}{{4, true}, {5, false}, {0, false}} {
+		_, missing, _ := checkCapabilities(capabilities, available, tt.version)
+		if (len(missing) > 0) != tt.wantMissing {
+			t.Errorf("FFMpeg %d: expected xstack missing to be %t, but got %v", tt.version, tt.wantMissing, missing)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	speech "google.golang.org/api/speech/v1"
+)
+
+const (
+	// maxInlineSpeechAudioBytes is the largest audio sent inline to the Speech-to-Text API; longer
+	// audio is staged in GCS.
+	maxInlineSpeechAudioBytes = 10 << 20
+	// speechPollInterval is how often a long-running recognition is polled.
+	speechPollInterval = 5 * time.Second
+	// speechTranscriptionTimeout bounds the transcription of one file.
+	speechTranscriptionTimeout = 30 * time.Minute
+
+	defaultCaptionMaxChars   = 42
+	defaultCaptionMaxSeconds = 6.0
+	minCaptionMaxChars       = 10
+	maxCaptionMaxChars       = 120
+	minCaptionMaxSeconds     = 1.0
+	maxCaptionMaxSeconds     = 20.0
+	// captionPauseSeconds is the silence between two words that starts a new caption.
+	captionPauseSeconds = 1.0
+)
+
+// captionFormats are the caption file formats produced by srt_from_audio.
+var captionFormats = []string{"srt", "vtt"}
+
+var (
+	speechService   *speech.Service
+	speechServiceMu sync.Mutex
+)
+
+// captionWord is a recognized word with its start and end time in seconds.
+type captionWord struct {
+	Text       string
+	Start, End float64
+}
+
+// captionCue is a caption shown from Start to End seconds.
+type captionCue struct {
+	Start, End float64
+	Text       string
+}
+
+// captionDetails is the tool-specific part of the srt_from_audio result.
+type captionDetails struct {
+	LanguageCode string `json:"language_code"`
+	Format       string `json:"format"`
+	Cues         int    `json:"cues"`
+	Words        int    `json:"words"`
+	Transcript   string `json:"transcript"`
+	Captions     string `json:"captions,omitempty"` // The caption file, if it was not saved.
+}
+
+// ensureSpeechService lazily creates the Speech-to-Text API client. The client outlives the
+// request that creates it, so it is not bound to the request's context, and a failure is retried
+// by the next request.
+func ensureSpeechService() (*speech.Service, error) {
+	speechServiceMu.Lock()
+	defer speechServiceMu.Unlock()
+	if speechService != nil {
+		return speechService, nil
+	}
+	svc, err := speech.NewService(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	speechService = svc
+	return speechService, nil
+}
+
+// validateCaptionLimits checks the caption length and duration limits against the bounds
+// advertised by srt_from_audio.
+func validateCaptionLimits(maxChars int, maxSeconds float64) error {
+	if maxChars < minCaptionMaxChars || maxChars > maxCaptionMaxChars {
+		return fmt.Errorf("max_chars_per_caption must be between %d and %d, but got %d", minCaptionMaxChars, maxCaptionMaxChars, maxChars)
+	}
+	if maxSeconds < minCaptionMaxSeconds || maxSeconds > maxCaptionMaxSeconds {
+		return fmt.Errorf("max_caption_seconds must be between %g and %g, but got %g", minCaptionMaxSeconds, maxCaptionMaxSeconds, maxSeconds)
+	}
+	return nil
+}
+
+// parseSpeechOffset parses a time offset returned by the Speech-to-Text API, such as "1.500s",
+// into seconds.
+func parseSpeechOffset(offset string) float64 {
+	if offset == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		common.LogWarnf("Warning: could not parse speech time offset %q: %v", offset, err)
+		return 0
+	}
+	return d.Seconds()
+}
+
+// wordsFromSpeechResults returns the timed words of the most likely alternative of each result.
+func wordsFromSpeechResults(results []*speech.SpeechRecognitionResult) []captionWord {
+	var words []captionWord
+	for _, result := range results {
+		if result == nil || len(result.Alternatives) == 0 || result.Alternatives[0] == nil {
+			continue
+		}
+		for _, w := range result.Alternatives[0].Words {
+			words = append(words, captionWord{Text: w.Word, Start: parseSpeechOffset(w.StartTime), End: parseSpeechOffset(w.EndTime)})
+		}
+	}
+	return words
+}
+
+// transcribeWithWordTimes transcribes FLAC audio with the Speech-to-Text API and returns the
+// recognized words with their timings. Audio is sent inline, or read from gcsURI if it is set.
+func transcribeWithWordTimes(ctx context.Context, audio []byte, gcsURI, languageCode string) ([]captionWord, error) {
+	svc, err := ensureSpeechService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Speech-to-Text client: %w", err)
+	}
+	recognitionAudio := &speech.RecognitionAudio{Uri: gcsURI}
+	if gcsURI == "" {
+		recognitionAudio.Content = base64.StdEncoding.EncodeToString(audio)
+	}
+	req := &speech.LongRunningRecognizeRequest{
+		Config: &speech.RecognitionConfig{
+			Encoding:                   "FLAC",
+			SampleRateHertz:            speechAudioSampleRate,
+			LanguageCode:               languageCode,
+			EnableWordTimeOffsets:      true,
+			EnableAutomaticPunctuation: true,
+		},
+		Audio: recognitionAudio,
+	}
+
+	op, err := svc.Speech.Longrunningrecognize(req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("speech recognition request failed: %w", err)
+	}
+	common.LogInfof("Started speech recognition operation %s", op.Name)
+	ticker := time.NewTicker(speechPollInterval)
+	defer ticker.Stop()
+	for !op.Done {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("speech recognition operation %s did not finish: %w", op.Name, ctx.Err())
+		case <-ticker.C:
+		}
+		if op, err = svc.Operations.Get(op.Name).Context(ctx).Do(); err != nil {
+			return nil, fmt.Errorf("failed to poll speech recognition operation: %w", err)
+		}
+	}
+	if op.Error != nil {
+		return nil, fmt.Errorf("speech recognition failed: %s", op.Error.Message)
+	}
+
+	var resp speech.LongRunningRecognizeResponse
+	if err := json.Unmarshal(op.Response, &resp); err != nil {
+		return nil, fmt.Errorf("invalid speech recognition response: %w", err)
+	}
+	return wordsFromSpeechResults(resp.Results), nil
+}
+
+// endsSentence reports whether a word ends with sentence-ending punctuation.
+func endsSentence(word string) bool {
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "?") || strings.HasSuffix(word, "!")
+}
+
+// buildCaptionCues groups timed words into captions of at most maxChars characters and
+// maxSeconds seconds. A new caption also starts after the end of a sentence and after a pause
+// in the speech.
+func buildCaptionCues(words []captionWord, maxChars int, maxSeconds float64) []captionCue {
+	var cues []captionCue
+	var current captionCue
+	var currentChars int
+	var lastWord string
+	for _, w := range words {
+		text := strings.TrimSpace(w.Text)
+		if text == "" {
+			continue
+		}
+		textChars := utf8.RuneCountInString(text)
+		if currentChars > 0 && (currentChars+1+textChars > maxChars ||
+			w.End-current.Start > maxSeconds ||
+			w.Start-current.End > captionPauseSeconds ||
+			endsSentence(lastWord)) {
+			cues = append(cues, current)
+			currentChars = 0
+		}
+		if currentChars == 0 {
+			current = captionCue{Start: w.Start, End: w.End, Text: text}
+			currentChars = textChars
+		} else {
+			current.Text += " " + text
+			current.End = w.End
+			currentChars += 1 + textChars
+		}
+		lastWord = text
+	}
+	if currentChars > 0 {
+		cues = append(cues, current)
+	}
+	return cues
+}
+
+// formatCaptionTimestamp formats seconds as HH:MM:SS followed by the millisecond separator
+// (',' for SRT, '.' for WebVTT) and milliseconds.
+func formatCaptionTimestamp(seconds float64, msSeparator string) string {
+	ms := int64(math.Round(math.Max(seconds, 0) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, msSeparator, ms%1000)
+}
+
+// formatCaptions renders caption cues as an SRT or WebVTT file.
+func formatCaptions(cues []captionCue, format string) string {
+	var b strings.Builder
+	separator := ","
+	if format == "vtt" {
+		b.WriteString("WEBVTT\n\n")
+		separator = "."
+	}
+	for i, cue := range cues {
+		if format != "vtt" {
+			fmt.Fprintf(&b, "%d\n", i+1)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatCaptionTimestamp(cue.Start, separator), formatCaptionTimestamp(cue.End, separator), cue.Text)
+	}
+	return b.String()
+}
+
+// addSRTFromAudioTool defines and registers the 'srt_from_audio' tool.
+func addSRTFromAudioTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("srt_from_audio",
+		mcp.WithDescription("Auto-captions a video or audio file: extracts the audio, transcribes it with word timings using the Speech-to-Text API, and writes the captions as an SRT or WebVTT file. Optionally burns the captions into a copy of the video."),
+		mcp.WithString("input_media_uri", mcp.Required(), mcp.Description("URI of the input video or audio file (local path or gs://).")),
+		mcp.WithString("language_code", mcp.DefaultString("en-US"), mcp.Description("Optional. BCP-47 code of the spoken language, e.g. 'en-US' or 'de-DE'. Defaults to en-US.")),
+		mcp.WithString("format", mcp.DefaultString("srt"), mcp.Enum(captionFormats...), mcp.Description("Optional. The caption file format: 'srt' (SubRip) or 'vtt' (WebVTT). Defaults to srt.")),
+		mcp.WithNumber("max_chars_per_caption", mcp.DefaultNumber(defaultCaptionMaxChars), mcp.Min(minCaptionMaxChars), mcp.Max(maxCaptionMaxChars), mcp.Description("Optional. The maximum length of a caption in characters. Defaults to 42.")),
+		mcp.WithNumber("max_caption_seconds", mcp.DefaultNumber(defaultCaptionMaxSeconds), mcp.Min(minCaptionMaxSeconds), mcp.Max(maxCaptionMaxSeconds), mcp.Description("Optional. The maximum time a caption is shown, in seconds. Defaults to 6.")),
+		mcp.WithBoolean("burn_in", mcp.Description("Optional. If true, also renders the captions onto a copy of the video (MP4, named after the caption file with a '_captioned' suffix). Requires a video input and an FFMpeg build with libass.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the caption file (e.g., 'captions.srt'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output files.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output files to (uses GENMEDIA_BUCKET if set and this is empty). Audio too large to send inline to the Speech-to-Text API is staged here.")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return srtFromAudioHandler(ctx, request, cfg)
+	})
+}
+
+// srtFromAudioHandler is the handler for the 'srt_from_audio' tool.
+func srtFromAudioHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "srt_from_audio")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "srt_from_audio", argsMap)
+
+	inputMediaURI, _ := argsMap["input_media_uri"].(string)
+	if strings.TrimSpace(inputMediaURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_media_uri' is required."), nil
+	}
+	languageCode, _ := argsMap["language_code"].(string)
+	if languageCode = strings.TrimSpace(languageCode); languageCode == "" {
+		languageCode = "en-US"
+	}
+	format, _ := argsMap["format"].(string)
+	if format = strings.ToLower(strings.TrimSpace(format)); format == "" {
+		format = "srt"
+	}
+	if format != "srt" && format != "vtt" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid format '%s'. Must be one of: %s.", format, strings.Join(captionFormats, ", "))), nil
+	}
+	maxChars := defaultCaptionMaxChars
+	if v, ok := argsMap["max_chars_per_caption"].(float64); ok {
+		maxChars = int(v)
+	}
+	maxSeconds := defaultCaptionMaxSeconds
+	if v, ok := argsMap["max_caption_seconds"].(float64); ok {
+		maxSeconds = v
+	}
+	if err := validateCaptionLimits(maxChars, maxSeconds); err != nil {
+		return mcp.NewToolResultError(err.Error() + "."), nil
+	}
+	burnIn, _ := argsMap["burn_in"].(bool)
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler srt_from_audio: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_media_uri", inputMediaURI),
+		attribute.String("language_code", languageCode),
+		attribute.String("format", format),
+		attribute.Bool("burn_in", burnIn),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInput, inputCleanup, err := common.PrepareInputFile(ctx, inputMediaURI, "input_media", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input media: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	if burnIn {
+		if probed, probeErr := executeGetMediaInfo(ctx, localInput); probeErr == nil {
+			if info, parseErr := parseProbeOutput(probed); parseErr == nil && info.VideoCodec == "" {
+				return mcp.NewToolResultError("burn_in requires a video input, but the input has no video stream."), nil
+			}
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "captions_")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create a temporary directory: %v", err)), nil
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	audioPath := filepath.Join(workDir, "speech.flac")
+	if _, ffmpegErr := executeExtractSpeechAudio(ctx, localInput, audioPath); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg audio extraction failed: %v", ffmpegErr)), nil
+	}
+	audio, err := os.ReadFile(audioPath)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read the extracted audio: %v", err)), nil
+	}
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, format)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+	baseName := strings.TrimSuffix(finalOutputFilename, filepath.Ext(finalOutputFilename))
+
+	var stagedAudioURI string
+	if len(audio) > maxInlineSpeechAudioBytes {
+		if outputGCSBucket == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("The extracted audio is %s, more than the %s that can be sent inline to the Speech-to-Text API. Set 'output_gcs_bucket' or GENMEDIA_BUCKET to stage it in GCS.", common.FormatBytes(int64(len(audio))), common.FormatBytes(maxInlineSpeechAudioBytes))), nil
+		}
+		objectName := baseName + "_speech.flac"
+		// The Speech-to-Text API reads the audio from Cloud Storage, whatever STORAGE_BACKEND.
+		if err := common.UploadGeneratedToGCS(ctx, outputGCSBucket, objectName, "audio/flac", audio); err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to stage the audio in GCS: %v", err)), nil
+		}
+		stagedAudioURI = fmt.Sprintf("gs://%s/%s", outputGCSBucket, objectName)
+		common.LogInfof("Staged %s of audio for speech recognition at %s", common.FormatBytes(int64(len(audio))), stagedAudioURI)
+		// The staged audio is only needed until the transcription finishes or fails. It is deleted
+		// even if the request is canceled.
+		defer func() {
+			if err := common.DeleteFromGCS(context.WithoutCancel(ctx), stagedAudioURI); err != nil {
+				common.LogWarnf("Warning: failed to delete the staged audio %s: %v", stagedAudioURI, err)
+			}
+		}()
+	}
+
+	transcribeCtx, cancel := context.WithTimeout(ctx, speechTranscriptionTimeout)
+	defer cancel()
+	words, err := transcribeWithWordTimes(transcribeCtx, audio, stagedAudioURI, languageCode)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return mcp.NewToolResultError(fmt.Sprintf("Transcription did not finish within %s.", speechTranscriptionTimeout)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Transcription failed: %v", err)), nil
+	}
+	cues := buildCaptionCues(words, maxChars, maxSeconds)
+	if len(cues) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("No speech was recognized in %s (language %s).", inputMediaURI, languageCode)), nil
+	}
+	captions := formatCaptions(cues, format)
+	if err := os.WriteFile(tempOutputFile, []byte(captions), 0644); err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to write the caption file: %v", err)), nil
+	}
+
+	var outputs []avtoolOutput
+	var messageParts []string
+	// The captioned video is rendered first, while the caption file is still in its temporary location.
+	if burnIn {
+		tempVideoFile, finalVideoFilename, videoCleanup, prepErr := common.HandleOutputPreparation(baseName+"_captioned.mp4", "mp4")
+		if prepErr != nil {
+			span.RecordError(prepErr)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", prepErr)), nil
+		}
+		defer videoCleanup()
+		if _, ffmpegErr := executeBurnSubtitles(ctx, localInput, tempOutputFile, tempVideoFile); ffmpegErr != nil {
+			span.RecordError(ffmpegErr)
+			return mcp.NewToolResultError(fmt.Sprintf("FFMpeg caption burn-in failed: %v", ffmpegErr)), nil
+		}
+		videoOutput := probeOutput(ctx, tempVideoFile)
+		videoLocalPath, videoGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempVideoFile, finalVideoFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+		if processErr != nil {
+			span.RecordError(processErr)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+		}
+		outputs = append(outputs, videoOutput.savedTo(outputLocalDir, videoLocalPath, videoGCSPath))
+		if videoGCSPath != "" {
+			messageParts = append(messageParts, fmt.Sprintf("Captioned video uploaded to GCS: %s.", videoGCSPath))
+		} else if outputLocalDir != "" {
+			messageParts = append(messageParts, fmt.Sprintf("Captioned video saved locally to: %s.", videoLocalPath))
+		}
+	}
+
+	captionOutput := avtoolOutput{Format: format, DurationSeconds: cues[len(cues)-1].End}
+	if info, statErr := os.Stat(tempOutputFile); statErr == nil {
+		captionOutput.SizeBytes = info.Size()
+	}
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process the caption file: %v", processErr)), nil
+	}
+	outputs = append([]avtoolOutput{captionOutput.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, outputs...)
+
+	duration := time.Since(startTime)
+	span.SetAttributes(
+		attribute.Int("cues", len(cues)),
+		attribute.Float64("duration_ms", float64(duration.Milliseconds())),
+	)
+
+	summary := fmt.Sprintf("Generated %d %s caption(s) from %d recognized word(s) (%s) in %v.", len(cues), strings.ToUpper(format), len(words), languageCode, duration)
+	if outputLocalDir != "" && finalLocalPath != "" {
+		summary += fmt.Sprintf(" Captions saved locally to: %s.", finalLocalPath)
+	}
+	if finalGCSPath != "" {
+		summary += fmt.Sprintf(" Captions uploaded to GCS: %s.", finalGCSPath)
+	}
+
+	transcript := make([]string, len(cues))
+	for i, cue := range cues {
+		transcript[i] = cue.Text
+	}
+	details := captionDetails{
+		LanguageCode: languageCode,
+		Format:       format,
+		Cues:         len(cues),
+		Words:        len(words),
+		Transcript:   strings.Join(transcript, " "),
+	}
+	if outputLocalDir == "" && finalGCSPath == "" {
+		// The caption file is only kept in a temporary directory, so return its contents.
+		details.Captions = captions
+		summary += " The captions are included in the result."
+	}
+	messageParts = append([]string{summary}, messageParts...)
+	return newAvtoolResult(ctx, "srt_from_audio", strings.Join(messageParts, " "), duration, outputs, details), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	speech "google.golang.org/api/speech/v1"
+)
+
+func TestWordsFromSpeechResults(t *testing.T) {
+	results := []*speech.SpeechRecognitionResult{
+		{Alternatives: []*speech.SpeechRecognitionAlternative{{Words: []*speech.WordInfo{
+			{Word: "Hello", StartTime: "0s", EndTime: "0.400s"},
+			{Word: "world.", StartTime: "0.400s", EndTime: "1.100s"},
+		}}}},
+		{},
+		{Alternatives: []*speech.SpeechRecognitionAlternative{{Words: []*speech.WordInfo{
+			{Word: "Again", StartTime: "62.500s", EndTime: "63s"},
+		}}}},
+	}
+	want := []captionWord{{"Hello", 0, 0.4}, {"world.", 0.4, 1.1}, {"Again", 62.5, 63}}
+	if got := wordsFromSpeechResults(results); !slices.Equal(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestBuildCaptionCues(t *testing.T) {
+	tests := []struct {
+		name       string
+		words      []captionWord
+		maxChars   int
+		maxSeconds float64
+		want       []captionCue
+	}{
+		{
+			name:       "sentence end starts a new caption",
+			words:      []captionWord{{"Hello", 0, 0.4}, {"world.", 0.4, 1.1}, {"How", 1.3, 1.5}, {"are", 1.5, 1.7}, {"you?", 1.7, 2.0}},
+			maxChars:   42,
+			maxSeconds: 6,
+			want:       []captionCue{{0, 1.1, "Hello world."}, {1.3, 2.0, "How are you?"}},
+		},
+		{
+			name:       "character limit",
+			words:      []captionWord{{"one", 0, 0.5}, {"two", 0.5, 1}, {"three", 1, 1.5}},
+			maxChars:   10,
+			maxSeconds: 6,
+			want:       []captionCue{{0, 1, "one two"}, {1, 1.5, "three"}},
+		},
+		{
+			name:       "duration limit",
+			words:      []captionWord{{"slowly", 0, 2}, {"spoken", 2, 4}, {"words", 4, 6}},
+			maxChars:   42,
+			maxSeconds: 5,
+			want:       []captionCue{{0, 4, "slowly spoken"}, {4, 6, "words"}},
+		},
+		{
+			name:       "pause starts a new caption",
+			words:      []captionWord{{"before", 0, 0.5}, {"", 0.5, 0.6}, {"after", 3, 3.5}},
+			maxChars:   42,
+			maxSeconds: 6,
+			want:       []captionCue{{0, 0.5, "before"}, {3, 3.5, "after"}},
+		},
+		{name: "no words", maxChars: 42, maxSeconds: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildCaptionCues(tt.words, tt.maxChars, tt.maxSeconds); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatCaptions(t *testing.T) {
+	cues := []captionCue{{0, 1.1, "Hello world."}, {3725.25, 3727, "An hour later."}}
+
+	wantSRT := "1\n00:00:00,000 --> 00:00:01,100\nHello world.\n\n2\n01:02:05,250 --> 01:02:07,000\nAn hour later.\n\n"
+	if got := formatCaptions(cues, "srt"); got != wantSRT {
+		t.Errorf("expected SRT %q, but got %q", wantSRT, got)
+	}
+	wantVTT := "WEBVTT\n\n00:00:00.000 --> 00:00:01.100\nHello world.\n\n01:02:05.250 --> 01:02:07.000\nAn hour later.\n\n"
+	if got := formatCaptions(cues, "vtt"); got != wantVTT {
+		t.Errorf("expected VTT %q, but got %q", wantVTT, got)
+	}
+}
+
+func TestEscapeFilterOptionValue(t *testing.T) {
+	tests := map[string]string{
+		"/tmp/output_1/captions.srt": "/tmp/output_1/captions.srt",
+		"C:/subs/it's,here.srt":      `C\\:/subs/it\\\'s\,here.srt`,
+	}
+	for in, want := range tests {
+		if got := escapeFilterOptionValue(in); got != want {
+			t.Errorf("expected %s to be escaped as %s, but got %s", in, want, got)
+		}
+	}
+}
+
+func TestValidateCaptionLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxChars   int
+		maxSeconds float64
+		wantErr    bool
+	}{
+		{"defaults", defaultCaptionMaxChars, defaultCaptionMaxSeconds, false},
+		{"upper bounds", 120, 20, false},
+		{"too few characters", 9, 6, true},
+		{"too many characters", 121, 6, true},
+		{"too short", 42, 0.5, true},
+		{"too long", 42, 20.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCaptionLimits(tt.maxChars, tt.maxSeconds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,33 +3,59 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 )
 
+// faststartOutputs makes runFFmpegCommand write MP4-family outputs with '-movflags +faststart',
+// so that they start playing in a browser before they are fully downloaded. It is set from
+// MP4_FASTSTART at startup.
+var faststartOutputs = true
+
 // runFFmpegCommand executes an FFMpeg command with the given arguments.
-// It logs the command being executed and captures the combined stdout and stderr.
+// Unless faststartOutputs is false, MP4-family outputs are written with '-movflags +faststart'.
+// It logs the command being executed and captures the combined stdout and stderr, which are
+// also streamed to the job tracking the current tool call, if any, along with the command itself.
 // If the command fails, it logs the error and the output, then returns an error.
 // Otherwise, it logs the last few lines of the output for brevity and returns the full output.
 func runFFmpegCommand(ctx context.Context, args ...string) (string, error) {
+	if faststartOutputs {
+		args = common.WithFaststart(args)
+	}
 	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
 		cmd.Env = append(os.Environ(), "PATH="+customPath)
 	}
-	log.Printf("Running FFMpeg command: ffmpeg %s", strings.Join(args, " "))
+	common.LogInfof("Running FFMpeg command: ffmpeg %s", strings.Join(args, " "))
 
-	output, err := cmd.CombinedOutput()
+	// When running under a tracked job, stream the output to the job as well so it can be polled.
+	var buf bytes.Buffer
+	if job := jobFromContext(ctx); job != nil {
+		job.recordCommand(args)
+		cmd.Stdout = io.MultiWriter(&buf, job)
+	} else {
+		cmd.Stdout = &buf
+	}
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+	output := buf.Bytes()
 	if err != nil {
-		log.Printf("FFMpeg command failed. Error: %v\nFFMpeg Output:\n%s", err, string(output))
+		common.LogErrorf("FFMpeg command failed. Error: %v\nFFMpeg Output:\n%s", err, string(output))
 		return string(output), fmt.Errorf("ffmpeg command failed: %w. Output: %s", err, string(output))
 	}
-	log.Printf("FFMpeg command successful. Output (last few lines):\n%s", common.GetTail(string(output), 5)) // getTail from file_utils.go
+	common.LogDebugf("FFMpeg command successful. Output (last few lines):\n%s", common.GetTail(string(output), 5)) // getTail from file_utils.go
 	return string(output), nil
 }
 
@@ -40,3 +66,1037 @@ func runFFmpegCommand(ctx context.Context, args ...string) (string, error) {
 // func executeConvertAudioToMP3(ctx context.Context, localInputAudio, tempOutputFile string) (string, error) {
 // 	 return runFFmpegCommand(ctx, "-y", "-i", localInputAudio, "-acodec", "libmp3lame", tempOutputFile)
 // }
+
+// x264Presets lists the libx264 speed/quality presets, from fastest (largest file) to slowest (smallest file).
+var x264Presets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// resolutionHeights maps named target resolutions to their output height in pixels.
+var resolutionHeights = map[string]int{
+	"2160p": 2160,
+	"1440p": 1440,
+	"1080p": 1080,
+	"720p":  720,
+	"480p":  480,
+	"360p":  360,
+}
+
+const (
+	// maxScaleDimension is the largest output width or height accepted by the scale tool.
+	maxScaleDimension = 8192
+	// maxScaleFactor is the largest upscaling factor accepted by the scale tool.
+	maxScaleFactor = 4.0
+)
+
+// validateEvenDimension checks an output width or height for H.264, which needs even
+// dimensions between 2 and maxScaleDimension.
+func validateEvenDimension(name string, dim int) error {
+	if dim < 2 || dim > maxScaleDimension {
+		return fmt.Errorf("%s must be between 2 and %d, got %d", name, maxScaleDimension, dim)
+	}
+	if dim%2 != 0 {
+		return fmt.Errorf("%s must be an even number for H.264 output, got %d", name, dim)
+	}
+	return nil
+}
+
+// scaleOptions describes the target size for scaling a video. Exactly one of Resolution,
+// Width/Height, or ScaleFactor must be set.
+type scaleOptions struct {
+	Resolution          string
+	Width, Height       int
+	ScaleFactor         float64
+	PreserveAspectRatio bool
+}
+
+// buildScaleFilter validates the scale options and returns the corresponding FFMpeg scale filter.
+// Output dimensions are always even, as required by H.264 with yuv420p.
+func buildScaleFilter(opts scaleOptions) (string, error) {
+	modes := 0
+	if opts.Resolution != "" {
+		modes++
+	}
+	if opts.Width != 0 || opts.Height != 0 {
+		modes++
+	}
+	if opts.ScaleFactor != 0 {
+		modes++
+	}
+	if modes != 1 {
+		return "", fmt.Errorf("exactly one of 'resolution', 'width'/'height', or 'scale_factor' must be provided")
+	}
+
+	switch {
+	case opts.Resolution != "":
+		height, ok := resolutionHeights[strings.ToLower(opts.Resolution)]
+		if !ok {
+			return "", fmt.Errorf("unsupported resolution '%s'. Supported values are 2160p, 1440p, 1080p, 720p, 480p, 360p", opts.Resolution)
+		}
+		return fmt.Sprintf("scale=-2:%d", height), nil
+
+	case opts.ScaleFactor != 0:
+		if opts.ScaleFactor < 0 || opts.ScaleFactor > maxScaleFactor {
+			return "", fmt.Errorf("scale_factor must be greater than 0 and at most %g, got %g", maxScaleFactor, opts.ScaleFactor)
+		}
+		factor := strconv.FormatFloat(opts.ScaleFactor, 'f', -1, 64)
+		return fmt.Sprintf("scale=trunc(iw*%[1]s/2)*2:trunc(ih*%[1]s/2)*2", factor), nil
+	}
+
+	// 0 leaves a dimension to be derived from the aspect ratio.
+	if opts.Width != 0 {
+		if err := validateEvenDimension("width", opts.Width); err != nil {
+			return "", err
+		}
+	}
+	if opts.Height != 0 {
+		if err := validateEvenDimension("height", opts.Height); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case opts.Width == 0:
+		return fmt.Sprintf("scale=-2:%d", opts.Height), nil
+	case opts.Height == 0:
+		return fmt.Sprintf("scale=%d:-2", opts.Width), nil
+	case opts.PreserveAspectRatio:
+		// Fit within the box without distortion; the shorter side may be smaller than requested.
+		return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2", opts.Width, opts.Height), nil
+	default:
+		return fmt.Sprintf("scale=%d:%d", opts.Width, opts.Height), nil
+	}
+}
+
+// executeScaleVideo re-encodes a video with the given scale filter using libx264.
+// The preset trades encoding speed for file size, and crf controls the quality (lower is better).
+// Audio is copied without re-encoding.
+func executeScaleVideo(ctx context.Context, localInputVideo, tempOutputFile, scaleFilter, preset string, crf int) (string, error) {
+	if !slices.Contains(x264Presets, preset) {
+		return "", fmt.Errorf("unsupported preset '%s'. Supported presets are: %s", preset, strings.Join(x264Presets, ", "))
+	}
+	return runFFmpegCommand(ctx, "-y", "-i", localInputVideo,
+		"-vf", scaleFilter,
+		"-c:v", "libx264", "-preset", preset, "-crf", strconv.Itoa(crf), "-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-movflags", "+faststart",
+		tempOutputFile)
+}
+
+// gifDitherModes lists the paletteuse dithering modes accepted by the GIF tool. sierra2_4a is
+// FFMpeg's default; bayer gives a stable, ordered pattern that compresses well; none gives flat
+// color bands but the smallest files.
+var gifDitherModes = []string{"sierra2_4a", "sierra2", "floyd_steinberg", "heckbert", "bayer", "none"}
+
+// gifStatsModes lists the palettegen statistics modes accepted by the GIF tool. "full" builds
+// the palette from all pixels; "diff" favors what moves, which suits static backgrounds.
+var gifStatsModes = []string{"full", "diff"}
+
+// maxGifWidth is the largest output width accepted by the GIF tool.
+const maxGifWidth = 4096
+
+// gifOptions controls the two-pass GIF conversion.
+type gifOptions struct {
+	FPS         float64
+	ScaleFactor float64 // Used if Width is 0.
+	Width       int     // Output width in pixels; the height keeps the aspect ratio.
+	Dither      string
+	StatsMode   string
+}
+
+// buildGifFilters validates the options and returns the filter for the palette generation pass
+// and the filter graph for the pass that applies the palette (input 1) to the video (input 0).
+func buildGifFilters(opts gifOptions) (paletteFilter, gifFilter string, err error) {
+	if opts.FPS < 1 || opts.FPS > 50 {
+		return "", "", fmt.Errorf("fps must be between 1 and 50, got %g", opts.FPS)
+	}
+	if !slices.Contains(gifDitherModes, opts.Dither) {
+		return "", "", fmt.Errorf("unsupported dither '%s'. Supported values are: %s", opts.Dither, strings.Join(gifDitherModes, ", "))
+	}
+	if !slices.Contains(gifStatsModes, opts.StatsMode) {
+		return "", "", fmt.Errorf("unsupported stats_mode '%s'. Supported values are: %s", opts.StatsMode, strings.Join(gifStatsModes, ", "))
+	}
+
+	var scale string
+	switch {
+	case opts.Width < 0 || opts.Width > maxGifWidth:
+		return "", "", fmt.Errorf("width must be between 1 and %d, got %d", maxGifWidth, opts.Width)
+	case opts.Width > 0:
+		scale = fmt.Sprintf("scale=%d:-1", opts.Width)
+	case opts.ScaleFactor > 0:
+		scale = fmt.Sprintf("scale=iw*%.2f:-1", opts.ScaleFactor)
+	default:
+		return "", "", fmt.Errorf("scale_width_factor must be greater than 0, got %g", opts.ScaleFactor)
+	}
+	prefix := fmt.Sprintf("fps=%.2f,%s:flags=lanczos+accurate_rnd+full_chroma_inp", opts.FPS, scale)
+
+	paletteUse := "paletteuse=dither=" + opts.Dither
+	if opts.StatsMode == "diff" {
+		// Only the changed rectangle of each frame is re-dithered, matching the diff palette.
+		paletteUse += ":diff_mode=rectangle"
+	}
+	return prefix + ",palettegen=stats_mode=" + opts.StatsMode, prefix + " [x]; [x][1:v] " + paletteUse, nil
+}
+
+const (
+	// minMixGainDB and maxMixGainDB bound the per-track gain accepted by the mix tool.
+	minMixGainDB = -60.0
+	maxMixGainDB = 24.0
+	// mixLimiterLevel is the peak level, as a fraction of full scale, that the mix is limited to.
+	mixLimiterLevel = 0.95
+)
+
+// mixDurationModes lists the amix duration modes accepted by the mix tool.
+var mixDurationModes = []string{"longest", "shortest", "first"}
+
+// audioMixTrack describes one input of an audio mix: its gain in dB and when it starts in the mix.
+type audioMixTrack struct {
+	URI         string
+	GainDB      float64
+	StartOffset float64 // Seconds from the beginning of the mix.
+}
+
+// buildMixAudioFilter validates the tracks and returns an FFMpeg filter graph that applies each
+// track's gain and start offset and mixes them into a single stream labeled [mixed].
+// amix normally divides every input by the number of inputs; that is disabled so the
+// requested gains are honored, and a limiter is applied instead when preventClipping is set.
+func buildMixAudioFilter(tracks []audioMixTrack, durationMode string, preventClipping bool) (string, error) {
+	if len(tracks) == 0 {
+		return "", fmt.Errorf("at least one track is required")
+	}
+	if !slices.Contains(mixDurationModes, durationMode) {
+		return "", fmt.Errorf("unsupported duration '%s'. Supported values are: %s", durationMode, strings.Join(mixDurationModes, ", "))
+	}
+
+	var chains []string
+	var labels strings.Builder
+	for i, track := range tracks {
+		if track.GainDB < minMixGainDB || track.GainDB > maxMixGainDB {
+			return "", fmt.Errorf("track %d: gain_db must be between %g and %g, got %g", i, minMixGainDB, maxMixGainDB, track.GainDB)
+		}
+		if track.StartOffset < 0 {
+			return "", fmt.Errorf("track %d: start_offset_seconds must not be negative, got %g", i, track.StartOffset)
+		}
+		chain := fmt.Sprintf("[%d:a]volume=%sdB", i, strconv.FormatFloat(track.GainDB, 'f', -1, 64))
+		if delayMs := int(track.StartOffset * 1000); delayMs > 0 {
+			chain += fmt.Sprintf(",adelay=%d:all=1", delayMs)
+		}
+		label := fmt.Sprintf("[t%d]", i)
+		chains = append(chains, chain+label)
+		labels.WriteString(label)
+	}
+
+	mix := fmt.Sprintf("%samix=inputs=%d:duration=%s:dropout_transition=0:normalize=0", labels.String(), len(tracks), durationMode)
+	if preventClipping {
+		mix += fmt.Sprintf(",alimiter=limit=%g:level=disabled", mixLimiterLevel)
+	}
+	chains = append(chains, mix+"[mixed]")
+	return strings.Join(chains, ";"), nil
+}
+
+// executeMixAudio mixes the local input files with the given filter graph (see buildMixAudioFilter
+// and buildSidechainDuckFilter).
+// The output codec is chosen by FFMpeg from the output file extension.
+func executeMixAudio(ctx context.Context, localInputFiles []string, tempOutputFile, filterGraph string) (string, error) {
+	args := []string{"-y"}
+	for _, input := range localInputFiles {
+		args = append(args, "-i", input)
+	}
+	args = append(args, "-filter_complex", filterGraph, "-map", "[mixed]", tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+const (
+	// Defaults for the ducking tool: the music is compressed by 8:1 once the narration exceeds
+	// -30 dB, reacting within 20 ms and recovering over 250 ms.
+	defaultDuckThresholdDB = -30.0
+	defaultDuckRatio       = 8.0
+	defaultDuckAttackMs    = 20.0
+	defaultDuckReleaseMs   = 250.0
+)
+
+// sidechainDuckSettings controls how music is ducked under narration with sidechaincompress.
+type sidechainDuckSettings struct {
+	MusicGainDB float64 // Gain applied to the music before ducking.
+	ThresholdDB float64 // Narration level above which the music is ducked.
+	Ratio       float64
+	AttackMs    float64
+	ReleaseMs   float64
+}
+
+// buildSidechainDuckFilter validates the settings and returns an FFMpeg filter graph that ducks
+// the music (input 1) under the narration (input 0) and mixes the two into a stream labeled
+// [mixed]. The narration copy used as the sidechain key is padded with silence so that music
+// longer than the narration continues, at full volume, after the narration ends.
+func buildSidechainDuckFilter(settings sidechainDuckSettings, durationMode string, preventClipping bool) (string, error) {
+	if !slices.Contains(mixDurationModes, durationMode) {
+		return "", fmt.Errorf("unsupported duration '%s'. Supported values are: %s", durationMode, strings.Join(mixDurationModes, ", "))
+	}
+	if settings.MusicGainDB < minMixGainDB || settings.MusicGainDB > maxMixGainDB {
+		return "", fmt.Errorf("music_gain_db must be between %g and %g, got %g", minMixGainDB, maxMixGainDB, settings.MusicGainDB)
+	}
+	// The ranges below are those accepted by sidechaincompress.
+	if settings.ThresholdDB < -60 || settings.ThresholdDB > 0 {
+		return "", fmt.Errorf("threshold_db must be between -60 and 0, got %g", settings.ThresholdDB)
+	}
+	if settings.Ratio < 1 || settings.Ratio > 20 {
+		return "", fmt.Errorf("ratio must be between 1 and 20, got %g", settings.Ratio)
+	}
+	if settings.AttackMs < 0.01 || settings.AttackMs > 2000 {
+		return "", fmt.Errorf("attack_ms must be between 0.01 and 2000, got %g", settings.AttackMs)
+	}
+	if settings.ReleaseMs < 0.01 || settings.ReleaseMs > 9000 {
+		return "", fmt.Errorf("release_ms must be between 0.01 and 9000, got %g", settings.ReleaseMs)
+	}
+
+	threshold := math.Pow(10, settings.ThresholdDB/20)
+	chains := []string{
+		"[0:a]asplit=2[voice][key]",
+		"[key]apad[sidechain]",
+		fmt.Sprintf("[1:a]volume=%sdB[music]", strconv.FormatFloat(settings.MusicGainDB, 'f', -1, 64)),
+		fmt.Sprintf("[music][sidechain]sidechaincompress=threshold=%s:ratio=%s:attack=%s:release=%s[ducked]",
+			strconv.FormatFloat(threshold, 'f', 6, 64),
+			strconv.FormatFloat(settings.Ratio, 'f', -1, 64),
+			strconv.FormatFloat(settings.AttackMs, 'f', -1, 64),
+			strconv.FormatFloat(settings.ReleaseMs, 'f', -1, 64)),
+	}
+	mix := fmt.Sprintf("[voice][ducked]amix=inputs=2:duration=%s:dropout_transition=0:normalize=0", durationMode)
+	if preventClipping {
+		mix += fmt.Sprintf(",alimiter=limit=%g:level=disabled", mixLimiterLevel)
+	}
+	chains = append(chains, mix+"[mixed]")
+	return strings.Join(chains, ";"), nil
+}
+
+const (
+	// Defaults for the chroma key tool: a pure green screen, keyed with a small tolerance and a
+	// slight soft edge.
+	defaultChromaKeyColor      = "0x00FF00"
+	defaultChromaKeySimilarity = 0.1
+	defaultChromaKeyBlend      = 0.05
+)
+
+// chromaKeyMethods lists the keying filters: chromakey compares chroma in YUV and tolerates
+// uneven lighting, colorkey compares RGB values and suits flat, computer-generated backgrounds.
+var chromaKeyMethods = []string{"chromakey", "colorkey"}
+
+// chromaKeyColorNames maps the key color names accepted by the chroma key tool to RGB values.
+var chromaKeyColorNames = map[string]string{
+	"green": "0x00FF00",
+	"blue":  "0x0000FF",
+}
+
+var hexColorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// alphaOutputFormat describes a container and codec that preserve transparency.
+type alphaOutputFormat struct {
+	PixelFormat string
+	CodecArgs   []string
+}
+
+// chromaKeyAlphaFormats lists the output formats for keyed video without a background.
+var chromaKeyAlphaFormats = map[string]alphaOutputFormat{
+	"webm": {PixelFormat: "yuva420p", CodecArgs: []string{"-c:v", "libvpx-vp9", "-auto-alt-ref", "0", "-c:a", "libopus"}},
+	"mov":  {PixelFormat: "yuva444p10le", CodecArgs: []string{"-c:v", "prores_ks", "-profile:v", "4444", "-c:a", "aac"}},
+}
+
+// chromaKeyOptions controls how the chroma key tool keys out the background.
+type chromaKeyOptions struct {
+	Method     string
+	KeyColor   string // A color name from chromaKeyColorNames or RRGGBB hex, optionally prefixed with # or 0x.
+	Similarity float64
+	Blend      float64
+	// Width and Height are the foreground size. If set, the background (input 1) is scaled and
+	// cropped to cover it, and the keyed foreground is composited over it.
+	Width, Height int
+	PixelFormat   string // Pixel format of the transparent output; unused with a background.
+}
+
+// parseKeyColor normalizes a key color to FFMpeg's 0xRRGGBB form.
+func parseKeyColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if rgb, ok := chromaKeyColorNames[color]; ok {
+		return rgb, nil
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(color, "#"), "0x")
+	if !hexColorPattern.MatchString(hex) {
+		return "", fmt.Errorf("unsupported key color '%s'. Use green, blue, or a hex color such as #00FF00", color)
+	}
+	return "0x" + strings.ToUpper(hex), nil
+}
+
+// buildChromaKeyFilter validates the options and returns an FFMpeg filter graph that keys the
+// foreground (input 0) and labels the result [out]. Without a background the output keeps an
+// alpha channel; with one, the background is scaled to cover the foreground frame and the keyed
+// foreground is overlaid on it until the foreground ends.
+func buildChromaKeyFilter(opts chromaKeyOptions) (string, error) {
+	if !slices.Contains(chromaKeyMethods, opts.Method) {
+		return "", fmt.Errorf("unsupported method '%s'. Supported methods are: %s", opts.Method, strings.Join(chromaKeyMethods, ", "))
+	}
+	color, err := parseKeyColor(opts.KeyColor)
+	if err != nil {
+		return "", err
+	}
+	// The ranges below are those accepted by chromakey and colorkey.
+	if opts.Similarity < 0.00001 || opts.Similarity > 1 {
+		return "", fmt.Errorf("similarity must be between 0.00001 and 1, got %g", opts.Similarity)
+	}
+	if opts.Blend < 0 || opts.Blend > 1 {
+		return "", fmt.Errorf("blend must be between 0 and 1, got %g", opts.Blend)
+	}
+	key := fmt.Sprintf("%s=%s:%s:%s", opts.Method, color,
+		strconv.FormatFloat(opts.Similarity, 'f', -1, 64),
+		strconv.FormatFloat(opts.Blend, 'f', -1, 64))
+
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return fmt.Sprintf("[0:v]%s,format=%s[out]", key, opts.PixelFormat), nil
+	}
+	return strings.Join([]string{
+		fmt.Sprintf("[1:v]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1[bg]", opts.Width, opts.Height, opts.Width, opts.Height),
+		fmt.Sprintf("[0:v]%s,format=yuva420p[fg]", key),
+		"[bg][fg]overlay=shortest=1,format=yuv420p[out]",
+	}, ";"), nil
+}
+
+// stillImageExtensions lists the file extensions treated as still images rather than video.
+var stillImageExtensions = []string{".png", ".jpg", ".jpeg", ".webp", ".bmp", ".tif", ".tiff"}
+
+// isStillImageFile reports whether path names a still image, based on its extension.
+func isStillImageFile(path string) bool {
+	return slices.Contains(stillImageExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// executeChromaKey runs a chroma key filter graph from buildChromaKeyFilter. If localBackground is
+// set it is looped, as a still image or a video, for the length of the foreground. The
+// foreground's audio, if any, is kept.
+func executeChromaKey(ctx context.Context, localForeground, localBackground string, backgroundIsImage bool, filterGraph string, codecArgs []string, tempOutputFile string) (string, error) {
+	args := []string{"-y", "-i", localForeground}
+	if localBackground != "" {
+		if backgroundIsImage {
+			args = append(args, "-loop", "1", "-i", localBackground)
+		} else {
+			args = append(args, "-stream_loop", "-1", "-i", localBackground)
+		}
+	}
+	args = append(args, "-filter_complex", filterGraph, "-map", "[out]", "-map", "0:a?")
+	args = append(args, codecArgs...)
+	args = append(args, tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+const (
+	// defaultInterpolationFPS is the frame rate the interpolation tool raises video to by default.
+	defaultInterpolationFPS = 60
+	// maxInterpolationFPS caps the target frame rate of the interpolation tool.
+	maxInterpolationFPS = 240
+)
+
+// interpolationModes maps the modes accepted by the interpolation tool to minterpolate's mi_mode:
+// "blend" averages neighboring frames, which is fast but ghosts on motion; "motion" estimates
+// motion and synthesizes in-between frames, which is smoother but far slower.
+var interpolationModes = map[string]string{
+	"blend":  "blend",
+	"motion": "mci",
+}
+
+// buildInterpolateFilter returns a minterpolate filter that raises the frame rate to targetFPS
+// with the given mode from interpolationModes.
+func buildInterpolateFilter(targetFPS float64, mode string) (string, error) {
+	if targetFPS <= 0 || targetFPS > maxInterpolationFPS {
+		return "", fmt.Errorf("target_fps must be greater than 0 and at most %d, got %g", maxInterpolationFPS, targetFPS)
+	}
+	miMode, ok := interpolationModes[mode]
+	if !ok {
+		return "", fmt.Errorf("unsupported mode '%s'. Supported modes are: blend, motion", mode)
+	}
+	filter := fmt.Sprintf("minterpolate=fps=%s:mi_mode=%s", strconv.FormatFloat(targetFPS, 'f', -1, 64), miMode)
+	if miMode == "mci" {
+		// Overlapped block motion compensation with variable-size blocks reduces blocking artifacts.
+		filter += ":mc_mode=aobmc:vsbmc=1"
+	}
+	return filter, nil
+}
+
+// executeInterpolateFPS re-encodes a video as H.264 with an interpolation filter from
+// buildInterpolateFilter. The audio is copied unchanged.
+func executeInterpolateFPS(ctx context.Context, localInputVideo, tempOutputFile, filter string) (string, error) {
+	return runFFmpegCommand(ctx, "-y", "-i", localInputVideo,
+		"-vf", filter,
+		"-c:v", "libx264", "-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-movflags", "+faststart",
+		tempOutputFile)
+}
+
+const (
+	// defaultSceneThreshold is the scene-change score above which a frame is treated as a cut.
+	defaultSceneThreshold = 0.4
+	// defaultMinSceneSeconds is the minimum spacing between reported cuts, which suppresses
+	// bursts of detections during fast motion or flashes.
+	defaultMinSceneSeconds = 0.5
+)
+
+// sceneCutPattern matches the presentation timestamp printed by the showinfo filter.
+var sceneCutPattern = regexp.MustCompile(`pts_time:\s*([0-9]+(?:\.[0-9]+)?)`)
+
+// buildSceneDetectFilter returns a video filter that selects the frames whose scene-change
+// score exceeds threshold and logs them with showinfo so their timestamps can be parsed.
+func buildSceneDetectFilter(threshold float64) (string, error) {
+	if threshold <= 0 || threshold >= 1 {
+		return "", fmt.Errorf("threshold must be greater than 0 and less than 1, got %g", threshold)
+	}
+	return fmt.Sprintf("select='gt(scene,%s)',showinfo", strconv.FormatFloat(threshold, 'f', -1, 64)), nil
+}
+
+// parseSceneCuts extracts the cut timestamps, in seconds, from the showinfo lines in the FFMpeg output.
+// Cuts closer than minGapSeconds to the previous cut (or to the start of the video) are dropped.
+func parseSceneCuts(output string, minGapSeconds float64) []float64 {
+	var cuts []float64
+	last := 0.0
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "showinfo") {
+			continue
+		}
+		match := sceneCutPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		ts, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || ts-last < minGapSeconds {
+			continue
+		}
+		cuts = append(cuts, ts)
+		last = ts
+	}
+	return cuts
+}
+
+// sceneDetectionDetails is the tool-specific part of the ffmpeg_trim_to_scene result.
+type sceneDetectionDetails struct {
+	Threshold   float64   `json:"threshold"`
+	CutsSeconds []float64 `json:"cuts_seconds"`
+}
+
+// sceneSegment is a span of a video between two scene cuts. An End of 0 means the end of the video.
+type sceneSegment struct {
+	Start float64
+	End   float64
+}
+
+// sceneSegments turns cut timestamps into the segments between them, starting at 0 and
+// running to the end of the video.
+func sceneSegments(cuts []float64) []sceneSegment {
+	segments := make([]sceneSegment, 0, len(cuts)+1)
+	start := 0.0
+	for _, cut := range cuts {
+		segments = append(segments, sceneSegment{Start: start, End: cut})
+		start = cut
+	}
+	return append(segments, sceneSegment{Start: start})
+}
+
+// executeDetectScenes runs scene detection on a video with the given filter (see buildSceneDetectFilter)
+// and returns the FFMpeg output to be parsed with parseSceneCuts. No output file is written.
+func executeDetectScenes(ctx context.Context, localInputVideo, sceneFilter string) (string, error) {
+	return runFFmpegCommand(ctx, "-hide_banner", "-i", localInputVideo, "-an", "-vf", sceneFilter, "-f", "null", "-")
+}
+
+// executeExtractSegment re-encodes one segment of a video with libx264 and AAC.
+// Re-encoding (rather than stream copy) keeps the cut frame-accurate instead of snapping to keyframes.
+func executeExtractSegment(ctx context.Context, localInputVideo, tempOutputFile string, segment sceneSegment) (string, error) {
+	args := []string{"-y", "-i", localInputVideo, "-ss", strconv.FormatFloat(segment.Start, 'f', 3, 64)}
+	if segment.End > 0 {
+		args = append(args, "-to", strconv.FormatFloat(segment.End, 'f', 3, 64))
+	}
+	args = append(args,
+		"-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-movflags", "+faststart",
+		tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+const (
+	// defaultAudioCrossfadeSeconds is the length of the audio crossfade at each clip boundary.
+	defaultAudioCrossfadeSeconds = 0.5
+	// maxAudioCrossfadeSeconds caps the 'audio_crossfade_duration' parameter.
+	maxAudioCrossfadeSeconds = 5
+)
+
+// crossfadeClip describes one standardized input of an audio-crossfaded concatenation.
+type crossfadeClip struct {
+	DurationSeconds float64
+	HasVideo        bool
+	HasAudio        bool
+}
+
+// buildAudioCrossfadeConcatFilter returns a filter graph that joins the clips with a hard video
+// cut but crossfades their audio with acrossfade, so background music carries across the
+// boundaries. Each crossfade overlaps the end of one clip with the start of the next and
+// shortens the audio by crossfadeSeconds, so the video of every clip but the last is trimmed
+// by the same amount to keep picture and sound in sync. The outputs are labeled [v] (only if
+// the clips have video) and [a].
+func buildAudioCrossfadeConcatFilter(clips []crossfadeClip, crossfadeSeconds float64) (string, error) {
+	if len(clips) < 2 {
+		return "", fmt.Errorf("an audio crossfade needs at least 2 input files, got %d", len(clips))
+	}
+	if crossfadeSeconds <= 0 || crossfadeSeconds > maxAudioCrossfadeSeconds {
+		return "", fmt.Errorf("audio_crossfade_duration must be greater than 0 and at most %d seconds, got %g", maxAudioCrossfadeSeconds, crossfadeSeconds)
+	}
+	hasVideo := clips[0].HasVideo
+	for i, clip := range clips {
+		if !clip.HasAudio {
+			return "", fmt.Errorf("input %d has no audio stream to crossfade", i+1)
+		}
+		if clip.HasVideo != hasVideo {
+			return "", fmt.Errorf("an audio crossfade cannot mix audio-only and video inputs (input %d differs from input 1)", i+1)
+		}
+		if clip.DurationSeconds <= crossfadeSeconds {
+			return "", fmt.Errorf("input %d (%gs) is not longer than the %gs audio crossfade", i+1, clip.DurationSeconds, crossfadeSeconds)
+		}
+	}
+
+	d := strconv.FormatFloat(crossfadeSeconds, 'f', -1, 64)
+	var parts []string
+	if hasVideo {
+		var labels strings.Builder
+		for i, clip := range clips {
+			if i < len(clips)-1 {
+				trimmed := strconv.FormatFloat(clip.DurationSeconds-crossfadeSeconds, 'f', 3, 64)
+				parts = append(parts, fmt.Sprintf("[%d:v]trim=duration=%s,setpts=PTS-STARTPTS[v%d]", i, trimmed, i))
+			} else {
+				parts = append(parts, fmt.Sprintf("[%d:v]setpts=PTS-STARTPTS[v%d]", i, i))
+			}
+			fmt.Fprintf(&labels, "[v%d]", i)
+		}
+		parts = append(parts, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", labels.String(), len(clips)))
+	}
+	previous := "[0:a]"
+	for i := 1; i < len(clips); i++ {
+		next := fmt.Sprintf("[a%d]", i)
+		if i == len(clips)-1 {
+			next = "[a]"
+		}
+		parts = append(parts, fmt.Sprintf("%s[%d:a]acrossfade=d=%s:c1=tri:c2=tri%s", previous, i, d, next))
+		previous = next
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// crossfadeAudioCodecArgs returns the audio encoder arguments of a crossfaded concatenation
+// for the output format, given by its extension. MP3 holds audio only, so it is rejected when the
+// inputs have video.
+func crossfadeAudioCodecArgs(format string, hasVideo bool) ([]string, error) {
+	switch format {
+	case "mp3":
+		if hasVideo {
+			return nil, fmt.Errorf("mp3 output cannot hold the video of the inputs; choose mp4, mov, or mkv")
+		}
+		return []string{"-c:a", "libmp3lame", "-b:a", "192k"}, nil
+	case "mp4", "m4a", "mov":
+		return []string{"-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart"}, nil
+	case "mkv", "aac":
+		return []string{"-c:a", "aac", "-b:a", "192k"}, nil
+	}
+	return nil, fmt.Errorf("concat_with_audio_crossfade supports the mp4, mov, mkv, m4a, aac, and mp3 output formats, got '%s'", format)
+}
+
+// executeAudioCrossfadeConcat joins the inputs with a filter graph from
+// buildAudioCrossfadeConcatFilter, encoding the video (if any) with libx264 and the audio with
+// the encoder arguments from crossfadeAudioCodecArgs.
+func executeAudioCrossfadeConcat(ctx context.Context, localInputFiles []string, filterGraph string, hasVideo bool, audioCodecArgs []string, tempOutputFile string) (string, error) {
+	args := []string{"-y"}
+	for _, f := range localInputFiles {
+		args = append(args, "-i", f)
+	}
+	args = append(args, "-filter_complex", filterGraph)
+	if hasVideo {
+		args = append(args, "-map", "[v]", "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p")
+	}
+	args = append(args, "-map", "[a]")
+	args = append(args, audioCodecArgs...)
+	args = append(args, tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+// channelLayouts maps the layouts accepted by the channel remix tool to their channel counts.
+var channelLayouts = map[string]int{
+	"mono":   1,
+	"stereo": 2,
+}
+
+// monoSources are the ways the channel remix tool can build a mono track: "mix" downmixes all
+// input channels, "left" and "right" keep only that channel of a stereo input.
+var monoSources = []string{"mix", "left", "right"}
+
+// buildRemixChannelsArgs returns the FFMpeg arguments that remix the audio to the given channel
+// layout. Downmixing with "mix" and upmixing to stereo use -ac, which lets FFMpeg apply its
+// standard downmix coefficients for any input layout and copies a mono channel to both sides;
+// picking a single channel uses the pan filter.
+func buildRemixChannelsArgs(layout, monoSource string) ([]string, error) {
+	channels, ok := channelLayouts[layout]
+	if !ok {
+		return nil, fmt.Errorf("unsupported channel_layout '%s'. Supported layouts are: mono, stereo", layout)
+	}
+	if layout != "mono" {
+		return []string{"-ac", strconv.Itoa(channels)}, nil
+	}
+	switch monoSource {
+	case "", "mix":
+		return []string{"-ac", "1"}, nil
+	case "left":
+		return []string{"-af", "pan=mono|c0=c0"}, nil
+	case "right":
+		return []string{"-af", "pan=mono|c0=c1"}, nil
+	}
+	return nil, fmt.Errorf("unsupported mono_source '%s'. Supported sources are: %s", monoSource, strings.Join(monoSources, ", "))
+}
+
+// executeRemixChannels remixes the audio of a media file with arguments from
+// buildRemixChannelsArgs. Any video stream is copied unchanged.
+func executeRemixChannels(ctx context.Context, localInputMedia, tempOutputFile string, remixArgs []string) (string, error) {
+	args := append([]string{"-y", "-i", localInputMedia}, remixArgs...)
+	args = append(args, "-c:v", "copy", tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+const (
+	// maxSlideshowImages caps the number of images in a slideshow.
+	maxSlideshowImages = 100
+	// defaultSlideshowSeconds is how long each image is shown by default.
+	defaultSlideshowSeconds = 3.0
+	// maxSlideshowSeconds caps how long each image is shown.
+	maxSlideshowSeconds = 60.0
+	// defaultSlideshowTransitionSeconds is the default length of a transition between images.
+	defaultSlideshowTransitionSeconds = 0.5
+	// slideshowFPS is the frame rate of slideshow videos.
+	slideshowFPS = 30
+)
+
+// slideshowTransitions are the transitions accepted by the slideshow tool: "none" cuts between
+// images, the others are xfade transitions.
+var slideshowTransitions = []string{"none", "fade", "fadeblack", "dissolve", "wipeleft", "slideleft", "circleopen"}
+
+// slideshowOptions describes a slideshow of ImageCount images, each shown for SecondsPerImage,
+// scaled and padded to Width x Height.
+type slideshowOptions struct {
+	ImageCount        int
+	SecondsPerImage   float64
+	Transition        string
+	TransitionSeconds float64
+	Width, Height     int
+	HasAudio          bool // If true, the audio input follows the images and is labeled [a].
+}
+
+// validate checks the slideshow options.
+func (opts slideshowOptions) validate() error {
+	if opts.ImageCount < 1 || opts.ImageCount > maxSlideshowImages {
+		return fmt.Errorf("between 1 and %d images are required, got %d", maxSlideshowImages, opts.ImageCount)
+	}
+	if opts.SecondsPerImage <= 0 || opts.SecondsPerImage > maxSlideshowSeconds {
+		return fmt.Errorf("seconds_per_image must be greater than 0 and at most %g, got %g", maxSlideshowSeconds, opts.SecondsPerImage)
+	}
+	if !slices.Contains(slideshowTransitions, opts.Transition) {
+		return fmt.Errorf("unsupported transition '%s'. Supported transitions are: %s", opts.Transition, strings.Join(slideshowTransitions, ", "))
+	}
+	if opts.Transition != "none" && (opts.TransitionSeconds <= 0 || opts.TransitionSeconds >= opts.SecondsPerImage) {
+		return fmt.Errorf("transition_duration must be greater than 0 and less than seconds_per_image (%g), got %g", opts.SecondsPerImage, opts.TransitionSeconds)
+	}
+	if err := validateEvenDimension("width", opts.Width); err != nil {
+		return err
+	}
+	return validateEvenDimension("height", opts.Height)
+}
+
+// totalSeconds returns the length of the slideshow: each image is on screen for SecondsPerImage,
+// whether or not there are transitions.
+func (opts slideshowOptions) totalSeconds() float64 {
+	return float64(opts.ImageCount) * opts.SecondsPerImage
+}
+
+// inputSeconds returns how long each looped image input must last. A transition overlaps the
+// end of one image with the start of the next, so every image but the last is extended by the
+// transition to keep the slideshow at totalSeconds.
+func (opts slideshowOptions) inputSeconds() []float64 {
+	durations := make([]float64, opts.ImageCount)
+	for i := range durations {
+		durations[i] = opts.SecondsPerImage
+		if opts.Transition != "none" && i < opts.ImageCount-1 {
+			durations[i] += opts.TransitionSeconds
+		}
+	}
+	return durations
+}
+
+// buildSlideshowFilter returns the filter graph that scales and pads each image to the output
+// size and joins them, with a cut (concat) or with xfade transitions, into [v]. With an audio
+// track, the audio is padded with silence into [a] so that it can be cut to the slideshow length.
+func buildSlideshowFilter(opts slideshowOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	var parts []string
+	for i := 0; i < opts.ImageCount; i++ {
+		parts = append(parts, fmt.Sprintf("[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d,format=yuv420p[v%d]",
+			i, opts.Width, opts.Height, opts.Width, opts.Height, slideshowFPS, i))
+	}
+
+	if opts.Transition == "none" || opts.ImageCount == 1 {
+		var labels strings.Builder
+		for i := 0; i < opts.ImageCount; i++ {
+			fmt.Fprintf(&labels, "[v%d]", i)
+		}
+		parts = append(parts, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", labels.String(), opts.ImageCount))
+	} else {
+		d := strconv.FormatFloat(opts.TransitionSeconds, 'f', -1, 64)
+		previous := "[v0]"
+		for i := 1; i < opts.ImageCount; i++ {
+			next := fmt.Sprintf("[x%d]", i)
+			if i == opts.ImageCount-1 {
+				next = "[v]"
+			}
+			// With extended inputs (see inputSeconds), transition i starts when image i is due.
+			offset := strconv.FormatFloat(float64(i)*opts.SecondsPerImage, 'f', -1, 64)
+			parts = append(parts, fmt.Sprintf("%s[v%d]xfade=transition=%s:duration=%s:offset=%s%s", previous, i, opts.Transition, d, offset, next))
+			previous = next
+		}
+	}
+
+	if opts.HasAudio {
+		parts = append(parts, fmt.Sprintf("[%d:a]apad[a]", opts.ImageCount))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// executeSlideshow renders the images (and optional audio track) into an H.264/AAC MP4 with a
+// filter graph from buildSlideshowFilter. Each image is looped for its input duration, and the
+// output is cut to the slideshow length, so narration longer than the slideshow is truncated.
+func executeSlideshow(ctx context.Context, localImages []string, localAudio string, opts slideshowOptions, filterGraph, tempOutputFile string) (string, error) {
+	args := []string{"-y"}
+	for i, seconds := range opts.inputSeconds() {
+		args = append(args, "-loop", "1", "-framerate", strconv.Itoa(slideshowFPS), "-t", strconv.FormatFloat(seconds, 'f', 3, 64), "-i", localImages[i])
+	}
+	if localAudio != "" {
+		args = append(args, "-i", localAudio)
+	}
+	args = append(args, "-filter_complex", filterGraph,
+		"-map", "[v]", "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p")
+	if localAudio != "" {
+		args = append(args, "-map", "[a]", "-c:a", "aac", "-b:a", "192k")
+	}
+	args = append(args,
+		"-t", strconv.FormatFloat(opts.totalSeconds(), 'f', 3, 64),
+		"-movflags", "+faststart",
+		tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+const (
+	// minRampSpeed and maxRampSpeed bound the playback speed of a speed ramp keyframe.
+	minRampSpeed = 0.25
+	maxRampSpeed = 4.0
+	// maxRampKeyframes limits the size of the generated setpts expression.
+	maxRampKeyframes = 20
+	// rampAudioChunkSeconds is the longest audio chunk played at a single tempo while the speed
+	// changes. Shorter chunks follow the ramp more closely but add more filters to the graph.
+	rampAudioChunkSeconds = 0.5
+)
+
+// speedKeyframe sets the playback speed at a point of the input, e.g. 0.5 for half speed.
+type speedKeyframe struct {
+	TimeSeconds float64 // Input time.
+	Speed       float64
+}
+
+// validateSpeedKeyframes checks that the keyframe times are strictly increasing and lie within
+// the input, and that the speeds are within range.
+func validateSpeedKeyframes(keyframes []speedKeyframe, durationSeconds float64) error {
+	if len(keyframes) == 0 || len(keyframes) > maxRampKeyframes {
+		return fmt.Errorf("between 1 and %d keyframes are required, got %d", maxRampKeyframes, len(keyframes))
+	}
+	for i, k := range keyframes {
+		if k.TimeSeconds < 0 {
+			return fmt.Errorf("keyframe %d: time must not be negative, got %g", i, k.TimeSeconds)
+		}
+		if durationSeconds > 0 && k.TimeSeconds > durationSeconds {
+			return fmt.Errorf("keyframe %d: time %gs is past the end of the %gs input", i, k.TimeSeconds, durationSeconds)
+		}
+		if i > 0 && k.TimeSeconds <= keyframes[i-1].TimeSeconds {
+			return fmt.Errorf("keyframe times must be strictly increasing, but keyframe %d (%gs) does not come after keyframe %d (%gs)", i, k.TimeSeconds, i-1, keyframes[i-1].TimeSeconds)
+		}
+		if k.Speed < minRampSpeed || k.Speed > maxRampSpeed {
+			return fmt.Errorf("keyframe %d: speed must be between %g and %g, got %g", i, minRampSpeed, maxRampSpeed, k.Speed)
+		}
+	}
+	return nil
+}
+
+// rampOutputTime returns the output time at which input time t plays. The speed is constant
+// before the first and after the last keyframe and changes linearly between keyframes, so
+// within a segment the output time is the integral of 1/speed, which is logarithmic.
+func rampOutputTime(keyframes []speedKeyframe, t float64) float64 {
+	first := keyframes[0]
+	if t <= first.TimeSeconds {
+		return t / first.Speed
+	}
+	out := first.TimeSeconds / first.Speed
+	for i := 0; i < len(keyframes)-1; i++ {
+		a, b := keyframes[i], keyframes[i+1]
+		end := math.Min(t, b.TimeSeconds)
+		if a.Speed == b.Speed {
+			out += (end - a.TimeSeconds) / a.Speed
+		} else {
+			slope := (b.Speed - a.Speed) / (b.TimeSeconds - a.TimeSeconds)
+			out += math.Log((a.Speed+slope*(end-a.TimeSeconds))/a.Speed) / slope
+		}
+		if t <= b.TimeSeconds {
+			return out
+		}
+	}
+	last := keyframes[len(keyframes)-1]
+	return out + (t-last.TimeSeconds)/last.Speed
+}
+
+// formatRampNumber formats a number for an FFMpeg expression or filter option.
+func formatRampNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// buildSpeedRampPTSExpr returns the setpts expression, in seconds of output time, that maps the
+// input time T as rampOutputTime does. Segments are selected with nested if(lt(T,...)) calls.
+func buildSpeedRampPTSExpr(keyframes []speedKeyframe) string {
+	last := keyframes[len(keyframes)-1]
+	expr := fmt.Sprintf("%s+(T-%s)/%s", formatRampNumber(rampOutputTime(keyframes, last.TimeSeconds)), formatRampNumber(last.TimeSeconds), formatRampNumber(last.Speed))
+	for i := len(keyframes) - 2; i >= 0; i-- {
+		a, b := keyframes[i], keyframes[i+1]
+		offset := formatRampNumber(rampOutputTime(keyframes, a.TimeSeconds))
+		var segment string
+		if a.Speed == b.Speed {
+			segment = fmt.Sprintf("%s+(T-%s)/%s", offset, formatRampNumber(a.TimeSeconds), formatRampNumber(a.Speed))
+		} else {
+			slope := formatRampNumber((b.Speed - a.Speed) / (b.TimeSeconds - a.TimeSeconds))
+			segment = fmt.Sprintf("%s+log((%s+%s*(T-%s))/%s)/%s", offset, formatRampNumber(a.Speed), slope, formatRampNumber(a.TimeSeconds), formatRampNumber(a.Speed), slope)
+		}
+		expr = fmt.Sprintf("if(lt(T,%s),%s,%s)", formatRampNumber(b.TimeSeconds), segment, expr)
+	}
+	first := keyframes[0]
+	if first.TimeSeconds > 0 {
+		expr = fmt.Sprintf("if(lt(T,%s),T/%s,%s)", formatRampNumber(first.TimeSeconds), formatRampNumber(first.Speed), expr)
+	}
+	return expr
+}
+
+// rampAudioChunks splits the input into the spans that are each played at one tempo: the
+// constant-speed spans before the first and after the last keyframe, and pieces of at most
+// rampAudioChunkSeconds between keyframes.
+func rampAudioChunks(keyframes []speedKeyframe, durationSeconds float64) [][2]float64 {
+	var bounds []float64
+	if keyframes[0].TimeSeconds > 0 {
+		bounds = append(bounds, 0)
+	}
+	for i, k := range keyframes {
+		bounds = append(bounds, k.TimeSeconds)
+		if i == len(keyframes)-1 {
+			break
+		}
+		next := keyframes[i+1]
+		if k.Speed == next.Speed {
+			continue
+		}
+		pieces := int(math.Ceil((next.TimeSeconds - k.TimeSeconds) / rampAudioChunkSeconds))
+		for p := 1; p < pieces; p++ {
+			bounds = append(bounds, k.TimeSeconds+float64(p)*(next.TimeSeconds-k.TimeSeconds)/float64(pieces))
+		}
+	}
+	if durationSeconds > bounds[len(bounds)-1] {
+		bounds = append(bounds, durationSeconds)
+	}
+	var chunks [][2]float64
+	for i := 0; i < len(bounds)-1; i++ {
+		chunks = append(chunks, [2]float64{bounds[i], bounds[i+1]})
+	}
+	return chunks
+}
+
+// atempoChain returns atempo filters that change the tempo by factor. Each atempo is kept
+// within 0.5 to 2, the range supported by all FFMpeg versions.
+func atempoChain(factor float64) string {
+	factor = math.Round(factor*1e6) / 1e6 // Avoid splitting off a factor of 1 because of rounding errors.
+	var filters []string
+	for factor < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		factor /= 0.5
+	}
+	for factor > 2 {
+		filters = append(filters, "atempo=2")
+		factor /= 2
+	}
+	return strings.Join(append(filters, "atempo="+strconv.FormatFloat(factor, 'f', 6, 64)), ",")
+}
+
+// buildSpeedRampFilter returns the filter graph that retimes the video of input 0 with setpts
+// into [v] and, if hasAudio, its audio into [a]. The audio is cut into the chunks of
+// rampAudioChunks, and each chunk is played at the tempo that makes it last exactly as long as
+// the matching video, so picture and sound stay in sync while the pitch is preserved.
+func buildSpeedRampFilter(keyframes []speedKeyframe, durationSeconds float64, hasAudio bool) (string, error) {
+	if err := validateSpeedKeyframes(keyframes, durationSeconds); err != nil {
+		return "", err
+	}
+	parts := []string{fmt.Sprintf("[0:v]setpts='(%s)/TB'[v]", buildSpeedRampPTSExpr(keyframes))}
+	if !hasAudio {
+		return parts[0], nil
+	}
+	if durationSeconds <= 0 {
+		return "", fmt.Errorf("the input duration is needed to retime its audio")
+	}
+
+	chunks := rampAudioChunks(keyframes, durationSeconds)
+	var splitLabels, concatLabels strings.Builder
+	for i := range chunks {
+		fmt.Fprintf(&splitLabels, "[as%d]", i)
+		fmt.Fprintf(&concatLabels, "[ar%d]", i)
+	}
+	parts = append(parts, fmt.Sprintf("[0:a]asplit=%d%s", len(chunks), splitLabels.String()))
+	for i, chunk := range chunks {
+		tempo := (chunk[1] - chunk[0]) / (rampOutputTime(keyframes, chunk[1]) - rampOutputTime(keyframes, chunk[0]))
+		parts = append(parts, fmt.Sprintf("[as%d]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS,%s[ar%d]",
+			i, strconv.FormatFloat(chunk[0], 'f', 3, 64), strconv.FormatFloat(chunk[1], 'f', 3, 64), atempoChain(tempo), i))
+	}
+	parts = append(parts, fmt.Sprintf("%sconcat=n=%d:v=0:a=1[a]", concatLabels.String(), len(chunks)))
+	return strings.Join(parts, ";"), nil
+}
+
+// executeSpeedRamp retimes a video with a filter graph from buildSpeedRampFilter, encoding it as
+// H.264 and, if hasAudio, AAC.
+func executeSpeedRamp(ctx context.Context, localInputVideo, filterGraph string, hasAudio bool, tempOutputFile string) (string, error) {
+	args := []string{"-y", "-i", localInputVideo, "-filter_complex", filterGraph,
+		"-map", "[v]", "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p"}
+	if hasAudio {
+		args = append(args, "-map", "[a]", "-c:a", "aac", "-b:a", "192k")
+	}
+	args = append(args, "-movflags", "+faststart", tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+// speechAudioSampleRate is the sample rate of the audio extracted for speech recognition.
+const speechAudioSampleRate = 16000
+
+// executeExtractSpeechAudio extracts the audio of a media file as 16 kHz mono FLAC, the format
+// recommended for the Speech-to-Text API.
+func executeExtractSpeechAudio(ctx context.Context, localInputMedia, tempOutputFile string) (string, error) {
+	return runFFmpegCommand(ctx, "-y", "-i", localInputMedia, "-vn", "-ac", "1", "-ar", strconv.Itoa(speechAudioSampleRate), "-c:a", "flac", tempOutputFile)
+}
+
+// escapeFilterOptionValue escapes a value, such as a file path, for use as a filter option in
+// an FFMpeg filtergraph. The value is escaped once for the option parser (':' separates options)
+// and once more for the filtergraph parser.
+func escapeFilterOptionValue(value string) string {
+	optionEscaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(optionEscaped)
+}
+
+// executeBurnSubtitles renders a subtitle file (SRT or WebVTT) onto a video with the libass-based
+// subtitles filter, re-encoding the video as H.264 and the audio as AAC.
+func executeBurnSubtitles(ctx context.Context, localInputVideo, localSubtitles, tempOutputFile string) (string, error) {
+	return runFFmpegCommand(ctx, "-y", "-i", localInputVideo,
+		"-vf", "subtitles=filename="+escapeFilterOptionValue(localSubtitles),
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", "-c:a", "aac", "-b:a", "192k",
+		"-movflags", "+faststart", tempOutputFile)
+}
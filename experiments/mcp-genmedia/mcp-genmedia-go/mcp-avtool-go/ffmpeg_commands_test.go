@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -13,3 +17,570 @@ func TestRunFFmpegCommand(t *testing.T) {
 		t.Errorf("expected no error, but got: %v", err)
 	}
 }
+
+func TestBuildScaleFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        scaleOptions
+		want        string
+		errContains string
+	}{
+		{"named resolution", scaleOptions{Resolution: "720p"}, "scale=-2:720", ""},
+		{"width only", scaleOptions{Width: 1280}, "scale=1280:-2", ""},
+		{"height only", scaleOptions{Height: 720}, "scale=-2:720", ""},
+		{"box preserving aspect ratio", scaleOptions{Width: 1280, Height: 720, PreserveAspectRatio: true}, "scale=1280:720:force_original_aspect_ratio=decrease:force_divisible_by=2", ""},
+		{"exact size", scaleOptions{Width: 1280, Height: 720}, "scale=1280:720", ""},
+		{"scale factor", scaleOptions{ScaleFactor: 0.5}, "scale=trunc(iw*0.5/2)*2:trunc(ih*0.5/2)*2", ""},
+		{"no target", scaleOptions{}, "", "exactly one of"},
+		{"multiple targets", scaleOptions{Resolution: "720p", ScaleFactor: 0.5}, "", "exactly one of"},
+		{"unknown resolution", scaleOptions{Resolution: "999p"}, "", "unsupported resolution"},
+		{"odd width", scaleOptions{Width: 1279}, "", "must be an even number"},
+		{"width too large", scaleOptions{Width: 10000}, "", "must be between"},
+		{"negative height", scaleOptions{Height: -720}, "", "must be between"},
+		{"width checked before height", scaleOptions{Width: 1279, Height: 10001}, "", "width must be an even number"},
+		{"scale factor too large", scaleOptions{ScaleFactor: 5}, "", "scale_factor must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildScaleFilter(tt.opts)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected filter %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildGifFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        gifOptions
+		wantPalette string
+		wantGif     string
+		errContains string
+	}{
+		{
+			name:        "defaults",
+			opts:        gifOptions{FPS: 15, ScaleFactor: 0.33, Dither: "sierra2_4a", StatsMode: "full"},
+			wantPalette: "fps=15.00,scale=iw*0.33:-1:flags=lanczos+accurate_rnd+full_chroma_inp,palettegen=stats_mode=full",
+			wantGif:     "fps=15.00,scale=iw*0.33:-1:flags=lanczos+accurate_rnd+full_chroma_inp [x]; [x][1:v] paletteuse=dither=sierra2_4a",
+		},
+		{
+			name:        "width overrides scale factor with diff palette",
+			opts:        gifOptions{FPS: 10, ScaleFactor: 0.33, Width: 480, Dither: "bayer", StatsMode: "diff"},
+			wantPalette: "fps=10.00,scale=480:-1:flags=lanczos+accurate_rnd+full_chroma_inp,palettegen=stats_mode=diff",
+			wantGif:     "fps=10.00,scale=480:-1:flags=lanczos+accurate_rnd+full_chroma_inp [x]; [x][1:v] paletteuse=dither=bayer:diff_mode=rectangle",
+		},
+		{name: "bad dither", opts: gifOptions{FPS: 15, ScaleFactor: 1, Dither: "random", StatsMode: "full"}, errContains: "unsupported dither"},
+		{name: "bad stats mode", opts: gifOptions{FPS: 15, ScaleFactor: 1, Dither: "none", StatsMode: "single"}, errContains: "unsupported stats_mode"},
+		{name: "width too large", opts: gifOptions{FPS: 15, Width: 10000, Dither: "none", StatsMode: "full"}, errContains: "width must be between"},
+		{name: "fps out of range", opts: gifOptions{FPS: 60, ScaleFactor: 1, Dither: "none", StatsMode: "full"}, errContains: "fps must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			palette, gif, err := buildGifFilters(tt.opts)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if palette != tt.wantPalette {
+				t.Errorf("expected palette filter %q, but got %q", tt.wantPalette, palette)
+			}
+			if gif != tt.wantGif {
+				t.Errorf("expected GIF filter %q, but got %q", tt.wantGif, gif)
+			}
+		})
+	}
+}
+
+func TestBuildMixAudioFilter(t *testing.T) {
+	tests := []struct {
+		name            string
+		tracks          []audioMixTrack
+		duration        string
+		preventClipping bool
+		want            string
+		errContains     string
+	}{
+		{
+			name:            "narration over music",
+			tracks:          []audioMixTrack{{GainDB: 0}, {GainDB: -12}},
+			duration:        "longest",
+			preventClipping: true,
+			want:            "[0:a]volume=0dB[t0];[1:a]volume=-12dB[t1];[t0][t1]amix=inputs=2:duration=longest:dropout_transition=0:normalize=0,alimiter=limit=0.95:level=disabled[mixed]",
+		},
+		{
+			name:     "start offset without limiter",
+			tracks:   []audioMixTrack{{GainDB: -3.5}, {GainDB: 2, StartOffset: 1.25}},
+			duration: "first",
+			want:     "[0:a]volume=-3.5dB[t0];[1:a]volume=2dB,adelay=1250:all=1[t1];[t0][t1]amix=inputs=2:duration=first:dropout_transition=0:normalize=0[mixed]",
+		},
+		{name: "no tracks", duration: "longest", errContains: "at least one track"},
+		{name: "bad duration", tracks: []audioMixTrack{{}}, duration: "forever", errContains: "unsupported duration"},
+		{name: "gain too high", tracks: []audioMixTrack{{GainDB: 30}}, duration: "longest", errContains: "gain_db must be between"},
+		{name: "negative offset", tracks: []audioMixTrack{{StartOffset: -1}}, duration: "longest", errContains: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildMixAudioFilter(tt.tracks, tt.duration, tt.preventClipping)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected filter %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildSidechainDuckFilter(t *testing.T) {
+	defaults := sidechainDuckSettings{ThresholdDB: defaultDuckThresholdDB, Ratio: defaultDuckRatio, AttackMs: defaultDuckAttackMs, ReleaseMs: defaultDuckReleaseMs}
+	tests := []struct {
+		name            string
+		settings        sidechainDuckSettings
+		duration        string
+		preventClipping bool
+		want            string
+		errContains     string
+	}{
+		{
+			name:            "defaults",
+			settings:        defaults,
+			duration:        "longest",
+			preventClipping: true,
+			want:            "[0:a]asplit=2[voice][key];[key]apad[sidechain];[1:a]volume=0dB[music];[music][sidechain]sidechaincompress=threshold=0.031623:ratio=8:attack=20:release=250[ducked];[voice][ducked]amix=inputs=2:duration=longest:dropout_transition=0:normalize=0,alimiter=limit=0.95:level=disabled[mixed]",
+		},
+		{
+			name:     "quieter music without limiter",
+			settings: sidechainDuckSettings{MusicGainDB: -6, ThresholdDB: -20, Ratio: 4, AttackMs: 5.5, ReleaseMs: 1000},
+			duration: "first",
+			want:     "[0:a]asplit=2[voice][key];[key]apad[sidechain];[1:a]volume=-6dB[music];[music][sidechain]sidechaincompress=threshold=0.100000:ratio=4:attack=5.5:release=1000[ducked];[voice][ducked]amix=inputs=2:duration=first:dropout_transition=0:normalize=0[mixed]",
+		},
+		{name: "bad duration", settings: defaults, duration: "forever", errContains: "unsupported duration"},
+		{name: "threshold too high", settings: sidechainDuckSettings{ThresholdDB: 3, Ratio: 8, AttackMs: 20, ReleaseMs: 250}, duration: "longest", errContains: "threshold_db must be between"},
+		{name: "ratio too low", settings: sidechainDuckSettings{ThresholdDB: -30, Ratio: 0.5, AttackMs: 20, ReleaseMs: 250}, duration: "longest", errContains: "ratio must be between"},
+		{name: "release too long", settings: sidechainDuckSettings{ThresholdDB: -30, Ratio: 8, AttackMs: 20, ReleaseMs: 10000}, duration: "longest", errContains: "release_ms must be between"},
+		{name: "music gain too high", settings: sidechainDuckSettings{MusicGainDB: 30, ThresholdDB: -30, Ratio: 8, AttackMs: 20, ReleaseMs: 250}, duration: "longest", errContains: "music_gain_db must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildSidechainDuckFilter(tt.settings, tt.duration, tt.preventClipping)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected filter %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildSceneDetectFilter(t *testing.T) {
+	got, err := buildSceneDetectFilter(0.4)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if want := "select='gt(scene,0.4)',showinfo"; got != want {
+		t.Errorf("expected filter %q, but got %q", want, got)
+	}
+	for _, threshold := range []float64{0, 1, -0.2, 1.5} {
+		if _, err := buildSceneDetectFilter(threshold); err == nil {
+			t.Errorf("expected an error for threshold %g, but got none", threshold)
+		}
+	}
+}
+
+func TestParseSceneCuts(t *testing.T) {
+	output := strings.Join([]string{
+		"Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':",
+		"[Parsed_showinfo_1 @ 0x600] n:   0 pts:  51200 pts_time:4       duration:    512 duration_time:0.04    fmt:yuv420p",
+		"[Parsed_showinfo_1 @ 0x600] n:   1 pts:  56320 pts_time:4.4     duration:    512 duration_time:0.04    fmt:yuv420p",
+		"[Parsed_showinfo_1 @ 0x600] n:   2 pts: 134400 pts_time:10.5    duration:    512 duration_time:0.04    fmt:yuv420p",
+		"[Parsed_showinfo_1 @ 0x600] n:   3 pts: 172800 pts_time:13.52   duration:    512 duration_time:0.04    fmt:yuv420p",
+		"frame=    4 fps=0.0 q=-0.0 Lsize=N/A time=00:00:13.52",
+	}, "\n")
+
+	tests := []struct {
+		name   string
+		minGap float64
+		want   []float64
+	}{
+		{"all cuts", 0, []float64{4, 4.4, 10.5, 13.52}},
+		{"close cuts dropped", 1, []float64{4, 10.5, 13.52}},
+		{"long minimum scene", 5, []float64{10.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSceneCuts(output, tt.minGap)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected cuts %v, but got %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := parseSceneCuts("frame=  100 fps=0.0", 0); len(got) != 0 {
+		t.Errorf("expected no cuts, but got %v", got)
+	}
+}
+
+func TestSceneSegments(t *testing.T) {
+	got := sceneSegments([]float64{4, 10.5})
+	want := []sceneSegment{{0, 4}, {4, 10.5}, {10.5, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected segments %v, but got %v", want, got)
+	}
+	if got := sceneSegments(nil); !slices.Equal(got, []sceneSegment{{0, 0}}) {
+		t.Errorf("expected a single segment for no cuts, but got %v", got)
+	}
+}
+
+func TestBuildChromaKeyFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    chromaKeyOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "transparent webm",
+			opts: chromaKeyOptions{Method: "chromakey", KeyColor: "green", Similarity: 0.1, Blend: 0.05, PixelFormat: "yuva420p"},
+			want: "[0:v]chromakey=0x00FF00:0.1:0.05,format=yuva420p[out]",
+		},
+		{
+			name: "colorkey with hex color",
+			opts: chromaKeyOptions{Method: "colorkey", KeyColor: "#1a2b3c", Similarity: 0.3, Blend: 0, PixelFormat: "yuva444p10le"},
+			want: "[0:v]colorkey=0x1A2B3C:0.3:0,format=yuva444p10le[out]",
+		},
+		{
+			name: "composited over background",
+			opts: chromaKeyOptions{Method: "chromakey", KeyColor: "0x0000ff", Similarity: 0.2, Blend: 0.1, Width: 1280, Height: 720},
+			want: "[1:v]scale=1280:720:force_original_aspect_ratio=increase,crop=1280:720,setsar=1[bg];[0:v]chromakey=0x0000FF:0.2:0.1,format=yuva420p[fg];[bg][fg]overlay=shortest=1,format=yuv420p[out]",
+		},
+		{name: "unknown method", opts: chromaKeyOptions{Method: "lumakey", KeyColor: "green", Similarity: 0.1}, wantErr: true},
+		{name: "invalid color", opts: chromaKeyOptions{Method: "chromakey", KeyColor: "purple", Similarity: 0.1}, wantErr: true},
+		{name: "similarity out of range", opts: chromaKeyOptions{Method: "chromakey", KeyColor: "green", Similarity: 0}, wantErr: true},
+		{name: "blend out of range", opts: chromaKeyOptions{Method: "chromakey", KeyColor: "green", Similarity: 0.1, Blend: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildChromaKeyFilter(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildInterpolateFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		targetFPS float64
+		mode      string
+		want      string
+		wantErr   bool
+	}{
+		{name: "motion compensated", targetFPS: 60, mode: "motion", want: "minterpolate=fps=60:mi_mode=mci:mc_mode=aobmc:vsbmc=1"},
+		{name: "blend", targetFPS: 48, mode: "blend", want: "minterpolate=fps=48:mi_mode=blend"},
+		{name: "fractional rate", targetFPS: 59.94, mode: "blend", want: "minterpolate=fps=59.94:mi_mode=blend"},
+		{name: "zero fps", targetFPS: 0, mode: "blend", wantErr: true},
+		{name: "fps too high", targetFPS: 500, mode: "motion", wantErr: true},
+		{name: "unknown mode", targetFPS: 60, mode: "dup", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildInterpolateFilter(tt.targetFPS, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildAudioCrossfadeConcatFilter(t *testing.T) {
+	video := func(d float64) crossfadeClip { return crossfadeClip{DurationSeconds: d, HasVideo: true, HasAudio: true} }
+	audio := func(d float64) crossfadeClip { return crossfadeClip{DurationSeconds: d, HasAudio: true} }
+	tests := []struct {
+		name      string
+		clips     []crossfadeClip
+		crossfade float64
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "two video clips",
+			clips:     []crossfadeClip{video(4), video(6)},
+			crossfade: 0.5,
+			want: "[0:v]trim=duration=3.500,setpts=PTS-STARTPTS[v0];[1:v]setpts=PTS-STARTPTS[v1];[v0][v1]concat=n=2:v=1:a=0[v];" +
+				"[0:a][1:a]acrossfade=d=0.5:c1=tri:c2=tri[a]",
+		},
+		{
+			name:      "three video clips",
+			clips:     []crossfadeClip{video(4), video(5), video(3)},
+			crossfade: 1,
+			want: "[0:v]trim=duration=3.000,setpts=PTS-STARTPTS[v0];[1:v]trim=duration=4.000,setpts=PTS-STARTPTS[v1];[2:v]setpts=PTS-STARTPTS[v2];" +
+				"[v0][v1][v2]concat=n=3:v=1:a=0[v];" +
+				"[0:a][1:a]acrossfade=d=1:c1=tri:c2=tri[a1];[a1][2:a]acrossfade=d=1:c1=tri:c2=tri[a]",
+		},
+		{
+			name:      "audio only",
+			clips:     []crossfadeClip{audio(10), audio(10)},
+			crossfade: 2,
+			want:      "[0:a][1:a]acrossfade=d=2:c1=tri:c2=tri[a]",
+		},
+		{name: "single clip", clips: []crossfadeClip{video(4)}, crossfade: 0.5, wantErr: true},
+		{name: "zero crossfade", clips: []crossfadeClip{video(4), video(4)}, crossfade: 0, wantErr: true},
+		{name: "crossfade too long", clips: []crossfadeClip{video(20), video(20)}, crossfade: 10, wantErr: true},
+		{name: "clip shorter than crossfade", clips: []crossfadeClip{video(4), video(0.4)}, crossfade: 0.5, wantErr: true},
+		{name: "missing audio", clips: []crossfadeClip{video(4), {DurationSeconds: 4, HasVideo: true}}, crossfade: 0.5, wantErr: true},
+		{name: "mixed audio and video", clips: []crossfadeClip{video(4), audio(4)}, crossfade: 0.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildAudioCrossfadeConcatFilter(tt.clips, tt.crossfade)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCrossfadeAudioCodecArgs(t *testing.T) {
+	tests := []struct {
+		format    string
+		hasVideo  bool
+		wantCodec string
+		wantErr   bool
+	}{
+		{"mp4", true, "aac", false},
+		{"m4a", false, "aac", false},
+		{"mkv", true, "aac", false},
+		{"mp3", false, "libmp3lame", false},
+		{"mp3", true, "", true},
+		{"ogg", false, "", true},
+	}
+	for _, tt := range tests {
+		args, err := crossfadeAudioCodecArgs(tt.format, tt.hasVideo)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("crossfadeAudioCodecArgs(%q, %t): expected error: %v, but got %v", tt.format, tt.hasVideo, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && !slices.Contains(args, tt.wantCodec) {
+			t.Errorf("crossfadeAudioCodecArgs(%q, %t): expected the %s encoder, but got %v", tt.format, tt.hasVideo, tt.wantCodec, args)
+		}
+	}
+}
+
+func TestBuildRemixChannelsArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		layout     string
+		monoSource string
+		want       []string
+		wantErr    bool
+	}{
+		{name: "downmix to mono", layout: "mono", want: []string{"-ac", "1"}},
+		{name: "explicit mix", layout: "mono", monoSource: "mix", want: []string{"-ac", "1"}},
+		{name: "left channel", layout: "mono", monoSource: "left", want: []string{"-af", "pan=mono|c0=c0"}},
+		{name: "right channel", layout: "mono", monoSource: "right", want: []string{"-af", "pan=mono|c0=c1"}},
+		{name: "upmix to stereo", layout: "stereo", want: []string{"-ac", "2"}},
+		{name: "unknown layout", layout: "5.1", wantErr: true},
+		{name: "unknown mono source", layout: "mono", monoSource: "center", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildRemixChannelsArgs(tt.layout, tt.monoSource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildSlideshowFilter(t *testing.T) {
+	scaled := func(i int) string {
+		return fmt.Sprintf("[%d:v]scale=1280:720:force_original_aspect_ratio=decrease,pad=1280:720:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p[v%d]", i, i)
+	}
+	tests := []struct {
+		name    string
+		opts    slideshowOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "cuts with narration",
+			opts: slideshowOptions{ImageCount: 2, SecondsPerImage: 3, Transition: "none", Width: 1280, Height: 720, HasAudio: true},
+			want: scaled(0) + ";" + scaled(1) + ";[v0][v1]concat=n=2:v=1:a=0[v];[2:a]apad[a]",
+		},
+		{
+			name: "fades",
+			opts: slideshowOptions{ImageCount: 3, SecondsPerImage: 3, Transition: "fade", TransitionSeconds: 0.5, Width: 1280, Height: 720},
+			want: scaled(0) + ";" + scaled(1) + ";" + scaled(2) + ";" +
+				"[v0][v1]xfade=transition=fade:duration=0.5:offset=3[x1];[x1][v2]xfade=transition=fade:duration=0.5:offset=6[v]",
+		},
+		{
+			name: "single image with transition",
+			opts: slideshowOptions{ImageCount: 1, SecondsPerImage: 5, Transition: "fade", TransitionSeconds: 1, Width: 1280, Height: 720},
+			want: scaled(0) + ";[v0]concat=n=1:v=1:a=0[v]",
+		},
+		{name: "no images", opts: slideshowOptions{SecondsPerImage: 3, Transition: "none", Width: 1280, Height: 720}, wantErr: true},
+		{name: "zero duration", opts: slideshowOptions{ImageCount: 2, Transition: "none", Width: 1280, Height: 720}, wantErr: true},
+		{name: "unknown transition", opts: slideshowOptions{ImageCount: 2, SecondsPerImage: 3, Transition: "spin", Width: 1280, Height: 720}, wantErr: true},
+		{name: "transition too long", opts: slideshowOptions{ImageCount: 2, SecondsPerImage: 3, Transition: "fade", TransitionSeconds: 3, Width: 1280, Height: 720}, wantErr: true},
+		{name: "odd width", opts: slideshowOptions{ImageCount: 2, SecondsPerImage: 3, Transition: "none", Width: 1279, Height: 720}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildSlideshowFilter(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+
+	opts := slideshowOptions{ImageCount: 5, SecondsPerImage: 3, Transition: "fade", TransitionSeconds: 0.5}
+	if got, want := opts.inputSeconds(), []float64{3.5, 3.5, 3.5, 3.5, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected input durations %v, but got %v", want, got)
+	}
+	if got := opts.totalSeconds(); got != 15 {
+		t.Errorf("expected a 15s slideshow, but got %gs", got)
+	}
+}
+
+func TestRampOutputTime(t *testing.T) {
+	ramp := []speedKeyframe{{0, 0.5}, {8, 2}}
+	tests := []struct {
+		name      string
+		keyframes []speedKeyframe
+		t         float64
+		want      float64
+	}{
+		{name: "constant speed", keyframes: []speedKeyframe{{0, 2}}, t: 10, want: 5},
+		{name: "before the first keyframe", keyframes: []speedKeyframe{{4, 0.5}, {8, 1}}, t: 2, want: 4},
+		{name: "ramp from 0.5x to 2x", keyframes: ramp, t: 8, want: math.Log(4) / 0.1875},
+		{name: "after the last keyframe", keyframes: ramp, t: 10, want: math.Log(4)/0.1875 + 1},
+		{name: "hold between equal keyframes", keyframes: []speedKeyframe{{0, 1}, {2, 0.5}, {4, 0.5}}, t: 4, want: math.Log(0.5)/-0.25 + 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rampOutputTime(tt.keyframes, tt.t); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected %g, but got %g", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildSpeedRampFilter(t *testing.T) {
+	got, err := buildSpeedRampFilter([]speedKeyframe{{1, 0.5}, {3, 0.5}}, 0, false)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	want := "[0:v]setpts='(if(lt(T,1),T/0.5,if(lt(T,3),2+(T-1)/0.5,6+(T-3)/0.5)))/TB'[v]"
+	if got != want {
+		t.Errorf("expected %q, but got %q", want, got)
+	}
+
+	ramp := []speedKeyframe{{0, 0.5}, {2, 2}}
+	got, err = buildSpeedRampFilter(ramp, 3, true)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if !strings.Contains(got, "log((0.5+0.75*(T-0))/0.5)/0.75") {
+		t.Errorf("expected a logarithmic ramp segment, but got %q", got)
+	}
+	// Four 0.5s chunks over the ramp, then one chunk at 2x to the end.
+	if !strings.Contains(got, "[0:a]asplit=5[as0][as1][as2][as3][as4];") || !strings.HasSuffix(got, "[ar0][ar1][ar2][ar3][ar4]concat=n=5:v=0:a=1[a]") {
+		t.Errorf("expected the audio to be split into 5 chunks, but got %q", got)
+	}
+	if !strings.Contains(got, "[as4]atrim=start=2.000:end=3.000,asetpts=PTS-STARTPTS,atempo=2.000000[ar4]") {
+		t.Errorf("expected the last chunk to play at 2x, but got %q", got)
+	}
+
+	chunks := rampAudioChunks(ramp, 3)
+	var total float64
+	for _, c := range chunks {
+		total += rampOutputTime(ramp, c[1]) - rampOutputTime(ramp, c[0])
+	}
+	if want := rampOutputTime(ramp, 3); math.Abs(total-want) > 1e-9 {
+		t.Errorf("expected the audio chunks to last %gs like the video, but got %gs", want, total)
+	}
+
+	for name, tt := range map[string]struct {
+		keyframes []speedKeyframe
+		duration  float64
+		hasAudio  bool
+	}{
+		"no keyframes":         {duration: 5},
+		"times not increasing": {keyframes: []speedKeyframe{{2, 1}, {2, 2}}, duration: 5},
+		"time past the end":    {keyframes: []speedKeyframe{{0, 1}, {6, 2}}, duration: 5},
+		"negative time":        {keyframes: []speedKeyframe{{-1, 1}}, duration: 5},
+		"speed too low":        {keyframes: []speedKeyframe{{0, 0.1}}, duration: 5},
+		"speed too high":       {keyframes: []speedKeyframe{{0, 8}}, duration: 5},
+		"unknown duration":     {keyframes: []speedKeyframe{{0, 1}}, hasAudio: true},
+	} {
+		if _, err := buildSpeedRampFilter(tt.keyframes, tt.duration, tt.hasAudio); err == nil {
+			t.Errorf("%s: expected an error, but got nil", name)
+		}
+	}
+}
+
+func TestAtempoChain(t *testing.T) {
+	tests := map[float64]string{
+		1.5:  "atempo=1.500000",
+		0.25: "atempo=0.5,atempo=0.500000",
+		4:    "atempo=2,atempo=2.000000",
+	}
+	for factor, want := range tests {
+		if got := atempoChain(factor); got != want {
+			t.Errorf("expected %g to be %s, but got %s", factor, want, got)
+		}
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 )
 
 // runFFprobeCommand executes an FFprobe command and returns its combined output.
@@ -18,19 +19,19 @@ func runFFprobeCommand(ctx context.Context, args ...string) (string, error) {
 	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
 		cmd.Env = append(os.Environ(), "PATH="+customPath)
 	}
-	log.Printf("Running FFprobe command: ffprobe %s", strings.Join(args, " "))
+	common.LogInfof("Running FFprobe command: ffprobe %s", strings.Join(args, " "))
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("FFprobe command execution failed. Error: %v\nFFprobe Output:\n%s", err, string(output))
+		common.LogErrorf("FFprobe command execution failed. Error: %v\nFFprobe Output:\n%s", err, string(output))
 		return string(output), fmt.Errorf("ffprobe command execution failed: %w. Output: %s", err, string(output))
 	}
 	var js json.RawMessage
 	if json.Unmarshal(output, &js) != nil && strings.TrimSpace(string(output)) != "" {
-		log.Printf("FFprobe output was not valid JSON, though command execution reported no error. Output:\n%s", string(output))
+		common.LogErrorf("FFprobe output was not valid JSON, though command execution reported no error. Output:\n%s", string(output))
 	}
 
-	log.Printf("FFprobe command successful.")
+	common.LogDebugf("FFprobe command successful.")
 	return string(output), nil
 }
 
@@ -0,0 +1,63 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// addValidateGCSAccessTool defines and registers the 'validate_gcs_access' tool.
+func addValidateGCSAccessTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("validate_gcs_access",
+		mcp.WithDescription("Diagnoses GCS permissions before a generation fails midway. Probes read (list and get objects), write (create and delete a small probe object), and signed-URL access to a bucket, and reports the identity (service account email) of the server's credentials."),
+		mcp.WithString("bucket", mcp.Description("Optional. Bucket to check, with or without the gs:// prefix. Defaults to GENMEDIA_BUCKET.")),
+	)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return validateGCSAccessHandler(ctx, request, cfg)
+	})
+}
+
+// validateGCSAccessHandler is the handler for the 'validate_gcs_access' tool.
+func validateGCSAccessHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "validate_gcs_access")
+	defer span.End()
+
+	bucket, _ := request.GetArguments()["bucket"].(string)
+	bucket = strings.TrimSpace(bucket)
+	if bucket == "" {
+		bucket = cfg.GenmediaBucket
+	}
+	bucket, _, _ = strings.Cut(strings.TrimPrefix(bucket, "gs://"), "/")
+	if bucket == "" {
+		return mcp.NewToolResultError("No bucket to check: set the 'bucket' parameter or GENMEDIA_BUCKET."), nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	report := common.CheckGCSAccess(checkCtx, bucket)
+	span.SetAttributes(
+		attribute.String("bucket", bucket),
+		attribute.String("identity", report.Identity),
+		attribute.Bool("read", report.Read),
+		attribute.Bool("write", report.Write),
+		attribute.Bool("sign", report.Sign),
+	)
+	common.LogInfof("validate_gcs_access: bucket %s, identity %q: read=%t write=%t sign=%t", bucket, report.Identity, report.Read, report.Write, report.Sign)
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode access report: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
@@ -0,0 +1,168 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ssimWindowSize is the edge length of the square window used for the SSIM calculation.
+	ssimWindowSize = 8
+	// ssimWindowStride is the step between successive SSIM windows.
+	ssimWindowStride = 4
+)
+
+// imageComparison holds the result of comparing two images.
+type imageComparison struct {
+	SSIM              float64
+	MeanPixelDiff     float64
+	Width, Height     int
+	ResizedForCompare bool
+	DiffVisualization *image.RGBA
+}
+
+// imageComparisonDetails is the tool-specific part of the compare_images result.
+type imageComparisonDetails struct {
+	SSIM              float64  `json:"ssim"`
+	MeanPixelDiff     float64  `json:"mean_pixel_diff"`
+	ResizedForCompare bool     `json:"resized_for_compare"`
+	Threshold         *float64 `json:"threshold,omitempty"`
+	Passed            *bool    `json:"passed,omitempty"` // Only set if a threshold was given.
+}
+
+// decodeImageFile reads and decodes an image file in any of the registered formats. Images
+// larger than maxInputPixels are rejected without being decoded.
+func decodeImageFile(path string) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, format, err := decodeImageBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	common.LogInfof("Decoded %s image %s (%dx%d)", format, path, img.Bounds().Dx(), img.Bounds().Dy())
+	return img, nil
+}
+
+// writePNG encodes an image as PNG to the given path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// compareImages computes the SSIM and mean pixel difference of two images.
+// If the dimensions differ, b is scaled to the size of a before comparing.
+// When withDiff is true, a visualization of the absolute per-pixel difference is included.
+func compareImages(a, b image.Image, withDiff bool) imageComparison {
+	bounds := a.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	rgbaA := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgbaA, rgbaA.Bounds(), a, bounds.Min, draw.Src)
+
+	rgbaB := image.NewRGBA(image.Rect(0, 0, width, height))
+	resized := b.Bounds().Dx() != width || b.Bounds().Dy() != height
+	if resized {
+		draw.CatmullRom.Scale(rgbaB, rgbaB.Bounds(), b, b.Bounds(), draw.Src, nil)
+	} else {
+		draw.Draw(rgbaB, rgbaB.Bounds(), b, b.Bounds().Min, draw.Src)
+	}
+
+	result := imageComparison{Width: width, Height: height, ResizedForCompare: resized}
+	if withDiff {
+		result.DiffVisualization = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	lumaA := make([]float64, width*height)
+	lumaB := make([]float64, width*height)
+	var totalDiff float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := rgbaA.PixOffset(x, y)
+			pa := rgbaA.Pix[offset : offset+3]
+			pb := rgbaB.Pix[offset : offset+3]
+
+			dr := math.Abs(float64(pa[0]) - float64(pb[0]))
+			dg := math.Abs(float64(pa[1]) - float64(pb[1]))
+			db := math.Abs(float64(pa[2]) - float64(pb[2]))
+			totalDiff += (dr + dg + db) / (3 * 255)
+
+			lumaA[y*width+x] = 0.299*float64(pa[0]) + 0.587*float64(pa[1]) + 0.114*float64(pa[2])
+			lumaB[y*width+x] = 0.299*float64(pb[0]) + 0.587*float64(pb[1]) + 0.114*float64(pb[2])
+
+			if withDiff {
+				// Render differences in red over a faded grayscale copy of image A.
+				mag := (dr + dg + db) / 3
+				base := uint8(lumaA[y*width+x] / 3)
+				red := math.Min(255, float64(base)+mag*4)
+				result.DiffVisualization.SetRGBA(x, y, color.RGBA{R: uint8(red), G: base, B: base, A: 255})
+			}
+		}
+	}
+	if width*height > 0 {
+		result.MeanPixelDiff = totalDiff / float64(width*height)
+	}
+	result.SSIM = computeSSIM(lumaA, lumaB, width, height)
+	return result
+}
+
+// computeSSIM returns the mean structural similarity index of two luminance planes,
+// computed over sliding windows. Images smaller than a window are compared as a single window.
+func computeSSIM(a, b []float64, width, height int) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	windowW, windowH := min(ssimWindowSize, width), min(ssimWindowSize, height)
+	if windowW == 0 || windowH == 0 {
+		return 1.0
+	}
+
+	var total float64
+	var windows int
+	for y := 0; y+windowH <= height; y += ssimWindowStride {
+		for x := 0; x+windowW <= width; x += ssimWindowStride {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for wy := y; wy < y+windowH; wy++ {
+				for wx := x; wx < x+windowW; wx++ {
+					va, vb := a[wy*width+wx], b[wy*width+wx]
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+			n := float64(windowW * windowH)
+			meanA, meanB := sumA/n, sumB/n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			covAB := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + c1) * (2*covAB + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+	return total / float64(windows)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestImage(w, h int, fill func(x, y int) color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, fill(x, y))
+		}
+	}
+	return img
+}
+
+func TestCompareImages(t *testing.T) {
+	gradient := newTestImage(64, 64, func(x, y int) color.RGBA {
+		return color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 128, A: 255}
+	})
+	checker := newTestImage(64, 64, func(x, y int) color.RGBA {
+		if (x/8+y/8)%2 == 0 {
+			return color.RGBA{A: 255}
+		}
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	})
+
+	identical := compareImages(gradient, gradient, true)
+	if identical.SSIM < 0.999 {
+		t.Errorf("expected SSIM ~1.0 for identical images, but got %f", identical.SSIM)
+	}
+	if identical.MeanPixelDiff != 0 {
+		t.Errorf("expected mean pixel difference 0 for identical images, but got %f", identical.MeanPixelDiff)
+	}
+	if identical.DiffVisualization == nil {
+		t.Errorf("expected a diff visualization when requested")
+	}
+
+	different := compareImages(gradient, checker, false)
+	if different.SSIM > 0.5 {
+		t.Errorf("expected a low SSIM for different images, but got %f", different.SSIM)
+	}
+	if different.DiffVisualization != nil {
+		t.Errorf("expected no diff visualization when not requested")
+	}
+
+	small := newTestImage(32, 32, func(x, y int) color.RGBA {
+		return color.RGBA{R: uint8(x * 8), G: uint8(y * 8), B: 128, A: 255}
+	})
+	resized := compareImages(gradient, small, false)
+	if !resized.ResizedForCompare {
+		t.Errorf("expected image B to be resized to match image A")
+	}
+	if resized.SSIM < 0.8 {
+		t.Errorf("expected a high SSIM for a scaled copy, but got %f", resized.SSIM)
+	}
+}
+
+func TestDecodeImageFileRejectsHugeImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.png")
+	if err := os.WriteFile(path, hugePNG(t), 0o644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	_, err := decodeImageFile(path)
+	if err == nil || !strings.Contains(err.Error(), "larger than the maximum") {
+		t.Errorf("expected the image to be rejected as too large, but got %v", err)
+	}
+}
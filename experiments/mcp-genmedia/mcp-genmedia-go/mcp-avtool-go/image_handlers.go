@@ -0,0 +1,278 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// imageTransform applies an image tool's operation to the decoded input image. It returns the
+// resulting image and a short description of what was done, for the result message.
+type imageTransform func(img image.Image, argsMap map[string]interface{}) (image.Image, string, error)
+
+// imageToolParams returns the input and output parameters shared by the image tools.
+func imageToolParams() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("input_image_uri", mcp.Description("URI of the input image (local path or gs://). PNG, JPEG, GIF, and WebP are supported. Provide this or 'input_image_base64'.")),
+		mcp.WithString("input_image_base64", mcp.Description("Base64-encoded input image data (optionally a data: URI). Provide this or 'input_image_uri'.")),
+		mcp.WithString("output_format", mcp.Enum(imageOutputFormats...), mcp.Description("Optional. Output image format. Defaults to the extension of 'output_file_name', or else the input format (WebP input is written as PNG).")),
+		mcp.WithNumber("jpeg_quality", mcp.DefaultNumber(defaultJPEGQuality), mcp.Min(1), mcp.Max(100), mcp.Description("Optional. JPEG quality (1-100) when the output format is JPEG.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output image file. If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output image to.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output image to (uses GENMEDIA_BUCKET if set and this is empty). If no output location is available, the image is returned inline.")),
+	}
+}
+
+// addResizeImageTool defines and registers the 'resize_image' tool.
+func addResizeImageTool(s *server.MCPServer, cfg *common.Config) {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Resizes an image. If only 'width' or 'height' is given, the other dimension follows the aspect ratio. With both, 'mode' selects how the image is fitted."),
+		mcp.WithNumber("width", mcp.Description(fmt.Sprintf("Optional. Target width in pixels (1-%d).", maxImageDimension))),
+		mcp.WithNumber("height", mcp.Description(fmt.Sprintf("Optional. Target height in pixels (1-%d).", maxImageDimension))),
+		mcp.WithString("mode", mcp.DefaultString("fit"), mcp.Enum(resizeModes...), mcp.Description("Optional. When both width and height are given: 'fit' scales to fit within them, 'fill' scales to cover them and crops the overflow from the center, 'stretch' ignores the aspect ratio.")),
+	}
+	tool := mcp.NewTool("resize_image", append(opts, imageToolParams()...)...)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imageToolHandler(ctx, request, cfg, "resize_image", func(img image.Image, argsMap map[string]interface{}) (image.Image, string, error) {
+			width, _ := argsMap["width"].(float64)
+			height, _ := argsMap["height"].(float64)
+			mode, _ := argsMap["mode"].(string)
+			if mode == "" {
+				mode = "fit"
+			}
+			out, err := resizeImage(img, int(width), int(height), mode)
+			if err != nil {
+				return nil, "", err
+			}
+			return out, fmt.Sprintf("Resized from %dx%d to %dx%d (mode %s).", img.Bounds().Dx(), img.Bounds().Dy(), out.Bounds().Dx(), out.Bounds().Dy(), mode), nil
+		})
+	})
+}
+
+// addCropImageTool defines and registers the 'crop_image' tool.
+func addCropImageTool(s *server.MCPServer, cfg *common.Config) {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Crops a rectangular region from an image. If 'x' and 'y' are omitted, the region is centered."),
+		mcp.WithNumber("width", mcp.Required(), mcp.Description("Width of the crop region in pixels.")),
+		mcp.WithNumber("height", mcp.Required(), mcp.Description("Height of the crop region in pixels.")),
+		mcp.WithNumber("x", mcp.Description("Optional. Left edge of the crop region, in pixels from the left of the image.")),
+		mcp.WithNumber("y", mcp.Description("Optional. Top edge of the crop region, in pixels from the top of the image.")),
+	}
+	tool := mcp.NewTool("crop_image", append(opts, imageToolParams()...)...)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imageToolHandler(ctx, request, cfg, "crop_image", func(img image.Image, argsMap map[string]interface{}) (image.Image, string, error) {
+			width, _ := argsMap["width"].(float64)
+			height, _ := argsMap["height"].(float64)
+			x := (img.Bounds().Dx() - int(width)) / 2
+			if xArg, ok := argsMap["x"].(float64); ok {
+				x = int(xArg)
+			}
+			y := (img.Bounds().Dy() - int(height)) / 2
+			if yArg, ok := argsMap["y"].(float64); ok {
+				y = int(yArg)
+			}
+			out, err := cropImage(img, x, y, int(width), int(height))
+			if err != nil {
+				return nil, "", err
+			}
+			return out, fmt.Sprintf("Cropped %dx%d at (%d,%d) from the %dx%d image.", int(width), int(height), x, y, img.Bounds().Dx(), img.Bounds().Dy()), nil
+		})
+	})
+}
+
+// addRotateImageTool defines and registers the 'rotate_image' tool.
+func addRotateImageTool(s *server.MCPServer, cfg *common.Config) {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Rotates an image clockwise by 90, 180, or 270 degrees."),
+		mcp.WithNumber("degrees", mcp.Required(), mcp.Description("Clockwise rotation in degrees: 90, 180, or 270.")),
+	}
+	tool := mcp.NewTool("rotate_image", append(opts, imageToolParams()...)...)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imageToolHandler(ctx, request, cfg, "rotate_image", func(img image.Image, argsMap map[string]interface{}) (image.Image, string, error) {
+			degrees, ok := argsMap["degrees"].(float64)
+			if !ok {
+				return nil, "", fmt.Errorf("parameter 'degrees' is required")
+			}
+			out, err := rotateImage(img, int(degrees))
+			if err != nil {
+				return nil, "", err
+			}
+			return out, fmt.Sprintf("Rotated %d degrees clockwise.", int(degrees)), nil
+		})
+	})
+}
+
+// addConvertImageTool defines and registers the 'convert_image' tool.
+func addConvertImageTool(s *server.MCPServer, cfg *common.Config) {
+	opts := []mcp.ToolOption{
+		mcp.WithDescription("Converts an image to another format (PNG, JPEG, or GIF) without changing its content. Set 'output_format' or an 'output_file_name' with the desired extension."),
+	}
+	tool := mcp.NewTool("convert_image", append(opts, imageToolParams()...)...)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imageToolHandler(ctx, request, cfg, "convert_image", func(img image.Image, _ map[string]interface{}) (image.Image, string, error) {
+			return img, "Converted.", nil
+		})
+	})
+}
+
+// loadImageInput reads the tool's input image from 'input_image_uri' or 'input_image_base64'.
+func loadImageInput(ctx context.Context, argsMap map[string]interface{}, toolName string, cfg *common.Config) ([]byte, error) {
+	inputURI, _ := argsMap["input_image_uri"].(string)
+	inputBase64, _ := argsMap["input_image_base64"].(string)
+	inputURI, inputBase64 = strings.TrimSpace(inputURI), strings.TrimSpace(inputBase64)
+
+	switch {
+	case inputURI != "" && inputBase64 != "":
+		return nil, fmt.Errorf("provide only one of 'input_image_uri' or 'input_image_base64'")
+	case inputBase64 != "":
+		if i := strings.Index(inputBase64, ";base64,"); strings.HasPrefix(inputBase64, "data:") && i >= 0 {
+			inputBase64 = inputBase64[i+len(";base64,"):]
+		}
+		data, err := base64.StdEncoding.DecodeString(inputBase64)
+		if err != nil {
+			return nil, fmt.Errorf("'input_image_base64' is not valid base64: %w", err)
+		}
+		return data, nil
+	case inputURI != "":
+		localPath, cleanup, err := common.PrepareInputFile(ctx, inputURI, toolName+"_input", cfg.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare input image: %w", err)
+		}
+		defer cleanup()
+		return os.ReadFile(localPath)
+	default:
+		return nil, fmt.Errorf("one of 'input_image_uri' or 'input_image_base64' is required")
+	}
+}
+
+// imageToolHandler implements the shared flow of the image tools: load and decode the input,
+// apply the transform, encode the result, and save it locally, to GCS, or return it inline.
+func imageToolHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config, toolName string, transform imageTransform) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, toolName)
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	inputURI, _ := argsMap["input_image_uri"].(string)
+	common.LogInfof("Handling %s request (input_image_uri: %q)", toolName, inputURI)
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler %s: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", toolName, outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+	jpegQuality := defaultJPEGQuality
+	if q, ok := argsMap["jpeg_quality"].(float64); ok {
+		if q < 1 || q > 100 {
+			return mcp.NewToolResultError(fmt.Sprintf("Parameter 'jpeg_quality' must be between 1 and 100, got %v.", q)), nil
+		}
+		jpegQuality = int(q)
+	}
+
+	data, err := loadImageInput(ctx, argsMap, toolName, cfg)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid input image: %v", err)), nil
+	}
+	img, inputFormat, err := decodeImageBytes(data)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	requestedFormat, _ := argsMap["output_format"].(string)
+	if requestedFormat == "" {
+		requestedFormat = filepath.Ext(outputFileName)
+	}
+	if requestedFormat == "" {
+		requestedFormat = inputFormat
+	}
+	outputFormat, err := normalizeImageFormat(requestedFormat)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	span.SetAttributes(
+		attribute.String("input_image_uri", inputURI),
+		attribute.String("input_format", inputFormat),
+		attribute.String("output_format", outputFormat),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	out, description, err := transform(img, argsMap)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid %s parameters: %v", toolName, err)), nil
+	}
+
+	var encoded bytes.Buffer
+	if err := encodeImage(&encoded, out, outputFormat, jpegQuality); err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode %s image: %v", outputFormat, err)), nil
+	}
+
+	messageParts := []string{fmt.Sprintf("%s %s image (%dx%d) completed in %v.", description, strings.ToUpper(outputFormat), out.Bounds().Dx(), out.Bounds().Dy(), time.Since(startTime))}
+	output := avtoolOutput{SizeBytes: int64(encoded.Len()), Format: outputFormat, Width: out.Bounds().Dx(), Height: out.Bounds().Dy()}
+
+	if outputLocalDir == "" && outputGCSBucket == "" {
+		duration := time.Since(startTime)
+		span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+		result := newAvtoolResult(ctx, toolName, strings.Join(append(messageParts, "No output location was specified, so the image is returned inline."), " "), duration, []avtoolOutput{output}, nil)
+		result.Content = append(result.Content, mcp.NewImageContent(base64.StdEncoding.EncodeToString(encoded.Bytes()), imageMIMETypes[outputFormat]))
+		return result, nil
+	}
+
+	extension := outputFormat
+	if extension == "jpeg" {
+		extension = "jpg"
+	}
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, extension)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+	if err := os.WriteFile(tempOutputFile, encoded.Bytes(), 0644); err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to write output image: %v", err)), nil
+	}
+
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process output image: %v", processErr)), nil
+	}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+	return newAvtoolResult(ctx, toolName, strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
@@ -0,0 +1,196 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// maxImageDimension is the largest output width or height accepted by the image tools.
+	maxImageDimension = 8192
+	// defaultJPEGQuality is the JPEG quality used when none is requested.
+	defaultJPEGQuality = 90
+	// maxInputPixels is the largest input image, in pixels, that the image tools decode. A
+	// small file can declare a huge image, so the size is checked before the full decode.
+	maxInputPixels = 64 << 20
+)
+
+// imageOutputFormats lists the formats the image tools can write.
+var imageOutputFormats = []string{"png", "jpeg", "gif"}
+
+// resizeModes lists how resize_image fits an image into the requested width and height.
+var resizeModes = []string{"fit", "fill", "stretch"}
+
+// imageMIMETypes maps output formats to their MIME types.
+var imageMIMETypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// normalizeImageFormat maps format names and common aliases (e.g. "jpg") to an output format.
+// Formats that can be decoded but not encoded (WebP) fall back to PNG.
+func normalizeImageFormat(format string) (string, error) {
+	switch f := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), ".")); f {
+	case "jpg", "jpeg":
+		return "jpeg", nil
+	case "png", "gif":
+		return f, nil
+	case "webp":
+		return "png", nil
+	default:
+		return "", fmt.Errorf("unsupported image format '%s'. Supported output formats are: %s", format, strings.Join(imageOutputFormats, ", "))
+	}
+}
+
+// decodeImageBytes decodes an image in any of the registered formats and returns it with its format name.
+// Images larger than maxInputPixels are rejected without being decoded.
+func decodeImageBytes(data []byte) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image (supported input formats are PNG, JPEG, GIF, and WebP): %w", err)
+	}
+	if pixels := int64(cfg.Width) * int64(cfg.Height); pixels > maxInputPixels {
+		return nil, "", fmt.Errorf("image is %dx%d (%d pixels), larger than the maximum of %d pixels", cfg.Width, cfg.Height, pixels, maxInputPixels)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image (supported input formats are PNG, JPEG, GIF, and WebP): %w", err)
+	}
+	return img, format, nil
+}
+
+// encodeImage writes img in the given output format. quality applies to JPEG only.
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "png":
+		return png.Encode(w, img)
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "gif":
+		return gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported output format '%s'", format)
+	}
+}
+
+// validateImageDimension checks a requested output width or height.
+func validateImageDimension(name string, value int) error {
+	if value < 1 || value > maxImageDimension {
+		return fmt.Errorf("%s must be between 1 and %d, got %d", name, maxImageDimension, value)
+	}
+	return nil
+}
+
+// resizeImage scales img to the requested size. If only one of width or height is given, the
+// other is derived from the aspect ratio. With both, mode selects how the image is fitted:
+// "fit" scales to fit within the box, "fill" scales to cover the box and crops the overflow
+// from the center, and "stretch" scales to the exact size, ignoring the aspect ratio.
+func resizeImage(img image.Image, width, height int, mode string) (image.Image, error) {
+	if width == 0 && height == 0 {
+		return nil, fmt.Errorf("at least one of width or height must be provided")
+	}
+	if !slices.Contains(resizeModes, mode) {
+		return nil, fmt.Errorf("unsupported mode '%s'. Supported modes are: %s", mode, strings.Join(resizeModes, ", "))
+	}
+	if width != 0 {
+		if err := validateImageDimension("width", width); err != nil {
+			return nil, err
+		}
+	}
+	if height != 0 {
+		if err := validateImageDimension("height", height); err != nil {
+			return nil, err
+		}
+	}
+
+	src := img.Bounds()
+	srcW, srcH := float64(src.Dx()), float64(src.Dy())
+	targetW, targetH := width, height
+	switch {
+	case width == 0:
+		targetW = max(1, int(srcW*float64(height)/srcH+0.5))
+	case height == 0:
+		targetH = max(1, int(srcH*float64(width)/srcW+0.5))
+	case mode == "fit":
+		scale := min(float64(width)/srcW, float64(height)/srcH)
+		targetW, targetH = max(1, int(srcW*scale+0.5)), max(1, int(srcH*scale+0.5))
+	case mode == "fill":
+		// The center of the source is cropped to the aspect ratio of the box before scaling, so
+		// no intermediate image larger than the box is allocated.
+		scale := max(float64(width)/srcW, float64(height)/srcH)
+		cropW := min(src.Dx(), max(1, int(float64(width)/scale+0.5)))
+		cropH := min(src.Dy(), max(1, int(float64(height)/scale+0.5)))
+		x0, y0 := src.Min.X+(src.Dx()-cropW)/2, src.Min.Y+(src.Dy()-cropH)/2
+		src = image.Rect(x0, y0, x0+cropW, y0+cropH)
+	}
+	// A size derived from the aspect ratio can exceed the limit, e.g. a tall image given a width.
+	if targetW > maxImageDimension || targetH > maxImageDimension {
+		return nil, fmt.Errorf("the resized image would be %dx%d, larger than %d pixels on a side", targetW, targetH, maxImageDimension)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, targetW, targetH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst, nil
+}
+
+// cropImage returns the width x height region of img whose top-left corner is at (x, y),
+// relative to the image's top-left corner. The region must lie within the image.
+func cropImage(img image.Image, x, y, width, height int) (image.Image, error) {
+	src := img.Bounds()
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("crop width and height must be positive, got %dx%d", width, height)
+	}
+	if x < 0 || y < 0 || x+width > src.Dx() || y+height > src.Dy() {
+		return nil, fmt.Errorf("crop region %dx%d at (%d,%d) is outside the %dx%d image", width, height, x, y, src.Dx(), src.Dy())
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), img, src.Min.Add(image.Pt(x, y)), draw.Src)
+	return dst, nil
+}
+
+// rotateImage rotates img clockwise by a multiple of 90 degrees.
+func rotateImage(img image.Image, degrees int) (image.Image, error) {
+	degrees = ((degrees % 360) + 360) % 360
+	if degrees%90 != 0 {
+		return nil, fmt.Errorf("rotation must be a multiple of 90 degrees, got %d", degrees)
+	}
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	if degrees == 0 {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), img, src.Min, draw.Src)
+		return dst, nil
+	}
+
+	dstW, dstH := w, h
+	if degrees != 180 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(src.Min.X+x, src.Min.Y+y)
+			switch degrees {
+			case 90:
+				dst.Set(h-1-y, x, c)
+			case 180:
+				dst.Set(w-1-x, h-1-y, c)
+			case 270:
+				dst.Set(y, w-1-x, c)
+			}
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestResizeImage(t *testing.T) {
+	src := newTestImage(200, 100, func(x, y int) color.RGBA {
+		return color.RGBA{R: uint8(x), G: uint8(y), B: 64, A: 255}
+	})
+
+	testCases := []struct {
+		name          string
+		width, height int
+		mode          string
+		wantW, wantH  int
+		wantErr       bool
+	}{
+		{name: "stretch to 512x512", width: 512, height: 512, mode: "stretch", wantW: 512, wantH: 512},
+		{name: "fill to 512x512", width: 512, height: 512, mode: "fill", wantW: 512, wantH: 512},
+		{name: "fit within 512x512", width: 512, height: 512, mode: "fit", wantW: 512, wantH: 256},
+		{name: "width only", width: 100, mode: "fit", wantW: 100, wantH: 50},
+		{name: "height only", height: 50, mode: "fit", wantW: 100, wantH: 50},
+		{name: "no dimensions", mode: "fit", wantErr: true},
+		{name: "too large", width: maxImageDimension + 1, mode: "fit", wantErr: true},
+		{name: "unknown mode", width: 10, height: 10, mode: "squash", wantErr: true},
+		{name: "derived width too large", height: maxImageDimension, mode: "fit", wantErr: true},
+		{name: "fill a tall box", width: 10, height: 400, mode: "fill", wantW: 10, wantH: 400},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resizeImage(src, tc.width, tc.height, tc.mode)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Bounds().Dx() != tc.wantW || got.Bounds().Dy() != tc.wantH {
+				t.Errorf("expected %dx%d, but got %dx%d", tc.wantW, tc.wantH, got.Bounds().Dx(), got.Bounds().Dy())
+			}
+		})
+	}
+}
+
+func TestResizeImagePNGRoundTrip(t *testing.T) {
+	src := newTestImage(64, 32, func(x, y int) color.RGBA {
+		return color.RGBA{R: 200, G: 100, B: 50, A: 255}
+	})
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, src, "png", defaultJPEGQuality); err != nil {
+		t.Fatalf("failed to encode source: %v", err)
+	}
+	decoded, format, err := decodeImageBytes(buf.Bytes())
+	if err != nil || format != "png" {
+		t.Fatalf("expected to decode a png, but got format %q, err %v", format, err)
+	}
+
+	resized, err := resizeImage(decoded, 512, 512, "stretch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf.Reset()
+	if err := encodeImage(&buf, resized, "png", defaultJPEGQuality); err != nil {
+		t.Fatalf("failed to encode resized image: %v", err)
+	}
+	out, _, err := decodeImageBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to decode resized image: %v", err)
+	}
+	if out.Bounds().Dx() != 512 || out.Bounds().Dy() != 512 {
+		t.Errorf("expected 512x512, but got %dx%d", out.Bounds().Dx(), out.Bounds().Dy())
+	}
+	r, g, b, _ := out.At(256, 256).RGBA()
+	if r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+		t.Errorf("expected the solid color to be preserved, but got (%d,%d,%d)", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestResizeImageChecksWidthFirst(t *testing.T) {
+	src := newTestImage(10, 10, func(x, y int) color.RGBA { return color.RGBA{A: 255} })
+	for range 10 {
+		_, err := resizeImage(src, -1, maxImageDimension+1, "fit")
+		if err == nil || !strings.HasPrefix(err.Error(), "width ") {
+			t.Fatalf("expected the width error, but got %v", err)
+		}
+	}
+}
+
+// hugePNG returns a small PNG file that declares a 20000x20000 image.
+func hugePNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, newTestImage(1, 1, func(x, y int) color.RGBA { return color.RGBA{A: 255} }), "png", defaultJPEGQuality); err != nil {
+		t.Fatalf("failed to encode source: %v", err)
+	}
+	// Declare the size in the IHDR chunk, which follows the 8-byte signature and the chunk's
+	// length, and fix up the chunk's CRC.
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:20], 20000)
+	binary.BigEndian.PutUint32(data[20:24], 20000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestDecodeImageBytesRejectsHugeImages(t *testing.T) {
+	_, _, err := decodeImageBytes(hugePNG(t))
+	if err == nil || !strings.Contains(err.Error(), "larger than the maximum") {
+		t.Errorf("expected the image to be rejected as too large, but got %v", err)
+	}
+}
+
+func TestCropImage(t *testing.T) {
+	src := newTestImage(100, 80, func(x, y int) color.RGBA {
+		return color.RGBA{R: uint8(x), G: uint8(y), A: 255}
+	})
+
+	got, err := cropImage(src, 10, 20, 30, 40)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Bounds() != image.Rect(0, 0, 30, 40) {
+		t.Errorf("expected bounds 30x40, but got %v", got.Bounds())
+	}
+	if r, g, _, _ := got.At(0, 0).RGBA(); r>>8 != 10 || g>>8 != 20 {
+		t.Errorf("expected top-left pixel from (10,20), but got (%d,%d)", r>>8, g>>8)
+	}
+
+	for _, rect := range [][4]int{{-1, 0, 10, 10}, {95, 0, 10, 10}, {0, 75, 10, 10}, {0, 0, 0, 10}} {
+		if _, err := cropImage(src, rect[0], rect[1], rect[2], rect[3]); err == nil {
+			t.Errorf("expected an error for crop %v, but got none", rect)
+		}
+	}
+}
+
+func TestRotateImage(t *testing.T) {
+	src := newTestImage(40, 20, func(x, y int) color.RGBA {
+		if x == 0 && y == 0 {
+			return color.RGBA{R: 255, A: 255}
+		}
+		return color.RGBA{A: 255}
+	})
+
+	testCases := []struct {
+		degrees      int
+		wantW, wantH int
+		redX, redY   int
+	}{
+		{degrees: 90, wantW: 20, wantH: 40, redX: 19, redY: 0},
+		{degrees: 180, wantW: 40, wantH: 20, redX: 39, redY: 19},
+		{degrees: 270, wantW: 20, wantH: 40, redX: 0, redY: 39},
+		{degrees: -90, wantW: 20, wantH: 40, redX: 0, redY: 39},
+	}
+	for _, tc := range testCases {
+		got, err := rotateImage(src, tc.degrees)
+		if err != nil {
+			t.Fatalf("unexpected error for %d degrees: %v", tc.degrees, err)
+		}
+		if got.Bounds().Dx() != tc.wantW || got.Bounds().Dy() != tc.wantH {
+			t.Errorf("%d degrees: expected %dx%d, but got %dx%d", tc.degrees, tc.wantW, tc.wantH, got.Bounds().Dx(), got.Bounds().Dy())
+		}
+		if r, _, _, _ := got.At(tc.redX, tc.redY).RGBA(); r>>8 != 255 {
+			t.Errorf("%d degrees: expected the red corner at (%d,%d)", tc.degrees, tc.redX, tc.redY)
+		}
+	}
+
+	if _, err := rotateImage(src, 45); err == nil {
+		t.Errorf("expected an error for 45 degrees, but got none")
+	}
+}
+
+func TestNormalizeImageFormat(t *testing.T) {
+	testCases := map[string]string{"jpg": "jpeg", ".JPEG": "jpeg", "png": "png", ".gif": "gif", "webp": "png"}
+	for input, want := range testCases {
+		got, err := normalizeImageFormat(input)
+		if err != nil || got != want {
+			t.Errorf("expected %q for %q, but got %q (err %v)", want, input, got, err)
+		}
+	}
+	if _, err := normalizeImageFormat("bmp"); err == nil {
+		t.Errorf("expected an error for bmp, but got none")
+	}
+}
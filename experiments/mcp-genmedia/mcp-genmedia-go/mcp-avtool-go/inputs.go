@@ -0,0 +1,94 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+// defaultDownloadConcurrency is how many inputs of one tool call are prepared at once by default.
+const defaultDownloadConcurrency = 4
+
+// inputDownloadConcurrency bounds the number of gs:// inputs of one tool call that are
+// downloaded in parallel. main sets it from AVTOOL_DOWNLOAD_CONCURRENCY once the configuration,
+// including .env, is loaded.
+var inputDownloadConcurrency = defaultDownloadConcurrency
+
+// prepareInputFile prepares one input; tests replace it to observe the concurrency of
+// prepareInputFiles.
+var prepareInputFile = common.PrepareInputFile
+
+// getDownloadConcurrency reads AVTOOL_DOWNLOAD_CONCURRENCY, defaulting to defaultDownloadConcurrency.
+func getDownloadConcurrency() int {
+	if v := os.Getenv("AVTOOL_DOWNLOAD_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		common.LogWarnf("Invalid AVTOOL_DOWNLOAD_CONCURRENCY value %q, using default of %d", v, defaultDownloadConcurrency)
+	}
+	return defaultDownloadConcurrency
+}
+
+// prepareInputFiles prepares the input files at uris like common.PrepareInputFile, downloading
+// up to inputDownloadConcurrency gs:// inputs at once. The local paths are returned in the order
+// of uris, along with a function that removes all downloads. If any input fails, the remaining
+// downloads are canceled, the completed ones are removed, and the error names the failed URI.
+func prepareInputFiles(ctx context.Context, uris []string, purpose, projectID string) ([]string, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	localPaths := make([]string, len(uris))
+	cleanups := make([]func(), len(uris))
+	cleanup := func() {
+		for _, c := range cleanups {
+			if c != nil {
+				c()
+			}
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, inputDownloadConcurrency)
+	for i, uri := range uris {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if ctx.Err() != nil {
+				return // Another input failed.
+			}
+			localPath, c, err := prepareInputFile(ctx, uri, fmt.Sprintf("%s_%d", purpose, i), projectID)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", uri, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			localPaths[i], cleanups[i] = localPath, c
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		cleanup()
+		return nil, func() {}, firstErr
+	}
+	return localPaths, cleanup, nil
+}
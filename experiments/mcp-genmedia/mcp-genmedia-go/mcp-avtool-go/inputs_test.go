@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrepareInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	var uris []string
+	for _, name := range []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4", "e.mp4", "f.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		uris = append(uris, path)
+	}
+
+	got, cleanup, err := prepareInputFiles(context.Background(), uris, "test_input", "")
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	defer cleanup()
+	if !reflect.DeepEqual(got, uris) {
+		t.Errorf("expected the inputs in order %v, but got %v", uris, got)
+	}
+
+	missing := filepath.Join(dir, "missing.mp4")
+	_, _, err = prepareInputFiles(context.Background(), append(uris, missing), "test_input", "")
+	if err == nil || !strings.HasPrefix(err.Error(), missing+":") {
+		t.Errorf("expected an error naming %s, but got: %v", missing, err)
+	}
+
+	if _, _, err := prepareInputFiles(context.Background(), []string{"gs://bucket/a.mp4"}, "test_input", ""); err == nil || !strings.Contains(err.Error(), "GOOGLE_CLOUD_PROJECT not set") {
+		t.Errorf("expected a GCS error without a project, but got: %v", err)
+	}
+}
+
+func TestGetDownloadConcurrency(t *testing.T) {
+	t.Setenv("AVTOOL_DOWNLOAD_CONCURRENCY", "8")
+	if got := getDownloadConcurrency(); got != 8 {
+		t.Errorf("expected 8, but got %d", got)
+	}
+	t.Setenv("AVTOOL_DOWNLOAD_CONCURRENCY", "0")
+	if got := getDownloadConcurrency(); got != defaultDownloadConcurrency {
+		t.Errorf("expected the default for an invalid value, but got %d", got)
+	}
+}
+
+func TestPrepareInputFilesConcurrency(t *testing.T) {
+	origPrepare, origConcurrency := prepareInputFile, inputDownloadConcurrency
+	t.Cleanup(func() { prepareInputFile, inputDownloadConcurrency = origPrepare, origConcurrency })
+
+	var running, peak atomic.Int32
+	prepareInputFile = func(ctx context.Context, uri, purpose, projectID string) (string, func(), error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond) // Long enough for the other inputs to start, if allowed.
+		return "/tmp/" + purpose, func() {}, nil
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		inputDownloadConcurrency = concurrency
+		peak.Store(0)
+		var uris []string
+		for i := range 10 {
+			uris = append(uris, fmt.Sprintf("gs://bucket/%d.mp4", i))
+		}
+		got, cleanup, err := prepareInputFiles(context.Background(), uris, "test_input", "project")
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		cleanup()
+		if len(got) != len(uris) || got[9] != "/tmp/test_input_9" {
+			t.Errorf("expected the inputs in order, but got %v", got)
+		}
+		if p := int(peak.Load()); p > concurrency {
+			t.Errorf("expected at most %d inputs prepared at once, but got %d", concurrency, p)
+		} else if concurrency > 1 && p < 2 {
+			t.Errorf("expected inputs to be prepared in parallel, but the peak was %d", p)
+		}
+	}
+}
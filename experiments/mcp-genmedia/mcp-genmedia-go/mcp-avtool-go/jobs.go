@@ -0,0 +1,313 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	jobStatusRunning = "running"
+	jobStatusDone    = "done"
+	jobStatusFailed  = "failed"
+
+	// asyncParam is the tool parameter that makes a tracked tool return its job ID immediately.
+	asyncParam = "async"
+	// defaultJobTTL is how long finished jobs are kept for polling.
+	defaultJobTTL = time.Hour
+	// defaultJobTimeout bounds how long an async job may run.
+	defaultJobTimeout = 2 * time.Hour
+	// jobOutputTailBytes is how much of the most recent FFMpeg output each job retains.
+	jobOutputTailBytes = 4096
+	// jobOutputTailLines is how many lines of FFMpeg output get_avtool_job returns.
+	jobOutputTailLines = 10
+)
+
+// avtoolJob tracks a single avtool tool invocation and the FFMpeg output it produced.
+type avtoolJob struct {
+	mu         sync.Mutex
+	id         string
+	tool       string
+	status     string
+	startedAt  time.Time
+	finishedAt time.Time
+	result     string
+	structured any      // Structured content of the tool result, if any.
+	output     []byte   // The last jobOutputTailBytes of FFMpeg output.
+	commands   []string // FFMpeg commands run by the job, as formatted by redactCommand.
+}
+
+// Write appends FFMpeg output to the job, keeping only the most recent bytes.
+func (j *avtoolJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.output = append(j.output, p...)
+	if over := len(j.output) - jobOutputTailBytes; over > 0 {
+		j.output = append(j.output[:0], j.output[over:]...)
+	}
+	return len(p), nil
+}
+
+// recordCommand records an FFMpeg command run by the job.
+func (j *avtoolJob) recordCommand(args []string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.commands = append(j.commands, redactCommand(args))
+}
+
+// recordedCommands returns the FFMpeg commands run by the job so far.
+func (j *avtoolJob) recordedCommands() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return slices.Clone(j.commands)
+}
+
+// finish records the outcome of the tool call.
+func (j *avtoolJob) finish(result *mcp.CallToolResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	switch {
+	case err != nil:
+		j.status, j.result = jobStatusFailed, err.Error()
+	case result == nil:
+		j.status = jobStatusDone
+	default:
+		var texts []string
+		for _, content := range result.Content {
+			if textContent, ok := content.(mcp.TextContent); ok {
+				texts = append(texts, textContent.Text)
+			}
+		}
+		j.result = strings.Join(texts, "\n")
+		j.structured = result.StructuredContent
+		j.status = jobStatusDone
+		if result.IsError {
+			j.status = jobStatusFailed
+		}
+	}
+}
+
+// avtoolJobStatus is the JSON view of a job returned by get_avtool_job.
+type avtoolJobStatus struct {
+	JobID          string  `json:"job_id"`
+	Tool           string  `json:"tool"`
+	Status         string  `json:"status"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Result         string  `json:"result,omitempty"`
+	Structured     any     `json:"structured_result,omitempty"`
+	OutputTail     string  `json:"output_tail,omitempty"`
+}
+
+// snapshot returns the current state of the job.
+func (j *avtoolJob) snapshot() avtoolJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := j.finishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	// FFMpeg rewrites its progress line with carriage returns; treat them as line breaks.
+	output := strings.ReplaceAll(string(j.output), "\r", "\n")
+	return avtoolJobStatus{
+		JobID:          j.id,
+		Tool:           j.tool,
+		Status:         j.status,
+		ElapsedSeconds: end.Sub(j.startedAt).Round(time.Millisecond).Seconds(),
+		Result:         j.result,
+		Structured:     j.structured,
+		OutputTail:     common.GetTail(strings.TrimSpace(output), jobOutputTailLines),
+	}
+}
+
+// jobRegistry holds the avtool jobs. Finished jobs are removed once they are older than ttl.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*avtoolJob
+	ttl  time.Duration
+}
+
+// avtoolJobs holds the jobs of the server. main replaces it with a registry using AVTOOL_JOB_TTL
+// once the configuration, including .env, is loaded.
+var avtoolJobs = newJobRegistry(defaultJobTTL)
+
+func newJobRegistry(ttl time.Duration) *jobRegistry {
+	return &jobRegistry{jobs: map[string]*avtoolJob{}, ttl: ttl}
+}
+
+// asyncJobTimeout bounds how long an async job may run. main sets it from AVTOOL_JOB_TIMEOUT.
+var asyncJobTimeout = defaultJobTimeout
+
+// getJobTTL reads AVTOOL_JOB_TTL (a Go duration string), defaulting to one hour.
+func getJobTTL() time.Duration {
+	if v := os.Getenv("AVTOOL_JOB_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		common.LogWarnf("Invalid AVTOOL_JOB_TTL value %q, using default of %v", v, defaultJobTTL)
+	}
+	return defaultJobTTL
+}
+
+// getJobTimeout reads AVTOOL_JOB_TIMEOUT (a Go duration string), defaulting to two hours.
+func getJobTimeout() time.Duration {
+	if v := os.Getenv("AVTOOL_JOB_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		common.LogWarnf("Invalid AVTOOL_JOB_TIMEOUT value %q, using default of %v", v, defaultJobTimeout)
+	}
+	return defaultJobTimeout
+}
+
+// start registers a new running job for the given tool.
+func (r *jobRegistry) start(tool string) *avtoolJob {
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	job := &avtoolJob{
+		id:        hex.EncodeToString(idBytes),
+		tool:      tool,
+		status:    jobStatusRunning,
+		startedAt: time.Now(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	r.jobs[job.id] = job
+	return job
+}
+
+// get returns the job with the given ID, if it exists and has not expired.
+func (r *jobRegistry) get(id string) (*avtoolJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+// list returns all known jobs, most recently started first.
+func (r *jobRegistry) list() []*avtoolJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	jobs := make([]*avtoolJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].startedAt.After(jobs[b].startedAt) })
+	return jobs
+}
+
+// pruneLocked removes finished jobs older than the TTL. r.mu must be held.
+func (r *jobRegistry) pruneLocked() {
+	cutoff := time.Now().Add(-r.ttl)
+	for id, job := range r.jobs {
+		job.mu.Lock()
+		expired := !job.finishedAt.IsZero() && job.finishedAt.Before(cutoff)
+		job.mu.Unlock()
+		if expired {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+type jobContextKey struct{}
+
+// jobFromContext returns the job that the current tool call is running under, if any.
+func jobFromContext(ctx context.Context) *avtoolJob {
+	job, _ := ctx.Value(jobContextKey{}).(*avtoolJob)
+	return job
+}
+
+// addTrackedTool registers an FFMpeg-backed tool so that every invocation is tracked as a job.
+// It adds an optional 'async' parameter: when true, the tool runs in the background and
+// immediately returns a job ID that can be polled with get_avtool_job.
+func addTrackedTool(s *server.MCPServer, cfg *common.Config, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = map[string]any{}
+	}
+	tool.InputSchema.Properties[asyncParam] = map[string]any{
+		"type":        "boolean",
+		"description": "Optional. If true, run in the background and return a job ID immediately. Poll it with get_avtool_job.",
+	}
+
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		job := avtoolJobs.start(tool.Name)
+		async, _ := request.GetArguments()[asyncParam].(bool)
+		common.LogInfof("Started %s job %s (async: %t)", tool.Name, job.id, async)
+
+		if !async {
+			result, err := handler(context.WithValue(ctx, jobContextKey{}, job), request)
+			job.finish(result, err)
+			return result, err
+		}
+
+		// The request context ends when this call returns, so the background job must not inherit its
+		// cancellation. It is bounded by AVTOOL_JOB_TIMEOUT instead, so a stuck FFMpeg run does not
+		// live forever.
+		jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncJobTimeout)
+		jobCtx = context.WithValue(jobCtx, jobContextKey{}, job)
+		go func() {
+			defer cancel()
+			result, err := handler(jobCtx, request)
+			job.finish(result, err)
+			common.LogInfof("%s job %s finished with status %s", tool.Name, job.id, job.snapshot().Status)
+		}()
+		return mcp.NewToolResultText(fmt.Sprintf("Started %s as job %s. Poll its status with get_avtool_job.", tool.Name, job.id)), nil
+	})
+}
+
+// addGetJobTool defines and registers the 'get_avtool_job' tool.
+// This tool reports the status of avtool jobs started by the other tools.
+func addGetJobTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("get_avtool_job",
+		mcp.WithDescription("Returns the status (running, done, or failed), elapsed time, result, and the tail of the FFMpeg output of an avtool job. If 'job_id' is omitted, lists all known jobs. Finished jobs are kept for AVTOOL_JOB_TTL (default 1h)."),
+		mcp.WithString("job_id", mcp.Description("Optional. ID of the job returned by a tool called with 'async': true.")),
+	)
+	common.AddTool(s, cfg, tool, getJobHandler)
+}
+
+// getJobHandler is the handler for the 'get_avtool_job' tool.
+func getJobHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	jobID, _ := request.GetArguments()["job_id"].(string)
+	jobID = strings.TrimSpace(jobID)
+
+	var payload any
+	if jobID == "" {
+		statuses := []avtoolJobStatus{}
+		for _, job := range avtoolJobs.list() {
+			status := job.snapshot()
+			status.OutputTail = ""
+			statuses = append(statuses, status)
+		}
+		payload = statuses
+	} else {
+		job, ok := avtoolJobs.get(jobID)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Job '%s' not found. It may have expired.", jobID)), nil
+		}
+		payload = job.snapshot()
+	}
+
+	out, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode job status: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
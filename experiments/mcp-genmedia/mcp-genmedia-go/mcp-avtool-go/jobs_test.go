@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestAvtoolJobLifecycle(t *testing.T) {
+	registry := newJobRegistry(time.Hour)
+
+	tests := []struct {
+		name       string
+		result     *mcp.CallToolResult
+		err        error
+		wantStatus string
+		wantResult string
+	}{
+		{"success", mcp.NewToolResultText("Video scaling completed."), nil, jobStatusDone, "Video scaling completed."},
+		{"tool error", mcp.NewToolResultError("FFMpeg scale video failed"), nil, jobStatusFailed, "FFMpeg scale video failed"},
+		{"handler error", nil, errors.New("boom"), jobStatusFailed, "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := registry.start("ffmpeg_scale_video")
+			if got := job.snapshot().Status; got != jobStatusRunning {
+				t.Fatalf("expected status %s, but got %s", jobStatusRunning, got)
+			}
+			job.finish(tt.result, tt.err)
+
+			found, ok := registry.get(job.id)
+			if !ok {
+				t.Fatalf("expected job %s to be registered", job.id)
+			}
+			status := found.snapshot()
+			if status.Status != tt.wantStatus {
+				t.Errorf("expected status %s, but got %s", tt.wantStatus, status.Status)
+			}
+			if status.Result != tt.wantResult {
+				t.Errorf("expected result %q, but got %q", tt.wantResult, status.Result)
+			}
+		})
+	}
+}
+
+func TestAvtoolJobOutputTail(t *testing.T) {
+	job := newJobRegistry(time.Hour).start("ffmpeg_convert_audio_wav_to_mp3")
+	job.Write([]byte(strings.Repeat("x", jobOutputTailBytes)))
+	job.Write([]byte("\nframe=  10\rframe=  20\rframe=  30"))
+
+	if len(job.output) != jobOutputTailBytes {
+		t.Errorf("expected %d bytes of output to be kept, but got %d", jobOutputTailBytes, len(job.output))
+	}
+	tail := job.snapshot().OutputTail
+	if !strings.HasSuffix(tail, "frame=  20\nframe=  30") {
+		t.Errorf("expected tail to end with the latest progress lines, but got %q", tail)
+	}
+}
+
+func TestJobRegistryPrunesExpiredJobs(t *testing.T) {
+	registry := newJobRegistry(time.Minute)
+	finished := registry.start("ffmpeg_mix_audio")
+	finished.finish(mcp.NewToolResultText("done"), nil)
+	finished.finishedAt = time.Now().Add(-2 * time.Minute)
+	running := registry.start("ffmpeg_mix_audio")
+
+	if _, ok := registry.get(finished.id); ok {
+		t.Errorf("expected expired job %s to be pruned", finished.id)
+	}
+	if _, ok := registry.get(running.id); !ok {
+		t.Errorf("expected running job %s to be kept", running.id)
+	}
+}
+
+func TestAsyncJobTimeout(t *testing.T) {
+	saved := asyncJobTimeout
+	t.Cleanup(func() { asyncJobTimeout = saved })
+	asyncJobTimeout = time.Minute
+
+	deadlines := make(chan time.Time, 1)
+	s := server.NewMCPServer("test", "1.0.0")
+	addTrackedTool(s, &common.Config{}, mcp.NewTool("ffmpeg_test"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		return mcp.NewToolResultText("done"), nil
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "ffmpeg_test"
+	request.Params.Arguments = map[string]any{asyncParam: true}
+	if _, err := s.GetTool("ffmpeg_test").Handler(context.Background(), request); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	deadline := <-deadlines
+	if deadline.IsZero() || time.Until(deadline) > time.Minute {
+		t.Errorf("expected the async job to have a deadline within %v, but got %v", asyncJobTimeout, deadline)
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,12 +25,12 @@ import (
 // This function helps in gracefully handling malformed or missing arguments.
 func getArguments(request mcp.CallToolRequest) (map[string]interface{}, error) {
 	if request.Params.Arguments == nil {
-		log.Println("Warning: request.Params.Arguments is nil, treating as empty arguments.")
+		common.LogWarnf("Warning: request.Params.Arguments is nil, treating as empty arguments.")
 		return make(map[string]interface{}), nil
 	}
 	argsMap, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
-		log.Printf("Error: request.Params.Arguments is of type %T, not map[string]interface{}", request.Params.Arguments)
+		common.LogErrorf("Error: request.Params.Arguments is of type %T, not map[string]interface{}", request.Params.Arguments)
 		return nil, fmt.Errorf("internal error: request arguments are not in the expected map format (type: %T)", request.Params.Arguments)
 	}
 	return argsMap, nil
@@ -43,7 +43,7 @@ func addGetMediaInfoTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithDescription("Gets media information (streams, format, etc.) from a media file using ffprobe. Returns JSON output."),
 		mcp.WithString("input_media_uri", mcp.Required(), mcp.Description("URI of the input media file (local path or gs://).")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegGetMediaInfoHandler(ctx, request, cfg)
 	})
 }
@@ -61,7 +61,7 @@ func ffmpegGetMediaInfoHandler(ctx context.Context, request mcp.CallToolRequest,
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_get_media_info", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_get_media_info", argsMap)
 
 	inputMediaURI, _ := argsMap["input_media_uri"].(string)
 	if strings.TrimSpace(inputMediaURI) == "" {
@@ -85,12 +85,12 @@ func ffmpegGetMediaInfoHandler(ctx context.Context, request mcp.CallToolRequest,
 
 	var jsTest map[string]interface{}
 	if errUnmarshal := json.Unmarshal([]byte(outputJSON), &jsTest); errUnmarshal != nil {
-		log.Printf("Warning: FFprobe output for %s was not valid JSON, though command reported success. Output: %s", inputMediaURI, outputJSON)
+		common.LogWarnf("Warning: FFprobe output for %s was not valid JSON, though command reported success. Output: %s", inputMediaURI, outputJSON)
 		return mcp.NewToolResultText(fmt.Sprintf("FFprobe returned non-JSON output: %s", outputJSON)), nil
 	}
 
 	duration := time.Since(startTime)
-	log.Printf("FFprobe for %s completed in %v.", inputMediaURI, duration)
+	common.LogInfof("FFprobe for %s completed in %v.", inputMediaURI, duration)
 	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
 	return mcp.NewToolResultText(outputJSON), nil
 }
@@ -105,7 +105,7 @@ func addConvertAudioTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output MP3 file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output MP3 file to.")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegConvertAudioHandler(ctx, request, cfg)
 	})
 }
@@ -123,7 +123,7 @@ func ffmpegConvertAudioHandler(ctx context.Context, request mcp.CallToolRequest,
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_convert_audio_wav_to_mp3", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_convert_audio_wav_to_mp3", argsMap)
 
 	inputAudioURI, _ := argsMap["input_audio_uri"].(string)
 	outputFileName, _ := argsMap["output_file_name"].(string)
@@ -133,7 +133,7 @@ func ffmpegConvertAudioHandler(ctx context.Context, request mcp.CallToolRequest,
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_convert_audio_wav_to_mp3: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_convert_audio_wav_to_mp3: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -169,6 +169,7 @@ func ffmpegConvertAudioHandler(ctx context.Context, request mcp.CallToolRequest,
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg conversion failed: %v", ffmpegErr)), nil
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -193,7 +194,7 @@ func ffmpegConvertAudioHandler(ctx context.Context, request mcp.CallToolRequest,
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_convert_audio_wav_to_mp3", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addCreateGifTool defines and registers the 'ffmpeg_video_to_gif' tool.
@@ -204,11 +205,14 @@ func addCreateGifTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the input video file (local path or gs://).")),
 		mcp.WithNumber("scale_width_factor", mcp.DefaultNumber(0.33), mcp.Description("Factor to scale the input video's width by (e.g., 0.33 for 33%). Height is scaled automatically to maintain aspect ratio. Use 1.0 for original width.")),
 		mcp.WithNumber("fps", mcp.DefaultNumber(15), mcp.Min(1), mcp.Max(50), mcp.Description("Frames per second for the output GIF (e.g., 10, 15, 25).")),
+		mcp.WithNumber("width", mcp.Min(1), mcp.Max(maxGifWidth), mcp.Description("Optional. Output width in pixels (e.g., 480). Overrides scale_width_factor. Height is scaled automatically to maintain aspect ratio.")),
+		mcp.WithString("dither", mcp.DefaultString("sierra2_4a"), mcp.Enum(gifDitherModes...), mcp.Description("Optional. Dithering used when mapping frames to the palette. 'sierra2_4a' (default) and 'floyd_steinberg' give smooth gradients; 'bayer' gives an ordered pattern that compresses better; 'none' gives the smallest files with visible color banding.")),
+		mcp.WithString("stats_mode", mcp.DefaultString("full"), mcp.Enum(gifStatsModes...), mcp.Description("Optional. How the palette is generated: 'full' (default) from all pixels, or 'diff' from the pixels that change between frames, which improves moving subjects over a static background.")),
 		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output GIF file (e.g., 'animation.gif'). If omitted, a unique name is generated.")),
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output GIF file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output GIF file to (uses GENMEDIA_BUCKET if set and this is empty).")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegVideoToGifHandler(ctx, request, cfg)
 	})
 }
@@ -226,7 +230,7 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_video_to_gif", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_video_to_gif", argsMap)
 
 	inputVideoURI, _ := argsMap["input_video_uri"].(string)
 	if strings.TrimSpace(inputVideoURI) == "" {
@@ -247,6 +251,25 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 	if fpsParam > 50 {
 		fpsParam = 50
 	}
+	widthParam, _ := argsMap["width"].(float64)
+	ditherParam, _ := argsMap["dither"].(string)
+	if ditherParam == "" {
+		ditherParam = "sierra2_4a"
+	}
+	statsModeParam, _ := argsMap["stats_mode"].(string)
+	if statsModeParam == "" {
+		statsModeParam = "full"
+	}
+	paletteVFFilter, gifLavfiFilter, err := buildGifFilters(gifOptions{
+		FPS:         fpsParam,
+		ScaleFactor: scaleFactorParam,
+		Width:       int(widthParam),
+		Dither:      ditherParam,
+		StatsMode:   statsModeParam,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid GIF parameters: %v", err)), nil
+	}
 
 	outputFileName, _ := argsMap["output_file_name"].(string)
 	outputLocalDir, _ := argsMap["output_local_dir"].(string)
@@ -254,7 +277,7 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_video_to_gif: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_video_to_gif: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -264,6 +287,9 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 		attribute.String("input_video_uri", inputVideoURI),
 		attribute.Float64("scale_width_factor", scaleFactorParam),
 		attribute.Float64("fps", fpsParam),
+		attribute.Int("width", int(widthParam)),
+		attribute.String("dither", ditherParam),
+		attribute.String("stats_mode", statsModeParam),
 		attribute.String("output_file_name", outputFileName),
 		attribute.String("output_local_dir", outputLocalDir),
 		attribute.String("output_gcs_bucket", outputGCSBucket),
@@ -282,19 +308,18 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp directory for GIF processing: %v", err)), nil
 	}
 	defer func() {
-		log.Printf("Cleaning up GIF processing temporary directory: %s", gifProcessingTempDir)
+		common.LogDebugf("Cleaning up GIF processing temporary directory: %s", gifProcessingTempDir)
 		_ = os.RemoveAll(gifProcessingTempDir)
 	}()
 
 	palettePath := filepath.Join(gifProcessingTempDir, "palette.png")
-	paletteVFFilter := fmt.Sprintf("fps=%.2f,scale=iw*%.2f:-1:flags=lanczos+accurate_rnd+full_chroma_inp,palettegen", fpsParam, scaleFactorParam)
-	log.Printf("Generating palette with VF filter: %s", paletteVFFilter)
+	common.LogDebugf("Generating palette with VF filter: %s", paletteVFFilter)
 	_, ffmpegErrPalette := runFFmpegCommand(ctx, "-y", "-i", localInputVideo, "-vf", paletteVFFilter, palettePath)
 	if ffmpegErrPalette != nil {
 		span.RecordError(ffmpegErrPalette)
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg palette generation failed: %v", ffmpegErrPalette)), nil
 	}
-	log.Printf("Palette generated successfully: %s", palettePath)
+	common.LogDebugf("Palette generated successfully: %s", palettePath)
 
 	var finalGifFilename string
 	if strings.TrimSpace(outputFileName) == "" {
@@ -308,15 +333,15 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 	}
 	tempGifOutputPath := filepath.Join(gifProcessingTempDir, finalGifFilename)
 
-	gifLavfiFilter := fmt.Sprintf("fps=%.2f,scale=iw*%.2f:-1:flags=lanczos+accurate_rnd+full_chroma_inp [x]; [x][1:v] paletteuse", fpsParam, scaleFactorParam)
-	log.Printf("Creating GIF with LAVFI filter: %s", gifLavfiFilter)
+	common.LogDebugf("Creating GIF with LAVFI filter: %s", gifLavfiFilter)
 	_, ffmpegErrGif := runFFmpegCommand(ctx, "-y", "-i", localInputVideo, "-i", palettePath, "-lavfi", gifLavfiFilter, tempGifOutputPath)
 	if ffmpegErrGif != nil {
 		span.RecordError(ffmpegErrGif)
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg GIF creation failed: %v", ffmpegErrGif)), nil
 	}
-	log.Printf("GIF created successfully in temp location: %s", tempGifOutputPath)
+	common.LogDebugf("GIF created successfully in temp location: %s", tempGifOutputPath)
 
+	output := probeOutput(ctx, tempGifOutputPath)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempGifOutputPath, finalGifFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -341,7 +366,7 @@ func ffmpegVideoToGifHandler(ctx context.Context, request mcp.CallToolRequest, c
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location (local/GCS) was processed or an issue occurred in processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_video_to_gif", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addCombineAudioVideoTool defines and registers the 'ffmpeg_combine_audio_and_video' tool.
@@ -357,7 +382,7 @@ func addCombineAudioVideoTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to.")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegCombineAudioVideoHandler(ctx, request, cfg)
 	})
 }
@@ -376,7 +401,7 @@ func ffmpegCombineAudioVideoHandler(ctx context.Context, request mcp.CallToolReq
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_combine_audio_and_video", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_combine_audio_and_video", argsMap)
 
 	inputVideoURI, _ := argsMap["input_video_uri"].(string)
 	inputAudioURI, _ := argsMap["input_audio_uri"].(string)
@@ -390,7 +415,7 @@ func ffmpegCombineAudioVideoHandler(ctx context.Context, request mcp.CallToolReq
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_combine_audio_and_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_combine_audio_and_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -483,6 +508,7 @@ func ffmpegCombineAudioVideoHandler(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg combine audio/video failed: %v", ffmpegErr)), nil
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -505,7 +531,7 @@ func ffmpegCombineAudioVideoHandler(ctx context.Context, request mcp.CallToolReq
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_combine_audio_and_video", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addOverlayImageOnVideoTool defines and registers the 'ffmpeg_overlay_image_on_video' tool.
@@ -521,7 +547,7 @@ func addOverlayImageOnVideoTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to.")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegOverlayImageHandler(ctx, request, cfg)
 	})
 }
@@ -539,7 +565,7 @@ func ffmpegOverlayImageHandler(ctx context.Context, request mcp.CallToolRequest,
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_overlay_image_on_video", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_overlay_image_on_video", argsMap)
 
 	inputVideoURI, _ := argsMap["input_video_uri"].(string)
 	inputImageURI, _ := argsMap["input_image_uri"].(string)
@@ -554,7 +580,7 @@ func ffmpegOverlayImageHandler(ctx context.Context, request mcp.CallToolRequest,
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_overlay_image_on_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_overlay_image_on_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -601,6 +627,7 @@ func ffmpegOverlayImageHandler(ctx context.Context, request mcp.CallToolRequest,
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg overlay image failed: %v", ffmpegErr)), nil
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -623,7 +650,7 @@ func ffmpegOverlayImageHandler(ctx context.Context, request mcp.CallToolRequest,
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_overlay_image_on_video", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addConcatenateMediaTool defines and registers the 'ffmpeg_concatenate_media_files' tool.
@@ -636,8 +663,13 @@ func addConcatenateMediaTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output file (e.g., 'concatenated.mp4'). Extension determines behavior for audio concatenation.")),
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to.")),
+		mcp.WithBoolean("concat_with_audio_crossfade", mcp.Description("Optional. If true, the audio is crossfaded across each clip boundary while the video still cuts hard, so continuous background music does not drop out between clips. Each crossfade overlaps the clips, so the output is shorter by the crossfade duration per boundary. The output format must be MP4, MOV, MKV, M4A, AAC, or (for audio-only inputs) MP3.")),
+		mcp.WithNumber("audio_crossfade_duration",
+			mcp.DefaultNumber(defaultAudioCrossfadeSeconds),
+			mcp.Description(fmt.Sprintf("Optional. Length of each audio crossfade in seconds (greater than 0, at most %d). Every input must be longer than this.", maxAudioCrossfadeSeconds)),
+		),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegConcatenateMediaHandler(ctx, request, cfg)
 	})
 }
@@ -657,7 +689,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_concatenate_media_files", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_concatenate_media_files", argsMap)
 
 	inputMediaURIsRaw, _ := argsMap["input_media_uris"].([]interface{})
 	var inputMediaURIs []string
@@ -671,10 +703,15 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 	outputLocalDir, _ := argsMap["output_local_dir"].(string)
 	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
 	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	audioCrossfade, _ := argsMap["concat_with_audio_crossfade"].(bool)
+	crossfadeSeconds := defaultAudioCrossfadeSeconds
+	if v, ok := argsMap["audio_crossfade_duration"].(float64); ok {
+		crossfadeSeconds = v
+	}
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_concatenate_media_files: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_concatenate_media_files: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -683,10 +720,10 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 		if len(inputMediaURIs) == 0 {
 			return mcp.NewToolResultError("At least one media file is required for concatenation."), nil
 		}
-		log.Println("Warning: Only one input file provided for concatenation. Will process it as a single file operation.")
+		common.LogWarnf("Warning: Only one input file provided for concatenation. Will process it as a single file operation.")
 	}
 	if len(inputMediaURIs) < 2 && len(inputMediaURIs) > 0 {
-		log.Println("Warning: Only one input file provided for concatenation. The 'concatenation' will essentially be a copy or re-encode of this single file through the chosen path (PCM or AAC standardization).")
+		common.LogWarnf("Warning: Only one input file provided for concatenation. The 'concatenation' will essentially be a copy or re-encode of this single file through the chosen path (PCM or AAC standardization).")
 	}
 
 	span.SetAttributes(
@@ -694,25 +731,18 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 		attribute.String("output_file_name", outputFileName),
 		attribute.String("output_local_dir", outputLocalDir),
 		attribute.String("output_gcs_bucket", outputGCSBucket),
+		attribute.Bool("concat_with_audio_crossfade", audioCrossfade),
 	)
+	if audioCrossfade {
+		span.SetAttributes(attribute.Float64("audio_crossfade_duration", crossfadeSeconds))
+	}
 
-	var localInputFilePaths []string
-	var inputCleanups []func()
-	defer func() {
-		for _, c := range inputCleanups {
-			c()
-		}
-	}()
-
-	for i, uri := range inputMediaURIs {
-		localPath, cleanup, errPrep := common.PrepareInputFile(ctx, uri, fmt.Sprintf("concat_input_%d", i), cfg.ProjectID)
-		if errPrep != nil {
-			span.RecordError(errPrep)
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input media file %s: %v", uri, errPrep)), nil
-		}
-		inputCleanups = append(inputCleanups, cleanup)
-		localInputFilePaths = append(localInputFilePaths, localPath)
+	localInputFilePaths, inputCleanup, errPrep := prepareInputFiles(ctx, inputMediaURIs, "concat_input", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input media file %v", errPrep)), nil
 	}
+	defer inputCleanup()
 
 	defaultOutputExt := "mp4"
 	if len(localInputFilePaths) > 0 {
@@ -736,9 +766,18 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 	defer outputProcessingCleanup()
 
 	isOutputWav := strings.ToLower(defaultOutputExt) == "wav"
+	crossfadeApplied := false
+	if isOutputWav && audioCrossfade {
+		return mcp.NewToolResultError("Error: concat_with_audio_crossfade is not supported for WAV output, which is joined without re-encoding. Choose a different output format (e.g., M4A, MP4)."), nil
+	}
+	if audioCrossfade && !isOutputWav {
+		if _, err := crossfadeAudioCodecArgs(defaultOutputExt, false); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+	}
 
 	if isOutputWav {
-		log.Println("Output is WAV. Checking if all inputs are compatible PCM WAV for direct concatenation.")
+		common.LogInfof("Output is WAV. Checking if all inputs are compatible PCM WAV for direct concatenation.")
 		allInputsAreCompatiblePcmWav := true
 		var firstPcmInfo struct {
 			SampleFmt   string
@@ -754,11 +793,11 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 		}
 
 		for i, path := range localInputFilePaths {
-			log.Printf("Checking codec and properties for input %d: %s", i+1, path)
+			common.LogDebugf("Checking codec and properties for input %d: %s", i+1, path)
 			mediaInfoJSON, ffprobeErr := executeGetMediaInfo(ctx, path)
 			if ffprobeErr != nil {
 				allInputsAreCompatiblePcmWav = false
-				log.Printf("Failed to get media info for input %s: %v. Cannot ensure PCM WAV compatibility.", path, ffprobeErr)
+				common.LogWarnf("Failed to get media info for input %s: %v. Cannot ensure PCM WAV compatibility.", path, ffprobeErr)
 				break
 			}
 
@@ -773,7 +812,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 			}
 			if err := json.Unmarshal([]byte(mediaInfoJSON), &info); err != nil {
 				allInputsAreCompatiblePcmWav = false
-				log.Printf("Failed to parse media info for input %s: %v. Cannot ensure PCM WAV compatibility.", path, err)
+				common.LogWarnf("Failed to parse media info for input %s: %v. Cannot ensure PCM WAV compatibility.", path, err)
 				break
 			}
 
@@ -789,7 +828,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 			for _, stream := range info.Streams {
 				if stream.CodecType == "audio" {
 					audioStreamFound = true
-					log.Printf("Audio stream found for %s: codec_name='%s', sample_fmt='%s', sample_rate='%s', channels=%d",
+					common.LogDebugf("Audio stream found for %s: codec_name='%s', sample_fmt='%s', sample_rate='%s', channels=%d",
 						path, stream.CodecName, stream.SampleFmt, stream.SampleRate, stream.Channels)
 					if strings.HasPrefix(stream.CodecName, "pcm_") {
 						isCurrentFilePcm = true
@@ -806,12 +845,12 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 
 			if !audioStreamFound {
 				allInputsAreCompatiblePcmWav = false
-				log.Printf("No audio stream found in input %s. Cannot treat as compatible PCM WAV.", path)
+				common.LogInfof("No audio stream found in input %s. Cannot treat as compatible PCM WAV.", path)
 				break
 			}
 			if !isCurrentFilePcm {
 				allInputsAreCompatiblePcmWav = false
-				log.Printf("Input file %s is not PCM WAV (audio codec: %s).", path, currentStreamInfo.CodecName)
+				common.LogInfof("Input file %s is not PCM WAV (audio codec: %s).", path, currentStreamInfo.CodecName)
 				break
 			}
 
@@ -821,25 +860,25 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 				firstPcmInfo.Channels = currentStreamInfo.Channels
 				firstPcmInfo.CodecName = currentStreamInfo.CodecName
 				firstPcmInfo.Initialized = true
-				log.Printf("First PCM WAV input %s (%s) sets standard: SR=%s, Fmt=%s, Ch=%d",
+				common.LogDebugf("First PCM WAV input %s (%s) sets standard: SR=%s, Fmt=%s, Ch=%d",
 					path, firstPcmInfo.CodecName, firstPcmInfo.SampleRate, firstPcmInfo.SampleFmt, firstPcmInfo.Channels)
 			} else {
 				if currentStreamInfo.SampleRate != firstPcmInfo.SampleRate ||
 					currentStreamInfo.Channels != firstPcmInfo.Channels ||
 					currentStreamInfo.SampleFmt != firstPcmInfo.SampleFmt {
 					allInputsAreCompatiblePcmWav = false
-					log.Printf("Input PCM WAV file %s (%s, SR=%s, Fmt=%s, Ch=%d) is incompatible with the first PCM WAV file (%s, SR=%s, Fmt=%s, Ch=%d).",
+					common.LogWarnf("Input PCM WAV file %s (%s, SR=%s, Fmt=%s, Ch=%d) is incompatible with the first PCM WAV file (%s, SR=%s, Fmt=%s, Ch=%d).",
 						path, currentStreamInfo.CodecName, currentStreamInfo.SampleRate, currentStreamInfo.SampleFmt, currentStreamInfo.Channels,
 						firstPcmInfo.CodecName, firstPcmInfo.SampleRate, firstPcmInfo.SampleFmt, firstPcmInfo.Channels)
 					break
 				}
-				log.Printf("Input PCM WAV file %s is compatible with the first.", path)
+				common.LogDebugf("Input PCM WAV file %s is compatible with the first.", path)
 			}
 			actualPcmInputPaths = append(actualPcmInputPaths, path)
 		}
 
 		if allInputsAreCompatiblePcmWav && firstPcmInfo.Initialized {
-			log.Println("All inputs are compatible PCM WAV. Proceeding with direct PCM concatenation.")
+			common.LogInfof("All inputs are compatible PCM WAV. Proceeding with direct PCM concatenation.")
 
 			concatListTempDir, errListTempDir := os.MkdirTemp("", "concat_list_pcm_")
 			if errListTempDir != nil {
@@ -847,7 +886,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 				return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp dir for PCM concat list: %v", errListTempDir)), nil
 			}
 			defer func() {
-				log.Printf("Cleaning up PCM concat list temporary directory: %s", concatListTempDir)
+				common.LogDebugf("Cleaning up PCM concat list temporary directory: %s", concatListTempDir)
 				_ = os.RemoveAll(concatListTempDir)
 			}()
 
@@ -867,21 +906,21 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 			}
 
 			concatCmdArgs := []string{"-y", "-f", "concat", "-safe", "0", "-i", concatListPath, "-c", "copy", tempOutputFile}
-			log.Printf("Attempting direct PCM concatenation of WAV files using concat demuxer (-c copy).")
+			common.LogInfof("Attempting direct PCM concatenation of WAV files using concat demuxer (-c copy).")
 			_, ffmpegErr := runFFmpegCommand(ctx, concatCmdArgs...)
 			if ffmpegErr != nil {
 				span.RecordError(ffmpegErr)
 				return mcp.NewToolResultError(fmt.Sprintf("FFMpeg direct PCM WAV concatenation failed: %v. Ensure input WAVs have compatible PCM formats (sample rate, channels, bit depth).", ffmpegErr)), nil
 			}
-			log.Println("Direct PCM WAV concatenation successful.")
+			common.LogInfof("Direct PCM WAV concatenation successful.")
 
 		} else {
-			log.Println("Output is WAV, but not all inputs are compatible PCM WAV, or an error occurred checking. Rejecting operation.")
+			common.LogWarnf("Output is WAV, but not all inputs are compatible PCM WAV, or an error occurred checking. Rejecting operation.")
 			return mcp.NewToolResultError("Error: When outputting to WAV, all input files must be PCM WAV with identical characteristics (sample rate, sample format, and channel count). Please convert inputs to a common PCM WAV format or choose a different output format (e.g., M4A, MP4)."), nil
 		}
 
 	} else {
-		log.Println("Output is not WAV. Proceeding with standardization to MP4/AAC before concatenation.")
+		common.LogInfof("Output is not WAV. Proceeding with standardization to MP4/AAC before concatenation.")
 		var standardizedFiles []string
 		standardizationTempDir, errStdTempDir := os.MkdirTemp("", "concat_standardize_")
 		if errStdTempDir != nil {
@@ -889,7 +928,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp dir for standardization: %v", errStdTempDir)), nil
 		}
 		defer func() {
-			log.Printf("Cleaning up standardization temporary directory: %s", standardizationTempDir)
+			common.LogDebugf("Cleaning up standardization temporary directory: %s", standardizationTempDir)
 			_ = os.RemoveAll(standardizationTempDir)
 		}()
 
@@ -929,10 +968,10 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 
 			var standardizeCmdArgs []string
 			if isAudioOnly {
-				log.Printf("Standardizing audio-only input %d ('%s') to AAC in MP4 container: '%s'", i+1, localInputFile, standardizedOutputPath)
+				common.LogInfof("Standardizing audio-only input %d ('%s') to AAC in MP4 container: '%s'", i+1, localInputFile, standardizedOutputPath)
 				standardizeCmdArgs = []string{"-y", "-i", localInputFile, "-vn", "-c:a", "aac", "-ar", commonSampleRate, "-ac", commonChannels, "-b:a", "192k", standardizedOutputPath}
 			} else {
-				log.Printf("Standardizing video/mixed input %d ('%s') to H264/AAC in MP4 container: '%s'", i+1, localInputFile, standardizedOutputPath)
+				common.LogInfof("Standardizing video/mixed input %d ('%s') to H264/AAC in MP4 container: '%s'", i+1, localInputFile, standardizedOutputPath)
 				vfArgs := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:0:0,fps=%s", commonWidth, commonHeight, commonWidth, commonHeight, commonFPS)
 				standardizeCmdArgs = []string{"-y", "-i", localInputFile, "-vf", vfArgs, "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-c:a", "aac", "-ar", commonSampleRate, "-ac", commonChannels, "-b:a", "192k", standardizedOutputPath}
 			}
@@ -949,41 +988,70 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 			return mcp.NewToolResultError("No files were successfully standardized for concatenation."), nil
 		}
 
-		concatListTempDir, errListTempDir := os.MkdirTemp("", "concat_list_std_")
-		if errListTempDir != nil {
-			span.RecordError(errListTempDir)
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp dir for standardized concat list: %v", errListTempDir)), nil
-		}
-		defer func() {
-			log.Printf("Cleaning up standardized concat list temporary directory: %s", concatListTempDir)
-			_ = os.RemoveAll(concatListTempDir)
-		}()
+		if audioCrossfade && len(standardizedFiles) > 1 {
+			clips := make([]crossfadeClip, len(standardizedFiles))
+			for i, sf := range standardizedFiles {
+				info := probeOutput(ctx, sf)
+				clips[i] = crossfadeClip{DurationSeconds: info.DurationSeconds, HasVideo: info.VideoCodec != "", HasAudio: info.AudioCodec != ""}
+			}
+			filterGraph, errFilter := buildAudioCrossfadeConcatFilter(clips, crossfadeSeconds)
+			if errFilter != nil {
+				span.RecordError(errFilter)
+				return mcp.NewToolResultError(fmt.Sprintf("Cannot crossfade the audio of these inputs: %v", errFilter)), nil
+			}
+			audioCodecArgs, errCodec := crossfadeAudioCodecArgs(defaultOutputExt, clips[0].HasVideo)
+			if errCodec != nil {
+				span.RecordError(errCodec)
+				return mcp.NewToolResultError(fmt.Sprintf("Cannot crossfade the audio of these inputs: %v", errCodec)), nil
+			}
+			common.LogInfof("Concatenating standardized files with a %gs audio crossfade at each boundary.", crossfadeSeconds)
+			if _, ffmpegErr := executeAudioCrossfadeConcat(ctx, standardizedFiles, filterGraph, clips[0].HasVideo, audioCodecArgs, tempOutputFile); ffmpegErr != nil {
+				span.RecordError(ffmpegErr)
+				return mcp.NewToolResultError(fmt.Sprintf("FFMpeg concatenation with audio crossfade failed: %v", ffmpegErr)), nil
+			}
+			crossfadeApplied = true
+			common.LogInfof("Concatenation with audio crossfade successful.")
+		} else {
+			if audioCrossfade {
+				common.LogInfof("Only one input file provided; skipping the audio crossfade.")
+			}
+			concatListTempDir, errListTempDir := os.MkdirTemp("", "concat_list_std_")
+			if errListTempDir != nil {
+				span.RecordError(errListTempDir)
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp dir for standardized concat list: %v", errListTempDir)), nil
+			}
+			defer func() {
+				common.LogDebugf("Cleaning up standardized concat list temporary directory: %s", concatListTempDir)
+				_ = os.RemoveAll(concatListTempDir)
+			}()
 
-		concatListPath := filepath.Join(concatListTempDir, "concat_list_std.txt")
-		var fileListContent strings.Builder
-		for _, sf := range standardizedFiles {
-			absPath, absErr := filepath.Abs(sf)
-			if absErr != nil {
-				span.RecordError(absErr)
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to get absolute path for standardized file %s: %v", sf, absErr)), nil
+			concatListPath := filepath.Join(concatListTempDir, "concat_list_std.txt")
+			var fileListContent strings.Builder
+			for _, sf := range standardizedFiles {
+				absPath, absErr := filepath.Abs(sf)
+				if absErr != nil {
+					span.RecordError(absErr)
+					return mcp.NewToolResultError(fmt.Sprintf("Failed to get absolute path for standardized file %s: %v", sf, absErr)), nil
+				}
+				fmt.Fprintf(&fileListContent, "file '%s'\n", absPath)
+			}
+			if errWriteList := os.WriteFile(concatListPath, []byte(fileListContent.String()), 0644); errWriteList != nil {
+				span.RecordError(errWriteList)
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to write standardized concat list file: %v", errWriteList)), nil
 			}
-			fmt.Fprintf(&fileListContent, "file '%s'\n", absPath)
-		}
-		if errWriteList := os.WriteFile(concatListPath, []byte(fileListContent.String()), 0644); errWriteList != nil {
-			span.RecordError(errWriteList)
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to write standardized concat list file: %v", errWriteList)), nil
-		}
 
-		concatDemuxerCmdArgs := []string{"-y", "-f", "concat", "-safe", "0", "-i", concatListPath, "-c", "copy", tempOutputFile}
-		log.Printf("Attempting concatenation of standardized files using concat demuxer (-c copy).")
-		_, ffmpegErr := runFFmpegCommand(ctx, concatDemuxerCmdArgs...)
-		if ffmpegErr != nil {
-			span.RecordError(ffmpegErr)
-			return mcp.NewToolResultError(fmt.Sprintf("FFMpeg concatenation (concat demuxer with -c copy) failed: %v", ffmpegErr)), nil
+			concatDemuxerCmdArgs := []string{"-y", "-f", "concat", "-safe", "0", "-i", concatListPath, "-c", "copy", tempOutputFile}
+			common.LogInfof("Attempting concatenation of standardized files using concat demuxer (-c copy).")
+			_, ffmpegErr := runFFmpegCommand(ctx, concatDemuxerCmdArgs...)
+			if ffmpegErr != nil {
+				span.RecordError(ffmpegErr)
+				return mcp.NewToolResultError(fmt.Sprintf("FFMpeg concatenation (concat demuxer with -c copy) failed: %v", ffmpegErr)), nil
+			}
+			common.LogInfof("Concatenation of standardized files successful.")
 		}
-		log.Println("Concatenation of standardized files successful.")
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -995,6 +1063,9 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 
 	var messageParts []string
 	messageParts = append(messageParts, fmt.Sprintf("Media concatenation completed in %v.", duration))
+	if crossfadeApplied {
+		messageParts = append(messageParts, fmt.Sprintf("Audio was crossfaded over %gs at each of the %d clip boundaries.", crossfadeSeconds, len(localInputFilePaths)-1))
+	}
 	if outputLocalDir != "" && finalLocalPath != "" {
 		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
 	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
@@ -1006,7 +1077,7 @@ func ffmpegConcatenateMediaHandler(ctx context.Context, request mcp.CallToolRequ
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing, or an issue occurred.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_concatenate_media_files", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addAdjustVolumeTool defines and registers the 'ffmpeg_adjust_volume' tool.
@@ -1020,7 +1091,7 @@ func addAdjustVolumeTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output audio file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output audio file to.")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegAdjustVolumeHandler(ctx, request, cfg)
 	})
 }
@@ -1038,7 +1109,7 @@ func ffmpegAdjustVolumeHandler(ctx context.Context, request mcp.CallToolRequest,
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_adjust_volume", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_adjust_volume", argsMap)
 
 	inputAudioURI, _ := argsMap["input_audio_uri"].(string)
 	volumeDBChangeFloat, paramOK := argsMap["volume_db_change"].(float64)
@@ -1053,7 +1124,7 @@ func ffmpegAdjustVolumeHandler(ctx context.Context, request mcp.CallToolRequest,
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_adjust_volume: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_adjust_volume: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -1106,6 +1177,7 @@ func ffmpegAdjustVolumeHandler(ctx context.Context, request mcp.CallToolRequest,
 		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg adjust volume failed: %v", ffmpegErr)), nil
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -1128,7 +1200,7 @@ func ffmpegAdjustVolumeHandler(ctx context.Context, request mcp.CallToolRequest,
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_adjust_volume", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
 
 // addLayerAudioTool defines and registers the 'ffmpeg_layer_audio_files' tool.
@@ -1141,7 +1213,7 @@ func addLayerAudioTool(s *server.MCPServer, cfg *common.Config) {
 		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
 		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to.")),
 	)
-	s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return ffmpegLayerAudioHandler(ctx, request, cfg)
 	})
 
@@ -1203,7 +1275,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 		span.RecordError(err)
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	log.Printf("Handling %s request with arguments: %v", "ffmpeg_layer_audio_files", argsMap)
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_layer_audio_files", argsMap)
 
 	inputAudioURIsRaw, _ := argsMap["input_audio_uris"].([]interface{})
 	var inputAudioURIs []string
@@ -1220,7 +1292,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 
 	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
 		outputGCSBucket = cfg.GenmediaBucket
-		log.Printf("Handler ffmpeg_layer_audio_files: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+		common.LogInfof("Handler ffmpeg_layer_audio_files: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
 	}
 	if outputGCSBucket != "" {
 		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
@@ -1229,7 +1301,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 		if len(inputAudioURIs) == 0 {
 			return mcp.NewToolResultError("At least one audio file is required for layering."), nil
 		}
-		log.Println("Warning: Only one input file provided for layering. The 'layering' will essentially be a copy or re-encode of this single file.")
+		common.LogWarnf("Warning: Only one input file provided for layering. The 'layering' will essentially be a copy or re-encode of this single file.")
 	}
 
 	span.SetAttributes(
@@ -1239,23 +1311,15 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 		attribute.String("output_gcs_bucket", outputGCSBucket),
 	)
 
-	var localInputFiles []string
-	var inputCleanups []func()
-	defer func() {
-		for _, c := range inputCleanups {
-			c()
-		}
-	}()
+	localInputFiles, inputCleanup, errPrep := prepareInputFiles(ctx, inputAudioURIs, "layer_input", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input audio file %v", errPrep)), nil
+	}
+	defer inputCleanup()
 
 	var ffmpegInputArgs []string
-	for i, uri := range inputAudioURIs {
-		localPath, cleanup, errPrep := common.PrepareInputFile(ctx, uri, fmt.Sprintf("layer_input_%d", i), cfg.ProjectID)
-		if errPrep != nil {
-			span.RecordError(errPrep)
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input audio file %s: %v", uri, errPrep)), nil
-		}
-		inputCleanups = append(inputCleanups, cleanup)
-		localInputFiles = append(localInputFiles, localPath)
+	for _, localPath := range localInputFiles {
 		ffmpegInputArgs = append(ffmpegInputArgs, "-i", localPath)
 	}
 
@@ -1289,7 +1353,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 		commandArgs = append(commandArgs, "-filter_complex", amixFilter, tempOutputFile)
 	} else if len(localInputFiles) == 1 {
 		commandArgs = append(commandArgs, "-c:a", "copy", tempOutputFile)
-		log.Println("Layering with single input: attempting codec copy. FFMpeg may re-encode if necessary for container.")
+		common.LogInfof("Layering with single input: attempting codec copy. FFMpeg may re-encode if necessary for container.")
 	} else {
 		return mcp.NewToolResultError("No input files for layering."), nil
 	}
@@ -1297,7 +1361,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 	_, ffmpegErr := runFFmpegCommand(ctx, commandArgs...)
 	if ffmpegErr != nil {
 		if len(localInputFiles) == 1 && strings.Contains(ffmpegErr.Error(), "could not find tag for codec") || strings.Contains(ffmpegErr.Error(), "does not support stream copying") {
-			log.Printf("Codec copy failed for single file layering, attempting re-encode. Original error: %v", ffmpegErr)
+			common.LogWarnf("Codec copy failed for single file layering, attempting re-encode. Original error: %v", ffmpegErr)
 			var reencodeArgs []string
 			reencodeArgs = append(reencodeArgs, "-y", "-i", localInputFiles[0])
 			if defaultOutputExt == "wav" {
@@ -1313,6 +1377,7 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 		}
 	}
 
+	output := probeOutput(ctx, tempOutputFile)
 	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
 	if processErr != nil {
 		span.RecordError(processErr)
@@ -1335,5 +1400,1457 @@ func ffmpegLayerAudioHandler(ctx context.Context, request mcp.CallToolRequest, c
 	if len(messageParts) == 1 {
 		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
 	}
-	return mcp.NewToolResultText(strings.Join(messageParts, " ")), nil
+	return newAvtoolResult(ctx, "ffmpeg_layer_audio_files", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addCompareImagesTool defines and registers the 'compare_images' tool.
+// This tool computes a perceptual similarity score between two images for QA of generated media.
+func addCompareImagesTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("compare_images",
+		mcp.WithDescription("Compares two images and returns a similarity score (SSIM, 0.0-1.0) and the mean pixel difference. Optionally produces a diff visualization image and a pass/fail verdict against a threshold. If the images differ in size, the second image is scaled to the size of the first."),
+		mcp.WithString("image_a_uri", mcp.Required(), mcp.Description("URI of the reference image (local path or gs://). PNG, JPEG, GIF, and WebP are supported.")),
+		mcp.WithString("image_b_uri", mcp.Required(), mcp.Description("URI of the image to compare against the reference (local path or gs://).")),
+		mcp.WithNumber("threshold", mcp.Min(0), mcp.Max(1), mcp.Description("Optional. Minimum SSIM score (0.0-1.0) for the comparison to pass. If omitted, no pass/fail verdict is returned.")),
+		mcp.WithBoolean("generate_diff_image", mcp.DefaultBool(false), mcp.Description("Optional. If true, writes a PNG highlighting per-pixel differences.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the diff PNG file. If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the diff PNG file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the diff PNG file to.")),
+	)
+	common.AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return compareImagesHandler(ctx, request, cfg)
+	})
+}
+
+// compareImagesHandler is the handler for the 'compare_images' tool.
+// It loads both images, computes SSIM and mean pixel difference, and optionally saves a diff visualization.
+func compareImagesHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "compare_images")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "compare_images", argsMap)
+
+	imageAURI, _ := argsMap["image_a_uri"].(string)
+	imageBURI, _ := argsMap["image_b_uri"].(string)
+	if strings.TrimSpace(imageAURI) == "" || strings.TrimSpace(imageBURI) == "" {
+		return mcp.NewToolResultError("Parameters 'image_a_uri' and 'image_b_uri' are required."), nil
+	}
+	threshold, hasThreshold := argsMap["threshold"].(float64)
+	if hasThreshold && (threshold < 0 || threshold > 1) {
+		return mcp.NewToolResultError("Parameter 'threshold' must be between 0.0 and 1.0."), nil
+	}
+	generateDiff, _ := argsMap["generate_diff_image"].(bool)
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+
+	if generateDiff && outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler compare_images: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("image_a_uri", imageAURI),
+		attribute.String("image_b_uri", imageBURI),
+		attribute.Bool("generate_diff_image", generateDiff),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localImageA, cleanupA, err := common.PrepareInputFile(ctx, imageAURI, "compare_image_a", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare image A: %v", err)), nil
+	}
+	defer cleanupA()
+
+	localImageB, cleanupB, err := common.PrepareInputFile(ctx, imageBURI, "compare_image_b", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare image B: %v", err)), nil
+	}
+	defer cleanupB()
+
+	imgA, err := decodeImageFile(localImageA)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode image A: %v", err)), nil
+	}
+	imgB, err := decodeImageFile(localImageB)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode image B: %v", err)), nil
+	}
+
+	result := compareImages(imgA, imgB, generateDiff)
+
+	span.SetAttributes(
+		attribute.Float64("ssim", result.SSIM),
+		attribute.Float64("mean_pixel_diff", result.MeanPixelDiff),
+	)
+
+	var messageParts []string
+	messageParts = append(messageParts, fmt.Sprintf("Image comparison completed in %v.", time.Since(startTime)))
+	messageParts = append(messageParts, fmt.Sprintf("SSIM: %.4f. Mean pixel difference: %.4f (0.0 = identical, 1.0 = maximally different).", result.SSIM, result.MeanPixelDiff))
+	if result.ResizedForCompare {
+		messageParts = append(messageParts, fmt.Sprintf("Image B was scaled to %dx%d to match image A.", result.Width, result.Height))
+	}
+	if hasThreshold {
+		verdict := "FAIL"
+		if result.SSIM >= threshold {
+			verdict = "PASS"
+		}
+		span.SetAttributes(attribute.String("verdict", verdict))
+		messageParts = append(messageParts, fmt.Sprintf("Result: %s (threshold %.4f).", verdict, threshold))
+	}
+
+	details := imageComparisonDetails{SSIM: result.SSIM, MeanPixelDiff: result.MeanPixelDiff, ResizedForCompare: result.ResizedForCompare}
+	if hasThreshold {
+		passed := result.SSIM >= threshold
+		details.Threshold, details.Passed = &threshold, &passed
+	}
+	var outputs []avtoolOutput
+	if generateDiff {
+		tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "png")
+		if err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+		}
+		defer outputCleanup()
+
+		if err := writePNG(tempOutputFile, result.DiffVisualization); err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to write diff image: %v", err)), nil
+		}
+
+		diffOutput := avtoolOutput{Format: "png", Width: result.Width, Height: result.Height}
+		if info, err := os.Stat(tempOutputFile); err == nil {
+			diffOutput.SizeBytes = info.Size()
+		}
+		finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+		if processErr != nil {
+			span.RecordError(processErr)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to process diff image output: %v", processErr)), nil
+		}
+		if outputLocalDir != "" || finalGCSPath != "" {
+			outputs = append(outputs, diffOutput.savedTo(outputLocalDir, finalLocalPath, finalGCSPath))
+		}
+		if outputLocalDir != "" && finalLocalPath != "" {
+			messageParts = append(messageParts, fmt.Sprintf("Diff image saved locally to: %s.", finalLocalPath))
+		}
+		if finalGCSPath != "" {
+			messageParts = append(messageParts, fmt.Sprintf("Diff image uploaded to GCS: %s.", finalGCSPath))
+		}
+		if outputLocalDir == "" && finalGCSPath == "" {
+			messageParts = append(messageParts, "Diff image was generated but no output location was specified, so it was discarded.")
+		}
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+	return newAvtoolResult(ctx, "compare_images", strings.Join(messageParts, " "), duration, outputs, details), nil
+}
+
+// addScaleVideoTool defines and registers the 'ffmpeg_scale_video' tool.
+// This tool changes the resolution of a video, e.g. downscaling 1080p Veo output to 720p.
+func addScaleVideoTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_scale_video",
+		mcp.WithDescription("Scales (changes the resolution of) a video and re-encodes it as H.264. Provide exactly one of 'resolution', 'width'/'height', or 'scale_factor'."),
+		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the input video file (local path or gs://).")),
+		mcp.WithString("resolution", mcp.Enum("2160p", "1440p", "1080p", "720p", "480p", "360p"), mcp.Description("Optional. Target resolution by height (e.g., '720p'). Width is derived from the aspect ratio.")),
+		mcp.WithNumber("width", mcp.Description("Optional. Target width in pixels (even number). If only width is given, height is derived from the aspect ratio.")),
+		mcp.WithNumber("height", mcp.Description("Optional. Target height in pixels (even number). If only height is given, width is derived from the aspect ratio.")),
+		mcp.WithNumber("scale_factor", mcp.Description("Optional. Factor to scale both dimensions by (e.g., 0.5 for half size). Must be greater than 0 and at most 4.")),
+		mcp.WithBoolean("preserve_aspect_ratio", mcp.DefaultBool(true), mcp.Description("Optional. When both width and height are given, fit the video within them without distortion. Set to false to stretch to the exact size.")),
+		mcp.WithString("preset", mcp.DefaultString("medium"), mcp.Enum(x264Presets...), mcp.Description("Optional. x264 encoding preset. Slower presets produce smaller files at the same quality.")),
+		mcp.WithNumber("crf", mcp.DefaultNumber(23), mcp.Min(0), mcp.Max(51), mcp.Description("Optional. x264 constant rate factor (0-51). Lower values mean higher quality and larger files.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'video_720p.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegScaleVideoHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegScaleVideoHandler is the handler for the video scaling tool.
+// It validates the requested target size, then re-encodes the video with FFmpeg's scale filter.
+func ffmpegScaleVideoHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_scale_video")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_scale_video", argsMap)
+
+	inputVideoURI, _ := argsMap["input_video_uri"].(string)
+	if strings.TrimSpace(inputVideoURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_video_uri' is required."), nil
+	}
+
+	opts := scaleOptions{PreserveAspectRatio: true}
+	opts.Resolution, _ = argsMap["resolution"].(string)
+	if width, ok := argsMap["width"].(float64); ok {
+		opts.Width = int(width)
+	}
+	if height, ok := argsMap["height"].(float64); ok {
+		opts.Height = int(height)
+	}
+	opts.ScaleFactor, _ = argsMap["scale_factor"].(float64)
+	if preserve, ok := argsMap["preserve_aspect_ratio"].(bool); ok {
+		opts.PreserveAspectRatio = preserve
+	}
+	scaleFilter, err := buildScaleFilter(opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid scale parameters: %v", err)), nil
+	}
+
+	preset, _ := argsMap["preset"].(string)
+	if preset == "" {
+		preset = "medium"
+	}
+	crf := 23
+	if crfParam, ok := argsMap["crf"].(float64); ok {
+		if crfParam < 0 || crfParam > 51 {
+			return mcp.NewToolResultError(fmt.Sprintf("Parameter 'crf' must be between 0 and 51, got %v.", crfParam)), nil
+		}
+		crf = int(crfParam)
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_scale_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_video_uri", inputVideoURI),
+		attribute.String("scale_filter", scaleFilter),
+		attribute.String("preset", preset),
+		attribute.Int("crf", crf),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputVideo, inputCleanup, err := common.PrepareInputFile(ctx, inputVideoURI, "input_video_scale", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input video: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeScaleVideo(ctx, localInputVideo, tempOutputFile, scaleFilter, preset, crf); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg scale video failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	var messageParts []string
+	messageParts = append(messageParts, fmt.Sprintf("Video scaling (%s, preset %s, crf %d) completed in %v.", scaleFilter, preset, crf, duration))
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	if len(messageParts) == 1 {
+		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
+	}
+	return newAvtoolResult(ctx, "ffmpeg_scale_video", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addMixAudioTool defines and registers the 'ffmpeg_mix_audio' tool.
+// Unlike 'ffmpeg_layer_audio_files', each track can be given its own gain and start offset.
+func addMixAudioTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_mix_audio",
+		mcp.WithDescription("Mixes multiple audio tracks into a single track, with a per-track gain (dB) and optional start offset. For example, narration at 0 dB over music at -12 dB. A limiter prevents the mix from clipping."),
+		mcp.WithArray("tracks", mcp.Required(), mcp.Description("Array of tracks to mix. Each track is an object with 'uri' (local path or gs://), optional 'gain_db' (-60 to 24, default 0), and optional 'start_offset_seconds' (default 0)."), mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"uri":                  map[string]any{"type": "string", "description": "URI of the audio file (local path or gs://)."},
+				"gain_db":              map[string]any{"type": "number", "description": "Gain applied to the track in dB. Negative values make it quieter."},
+				"start_offset_seconds": map[string]any{"type": "number", "description": "Delay before the track starts in the mix, in seconds."},
+			},
+			"required": []string{"uri"},
+		})),
+		mcp.WithString("duration", mcp.DefaultString("longest"), mcp.Enum(mixDurationModes...), mcp.Description("Optional. Length of the mix: the 'longest' track, the 'shortest' track, or the 'first' track.")),
+		mcp.WithBoolean("prevent_clipping", mcp.DefaultBool(true), mcp.Description("Optional. Apply a limiter so that the summed tracks do not clip. Defaults to true.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output mixed audio file (e.g., 'mix.mp3'). The extension selects the output format.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegMixAudioHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegMixAudioHandler is the handler for the audio mixing tool.
+// It applies each track's gain and offset and mixes them with FFmpeg's amix filter.
+func ffmpegMixAudioHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_mix_audio")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_mix_audio", argsMap)
+
+	tracksRaw, _ := argsMap["tracks"].([]interface{})
+	if len(tracksRaw) == 0 {
+		return mcp.NewToolResultError("Parameter 'tracks' must contain at least one track."), nil
+	}
+	var tracks []audioMixTrack
+	for i, item := range tracksRaw {
+		trackMap, ok := item.(map[string]interface{})
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Track %d must be an object with a 'uri' field.", i)), nil
+		}
+		var track audioMixTrack
+		track.URI, _ = trackMap["uri"].(string)
+		if strings.TrimSpace(track.URI) == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("Track %d is missing 'uri'.", i)), nil
+		}
+		track.GainDB, _ = trackMap["gain_db"].(float64)
+		track.StartOffset, _ = trackMap["start_offset_seconds"].(float64)
+		tracks = append(tracks, track)
+	}
+
+	durationMode, _ := argsMap["duration"].(string)
+	if durationMode == "" {
+		durationMode = "longest"
+	}
+	preventClipping := true
+	if v, ok := argsMap["prevent_clipping"].(bool); ok {
+		preventClipping = v
+	}
+	filterGraph, err := buildMixAudioFilter(tracks, durationMode, preventClipping)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid mix parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_mix_audio: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.Int("num_tracks", len(tracks)),
+		attribute.String("filter_graph", filterGraph),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	var trackURIs []string
+	for _, track := range tracks {
+		trackURIs = append(trackURIs, track.URI)
+	}
+	localInputFiles, inputCleanup, errPrep := prepareInputFiles(ctx, trackURIs, "mix_input", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input audio file %v", errPrep)), nil
+	}
+	defer inputCleanup()
+
+	defaultOutputExt := "mp3"
+	if userExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFileName), ".")); userExt != "" {
+		defaultOutputExt = userExt
+	}
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, defaultOutputExt)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeMixAudio(ctx, localInputFiles, tempOutputFile, filterGraph); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg audio mixing failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	var messageParts []string
+	messageParts = append(messageParts, fmt.Sprintf("Audio mix of %d tracks completed in %v.", len(tracks), duration))
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	if len(messageParts) == 1 {
+		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
+	}
+	return newAvtoolResult(ctx, "ffmpeg_mix_audio", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addSidechainDuckTool defines and registers the 'ffmpeg_sidechain_duck' tool.
+func addSidechainDuckTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_sidechain_duck",
+		mcp.WithDescription("Mixes background music under a narration track, automatically lowering (ducking) the music while the narration is speaking and restoring it during pauses. Uses FFMpeg's sidechaincompress filter keyed on the narration."),
+		mcp.WithString("narration_uri", mcp.Required(), mcp.Description("URI of the narration audio file (local path or gs://). Its level controls the ducking.")),
+		mcp.WithString("music_uri", mcp.Required(), mcp.Description("URI of the background music audio file (local path or gs://).")),
+		mcp.WithNumber("music_gain_db", mcp.DefaultNumber(0), mcp.Description("Optional. Gain applied to the music before ducking, in dB (-60 to 24). Defaults to 0.")),
+		mcp.WithNumber("threshold_db", mcp.DefaultNumber(defaultDuckThresholdDB), mcp.Description("Optional. Narration level, in dB (-60 to 0), above which the music is ducked. Lower values duck on quieter speech. Defaults to -30.")),
+		mcp.WithNumber("ratio", mcp.DefaultNumber(defaultDuckRatio), mcp.Description("Optional. Compression ratio applied to the music while ducked (1 to 20). Higher values duck more. Defaults to 8.")),
+		mcp.WithNumber("attack_ms", mcp.DefaultNumber(defaultDuckAttackMs), mcp.Description("Optional. How quickly the music is lowered when narration starts, in milliseconds (0.01 to 2000). Defaults to 20.")),
+		mcp.WithNumber("release_ms", mcp.DefaultNumber(defaultDuckReleaseMs), mcp.Description("Optional. How quickly the music recovers after narration stops, in milliseconds (0.01 to 9000). Defaults to 250.")),
+		mcp.WithString("duration", mcp.DefaultString("longest"), mcp.Enum(mixDurationModes...), mcp.Description("Optional. Length of the output: the 'longest' track, the 'shortest' track, or the 'first' (narration) track.")),
+		mcp.WithBoolean("prevent_clipping", mcp.DefaultBool(true), mcp.Description("Optional. Apply a limiter so that the mix does not clip. Defaults to true.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output audio file (e.g., 'narrated.mp3'). The extension selects the output format.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegSidechainDuckHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegSidechainDuckHandler is the handler for the ducking tool.
+// It compresses the music with the narration as the sidechain key and mixes the result with the narration.
+func ffmpegSidechainDuckHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_sidechain_duck")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_sidechain_duck", argsMap)
+
+	narrationURI, _ := argsMap["narration_uri"].(string)
+	musicURI, _ := argsMap["music_uri"].(string)
+	if strings.TrimSpace(narrationURI) == "" || strings.TrimSpace(musicURI) == "" {
+		return mcp.NewToolResultError("Parameters 'narration_uri' and 'music_uri' are required."), nil
+	}
+
+	settings := sidechainDuckSettings{
+		ThresholdDB: defaultDuckThresholdDB,
+		Ratio:       defaultDuckRatio,
+		AttackMs:    defaultDuckAttackMs,
+		ReleaseMs:   defaultDuckReleaseMs,
+	}
+	settings.MusicGainDB, _ = argsMap["music_gain_db"].(float64)
+	if v, ok := argsMap["threshold_db"].(float64); ok {
+		settings.ThresholdDB = v
+	}
+	if v, ok := argsMap["ratio"].(float64); ok {
+		settings.Ratio = v
+	}
+	if v, ok := argsMap["attack_ms"].(float64); ok {
+		settings.AttackMs = v
+	}
+	if v, ok := argsMap["release_ms"].(float64); ok {
+		settings.ReleaseMs = v
+	}
+	durationMode, _ := argsMap["duration"].(string)
+	if durationMode == "" {
+		durationMode = "longest"
+	}
+	preventClipping := true
+	if v, ok := argsMap["prevent_clipping"].(bool); ok {
+		preventClipping = v
+	}
+	filterGraph, err := buildSidechainDuckFilter(settings, durationMode, preventClipping)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid ducking parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_sidechain_duck: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("narration_uri", narrationURI),
+		attribute.String("music_uri", musicURI),
+		attribute.String("filter_graph", filterGraph),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localNarration, narrationCleanup, errPrep := common.PrepareInputFile(ctx, narrationURI, "duck_narration", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare narration audio file %s: %v", narrationURI, errPrep)), nil
+	}
+	defer narrationCleanup()
+	localMusic, musicCleanup, errPrep := common.PrepareInputFile(ctx, musicURI, "duck_music", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare music audio file %s: %v", musicURI, errPrep)), nil
+	}
+	defer musicCleanup()
+
+	defaultOutputExt := "mp3"
+	if userExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFileName), ".")); userExt != "" {
+		defaultOutputExt = userExt
+	}
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, defaultOutputExt)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeMixAudio(ctx, []string{localNarration, localMusic}, tempOutputFile, filterGraph); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg ducking failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	var messageParts []string
+	messageParts = append(messageParts, fmt.Sprintf("Music ducked under narration (threshold %g dB, ratio %g:1) in %v.", settings.ThresholdDB, settings.Ratio, duration))
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	if len(messageParts) == 1 {
+		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
+	}
+	return newAvtoolResult(ctx, "ffmpeg_sidechain_duck", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addChromaKeyTool defines and registers the 'ffmpeg_chroma_key' tool.
+func addChromaKeyTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_chroma_key",
+		mcp.WithDescription("Removes a green screen or other solid background from a video with FFMpeg's chromakey or colorkey filter. The result is either a video with transparency or the subject composited over a provided background image or video."),
+		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the video to key (local path or gs://).")),
+		mcp.WithString("background_uri", mcp.Description("Optional. URI of a background image or video (local path or gs://) to composite the subject over. It is scaled and cropped to cover the frame, and a background video is looped for the length of the input. If not provided, the output keeps an alpha channel.")),
+		mcp.WithString("key_color", mcp.DefaultString("green"), mcp.Description("Optional. The background color to remove: 'green', 'blue', or a hex color such as '#00FF00'. Defaults to green.")),
+		mcp.WithNumber("similarity", mcp.DefaultNumber(defaultChromaKeySimilarity), mcp.Description("Optional. How close to the key color a pixel must be to become transparent (0.00001 to 1). Raise it if background remains; lower it if the subject is eaten away. Defaults to 0.1.")),
+		mcp.WithNumber("blend", mcp.DefaultNumber(defaultChromaKeyBlend), mcp.Description("Optional. How softly the edges fade out (0 to 1). 0 makes pixels either fully transparent or fully opaque. Defaults to 0.05.")),
+		mcp.WithString("method", mcp.DefaultString("chromakey"), mcp.Enum(chromaKeyMethods...), mcp.Description("Optional. 'chromakey' compares color in YUV and suits filmed green screens; 'colorkey' compares RGB values and suits flat, generated backgrounds. Defaults to chromakey.")),
+		mcp.WithString("transparent_format", mcp.DefaultString("webm"), mcp.Enum("webm", "mov"), mcp.Description("Optional. Container for output with transparency, used when no background is given: 'webm' (VP9 with alpha) or 'mov' (ProRes 4444). An output_file_name with a .webm or .mov extension takes precedence. Defaults to webm.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'keyed.webm', or 'composited.mp4' with a background).")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegChromaKeyHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegChromaKeyHandler is the handler for the chroma key tool.
+// It keys out the background of the input video and either keeps the transparency or composites
+// the result over a background.
+func ffmpegChromaKeyHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_chroma_key")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_chroma_key", argsMap)
+
+	inputVideoURI, _ := argsMap["input_video_uri"].(string)
+	if strings.TrimSpace(inputVideoURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_video_uri' is required."), nil
+	}
+	backgroundURI, _ := argsMap["background_uri"].(string)
+	backgroundURI = strings.TrimSpace(backgroundURI)
+
+	opts := chromaKeyOptions{
+		Method:     "chromakey",
+		KeyColor:   defaultChromaKeyColor,
+		Similarity: defaultChromaKeySimilarity,
+		Blend:      defaultChromaKeyBlend,
+	}
+	if v, ok := argsMap["method"].(string); ok && v != "" {
+		opts.Method = v
+	}
+	if v, ok := argsMap["key_color"].(string); ok && strings.TrimSpace(v) != "" {
+		opts.KeyColor = v
+	}
+	if v, ok := argsMap["similarity"].(float64); ok {
+		opts.Similarity = v
+	}
+	if v, ok := argsMap["blend"].(float64); ok {
+		opts.Blend = v
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_chroma_key: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	// Without a background the output format must support an alpha channel.
+	outputExt := "mp4"
+	codecArgs := []string{"-c:v", "libx264", "-pix_fmt", "yuv420p", "-c:a", "aac", "-movflags", "+faststart"}
+	if backgroundURI == "" {
+		outputExt, _ = argsMap["transparent_format"].(string)
+		if userExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFileName), ".")); userExt != "" {
+			outputExt = userExt
+		} else if outputExt == "" {
+			outputExt = "webm"
+		}
+		format, ok := chromaKeyAlphaFormats[outputExt]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Output format '%s' does not support transparency. Use a .webm or .mov output, or provide a background_uri.", outputExt)), nil
+		}
+		opts.PixelFormat = format.PixelFormat
+		codecArgs = format.CodecArgs
+	}
+	if _, err := buildChromaKeyFilter(opts); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid chroma key parameters: %v", err)), nil
+	}
+
+	span.SetAttributes(
+		attribute.String("input_video_uri", inputVideoURI),
+		attribute.String("background_uri", backgroundURI),
+		attribute.String("method", opts.Method),
+		attribute.String("key_color", opts.KeyColor),
+		attribute.Float64("similarity", opts.Similarity),
+		attribute.Float64("blend", opts.Blend),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputVideo, videoCleanup, err := common.PrepareInputFile(ctx, inputVideoURI, "chroma_key_input", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input video: %v", err)), nil
+	}
+	defer videoCleanup()
+
+	var localBackground string
+	if backgroundURI != "" {
+		var backgroundCleanup func()
+		localBackground, backgroundCleanup, err = common.PrepareInputFile(ctx, backgroundURI, "chroma_key_background", cfg.ProjectID)
+		if err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare background: %v", err)), nil
+		}
+		defer backgroundCleanup()
+
+		// The background is fitted to the input's frame, so the input must be probed first.
+		probeJSON, err := executeGetMediaInfo(ctx, localInputVideo)
+		if err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to probe input video: %v", err)), nil
+		}
+		probed, err := parseProbeOutput(probeJSON)
+		if err != nil || probed.Width <= 0 || probed.Height <= 0 {
+			return mcp.NewToolResultError("Could not determine the input video's frame size."), nil
+		}
+		opts.Width, opts.Height = probed.Width, probed.Height
+	}
+	filterGraph, err := buildChromaKeyFilter(opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid chroma key parameters: %v", err)), nil
+	}
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, outputExt)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeChromaKey(ctx, localInputVideo, localBackground, isStillImageFile(localBackground), filterGraph, codecArgs, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg chroma key failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	var messageParts []string
+	if localBackground != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Background keyed out and composited over %s in %v.", backgroundURI, duration))
+	} else {
+		messageParts = append(messageParts, fmt.Sprintf("Background keyed out to a transparent %s video in %v.", outputExt, duration))
+	}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	if len(messageParts) == 1 {
+		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
+	}
+	return newAvtoolResult(ctx, "ffmpeg_chroma_key", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addInterpolateFPSTool defines and registers the 'ffmpeg_interpolate_fps' tool.
+func addInterpolateFPSTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_interpolate_fps",
+		mcp.WithDescription("Raises the frame rate of a video by synthesizing in-between frames with FFMpeg's minterpolate filter, e.g. to smooth a 24fps generated clip to 60fps. Interpolation is CPU-intensive: motion-compensated mode can take many times the clip's duration, so consider 'async' for long or high-resolution videos."),
+		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the input video file (local path or gs://).")),
+		mcp.WithNumber("target_fps", mcp.DefaultNumber(defaultInterpolationFPS), mcp.Min(1), mcp.Max(maxInterpolationFPS), mcp.Description("Optional. The frame rate of the output video. Defaults to 60.")),
+		mcp.WithString("mode", mcp.DefaultString("motion"), mcp.Enum("blend", "motion"), mcp.Description("Optional. 'motion' estimates motion to synthesize new frames, which is smoothest but slowest. 'blend' cross-fades neighboring frames, which is much faster but ghosts on fast motion. Defaults to motion.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'video_60fps.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegInterpolateFPSHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegInterpolateFPSHandler is the handler for the frame interpolation tool.
+// It re-encodes the video at the target frame rate with interpolated frames.
+func ffmpegInterpolateFPSHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_interpolate_fps")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_interpolate_fps", argsMap)
+
+	inputVideoURI, _ := argsMap["input_video_uri"].(string)
+	if strings.TrimSpace(inputVideoURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_video_uri' is required."), nil
+	}
+	targetFPS := float64(defaultInterpolationFPS)
+	if v, ok := argsMap["target_fps"].(float64); ok {
+		targetFPS = v
+	}
+	mode := "motion"
+	if v, ok := argsMap["mode"].(string); ok && v != "" {
+		mode = v
+	}
+	filter, err := buildInterpolateFilter(targetFPS, mode)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid interpolation parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_interpolate_fps: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_video_uri", inputVideoURI),
+		attribute.Float64("target_fps", targetFPS),
+		attribute.String("mode", mode),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputVideo, inputCleanup, err := common.PrepareInputFile(ctx, inputVideoURI, "interpolate_input", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input video: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if mode == "motion" {
+		common.LogWarnf("Handler ffmpeg_interpolate_fps: motion-compensated interpolation of %s to %g fps may take a long time.", inputVideoURI, targetFPS)
+	}
+	if _, ffmpegErr := executeInterpolateFPS(ctx, localInputVideo, tempOutputFile, filter); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg frame interpolation failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	messageParts := []string{fmt.Sprintf("Video interpolated to %g fps (%s mode) in %v.", targetFPS, mode, duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	if len(messageParts) == 1 {
+		messageParts = append(messageParts, "No specific output location requested beyond temporary processing.")
+	}
+	return newAvtoolResult(ctx, "ffmpeg_interpolate_fps", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addTrimToSceneTool defines and registers the 'ffmpeg_trim_to_scene' tool.
+func addTrimToSceneTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_trim_to_scene",
+		mcp.WithDescription("Detects scene cuts in a video using FFMpeg's scene-change score and returns their timestamps. Optionally splits the video into one file per scene."),
+		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the input video file (local path or gs://).")),
+		mcp.WithNumber("threshold", mcp.DefaultNumber(defaultSceneThreshold), mcp.Min(0), mcp.Max(1), mcp.Description("Optional. Scene-change score (between 0 and 1) above which a frame is treated as a cut. Lower values detect more cuts.")),
+		mcp.WithNumber("min_scene_seconds", mcp.DefaultNumber(defaultMinSceneSeconds), mcp.Min(0), mcp.Description("Optional. Minimum length of a scene in seconds. Cuts closer than this to the previous cut are ignored.")),
+		mcp.WithBoolean("split", mcp.DefaultBool(false), mcp.Description("Optional. If true, the video is split at the detected cuts into one MP4 file per scene.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Base name for the scene files when splitting (e.g., 'scene' produces 'scene_001.mp4', 'scene_002.mp4', ...). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the scene files.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the scene files to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegTrimToSceneHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegTrimToSceneHandler is the handler for the scene detection tool.
+// It reports the detected cut timestamps and, if requested, re-encodes each scene to its own file.
+func ffmpegTrimToSceneHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_trim_to_scene")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_trim_to_scene", argsMap)
+
+	inputVideoURI, _ := argsMap["input_video_uri"].(string)
+	if strings.TrimSpace(inputVideoURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_video_uri' is required."), nil
+	}
+
+	threshold := defaultSceneThreshold
+	if thresholdParam, ok := argsMap["threshold"].(float64); ok {
+		threshold = thresholdParam
+	}
+	sceneFilter, err := buildSceneDetectFilter(threshold)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid scene detection parameters: %v", err)), nil
+	}
+	minSceneSeconds := defaultMinSceneSeconds
+	if minParam, ok := argsMap["min_scene_seconds"].(float64); ok {
+		if minParam < 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Parameter 'min_scene_seconds' must not be negative, got %v.", minParam)), nil
+		}
+		minSceneSeconds = minParam
+	}
+	split, _ := argsMap["split"].(bool)
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if split && outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_trim_to_scene: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_video_uri", inputVideoURI),
+		attribute.Float64("threshold", threshold),
+		attribute.Float64("min_scene_seconds", minSceneSeconds),
+		attribute.Bool("split", split),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputVideo, inputCleanup, err := common.PrepareInputFile(ctx, inputVideoURI, "input_video_scene", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input video: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	detectOutput, ffmpegErr := executeDetectScenes(ctx, localInputVideo, sceneFilter)
+	if ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg scene detection failed: %v", ffmpegErr)), nil
+	}
+	cuts := parseSceneCuts(detectOutput, minSceneSeconds)
+	span.SetAttributes(attribute.Int("scene_cut_count", len(cuts)))
+
+	cutStrings := make([]string, len(cuts))
+	for i, cut := range cuts {
+		cutStrings[i] = strconv.FormatFloat(cut, 'f', 3, 64)
+	}
+
+	var messageParts []string
+	if len(cuts) == 0 {
+		messageParts = append(messageParts, fmt.Sprintf("No scene cuts detected at threshold %g.", threshold))
+	} else {
+		messageParts = append(messageParts, fmt.Sprintf("Detected %d scene cut(s) at threshold %g at timestamps (seconds): %s.", len(cuts), threshold, strings.Join(cutStrings, ", ")))
+	}
+
+	details := sceneDetectionDetails{Threshold: threshold, CutsSeconds: cuts}
+	if details.CutsSeconds == nil {
+		details.CutsSeconds = []float64{}
+	}
+	var outputs []avtoolOutput
+	if split {
+		baseName := strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		if baseName == "" {
+			uid, _ := shortid.Generate()
+			baseName = fmt.Sprintf("scene_%s", uid)
+		}
+		segments := sceneSegments(cuts)
+		for i, segment := range segments {
+			segmentName := fmt.Sprintf("%s_%03d.mp4", baseName, i+1)
+			tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(segmentName, "mp4")
+			if err != nil {
+				span.RecordError(err)
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+			}
+			if _, ffmpegErr := executeExtractSegment(ctx, localInputVideo, tempOutputFile, segment); ffmpegErr != nil {
+				outputCleanup()
+				span.RecordError(ffmpegErr)
+				return mcp.NewToolResultError(fmt.Sprintf("FFMpeg failed to extract scene %d: %v", i+1, ffmpegErr)), nil
+			}
+			output := probeOutput(ctx, tempOutputFile)
+			finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+			outputCleanup()
+			if processErr != nil {
+				span.RecordError(processErr)
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output for scene %d: %v", i+1, processErr)), nil
+			}
+			if outputLocalDir != "" || finalGCSPath != "" {
+				outputs = append(outputs, output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath))
+			}
+
+			end := "end"
+			if segment.End > 0 {
+				end = strconv.FormatFloat(segment.End, 'f', 3, 64) + "s"
+			}
+			location := "temporary output cleaned up"
+			switch {
+			case finalGCSPath != "" && outputLocalDir != "" && finalLocalPath != "":
+				location = fmt.Sprintf("%s and %s", finalLocalPath, finalGCSPath)
+			case finalGCSPath != "":
+				location = finalGCSPath
+			case outputLocalDir != "" && finalLocalPath != "":
+				location = finalLocalPath
+			}
+			messageParts = append(messageParts, fmt.Sprintf("Scene %d (%.3fs to %s): %s.", i+1, segment.Start, end, location))
+		}
+		if outputLocalDir == "" && outputGCSBucket == "" {
+			messageParts = append(messageParts, "No output location was requested, so the scene files were not kept.")
+		}
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+	messageParts = append(messageParts, fmt.Sprintf("Completed in %v.", duration))
+	return newAvtoolResult(ctx, "ffmpeg_trim_to_scene", strings.Join(messageParts, " "), duration, outputs, details), nil
+}
+
+// addRemixChannelsTool defines and registers the 'ffmpeg_remix_channels' tool.
+func addRemixChannelsTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_remix_channels",
+		mcp.WithDescription("Remixes the audio channels of an audio or video file: downmixes stereo (or multichannel) audio to mono, e.g. for speech recognition, or upmixes mono audio to stereo. Any video stream is copied unchanged."),
+		mcp.WithString("input_media_uri", mcp.Required(), mcp.Description("URI of the input audio or video file (local path or gs://).")),
+		mcp.WithString("channel_layout", mcp.Required(), mcp.Enum("mono", "stereo"), mcp.Description("The channel layout of the output audio. 'mono' downmixes to a single channel; 'stereo' duplicates a mono channel to both sides or downmixes multichannel audio to two channels.")),
+		mcp.WithString("mono_source", mcp.DefaultString("mix"), mcp.Enum(monoSources...), mcp.Description("Optional. For 'mono' output: 'mix' averages all input channels, 'left' or 'right' keeps only that channel of a stereo input (e.g., when each side carries a different speaker). Defaults to mix.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output file (e.g., 'speech_mono.wav'). Defaults to the input's format if omitted.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegRemixChannelsHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegRemixChannelsHandler is the handler for the channel remix tool.
+// It re-encodes the audio with the requested channel layout and reports the channel count of the result.
+func ffmpegRemixChannelsHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_remix_channels")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_remix_channels", argsMap)
+
+	inputMediaURI, _ := argsMap["input_media_uri"].(string)
+	if strings.TrimSpace(inputMediaURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_media_uri' is required."), nil
+	}
+	channelLayout, _ := argsMap["channel_layout"].(string)
+	channelLayout = strings.ToLower(strings.TrimSpace(channelLayout))
+	monoSource, _ := argsMap["mono_source"].(string)
+	monoSource = strings.ToLower(strings.TrimSpace(monoSource))
+	remixArgs, err := buildRemixChannelsArgs(channelLayout, monoSource)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid channel remix parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_remix_channels: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_media_uri", inputMediaURI),
+		attribute.String("channel_layout", channelLayout),
+		attribute.String("mono_source", monoSource),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputMedia, inputCleanup, err := common.PrepareInputFile(ctx, inputMediaURI, "remix_input", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input media: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	defaultOutputExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(localInputMedia), "."))
+	if defaultOutputExt == "" {
+		defaultOutputExt = "wav"
+	}
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, defaultOutputExt)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeRemixChannels(ctx, localInputMedia, tempOutputFile, remixArgs); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg channel remix failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	messageParts := []string{fmt.Sprintf("Audio remixed to %s in %v.", channelLayout, duration)}
+	if output.AudioChannels > 0 {
+		messageParts = append(messageParts, fmt.Sprintf("The output has %d audio channel(s).", output.AudioChannels))
+	}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "ffmpeg_remix_channels", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addImagesToVideoTool defines and registers the 'ffmpeg_images_to_video' tool.
+func addImagesToVideoTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_images_to_video",
+		mcp.WithDescription("Assembles an ordered list of images, plus an optional narration or music track, into a slideshow MP4. Each image is shown for the same duration, scaled to fit the output resolution with letterboxing, and joined with cuts or transitions."),
+		mcp.WithArray("image_uris", mcp.Required(), mcp.Description(fmt.Sprintf("The images in display order, as URIs (local paths or gs://). At most %d.", maxSlideshowImages)), mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithNumber("seconds_per_image", mcp.DefaultNumber(defaultSlideshowSeconds), mcp.Description(fmt.Sprintf("Optional. How long each image is shown, in seconds (at most %g). The slideshow lasts this times the number of images. Defaults to 3.", maxSlideshowSeconds))),
+		mcp.WithString("audio_uri", mcp.Description("Optional. URI of an audio track (local path or gs://), e.g. narration. It is cut at the end of the slideshow, or padded with silence if shorter.")),
+		mcp.WithString("transition", mcp.DefaultString("none"), mcp.Enum(slideshowTransitions...), mcp.Description("Optional. The transition between images: 'none' cuts, the others are FFMpeg xfade transitions. Defaults to none.")),
+		mcp.WithNumber("transition_duration", mcp.DefaultNumber(defaultSlideshowTransitionSeconds), mcp.Description("Optional. Length of each transition in seconds; must be less than seconds_per_image. Defaults to 0.5.")),
+		mcp.WithNumber("width", mcp.DefaultNumber(1280), mcp.Description("Optional. Output width in pixels (even). Defaults to 1280.")),
+		mcp.WithNumber("height", mcp.DefaultNumber(720), mcp.Description("Optional. Output height in pixels (even). Defaults to 720.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'slideshow.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegImagesToVideoHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegImagesToVideoHandler is the handler for the slideshow tool.
+// It renders the images and optional audio track into an H.264/AAC MP4.
+func ffmpegImagesToVideoHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_images_to_video")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_images_to_video", argsMap)
+
+	imageURIsRaw, _ := argsMap["image_uris"].([]interface{})
+	var imageURIs []string
+	for _, item := range imageURIsRaw {
+		if uri, ok := item.(string); ok && strings.TrimSpace(uri) != "" {
+			imageURIs = append(imageURIs, uri)
+		}
+	}
+	audioURI, _ := argsMap["audio_uri"].(string)
+	audioURI = strings.TrimSpace(audioURI)
+
+	opts := slideshowOptions{
+		ImageCount:        len(imageURIs),
+		SecondsPerImage:   defaultSlideshowSeconds,
+		Transition:        "none",
+		TransitionSeconds: defaultSlideshowTransitionSeconds,
+		Width:             1280,
+		Height:            720,
+		HasAudio:          audioURI != "",
+	}
+	if v, ok := argsMap["seconds_per_image"].(float64); ok {
+		opts.SecondsPerImage = v
+	}
+	if v, ok := argsMap["transition"].(string); ok && v != "" {
+		opts.Transition = strings.ToLower(v)
+	}
+	if v, ok := argsMap["transition_duration"].(float64); ok {
+		opts.TransitionSeconds = v
+	}
+	if v, ok := argsMap["width"].(float64); ok {
+		opts.Width = int(v)
+	}
+	if v, ok := argsMap["height"].(float64); ok {
+		opts.Height = int(v)
+	}
+	filterGraph, err := buildSlideshowFilter(opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid slideshow parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_images_to_video: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.StringSlice("image_uris", imageURIs),
+		attribute.String("audio_uri", audioURI),
+		attribute.Float64("seconds_per_image", opts.SecondsPerImage),
+		attribute.String("transition", opts.Transition),
+		attribute.Int("width", opts.Width),
+		attribute.Int("height", opts.Height),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localImages, imagesCleanup, errPrep := prepareInputFiles(ctx, imageURIs, "slideshow_image", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare image %v", errPrep)), nil
+	}
+	defer imagesCleanup()
+	var localAudio string
+	if audioURI != "" {
+		localPath, cleanup, errPrep := common.PrepareInputFile(ctx, audioURI, "slideshow_audio", cfg.ProjectID)
+		if errPrep != nil {
+			span.RecordError(errPrep)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare audio track %s: %v", audioURI, errPrep)), nil
+		}
+		defer cleanup()
+		localAudio = localPath
+	}
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeSlideshow(ctx, localImages, localAudio, opts, filterGraph, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg slideshow rendering failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	messageParts := []string{fmt.Sprintf("Slideshow of %d image(s) at %gs each (%gs, transition: %s) rendered in %v.", opts.ImageCount, opts.SecondsPerImage, opts.totalSeconds(), opts.Transition, duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "ffmpeg_images_to_video", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
+}
+
+// addSpeedRampTool defines and registers the 'ffmpeg_speed_ramp' tool.
+func addSpeedRampTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("ffmpeg_speed_ramp",
+		mcp.WithDescription("Changes the playback speed of a video over time, e.g. ramping from slow motion to fast forward. The speed is set at keyframes and changes smoothly between them; before the first and after the last keyframe it stays constant. The audio is retimed with the video, keeping its pitch."),
+		mcp.WithString("input_video_uri", mcp.Required(), mcp.Description("URI of the input video file (local path or gs://).")),
+		mcp.WithArray("keyframes", mcp.Required(), mcp.Description(fmt.Sprintf("Array of at most %d speed keyframes in increasing time order. Each keyframe is an object with 'time_seconds' (a time in the input video) and 'speed' (%g to %g, where 1 is normal speed and 0.5 half speed).", maxRampKeyframes, minRampSpeed, maxRampSpeed)), mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"time_seconds": map[string]any{"type": "number", "description": "Time in the input video, in seconds."},
+				"speed":        map[string]any{"type": "number", "description": "Playback speed at that time."},
+			},
+			"required": []string{"time_seconds", "speed"},
+		})),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'ramped.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return ffmpegSpeedRampHandler(ctx, request, cfg)
+	})
+}
+
+// ffmpegSpeedRampHandler is the handler for the speed ramp tool.
+// It retimes the video and its audio following the speed keyframes.
+func ffmpegSpeedRampHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "ffmpeg_speed_ramp")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "ffmpeg_speed_ramp", argsMap)
+
+	inputVideoURI, _ := argsMap["input_video_uri"].(string)
+	if strings.TrimSpace(inputVideoURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_video_uri' is required."), nil
+	}
+	keyframesRaw, _ := argsMap["keyframes"].([]interface{})
+	if len(keyframesRaw) == 0 {
+		return mcp.NewToolResultError("Parameter 'keyframes' must contain at least one keyframe."), nil
+	}
+	var keyframes []speedKeyframe
+	for i, item := range keyframesRaw {
+		keyframeMap, ok := item.(map[string]interface{})
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Keyframe %d must be an object with 'time_seconds' and 'speed' fields.", i)), nil
+		}
+		timeSeconds, okTime := keyframeMap["time_seconds"].(float64)
+		speed, okSpeed := keyframeMap["speed"].(float64)
+		if !okTime || !okSpeed {
+			return mcp.NewToolResultError(fmt.Sprintf("Keyframe %d must have numeric 'time_seconds' and 'speed' fields.", i)), nil
+		}
+		keyframes = append(keyframes, speedKeyframe{TimeSeconds: timeSeconds, Speed: speed})
+	}
+	if err := validateSpeedKeyframes(keyframes, 0); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid speed ramp parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler ffmpeg_speed_ramp: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_video_uri", inputVideoURI),
+		attribute.Int("num_keyframes", len(keyframes)),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputVideo, inputCleanup, err := common.PrepareInputFile(ctx, inputVideoURI, "speed_ramp_input", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input video: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	input := probeOutput(ctx, localInputVideo)
+	if input.VideoCodec == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("Input %s has no video stream to retime.", inputVideoURI)), nil
+	}
+	hasAudio := input.AudioCodec != ""
+	filterGraph, err := buildSpeedRampFilter(keyframes, input.DurationSeconds, hasAudio)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid speed ramp parameters: %v", err)), nil
+	}
+	span.SetAttributes(attribute.String("filter_graph", filterGraph))
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeSpeedRamp(ctx, localInputVideo, filterGraph, hasAudio, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg speed ramp failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	messageParts := []string{fmt.Sprintf("Video retimed with %d speed keyframe(s) from %.2fs to %.2fs in %v.", len(keyframes), input.DurationSeconds, rampOutputTime(keyframes, input.DurationSeconds), duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "ffmpeg_speed_ramp", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, nil), nil
 }
@@ -0,0 +1,148 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// avtoolOutput describes a file produced by an avtool tool.
+type avtoolOutput struct {
+	LocalPath       string  `json:"local_path,omitempty"` // Only set if the file was saved to output_local_dir.
+	GCSURI          string  `json:"gcs_uri,omitempty"`
+	SizeBytes       int64   `json:"size_bytes,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Format          string  `json:"format,omitempty"`
+	VideoCodec      string  `json:"video_codec,omitempty"`
+	AudioCodec      string  `json:"audio_codec,omitempty"`
+	AudioChannels   int     `json:"audio_channels,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+}
+
+// avtoolResult is the structured result returned by the avtool tools that produce output files,
+// so that automation can chain one operation's outputs into the next.
+type avtoolResult struct {
+	Tool           string         `json:"tool"`
+	Message        string         `json:"message"`
+	Outputs        []avtoolOutput `json:"outputs"`
+	FFmpegCommands []string       `json:"ffmpeg_commands,omitempty"` // Local paths are reduced to file names.
+	ElapsedMs      int64          `json:"elapsed_ms"`
+	Details        any            `json:"details,omitempty"` // Tool-specific results, e.g. detected scene cuts.
+}
+
+// newAvtoolResult returns a tool result carrying an avtoolResult as structured content and the
+// message as text content. The FFMpeg commands are taken from the job tracking the call, if any.
+func newAvtoolResult(ctx context.Context, tool, message string, elapsed time.Duration, outputs []avtoolOutput, details any) *mcp.CallToolResult {
+	if outputs == nil {
+		outputs = []avtoolOutput{}
+	}
+	result := avtoolResult{
+		Tool:      tool,
+		Message:   message,
+		Outputs:   outputs,
+		ElapsedMs: elapsed.Milliseconds(),
+		Details:   details,
+	}
+	if job := jobFromContext(ctx); job != nil {
+		result.FFmpegCommands = job.recordedCommands()
+	}
+	return mcp.NewToolResultStructured(result, message)
+}
+
+// savedTo returns a copy of the output with its final locations. The local path is only
+// reported if the file was saved to a requested directory, as temporary files are removed.
+func (o avtoolOutput) savedTo(outputLocalDir, finalLocalPath, finalGCSPath string) avtoolOutput {
+	if outputLocalDir != "" {
+		o.LocalPath = finalLocalPath
+	}
+	o.GCSURI = finalGCSPath
+	return o
+}
+
+// probeOutput describes the output file at path with ffprobe. It must be called before the
+// file is moved or uploaded. If ffprobe fails, only the file size is reported.
+func probeOutput(ctx context.Context, path string) avtoolOutput {
+	var output avtoolOutput
+	if info, err := os.Stat(path); err == nil {
+		output.SizeBytes = info.Size()
+	}
+	probeJSON, err := executeGetMediaInfo(ctx, path)
+	if err != nil {
+		common.LogWarnf("Warning: could not probe output %s: %v", path, err)
+		return output
+	}
+	probed, err := parseProbeOutput(probeJSON)
+	if err != nil {
+		common.LogWarnf("Warning: could not parse ffprobe output for %s: %v", path, err)
+		return output
+	}
+	if probed.SizeBytes == 0 {
+		probed.SizeBytes = output.SizeBytes
+	}
+	return probed
+}
+
+// parseProbeOutput extracts the output metadata from the JSON printed by executeGetMediaInfo.
+// The first video and audio streams determine the codecs and dimensions.
+func parseProbeOutput(probeJSON string) (avtoolOutput, error) {
+	var probe struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+			Size       string `json:"size"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			Channels  int    `json:"channels"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(probeJSON), &probe); err != nil {
+		return avtoolOutput{}, fmt.Errorf("invalid ffprobe JSON: %w", err)
+	}
+
+	output := avtoolOutput{Format: probe.Format.FormatName}
+	output.DurationSeconds, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	output.SizeBytes, _ = strconv.ParseInt(probe.Format.Size, 10, 64)
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if output.VideoCodec == "" {
+				output.VideoCodec = stream.CodecName
+				output.Width, output.Height = stream.Width, stream.Height
+			}
+		case "audio":
+			if output.AudioCodec == "" {
+				output.AudioCodec = stream.CodecName
+				output.AudioChannels = stream.Channels
+			}
+		}
+	}
+	return output, nil
+}
+
+// redactCommand formats FFMpeg arguments as a command line, reducing absolute paths to their
+// file names so that server directory layouts are not exposed in results.
+func redactCommand(args []string) string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if filepath.IsAbs(arg) {
+			arg = filepath.Base(arg)
+		}
+		redacted[i] = arg
+	}
+	return "ffmpeg " + strings.Join(redacted, " ")
+}
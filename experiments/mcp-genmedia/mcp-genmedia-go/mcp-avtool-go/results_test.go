@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const testProbeOutput = `{
+  "streams": [
+    {"index": 0, "codec_name": "h264", "codec_type": "video", "width": 1280, "height": 720},
+    {"index": 1, "codec_name": "aac", "codec_type": "audio", "channels": 2},
+    {"index": 2, "codec_name": "mjpeg", "codec_type": "video", "width": 320, "height": 180}
+  ],
+  "format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "8.000000", "size": "1048576"}
+}`
+
+func TestParseProbeOutput(t *testing.T) {
+	got, err := parseProbeOutput(testProbeOutput)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	want := avtoolOutput{
+		SizeBytes:       1048576,
+		DurationSeconds: 8,
+		Format:          "mov,mp4,m4a,3gp,3g2,mj2",
+		VideoCodec:      "h264",
+		AudioCodec:      "aac",
+		AudioChannels:   2,
+		Width:           1280,
+		Height:          720,
+	}
+	if got != want {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+
+	if _, err := parseProbeOutput("not json"); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestAvtoolOutputSavedTo(t *testing.T) {
+	output := avtoolOutput{SizeBytes: 10}
+	if got := output.savedTo("", "/tmp/x.mp4", "gs://bucket/x.mp4"); got.LocalPath != "" || got.GCSURI != "gs://bucket/x.mp4" {
+		t.Errorf("expected only the GCS URI for a temporary file, but got %+v", got)
+	}
+	if got := output.savedTo("/out", "/out/x.mp4", ""); got.LocalPath != "/out/x.mp4" || got.GCSURI != "" || got.SizeBytes != 10 {
+		t.Errorf("expected the local path to be kept, but got %+v", got)
+	}
+}
+
+func TestRedactCommand(t *testing.T) {
+	got := redactCommand([]string{"-y", "-i", "/tmp/mcp_input_123/input.wav", "-acodec", "libmp3lame", "/var/tmp/out.mp3"})
+	want := "ffmpeg -y -i input.wav -acodec libmp3lame out.mp3"
+	if got != want {
+		t.Errorf("expected %q, but got %q", want, got)
+	}
+}
+
+func TestNewAvtoolResult(t *testing.T) {
+	job := newJobRegistry(time.Hour).start("ffmpeg_adjust_volume")
+	job.recordCommand([]string{"-y", "-i", "/tmp/in.mp3", "-af", "volume=3dB", "/tmp/out.mp3"})
+	ctx := context.WithValue(context.Background(), jobContextKey{}, job)
+
+	result := newAvtoolResult(ctx, "ffmpeg_adjust_volume", "Volume adjusted.", 1500*time.Millisecond, nil, nil)
+	structured, ok := result.StructuredContent.(avtoolResult)
+	if !ok {
+		t.Fatalf("expected avtoolResult structured content, but got %T", result.StructuredContent)
+	}
+	if structured.Tool != "ffmpeg_adjust_volume" || structured.Message != "Volume adjusted." || structured.ElapsedMs != 1500 {
+		t.Errorf("unexpected result fields: %+v", structured)
+	}
+	if structured.Outputs == nil {
+		t.Errorf("expected outputs to be an empty list rather than nil")
+	}
+	if len(structured.FFmpegCommands) != 1 || structured.FFmpegCommands[0] != "ffmpeg -y -i in.mp3 -af volume=3dB out.mp3" {
+		t.Errorf("expected the recorded command, but got %v", structured.FFmpegCommands)
+	}
+
+	job.finish(result, nil)
+	if job.snapshot().Structured == nil {
+		t.Errorf("expected the job to keep the structured result")
+	}
+}
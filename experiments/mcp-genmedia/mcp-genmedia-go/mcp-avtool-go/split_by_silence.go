@@ -0,0 +1,360 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/teris-io/shortid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// defaultMaxChunkSeconds is the default maximum length of a 'split_by_silence' chunk.
+	defaultMaxChunkSeconds = 300.0
+	// minMaxChunkSeconds is the shortest maximum chunk length accepted.
+	minMaxChunkSeconds = 1.0
+	// defaultSilenceNoiseDB is the level below which audio counts as silence.
+	defaultSilenceNoiseDB = -30.0
+	// defaultMinSilenceSeconds is the shortest pause that counts as a silence.
+	defaultMinSilenceSeconds = 0.5
+	// maxSilenceChunks caps the number of chunks of a 'split_by_silence' call.
+	maxSilenceChunks = 1000
+)
+
+// silenceLinePattern matches the silence_start and silence_end lines logged by the silencedetect filter.
+var silenceLinePattern = regexp.MustCompile(`silence_(start|end):\s*(-?[0-9.]+)`)
+
+// silence is a pause found by silencedetect. An End of 0 means the silence lasts until the end of the audio.
+type silence struct {
+	Start float64
+	End   float64
+}
+
+// Where an audioChunk ends: in a pause, at the maximum chunk length because there was no pause
+// to cut at, or at the end of the audio.
+const (
+	chunkEndsAtSilence   = "silence"
+	chunkEndsAtMaxLength = "max_length"
+	chunkEndsAtEnd       = "end"
+)
+
+// audioChunk is one chunk of a 'split_by_silence' result.
+type audioChunk struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	EndsAt       string  `json:"ends_at"`
+}
+
+// buildSilenceDetectFilter returns the silencedetect filter for the given noise level in dB and
+// minimum silence length in seconds.
+func buildSilenceDetectFilter(noiseDB, minSilenceSeconds float64) (string, error) {
+	if noiseDB >= 0 || noiseDB < -100 {
+		return "", fmt.Errorf("noise_db must be between -100 and 0, got %g", noiseDB)
+	}
+	if minSilenceSeconds <= 0 {
+		return "", fmt.Errorf("min_silence_seconds must be positive, got %g", minSilenceSeconds)
+	}
+	return fmt.Sprintf("silencedetect=noise=%gdB:d=%g", noiseDB, minSilenceSeconds), nil
+}
+
+// parseSilences extracts the silences logged by the silencedetect filter, in order.
+func parseSilences(ffmpegOutput string) []silence {
+	var silences []silence
+	open := false
+	for _, match := range silenceLinePattern.FindAllStringSubmatch(ffmpegOutput, -1) {
+		ts, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		if ts < 0 {
+			ts = 0
+		}
+		switch match[1] {
+		case "start":
+			if !open {
+				silences = append(silences, silence{Start: ts})
+				open = true
+			}
+		case "end":
+			if open {
+				silences[len(silences)-1].End = ts
+				open = false
+			}
+		}
+	}
+	return silences
+}
+
+// planSilenceChunks splits audio of the given length into chunks of at most maxChunkSeconds.
+// Each chunk ends in the middle of the last silence that fits, so that no words are cut; a
+// chunk with no silence to end at is cut at the maximum length.
+func planSilenceChunks(totalSeconds float64, silences []silence, maxChunkSeconds float64) ([]audioChunk, error) {
+	if totalSeconds <= 0 {
+		return nil, fmt.Errorf("the duration of the audio is unknown")
+	}
+	if maxChunkSeconds < minMaxChunkSeconds {
+		return nil, fmt.Errorf("max_chunk_seconds must be at least %g, got %g", minMaxChunkSeconds, maxChunkSeconds)
+	}
+
+	var cutPoints []float64
+	for _, s := range silences {
+		end := s.End
+		if end <= 0 || end > totalSeconds {
+			end = totalSeconds
+		}
+		if mid := (s.Start + end) / 2; mid > 0 && mid < totalSeconds {
+			cutPoints = append(cutPoints, mid)
+		}
+	}
+
+	var chunks []audioChunk
+	start := 0.0
+	next := 0
+	for totalSeconds-start > maxChunkSeconds {
+		if len(chunks) == maxSilenceChunks-1 {
+			return nil, fmt.Errorf("the audio would be split into more than %d chunks; use a larger max_chunk_seconds", maxSilenceChunks)
+		}
+		chunk := audioChunk{StartSeconds: start, EndSeconds: start + maxChunkSeconds, EndsAt: chunkEndsAtMaxLength}
+		for ; next < len(cutPoints) && cutPoints[next] <= start+maxChunkSeconds; next++ {
+			if cutPoints[next] > start {
+				chunk.EndSeconds, chunk.EndsAt = cutPoints[next], chunkEndsAtSilence
+			}
+		}
+		chunks = append(chunks, chunk)
+		start = chunk.EndSeconds
+	}
+	return append(chunks, audioChunk{StartSeconds: start, EndSeconds: totalSeconds, EndsAt: chunkEndsAtEnd}), nil
+}
+
+// executeDetectSilences runs silence detection on the audio of a media file with the given
+// filter (see buildSilenceDetectFilter) and returns the FFMpeg output to be parsed with
+// parseSilences. No output file is written.
+func executeDetectSilences(ctx context.Context, localInputMedia, silenceFilter string) (string, error) {
+	return runFFmpegCommand(ctx, "-hide_banner", "-i", localInputMedia, "-vn", "-af", silenceFilter, "-f", "null", "-")
+}
+
+// buildExtractAudioChunkArgs returns the FFMpeg arguments that extract one chunk of the audio of
+// a media file. The start is an input option, so FFMpeg seeks to it instead of decoding and
+// discarding everything before it, and -t gives the chunk's length; as the audio is re-encoded
+// with the default encoder for the output's format, the cut is still sample-accurate.
+func buildExtractAudioChunkArgs(localInputMedia, tempOutputFile string, chunk audioChunk) []string {
+	return []string{"-y",
+		"-ss", strconv.FormatFloat(chunk.StartSeconds, 'f', 3, 64),
+		"-i", localInputMedia,
+		"-t", strconv.FormatFloat(chunk.EndSeconds-chunk.StartSeconds, 'f', 3, 64),
+		"-vn", tempOutputFile}
+}
+
+// executeExtractAudioChunk extracts one chunk of the audio of a media file (see
+// buildExtractAudioChunkArgs).
+func executeExtractAudioChunk(ctx context.Context, localInputMedia, tempOutputFile string, chunk audioChunk) (string, error) {
+	return runFFmpegCommand(ctx, buildExtractAudioChunkArgs(localInputMedia, tempOutputFile, chunk)...)
+}
+
+// chunkOutput describes a chunk file from the probe of the first chunk, which has the same format
+// and codec, so that only one chunk is probed. The length is taken from the chunk plan.
+func chunkOutput(first avtoolOutput, path string, chunk audioChunk) avtoolOutput {
+	output := first
+	output.SizeBytes = 0
+	if info, err := os.Stat(path); err == nil {
+		output.SizeBytes = info.Size()
+	}
+	output.DurationSeconds = chunk.EndSeconds - chunk.StartSeconds
+	return output
+}
+
+// silenceSplitDetails is the tool-specific part of the 'split_by_silence' result.
+type silenceSplitDetails struct {
+	TotalSeconds    float64      `json:"total_seconds"`
+	MaxChunkSeconds float64      `json:"max_chunk_seconds"`
+	SilencesFound   int          `json:"silences_found"`
+	Chunks          []audioChunk `json:"chunks"`
+}
+
+// addSplitBySilenceTool defines and registers the 'split_by_silence' tool.
+func addSplitBySilenceTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("split_by_silence",
+		mcp.WithDescription("Splits long audio into chunks no longer than a maximum length, cutting in the pauses between words where possible, e.g. to transcribe it in parts. Pauses are found with FFMpeg's silencedetect filter; a chunk with no pause to end at is cut at the maximum length. Returns the list of chunks with their start and end times."),
+		mcp.WithString("input_audio_uri", mcp.Required(), mcp.Description("URI of the input audio (or video) file (local path or gs://).")),
+		mcp.WithNumber("max_chunk_seconds", mcp.DefaultNumber(defaultMaxChunkSeconds), mcp.Description(fmt.Sprintf("Optional. The maximum length of a chunk in seconds (at least %g). Defaults to %g.", minMaxChunkSeconds, defaultMaxChunkSeconds))),
+		mcp.WithNumber("noise_db", mcp.DefaultNumber(defaultSilenceNoiseDB), mcp.Description(fmt.Sprintf("Optional. The level in dB below which audio counts as silence, between -100 and 0. Raise it (e.g. -25) for noisy recordings. Defaults to %g.", defaultSilenceNoiseDB))),
+		mcp.WithNumber("min_silence_seconds", mcp.DefaultNumber(defaultMinSilenceSeconds), mcp.Description(fmt.Sprintf("Optional. The shortest pause in seconds that can be cut at. Defaults to %g.", defaultMinSilenceSeconds))),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Base name for the chunk files, which are numbered '<name>_001.<ext>', '<name>_002.<ext>', and so on. The extension selects the format (e.g. '.flac', '.wav', '.mp3'); defaults to the input's format.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the chunk files.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the chunk files to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return splitBySilenceHandler(ctx, request, cfg)
+	})
+}
+
+// splitBySilenceHandler is the handler for the 'split_by_silence' tool. It detects the silences
+// in the audio, plans the chunks around them, and extracts each chunk.
+func splitBySilenceHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "split_by_silence")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "split_by_silence", argsMap)
+
+	inputAudioURI, _ := argsMap["input_audio_uri"].(string)
+	if strings.TrimSpace(inputAudioURI) == "" {
+		return mcp.NewToolResultError("Parameter 'input_audio_uri' is required."), nil
+	}
+	maxChunkSeconds := defaultMaxChunkSeconds
+	if maxParam, ok := argsMap["max_chunk_seconds"].(float64); ok {
+		maxChunkSeconds = maxParam
+	}
+	if maxChunkSeconds < minMaxChunkSeconds {
+		return mcp.NewToolResultError(fmt.Sprintf("Parameter 'max_chunk_seconds' must be at least %g, got %v.", minMaxChunkSeconds, maxChunkSeconds)), nil
+	}
+	noiseDB := defaultSilenceNoiseDB
+	if noiseParam, ok := argsMap["noise_db"].(float64); ok {
+		noiseDB = noiseParam
+	}
+	minSilenceSeconds := defaultMinSilenceSeconds
+	if minParam, ok := argsMap["min_silence_seconds"].(float64); ok {
+		minSilenceSeconds = minParam
+	}
+	silenceFilter, err := buildSilenceDetectFilter(noiseDB, minSilenceSeconds)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid silence detection parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler split_by_silence: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.String("input_audio_uri", inputAudioURI),
+		attribute.Float64("max_chunk_seconds", maxChunkSeconds),
+		attribute.Float64("noise_db", noiseDB),
+		attribute.Float64("min_silence_seconds", minSilenceSeconds),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localInputAudio, inputCleanup, err := common.PrepareInputFile(ctx, inputAudioURI, "input_audio_silence", cfg.ProjectID)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare input audio: %v", err)), nil
+	}
+	defer inputCleanup()
+
+	input := probeOutput(ctx, localInputAudio)
+	if input.AudioCodec == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("Input %s has no audio stream.", inputAudioURI)), nil
+	}
+
+	detectOutput, ffmpegErr := executeDetectSilences(ctx, localInputAudio, silenceFilter)
+	if ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg silence detection failed: %v", ffmpegErr)), nil
+	}
+	silences := parseSilences(detectOutput)
+	chunks, err := planSilenceChunks(input.DurationSeconds, silences, maxChunkSeconds)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to plan the chunks: %v", err)), nil
+	}
+	span.SetAttributes(attribute.Int("silence_count", len(silences)), attribute.Int("chunk_count", len(chunks)))
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFileName), "."))
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(localInputAudio), "."))
+	}
+	if format == "" {
+		format = "wav"
+	}
+	baseName := strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+	if baseName == "" {
+		uid, _ := shortid.Generate()
+		baseName = fmt.Sprintf("chunk_%s", uid)
+	}
+
+	messageParts := []string{fmt.Sprintf("Found %d silence(s) in %.3fs of audio and split it into %d chunk(s) of at most %gs.", len(silences), input.DurationSeconds, len(chunks), maxChunkSeconds)}
+	var outputs []avtoolOutput
+	var firstOutput avtoolOutput
+	for i, chunk := range chunks {
+		chunkName := fmt.Sprintf("%s_%03d.%s", baseName, i+1, format)
+		tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(chunkName, format)
+		if err != nil {
+			span.RecordError(err)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+		}
+		if _, ffmpegErr := executeExtractAudioChunk(ctx, localInputAudio, tempOutputFile, chunk); ffmpegErr != nil {
+			outputCleanup()
+			span.RecordError(ffmpegErr)
+			return mcp.NewToolResultError(fmt.Sprintf("FFMpeg failed to extract chunk %d: %v", i+1, ffmpegErr)), nil
+		}
+		var output avtoolOutput
+		if i == 0 {
+			firstOutput = probeOutput(ctx, tempOutputFile)
+			output = firstOutput
+		} else {
+			output = chunkOutput(firstOutput, tempOutputFile, chunk)
+		}
+		finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+		outputCleanup()
+		if processErr != nil {
+			span.RecordError(processErr)
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output for chunk %d: %v", i+1, processErr)), nil
+		}
+		if outputLocalDir != "" || finalGCSPath != "" {
+			outputs = append(outputs, output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath))
+		}
+
+		location := "temporary output cleaned up"
+		switch {
+		case finalGCSPath != "" && outputLocalDir != "" && finalLocalPath != "":
+			location = fmt.Sprintf("%s and %s", finalLocalPath, finalGCSPath)
+		case finalGCSPath != "":
+			location = finalGCSPath
+		case outputLocalDir != "" && finalLocalPath != "":
+			location = finalLocalPath
+		}
+		var cut string
+		switch chunk.EndsAt {
+		case chunkEndsAtSilence:
+			cut = ", cut at a pause"
+		case chunkEndsAtMaxLength:
+			cut = ", cut at the maximum length as no pause was found"
+		}
+		messageParts = append(messageParts, fmt.Sprintf("Chunk %d (%.3fs to %.3fs%s): %s.", i+1, chunk.StartSeconds, chunk.EndSeconds, cut, location))
+	}
+	if outputLocalDir == "" && outputGCSBucket == "" {
+		messageParts = append(messageParts, "No output location was requested, so the chunk files were not kept.")
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+	messageParts = append(messageParts, fmt.Sprintf("Completed in %v.", duration))
+	details := silenceSplitDetails{TotalSeconds: input.DurationSeconds, MaxChunkSeconds: maxChunkSeconds, SilencesFound: len(silences), Chunks: chunks}
+	return newAvtoolResult(ctx, "split_by_silence", strings.Join(messageParts, " "), duration, outputs, details), nil
+}
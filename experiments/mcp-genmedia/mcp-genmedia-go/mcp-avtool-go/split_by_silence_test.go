@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildSilenceDetectFilter(t *testing.T) {
+	got, err := buildSilenceDetectFilter(-30, 0.5)
+	if err != nil || got != "silencedetect=noise=-30dB:d=0.5" {
+		t.Errorf("expected silencedetect=noise=-30dB:d=0.5, but got %q (err: %v)", got, err)
+	}
+	for _, tt := range []struct{ noiseDB, minSilence float64 }{{0, 0.5}, {-120, 0.5}, {-30, 0}} {
+		if _, err := buildSilenceDetectFilter(tt.noiseDB, tt.minSilence); err == nil {
+			t.Errorf("expected an error for noise %v and min silence %v", tt.noiseDB, tt.minSilence)
+		}
+	}
+}
+
+func TestParseSilences(t *testing.T) {
+	output := strings.Join([]string{
+		"Input #0, wav, from 'speech.wav':",
+		"[silencedetect @ 0x5581] silence_start: -0.0120",
+		"[silencedetect @ 0x5581] silence_end: 0.8 | silence_duration: 0.812",
+		"size=N/A time=00:00:10.00 bitrate=N/A speed= 500x",
+		"[silencedetect @ 0x5581] silence_start: 4.25",
+		"[silencedetect @ 0x5581] silence_end: 5.1 | silence_duration: 0.85",
+		"[silencedetect @ 0x5581] silence_start: 9.5",
+	}, "\n")
+	got := parseSilences(output)
+	want := []silence{{0, 0.8}, {4.25, 5.1}, {9.5, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected silences %v, but got %v", want, got)
+	}
+	if got := parseSilences("size=N/A time=00:00:10.00"); len(got) != 0 {
+		t.Errorf("expected no silences, but got %v", got)
+	}
+}
+
+func TestPlanSilenceChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    float64
+		silences []silence
+		max      float64
+		want     []audioChunk
+	}{
+		{
+			name:  "shorter than the maximum",
+			total: 50,
+			max:   60,
+			want:  []audioChunk{{0, 50, chunkEndsAtEnd}},
+		},
+		{
+			name:     "cut at the last pause that fits",
+			total:    150,
+			silences: []silence{{20, 21}, {55, 57}, {62, 63}, {110, 112}},
+			max:      60,
+			want:     []audioChunk{{0, 56, chunkEndsAtSilence}, {56, 111, chunkEndsAtSilence}, {111, 150, chunkEndsAtEnd}},
+		},
+		{
+			name:     "no pause to cut at",
+			total:    130,
+			silences: []silence{{100, 101}},
+			max:      60,
+			want:     []audioChunk{{0, 60, chunkEndsAtMaxLength}, {60, 100.5, chunkEndsAtSilence}, {100.5, 130, chunkEndsAtEnd}},
+		},
+		{
+			name:     "trailing silence",
+			total:    100,
+			silences: []silence{{30, 0}},
+			max:      70,
+			want:     []audioChunk{{0, 65, chunkEndsAtSilence}, {65, 100, chunkEndsAtEnd}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planSilenceChunks(tt.total, tt.silences, tt.max)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected chunks %v, but got %v", tt.want, got)
+			}
+			for _, chunk := range got {
+				if chunk.EndSeconds-chunk.StartSeconds > tt.max {
+					t.Errorf("chunk %v is longer than %vs", chunk, tt.max)
+				}
+			}
+		})
+	}
+
+	if _, err := planSilenceChunks(0, nil, 60); err == nil {
+		t.Error("expected an error for an unknown duration")
+	}
+	if _, err := planSilenceChunks(60, nil, 0.5); err == nil {
+		t.Error("expected an error for a maximum chunk length below the minimum")
+	}
+	if _, err := planSilenceChunks(2000, nil, 1); err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Errorf("expected an error for too many chunks, got %v", err)
+	}
+}
+
+func TestBuildExtractAudioChunkArgs(t *testing.T) {
+	got := buildExtractAudioChunkArgs("in.mp4", "out.flac", audioChunk{StartSeconds: 12.5, EndSeconds: 71.25})
+	want := []string{"-y", "-ss", "12.500", "-i", "in.mp4", "-t", "58.750", "-vn", "out.flac"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestChunkOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunk_002.flac")
+	if err := os.WriteFile(path, make([]byte, 42), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first := avtoolOutput{SizeBytes: 1000, DurationSeconds: 60, Format: "flac", AudioCodec: "flac", AudioChannels: 2}
+	got := chunkOutput(first, path, audioChunk{StartSeconds: 60, EndSeconds: 90.5})
+	want := avtoolOutput{SizeBytes: 42, DurationSeconds: 30.5, Format: "flac", AudioCodec: "flac", AudioChannels: 2}
+	if got != want {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
@@ -0,0 +1,598 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxTimelineClips, maxTimelineOverlays and maxTimelineAudioTracks cap the size of a timeline.
+	maxTimelineClips       = 50
+	maxTimelineOverlays    = 20
+	maxTimelineAudioTracks = 10
+	// defaultTimelineWidth, defaultTimelineHeight and defaultTimelineFPS describe the output
+	// video when the timeline does not set them.
+	defaultTimelineWidth  = 1280
+	defaultTimelineHeight = 720
+	defaultTimelineFPS    = 30
+	// maxTimelineFPS caps the output frame rate.
+	maxTimelineFPS = 60
+	// maxTimelineVolume caps the volume multiplier of clips and audio tracks.
+	maxTimelineVolume = 4.0
+	// timelineAudioFormat is the format every audio stream is converted to before it is joined
+	// or mixed, as concat, acrossfade and amix need matching inputs.
+	timelineAudioFormat = "aformat=sample_rates=48000:channel_layouts=stereo"
+)
+
+// timeline is a declarative description of an edit, as accepted by the 'render_timeline' tool.
+// The clips are played one after another; overlays and audio tracks are placed on top of them
+// at timeline times.
+type timeline struct {
+	Width    int                  `json:"width,omitempty"`
+	Height   int                  `json:"height,omitempty"`
+	FPS      int                  `json:"fps,omitempty"`
+	Clips    []timelineClip       `json:"clips"`
+	Overlays []timelineOverlay    `json:"overlays,omitempty"`
+	Audio    []timelineAudioTrack `json:"audio,omitempty"`
+}
+
+// timelineClip is a trimmed section of a video on the main track.
+type timelineClip struct {
+	URI               string   `json:"uri"`
+	TrimStartSeconds  float64  `json:"trim_start_seconds,omitempty"`
+	TrimEndSeconds    float64  `json:"trim_end_seconds,omitempty"` // 0 means the end of the clip.
+	Volume            *float64 `json:"volume,omitempty"`           // Multiplier for the clip's own audio; defaults to 1.
+	Transition        string   `json:"transition,omitempty"`       // Transition into the next clip; defaults to "none".
+	TransitionSeconds float64  `json:"transition_seconds,omitempty"`
+}
+
+// timelineOverlay is an image or video shown over the clips from StartSeconds to EndSeconds.
+type timelineOverlay struct {
+	URI          string  `json:"uri"`
+	StartSeconds float64 `json:"start_seconds,omitempty"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"` // 0 means the end of the timeline.
+	X            int     `json:"x,omitempty"`
+	Y            int     `json:"y,omitempty"`
+	Width        int     `json:"width,omitempty"` // 0 keeps the overlay's own size.
+}
+
+// timelineAudioTrack is an audio file, e.g. background music, mixed with the clips' audio from
+// StartSeconds on.
+type timelineAudioTrack struct {
+	URI              string   `json:"uri"`
+	StartSeconds     float64  `json:"start_seconds,omitempty"`
+	TrimStartSeconds float64  `json:"trim_start_seconds,omitempty"`
+	Volume           *float64 `json:"volume,omitempty"` // Defaults to 1.
+}
+
+// timelineClipInfo describes a clip's input file, as probed before rendering.
+type timelineClipInfo struct {
+	DurationSeconds float64
+	HasAudio        bool
+}
+
+// parseTimeline decodes a timeline from JSON, rejecting unknown fields, fills in the defaults
+// and validates everything that does not depend on the input files.
+func parseTimeline(data []byte) (timeline, error) {
+	var tl timeline
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&tl); err != nil {
+		return timeline{}, fmt.Errorf("invalid timeline JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return timeline{}, fmt.Errorf("invalid timeline JSON: unexpected data after the timeline object")
+	}
+	if tl.Width == 0 {
+		tl.Width = defaultTimelineWidth
+	}
+	if tl.Height == 0 {
+		tl.Height = defaultTimelineHeight
+	}
+	if tl.FPS == 0 {
+		tl.FPS = defaultTimelineFPS
+	}
+	for i := range tl.Clips {
+		tl.Clips[i].Transition = strings.ToLower(strings.TrimSpace(tl.Clips[i].Transition))
+		if tl.Clips[i].Transition == "" {
+			tl.Clips[i].Transition = "none"
+		}
+	}
+	return tl, tl.validate()
+}
+
+// validateVolume checks an optional volume multiplier.
+func validateVolume(volume *float64) error {
+	if volume != nil && (*volume < 0 || *volume > maxTimelineVolume) {
+		return fmt.Errorf("volume must be between 0 and %g, got %g", maxTimelineVolume, *volume)
+	}
+	return nil
+}
+
+// volumeFilter returns the volume filter for an optional volume multiplier, or "" for full volume.
+func volumeFilter(volume *float64) string {
+	if volume == nil || *volume == 1 {
+		return ""
+	}
+	return ",volume=" + strconv.FormatFloat(*volume, 'f', -1, 64)
+}
+
+// validate checks the timeline fields.
+func (tl timeline) validate() error {
+	if err := validateEvenDimension("width", tl.Width); err != nil {
+		return err
+	}
+	if err := validateEvenDimension("height", tl.Height); err != nil {
+		return err
+	}
+	if tl.FPS < 1 || tl.FPS > maxTimelineFPS {
+		return fmt.Errorf("fps must be between 1 and %d, got %d", maxTimelineFPS, tl.FPS)
+	}
+	if len(tl.Clips) == 0 || len(tl.Clips) > maxTimelineClips {
+		return fmt.Errorf("between 1 and %d clips are required, got %d", maxTimelineClips, len(tl.Clips))
+	}
+	if len(tl.Overlays) > maxTimelineOverlays {
+		return fmt.Errorf("at most %d overlays are supported, got %d", maxTimelineOverlays, len(tl.Overlays))
+	}
+	if len(tl.Audio) > maxTimelineAudioTracks {
+		return fmt.Errorf("at most %d audio tracks are supported, got %d", maxTimelineAudioTracks, len(tl.Audio))
+	}
+
+	for i, clip := range tl.Clips {
+		if strings.TrimSpace(clip.URI) == "" {
+			return fmt.Errorf("clip %d: uri is required", i)
+		}
+		if clip.TrimStartSeconds < 0 || clip.TrimEndSeconds < 0 {
+			return fmt.Errorf("clip %d: trim times must not be negative", i)
+		}
+		if clip.TrimEndSeconds > 0 && clip.TrimEndSeconds <= clip.TrimStartSeconds {
+			return fmt.Errorf("clip %d: trim_end_seconds (%g) must be after trim_start_seconds (%g)", i, clip.TrimEndSeconds, clip.TrimStartSeconds)
+		}
+		if err := validateVolume(clip.Volume); err != nil {
+			return fmt.Errorf("clip %d: %w", i, err)
+		}
+		if !slices.Contains(slideshowTransitions, clip.Transition) {
+			return fmt.Errorf("clip %d: unsupported transition '%s'. Supported transitions are: %s", i, clip.Transition, strings.Join(slideshowTransitions, ", "))
+		}
+		if clip.Transition != "none" {
+			if i == len(tl.Clips)-1 {
+				return fmt.Errorf("clip %d: the last clip cannot have a transition into a next clip", i)
+			}
+			if clip.TransitionSeconds <= 0 {
+				return fmt.Errorf("clip %d: transition_seconds must be greater than 0 for transition '%s'", i, clip.Transition)
+			}
+		}
+	}
+	for i, overlay := range tl.Overlays {
+		if strings.TrimSpace(overlay.URI) == "" {
+			return fmt.Errorf("overlay %d: uri is required", i)
+		}
+		if overlay.StartSeconds < 0 || overlay.EndSeconds < 0 {
+			return fmt.Errorf("overlay %d: times must not be negative", i)
+		}
+		if overlay.EndSeconds > 0 && overlay.EndSeconds <= overlay.StartSeconds {
+			return fmt.Errorf("overlay %d: end_seconds (%g) must be after start_seconds (%g)", i, overlay.EndSeconds, overlay.StartSeconds)
+		}
+		if overlay.Width < 0 || overlay.Width > maxScaleDimension {
+			return fmt.Errorf("overlay %d: width must be between 0 and %d, got %d", i, maxScaleDimension, overlay.Width)
+		}
+	}
+	for i, track := range tl.Audio {
+		if strings.TrimSpace(track.URI) == "" {
+			return fmt.Errorf("audio track %d: uri is required", i)
+		}
+		if track.StartSeconds < 0 || track.TrimStartSeconds < 0 {
+			return fmt.Errorf("audio track %d: times must not be negative", i)
+		}
+		if err := validateVolume(track.Volume); err != nil {
+			return fmt.Errorf("audio track %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// clipSeconds returns the trimmed length of each clip, given the probed inputs.
+func (tl timeline) clipSeconds(infos []timelineClipInfo) ([]float64, error) {
+	if len(infos) != len(tl.Clips) {
+		return nil, fmt.Errorf("expected %d probed clips, got %d", len(tl.Clips), len(infos))
+	}
+	seconds := make([]float64, len(tl.Clips))
+	for i, clip := range tl.Clips {
+		end := clip.TrimEndSeconds
+		if end == 0 {
+			end = infos[i].DurationSeconds
+		}
+		if infos[i].DurationSeconds > 0 && end > infos[i].DurationSeconds {
+			return nil, fmt.Errorf("clip %d: trim_end_seconds (%g) is past the end of the %gs input", i, end, infos[i].DurationSeconds)
+		}
+		if end <= clip.TrimStartSeconds {
+			return nil, fmt.Errorf("clip %d: trim_start_seconds (%g) leaves nothing of the %gs input", i, clip.TrimStartSeconds, infos[i].DurationSeconds)
+		}
+		seconds[i] = end - clip.TrimStartSeconds
+	}
+	return seconds, nil
+}
+
+// totalSeconds returns the length of the rendered timeline: the clips back to back, less the
+// overlap of each transition.
+func (tl timeline) totalSeconds(clipSeconds []float64) float64 {
+	var total float64
+	for i, seconds := range clipSeconds {
+		total += seconds
+		if tl.Clips[i].Transition != "none" {
+			total -= tl.Clips[i].TransitionSeconds
+		}
+	}
+	return total
+}
+
+// buildTimelineFilter returns the filter graph that renders the timeline, and the length of the
+// result. The inputs are the clips, then the overlays, then the audio tracks. Each clip is
+// trimmed, scaled and padded to the output size, and joined to the previous ones with a cut
+// (concat) or an xfade transition, while its audio (or silence, if it has none) is joined with
+// concat or acrossfade. The overlays are then placed over the video with overlay, and the audio
+// tracks are mixed into the clips' audio with amix. The outputs are labeled [v] and [a].
+func buildTimelineFilter(tl timeline, infos []timelineClipInfo) (string, float64, error) {
+	if err := tl.validate(); err != nil {
+		return "", 0, err
+	}
+	clipSeconds, err := tl.clipSeconds(infos)
+	if err != nil {
+		return "", 0, err
+	}
+	for i, clip := range tl.Clips {
+		if clip.Transition == "none" {
+			continue
+		}
+		// Both clips must last longer than the transitions that overlap them.
+		incoming := 0.0
+		if i > 0 && tl.Clips[i-1].Transition != "none" {
+			incoming = tl.Clips[i-1].TransitionSeconds
+		}
+		if incoming+clip.TransitionSeconds >= clipSeconds[i] || clip.TransitionSeconds >= clipSeconds[i+1] {
+			return "", 0, fmt.Errorf("clip %d: the %gs transition is too long for the %gs and %gs clips it joins", i, clip.TransitionSeconds, clipSeconds[i], clipSeconds[i+1])
+		}
+	}
+	total := tl.totalSeconds(clipSeconds)
+
+	var parts []string
+	for i, clip := range tl.Clips {
+		start := strconv.FormatFloat(clip.TrimStartSeconds, 'f', 3, 64)
+		end := strconv.FormatFloat(clip.TrimStartSeconds+clipSeconds[i], 'f', 3, 64)
+		parts = append(parts, fmt.Sprintf("[%d:v]trim=start=%s:end=%s,setpts=PTS-STARTPTS,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d,format=yuv420p[cv%d]",
+			i, start, end, tl.Width, tl.Height, tl.Width, tl.Height, tl.FPS, i))
+		if infos[i].HasAudio {
+			parts = append(parts, fmt.Sprintf("[%d:a]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS,%s%s[ca%d]", i, start, end, timelineAudioFormat, volumeFilter(clip.Volume), i))
+		} else {
+			parts = append(parts, fmt.Sprintf("anullsrc=r=48000:cl=stereo,atrim=duration=%s[ca%d]", strconv.FormatFloat(clipSeconds[i], 'f', 3, 64), i))
+		}
+	}
+
+	videoOut, audioOut := "[v]", "[a]"
+	if len(tl.Overlays) > 0 {
+		videoOut = "[main]"
+	}
+	if len(tl.Audio) > 0 {
+		audioOut = "[clipaudio]"
+	}
+	previousVideo, previousAudio := "[cv0]", "[ca0]"
+	length := clipSeconds[0]
+	for i := 1; i < len(tl.Clips); i++ {
+		nextVideo, nextAudio := fmt.Sprintf("[jv%d]", i), fmt.Sprintf("[ja%d]", i)
+		if i == len(tl.Clips)-1 {
+			nextVideo, nextAudio = videoOut, audioOut
+		}
+		if joining := tl.Clips[i-1]; joining.Transition == "none" {
+			parts = append(parts,
+				fmt.Sprintf("%s[cv%d]concat=n=2:v=1:a=0%s", previousVideo, i, nextVideo),
+				fmt.Sprintf("%s[ca%d]concat=n=2:v=0:a=1%s", previousAudio, i, nextAudio))
+			length += clipSeconds[i]
+		} else {
+			d := strconv.FormatFloat(joining.TransitionSeconds, 'f', -1, 64)
+			offset := strconv.FormatFloat(length-joining.TransitionSeconds, 'f', 3, 64)
+			parts = append(parts,
+				fmt.Sprintf("%s[cv%d]xfade=transition=%s:duration=%s:offset=%s%s", previousVideo, i, joining.Transition, d, offset, nextVideo),
+				fmt.Sprintf("%s[ca%d]acrossfade=d=%s:c1=tri:c2=tri%s", previousAudio, i, d, nextAudio))
+			length += clipSeconds[i] - joining.TransitionSeconds
+		}
+		previousVideo, previousAudio = nextVideo, nextAudio
+	}
+	if len(tl.Clips) == 1 {
+		parts = append(parts, fmt.Sprintf("[cv0]null%s", videoOut), fmt.Sprintf("[ca0]anull%s", audioOut))
+	}
+
+	base := videoOut
+	for j, overlay := range tl.Overlays {
+		end := overlay.EndSeconds
+		if end == 0 || end > total {
+			end = total
+		}
+		if overlay.StartSeconds >= total {
+			return "", 0, fmt.Errorf("overlay %d: start_seconds (%g) is not before the end of the %gs timeline", j, overlay.StartSeconds, total)
+		}
+		input := len(tl.Clips) + j
+		start := strconv.FormatFloat(overlay.StartSeconds, 'f', 3, 64)
+		chain := fmt.Sprintf("[%d:v]", input)
+		if overlay.Width > 0 {
+			chain += fmt.Sprintf("scale=%d:-2,", overlay.Width)
+		}
+		// Shift the overlay so that a video overlay starts playing when it appears.
+		parts = append(parts, fmt.Sprintf("%ssetpts=PTS-STARTPTS+%s/TB[ov%d]", chain, start, j))
+		next := fmt.Sprintf("[vo%d]", j)
+		if j == len(tl.Overlays)-1 {
+			next = "[v]"
+		}
+		parts = append(parts, fmt.Sprintf("%s[ov%d]overlay=x=%d:y=%d:eof_action=pass:enable='between(t,%s,%s)'%s",
+			base, j, overlay.X, overlay.Y, start, strconv.FormatFloat(end, 'f', 3, 64), next))
+		base = next
+	}
+
+	if len(tl.Audio) > 0 {
+		labels := audioOut
+		for j, track := range tl.Audio {
+			if track.StartSeconds >= total {
+				return "", 0, fmt.Errorf("audio track %d: start_seconds (%g) is not before the end of the %gs timeline", j, track.StartSeconds, total)
+			}
+			input := len(tl.Clips) + len(tl.Overlays) + j
+			chain := fmt.Sprintf("[%d:a]", input)
+			if track.TrimStartSeconds > 0 {
+				chain += fmt.Sprintf("atrim=start=%s,asetpts=PTS-STARTPTS,", strconv.FormatFloat(track.TrimStartSeconds, 'f', 3, 64))
+			}
+			chain += timelineAudioFormat + volumeFilter(track.Volume)
+			if delayMs := int(track.StartSeconds * 1000); delayMs > 0 {
+				chain += fmt.Sprintf(",adelay=%d:all=1", delayMs)
+			}
+			parts = append(parts, fmt.Sprintf("%s[ta%d]", chain, j))
+			labels += fmt.Sprintf("[ta%d]", j)
+		}
+		// The clips' audio spans the whole timeline, so it sets the length of the mix.
+		parts = append(parts, fmt.Sprintf("%samix=inputs=%d:duration=first:dropout_transition=0:normalize=0[a]", labels, len(tl.Audio)+1))
+	}
+	return strings.Join(parts, ";"), total, nil
+}
+
+// executeRenderTimeline renders a timeline with a filter graph from buildTimelineFilter into an
+// H.264/AAC MP4 of totalSeconds. Still image overlays are looped for the whole timeline.
+func executeRenderTimeline(ctx context.Context, localClips, localOverlays, localAudio []string, filterGraph string, totalSeconds float64, tempOutputFile string) (string, error) {
+	total := strconv.FormatFloat(totalSeconds, 'f', 3, 64)
+	args := []string{"-y"}
+	for _, clip := range localClips {
+		args = append(args, "-i", clip)
+	}
+	for _, overlay := range localOverlays {
+		if isStillImageFile(overlay) {
+			args = append(args, "-loop", "1", "-t", total)
+		}
+		args = append(args, "-i", overlay)
+	}
+	for _, audio := range localAudio {
+		args = append(args, "-i", audio)
+	}
+	args = append(args, "-filter_complex", filterGraph,
+		"-map", "[v]", "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-map", "[a]", "-c:a", "aac", "-b:a", "192k",
+		"-t", total,
+		"-movflags", "+faststart",
+		tempOutputFile)
+	return runFFmpegCommand(ctx, args...)
+}
+
+// timelineDetails is the tool-specific part of the 'render_timeline' result.
+type timelineDetails struct {
+	DurationSeconds float64   `json:"duration_seconds"`
+	ClipSeconds     []float64 `json:"clip_seconds"`
+	Overlays        int       `json:"overlays"`
+	AudioTracks     int       `json:"audio_tracks"`
+}
+
+// addRenderTimelineTool defines and registers the 'render_timeline' tool.
+func addRenderTimelineTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("render_timeline",
+		mcp.WithDescription("Renders a complete edit described by a JSON timeline into one H.264/AAC MP4: trimmed video clips played in order and joined with cuts or transitions, image or video overlays shown at given times, and audio tracks such as background music mixed with the clips' audio. The timeline is validated before anything is rendered."),
+		mcp.WithObject("timeline", mcp.Required(), mcp.Description(fmt.Sprintf("The timeline. 'clips' (1 to %d) play back to back; each has 'uri' (local path or gs://), optional 'trim_start_seconds' and 'trim_end_seconds', 'volume' (0 to %g, default 1) for its own audio, and 'transition' (%s) with 'transition_seconds' into the next clip. 'overlays' (at most %d) have 'uri' (an image or video), 'start_seconds' and 'end_seconds' in timeline time (end defaults to the end of the timeline), 'x' and 'y' of the top-left corner, and optional 'width' (the height keeps the aspect ratio). 'audio' tracks (at most %d) have 'uri', 'start_seconds' in timeline time, optional 'trim_start_seconds' and 'volume'; they are cut at the end of the timeline. Optional 'width' and 'height' (default %dx%d) and 'fps' (default %d) set the output video; clips are scaled to fit with letterboxing.", maxTimelineClips, maxTimelineVolume, strings.Join(slideshowTransitions, ", "), maxTimelineOverlays, maxTimelineAudioTracks, defaultTimelineWidth, defaultTimelineHeight, defaultTimelineFPS)), mcp.Properties(map[string]any{
+			"width":  map[string]any{"type": "integer", "description": "Output width in pixels (even)."},
+			"height": map[string]any{"type": "integer", "description": "Output height in pixels (even)."},
+			"fps":    map[string]any{"type": "integer", "description": "Output frame rate."},
+			"clips": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"uri":                map[string]any{"type": "string"},
+						"trim_start_seconds": map[string]any{"type": "number"},
+						"trim_end_seconds":   map[string]any{"type": "number"},
+						"volume":             map[string]any{"type": "number"},
+						"transition":         map[string]any{"type": "string", "enum": slideshowTransitions},
+						"transition_seconds": map[string]any{"type": "number"},
+					},
+					"required": []string{"uri"},
+				},
+			},
+			"overlays": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"uri":           map[string]any{"type": "string"},
+						"start_seconds": map[string]any{"type": "number"},
+						"end_seconds":   map[string]any{"type": "number"},
+						"x":             map[string]any{"type": "integer"},
+						"y":             map[string]any{"type": "integer"},
+						"width":         map[string]any{"type": "integer"},
+					},
+					"required": []string{"uri"},
+				},
+			},
+			"audio": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"uri":                map[string]any{"type": "string"},
+						"start_seconds":      map[string]any{"type": "number"},
+						"trim_start_seconds": map[string]any{"type": "number"},
+						"volume":             map[string]any{"type": "number"},
+					},
+					"required": []string{"uri"},
+				},
+			},
+		})),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'edit.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return renderTimelineHandler(ctx, request, cfg)
+	})
+}
+
+// renderTimelineHandler is the handler for the timeline tool.
+// It validates the timeline, fetches and probes its inputs, and renders the edit.
+func renderTimelineHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "render_timeline")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "render_timeline", argsMap)
+
+	// Clients may send the timeline as an object or as a JSON string.
+	var timelineJSON []byte
+	switch v := argsMap["timeline"].(type) {
+	case string:
+		timelineJSON = []byte(v)
+	case map[string]interface{}:
+		if timelineJSON, err = json.Marshal(v); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid timeline: %v", err)), nil
+		}
+	default:
+		return mcp.NewToolResultError("Parameter 'timeline' is required and must be an object."), nil
+	}
+	tl, err := parseTimeline(timelineJSON)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid timeline: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler render_timeline: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.Int("num_clips", len(tl.Clips)),
+		attribute.Int("num_overlays", len(tl.Overlays)),
+		attribute.Int("num_audio_tracks", len(tl.Audio)),
+		attribute.Int("width", tl.Width),
+		attribute.Int("height", tl.Height),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	var clipURIs, overlayURIs, audioURIs []string
+	for _, clip := range tl.Clips {
+		clipURIs = append(clipURIs, clip.URI)
+	}
+	for _, overlay := range tl.Overlays {
+		overlayURIs = append(overlayURIs, overlay.URI)
+	}
+	for _, track := range tl.Audio {
+		audioURIs = append(audioURIs, track.URI)
+	}
+
+	localClips, clipsCleanup, errPrep := prepareInputFiles(ctx, clipURIs, "timeline_clip", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare clip %v", errPrep)), nil
+	}
+	defer clipsCleanup()
+	localOverlays, overlaysCleanup, errPrep := prepareInputFiles(ctx, overlayURIs, "timeline_overlay", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare overlay %v", errPrep)), nil
+	}
+	defer overlaysCleanup()
+	localAudio, audioCleanup, errPrep := prepareInputFiles(ctx, audioURIs, "timeline_audio", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare audio track %v", errPrep)), nil
+	}
+	defer audioCleanup()
+
+	var infos []timelineClipInfo
+	for i, localPath := range localClips {
+		input := probeOutput(ctx, localPath)
+		if input.VideoCodec == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("Clip %s has no video stream.", clipURIs[i])), nil
+		}
+		infos = append(infos, timelineClipInfo{DurationSeconds: input.DurationSeconds, HasAudio: input.AudioCodec != ""})
+	}
+
+	filterGraph, totalSeconds, err := buildTimelineFilter(tl, infos)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid timeline: %v", err)), nil
+	}
+	span.SetAttributes(attribute.String("filter_graph", filterGraph))
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeRenderTimeline(ctx, localClips, localOverlays, localAudio, filterGraph, totalSeconds, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg timeline rendering failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	clipSeconds, _ := tl.clipSeconds(infos)
+	details := timelineDetails{DurationSeconds: totalSeconds, ClipSeconds: clipSeconds, Overlays: len(tl.Overlays), AudioTracks: len(tl.Audio)}
+	messageParts := []string{fmt.Sprintf("Timeline of %d clip(s), %d overlay(s) and %d audio track(s) rendered to %.2fs in %v.", len(tl.Clips), len(tl.Overlays), len(tl.Audio), totalSeconds, duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "render_timeline", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, details), nil
+}
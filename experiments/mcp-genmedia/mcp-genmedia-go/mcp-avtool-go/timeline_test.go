@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTimeline(t *testing.T) {
+	tl, err := parseTimeline([]byte(`{"clips": [{"uri": "a.mp4", "transition": "Fade", "transition_seconds": 0.5}, {"uri": "b.mp4"}]}`))
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if tl.Width != defaultTimelineWidth || tl.Height != defaultTimelineHeight || tl.FPS != defaultTimelineFPS {
+		t.Errorf("expected the default output settings, but got %dx%d at %d fps", tl.Width, tl.Height, tl.FPS)
+	}
+	if tl.Clips[0].Transition != "fade" || tl.Clips[1].Transition != "none" {
+		t.Errorf("expected the transitions to be normalized, but got %q and %q", tl.Clips[0].Transition, tl.Clips[1].Transition)
+	}
+
+	tests := map[string]string{
+		"malformed JSON":           `{"clips": [`,
+		"unknown field":            `{"clips": [{"uri": "a.mp4", "speed": 2}]}`,
+		"trailing data":            `{"clips": [{"uri": "a.mp4"}]} {}`,
+		"no clips":                 `{"clips": []}`,
+		"missing uri":              `{"clips": [{"trim_start_seconds": 1}]}`,
+		"trim end before start":    `{"clips": [{"uri": "a.mp4", "trim_start_seconds": 4, "trim_end_seconds": 2}]}`,
+		"unknown transition":       `{"clips": [{"uri": "a.mp4", "transition": "spin", "transition_seconds": 1}, {"uri": "b.mp4"}]}`,
+		"transition without time":  `{"clips": [{"uri": "a.mp4", "transition": "fade"}, {"uri": "b.mp4"}]}`,
+		"transition on last clip":  `{"clips": [{"uri": "a.mp4", "transition": "fade", "transition_seconds": 1}]}`,
+		"volume too high":          `{"clips": [{"uri": "a.mp4", "volume": 10}]}`,
+		"odd width":                `{"width": 641, "clips": [{"uri": "a.mp4"}]}`,
+		"overlay end before start": `{"clips": [{"uri": "a.mp4"}], "overlays": [{"uri": "logo.png", "start_seconds": 3, "end_seconds": 1}]}`,
+		"negative audio start":     `{"clips": [{"uri": "a.mp4"}], "audio": [{"uri": "music.mp3", "start_seconds": -1}]}`,
+	}
+	for name, input := range tests {
+		if _, err := parseTimeline([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error, but got nil", name)
+		}
+	}
+}
+
+func TestBuildTimelineFilter(t *testing.T) {
+	tl, err := parseTimeline([]byte(`{
+		"clips": [
+			{"uri": "a.mp4", "trim_start_seconds": 2, "trim_end_seconds": 6, "transition": "fade", "transition_seconds": 1},
+			{"uri": "b.mp4", "trim_end_seconds": 5, "volume": 0}
+		],
+		"overlays": [{"uri": "logo.png", "start_seconds": 1, "end_seconds": 3, "x": 20, "y": 20, "width": 200}],
+		"audio": [{"uri": "music.mp3", "start_seconds": 0.5, "volume": 0.3}]
+	}`))
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	got, total, err := buildTimelineFilter(tl, []timelineClipInfo{{DurationSeconds: 10, HasAudio: true}, {DurationSeconds: 8}})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if total != 8 {
+		t.Errorf("expected an 8s timeline (4s + 5s less the 1s transition), but got %gs", total)
+	}
+	for _, want := range []string{
+		"[0:v]trim=start=2.000:end=6.000,setpts=PTS-STARTPTS,scale=1280:720:force_original_aspect_ratio=decrease,pad=1280:720:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p[cv0]",
+		"[0:a]atrim=start=2.000:end=6.000,asetpts=PTS-STARTPTS," + timelineAudioFormat + "[ca0]",
+		"anullsrc=r=48000:cl=stereo,atrim=duration=5.000[ca1]",
+		"[cv0][cv1]xfade=transition=fade:duration=1:offset=3.000[main]",
+		"[ca0][ca1]acrossfade=d=1:c1=tri:c2=tri[clipaudio]",
+		"[2:v]scale=200:-2,setpts=PTS-STARTPTS+1.000/TB[ov0]",
+		"[main][ov0]overlay=x=20:y=20:eof_action=pass:enable='between(t,1.000,3.000)'[v]",
+		"[3:a]" + timelineAudioFormat + ",volume=0.3,adelay=500:all=1[ta0]",
+		"[clipaudio][ta0]amix=inputs=2:duration=first:dropout_transition=0:normalize=0[a]",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected the filter graph to contain %q, but got %q", want, got)
+		}
+	}
+
+	cuts, err := parseTimeline([]byte(`{"clips": [{"uri": "a.mp4"}, {"uri": "b.mp4"}, {"uri": "c.mp4"}]}`))
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	got, total, err = buildTimelineFilter(cuts, []timelineClipInfo{{3, true}, {2, true}, {4, true}})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if total != 9 {
+		t.Errorf("expected a 9s timeline, but got %gs", total)
+	}
+	if !strings.Contains(got, "[cv0][cv1]concat=n=2:v=1:a=0[jv1]") || !strings.Contains(got, "[jv1][cv2]concat=n=2:v=1:a=0[v]") || !strings.HasSuffix(got, "[ja1][ca2]concat=n=2:v=0:a=1[a]") {
+		t.Errorf("expected the clips to be joined with cuts, but got %q", got)
+	}
+
+	single, _ := parseTimeline([]byte(`{"clips": [{"uri": "a.mp4"}]}`))
+	if got, _, err := buildTimelineFilter(single, []timelineClipInfo{{3, true}}); err != nil || !strings.HasSuffix(got, "[cv0]null[v];[ca0]anull[a]") {
+		t.Errorf("expected a single clip to be passed through, but got %q (error: %v)", got, err)
+	}
+
+	for name, tt := range map[string]struct {
+		timeline string
+		infos    []timelineClipInfo
+	}{
+		"trim past the end":        {`{"clips": [{"uri": "a.mp4", "trim_end_seconds": 12}]}`, []timelineClipInfo{{10, true}}},
+		"trim start past the end":  {`{"clips": [{"uri": "a.mp4", "trim_start_seconds": 12}]}`, []timelineClipInfo{{10, true}}},
+		"transition too long":      {`{"clips": [{"uri": "a.mp4", "transition": "fade", "transition_seconds": 3}, {"uri": "b.mp4"}]}`, []timelineClipInfo{{10, true}, {2, true}}},
+		"overlay after the end":    {`{"clips": [{"uri": "a.mp4"}], "overlays": [{"uri": "logo.png", "start_seconds": 12}]}`, []timelineClipInfo{{10, true}}},
+		"audio after the end":      {`{"clips": [{"uri": "a.mp4"}], "audio": [{"uri": "music.mp3", "start_seconds": 10}]}`, []timelineClipInfo{{10, true}}},
+		"missing probed clip info": {`{"clips": [{"uri": "a.mp4"}, {"uri": "b.mp4"}]}`, []timelineClipInfo{{10, true}}},
+	} {
+		tl, err := parseTimeline([]byte(tt.timeline))
+		if err != nil {
+			t.Fatalf("%s: expected the timeline to parse, but got %v", name, err)
+		}
+		if _, _, err := buildTimelineFilter(tl, tt.infos); err == nil {
+			t.Errorf("%s: expected an error, but got nil", name)
+		}
+	}
+}
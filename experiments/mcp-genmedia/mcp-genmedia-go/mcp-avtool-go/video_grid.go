@@ -0,0 +1,261 @@
+// Package main implements an MCP server for audio and video processing.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxGridVideos caps the number of videos of a 'video_grid' call.
+	maxGridVideos = 16
+	// defaultGridCellWidth and defaultGridCellHeight are the default size of each grid cell.
+	defaultGridCellWidth  = 640
+	defaultGridCellHeight = 360
+	// gridFPS is the frame rate of grid videos.
+	gridFPS = 30
+)
+
+// videoGridOptions describes a grid of VideoCount videos, filled row by row, Columns wide, with
+// each video scaled and padded to CellWidth x CellHeight.
+type videoGridOptions struct {
+	VideoCount            int
+	Columns               int // 0 picks the smallest square-ish grid; see columns.
+	CellWidth, CellHeight int
+	AudioFrom             int // 1-based index of the video whose audio is kept; 0 for a silent grid.
+}
+
+// columns returns the number of columns of the grid: Columns if set, or the square root of the
+// number of videos rounded up, e.g. 2 for 4 videos and 3 for 5 to 9 videos.
+func (opts videoGridOptions) columns() int {
+	if opts.Columns > 0 {
+		return opts.Columns
+	}
+	return int(math.Ceil(math.Sqrt(float64(opts.VideoCount))))
+}
+
+// rows returns the number of rows of the grid.
+func (opts videoGridOptions) rows() int {
+	columns := opts.columns()
+	return (opts.VideoCount + columns - 1) / columns
+}
+
+// validate checks the grid options.
+func (opts videoGridOptions) validate() error {
+	if opts.VideoCount < 2 || opts.VideoCount > maxGridVideos {
+		return fmt.Errorf("between 2 and %d videos are required, got %d", maxGridVideos, opts.VideoCount)
+	}
+	if opts.Columns < 0 || opts.Columns > opts.VideoCount {
+		return fmt.Errorf("columns must be between 1 and the number of videos (%d), got %d", opts.VideoCount, opts.Columns)
+	}
+	if err := validateEvenDimension("cell_width", opts.CellWidth); err != nil {
+		return err
+	}
+	if err := validateEvenDimension("cell_height", opts.CellHeight); err != nil {
+		return err
+	}
+	if width, height := opts.columns()*opts.CellWidth, opts.rows()*opts.CellHeight; width > maxScaleDimension || height > maxScaleDimension {
+		return fmt.Errorf("the grid would be %dx%d, larger than %d pixels on a side; use smaller cells", width, height, maxScaleDimension)
+	}
+	if opts.AudioFrom < 0 || opts.AudioFrom > opts.VideoCount {
+		return fmt.Errorf("audio_from must be between 0 (no audio) and the number of videos (%d), got %d", opts.VideoCount, opts.AudioFrom)
+	}
+	return nil
+}
+
+// buildVideoGridFilter returns the filter graph that scales and pads each video to the cell size
+// and tiles them with xstack into [v], row by row. Empty cells of the last row are black. The
+// grid ends with the shortest video, so that all the videos play in sync.
+func buildVideoGridFilter(opts videoGridOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	var parts []string
+	var labels strings.Builder
+	layout := make([]string, opts.VideoCount)
+	columns := opts.columns()
+	for i := 0; i < opts.VideoCount; i++ {
+		parts = append(parts, fmt.Sprintf("[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d,format=yuv420p,setpts=PTS-STARTPTS[v%d]",
+			i, opts.CellWidth, opts.CellHeight, opts.CellWidth, opts.CellHeight, gridFPS, i))
+		fmt.Fprintf(&labels, "[v%d]", i)
+		layout[i] = fmt.Sprintf("%d_%d", (i%columns)*opts.CellWidth, (i/columns)*opts.CellHeight)
+	}
+	parts = append(parts, fmt.Sprintf("%sxstack=inputs=%d:layout=%s:fill=black:shortest=1[v]", labels.String(), opts.VideoCount, strings.Join(layout, "|")))
+	return strings.Join(parts, ";"), nil
+}
+
+// executeVideoGrid renders the videos into an H.264 MP4 with a filter graph from
+// buildVideoGridFilter, with the audio of the AudioFrom video (AAC) cut to the grid's length. The
+// audio is mapped optionally, so a video without audio gives a silent grid rather than an error.
+func executeVideoGrid(ctx context.Context, localVideos []string, opts videoGridOptions, filterGraph, tempOutputFile string) (string, error) {
+	return runFFmpegCommand(ctx, buildVideoGridArgs(localVideos, opts, filterGraph, tempOutputFile)...)
+}
+
+// buildVideoGridArgs returns the FFMpeg arguments of executeVideoGrid.
+func buildVideoGridArgs(localVideos []string, opts videoGridOptions, filterGraph, tempOutputFile string) []string {
+	args := []string{"-y"}
+	for _, video := range localVideos {
+		args = append(args, "-i", video)
+	}
+	args = append(args, "-filter_complex", filterGraph,
+		"-map", "[v]", "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p")
+	if opts.AudioFrom > 0 {
+		args = append(args, "-map", fmt.Sprintf("%d:a?", opts.AudioFrom-1), "-c:a", "aac", "-b:a", "192k", "-shortest")
+	}
+	return append(args, "-movflags", "+faststart", tempOutputFile)
+}
+
+// videoGridDetails is the tool-specific part of the 'video_grid' result.
+type videoGridDetails struct {
+	Columns    int `json:"columns"`
+	Rows       int `json:"rows"`
+	CellWidth  int `json:"cell_width"`
+	CellHeight int `json:"cell_height"`
+}
+
+// addVideoGridTool defines and registers the 'video_grid' tool.
+func addVideoGridTool(s *server.MCPServer, cfg *common.Config) {
+	tool := mcp.NewTool("video_grid",
+		mcp.WithDescription("Tiles several videos, e.g. the candidates of a Veo generation, into one comparison video. Each video is scaled to fit a common cell size with letterboxing, and the cells are laid out row by row in a grid with FFMpeg's xstack filter. All the videos start together and the grid ends with the shortest one, so they play in sync."),
+		mcp.WithArray("input_video_uris", mcp.Required(), mcp.Description(fmt.Sprintf("The videos in grid order (left to right, then top to bottom), as URIs (local paths or gs://). 2 to %d videos.", maxGridVideos)), mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithNumber("columns", mcp.Description("Optional. The number of columns of the grid. Defaults to the square root of the number of videos, rounded up, e.g. 2 for a 2x2 grid of 4 videos.")),
+		mcp.WithNumber("cell_width", mcp.DefaultNumber(defaultGridCellWidth), mcp.Description(fmt.Sprintf("Optional. Width of each cell in pixels (even). Defaults to %d.", defaultGridCellWidth))),
+		mcp.WithNumber("cell_height", mcp.DefaultNumber(defaultGridCellHeight), mcp.Description(fmt.Sprintf("Optional. Height of each cell in pixels (even). Defaults to %d.", defaultGridCellHeight))),
+		mcp.WithNumber("audio_from", mcp.DefaultNumber(0), mcp.Description("Optional. The 1-based position of the video whose audio is kept. Defaults to 0, a silent grid.")),
+		mcp.WithString("output_file_name", mcp.Description("Optional. Desired name for the output video file (e.g., 'grid.mp4'). If omitted, a unique name is generated.")),
+		mcp.WithString("output_local_dir", mcp.Description("Optional. Local directory to save the output video file.")),
+		mcp.WithString("output_gcs_bucket", mcp.Description("Optional. GCS bucket to upload the output video file to (uses GENMEDIA_BUCKET if set and this is empty).")),
+	)
+	addTrackedTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return videoGridHandler(ctx, request, cfg)
+	})
+}
+
+// videoGridHandler is the handler for the 'video_grid' tool.
+// It tiles the videos into an H.264 MP4, with the audio of one of them if requested.
+func videoGridHandler(ctx context.Context, request mcp.CallToolRequest, cfg *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "video_grid")
+	defer span.End()
+
+	startTime := time.Now()
+	argsMap, err := getArguments(request)
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	common.LogInfof("Handling %s request with arguments: %v", "video_grid", argsMap)
+
+	videoURIsRaw, _ := argsMap["input_video_uris"].([]interface{})
+	var videoURIs []string
+	for _, item := range videoURIsRaw {
+		if uri, ok := item.(string); ok && strings.TrimSpace(uri) != "" {
+			videoURIs = append(videoURIs, uri)
+		}
+	}
+
+	opts := videoGridOptions{
+		VideoCount: len(videoURIs),
+		CellWidth:  defaultGridCellWidth,
+		CellHeight: defaultGridCellHeight,
+	}
+	if v, ok := argsMap["columns"].(float64); ok {
+		opts.Columns = int(v)
+	}
+	if v, ok := argsMap["cell_width"].(float64); ok {
+		opts.CellWidth = int(v)
+	}
+	if v, ok := argsMap["cell_height"].(float64); ok {
+		opts.CellHeight = int(v)
+	}
+	if v, ok := argsMap["audio_from"].(float64); ok {
+		opts.AudioFrom = int(v)
+	}
+	filterGraph, err := buildVideoGridFilter(opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid grid parameters: %v", err)), nil
+	}
+
+	outputFileName, _ := argsMap["output_file_name"].(string)
+	outputLocalDir, _ := argsMap["output_local_dir"].(string)
+	outputGCSBucket, _ := argsMap["output_gcs_bucket"].(string)
+	outputGCSBucket = strings.TrimSpace(outputGCSBucket)
+	if outputGCSBucket == "" && cfg.GenmediaBucket != "" {
+		outputGCSBucket = cfg.GenmediaBucket
+		common.LogInfof("Handler video_grid: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", outputGCSBucket)
+	}
+	if outputGCSBucket != "" {
+		outputGCSBucket = strings.TrimPrefix(outputGCSBucket, "gs://")
+	}
+
+	span.SetAttributes(
+		attribute.StringSlice("input_video_uris", videoURIs),
+		attribute.Int("columns", opts.columns()),
+		attribute.Int("cell_width", opts.CellWidth),
+		attribute.Int("cell_height", opts.CellHeight),
+		attribute.Int("audio_from", opts.AudioFrom),
+		attribute.String("output_file_name", outputFileName),
+		attribute.String("output_local_dir", outputLocalDir),
+		attribute.String("output_gcs_bucket", outputGCSBucket),
+	)
+
+	localVideos, inputCleanup, errPrep := prepareInputFiles(ctx, videoURIs, "grid_input", cfg.ProjectID)
+	if errPrep != nil {
+		span.RecordError(errPrep)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare video %v", errPrep)), nil
+	}
+	defer inputCleanup()
+	if opts.AudioFrom > 0 {
+		// Only a successful probe can tell that the video has no audio; otherwise FFMpeg decides.
+		audioVideo := localVideos[opts.AudioFrom-1]
+		if probeJSON, err := executeGetMediaInfo(ctx, audioVideo); err != nil {
+			common.LogWarnf("Warning: could not probe %s for audio: %v", audioVideo, err)
+		} else if input, err := parseProbeOutput(probeJSON); err == nil && input.AudioCodec == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("Video %d (%s) has no audio stream to keep. Pick another video with audio_from, or 0 for a silent grid.", opts.AudioFrom, videoURIs[opts.AudioFrom-1])), nil
+		}
+	}
+
+	tempOutputFile, finalOutputFilename, outputCleanup, err := common.HandleOutputPreparation(outputFileName, "mp4")
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to prepare output file: %v", err)), nil
+	}
+	defer outputCleanup()
+
+	if _, ffmpegErr := executeVideoGrid(ctx, localVideos, opts, filterGraph, tempOutputFile); ffmpegErr != nil {
+		span.RecordError(ffmpegErr)
+		return mcp.NewToolResultError(fmt.Sprintf("FFMpeg video grid failed: %v", ffmpegErr)), nil
+	}
+
+	output := probeOutput(ctx, tempOutputFile)
+	finalLocalPath, finalGCSPath, processErr := common.ProcessOutputAfterFFmpeg(ctx, tempOutputFile, finalOutputFilename, outputLocalDir, outputGCSBucket, cfg.ProjectID)
+	if processErr != nil {
+		span.RecordError(processErr)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process FFMpeg output: %v", processErr)), nil
+	}
+
+	duration := time.Since(startTime)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(duration.Milliseconds())))
+
+	details := videoGridDetails{Columns: opts.columns(), Rows: opts.rows(), CellWidth: opts.CellWidth, CellHeight: opts.CellHeight}
+	messageParts := []string{fmt.Sprintf("Tiled %d video(s) into a %dx%d grid (%dx%d pixels) in %v.", opts.VideoCount, details.Columns, details.Rows, details.Columns*opts.CellWidth, details.Rows*opts.CellHeight, duration)}
+	if outputLocalDir != "" && finalLocalPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output saved locally to: %s.", finalLocalPath))
+	} else if finalLocalPath != "" && (outputGCSBucket == "" || finalGCSPath == "") {
+		messageParts = append(messageParts, fmt.Sprintf("Temporary output was at: %s (cleaned up if not moved/uploaded).", finalLocalPath))
+	}
+	if finalGCSPath != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Output uploaded to GCS: %s.", finalGCSPath))
+	}
+	return newAvtoolResult(ctx, "video_grid", strings.Join(messageParts, " "), duration, []avtoolOutput{output.savedTo(outputLocalDir, finalLocalPath, finalGCSPath)}, details), nil
+}
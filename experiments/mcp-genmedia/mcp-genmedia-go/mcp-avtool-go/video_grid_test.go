@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildVideoGridFilter(t *testing.T) {
+	got, err := buildVideoGridFilter(videoGridOptions{VideoCount: 4, CellWidth: 640, CellHeight: 360})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[0:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p,setpts=PTS-STARTPTS[v0];" +
+		"[1:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p,setpts=PTS-STARTPTS[v1];" +
+		"[2:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p,setpts=PTS-STARTPTS[v2];" +
+		"[3:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30,format=yuv420p,setpts=PTS-STARTPTS[v3];" +
+		"[v0][v1][v2][v3]xstack=inputs=4:layout=0_0|640_0|0_360|640_360:fill=black:shortest=1[v]"
+	if got != want {
+		t.Errorf("expected filter\n%s\nbut got\n%s", want, got)
+	}
+}
+
+func TestVideoGridLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       videoGridOptions
+		wantLayout string
+		wantRows   int
+	}{
+		{name: "3 videos in a 2x2 grid", opts: videoGridOptions{VideoCount: 3, CellWidth: 320, CellHeight: 180}, wantLayout: "layout=0_0|320_0|0_180:", wantRows: 2},
+		{name: "5 videos in 3 columns", opts: videoGridOptions{VideoCount: 5, CellWidth: 320, CellHeight: 180}, wantLayout: "layout=0_0|320_0|640_0|0_180|320_180:", wantRows: 2},
+		{name: "one row", opts: videoGridOptions{VideoCount: 3, Columns: 3, CellWidth: 320, CellHeight: 180}, wantLayout: "layout=0_0|320_0|640_0:", wantRows: 1},
+		{name: "one column", opts: videoGridOptions{VideoCount: 2, Columns: 1, CellWidth: 320, CellHeight: 180}, wantLayout: "layout=0_0|0_180:", wantRows: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildVideoGridFilter(tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(got, tt.wantLayout) {
+				t.Errorf("expected filter to contain %q, but got %s", tt.wantLayout, got)
+			}
+			if rows := tt.opts.rows(); rows != tt.wantRows {
+				t.Errorf("expected %d rows, but got %d", tt.wantRows, rows)
+			}
+		})
+	}
+}
+
+func TestVideoGridOptionsValidate(t *testing.T) {
+	valid := videoGridOptions{VideoCount: 4, CellWidth: 640, CellHeight: 360, AudioFrom: 1}
+	if err := valid.validate(); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        videoGridOptions
+		errContains string
+	}{
+		{name: "one video", opts: videoGridOptions{VideoCount: 1, CellWidth: 640, CellHeight: 360}, errContains: "between 2 and 16 videos"},
+		{name: "too many columns", opts: videoGridOptions{VideoCount: 4, Columns: 5, CellWidth: 640, CellHeight: 360}, errContains: "columns must be between"},
+		{name: "odd cell width", opts: videoGridOptions{VideoCount: 4, CellWidth: 641, CellHeight: 360}, errContains: "cell_width must be an even number"},
+		{name: "grid too large", opts: videoGridOptions{VideoCount: 16, Columns: 16, CellWidth: 1920, CellHeight: 1080}, errContains: "use smaller cells"},
+		{name: "audio out of range", opts: videoGridOptions{VideoCount: 4, CellWidth: 640, CellHeight: 360, AudioFrom: 5}, errContains: "audio_from must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, but got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestBuildVideoGridArgs(t *testing.T) {
+	videos := []string{"a.mp4", "b.mp4"}
+	silent := strings.Join(buildVideoGridArgs(videos, videoGridOptions{VideoCount: 2}, "graph", "out.mp4"), " ")
+	if strings.Contains(silent, ":a") || strings.Contains(silent, "-c:a") {
+		t.Errorf("expected no audio for a silent grid, but got %s", silent)
+	}
+	withAudio := strings.Join(buildVideoGridArgs(videos, videoGridOptions{VideoCount: 2, AudioFrom: 2}, "graph", "out.mp4"), " ")
+	if !strings.Contains(withAudio, "-map 1:a? -c:a aac") {
+		t.Errorf("expected the audio of the second video to be mapped optionally, but got %s", withAudio)
+	}
+	if !strings.HasSuffix(withAudio, "-movflags +faststart out.mp4") {
+		t.Errorf("expected the output last, but got %s", withAudio)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,6 +31,7 @@ import (
 )
 
 var (
+	appConfig       *common.Config
 	ttsClient       *texttospeech.Client // Global Text-to-Speech client
 	availableVoices []*texttospeechpb.Voice
 	transport       string
@@ -53,6 +55,32 @@ var validChirpRegions = map[string]bool{
 	"asia-northeast1": true,
 }
 
+// chirpAudioEncoding is an output encoding of the chirp_tts tool.
+type chirpAudioEncoding struct {
+	apiEncoding texttospeechpb.AudioEncoding
+	format      common.AudioFormat // Extension and MIME type of the output, and the ffmpeg format used to transcode to it.
+}
+
+// chirpAudioEncodingNames lists the encodings accepted by the 'audio_encoding' parameter.
+var chirpAudioEncodingNames = []string{"LINEAR16", "MP3", "OGG_OPUS"}
+
+// wavAudioFormat is the format of LINEAR16 output, which the API returns with a WAV header.
+var wavAudioFormat = mustLookupAudioFormat("wav")
+
+var chirpAudioEncodings = map[string]chirpAudioEncoding{
+	"LINEAR16": {apiEncoding: texttospeechpb.AudioEncoding_LINEAR16, format: wavAudioFormat},
+	"MP3":      {apiEncoding: texttospeechpb.AudioEncoding_MP3, format: mustLookupAudioFormat("mp3")},
+	"OGG_OPUS": {apiEncoding: texttospeechpb.AudioEncoding_OGG_OPUS, format: mustLookupAudioFormat("ogg")},
+}
+
+func mustLookupAudioFormat(name string) common.AudioFormat {
+	format, ok := common.LookupAudioFormat(name)
+	if !ok {
+		panic("unknown audio format " + name)
+	}
+	return format
+}
+
 // LanguageNameToCodeMap maps descriptive language names (lowercase) to BCP-47 codes (canonical casing).
 var LanguageNameToCodeMap = map[string]string{
 	"german (germany)":         "de-DE",
@@ -96,7 +124,6 @@ func init() {
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse, or http)")
 	flag.IntVar(&port, "p", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
 	flag.IntVar(&port, "port", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
-	flag.Parse()
 
 	titleCaser := cases.Title(language.Und)
 	for k := range LanguageNameToCodeMap {
@@ -112,21 +139,21 @@ func getChirpClientOptions(location string) []option.ClientOption {
 
 	// Handle the default us-central1 fallback gracefully
 	if loc == "us-central1" {
-		log.Printf("Warning: 'us-central1' is not a supported region for Chirp3-HD. Automatically mapping to 'us'.")
+		common.LogWarnf("Warning: 'us-central1' is not a supported region for Chirp3-HD. Automatically mapping to 'us'.")
 		loc = "us"
 	}
 
 	if !validChirpRegions[loc] {
-		log.Printf("Warning: Unsupported Chirp3-HD region '%s'. Falling back to 'global'. Supported regions: global, us, eu, asia-southeast1, europe-west2, asia-northeast1", loc)
+		common.LogWarnf("Warning: Unsupported Chirp3-HD region '%s'. Falling back to 'global'. Supported regions: global, us, eu, asia-southeast1, europe-west2, asia-northeast1", loc)
 		loc = "global"
 	}
 
 	if loc != "global" {
 		endpoint := fmt.Sprintf("%s-texttospeech.googleapis.com:443", loc)
-		log.Printf("Routing Chirp API calls to regional endpoint: %s", endpoint)
+		common.LogInfof("Routing Chirp API calls to regional endpoint: %s", endpoint)
 		opts = append(opts, option.WithEndpoint(endpoint))
 	} else {
-		log.Printf("Routing Chirp API calls to global endpoint.")
+		common.LogInfof("Routing Chirp API calls to global endpoint.")
 	}
 
 	return opts
@@ -137,7 +164,7 @@ func getChirpClientOptions(location string) []option.ClientOption {
 // Chirp3-HD voices. This cached list is used by other functions to validate
 // voice selections and provide voice options.
 func listAndCacheChirpHDVoices(ctx context.Context, location string) error {
-	log.Println("Fetching available Chirp3-HD voices...")
+	common.LogInfof("Fetching available Chirp3-HD voices...")
 
 	opts := getChirpClientOptions(location)
 	tempClient, err := texttospeech.NewClient(ctx, opts...)
@@ -160,18 +187,21 @@ func listAndCacheChirpHDVoices(ctx context.Context, location string) error {
 	availableVoices = foundVoices
 
 	if len(availableVoices) == 0 {
-		log.Println("Warning: No Chirp3-HD voices found. TTS functionality might be limited.")
+		common.LogWarnf("Warning: No Chirp3-HD voices found. TTS functionality might be limited.")
 	} else {
-		log.Printf("Found and cached %d Chirp3-HD voices.", len(availableVoices))
+		common.LogInfof("Found and cached %d Chirp3-HD voices.", len(availableVoices))
 	}
 	return nil
 }
 
 // parseMcpPronunciations processes custom pronunciation parameters provided in an MCP request.
-// It takes the raw `pronunciations` parameter (expected as an array of strings)
-// and an encoding string ('ipa' or 'xsampa'). Each string in the array should be in
-// the format 'phrase:phonetic_form'. The function validates the inputs and converts
-// them into the appropriate protobuf message structure required by the Text-to-Speech API.
+// It takes the raw `pronunciations` parameter and an encoding string ('ipa' or 'xsampa').
+// Each item is either a string in the format 'phrase:phonetic_form' or an object with
+// `phrase` and `pronunciation` fields. In the string format the phrase ends at the first
+// unescaped colon, so colons in the pronunciation (e.g. X-SAMPA length marks) need no
+// escaping, while a literal colon in the phrase is written as '\:'. The function validates
+// the inputs and converts them into the protobuf message structure required by the
+// Text-to-Speech API.
 func parseMcpPronunciations(pronunciationsParam interface{}, encodingStr string) (*texttospeechpb.CustomPronunciations, error) {
 	if pronunciationsParam == nil {
 		return nil, nil // No pronunciations provided
@@ -198,24 +228,29 @@ func parseMcpPronunciations(pronunciationsParam interface{}, encodingStr string)
 
 	var parsedParams []*texttospeechpb.CustomPronunciationParams
 	for i, item := range pronunciationItems {
-		entryStr, ok := item.(string)
-		if !ok {
-			return nil, fmt.Errorf("pronunciation item at index %d is not a string, got %T", i, item)
-		}
-
-		trimmedEntry := strings.TrimSpace(entryStr)
-		if trimmedEntry == "" {
-			continue
-		}
-		parts := strings.SplitN(trimmedEntry, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("malformed pronunciation entry at index %d: %q. Expected format 'phrase:pronunciation'", i, trimmedEntry)
+		var phrase, pronunciation string
+		switch entry := item.(type) {
+		case string:
+			trimmedEntry := strings.TrimSpace(entry)
+			if trimmedEntry == "" {
+				continue
+			}
+			var found bool
+			phrase, pronunciation, found = splitPronunciationEntry(trimmedEntry)
+			if !found {
+				return nil, fmt.Errorf("malformed pronunciation entry at index %d: %q. Expected format 'phrase:pronunciation'", i, trimmedEntry)
+			}
+		case map[string]interface{}:
+			phrase, _ = entry["phrase"].(string)
+			pronunciation, _ = entry["pronunciation"].(string)
+		default:
+			return nil, fmt.Errorf("pronunciation item at index %d must be a string or an object with 'phrase' and 'pronunciation', got %T", i, item)
 		}
-		phrase := strings.TrimSpace(parts[0])
-		pronunciation := strings.TrimSpace(parts[1])
 
+		phrase = strings.TrimSpace(phrase)
+		pronunciation = strings.TrimSpace(pronunciation)
 		if phrase == "" || pronunciation == "" {
-			return nil, fmt.Errorf("empty phrase or pronunciation in entry at index %d: %q", i, trimmedEntry)
+			return nil, fmt.Errorf("empty phrase or pronunciation in entry at index %d: %v", i, item)
 		}
 
 		params := &texttospeechpb.CustomPronunciationParams{
@@ -235,17 +270,37 @@ func parseMcpPronunciations(pronunciationsParam interface{}, encodingStr string)
 	}, nil
 }
 
+// splitPronunciationEntry splits a 'phrase:pronunciation' entry at the first colon not
+// escaped with a backslash. Escaped colons ('\:') in the phrase are unescaped.
+func splitPronunciationEntry(entry string) (phrase, pronunciation string, found bool) {
+	for i := 0; i < len(entry); i++ {
+		switch entry[i] {
+		case '\\':
+			i++ // Skip the escaped character.
+		case ':':
+			return strings.ReplaceAll(entry[:i], `\:`, ":"), entry[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
 // main is the entry point for the mcp-chirp3-go service.
 // It initializes the OpenTelemetry provider, the Google Cloud Text-to-Speech client,
 // and caches the available Chirp3-HD voices. It then sets up an MCP server, registers
 // the 'chirp_tts' and 'list_chirp_voices' tools, and starts listening for requests
 // on the configured transport (stdio, sse, or http).
 func main() {
+	flag.Parse() // Ensure flags are parsed before use
+
 	// Initialize OpenTelemetry
 	var cleanup func()
-	_, cleanup = common.Init(serviceName, version)
+	appConfig, cleanup = common.Init(serviceName, version)
 	defer cleanup()
-	log.Printf("Initializing global Text-to-Speech client... (Deferred to runtime)")
+	if appConfig.TTSDefaultEncoding != "" && !slices.Contains(chirpAudioEncodingNames, appConfig.TTSDefaultEncoding) {
+		common.LogWarnf("Warning: TTS_DEFAULT_ENCODING %s is not supported by chirp_tts (supported: %s); using %s.", appConfig.TTSDefaultEncoding, strings.Join(chirpAudioEncodingNames, ", "), common.DefaultTTSEncoding)
+	}
+	voiceFallbacks = loadVoiceFallbacks()
+	common.LogInfof("Initializing global Text-to-Speech client... (Deferred to runtime)")
 	// In order to allow mcptools to verify the schema without Google Cloud credentials,
 	// we defer the actual client initialization to the first tool invocation.
 
@@ -257,11 +312,13 @@ func main() {
 	chirpTool := mcp.NewTool("chirp_tts",
 		mcp.WithDescription("Synthesizes speech from text using Google Cloud TTS with Chirp3-HD voices. Returns audio data and optionally saves it locally."),
 		mcp.WithString("text",
-			mcp.Required(),
-			mcp.Description("The text to synthesize into speech."),
+			mcp.Description("The text to synthesize into speech. Either 'text' or 'text_uri' is required."),
+		),
+		mcp.WithString(common.TextURIParam,
+			mcp.Description("Optional. A local path or gs:// URI of a UTF-8 text file to synthesize instead of 'text', e.g. for long scripts. Files larger than MAX_INPUT_TEXT_BYTES (1 MB by default) are rejected."),
 		),
 		mcp.WithString("voice_name",
-			mcp.Description(fmt.Sprintf("Optional. The specific Chirp3-HD voice name to use (e.g., '%s'). If not provided, defaults to '%s' if available, otherwise the first available Chirp3-HD voice.", defaultChirpVoiceName, defaultChirpVoiceName)),
+			mcp.Description(fmt.Sprintf("Optional. The specific Chirp3-HD voice name to use (e.g., '%s'). If not provided, defaults to '%s' if available, otherwise the first available Chirp3-HD voice. If the voice is unavailable, the fallback chain configured in CHIRP3_VOICE_FALLBACKS for its language is tried first, and the substitution is reported in the result.", defaultChirpVoiceName, defaultChirpVoiceName)),
 		),
 		mcp.WithString("output_filename_prefix",
 			mcp.DefaultString("chirp_audio"),
@@ -271,61 +328,190 @@ func main() {
 			mcp.Description("Optional. If provided, specifies a local directory to save the generated audio file to. Filenames will be generated automatically using the prefix. If not provided, audio data is returned in the response."),
 		),
 		mcp.WithArray("pronunciations", // New array parameter for pronunciations
-			mcp.Description("Optional. An array of custom pronunciations. Each item should be a string in the format 'phrase:phonetic_representation' (e.g., 'tomato:təˈmeɪtoʊ'). All items must use the same encoding specified by 'pronunciation_encoding'."),
-			mcp.Items(map[string]any{"type": "string"}), // Specify that array items are strings
+			mcp.Description("Optional. An array of custom pronunciations. Each item is either a string in the format 'phrase:phonetic_representation' (e.g., 'tomato:təˈmeɪtoʊ'; escape a colon in the phrase as '\\:') or an object with 'phrase' and 'pronunciation' fields. All items must use the same encoding specified by 'pronunciation_encoding'."),
+			mcp.Items(map[string]any{
+				"anyOf": []map[string]any{
+					{"type": "string"},
+					{
+						"type": "object",
+						"properties": map[string]any{
+							"phrase":        map[string]any{"type": "string"},
+							"pronunciation": map[string]any{"type": "string"},
+						},
+						"required": []string{"phrase", "pronunciation"},
+					},
+				},
+			}),
 		),
 		mcp.WithString("pronunciation_encoding", // New string parameter for encoding type
 			mcp.DefaultString("ipa"), // Default to IPA
 			mcp.Description("Optional. The phonetic encoding used for the 'pronunciations' array. Can be 'ipa' or 'xsampa'. Defaults to 'ipa'."),
 			mcp.Enum("ipa", "xsampa"), // Specify allowed values
 		),
+		mcp.WithBoolean("stream_to_file",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. For long text: splits the text on sentence boundaries, synthesizes the chunks in sequence, and streams the audio into a single WAV file in 'output_directory' (required) so memory use stays bounded. Audio is not returned inline in this mode."),
+		),
+		mcp.WithBoolean("trim_silence",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, trims leading (beginning of speech) and trailing (end of speech) silence from the synthesized audio."),
+		),
+		mcp.WithNumber("silence_threshold",
+			mcp.DefaultNumber(defaultSilenceThreshold),
+			mcp.Description("Optional. Used with trim_silence. Peak amplitude, as a fraction of full scale (0.0-1.0), at or below which audio is treated as silence."),
+		),
+		mcp.WithNumber("max_trim_ms",
+			mcp.DefaultNumber(float64(defaultMaxSilenceTrim.Milliseconds())),
+			mcp.Description("Optional. Used with trim_silence. Maximum silence, in milliseconds, removed from each end of the clip. 0 means no limit."),
+		),
+		mcp.WithBoolean("clarity_enhance",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, post-processes the speech with light dynamic-range compression and EQ for clearer, more consistent-volume playback in noisy environments. Requires ffmpeg on the server."),
+		),
+		mcp.WithString("clarity_preset",
+			mcp.DefaultString(common.DefaultClarityPreset),
+			mcp.Enum(common.ClarityPresetNames()...),
+			mcp.Description("Optional. Used with clarity_enhance. How strongly to process the speech: 'light', 'standard', or 'strong'."),
+		),
+		mcp.WithString("audio_encoding",
+			mcp.Enum(chirpAudioEncodingNames...),
+			mcp.Description("Optional. The encoding of the output audio: LINEAR16 (WAV), MP3, or OGG_OPUS. Defaults to TTS_DEFAULT_ENCODING, or LINEAR16. In stream_to_file mode the audio is streamed to a WAV file and transcoded to other encodings with ffmpeg."),
+		),
+		mcp.WithArray("additional_encodings",
+			mcp.Description(fmt.Sprintf("Optional. Extra formats to transcode the primary output (in audio_encoding) to (e.g., ['mp3'] alongside the default LINEAR16 to get both a .wav for editing and an .mp3 for delivery). Each is saved next to the primary file, or returned inline if no output_directory is given. Supported: %s. Requires ffmpeg on the server.", strings.Join(common.AudioFormatNames(), ", "))),
+			mcp.WithStringItems(),
+		),
 	)
-	s.AddTool(chirpTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		if ttsClient == nil {
-			log.Printf("Initializing global Text-to-Speech client...")
-			cfg := common.LoadConfig(serviceName)
-			opts := getChirpClientOptions(cfg.Location)
-			client, err := texttospeech.NewClient(context.Background(), opts...)
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize Text-to-Speech client: %w", err)
-			}
-			ttsClient = client
+	common.AddTool(s, appConfig, chirpTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ensureTTSClient(); err != nil {
+			return nil, err
+		}
+		return chirpTTSHandler(ttsClient, toolCtx, request)
+	})
 
-			if len(availableVoices) == 0 {
-				if err := listAndCacheChirpHDVoices(context.Background(), cfg.Location); err != nil {
-					log.Printf("Warning: Failed to fetch voices during initialization: %v", err)
-				}
-			}
+	longFormTool := mcp.NewTool("synthesize_long_form",
+		mcp.WithDescription("Synthesizes a structured document, such as an article or chapter, into a single WAV file. Each text segment is synthesized in order and followed by a pause of the requested length."),
+		mcp.WithArray("segments",
+			mcp.Required(),
+			mcp.Description(fmt.Sprintf("The document sections in order. Each item is either a string or an object with 'text' and an optional 'pause_after_ms', the silence in milliseconds to insert after the segment (at most %d). Long segments are split on sentence boundaries automatically.", maxSegmentPause.Milliseconds())),
+			mcp.Items(map[string]any{
+				"anyOf": []map[string]any{
+					{"type": "string"},
+					{
+						"type": "object",
+						"properties": map[string]any{
+							"text":           map[string]any{"type": "string"},
+							"pause_after_ms": map[string]any{"type": "number"},
+						},
+						"required": []string{"text"},
+					},
+				},
+			}),
+		),
+		mcp.WithNumber("default_pause_ms",
+			mcp.DefaultNumber(float64(defaultSegmentPause.Milliseconds())),
+			mcp.Description("Optional. The pause, in milliseconds, after segments that do not set 'pause_after_ms'. No pause is added after the last segment."),
+		),
+		mcp.WithString("voice_name",
+			mcp.Description(fmt.Sprintf("Optional. The Chirp3-HD voice name to use. Defaults to '%s'; unavailable voices fall back as in chirp_tts.", defaultChirpVoiceName)),
+		),
+		mcp.WithString("output_filename_prefix",
+			mcp.DefaultString("chirp_document"),
+			mcp.Description("Optional. A prefix for the output WAV filename. A timestamp and .wav extension will be appended when saving locally."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Optional. A local directory to save the WAV file to. If not provided, the audio is returned in the response."),
+		),
+		mcp.WithArray("pronunciations",
+			mcp.Description("Optional. Custom pronunciations applied to every segment, in the same format as chirp_tts."),
+		),
+		mcp.WithString("pronunciation_encoding",
+			mcp.DefaultString("ipa"),
+			mcp.Description("Optional. The phonetic encoding used for the 'pronunciations' array. Can be 'ipa' or 'xsampa'."),
+			mcp.Enum("ipa", "xsampa"),
+		),
+	)
+	common.AddTool(s, appConfig, longFormTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ensureTTSClient(); err != nil {
+			return nil, err
 		}
+		return synthesizeLongFormHandler(ttsClient, toolCtx, request)
+	})
 
-		return chirpTTSHandler(ttsClient, toolCtx, request)
+	translateTool := mcp.NewTool("translate_and_synthesize",
+		mcp.WithDescription("Translates text to a target language with the Cloud Translation API and synthesizes the translation with a Chirp3-HD voice for that language. Returns the translation and the audio."),
+		mcp.WithString("text",
+			mcp.Description("The text to translate and synthesize. Either 'text' or 'text_uri' is required."),
+		),
+		mcp.WithString(common.TextURIParam,
+			mcp.Description("Optional. A local path or gs:// URI of a UTF-8 text file to translate and synthesize instead of 'text'. Files larger than MAX_INPUT_TEXT_BYTES (1 MB by default) are rejected."),
+		),
+		mcp.WithString("target_language",
+			mcp.Required(),
+			mcp.Description("The language to translate to and speak, as a BCP-47 code (e.g., 'ja-JP') or a descriptive name (e.g., 'Japanese (Japan)'). See the 'chirp://language_codes' resource."),
+		),
+		mcp.WithString("source_language",
+			mcp.Description("Optional. The language of the input text. Detected automatically if not provided."),
+		),
+		mcp.WithString("voice_name",
+			mcp.Description("Optional. A Chirp3-HD voice for the target language. If not provided or not for the target language, the target language's counterpart of the default voice is used."),
+		),
+		mcp.WithString("output_filename_prefix",
+			mcp.DefaultString("chirp_translation"),
+			mcp.Description("Optional. A prefix for the output WAV filename. A timestamp and .wav extension will be appended when saving locally."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Optional. A local directory to save the WAV file to. If not provided, the audio is returned in the response."),
+		),
+	)
+	common.AddTool(s, appConfig, translateTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ensureTTSClient(); err != nil {
+			return nil, err
+		}
+		return translateAndSynthesizeHandler(ttsClient, toolCtx, request)
 	})
 
-	listVoicesTool := mcp.NewTool("list_chirp_voices",
-		mcp.WithDescription("Lists Chirp3-HD voices, filtered by the provided language (either descriptive name or BCP-47 code)."),
-		mcp.WithString("language",
+	detectLanguageTool := mcp.NewTool("detect_language",
+		mcp.WithDescription("Detects the language of text with the Cloud Translation API. Returns the BCP-47 language code (as a Chirp3-HD language code, e.g. 'de-DE', where Chirp3-HD supports the language), its descriptive name, and the confidence, e.g. to pick a voice or translation target."),
+		mcp.WithString("text",
 			mcp.Required(),
-			mcp.Description("The language to filter voices by. Can be a descriptive name (e.g., 'English (United States)') or a BCP-47 code (e.g., 'en-US')."),
+			mcp.Description("The text whose language to detect."),
 		),
 	)
-	s.AddTool(listVoicesTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		if ttsClient == nil {
-			log.Printf("Initializing global Text-to-Speech client for list voices...")
-			cfg := common.LoadConfig(serviceName)
-			opts := getChirpClientOptions(cfg.Location)
-			client, err := texttospeech.NewClient(context.Background(), opts...)
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize Text-to-Speech client: %w", err)
-			}
-			ttsClient = client
+	common.AddTool(s, appConfig, detectLanguageTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return detectLanguageHandler(toolCtx, request)
+	})
 
-			if len(availableVoices) == 0 {
-				if err := listAndCacheChirpHDVoices(context.Background(), cfg.Location); err != nil {
-					log.Printf("Warning: Failed to fetch voices during initialization: %v", err)
-				}
-			}
+	previewVoiceTool := mcp.NewTool("chirp_preview_voice",
+		mcp.WithDescription("Synthesizes a short sample phrase with a Chirp3-HD voice and returns the audio inline, so a voice can be auditioned before use."),
+		mcp.WithString("voice_name",
+			mcp.Required(),
+			mcp.Description("The Chirp3-HD voice name to preview (e.g., 'en-US-Chirp3-HD-Zephyr'). Use 'list_chirp_voices' to find voices."),
+		),
+		mcp.WithString("text",
+			mcp.Description(fmt.Sprintf("Optional. A short sample phrase of at most %d characters. Defaults to a fixed preview phrase.", common.MaxVoicePreviewTextLength)),
+		),
+	)
+	common.AddTool(s, appConfig, previewVoiceTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ensureTTSClient(); err != nil {
+			return nil, err
 		}
+		return chirpPreviewVoiceHandler(ttsClient, toolCtx, request)
+	})
 
+	listVoicesTool := mcp.NewTool("list_chirp_voices",
+		mcp.WithDescription("Lists Chirp3-HD voices, filtered by the provided language (either descriptive name or BCP-47 code), or all voices grouped by BCP-47 code if 'all' is true."),
+		mcp.WithString("language",
+			mcp.Description("The language to filter voices by. Can be a descriptive name (e.g., 'English (United States)') or a BCP-47 code (e.g., 'en-US'). Required unless 'all' is true."),
+		),
+		mcp.WithBoolean("all",
+			mcp.Description("Optional. If true, lists every Chirp3-HD voice, grouped by BCP-47 language code. Cannot be combined with 'language'."),
+		),
+	)
+	common.AddTool(s, appConfig, listVoicesTool, func(toolCtx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ensureTTSClient(); err != nil {
+			return nil, err
+		}
 		return listChirpVoicesHandler(toolCtx, request)
 	})
 
@@ -337,7 +523,7 @@ func main() {
 		),
 	), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 		if ttsClient == nil {
-			log.Printf("Initializing global Text-to-Speech client for prompt...")
+			common.LogInfof("Initializing global Text-to-Speech client for prompt...")
 			cfg := common.LoadConfig(serviceName)
 			opts := getChirpClientOptions(cfg.Location)
 			client, err := texttospeech.NewClient(context.Background(), opts...)
@@ -348,7 +534,7 @@ func main() {
 
 			if len(availableVoices) == 0 {
 				if err := listAndCacheChirpHDVoices(context.Background(), cfg.Location); err != nil {
-					log.Printf("Warning: Failed to fetch voices during initialization: %v", err)
+					common.LogWarnf("Warning: Failed to fetch voices during initialization: %v", err)
 				}
 			}
 		}
@@ -411,6 +597,9 @@ func main() {
 		}, nil
 	})
 
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
+
 	switch transport {
 	case "sse":
 		ssePort := 8081 // Default SSE port
@@ -419,10 +608,10 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -431,7 +620,7 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
 		mcpHTTPHandler := server.NewStreamableHTTPServer(s) // Base path /mcp
 		c := cors.New(cors.Options{
 			AllowedOrigins:   []string{"*"},
@@ -443,24 +632,95 @@ func main() {
 		})
 		handlerWithCORS := c.Handler(mcpHTTPHandler)
 		listenAddr := fmt.Sprintf(":%d", httpPort)
-		if err := http.ListenAndServe(listenAddr, handlerWithCORS); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		httpServer := common.NewHTTPServer(listenAddr, handlerWithCORS)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 
-	log.Printf("%s Server has stopped.", serviceName)
+	common.LogInfof("%s Server has stopped.", serviceName)
 	if ttsClient != nil {
 		_ = ttsClient.Close()
 	}
 }
 
+// ensureTTSClient lazily initializes the global Text-to-Speech client and voice cache.
+// Initialization is deferred to the first tool call so that the schema can be inspected
+// without Google Cloud credentials.
+func ensureTTSClient() error {
+	if ttsClient != nil {
+		return nil
+	}
+	common.LogInfof("Initializing global Text-to-Speech client...")
+	cfg := common.LoadConfig(serviceName)
+	opts := getChirpClientOptions(cfg.Location)
+	client, err := texttospeech.NewClient(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Text-to-Speech client: %w", err)
+	}
+	ttsClient = client
+
+	if len(availableVoices) == 0 {
+		if err := listAndCacheChirpHDVoices(context.Background(), cfg.Location); err != nil {
+			common.LogWarnf("Warning: Failed to fetch voices during initialization: %v", err)
+		}
+	}
+	return nil
+}
+
+// chirpPreviewVoiceHandler handles the 'chirp_preview_voice' tool. It synthesizes a short
+// sample phrase with the requested voice under a tight timeout and returns the audio inline.
+func chirpPreviewVoiceHandler(client *texttospeech.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.LogInfof("Handling chirp_preview_voice request with arguments: %v", request.GetArguments())
+
+	voiceName, _ := request.GetArguments()["voice_name"].(string)
+	voiceName = strings.TrimSpace(voiceName)
+	if voiceName == "" {
+		return mcp.NewToolResultError("voice_name parameter must be a non-empty string and is required"), nil
+	}
+	var selectedVoice *texttospeechpb.Voice
+	for _, v := range availableVoices {
+		if v.Name == voiceName {
+			selectedVoice = v
+			break
+		}
+	}
+	if selectedVoice == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("voice '%s' is not an available Chirp3-HD voice. Use 'list_chirp_voices' to see available voices", voiceName)), nil
+	}
+
+	text, _ := request.GetArguments()["text"].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = common.DefaultVoicePreviewText
+	}
+	if len([]rune(text)) > common.MaxVoicePreviewTextLength {
+		return mcp.NewToolResultError(fmt.Sprintf("text parameter cannot exceed %d characters for a voice preview", common.MaxVoicePreviewTextLength)), nil
+	}
+
+	previewCtx, cancel := context.WithTimeout(ctx, common.VoicePreviewTimeout)
+	defer cancel()
+	audio, err := synthesizeWithVoice(previewCtx, client, selectedVoice, text, nil, texttospeechpb.AudioEncoding_LINEAR16)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return mcp.NewToolResultError(fmt.Sprintf("voice preview timed out after %v", common.VoicePreviewTimeout)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("error synthesizing voice preview: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.TextContent{Type: "text", Text: fmt.Sprintf("Preview of voice %s: %q", selectedVoice.Name, text)},
+		mcp.AudioContent{Type: "audio", Data: base64.StdEncoding.EncodeToString(audio), MIMEType: "audio/wav"},
+	}}, nil
+}
+
 // chirpTTSHandler is the core logic for the 'chirp_tts' tool.
 // It handles requests to synthesize speech from text. The function extracts parameters
 // from the request, selects an appropriate voice, and calls the Text-to-Speech API.
@@ -470,17 +730,16 @@ func chirpTTSHandler(client *texttospeech.Client, ctx context.Context, request m
 	var contentItems []mcp.Content
 
 	if err := ctx.Err(); err != nil {
-		log.Printf("chirpTTSHandler: Incoming context (ctx) is already canceled or has an error upon entry: %v. Will attempt to proceed with TTS using a background context.", err)
+		common.LogWarnf("chirpTTSHandler: Incoming context (ctx) is already canceled or has an error upon entry: %v. Will attempt to proceed with TTS using a background context.", err)
 	} else {
-		log.Printf("chirpTTSHandler: Incoming context (ctx) is active upon entry.")
+		common.LogInfof("chirpTTSHandler: Incoming context (ctx) is active upon entry.")
 	}
 
-	log.Printf("Handling chirp_tts request with arguments: %v", request.GetArguments())
+	common.LogInfof("Handling chirp_tts request with arguments: %v", request.GetArguments())
 
-	text, ok := request.GetArguments()["text"].(string)
-	if !ok || strings.TrimSpace(text) == "" {
-		errMsg := "text parameter must be a non-empty string and is required"
-		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
+	text, err := appConfig.InputText(ctx, request.GetArguments())
+	if err != nil {
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: err.Error()})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 
@@ -494,51 +753,44 @@ func chirpTTSHandler(client *texttospeech.Client, ctx context.Context, request m
 	customPronos, err := parseMcpPronunciations(pronunciationsParam, pronunciationEncodingStr)
 	if err != nil {
 		errMsg := fmt.Sprintf("Error parsing custom pronunciations: %v", err)
-		log.Print(errMsg)
+		common.LogErrorf("%s", errMsg)
 		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 	if customPronos != nil {
-		log.Printf("Applying %d custom pronunciations with %s encoding.", len(customPronos.Pronunciations), pronunciationEncodingStr)
+		common.LogInfof("Applying %d custom pronunciations with %s encoding.", len(customPronos.Pronunciations), pronunciationEncodingStr)
 	}
 
-	var selectedVoice *texttospeechpb.Voice
-	voiceNameParam, voiceNameProvided := request.GetArguments()["voice_name"].(string)
-
-	if voiceNameProvided && strings.TrimSpace(voiceNameParam) != "" {
-		voiceNameParam = strings.TrimSpace(voiceNameParam)
-		found := false
-		for _, v := range availableVoices {
-			if v.Name == voiceNameParam {
-				selectedVoice = v
-				found = true
-				break
-			}
-		}
-		if !found {
-			log.Printf("Requested voice_name '%s' not found among available Chirp3-HD voices. Attempting default.", voiceNameParam)
-		} else {
-			log.Printf("Using requested voice: %s", selectedVoice.Name)
-		}
+	additionalFormats, err := common.ParseAudioEncodings(request.GetArguments()["additional_encodings"])
+	if err != nil {
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: err.Error()})
+		return &mcp.CallToolResult{Content: contentItems}, nil
+	}
+	clarityPreset, err := common.ParseClarityPreset(request.GetArguments())
+	if err != nil {
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: err.Error()})
+		return &mcp.CallToolResult{Content: contentItems}, nil
+	}
+	audioEncodingArg, _ := request.GetArguments()["audio_encoding"].(string)
+	encodingName, err := appConfig.ResolveTTSEncoding(audioEncodingArg, chirpAudioEncodingNames)
+	if err != nil {
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: err.Error()})
+		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
+	outputEncoding := chirpAudioEncodings[encodingName]
 
+	voiceNameParam, _ := request.GetArguments()["voice_name"].(string)
+	selectedVoice, voiceSubstitution := selectChirpVoice(strings.TrimSpace(voiceNameParam), availableVoices, voiceFallbacks)
 	if selectedVoice == nil {
-		for _, v := range availableVoices {
-			if v.Name == defaultChirpVoiceName {
-				selectedVoice = v
-				log.Printf("Voice_name not provided or invalid/not found. Defaulting to preferred voice: %s", selectedVoice.Name)
-				break
-			}
-		}
-		if selectedVoice == nil && len(availableVoices) > 0 {
-			selectedVoice = availableVoices[0]
-			log.Printf("Preferred default voice '%s' not found. Defaulting to first available Chirp3-HD voice: %s", defaultChirpVoiceName, selectedVoice.Name)
-		} else if selectedVoice == nil {
-			errMsg := "No Chirp3-HD voices available for synthesis. Please check server logs for voice fetching issues at startup."
-			log.Println("Error: " + errMsg)
-			contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
-			return &mcp.CallToolResult{Content: contentItems}, nil
-		}
+		errMsg := "No Chirp3-HD voices available for synthesis. Please check server logs for voice fetching issues at startup."
+		common.LogErrorf("Error: %s", errMsg)
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
+		return &mcp.CallToolResult{Content: contentItems}, nil
+	}
+	if voiceSubstitution != "" {
+		common.LogWarnf("%s", voiceSubstitution)
+	} else {
+		common.LogInfof("Using voice: %s", selectedVoice.Name)
 	}
 
 	filenamePrefix, _ := request.GetArguments()["output_filename_prefix"].(string)
@@ -551,73 +803,163 @@ func chirpTTSHandler(client *texttospeech.Client, ctx context.Context, request m
 		outputDir = strings.TrimSpace(dir)
 	}
 	attemptLocalSave := outputDir != ""
-	log.Printf("Output directory: '%s', Attempt local save: %t", outputDir, attemptLocalSave)
+	common.LogInfof("Output directory: '%s', Attempt local save: %t", outputDir, attemptLocalSave)
+
+	if streamToFile, _ := request.GetArguments()["stream_to_file"].(bool); streamToFile {
+		// Chunks are streamed into a WAV file; other encodings are transcoded from it.
+		if outputEncoding.format.Name != "wav" && !slices.ContainsFunc(additionalFormats, func(f common.AudioFormat) bool { return f.Name == outputEncoding.format.Name }) {
+			additionalFormats = append(additionalFormats, outputEncoding.format)
+		}
+		return chirpStreamToFile(ctx, client, request, selectedVoice, voiceSubstitution, text, customPronos, outputDir, filenamePrefix, additionalFormats)
+	}
 
 	synthesisAPICallCtx, synthesisAPICallCancel := context.WithTimeout(ctx, 30*time.Second)
 	defer synthesisAPICallCancel()
 
-	log.Printf("Synthesizing speech for text: \"%s\" with voice: %s. API call using independent context with timeout: 30s", text, selectedVoice.Name)
+	common.LogInfof("Synthesizing speech for text: \"%s\" with voice: %s. API call using independent context with timeout: 30s", text, selectedVoice.Name)
 	// Pass customPronos to synthesizeWithVoice
-	audioContentBytes, err := synthesizeWithVoice(synthesisAPICallCtx, client, selectedVoice, text, customPronos)
+	audioContentBytes, err := synthesizeWithVoice(synthesisAPICallCtx, client, selectedVoice, text, customPronos, outputEncoding.apiEncoding)
 
 	if err != nil {
 		errMsg := fmt.Sprintf("Error synthesizing speech: %v", err)
-		log.Print(errMsg)
+		common.LogErrorf("%s", errMsg)
 		if errors.Is(err, context.DeadlineExceeded) && synthesisAPICallCtx.Err() == context.DeadlineExceeded {
 			errMsg = "Speech synthesis API call timed out."
-			log.Printf("SynthesizeSpeech call timed out after 30 seconds (independent synthesisAPICallCtx).")
+			common.LogWarnf("SynthesizeSpeech call timed out after 30 seconds (independent synthesisAPICallCtx).")
 		} else if errors.Is(err, context.Canceled) && synthesisAPICallCtx.Err() == context.Canceled {
 			errMsg = "Speech synthesis API call was canceled."
-			log.Printf("SynthesizeSpeech call canceled (independent synthesisAPICallCtx).")
+			common.LogWarnf("SynthesizeSpeech call canceled (independent synthesisAPICallCtx).")
 		}
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "chirp_tts",
+			Prompt:     text,
+			Model:      selectedVoice.Name,
+			Parameters: request.GetArguments(),
+			Error:      errMsg,
+		})
 		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 
 	if len(audioContentBytes) == 0 {
 		errMsg := fmt.Sprintf("Synthesized audio is empty for voice %s.", selectedVoice.Name)
-		log.Print(errMsg)
+		common.LogErrorf("%s", errMsg)
 		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 
+	var trimMessage string
+	if trimSilence, _ := request.GetArguments()["trim_silence"].(bool); trimSilence && encodingName != "LINEAR16" {
+		trimMessage = "Silence trimming is only applied to LINEAR16 output."
+	} else if trimSilence {
+		threshold := defaultSilenceThreshold
+		if v, ok := request.GetArguments()["silence_threshold"].(float64); ok {
+			if v < 0 || v > 1 {
+				errMsg := fmt.Sprintf("silence_threshold must be between 0.0 and 1.0, got %v", v)
+				contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errMsg})
+				return &mcp.CallToolResult{Content: contentItems}, nil
+			}
+			threshold = v
+		}
+		maxTrim := defaultMaxSilenceTrim
+		if v, ok := request.GetArguments()["max_trim_ms"].(float64); ok && v >= 0 {
+			maxTrim = time.Duration(v) * time.Millisecond
+		}
+
+		trimmed, leading, trailing, err := trimWAVSilence(audioContentBytes, threshold, maxTrim)
+		if err != nil {
+			// Trimming is best-effort; fall back to the untrimmed audio.
+			common.LogWarnf("Warning: failed to trim silence, returning untrimmed audio: %v", err)
+			trimMessage = "Silence trimming was skipped: " + err.Error() + "."
+		} else {
+			common.LogInfof("Trimmed %v leading and %v trailing silence (threshold %.3f, max %v).", leading, trailing, threshold, maxTrim)
+			audioContentBytes = trimmed
+			trimMessage = fmt.Sprintf("Trimmed %v leading and %v trailing silence.", leading, trailing)
+		}
+	}
+
+	var clarityMessage string
+	if clarityPreset != "" {
+		enhanced, err := common.EnhanceSpeechClarity(ctx, audioContentBytes, clarityPreset, outputEncoding.format)
+		if err != nil {
+			// Enhancement is best-effort; fall back to the unprocessed audio.
+			common.LogWarnf("Warning: %v", err)
+			clarityMessage = "Clarity enhancement was skipped: " + err.Error() + "."
+		} else {
+			audioContentBytes = enhanced
+			clarityMessage = fmt.Sprintf("Applied the '%s' clarity enhancement.", clarityPreset)
+		}
+	}
+
 	var fileSaveMessage string
 	var savedFilename string
 
 	if attemptLocalSave {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
 			fileSaveMessage = fmt.Sprintf("Error creating directory %s: %v. Audio data will be returned in response instead.", outputDir, err)
-			log.Print(fileSaveMessage)
-			base64AudioData := base64.StdEncoding.EncodeToString(audioContentBytes)
-			audioItem := mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: "audio/wav"}
-			contentItems = append(contentItems, audioItem)
+			common.LogWarnf("%s", fileSaveMessage)
+			contentItems = append(contentItems, inlineAudioContent(ctx, audioContentBytes, filenamePrefix+outputEncoding.format.Extension, outputEncoding.format.MIMEType))
 		} else {
 			safeVoiceName := strings.ReplaceAll(selectedVoice.Name, "/", "_")
 			safeVoiceName = strings.ReplaceAll(safeVoiceName, ":", "_")
-			genFilename := fmt.Sprintf("%s-%s-%s.wav", filenamePrefix, safeVoiceName, time.Now().Format(timeFormatForFilename))
+			genFilename := fmt.Sprintf("%s-%s-%s%s", filenamePrefix, safeVoiceName, time.Now().Format(timeFormatForFilename), outputEncoding.format.Extension)
 			savedFilename = filepath.Join(outputDir, genFilename)
 			savedFilename = filepath.Clean(savedFilename)
 
 			err = os.WriteFile(savedFilename, audioContentBytes, 0644)
 			if err != nil {
 				fileSaveMessage = fmt.Sprintf("Error writing audio file %s: %v. Audio data will be returned in response instead.", savedFilename, err)
-				log.Print(fileSaveMessage)
-				base64AudioData := base64.StdEncoding.EncodeToString(audioContentBytes)
-				audioItem := mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: "audio/wav"}
-				contentItems = append(contentItems, audioItem)
+				common.LogWarnf("%s", fileSaveMessage)
+				contentItems = append(contentItems, inlineAudioContent(ctx, audioContentBytes, filenamePrefix+outputEncoding.format.Extension, outputEncoding.format.MIMEType))
 				savedFilename = ""
 			} else {
 				fileSaveMessage = fmt.Sprintf("Audio saved to: %s (%d bytes).", savedFilename, len(audioContentBytes))
-				log.Printf("Audio content (%d bytes) written to file: %s", len(audioContentBytes), savedFilename)
+				common.LogInfof("Audio content (%d bytes) written to file: %s", len(audioContentBytes), savedFilename)
 			}
 		}
 	} else {
-		base64AudioData := base64.StdEncoding.EncodeToString(audioContentBytes)
-		audioItem := mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: "audio/wav"}
-		contentItems = append(contentItems, audioItem)
+		contentItems = append(contentItems, inlineAudioContent(ctx, audioContentBytes, filenamePrefix+outputEncoding.format.Extension, outputEncoding.format.MIMEType))
 		fileSaveMessage = "Audio data is included in the response."
 	}
 
+	var auditOutputURIs []string
+	if savedFilename != "" {
+		auditOutputURIs = append(auditOutputURIs, savedFilename)
+	}
+
+	var encodingMessages []string
+	if len(additionalFormats) > 0 {
+		var encodedItems []mcp.Content
+		var encodedPaths []string
+		encodedItems, encodingMessages, encodedPaths = common.WriteAdditionalEncodings(ctx, savedFilename, audioContentBytes, outputEncoding.format, additionalFormats, inlineEncoding(ctx, filenamePrefix))
+		contentItems = append(contentItems, encodedItems...)
+		auditOutputURIs = append(auditOutputURIs, encodedPaths...)
+	}
+	if tagMessage := appConfig.TagGeneratedAudioFiles(ctx, auditOutputURIs, selectedVoice.Name, text); tagMessage != "" {
+		encodingMessages = append(encodingMessages, tagMessage)
+	}
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "chirp_tts",
+		Prompt:     text,
+		Model:      selectedVoice.Name,
+		Parameters: request.GetArguments(),
+		OutputURIs: auditOutputURIs,
+	})
+
+	if len(encodingMessages) > 0 {
+		fileSaveMessage += " " + strings.Join(encodingMessages, " ")
+	}
+	if clarityMessage != "" {
+		fileSaveMessage = clarityMessage + " " + fileSaveMessage
+	}
+	if trimMessage != "" {
+		fileSaveMessage = trimMessage + " " + fileSaveMessage
+	}
+	if voiceSubstitution != "" {
+		fileSaveMessage = voiceSubstitution + " " + fileSaveMessage
+	}
 	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s. %s",
 		selectedVoice.Name,
 		fileSaveMessage,
@@ -625,24 +967,90 @@ func chirpTTSHandler(client *texttospeech.Client, ctx context.Context, request m
 	textItem := mcp.TextContent{Type: "text", Text: strings.TrimSpace(resultText)}
 
 	finalContentItems := []mcp.Content{textItem}
-	// Only append audio to finalContentItems if it's meant to be returned in the response
-	if !attemptLocalSave || (attemptLocalSave && savedFilename == "") {
-		// Find the audioItem in contentItems (it should be the only one if it exists)
-		for _, item := range contentItems {
-			if _, ok := item.(mcp.AudioContent); ok {
-				finalContentItems = append(finalContentItems, item)
-				break
-			}
-		}
-	}
+	// contentItems only holds audio that is meant to be returned in the response: the inline
+	// (or spilled) audio when it was not saved locally, and any inline additional encodings.
+	finalContentItems = append(finalContentItems, contentItems...)
 
 	return &mcp.CallToolResult{Content: finalContentItems}, nil
 }
 
+// chirpStreamToFile implements the 'stream_to_file' mode of chirp_tts: long text is synthesized
+// in sentence-aligned chunks that are appended to a single WAV file in outputDir.
+func chirpStreamToFile(ctx context.Context, client *texttospeech.Client, request mcp.CallToolRequest, voice *texttospeechpb.Voice, voiceSubstitution, text string, customPronos *texttospeechpb.CustomPronunciations, outputDir, filenamePrefix string, additionalFormats []common.AudioFormat) (*mcp.CallToolResult, error) {
+	if outputDir == "" {
+		return mcp.NewToolResultError("stream_to_file requires output_directory to be set"), nil
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory %s: %v", outputDir, err)), nil
+	}
+	safeVoiceName := strings.ReplaceAll(voice.Name, "/", "_")
+	safeVoiceName = strings.ReplaceAll(safeVoiceName, ":", "_")
+	savedFilename := filepath.Clean(filepath.Join(outputDir, fmt.Sprintf("%s-%s-%s.wav", filenamePrefix, safeVoiceName, time.Now().Format(timeFormatForFilename))))
+
+	startTime := time.Now()
+	numChunks, duration, err := synthesizeToWAVFile(ctx, client, voice, text, customPronos, savedFilename)
+	auditRecord := common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "chirp_tts",
+		Prompt:     text,
+		Model:      voice.Name,
+		Parameters: request.GetArguments(),
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Error synthesizing speech in streaming mode: %v", err)
+		common.LogErrorf("%s", errMsg)
+		auditRecord.Error = errMsg
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		return mcp.NewToolResultError(errMsg), nil
+	}
+	common.LogInfof("Streamed %d chunks (%v of audio) to %s in %v", numChunks, duration.Round(time.Millisecond), savedFilename, time.Since(startTime))
+
+	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s in %d chunk(s) (%v of audio). Audio saved to: %s.", voice.Name, numChunks, duration.Round(time.Second), savedFilename)
+	auditRecord.OutputURIs = []string{savedFilename}
+	if len(additionalFormats) > 0 {
+		// ffmpeg reads the streamed file itself, so the audio is never held in memory.
+		_, encodingMessages, encodedPaths := common.WriteAdditionalEncodings(ctx, savedFilename, nil, wavAudioFormat, additionalFormats, nil)
+		resultText += " " + strings.Join(encodingMessages, " ")
+		auditRecord.OutputURIs = append(auditRecord.OutputURIs, encodedPaths...)
+	}
+	if tagMessage := appConfig.TagGeneratedAudioFiles(ctx, auditRecord.OutputURIs, voice.Name, text); tagMessage != "" {
+		resultText += " " + tagMessage
+	}
+	common.WriteAuditRecord(ctx, appConfig, auditRecord)
+	if trimSilence, _ := request.GetArguments()["trim_silence"].(bool); trimSilence {
+		resultText += " Silence trimming is not applied in streaming mode."
+	}
+	if clarityEnhance, _ := request.GetArguments()["clarity_enhance"].(bool); clarityEnhance {
+		resultText += " Clarity enhancement is not applied in streaming mode."
+	}
+	if voiceSubstitution != "" {
+		resultText = voiceSubstitution + " " + resultText
+	}
+	return mcp.NewToolResultText(resultText), nil
+}
+
+// inlineAudioContent returns audio as base64 audio content. If the audio exceeds
+// MCP_MAX_INLINE_BYTES and GENMEDIA_BUCKET is set, it is uploaded to GCS instead and a
+// text item with the gs:// URI is returned.
+func inlineAudioContent(ctx context.Context, audio []byte, fileName, mimeType string) mcp.Content {
+	if gcsURI := common.SpillInlineOutput(ctx, appConfig, serviceName, fileName, mimeType, audio); gcsURI != "" {
+		return mcp.TextContent{Type: "text", Text: fmt.Sprintf("Audio (%s) exceeds the inline size limit and was saved to %s", common.FormatBytes(int64(len(audio))), gcsURI)}
+	}
+	return mcp.AudioContent{Type: "audio", Data: base64.StdEncoding.EncodeToString(audio), MIMEType: mimeType}
+}
+
+// inlineEncoding returns the content of an additional encoding that is returned in the response,
+// for common.WriteAdditionalEncodings.
+func inlineEncoding(ctx context.Context, filenamePrefix string) func([]byte, common.AudioFormat) mcp.Content {
+	return func(encoded []byte, format common.AudioFormat) mcp.Content {
+		return inlineAudioContent(ctx, encoded, filenamePrefix+format.Extension, format.MIMEType)
+	}
+}
+
 // synthesizeWithVoice encapsulates the call to the Google Cloud Text-to-Speech API.
-// It constructs the synthesis request with the specified voice, text, and custom pronunciations,
-// sends it to the API, and returns the raw audio content as a byte slice.
-func synthesizeWithVoice(ctx context.Context, client *texttospeech.Client, voice *texttospeechpb.Voice, textToSynthesize string, customPronos *texttospeechpb.CustomPronunciations) ([]byte, error) {
+// It constructs the synthesis request with the specified voice, text, custom pronunciations,
+// and audio encoding, sends it to the API, and returns the raw audio content as a byte slice.
+func synthesizeWithVoice(ctx context.Context, client *texttospeech.Client, voice *texttospeechpb.Voice, textToSynthesize string, customPronos *texttospeechpb.CustomPronunciations, encoding texttospeechpb.AudioEncoding) ([]byte, error) {
 	req := texttospeechpb.SynthesizeSpeechRequest{
 		Input: &texttospeechpb.SynthesisInput{
 			InputSource:          &texttospeechpb.SynthesisInput_Text{Text: textToSynthesize},
@@ -653,7 +1061,7 @@ func synthesizeWithVoice(ctx context.Context, client *texttospeech.Client, voice
 			Name:         voice.GetName(),
 		},
 		AudioConfig: &texttospeechpb.AudioConfig{
-			AudioEncoding: texttospeechpb.AudioEncoding_LINEAR16, // WAV format
+			AudioEncoding: encoding,
 		},
 	}
 
@@ -783,20 +1191,84 @@ func getFilteredVoices(languageQuery string) (string, string, error) {
 	return summaryText, string(jsonData), nil
 }
 
+// groupVoicesByLanguage groups voices by their primary BCP-47 language code, sorted by name
+// within each language.
+func groupVoicesByLanguage(voices []*texttospeechpb.Voice) map[string][]VoiceInfo {
+	grouped := make(map[string][]VoiceInfo)
+	for _, v := range voices {
+		var primaryLangCode string
+		if len(v.GetLanguageCodes()) > 0 {
+			primaryLangCode = v.GetLanguageCodes()[0]
+		}
+		grouped[primaryLangCode] = append(grouped[primaryLangCode], VoiceInfo{
+			Name:         v.GetName(),
+			LanguageCode: primaryLangCode,
+			Gender:       v.GetSsmlGender().String(),
+		})
+	}
+	for _, infos := range grouped {
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	}
+	return grouped
+}
+
+// getAllVoices returns a summary and the JSON of all cached Chirp3-HD voices, grouped by
+// BCP-47 language code.
+func getAllVoices() (string, string, error) {
+	if len(availableVoices) == 0 {
+		return "", "", errors.New("no Chirp3-HD voices are currently available or cached")
+	}
+
+	grouped := groupVoicesByLanguage(availableVoices)
+	langCodes := make([]string, 0, len(grouped))
+	for lc := range grouped {
+		langCodes = append(langCodes, lc)
+	}
+	sort.Strings(langCodes)
+	var counts []string
+	for _, lc := range langCodes {
+		counts = append(counts, fmt.Sprintf("%s (%d)", lc, len(grouped[lc])))
+	}
+
+	summaryText := fmt.Sprintf("Found %d Chirp3-HD voice(s) in %d language(s): %s",
+		len(availableVoices),
+		len(langCodes),
+		strings.Join(counts, ", "),
+	)
+
+	jsonData, err := json.MarshalIndent(grouped, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("error marshalling voice list to JSON: %w", err)
+	}
+
+	return summaryText, string(jsonData), nil
+}
+
 func listChirpVoicesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if err := ctx.Err(); err != nil {
-		log.Printf("listChirpVoicesHandler: Incoming context (ctx) is already canceled or has an error upon entry: %v. Attempting to proceed with listing.", err)
+		common.LogWarnf("listChirpVoicesHandler: Incoming context (ctx) is already canceled or has an error upon entry: %v. Attempting to proceed with listing.", err)
 	} else {
-		log.Printf("listChirpVoicesHandler: Incoming context (ctx) is active upon entry.")
+		common.LogInfof("listChirpVoicesHandler: Incoming context (ctx) is active upon entry.")
 	}
-	log.Println("Handling list_chirp_voices request.")
+	common.LogInfof("Handling list_chirp_voices request.")
 
 	languageParam, langProvided := request.GetArguments()["language"].(string)
-	if !langProvided || strings.TrimSpace(languageParam) == "" {
-		return mcp.NewToolResultError("'language' parameter must be provided and non-empty."), nil
+	listAll, _ := request.GetArguments()["all"].(bool)
+	langProvided = langProvided && strings.TrimSpace(languageParam) != ""
+	if listAll && langProvided {
+		return mcp.NewToolResultError("'language' and 'all' cannot be used together. Omit 'language' to list all voices."), nil
+	}
+	if !listAll && !langProvided {
+		return mcp.NewToolResultError("'language' parameter must be provided and non-empty, unless 'all' is true."), nil
 	}
 
-	summary, jsonData, err := getFilteredVoices(languageParam)
+	var summary, jsonData string
+	var err error
+	if listAll {
+		summary, jsonData, err = getAllVoices()
+	} else {
+		summary, jsonData, err = getFilteredVoices(languageParam)
+	}
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseMcpPronunciations(t *testing.T) {
+	tests := []struct {
+		name              string
+		param             interface{}
+		encoding          string
+		wantPhrase        string
+		wantPronunciation string
+		errContains       string
+	}{
+		{
+			name:              "string format",
+			param:             []interface{}{"tomato:təˈmeɪtoʊ"},
+			encoding:          "ipa",
+			wantPhrase:        "tomato",
+			wantPronunciation: "təˈmeɪtoʊ",
+		},
+		{
+			name:              "colon in x-sampa pronunciation",
+			param:             []interface{}{"two:t_hu:"},
+			encoding:          "xsampa",
+			wantPhrase:        "two",
+			wantPronunciation: "t_hu:",
+		},
+		{
+			name:              "escaped colon in phrase",
+			param:             []interface{}{`10\:30:tEn TEr4i`},
+			encoding:          "xsampa",
+			wantPhrase:        "10:30",
+			wantPronunciation: "tEn TEr4i",
+		},
+		{
+			name:              "object format",
+			param:             []interface{}{map[string]interface{}{"phrase": "ratio: a", "pronunciation": "reI:SoU"}},
+			encoding:          "xsampa",
+			wantPhrase:        "ratio: a",
+			wantPronunciation: "reI:SoU",
+		},
+		{
+			name:        "missing delimiter",
+			param:       []interface{}{"tomato"},
+			encoding:    "ipa",
+			errContains: "malformed pronunciation entry",
+		},
+		{
+			name:        "object missing pronunciation",
+			param:       []interface{}{map[string]interface{}{"phrase": "tomato"}},
+			encoding:    "ipa",
+			errContains: "empty phrase or pronunciation",
+		},
+		{
+			name:        "unsupported item type",
+			param:       []interface{}{42.0},
+			encoding:    "ipa",
+			errContains: "must be a string or an object",
+		},
+		{
+			name:        "unsupported encoding",
+			param:       []interface{}{"tomato:təˈmeɪtoʊ"},
+			encoding:    "arpabet",
+			errContains: "unsupported pronunciation_encoding",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMcpPronunciations(tt.param, tt.encoding)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got == nil || len(got.Pronunciations) != 1 {
+				t.Fatalf("expected 1 pronunciation, but got %v", got)
+			}
+			p := got.Pronunciations[0]
+			if p.GetPhrase() != tt.wantPhrase {
+				t.Errorf("expected phrase %q, but got %q", tt.wantPhrase, p.GetPhrase())
+			}
+			if p.GetPronunciation() != tt.wantPronunciation {
+				t.Errorf("expected pronunciation %q, but got %q", tt.wantPronunciation, p.GetPronunciation())
+			}
+		})
+	}
+}
+
+func TestParseMcpPronunciationsEmpty(t *testing.T) {
+	for _, param := range []interface{}{nil, []interface{}{}, []interface{}{"  "}} {
+		got, err := parseMcpPronunciations(param, "ipa")
+		if err != nil || got != nil {
+			t.Errorf("expected nil result for %v, but got %v (err: %v)", param, got, err)
+		}
+	}
+}
+
+func TestChirpPreviewVoiceHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		errContains string
+	}{
+		{"missing voice", map[string]interface{}{}, "voice_name parameter"},
+		{"unknown voice", map[string]interface{}{"voice_name": "not-a-voice"}, "is not an available Chirp3-HD voice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: tt.args}}
+			result, err := chirpPreviewVoiceHandler(nil, context.Background(), req)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("expected an error result")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.Contains(text, tt.errContains) {
+				t.Errorf("expected error containing %q, but got %q", tt.errContains, text)
+			}
+		})
+	}
+}
+
+func TestGroupVoicesByLanguage(t *testing.T) {
+	voices := []*texttospeechpb.Voice{
+		{Name: "en-US-Chirp3-HD-Zephyr", LanguageCodes: []string{"en-US"}, SsmlGender: texttospeechpb.SsmlVoiceGender_FEMALE},
+		{Name: "de-DE-Chirp3-HD-Kore", LanguageCodes: []string{"de-DE"}},
+		{Name: "en-US-Chirp3-HD-Puck", LanguageCodes: []string{"en-US"}, SsmlGender: texttospeechpb.SsmlVoiceGender_MALE},
+	}
+	got := groupVoicesByLanguage(voices)
+	want := map[string][]VoiceInfo{
+		"de-DE": {{Name: "de-DE-Chirp3-HD-Kore", LanguageCode: "de-DE", Gender: "SSML_VOICE_GENDER_UNSPECIFIED"}},
+		"en-US": {
+			{Name: "en-US-Chirp3-HD-Puck", LanguageCode: "en-US", Gender: "MALE"},
+			{Name: "en-US-Chirp3-HD-Zephyr", LanguageCode: "en-US", Gender: "FEMALE"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
+
+func TestListChirpVoicesHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		errContains string
+	}{
+		{"no language", map[string]interface{}{}, "unless 'all' is true"},
+		{"language and all", map[string]interface{}{"language": "en-US", "all": true}, "cannot be used together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: tt.args}}
+			result, err := listChirpVoicesHandler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("expected an error result")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.Contains(text, tt.errContains) {
+				t.Errorf("expected error containing %q, but got %q", tt.errContains, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Package main implements an MCP server for Google's Chirp3 text-to-speech models.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultSegmentPause is the silence inserted after a segment that does not set pause_after_ms.
+	defaultSegmentPause = 500 * time.Millisecond
+	// maxSegmentPause caps the silence inserted after a single segment.
+	maxSegmentPause = 10 * time.Second
+)
+
+// documentSegment is one section of a long-form document and the silence that follows it.
+type documentSegment struct {
+	Text       string
+	PauseAfter time.Duration
+}
+
+// parseDocumentSegments parses the 'segments' argument of synthesize_long_form. Each item is
+// either a string or an object with 'text' and an optional 'pause_after_ms'; segments without a
+// pause use defaultPause. The pause after the last segment is dropped.
+func parseDocumentSegments(arg interface{}, defaultPause time.Duration) ([]documentSegment, error) {
+	items, ok := arg.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errors.New("segments must be a non-empty array")
+	}
+	segments := make([]documentSegment, 0, len(items))
+	for i, item := range items {
+		segment := documentSegment{PauseAfter: defaultPause}
+		switch v := item.(type) {
+		case string:
+			segment.Text = v
+		case map[string]interface{}:
+			segment.Text, _ = v["text"].(string)
+			if raw, present := v["pause_after_ms"]; present {
+				ms, ok := raw.(float64)
+				if !ok || ms < 0 {
+					return nil, fmt.Errorf("segment %d: pause_after_ms must be a non-negative number", i+1)
+				}
+				segment.PauseAfter = time.Duration(ms) * time.Millisecond
+			}
+		default:
+			return nil, fmt.Errorf("segment %d must be a string or an object with a 'text' field", i+1)
+		}
+		segment.Text = strings.TrimSpace(segment.Text)
+		if segment.Text == "" {
+			return nil, fmt.Errorf("segment %d has no text", i+1)
+		}
+		if segment.PauseAfter > maxSegmentPause {
+			return nil, fmt.Errorf("segment %d: pause of %v exceeds the maximum of %v", i+1, segment.PauseAfter, maxSegmentPause)
+		}
+		segments = append(segments, segment)
+	}
+	segments[len(segments)-1].PauseAfter = 0
+	return segments, nil
+}
+
+// synthesizeDocumentToWAVFile synthesizes each segment in order and streams the audio, separated by
+// the requested pauses, into a single WAV file at path. Long segments are split into chunks as in
+// synthesizeToWAVFile. On failure the partial file is removed. It returns the audio duration.
+func synthesizeDocumentToWAVFile(ctx context.Context, client *texttospeech.Client, voice *texttospeechpb.Voice, segments []documentSegment, customPronos *texttospeechpb.CustomPronunciations, path string) (time.Duration, error) {
+	writer, err := newWAVStreamWriter(path)
+	if err != nil {
+		return 0, fmt.Errorf("creating %s: %w", path, err)
+	}
+	fail := func(err error) (time.Duration, error) {
+		_ = writer.f.Close()
+		_ = os.Remove(path)
+		return 0, err
+	}
+
+	for i, segment := range segments {
+		common.LogInfof("Synthesizing segment %d/%d", i+1, len(segments))
+		chunks := splitTextIntoChunks(segment.Text, maxChunkBytes)
+		if err := appendSynthesizedChunks(ctx, client, voice, chunks, customPronos, writer); err != nil {
+			return fail(fmt.Errorf("segment %d of %d: %w", i+1, len(segments), err))
+		}
+		if segment.PauseAfter > 0 {
+			if err := writer.AppendSilence(segment.PauseAfter); err != nil {
+				return fail(fmt.Errorf("writing pause after segment %d: %w", i+1, err))
+			}
+		}
+	}
+
+	duration := writer.Duration()
+	if err := writer.Close(); err != nil {
+		_ = os.Remove(path)
+		return 0, fmt.Errorf("finalizing %s: %w", path, err)
+	}
+	return duration, nil
+}
+
+// synthesizeLongFormHandler is the handler for the 'synthesize_long_form' tool. It synthesizes a
+// document of text segments into one WAV file, which is saved to output_directory if given and
+// returned inline otherwise.
+func synthesizeLongFormHandler(client *texttospeech.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	defaultPause := defaultSegmentPause
+	if v, ok := args["default_pause_ms"].(float64); ok {
+		if v < 0 {
+			return mcp.NewToolResultError("default_pause_ms must be a non-negative number"), nil
+		}
+		defaultPause = time.Duration(v) * time.Millisecond
+	}
+	segments, err := parseDocumentSegments(args["segments"], defaultPause)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	pronunciationEncodingStr, _ := args["pronunciation_encoding"].(string)
+	if pronunciationEncodingStr == "" {
+		pronunciationEncodingStr = "ipa"
+	}
+	customPronos, err := parseMcpPronunciations(args["pronunciations"], pronunciationEncodingStr)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error parsing custom pronunciations: %v", err)), nil
+	}
+
+	voiceNameParam, _ := args["voice_name"].(string)
+	voice, voiceSubstitution := selectChirpVoice(strings.TrimSpace(voiceNameParam), availableVoices, voiceFallbacks)
+	if voice == nil {
+		return mcp.NewToolResultError("No Chirp3-HD voices available for synthesis. Please check server logs for voice fetching issues at startup."), nil
+	}
+	if voiceSubstitution != "" {
+		common.LogWarnf("%s", voiceSubstitution)
+	}
+
+	filenamePrefix, _ := args["output_filename_prefix"].(string)
+	if strings.TrimSpace(filenamePrefix) == "" {
+		filenamePrefix = "chirp_document"
+	}
+	outputDir, _ := args["output_directory"].(string)
+	outputDir = strings.TrimSpace(outputDir)
+
+	var savedFilename string
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error creating directory %s: %v", outputDir, err)), nil
+		}
+		safeVoiceName := strings.ReplaceAll(voice.Name, "/", "_")
+		safeVoiceName = strings.ReplaceAll(safeVoiceName, ":", "_")
+		savedFilename = filepath.Clean(filepath.Join(outputDir, fmt.Sprintf("%s-%s-%s.wav", filenamePrefix, safeVoiceName, time.Now().Format(timeFormatForFilename))))
+	} else {
+		tempDir, err := os.MkdirTemp("", "chirp_document_")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error creating temporary directory: %v", err)), nil
+		}
+		defer os.RemoveAll(tempDir)
+		savedFilename = filepath.Join(tempDir, filenamePrefix+".wav")
+	}
+
+	startTime := time.Now()
+	duration, err := synthesizeDocumentToWAVFile(ctx, client, voice, segments, customPronos, savedFilename)
+	auditRecord := common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "synthesize_long_form",
+		Model:      voice.Name,
+		Parameters: args,
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Error synthesizing document: %v", err)
+		common.LogErrorf("%s", errMsg)
+		auditRecord.Error = errMsg
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		return mcp.NewToolResultError(errMsg), nil
+	}
+	common.LogInfof("Synthesized %d segments (%v of audio) in %v", len(segments), duration.Round(time.Millisecond), time.Since(startTime))
+
+	resultText := fmt.Sprintf("Document of %d segment(s) synthesized successfully with voice %s (%v of audio).", len(segments), voice.Name, duration.Round(time.Millisecond))
+	if voiceSubstitution != "" {
+		resultText = voiceSubstitution + " " + resultText
+	}
+	if outputDir != "" {
+		auditRecord.OutputURIs = []string{savedFilename}
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		resultText = fmt.Sprintf("%s Audio saved to: %s.", resultText, savedFilename)
+		var texts []string
+		for _, segment := range segments {
+			texts = append(texts, segment.Text)
+		}
+		if tagMessage := appConfig.TagGeneratedAudioFiles(ctx, auditRecord.OutputURIs, voice.Name, strings.Join(texts, "\n")); tagMessage != "" {
+			resultText += " " + tagMessage
+		}
+		return mcp.NewToolResultText(resultText), nil
+	}
+
+	audio, err := os.ReadFile(savedFilename)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error reading synthesized audio: %v", err)), nil
+	}
+	common.WriteAuditRecord(ctx, appConfig, auditRecord)
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.TextContent{Type: "text", Text: resultText + " Audio data is included in the response."},
+		inlineAudioContent(ctx, audio, filenamePrefix+".wav", "audio/wav"),
+	}}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDocumentSegments(t *testing.T) {
+	segments, err := parseDocumentSegments([]interface{}{
+		"Introduction.",
+		map[string]interface{}{"text": " Chapter one. ", "pause_after_ms": float64(2000)},
+		map[string]interface{}{"text": "Chapter two."},
+		map[string]interface{}{"text": "The end.", "pause_after_ms": float64(3000)},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	want := []documentSegment{
+		{Text: "Introduction.", PauseAfter: time.Second},
+		{Text: "Chapter one.", PauseAfter: 2 * time.Second},
+		{Text: "Chapter two.", PauseAfter: time.Second},
+		{Text: "The end.", PauseAfter: 0},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("expected %d segments, but got %d", len(want), len(segments))
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d: expected %+v, but got %+v", i, want[i], segments[i])
+		}
+	}
+
+	invalid := []struct {
+		name string
+		arg  interface{}
+	}{
+		{name: "missing", arg: nil},
+		{name: "empty", arg: []interface{}{}},
+		{name: "blank text", arg: []interface{}{"  "}},
+		{name: "wrong type", arg: []interface{}{float64(1)}},
+		{name: "negative pause", arg: []interface{}{map[string]interface{}{"text": "a", "pause_after_ms": float64(-1)}}},
+		{name: "pause too long", arg: []interface{}{map[string]interface{}{"text": "a", "pause_after_ms": float64(60000)}}},
+	}
+	for _, tt := range invalid {
+		if _, err := parseDocumentSegments(tt.arg, time.Second); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestWAVStreamWriterAppendSilence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	writer, err := newWAVStreamWriter(path)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if err := writer.AppendSilence(time.Second); err == nil {
+		t.Errorf("expected an error when appending silence before any audio")
+	}
+	if err := writer.AppendWAV(buildTestWAV(clip(0, 500, 0))); err != nil {
+		t.Fatalf("failed to append audio: %v", err)
+	}
+	if err := writer.AppendSilence(time.Second); err != nil {
+		t.Fatalf("failed to append silence: %v", err)
+	}
+	if got := writer.Duration(); got != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s of audio, but got %v", got)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	info, err := parseWAV(data)
+	if err != nil {
+		t.Fatalf("expected a valid WAV file, but got: %v", err)
+	}
+	for i := info.dataOffset + 1000; i < info.dataOffset+info.dataSize; i++ {
+		if data[i] != 0 {
+			t.Fatalf("expected silence after the audio, but found a non-zero byte at offset %d", i)
+		}
+	}
+}
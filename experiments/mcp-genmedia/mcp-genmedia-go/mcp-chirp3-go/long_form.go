@@ -0,0 +1,238 @@
+// Package main implements an MCP server for Google's Chirp3 text-to-speech models.
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+const (
+	// maxChunkBytes keeps each synthesis request under the Text-to-Speech input limit of 5000 bytes.
+	maxChunkBytes = 4500
+	// chunkSynthesisTimeout bounds each per-chunk SynthesizeSpeech call in streaming mode.
+	chunkSynthesisTimeout = 30 * time.Second
+	// wavHeaderSize is the size of the canonical 44-byte PCM WAV header written by wavStreamWriter.
+	wavHeaderSize = 44
+)
+
+// splitTextIntoChunks splits text on sentence boundaries into chunks of at most maxBytes bytes.
+// Sentences longer than maxBytes are split on whitespace, and words longer than maxBytes are
+// split at rune boundaries.
+func splitTextIntoChunks(text string, maxBytes int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+	add := func(piece string) {
+		if current.Len() > 0 && current.Len()+1+len(piece) > maxBytes {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteByte(' ')
+		}
+		current.WriteString(piece)
+	}
+
+	for _, sentence := range splitSentences(text) {
+		if len(sentence) <= maxBytes {
+			add(sentence)
+			continue
+		}
+		for _, word := range strings.Fields(sentence) {
+			for len(word) > maxBytes {
+				cut := maxBytes
+				for cut > 0 && !utf8.RuneStart(word[cut]) {
+					cut--
+				}
+				flush()
+				chunks = append(chunks, word[:cut])
+				word = word[cut:]
+			}
+			add(word)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitSentences splits text after sentence-ending punctuation followed by whitespace, and at
+// blank lines. Each returned sentence is trimmed and non-empty.
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		end := false
+		switch {
+		case r == '\n' && i+1 < len(runes) && runes[i+1] == '\n':
+			end = true
+		case strings.ContainsRune(".!?。！？", r) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			end = true
+		}
+		if end {
+			if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+				sentences = append(sentences, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// wavStreamWriter writes a 16-bit PCM WAV file incrementally. It writes a placeholder header,
+// appends the PCM data of each synthesized chunk, and fills in the sizes on Close.
+type wavStreamWriter struct {
+	f         *os.File
+	format    wavInfo // Format of the first chunk; later chunks must match.
+	dataBytes int64
+}
+
+// newWAVStreamWriter creates the output file and reserves space for the header.
+func newWAVStreamWriter(path string) (*wavStreamWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(make([]byte, wavHeaderSize)); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &wavStreamWriter{f: f}, nil
+}
+
+// AppendWAV appends the PCM samples of a WAV buffer, which must match the format of the first buffer.
+func (w *wavStreamWriter) AppendWAV(wav []byte) error {
+	info, err := parseWAV(wav)
+	if err != nil {
+		return err
+	}
+	if w.format.sampleRate == 0 {
+		w.format = info
+	} else if info.sampleRate != w.format.sampleRate || info.channels != w.format.channels || info.bitsPerSample != w.format.bitsPerSample {
+		return fmt.Errorf("chunk format %d Hz/%d ch/%d-bit does not match %d Hz/%d ch/%d-bit", info.sampleRate, info.channels, info.bitsPerSample, w.format.sampleRate, w.format.channels, w.format.bitsPerSample)
+	}
+	n, err := w.f.Write(wav[info.dataOffset : info.dataOffset+info.dataSize])
+	w.dataBytes += int64(n)
+	return err
+}
+
+// AppendSilence appends d of silence in the format of the audio written so far, so at least one
+// WAV buffer must have been appended first.
+func (w *wavStreamWriter) AppendSilence(d time.Duration) error {
+	if w.format.sampleRate == 0 {
+		return errors.New("cannot append silence before any audio")
+	}
+	frameBytes := w.format.channels * w.format.bitsPerSample / 8
+	frames := int64(d) * int64(w.format.sampleRate) / int64(time.Second)
+	n, err := w.f.Write(make([]byte, frames*int64(frameBytes)))
+	w.dataBytes += int64(n)
+	return err
+}
+
+// Close writes the final header and closes the file.
+func (w *wavStreamWriter) Close() error {
+	if w.format.sampleRate == 0 {
+		_ = w.f.Close()
+		return errors.New("no audio was written")
+	}
+	if w.dataBytes > 0xFFFFFFFF-wavHeaderSize {
+		_ = w.f.Close()
+		return errors.New("audio exceeds the 4 GiB WAV size limit")
+	}
+	blockAlign := w.format.channels * w.format.bitsPerSample / 8
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(wavHeaderSize-8+w.dataBytes))
+	copy(header[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:24], uint16(w.format.channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(w.format.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(w.format.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(w.format.bitsPerSample))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(w.dataBytes))
+
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	if _, err := w.f.Write(header); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// Duration returns the length of the audio written so far.
+func (w *wavStreamWriter) Duration() time.Duration {
+	bytesPerSecond := w.format.sampleRate * w.format.channels * w.format.bitsPerSample / 8
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(w.dataBytes) * time.Second / time.Duration(bytesPerSecond)
+}
+
+// synthesizeToWAVFile synthesizes text chunk by chunk and streams each chunk's audio into a single
+// WAV file at path, so that only one chunk of audio is held in memory at a time. On failure the
+// partial file is removed. It returns the number of chunks and the audio duration.
+func synthesizeToWAVFile(ctx context.Context, client *texttospeech.Client, voice *texttospeechpb.Voice, text string, customPronos *texttospeechpb.CustomPronunciations, path string) (int, time.Duration, error) {
+	chunks := splitTextIntoChunks(text, maxChunkBytes)
+	if len(chunks) == 0 {
+		return 0, 0, errors.New("text contains nothing to synthesize")
+	}
+	writer, err := newWAVStreamWriter(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("creating %s: %w", path, err)
+	}
+	if err := appendSynthesizedChunks(ctx, client, voice, chunks, customPronos, writer); err != nil {
+		_ = writer.f.Close()
+		_ = os.Remove(path)
+		return 0, 0, err
+	}
+
+	duration := writer.Duration()
+	if err := writer.Close(); err != nil {
+		_ = os.Remove(path)
+		return 0, 0, fmt.Errorf("finalizing %s: %w", path, err)
+	}
+	return len(chunks), duration, nil
+}
+
+// appendSynthesizedChunks synthesizes each chunk in sequence and appends its audio to writer.
+func appendSynthesizedChunks(ctx context.Context, client *texttospeech.Client, voice *texttospeechpb.Voice, chunks []string, customPronos *texttospeechpb.CustomPronunciations, writer *wavStreamWriter) error {
+	for i, chunk := range chunks {
+		common.LogInfof("Synthesizing chunk %d/%d (%d bytes) with voice %s", i+1, len(chunks), len(chunk), voice.GetName())
+		chunkCtx, cancel := context.WithTimeout(ctx, chunkSynthesisTimeout)
+		audio, err := synthesizeWithVoice(chunkCtx, client, voice, chunk, customPronos, texttospeechpb.AudioEncoding_LINEAR16)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if err := writer.AppendWAV(audio); err != nil {
+			return fmt.Errorf("writing chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+	return nil
+}
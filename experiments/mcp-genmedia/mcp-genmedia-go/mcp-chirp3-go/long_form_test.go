@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitTextIntoChunks(t *testing.T) {
+	sentence := "The quick brown fox jumps over the lazy dog. "
+	long := strings.Repeat(sentence, 20000/len(sentence)+1)
+
+	chunks := splitTextIntoChunks(long, maxChunkBytes)
+	if len(chunks) < 2 {
+		t.Fatalf("expected a 20,000-character input to be split, but got %d chunk(s)", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > maxChunkBytes {
+			t.Errorf("chunk %d is %d bytes, over the %d byte limit", i, len(chunk), maxChunkBytes)
+		}
+		if !strings.HasSuffix(chunk, ".") {
+			t.Errorf("expected chunk %d to end on a sentence boundary, but got %q", i, chunk[max(0, len(chunk)-20):])
+		}
+	}
+	if got, want := strings.Join(chunks, " "), strings.TrimSpace(long); got != want {
+		t.Errorf("expected the chunks to reassemble the input")
+	}
+
+	// A single sentence longer than the limit is split on whitespace, and a long word at rune boundaries.
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{name: "short text", text: "Hello there. How are you?", max: 100, want: []string{"Hello there. How are you?"}},
+		{name: "sentences packed", text: "One. Two. Three.", max: 9, want: []string{"One. Two.", "Three."}},
+		{name: "long sentence", text: "alpha beta gamma delta", max: 11, want: []string{"alpha beta", "gamma delta"}},
+		{name: "long word", text: "ééééé", max: 4, want: []string{"éé", "éé", "é"}},
+		{name: "blank", text: "  \n ", max: 10, want: nil},
+	}
+	for _, tt := range tests {
+		got := splitTextIntoChunks(tt.text, tt.max)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: expected %q, but got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestWAVStreamWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	writer, err := newWAVStreamWriter(path)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if err := writer.AppendWAV(buildTestWAV(clip(0, 500, 0))); err != nil {
+		t.Fatalf("failed to append first chunk: %v", err)
+	}
+	if err := writer.AppendWAV(buildTestWAV(clip(250, 250, 0))); err != nil {
+		t.Fatalf("failed to append second chunk: %v", err)
+	}
+	if got := writer.Duration(); got != time.Second {
+		t.Errorf("expected 1s of audio, but got %v", got)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	info, err := parseWAV(data)
+	if err != nil {
+		t.Fatalf("expected a valid WAV file, but got: %v", err)
+	}
+	if info.dataSize != 2000 || info.sampleRate != 1000 || info.channels != 1 {
+		t.Errorf("expected 2000 bytes of 1 kHz mono audio, but got %d bytes at %d Hz, %d channel(s)", info.dataSize, info.sampleRate, info.channels)
+	}
+	if len(data) != wavHeaderSize+2000 {
+		t.Errorf("expected a %d byte file, but got %d bytes", wavHeaderSize+2000, len(data))
+	}
+}
@@ -0,0 +1,133 @@
+// Package main implements an MCP server for Google's Chirp3 text-to-speech models.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// defaultSilenceThreshold is the peak amplitude, as a fraction of full scale, at or below which a frame is silent.
+	defaultSilenceThreshold = 0.01
+	// defaultMaxSilenceTrim caps how much silence is removed from each end of the clip.
+	defaultMaxSilenceTrim = 1000 * time.Millisecond
+)
+
+// wavInfo describes the PCM layout and data chunk location of a WAV file.
+type wavInfo struct {
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	dataOffset    int // Offset of the data chunk payload.
+	dataSize      int // Size of the data chunk payload, clamped to the buffer length.
+}
+
+// parseWAV locates the fmt and data chunks of a RIFF/WAVE buffer. Only 16-bit PCM is supported,
+// which is what the Text-to-Speech API returns for LINEAR16.
+func parseWAV(wav []byte) (wavInfo, error) {
+	var info wavInfo
+	if len(wav) < 12 || string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		return info, errors.New("not a RIFF/WAVE buffer")
+	}
+
+	foundFmt := false
+	for offset := 12; offset+8 <= len(wav); {
+		chunkID := string(wav[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(wav[offset+4 : offset+8]))
+		payload := offset + 8
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 || payload+16 > len(wav) {
+				return info, errors.New("malformed fmt chunk")
+			}
+			if format := binary.LittleEndian.Uint16(wav[payload : payload+2]); format != 1 {
+				return info, fmt.Errorf("unsupported WAV audio format %d, expected PCM", format)
+			}
+			info.channels = int(binary.LittleEndian.Uint16(wav[payload+2 : payload+4]))
+			info.sampleRate = int(binary.LittleEndian.Uint32(wav[payload+4 : payload+8]))
+			info.bitsPerSample = int(binary.LittleEndian.Uint16(wav[payload+14 : payload+16]))
+			if info.bitsPerSample != 16 || info.channels < 1 || info.sampleRate < 1 {
+				return info, fmt.Errorf("unsupported WAV layout: %d channel(s), %d Hz, %d-bit", info.channels, info.sampleRate, info.bitsPerSample)
+			}
+			foundFmt = true
+		case "data":
+			if !foundFmt {
+				return info, errors.New("data chunk precedes fmt chunk")
+			}
+			info.dataOffset = payload
+			// Streaming encoders may write a placeholder size; trust the buffer length instead.
+			info.dataSize = min(chunkSize, len(wav)-payload)
+			return info, nil
+		}
+		offset = payload + chunkSize + chunkSize%2 // Chunks are word-aligned.
+	}
+	return info, errors.New("no data chunk found")
+}
+
+// trimWAVSilence removes leading and trailing silence from a 16-bit PCM WAV buffer.
+// A frame is silent when every channel's amplitude is at or below threshold (a fraction of
+// full scale). At most maxTrim is removed from each end. It returns the trimmed WAV along
+// with the durations removed from the beginning and end of the clip.
+func trimWAVSilence(wav []byte, threshold float64, maxTrim time.Duration) ([]byte, time.Duration, time.Duration, error) {
+	info, err := parseWAV(wav)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	frameSize := info.channels * 2
+	totalFrames := info.dataSize / frameSize
+	if totalFrames == 0 {
+		return wav, 0, 0, nil
+	}
+	limit := int(math.Round(threshold * math.MaxInt16))
+	data := wav[info.dataOffset : info.dataOffset+totalFrames*frameSize]
+
+	isSilent := func(frame int) bool {
+		for ch := 0; ch < info.channels; ch++ {
+			i := frame*frameSize + ch*2
+			sample := int(int16(binary.LittleEndian.Uint16(data[i : i+2])))
+			if sample > limit || sample < -limit {
+				return false
+			}
+		}
+		return true
+	}
+
+	maxTrimFrames := totalFrames
+	if maxTrim > 0 {
+		maxTrimFrames = int(maxTrim.Seconds() * float64(info.sampleRate))
+	}
+
+	start := 0
+	for start < totalFrames && start < maxTrimFrames && isSilent(start) {
+		start++
+	}
+	if start == totalFrames {
+		// The whole clip is below the threshold; leave it untouched rather than return nothing.
+		return wav, 0, 0, nil
+	}
+	end := totalFrames
+	for end > start && totalFrames-end < maxTrimFrames && isSilent(end-1) {
+		end--
+	}
+	if start == 0 && end == totalFrames {
+		return wav, 0, 0, nil
+	}
+
+	trimmedData := data[start*frameSize : end*frameSize]
+	out := make([]byte, 0, info.dataOffset+len(trimmedData))
+	out = append(out, wav[:info.dataOffset]...)
+	out = append(out, trimmedData...)
+	binary.LittleEndian.PutUint32(out[info.dataOffset-4:info.dataOffset], uint32(len(trimmedData)))
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+
+	frameDuration := func(frames int) time.Duration {
+		return time.Duration(frames) * time.Second / time.Duration(info.sampleRate)
+	}
+	return out, frameDuration(start), frameDuration(totalFrames - end), nil
+}
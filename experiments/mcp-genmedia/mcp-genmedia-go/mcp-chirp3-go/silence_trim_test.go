@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// buildTestWAV builds a mono 16-bit PCM WAV at 1 kHz from the given samples.
+func buildTestWAV(samples []int16) []byte {
+	var buf bytes.Buffer
+	dataSize := len(samples) * 2
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))    // PCM
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))    // mono
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(1000)) // sample rate
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(2000)) // byte rate
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))    // block align
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))   // bits per sample
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	_ = binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// clip returns lead silent samples, body loud samples, and tail silent samples.
+func clip(lead, body, tail int) []int16 {
+	samples := make([]int16, lead+body+tail)
+	for i := lead; i < lead+body; i++ {
+		samples[i] = 10000
+	}
+	return samples
+}
+
+func TestTrimWAVSilence(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     []int16
+		maxTrim     time.Duration
+		wantSamples int
+		wantLead    time.Duration
+		wantTail    time.Duration
+	}{
+		{"trims both ends", clip(200, 500, 300), 0, 500, 200 * time.Millisecond, 300 * time.Millisecond},
+		{"respects max trim", clip(200, 500, 300), 100 * time.Millisecond, 800, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"no silence is unchanged", clip(0, 500, 0), 0, 500, 0, 0},
+		{"all silence is unchanged", clip(500, 0, 0), 0, 500, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, lead, tail, err := trimWAVSilence(buildTestWAV(tt.samples), defaultSilenceThreshold, tt.maxTrim)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			info, err := parseWAV(out)
+			if err != nil {
+				t.Fatalf("expected trimmed output to be a valid WAV, but got: %v", err)
+			}
+			if got := info.dataSize / 2; got != tt.wantSamples {
+				t.Errorf("expected %d samples, but got %d", tt.wantSamples, got)
+			}
+			if got := int(binary.LittleEndian.Uint32(out[4:8])); got != len(out)-8 {
+				t.Errorf("expected RIFF size %d, but got %d", len(out)-8, got)
+			}
+			if lead != tt.wantLead || tail != tt.wantTail {
+				t.Errorf("expected trims %v/%v, but got %v/%v", tt.wantLead, tt.wantTail, lead, tail)
+			}
+		})
+	}
+}
+
+func TestTrimWAVSilenceRejectsNonWAV(t *testing.T) {
+	if _, _, _, err := trimWAVSilence([]byte("not a wav file"), defaultSilenceThreshold, 0); err == nil {
+		t.Errorf("expected an error for non-WAV input, but got nil")
+	}
+}
@@ -0,0 +1,346 @@
+// Package main implements an MCP server for Google's Chirp3 text-to-speech models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	translate "google.golang.org/api/translate/v3"
+)
+
+// translationTimeout bounds the call to the Cloud Translation API.
+const translationTimeout = 30 * time.Second
+
+var (
+	translateService   *translate.Service
+	translateServiceMu sync.Mutex
+)
+
+// translationLanguageOverrides maps Chirp3-HD language codes to the codes accepted by the Cloud
+// Translation API where the two differ. Other languages are translated by their base language.
+var translationLanguageOverrides = map[string]string{
+	"cmn-cn": "zh-CN",
+	"pt-br":  "pt-BR",
+	"fr-ca":  "fr-CA",
+}
+
+// resolveLanguageCode returns the BCP-47 code for a language given as a code (e.g. 'ja-JP') or as
+// a descriptive name from LanguageNameToCodeMap (e.g. 'Japanese (Japan)').
+func resolveLanguageCode(language string) string {
+	language = strings.TrimSpace(language)
+	if code, ok := LanguageNameToCodeMap[strings.ToLower(language)]; ok {
+		return code
+	}
+	return language
+}
+
+// translationLanguageCode returns the Cloud Translation API code for a Chirp3-HD language code.
+func translationLanguageCode(languageCode string) string {
+	if code, ok := translationLanguageOverrides[strings.ToLower(languageCode)]; ok {
+		return code
+	}
+	base, _, _ := strings.Cut(languageCode, "-")
+	return strings.ToLower(base)
+}
+
+// selectVoiceForLanguage picks a Chirp3-HD voice that speaks languageCode. The requested voice is
+// used if it speaks the language; otherwise the voice with the same speaker as the default voice
+// (e.g. ja-JP-Chirp3-HD-Zephyr) is preferred, then the first such voice by name.
+func selectVoiceForLanguage(languageCode, requested string, voices []*texttospeechpb.Voice) *texttospeechpb.Voice {
+	var candidates []*texttospeechpb.Voice
+	for _, v := range voices {
+		for _, code := range v.GetLanguageCodes() {
+			if strings.EqualFold(code, languageCode) {
+				candidates = append(candidates, v)
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].GetName() < candidates[j].GetName() })
+
+	speaker := defaultChirpVoiceName[strings.LastIndex(defaultChirpVoiceName, "-")+1:]
+	for _, preferred := range []string{requested, languageCode + "-Chirp3-HD-" + speaker} {
+		for _, v := range candidates {
+			if preferred != "" && strings.EqualFold(v.GetName(), preferred) {
+				return v
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// safeFilenamePrefix makes a caller-supplied filename prefix safe to join into an output path:
+// path separators and ".." are replaced, so the file cannot be written outside the output
+// directory. An empty result falls back to fallback.
+func safeFilenamePrefix(prefix, fallback string) string {
+	prefix = strings.NewReplacer("/", "_", `\`, "_", ":", "_", "..", "_").Replace(strings.TrimSpace(prefix))
+	if strings.Trim(prefix, "._") == "" {
+		return fallback
+	}
+	return prefix
+}
+
+// ensureTranslateService lazily creates the Cloud Translation API client. The client outlives
+// the request that creates it, so it is not bound to the request's context, and a failure is
+// retried by the next request.
+func ensureTranslateService() (*translate.Service, error) {
+	translateServiceMu.Lock()
+	defer translateServiceMu.Unlock()
+	if translateService != nil {
+		return translateService, nil
+	}
+	svc, err := translate.NewService(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	translateService = svc
+	return translateService, nil
+}
+
+// translateText translates text to targetLanguage with the Cloud Translation API. If
+// sourceLanguage is empty, it is detected and returned.
+func translateText(ctx context.Context, text, sourceLanguage, targetLanguage string) (translated, detectedSource string, err error) {
+	svc, err := ensureTranslateService()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create Cloud Translation client: %w", err)
+	}
+	req := &translate.TranslateTextRequest{
+		Contents:           []string{text},
+		MimeType:           "text/plain",
+		SourceLanguageCode: sourceLanguage,
+		TargetLanguageCode: targetLanguage,
+	}
+	parent := fmt.Sprintf("projects/%s/locations/global", appConfig.ProjectID)
+
+	callCtx, cancel := context.WithTimeout(ctx, translationTimeout)
+	defer cancel()
+	resp, err := svc.Projects.Locations.TranslateText(parent, req).Context(callCtx).Do()
+	if err != nil {
+		return "", "", fmt.Errorf("TranslateText: %w", err)
+	}
+	if len(resp.Translations) == 0 {
+		return "", "", fmt.Errorf("TranslateText returned no translations")
+	}
+	return resp.Translations[0].TranslatedText, resp.Translations[0].DetectedLanguageCode, nil
+}
+
+// translateAndSynthesizeHandler is the handler for the 'translate_and_synthesize' tool. It
+// translates the text to the target language and synthesizes the translation with a Chirp3-HD
+// voice for that language.
+func translateAndSynthesizeHandler(client *texttospeech.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	text, err := appConfig.InputText(ctx, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	targetParam, _ := args["target_language"].(string)
+	targetLanguage := resolveLanguageCode(targetParam)
+	if targetLanguage == "" {
+		return mcp.NewToolResultError("target_language parameter is required"), nil
+	}
+	sourceParam, _ := args["source_language"].(string)
+	sourceLanguage := resolveLanguageCode(sourceParam)
+	if sourceLanguage != "" {
+		sourceLanguage = translationLanguageCode(sourceLanguage)
+	}
+
+	voiceNameParam, _ := args["voice_name"].(string)
+	voice := selectVoiceForLanguage(targetLanguage, strings.TrimSpace(voiceNameParam), availableVoices)
+	if voice == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("No Chirp3-HD voice is available for language '%s'. Use 'list_chirp_voices' or the 'chirp://language_codes' resource to find supported languages.", targetLanguage)), nil
+	}
+
+	auditRecord := common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "translate_and_synthesize",
+		Prompt:     text,
+		Model:      voice.GetName(),
+		Parameters: args,
+	}
+
+	common.LogInfof("Translating %d characters to %s", len(text), targetLanguage)
+	translated, detectedSource, err := translateText(ctx, text, sourceLanguage, translationLanguageCode(targetLanguage))
+	if err != nil {
+		errMsg := fmt.Sprintf("Error translating text: %v", err)
+		common.LogErrorf("%s", errMsg)
+		auditRecord.Error = errMsg
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		return mcp.NewToolResultError(errMsg), nil
+	}
+	if detectedSource != "" {
+		sourceLanguage = detectedSource
+	}
+
+	filenamePrefix, _ := args["output_filename_prefix"].(string)
+	filenamePrefix = safeFilenamePrefix(filenamePrefix, "chirp_translation")
+	outputDir, _ := args["output_directory"].(string)
+	outputDir = strings.TrimSpace(outputDir)
+
+	var savedFilename string
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error creating directory %s: %v", outputDir, err)), nil
+		}
+		safeVoiceName := strings.ReplaceAll(voice.GetName(), "/", "_")
+		safeVoiceName = strings.ReplaceAll(safeVoiceName, ":", "_")
+		savedFilename = filepath.Clean(filepath.Join(outputDir, fmt.Sprintf("%s-%s-%s.wav", filenamePrefix, safeVoiceName, time.Now().Format(timeFormatForFilename))))
+	} else {
+		tempDir, err := os.MkdirTemp("", "chirp_translation_")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error creating temporary directory: %v", err)), nil
+		}
+		defer os.RemoveAll(tempDir)
+		savedFilename = filepath.Join(tempDir, filenamePrefix+".wav")
+	}
+
+	// The translation can be longer than a single synthesis request allows, so it is synthesized
+	// in chunks as in stream_to_file mode.
+	_, duration, err := synthesizeToWAVFile(ctx, client, voice, translated, nil, savedFilename)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error synthesizing translated speech: %v", err)
+		common.LogErrorf("%s", errMsg)
+		auditRecord.Error = errMsg
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		return mcp.NewToolResultError(errMsg), nil
+	}
+
+	resultText := fmt.Sprintf("Translated from %s to %s and synthesized with voice %s (%v of audio).\nTranslation: %s", sourceLanguage, targetLanguage, voice.GetName(), duration.Round(time.Millisecond), translated)
+	if outputDir != "" {
+		auditRecord.OutputURIs = []string{savedFilename}
+		common.WriteAuditRecord(ctx, appConfig, auditRecord)
+		resultText = fmt.Sprintf("%s\nAudio saved to: %s", resultText, savedFilename)
+		if tagMessage := appConfig.TagGeneratedAudioFiles(ctx, auditRecord.OutputURIs, voice.GetName(), text); tagMessage != "" {
+			resultText += "\n" + tagMessage
+		}
+		return mcp.NewToolResultText(resultText), nil
+	}
+
+	audio, err := os.ReadFile(savedFilename)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error reading synthesized audio: %v", err)), nil
+	}
+	common.WriteAuditRecord(ctx, appConfig, auditRecord)
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.TextContent{Type: "text", Text: resultText},
+		inlineAudioContent(ctx, audio, filenamePrefix+".wav", "audio/wav"),
+	}}, nil
+}
+
+// detectedLanguageRegions maps detected languages that Chirp3-HD speaks in several regions, or
+// under a regional code only, to the Chirp3-HD language reported by detect_language. Text alone
+// rarely identifies a regional variant.
+var detectedLanguageRegions = map[string]string{
+	"en": "en-US",
+	"es": "es-ES",
+	"fr": "fr-FR",
+	"pt": "pt-BR",
+	"zh": "cmn-CN",
+}
+
+// chirpLanguageForDetectedCode returns the Chirp3-HD language code and descriptive name for a
+// language code returned by the Cloud Translation API (e.g. 'de' -> 'de-DE', 'German (Germany)').
+// It returns empty strings if no Chirp3-HD language matches.
+func chirpLanguageForDetectedCode(detected string) (code, name string) {
+	if regional, ok := detectedLanguageRegions[strings.ToLower(detected)]; ok {
+		detected = regional
+	}
+	for name, code := range LanguageNameToCodeMap {
+		if strings.EqualFold(code, detected) || strings.EqualFold(translationLanguageCode(code), detected) {
+			return code, OriginalLanguageNames[name]
+		}
+	}
+	return "", ""
+}
+
+// detectedLanguage is a language detected by the 'detect_language' tool.
+type detectedLanguage struct {
+	LanguageCode   string  `json:"language_code"`           // Chirp3-HD language code, or the detected code if Chirp3-HD does not support the language.
+	DetectedCode   string  `json:"detected_code"`           // Code returned by the Cloud Translation API.
+	LanguageName   string  `json:"language_name,omitempty"` // Descriptive name, if Chirp3-HD supports the language.
+	Confidence     float64 `json:"confidence"`
+	ChirpSupported bool    `json:"chirp_supported"`
+}
+
+// detectLanguageResult is the structured content of the 'detect_language' result. The most
+// likely language comes first.
+type detectLanguageResult struct {
+	detectedLanguage
+	Alternatives []detectedLanguage `json:"alternatives,omitempty"`
+}
+
+// detectLanguage detects the language of text with the Cloud Translation API. The candidates are
+// returned most likely first.
+func detectLanguage(ctx context.Context, text string) ([]detectedLanguage, error) {
+	svc, err := ensureTranslateService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Translation client: %w", err)
+	}
+	req := &translate.DetectLanguageRequest{
+		Content:  text,
+		MimeType: "text/plain",
+	}
+	parent := fmt.Sprintf("projects/%s/locations/global", appConfig.ProjectID)
+
+	callCtx, cancel := context.WithTimeout(ctx, translationTimeout)
+	defer cancel()
+	resp, err := svc.Projects.Locations.DetectLanguage(parent, req).Context(callCtx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("DetectLanguage: %w", err)
+	}
+
+	var languages []detectedLanguage
+	for _, l := range resp.Languages {
+		language := detectedLanguage{LanguageCode: l.LanguageCode, DetectedCode: l.LanguageCode, Confidence: l.Confidence}
+		if code, name := chirpLanguageForDetectedCode(l.LanguageCode); code != "" {
+			language.LanguageCode, language.LanguageName, language.ChirpSupported = code, name, true
+		}
+		languages = append(languages, language)
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].Confidence > languages[j].Confidence })
+	return languages, nil
+}
+
+// detectLanguageHandler is the handler for the 'detect_language' tool. It returns the most
+// likely language of the text, as a Chirp3-HD language code where possible, so the result can be
+// passed to the TTS and translation tools.
+func detectLanguageHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text, _ := request.GetArguments()["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return mcp.NewToolResultError("text parameter must be a non-empty string and is required"), nil
+	}
+
+	common.LogInfof("Detecting the language of %d characters", len(text))
+	languages, err := detectLanguage(ctx, text)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error detecting language: %v", err)
+		common.LogErrorf("%s", errMsg)
+		return mcp.NewToolResultError(errMsg), nil
+	}
+	if len(languages) == 0 {
+		return mcp.NewToolResultError("The Cloud Translation API could not detect the language of the text."), nil
+	}
+
+	result := detectLanguageResult{detectedLanguage: languages[0], Alternatives: languages[1:]}
+	text = fmt.Sprintf("Detected language: %s (confidence %.2f).", result.LanguageCode, result.Confidence)
+	if result.ChirpSupported {
+		text = fmt.Sprintf("Detected language: %s, %s (confidence %.2f).", result.LanguageCode, result.LanguageName, result.Confidence)
+	} else {
+		text += " Chirp3-HD has no voices for this language."
+	}
+	return mcp.NewToolResultStructured(result, text), nil
+}
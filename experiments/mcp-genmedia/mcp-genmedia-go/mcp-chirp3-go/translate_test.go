@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+)
+
+func TestTranslationLanguageCode(t *testing.T) {
+	tests := map[string]string{
+		"ja-JP":  "ja",
+		"en-US":  "en",
+		"cmn-CN": "zh-CN",
+		"pt-BR":  "pt-BR",
+		"fr-CA":  "fr-CA",
+		"ar-XA":  "ar",
+	}
+	for code, want := range tests {
+		if got := translationLanguageCode(code); got != want {
+			t.Errorf("translationLanguageCode(%q): expected %q, but got %q", code, want, got)
+		}
+	}
+	if got := resolveLanguageCode("Japanese (Japan)"); got != "ja-JP" {
+		t.Errorf("expected a descriptive name to resolve to ja-JP, but got %q", got)
+	}
+}
+
+func TestChirpLanguageForDetectedCode(t *testing.T) {
+	tests := []struct {
+		detected string
+		wantCode string
+		wantName string
+	}{
+		{"de", "de-DE", "German (Germany)"},
+		{"en", "en-US", "English (United States)"},
+		{"fr-CA", "fr-CA", "French (Canada)"},
+		{"zh-CN", "cmn-CN", "Mandarin Chinese (China)"},
+		{"pt", "pt-BR", "Portuguese (Brazil)"},
+		{"sw", "", ""},
+	}
+	for _, tt := range tests {
+		code, name := chirpLanguageForDetectedCode(tt.detected)
+		if code != tt.wantCode || name != tt.wantName {
+			t.Errorf("chirpLanguageForDetectedCode(%q): expected (%q, %q), but got (%q, %q)", tt.detected, tt.wantCode, tt.wantName, code, name)
+		}
+	}
+}
+
+func TestSelectVoiceForLanguage(t *testing.T) {
+	voice := func(name, lang string) *texttospeechpb.Voice {
+		return &texttospeechpb.Voice{Name: name, LanguageCodes: []string{lang}}
+	}
+	voices := []*texttospeechpb.Voice{
+		voice("en-US-Chirp3-HD-Zephyr", "en-US"),
+		voice("ja-JP-Chirp3-HD-Puck", "ja-JP"),
+		voice("ja-JP-Chirp3-HD-Zephyr", "ja-JP"),
+		voice("ja-JP-Chirp3-HD-Aoede", "ja-JP"),
+	}
+
+	tests := []struct {
+		name      string
+		language  string
+		requested string
+		want      string
+	}{
+		{name: "default speaker", language: "ja-JP", want: "ja-JP-Chirp3-HD-Zephyr"},
+		{name: "requested voice", language: "ja-JP", requested: "ja-JP-Chirp3-HD-Puck", want: "ja-JP-Chirp3-HD-Puck"},
+		{name: "requested voice for another language", language: "ja-JP", requested: "en-US-Chirp3-HD-Zephyr", want: "ja-JP-Chirp3-HD-Zephyr"},
+		{name: "unsupported language", language: "de-DE", want: ""},
+	}
+	for _, tt := range tests {
+		got := selectVoiceForLanguage(tt.language, tt.requested, voices)
+		if got.GetName() != tt.want {
+			t.Errorf("%s: expected %q, but got %q", tt.name, tt.want, got.GetName())
+		}
+	}
+
+	withoutDefault := []*texttospeechpb.Voice{voice("ja-JP-Chirp3-HD-Puck", "ja-JP"), voice("ja-JP-Chirp3-HD-Aoede", "ja-JP")}
+	if got := selectVoiceForLanguage("ja-JP", "", withoutDefault); got.GetName() != "ja-JP-Chirp3-HD-Aoede" {
+		t.Errorf("expected the first voice by name, but got %q", got.GetName())
+	}
+}
+
+func TestSafeFilenamePrefix(t *testing.T) {
+	tests := map[string]string{
+		"":                 "chirp_translation",
+		"  ":               "chirp_translation",
+		"greeting":         "greeting",
+		"../../etc/passwd": "____etc_passwd",
+		`..\windows`:       "__windows",
+		"..":               "chirp_translation",
+		"a/b":              "a_b",
+	}
+	for prefix, want := range tests {
+		if got := safeFilenamePrefix(prefix, "chirp_translation"); got != want {
+			t.Errorf("safeFilenamePrefix(%q): expected %q, but got %q", prefix, want, got)
+		}
+	}
+}
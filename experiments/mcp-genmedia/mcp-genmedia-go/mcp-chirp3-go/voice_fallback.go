@@ -0,0 +1,116 @@
+// Package main implements an MCP server for Google's Chirp3 text-to-speech models.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+// anyLanguageFallbackKey is the CHIRP3_VOICE_FALLBACKS key whose chain applies to every language.
+const anyLanguageFallbackKey = "*"
+
+// voiceFallbacks holds the ordered fallback voices per language code (lowercase), read from
+// CHIRP3_VOICE_FALLBACKS.
+var voiceFallbacks map[string][]string
+
+// parseVoiceFallbacks parses a JSON object mapping BCP-47 language codes (or "*" for any
+// language) to ordered lists of voice names, e.g.
+// {"en-US": ["en-US-Chirp3-HD-Puck", "en-US-Chirp3-HD-Kore"], "*": ["en-US-Chirp3-HD-Zephyr"]}.
+func parseVoiceFallbacks(value string) (map[string][]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var raw map[string][]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("CHIRP3_VOICE_FALLBACKS must be a JSON object of language code to voice names: %w", err)
+	}
+	fallbacks := make(map[string][]string, len(raw))
+	for lang, voices := range raw {
+		var chain []string
+		for _, v := range voices {
+			if v = strings.TrimSpace(v); v != "" {
+				chain = append(chain, v)
+			}
+		}
+		fallbacks[strings.ToLower(strings.TrimSpace(lang))] = chain
+	}
+	return fallbacks, nil
+}
+
+// loadVoiceFallbacks reads CHIRP3_VOICE_FALLBACKS. An invalid value is logged and ignored.
+func loadVoiceFallbacks() map[string][]string {
+	fallbacks, err := parseVoiceFallbacks(os.Getenv("CHIRP3_VOICE_FALLBACKS"))
+	if err != nil {
+		common.LogWarnf("Warning: ignoring voice fallback chain: %v", err)
+		return nil
+	}
+	if len(fallbacks) > 0 {
+		common.LogInfof("Loaded voice fallback chains for %d language(s).", len(fallbacks))
+	}
+	return fallbacks
+}
+
+// voiceLanguageCode returns the language code prefix of a voice name, e.g. "en-US" for
+// "en-US-Chirp3-HD-Zephyr", or "" if the name has no such prefix.
+func voiceLanguageCode(voiceName string) string {
+	parts := strings.SplitN(voiceName, "-", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// selectChirpVoice picks the voice to synthesize with. The requested voice is used if available.
+// Otherwise the configured fallback chain for its language is tried in order, then the "*" chain,
+// then defaultChirpVoiceName, and finally the first available voice. substitution describes the
+// fallback that was used when a requested voice was unavailable, and is empty otherwise.
+func selectChirpVoice(requested string, voices []*texttospeechpb.Voice, fallbacks map[string][]string) (voice *texttospeechpb.Voice, substitution string) {
+	byName := make(map[string]*texttospeechpb.Voice, len(voices))
+	for _, v := range voices {
+		byName[v.Name] = v
+	}
+
+	if requested != "" {
+		if v, ok := byName[requested]; ok {
+			return v, ""
+		}
+		common.LogWarnf("Requested voice_name '%s' not found among available Chirp3-HD voices. Attempting fallbacks.", requested)
+
+		lang := voiceLanguageCode(requested)
+		for _, key := range []string{strings.ToLower(lang), anyLanguageFallbackKey} {
+			if key == "" {
+				continue
+			}
+			for _, name := range fallbacks[key] {
+				if v, ok := byName[name]; ok {
+					chain := "the fallback chain for " + lang
+					if key == anyLanguageFallbackKey {
+						chain = "the default fallback chain"
+					}
+					return v, fmt.Sprintf("Requested voice %s is not available; used %s from %s.", requested, v.Name, chain)
+				}
+			}
+		}
+	}
+
+	if v, ok := byName[defaultChirpVoiceName]; ok {
+		if requested != "" {
+			return v, fmt.Sprintf("Requested voice %s is not available; used the default voice %s.", requested, v.Name)
+		}
+		return v, ""
+	}
+	if len(voices) == 0 {
+		return nil, ""
+	}
+	common.LogWarnf("Preferred default voice '%s' not found. Defaulting to first available Chirp3-HD voice: %s", defaultChirpVoiceName, voices[0].Name)
+	if requested != "" {
+		return voices[0], fmt.Sprintf("Requested voice %s is not available; used the first available voice %s.", requested, voices[0].Name)
+	}
+	return voices[0], ""
+}
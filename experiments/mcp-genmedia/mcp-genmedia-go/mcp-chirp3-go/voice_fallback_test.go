@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+)
+
+func TestParseVoiceFallbacks(t *testing.T) {
+	fallbacks, err := parseVoiceFallbacks(`{"de-DE": ["de-DE-Chirp3-HD-Kore", " "], "*": ["en-US-Chirp3-HD-Puck"]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fallbacks["de-de"]; len(got) != 1 || got[0] != "de-DE-Chirp3-HD-Kore" {
+		t.Errorf("expected the de-DE chain under a lowercase key without blanks, but got %v", got)
+	}
+	if _, err := parseVoiceFallbacks(`["not", "an", "object"]`); err == nil {
+		t.Errorf("expected an error for a non-object value, but got none")
+	}
+	if fallbacks, err := parseVoiceFallbacks(""); err != nil || fallbacks != nil {
+		t.Errorf("expected no fallbacks for an empty value, but got %v (err %v)", fallbacks, err)
+	}
+}
+
+func TestSelectChirpVoice(t *testing.T) {
+	voices := []*texttospeechpb.Voice{
+		{Name: "de-DE-Chirp3-HD-Charon"},
+		{Name: "de-DE-Chirp3-HD-Kore"},
+		{Name: "en-US-Chirp3-HD-Puck"},
+		{Name: defaultChirpVoiceName},
+	}
+	fallbacks := map[string][]string{
+		"de-de": {"de-DE-Chirp3-HD-Missing", "de-DE-Chirp3-HD-Kore"},
+		"*":     {"en-US-Chirp3-HD-Puck"},
+	}
+
+	testCases := []struct {
+		name             string
+		requested        string
+		voices           []*texttospeechpb.Voice
+		wantVoice        string
+		wantSubstitution string
+	}{
+		{name: "available voice", requested: "de-DE-Chirp3-HD-Charon", voices: voices, wantVoice: "de-DE-Chirp3-HD-Charon"},
+		{name: "no voice requested", voices: voices, wantVoice: defaultChirpVoiceName},
+		{name: "language chain", requested: "de-DE-Chirp3-HD-Aoede", voices: voices, wantVoice: "de-DE-Chirp3-HD-Kore", wantSubstitution: "fallback chain for de-DE"},
+		{name: "wildcard chain", requested: "fr-FR-Chirp3-HD-Aoede", voices: voices, wantVoice: "en-US-Chirp3-HD-Puck", wantSubstitution: "default fallback chain"},
+		{name: "first available", requested: "fr-FR-Chirp3-HD-Aoede", voices: voices[:2], wantVoice: "de-DE-Chirp3-HD-Charon", wantSubstitution: "first available voice"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			voice, substitution := selectChirpVoice(tc.requested, tc.voices, fallbacks)
+			if voice == nil || voice.Name != tc.wantVoice {
+				t.Fatalf("expected voice %s, but got %v", tc.wantVoice, voice)
+			}
+			if tc.wantSubstitution == "" && substitution != "" {
+				t.Errorf("expected no substitution, but got %q", substitution)
+			}
+			if !strings.Contains(substitution, tc.wantSubstitution) {
+				t.Errorf("expected substitution to mention %q, but got %q", tc.wantSubstitution, substitution)
+			}
+		})
+	}
+
+	if voice, _ := selectChirpVoice("en-US-Chirp3-HD-Puck", nil, fallbacks); voice != nil {
+		t.Errorf("expected no voice when none are available, but got %s", voice.Name)
+	}
+}
@@ -0,0 +1,87 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// aspectRatioTolerance is the relative difference within which a decimal aspect ratio
+// (e.g. "1.77") is matched to a W:H ratio (e.g. "16:9").
+const aspectRatioTolerance = 0.02
+
+// aspectRatioAliases maps descriptive names to W:H aspect ratios.
+var aspectRatioAliases = map[string]string{
+	"square":     "1:1",
+	"landscape":  "16:9",
+	"widescreen": "16:9",
+	"portrait":   "9:16",
+	"vertical":   "9:16",
+	"ultrawide":  "21:9",
+}
+
+// commonAspectRatios are the candidates for decimal aspect ratios when no model-specific list is given.
+var commonAspectRatios = []string{"1:1", "3:2", "2:3", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9"}
+
+// aspectRatioPattern matches "W:H", "WxH", "W/H", and "W by H", e.g. "16x9" or "1920 x 1080".
+var aspectRatioPattern = regexp.MustCompile(`^(\d+)\s*(?::|x|/|by)\s*(\d+)$`)
+
+// NormalizeAspectRatio converts an aspect ratio given in a common form to the canonical "W:H"
+// string used by the models: "16:9", "16x9", "16/9", and "16 by 9" all become "16:9", pixel
+// dimensions such as "1920x1080" are reduced to "16:9", and aliases such as "landscape" are
+// resolved. A decimal ratio such as "1.77" is matched to the closest of candidates (or of a
+// list of common ratios if candidates is empty). An empty input is returned unchanged, so
+// callers can apply their default. Whether the model supports the result is left to the caller.
+func NormalizeAspectRatio(ratio string, candidates []string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(ratio))
+	if normalized == "" {
+		return "", nil
+	}
+	if alias, ok := aspectRatioAliases[normalized]; ok {
+		return alias, nil
+	}
+
+	if m := aspectRatioPattern.FindStringSubmatch(normalized); m != nil {
+		w, _ := strconv.Atoi(m[1])
+		h, _ := strconv.Atoi(m[2])
+		if w == 0 || h == 0 {
+			return "", fmt.Errorf("invalid aspect ratio '%s'", ratio)
+		}
+		d := gcd(w, h)
+		return fmt.Sprintf("%d:%d", w/d, h/d), nil
+	}
+
+	value, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || value <= 0 {
+		return "", fmt.Errorf("invalid aspect ratio '%s'. Use the form W:H, e.g. '16:9'", ratio)
+	}
+	if len(candidates) == 0 {
+		candidates = commonAspectRatios
+	}
+	best, bestDiff := "", math.Inf(1)
+	for _, candidate := range candidates {
+		var w, h float64
+		if _, err := fmt.Sscanf(candidate, "%g:%g", &w, &h); err != nil || h == 0 {
+			continue
+		}
+		if diff := math.Abs(value-w/h) / (w / h); diff < bestDiff {
+			best, bestDiff = candidate, diff
+		}
+	}
+	if best == "" || bestDiff > aspectRatioTolerance {
+		return "", fmt.Errorf("aspect ratio '%s' does not match any of: %s", ratio, strings.Join(candidates, ", "))
+	}
+	return best, nil
+}
+
+// gcd returns the greatest common divisor of two positive integers.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
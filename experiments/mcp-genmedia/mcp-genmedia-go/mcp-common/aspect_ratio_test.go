@@ -0,0 +1,50 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAspectRatio(t *testing.T) {
+	veoRatios := []string{"16:9", "9:16"}
+	tests := []struct {
+		name        string
+		ratio       string
+		candidates  []string
+		want        string
+		errContains string
+	}{
+		{"empty", "", nil, "", ""},
+		{"canonical", "16:9", nil, "16:9", ""},
+		{"x separator", "16x9", nil, "16:9", ""},
+		{"slash separator", "16/9", nil, "16:9", ""},
+		{"spaces and case", " 9 X 16 ", nil, "9:16", ""},
+		{"by separator", "4 by 3", nil, "4:3", ""},
+		{"pixel dimensions", "1920x1080", nil, "16:9", ""},
+		{"alias", "Landscape", nil, "16:9", ""},
+		{"decimal", "1.77", nil, "16:9", ""},
+		{"decimal portrait", "0.5625", veoRatios, "9:16", ""},
+		{"decimal ultrawide", "2.33", nil, "21:9", ""},
+		{"decimal without match", "1.5", veoRatios, "", "does not match"},
+		{"zero", "0:9", nil, "", "invalid aspect ratio"},
+		{"garbage", "wide-ish", nil, "", "invalid aspect ratio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAspectRatio(tt.ratio, tt.candidates)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AudioTags are the metadata tags embedded in generated audio files for provenance. They are
+// written as ID3 tags in MP3 files and as the container's native tags in other formats.
+type AudioTags struct {
+	Title   string
+	Artist  string // The model or voice that generated the audio.
+	Comment string // The hash of the prompt, see PromptHash.
+}
+
+// taggableAudioExtensions lists the audio containers that can hold metadata tags. Raw encodings
+// such as PCM and mu-law have no container and are not tagged.
+var taggableAudioExtensions = []string{".mp3", ".wav", ".ogg", ".flac", ".m4a"}
+
+// GenerationAudioTags returns the tags of an audio file saved at path: its file name as the
+// title, the model as the artist, and the hash of the prompt as the comment.
+func GenerationAudioTags(path, model, prompt string) AudioTags {
+	return AudioTags{
+		Title:   strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Artist:  model,
+		Comment: PromptHash(prompt),
+	}
+}
+
+// PromptHash identifies a prompt without revealing it, as "sha256:" followed by the hex-encoded
+// SHA-256 of the prompt.
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// audioTagArgs returns the FFMpeg arguments that write the tags to outputPath. MP3 files get
+// ID3v2.3 tags, which are more widely supported than FFMpeg's default of ID3v2.4.
+func audioTagArgs(tags AudioTags, outputPath string) []string {
+	var args []string
+	for _, tag := range []struct{ key, value string }{{"title", tags.Title}, {"artist", tags.Artist}, {"comment", tags.Comment}} {
+		if tag.value != "" {
+			args = append(args, "-metadata", tag.key+"="+tag.value)
+		}
+	}
+	if strings.EqualFold(filepath.Ext(outputPath), ".mp3") {
+		args = append(args, "-id3v2_version", "3")
+	}
+	return args
+}
+
+// TagAudioFile embeds the tags in the audio file at path, rewriting it in place without
+// re-encoding. ffmpeg is looked up in MCP_CUSTOM_PATH if set, otherwise in PATH.
+func TagAudioFile(ctx context.Context, path string, tags AudioTags) error {
+	tempPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".tagged" + filepath.Ext(path)
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", path, "-map", "0", "-c", "copy"}
+	args = append(args, audioTagArgs(tags, path)...)
+	args = append(args, tempPath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("ffmpeg failed to tag %s: %w: %s", path, err, GetTail(string(output), 5))
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// TagGeneratedAudioFiles tags each saved audio file in a taggable container with
+// GenerationAudioTags if TTS_METADATA_TAGS is set. It returns a sentence for the tool result, or
+// "" if tagging is off or no file was tagged, e.g. because all are raw encodings. A file that
+// cannot be tagged is kept untagged and reported, without failing the call.
+func (c *Config) TagGeneratedAudioFiles(ctx context.Context, paths []string, model, prompt string) string {
+	if c == nil || !c.TTSMetadataTags || len(paths) == 0 {
+		return ""
+	}
+	tagged := 0
+	var failures []string
+	for _, path := range paths {
+		if !slices.Contains(taggableAudioExtensions, strings.ToLower(filepath.Ext(path))) {
+			LogInfof("Not tagging %s: %s files cannot hold metadata tags.", path, filepath.Ext(path))
+			continue
+		}
+		if err := TagAudioFile(ctx, path, GenerationAudioTags(path, model, prompt)); err != nil {
+			LogWarnf("Warning: %v", err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		tagged++
+	}
+	var messages []string
+	if tagged > 0 {
+		messages = append(messages, fmt.Sprintf("Tagged %d saved audio file(s) with the model and prompt hash %s.", tagged, PromptHash(prompt)))
+	}
+	if len(failures) > 0 {
+		messages = append(messages, fmt.Sprintf("Metadata tagging failed: %s.", strings.Join(failures, "; ")))
+	}
+	return strings.Join(messages, " ")
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerationAudioTags(t *testing.T) {
+	got := GenerationAudioTags("/tmp/out/chirp_tts-en-US-Chirp3-HD-Zephyr.mp3", "en-US-Chirp3-HD-Zephyr", "hello")
+	want := AudioTags{
+		Title:   "chirp_tts-en-US-Chirp3-HD-Zephyr",
+		Artist:  "en-US-Chirp3-HD-Zephyr",
+		Comment: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+	if got != want {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
+
+func TestAudioTagArgs(t *testing.T) {
+	tags := AudioTags{Title: "speech", Artist: "gemini-2.5-flash-preview-tts", Comment: "sha256:abc"}
+	got := audioTagArgs(tags, "speech.MP3")
+	want := []string{"-metadata", "title=speech", "-metadata", "artist=gemini-2.5-flash-preview-tts", "-metadata", "comment=sha256:abc", "-id3v2_version", "3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+	if got := audioTagArgs(AudioTags{Artist: "voice"}, "speech.wav"); !reflect.DeepEqual(got, []string{"-metadata", "artist=voice"}) {
+		t.Errorf("expected only the artist tag for a WAV file, but got %v", got)
+	}
+}
+
+func TestTagGeneratedAudioFiles(t *testing.T) {
+	writeAudio := func(dir, name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("audio"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	enabled := &Config{TTSMetadataTags: true}
+
+	tests := []struct {
+		name     string
+		cfg      *Config
+		files    []string
+		fail     bool
+		want     []string // Substrings of the message.
+		wantNone bool
+	}{
+		{name: "disabled", cfg: &Config{}, files: []string{"speech.mp3"}, wantNone: true},
+		{name: "nil config", files: []string{"speech.mp3"}, wantNone: true},
+		{name: "no files", cfg: enabled, wantNone: true},
+		{name: "only raw encodings", cfg: enabled, files: []string{"speech.pcm", "speech.ulaw"}, wantNone: true},
+		{name: "tagged", cfg: enabled, files: []string{"speech.mp3", "speech.pcm", "speech.wav"}, want: []string{"Tagged 2 saved audio file(s)"}},
+		{name: "failed", cfg: enabled, files: []string{"speech.mp3"}, fail: true, want: []string{"Metadata tagging failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeFFmpeg(t, tt.fail)
+			dir := t.TempDir()
+			var paths []string
+			for _, name := range tt.files {
+				paths = append(paths, writeAudio(dir, name))
+			}
+			got := tt.cfg.TagGeneratedAudioFiles(t.Context(), paths, "model", "prompt")
+			if tt.wantNone {
+				if got != "" {
+					t.Errorf("expected no message, but got %q", got)
+				}
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected the message to contain %q, but got %q", want, got)
+				}
+			}
+			if strings.Contains(got, "Tagged") == tt.fail {
+				t.Errorf("unexpected message %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// AudioFormat describes an audio format that TTS output can be transcoded to.
+type AudioFormat struct {
+	Name       string // Canonical name, e.g. "mp3".
+	Extension  string // File extension including the dot, e.g. ".mp3".
+	MIMEType   string
+	ffmpegArgs []string
+}
+
+// audioFormats lists the formats accepted by the 'additional_encodings' parameter of the TTS tools.
+var audioFormats = map[string]AudioFormat{
+	"wav":  {Name: "wav", Extension: ".wav", MIMEType: "audio/wav", ffmpegArgs: []string{"-c:a", "pcm_s16le", "-f", "wav"}},
+	"mp3":  {Name: "mp3", Extension: ".mp3", MIMEType: "audio/mpeg", ffmpegArgs: []string{"-c:a", "libmp3lame", "-q:a", "2", "-f", "mp3"}},
+	"ogg":  {Name: "ogg", Extension: ".ogg", MIMEType: "audio/ogg", ffmpegArgs: []string{"-c:a", "libopus", "-f", "ogg"}},
+	"flac": {Name: "flac", Extension: ".flac", MIMEType: "audio/flac", ffmpegArgs: []string{"-c:a", "flac", "-f", "flac"}},
+}
+
+// audioFormatAliases maps Cloud TTS encoding names and common spellings to audio format names.
+var audioFormatAliases = map[string]string{
+	"linear16": "wav",
+	"wave":     "wav",
+	"mpeg":     "mp3",
+	"ogg_opus": "ogg",
+	"opus":     "ogg",
+}
+
+// AudioFormatNames returns the sorted names of the supported transcoding formats.
+func AudioFormatNames() []string {
+	names := make([]string, 0, len(audioFormats))
+	for name := range audioFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupAudioFormat returns the audio format for a name or alias, case-insensitively.
+func LookupAudioFormat(name string) (AudioFormat, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := audioFormatAliases[name]; ok {
+		name = alias
+	}
+	format, ok := audioFormats[name]
+	return format, ok
+}
+
+// ParseAudioEncodings parses an 'additional_encodings' tool parameter (an array of format names)
+// into a list of audio formats, dropping duplicates. A nil parameter yields no formats.
+func ParseAudioEncodings(param interface{}) ([]AudioFormat, error) {
+	if param == nil {
+		return nil, nil
+	}
+	items, ok := param.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("additional_encodings must be an array of strings")
+	}
+	var formats []AudioFormat
+	var seen []string
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("additional_encodings must be an array of strings, got %v", item)
+		}
+		format, ok := LookupAudioFormat(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported encoding '%s' in additional_encodings. Supported encodings are: %s", name, strings.Join(AudioFormatNames(), ", "))
+		}
+		if slices.Contains(seen, format.Name) {
+			continue
+		}
+		seen = append(seen, format.Name)
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// TranscodeAudio converts audio in any format FFMpeg can read (such as WAV or MP3) to the given
+// format by piping it through ffmpeg. ffmpeg is looked up in MCP_CUSTOM_PATH if set, otherwise in PATH.
+func TranscodeAudio(ctx context.Context, audio []byte, format AudioFormat) ([]byte, error) {
+	return pipeAudioThroughFFmpeg(ctx, audio, format, nil)
+}
+
+// TranscodeAudioFile converts the audio file at inputPath to the given format, writing it to
+// outputPath. ffmpeg reads and writes the files itself, so the audio is never held in memory.
+func TranscodeAudioFile(ctx context.Context, inputPath, outputPath string, format AudioFormat) error {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", inputPath}, format.ffmpegArgs...)
+	args = append(args, outputPath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg failed to transcode to %s: %w: %s", format.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// WriteAdditionalEncodings transcodes TTS audio, in the primary format, to each of the requested
+// formats for an 'additional_encodings' parameter. If the primary audio was saved to primaryPath,
+// ffmpeg reads that file and each encoding is saved next to it, with the format's extension, so
+// long audio is not loaded into memory. Otherwise audio is transcoded in memory and each encoding
+// is returned as the content made by inline. A failed encoding is reported in the messages but
+// does not fail the call, since the primary audio was produced.
+func WriteAdditionalEncodings(ctx context.Context, primaryPath string, audio []byte, primary AudioFormat, formats []AudioFormat, inline func(encoded []byte, format AudioFormat) mcp.Content) (items []mcp.Content, messages []string, savedPaths []string) {
+	for _, format := range formats {
+		if format.Name == primary.Name {
+			continue // Already the primary output.
+		}
+		if primaryPath == "" {
+			encoded, err := TranscodeAudio(ctx, audio, format)
+			if err != nil {
+				LogWarnf("Warning: %v", err)
+				messages = append(messages, fmt.Sprintf("The %s encoding failed: %v.", format.Name, err))
+				continue
+			}
+			items = append(items, inline(encoded, format))
+			messages = append(messages, fmt.Sprintf("The %s encoding is included in the response.", format.Name))
+			continue
+		}
+		path := strings.TrimSuffix(primaryPath, filepath.Ext(primaryPath)) + format.Extension
+		if err := TranscodeAudioFile(ctx, primaryPath, path, format); err != nil {
+			LogWarnf("Warning: %v", err)
+			_ = os.Remove(path)
+			messages = append(messages, fmt.Sprintf("The %s encoding failed: %v.", format.Name, err))
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("Error writing the %s encoding to %s: %v.", format.Name, path, err))
+			continue
+		}
+		LogInfof("Audio content (%d bytes) written to file: %s", info.Size(), path)
+		messages = append(messages, fmt.Sprintf("The %s encoding was saved to: %s (%d bytes).", format.Name, path, info.Size()))
+		savedPaths = append(savedPaths, path)
+	}
+	return items, messages, savedPaths
+}
+
+// pipeAudioThroughFFmpeg pipes audio through ffmpeg, applying the extra arguments (such as an
+// audio filter) before encoding to the given format.
+func pipeAudioThroughFFmpeg(ctx context.Context, audio []byte, format AudioFormat, extraArgs []string) ([]byte, error) {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-i", "pipe:0"}, extraArgs...)
+	args = append(args, format.ffmpegArgs...)
+	args = append(args, "pipe:1")
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	cmd.Stdin = bytes.NewReader(audio)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed to transcode to %s: %w: %s", format.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no %s output", format.Name)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseAudioEncodings(t *testing.T) {
+	tests := []struct {
+		name        string
+		param       interface{}
+		want        []string
+		errContains string
+	}{
+		{"nil", nil, nil, ""},
+		{"empty", []interface{}{}, nil, ""},
+		{"wav and mp3", []interface{}{"wav", "mp3"}, []string{"wav", "mp3"}, ""},
+		{"aliases and case", []interface{}{"LINEAR16", "OGG_OPUS", " Flac "}, []string{"wav", "ogg", "flac"}, ""},
+		{"duplicates dropped", []interface{}{"mp3", "MP3", "mpeg"}, []string{"mp3"}, ""},
+		{"unsupported", []interface{}{"aiff"}, nil, "unsupported encoding 'aiff'"},
+		{"not a string", []interface{}{42.0}, nil, "array of strings"},
+		{"not an array", "mp3", nil, "array of strings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formats, err := ParseAudioEncodings(tt.param)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			var got []string
+			for _, f := range formats {
+				got = append(got, f.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected formats %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLookupAudioFormat(t *testing.T) {
+	format, ok := LookupAudioFormat("mp3")
+	if !ok {
+		t.Fatalf("expected mp3 to be supported")
+	}
+	if format.Extension != ".mp3" || format.MIMEType != "audio/mpeg" {
+		t.Errorf("expected .mp3 and audio/mpeg, but got %s and %s", format.Extension, format.MIMEType)
+	}
+	if _, ok := LookupAudioFormat("mulaw"); ok {
+		t.Errorf("expected mulaw to be unsupported")
+	}
+}
+
+// fakeFFmpeg puts an ffmpeg on PATH that copies its input ("-i" argument, or stdin) to its
+// output (the last argument, or stdout), or fails if fail is set.
+func fakeFFmpeg(t *testing.T, fail bool) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+in=""; prev=""
+for arg in "$@"; do
+	if [ "$prev" = "-i" ]; then in="$arg"; fi
+	prev="$arg"; out="$arg"
+done
+if [ "$out" = "pipe:1" ]; then /bin/cat; else /bin/cp "$in" "$out"; fi
+`
+	if fail {
+		script = "#!/bin/sh\necho 'unknown encoder' >&2\nexit 1\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write the fake ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("MCP_CUSTOM_PATH", "")
+}
+
+func TestWriteAdditionalEncodingsToFiles(t *testing.T) {
+	fakeFFmpeg(t, false)
+	primaryPath := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(primaryPath, []byte("RIFF audio"), 0o644); err != nil {
+		t.Fatalf("failed to write the primary audio: %v", err)
+	}
+	wav, _ := LookupAudioFormat("wav")
+	mp3, _ := LookupAudioFormat("mp3")
+	inline := func([]byte, AudioFormat) mcp.Content {
+		t.Error("expected no inline content when the primary audio is saved")
+		return nil
+	}
+
+	// The audio is read from the primary file, not from memory.
+	items, messages, savedPaths := WriteAdditionalEncodings(t.Context(), primaryPath, nil, wav, []AudioFormat{wav, mp3}, inline)
+	wantPath := strings.TrimSuffix(primaryPath, ".wav") + ".mp3"
+	if len(items) != 0 || len(savedPaths) != 1 || savedPaths[0] != wantPath {
+		t.Fatalf("expected only %s to be saved, but got items %v and paths %v (messages: %v)", wantPath, items, savedPaths, messages)
+	}
+	if data, _ := os.ReadFile(wantPath); string(data) != "RIFF audio" {
+		t.Errorf("expected the encoding to be transcoded from the primary file, but got %q", data)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "The mp3 encoding was saved to: "+wantPath+" (10 bytes).") {
+		t.Errorf("unexpected messages: %v", messages)
+	}
+}
+
+func TestWriteAdditionalEncodingsInline(t *testing.T) {
+	fakeFFmpeg(t, false)
+	wav, _ := LookupAudioFormat("wav")
+	ogg, _ := LookupAudioFormat("ogg")
+	var inlined []string
+	inline := func(encoded []byte, format AudioFormat) mcp.Content {
+		inlined = append(inlined, format.Name+":"+string(encoded))
+		return mcp.TextContent{Type: "text", Text: format.Name}
+	}
+	items, messages, savedPaths := WriteAdditionalEncodings(t.Context(), "", []byte("RIFF"), wav, []AudioFormat{ogg}, inline)
+	if len(items) != 1 || len(savedPaths) != 0 || strings.Join(inlined, ",") != "ogg:RIFF" {
+		t.Errorf("expected the ogg encoding inline, but got items %v, paths %v, inlined %v", items, savedPaths, inlined)
+	}
+	if len(messages) != 1 || messages[0] != "The ogg encoding is included in the response." {
+		t.Errorf("unexpected messages: %v", messages)
+	}
+}
+
+func TestWriteAdditionalEncodingsFailure(t *testing.T) {
+	fakeFFmpeg(t, true)
+	primaryPath := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(primaryPath, []byte("RIFF"), 0o644); err != nil {
+		t.Fatalf("failed to write the primary audio: %v", err)
+	}
+	wav, _ := LookupAudioFormat("wav")
+	flac, _ := LookupAudioFormat("flac")
+	_, messages, savedPaths := WriteAdditionalEncodings(t.Context(), primaryPath, nil, wav, []AudioFormat{flac}, nil)
+	if len(savedPaths) != 0 || len(messages) != 1 || !strings.Contains(messages[0], "The flac encoding failed") || !strings.Contains(messages[0], "unknown encoder") {
+		t.Errorf("expected the failure to be reported, but got paths %v and messages %v", savedPaths, messages)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(primaryPath, ".wav") + ".flac"); err == nil {
+		t.Error("expected no file to be left for a failed encoding")
+	}
+}
@@ -0,0 +1,136 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teris-io/shortid"
+)
+
+// auditRedactedParameters lists tool parameters that carry user prompt text and are
+// dropped from the audit record when prompt redaction is enabled.
+var auditRedactedParameters = []string{"prompt", "negative_prompt", "text", "system_instruction"}
+
+// auditFileMu serializes appends to a local audit log file.
+var auditFileMu sync.Mutex
+
+// AuditRecord is a single entry in the generation audit log.
+type AuditRecord struct {
+	Timestamp      time.Time              `json:"timestamp"`
+	Service        string                 `json:"service"`
+	Tool           string                 `json:"tool"`
+	Prompt         string                 `json:"prompt,omitempty"`
+	PromptSHA256   string                 `json:"prompt_sha256,omitempty"`
+	PromptRedacted bool                   `json:"prompt_redacted,omitempty"`
+	Model          string                 `json:"model,omitempty"`
+	Parameters     map[string]interface{} `json:"parameters,omitempty"`
+	OutputURIs     []string               `json:"output_uris,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+}
+
+// WriteAuditRecord appends a record to the audit sink configured by AUDIT_LOG_SINK.
+// The sink is either a local JSONL file path or a gs:// prefix, under which one JSON
+// object is written per record. Auditing is fail-open: errors are logged and never
+// returned, so a broken sink does not block generation.
+func WriteAuditRecord(ctx context.Context, cfg *Config, record AuditRecord) {
+	if cfg == nil || cfg.AuditLogSink == "" {
+		return
+	}
+
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+	if record.Prompt != "" {
+		sum := sha256.Sum256([]byte(record.Prompt))
+		record.PromptSHA256 = hex.EncodeToString(sum[:])
+	}
+	if cfg.AuditRedactPrompts {
+		record.Prompt = ""
+		record.PromptRedacted = true
+		record.Parameters = redactAuditParameters(record.Parameters)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		LogWarnf("Warning: failed to marshal audit record for tool %s: %v", record.Tool, err)
+		return
+	}
+
+	if strings.HasPrefix(cfg.AuditLogSink, "gs://") {
+		err = writeAuditRecordToGCS(ctx, cfg.AuditLogSink, record, line)
+	} else {
+		err = appendAuditRecordToFile(cfg.AuditLogSink, line)
+	}
+	if err != nil {
+		LogWarnf("Warning: failed to write audit record for tool %s to %s: %v", record.Tool, cfg.AuditLogSink, err)
+	}
+}
+
+// redactAuditParameters returns a copy of params without the prompt-bearing parameters.
+func redactAuditParameters(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		redacted[k] = v
+	}
+	for _, k := range auditRedactedParameters {
+		delete(redacted, k)
+	}
+	return redacted
+}
+
+// appendAuditRecordToFile appends a single JSON line to a local audit log file.
+func appendAuditRecordToFile(filePath string, line []byte) error {
+	auditFileMu.Lock()
+	defer auditFileMu.Unlock()
+
+	if dir := filepath.Dir(filePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+	}
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+	return f.Close()
+}
+
+// writeAuditRecordToGCS writes a record as its own object, since GCS objects cannot be appended to.
+// Objects are laid out as <prefix>/<service>/<yyyy>/<mm>/<dd>/<timestamp>-<id>.json.
+func writeAuditRecordToGCS(ctx context.Context, sink string, record AuditRecord, line []byte) error {
+	bucket, prefix, err := ParseGCSPath(sink)
+	if err != nil {
+		bucket = strings.TrimSuffix(strings.TrimPrefix(sink, "gs://"), "/")
+		prefix = ""
+	}
+	uid, _ := shortid.Generate()
+	objectName := path.Join(
+		prefix,
+		record.Service,
+		record.Timestamp.Format("2006/01/02"),
+		fmt.Sprintf("%s-%s.json", record.Timestamp.Format("20060102T150405.000Z"), uid),
+	)
+
+	// Use a detached context so a canceled tool request still gets audited.
+	uploadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	return UploadToGCS(uploadCtx, bucket, objectName, "application/json", line)
+}
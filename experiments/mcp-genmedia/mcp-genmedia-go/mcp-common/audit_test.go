@@ -0,0 +1,95 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("failed to parse audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestWriteAuditRecord(t *testing.T) {
+	t.Run("appends one record per call to a local file", func(t *testing.T) {
+		sink := filepath.Join(t.TempDir(), "audit", "genmedia.jsonl")
+		cfg := &Config{AuditLogSink: sink}
+
+		for i := 0; i < 2; i++ {
+			WriteAuditRecord(context.Background(), cfg, AuditRecord{
+				Service:    "mcp-test-go",
+				Tool:       "test_tool",
+				Prompt:     "a cat",
+				Model:      "test-model",
+				Parameters: map[string]interface{}{"prompt": "a cat", "num_images": 1.0},
+				OutputURIs: []string{"gs://bucket/out.png"},
+			})
+		}
+
+		records := readAuditRecords(t, sink)
+		if len(records) != 2 {
+			t.Fatalf("expected 2 records, but got %d", len(records))
+		}
+		r := records[0]
+		if r.Prompt != "a cat" || r.PromptSHA256 == "" || r.PromptRedacted {
+			t.Errorf("expected unredacted prompt with hash, but got %+v", r)
+		}
+		if r.Timestamp.IsZero() {
+			t.Errorf("expected timestamp to be set")
+		}
+		if len(r.OutputURIs) != 1 || r.OutputURIs[0] != "gs://bucket/out.png" {
+			t.Errorf("expected output URIs to be recorded, but got %v", r.OutputURIs)
+		}
+	})
+
+	t.Run("redacts prompts when configured", func(t *testing.T) {
+		sink := filepath.Join(t.TempDir(), "audit.jsonl")
+		cfg := &Config{AuditLogSink: sink, AuditRedactPrompts: true}
+		params := map[string]interface{}{"prompt": "secret", "aspect_ratio": "16:9"}
+
+		WriteAuditRecord(context.Background(), cfg, AuditRecord{Tool: "test_tool", Prompt: "secret", Parameters: params})
+
+		records := readAuditRecords(t, sink)
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, but got %d", len(records))
+		}
+		r := records[0]
+		if r.Prompt != "" || !r.PromptRedacted || r.PromptSHA256 == "" {
+			t.Errorf("expected redacted prompt with hash, but got %+v", r)
+		}
+		if _, ok := r.Parameters["prompt"]; ok {
+			t.Errorf("expected prompt parameter to be removed, but got %v", r.Parameters)
+		}
+		if r.Parameters["aspect_ratio"] != "16:9" {
+			t.Errorf("expected other parameters to be kept, but got %v", r.Parameters)
+		}
+		if params["prompt"] != "secret" {
+			t.Errorf("expected caller's parameters to be left untouched")
+		}
+	})
+
+	t.Run("fails open on an unwritable sink", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := &Config{AuditLogSink: dir} // a directory cannot be opened for append
+		WriteAuditRecord(context.Background(), cfg, AuditRecord{Tool: "test_tool"})
+	})
+}
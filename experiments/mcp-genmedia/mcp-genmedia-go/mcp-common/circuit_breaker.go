@@ -0,0 +1,148 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultCircuitBreakerThreshold is the number of consecutive upstream failures that open the breaker.
+	DefaultCircuitBreakerThreshold = 5
+	// DefaultCircuitBreakerCooldown is how long an open breaker fails fast before probing recovery.
+	DefaultCircuitBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned, wrapped, by CircuitBreaker.Execute while the breaker is open.
+var ErrCircuitOpen = errors.New("service temporarily unavailable")
+
+// GenAIBreaker guards the generation calls to the GenAI API. It is created by Init from the
+// CIRCUIT_BREAKER_* settings and is nil (disabled) until then.
+var GenAIBreaker *CircuitBreaker
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops calling an upstream service that keeps failing. After threshold
+// consecutive failures it opens and fails fast with ErrCircuitOpen for the cooldown. It then
+// half-opens and lets a single call through as a probe: if the probe succeeds the breaker
+// closes, otherwise it opens for another cooldown. A nil *CircuitBreaker is disabled and
+// simply calls through.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a breaker for the named service, or nil (disabled) if threshold is
+// not positive.
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Execute calls fn unless the breaker is open, and records whether the call was an upstream
+// failure as reported by IsUpstreamFailure. Errors that are not upstream failures, such as
+// invalid requests, count as the service responding.
+func (b *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn(ctx)
+	b.record(ctx, err)
+	return err
+}
+
+// allow reports whether a call may proceed, moving an open breaker to half-open once the
+// cooldown has passed.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("%w: %s failed %d times in a row; retry in %v", ErrCircuitOpen, b.name, b.failures, remaining.Round(time.Second))
+		}
+		LogInfof("Circuit breaker for %s is half-open; probing with the next request.", b.name)
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		return fmt.Errorf("%w: %s is recovering from repeated failures; retry shortly", ErrCircuitOpen, b.name)
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil && ctx.Err() == context.Canceled {
+		// The caller gave up; this says nothing about the service. Let another call probe.
+		if b.state == circuitHalfOpen {
+			b.state = circuitOpen
+		}
+		return
+	}
+	if !IsUpstreamFailure(err) {
+		if b.state != circuitClosed {
+			LogInfof("Circuit breaker for %s closed; the service has recovered.", b.name)
+		}
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state != circuitOpen {
+			LogWarnf("Circuit breaker for %s opened after %d consecutive failure(s) (last: %v); failing fast for %v.", b.name, b.failures, err, b.cooldown)
+		}
+		b.state = circuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+// IsUpstreamFailure reports whether err indicates that the service is unavailable: a 5xx
+// response, a timeout, or a network error. Client errors such as 400 or 404, and 429 quota
+// errors, mean the service is up and do not count.
+func IsUpstreamFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= http.StatusInternalServerError
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return apiErrPtr.Code >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
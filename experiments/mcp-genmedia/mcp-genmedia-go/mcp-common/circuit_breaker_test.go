@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker("test", 2, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	calls := 0
+	outage := genai.APIError{Code: 503, Message: "unavailable"}
+	call := func(err error) error {
+		return b.Execute(context.Background(), func(ctx context.Context) error {
+			calls++
+			return err
+		})
+	}
+
+	// Client errors mean the service is up and do not count towards opening the breaker.
+	call(genai.APIError{Code: 400})
+	call(outage)
+	call(genai.APIError{Code: 404})
+	call(outage)
+	if err := call(nil); err != nil || calls != 5 {
+		t.Fatalf("expected the breaker to stay closed, but got %v after %d calls", err, calls)
+	}
+
+	call(outage)
+	call(fmt.Errorf("wrapped: %w", context.DeadlineExceeded))
+	if err := call(nil); !errors.Is(err, ErrCircuitOpen) || calls != 7 {
+		t.Fatalf("expected the open breaker to fail fast, but got %v after %d calls", err, calls)
+	}
+
+	// After the cooldown a single probe is let through; a failed probe reopens the breaker.
+	now = now.Add(31 * time.Second)
+	call(&outage)
+	if err := call(nil); !errors.Is(err, ErrCircuitOpen) || calls != 8 {
+		t.Fatalf("expected a failed probe to reopen the breaker, but got %v after %d calls", err, calls)
+	}
+
+	now = now.Add(31 * time.Second)
+	if err := call(nil); err != nil {
+		t.Fatalf("expected a successful probe, but got %v", err)
+	}
+	if err := call(nil); err != nil || calls != 10 {
+		t.Fatalf("expected the breaker to close after a successful probe, but got %v after %d calls", err, calls)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker("test", 0, time.Minute)
+	if b != nil {
+		t.Fatalf("expected a threshold of 0 to disable the breaker, but got %v", b)
+	}
+	for i := 0; i < 10; i++ {
+		if err := b.Execute(context.Background(), func(ctx context.Context) error { return genai.APIError{Code: 500} }); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected a disabled breaker to call through, but got %v", err)
+		}
+	}
+}
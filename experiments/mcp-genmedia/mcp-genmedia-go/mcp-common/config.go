@@ -3,8 +3,10 @@
 package common
 
 import (
-	"log"
+	"log/slog"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,19 +16,66 @@ import (
 type Config struct {
 	ProjectID                   string
 	Location                    string
+	LocationEnvVar              string // The environment variable Location was read from.
 	GenmediaBucket              string
 	ApiEndpoint                 string // New field
 	AllowUnsafeModels           bool
 	EnableOptionalHeaderCapture bool
+	AuditLogSink                string
+	AuditRedactPrompts          bool
+	MaxInlineBytes              int64 // Inline outputs larger than this are spilled to GCS; 0 disables spilling.
+	PromptPrefix                string
+	PromptSuffix                string
+	ResponseCacheSize           int           // Maximum number of cached generation responses; 0 disables the cache.
+	ResponseCacheTTL            time.Duration // How long cached generation responses are kept.
+	LogLevel                    slog.Level
+	LogFormat                   string        // LogFormatText or LogFormatJSON.
+	EnabledTools                []string      // If non-empty, only these tools are registered.
+	DisabledTools               []string      // These tools are never registered.
+	GenAIBackend                string        // GenAIBackendVertex or GenAIBackendGemini.
+	GeminiAPIKey                string        // API key for the Gemini API backend.
+	OutputRetention             time.Duration // Files older than this are removed from OutputRetentionDirs; 0 disables cleanup.
+	OutputRetentionDirs         []string
+	OutputRetentionInterval     time.Duration // How often the output janitor runs.
+	OutputRetentionDryRun       bool          // If true, the janitor only logs the files it would remove.
+	CircuitBreakerThreshold     int           // Consecutive upstream failures that open the GenAI circuit breaker; 0 disables it.
+	CircuitBreakerCooldown      time.Duration // How long the open circuit breaker fails fast before probing recovery.
+	TTSDefaultEncoding          string        // Output encoding of TTS requests that do not set one; empty means DefaultTTSEncoding.
+	MaxInputImages              int           // Maximum number of input images per generation request; 0 disables the check.
+	MaxInputImageBytes          int64         // Maximum total size of the input images sent inline; 0 disables the check.
+	MaxInputTextBytes           int64         // Maximum size of the text read from a text_uri; 0 disables the check.
+	InputTextLocalDir           string        // Directory local text_uri paths must be in; empty allows any readable file.
+	ModelDefinitionsFile        string        // JSON file of model definitions merged over the built-in models.
+	LogToolCalls                bool          // If true, every tool call logs a summary line with its duration and outcome.
+	ImagenImageSizePreference   string        // ImageSizePreferenceSmallest or ImageSizePreferenceLargest; empty means DefaultImageSizePreference.
+	OverrideBuckets             []string      // Buckets a request may use instead of GenmediaBucket.
+	OverrideLocations           []string      // Locations a request may use instead of Location.
+	MP4Faststart                bool          // If true, avtool writes MP4 outputs with their index at the front for web playback.
+	TTSMetadataTags             bool          // If true, saved TTS audio files are tagged with the model and a hash of the prompt.
+	StorageBackend              string        // StorageBackendGCS or StorageBackendLocal.
+	StorageLocalRoot            string        // Directory of the local storage backend.
+	VeoOutputPathTemplate       string        // Path under GenmediaBucket of the Veo outputs of requests without a bucket; see ExpandOutputPathTemplate.
 }
 
 func LoadConfig(serviceName string) *Config {
 	// Load .env file
 	err := godotenv.Load()
 	if err != nil {
-		log.Println("Error loading .env file, using environment variables only")
+		LogInfof("Error loading .env file, using environment variables only")
 	}
 
+	// Logging is configured first so that the rest of the configuration is logged in the requested format.
+	logLevel, err := ParseLogLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		LogWarnf("Invalid LOG_LEVEL value %q, using info", os.Getenv("LOG_LEVEL"))
+	}
+	logFormat := strings.ToLower(strings.TrimSpace(GetEnv("LOG_FORMAT", LogFormatText)))
+	if logFormat != LogFormatText && logFormat != LogFormatJSON {
+		LogWarnf("Invalid LOG_FORMAT value %q, using %s", logFormat, LogFormatText)
+		logFormat = LogFormatText
+	}
+	ConfigureLogging(logLevel, logFormat)
+
 	var projectID string
 
 	// Attempt to load server-specific override first
@@ -36,7 +85,7 @@ func LoadConfig(serviceName string) *Config {
 		overrideKey := prefix + "_PROJECT_ID"
 		projectID = os.Getenv(overrideKey)
 		if projectID != "" {
-			log.Printf("Using server-specific project override %s: %s", overrideKey, projectID)
+			LogInfof("Using server-specific project override %s: %s", overrideKey, projectID)
 		}
 	}
 
@@ -49,61 +98,339 @@ func LoadConfig(serviceName string) *Config {
 	if projectID == "" {
 		projectID = os.Getenv("PROJECT_ID")
 		if projectID != "" {
-			log.Printf("GOOGLE_CLOUD_PROJECT not set, using PROJECT_ID fallback: %s", projectID)
+			LogInfof("GOOGLE_CLOUD_PROJECT not set, using PROJECT_ID fallback: %s", projectID)
 		}
 	}
 
-	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT (or PROJECT_ID) environment variable not set. Please set the env variable, e.g. export GOOGLE_CLOUD_PROJECT=$(gcloud config get project)")
+	genAIBackend, err := ParseGenAIBackend(os.Getenv("GENAI_BACKEND"))
+	if err != nil {
+		LogFatalf("%v", err)
+	}
+	geminiAPIKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
+	if geminiAPIKey == "" {
+		geminiAPIKey = strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
+	}
+	if genAIBackend == GenAIBackendGemini {
+		if geminiAPIKey == "" {
+			LogFatalf("GENAI_BACKEND is set to gemini, but neither GEMINI_API_KEY nor GOOGLE_API_KEY is set.")
+		}
+		LogInfof("Using the Gemini API backend for GenAI clients.")
 	}
-	log.Printf("Project ID set to: %s", projectID)
 
-	var location string
+	if projectID == "" && genAIBackend == GenAIBackendGemini {
+		LogWarnf("GOOGLE_CLOUD_PROJECT is not set. Features that call other Google Cloud APIs, such as GCS, are unavailable.")
+	} else if projectID == "" {
+		LogFatalf("GOOGLE_CLOUD_PROJECT (or PROJECT_ID) environment variable not set. Please set the env variable, e.g. export GOOGLE_CLOUD_PROJECT=$(gcloud config get project)")
+	}
+	if projectID != "" {
+		LogInfof("Project ID set to: %s", projectID)
+	}
+
+	var location, locationEnvVar string
 	if serviceName != "" {
 		prefix := strings.ToUpper(strings.TrimSuffix(strings.TrimPrefix(serviceName, "mcp-"), "-go"))
 		overrideKey := prefix + "_LOCATION"
 		location = os.Getenv(overrideKey)
 		if location != "" {
-			log.Printf("Using server-specific location override %s: %s", overrideKey, location)
+			locationEnvVar = overrideKey
+			LogInfof("Using server-specific location override %s: %s", overrideKey, location)
 		}
 	}
 
 	if location == "" {
 		location = os.Getenv("GOOGLE_CLOUD_LOCATION")
+		locationEnvVar = "GOOGLE_CLOUD_LOCATION"
 	}
 
 	if location == "" {
 		location = GetEnv("LOCATION", "us-central1")
+		locationEnvVar = "LOCATION"
 	}
 
 	genmediaBucket := GetEnv("GENMEDIA_BUCKET", "")
 	if genmediaBucket != "" {
-		log.Printf("GENMEDIA_BUCKET set to: %s", genmediaBucket)
+		LogInfof("GENMEDIA_BUCKET set to: %s", genmediaBucket)
 		genmediaBucket = strings.TrimPrefix(genmediaBucket, "gs://")
 	} else {
-		log.Println("GENMEDIA_BUCKET is not set.")
+		LogInfof("GENMEDIA_BUCKET is not set.")
 	}
 
 	allowUnsafe := false
 	if strings.ToLower(os.Getenv("ALLOW_UNSAFE_MODELS")) == "true" {
 		allowUnsafe = true
-		log.Printf("Warning: ALLOW_UNSAFE_MODELS is enabled. Strict model validation will be bypassed.")
+		LogWarnf("Warning: ALLOW_UNSAFE_MODELS is enabled. Strict model validation will be bypassed.")
 	}
 
 	enableCapture := false
 	if strings.ToLower(os.Getenv("ENABLE_OPTIONAL_HEADER_CAPTURE")) == "true" {
 		enableCapture = true
-		log.Printf("Optional header capture is enabled.")
+		LogInfof("Optional header capture is enabled.")
+	}
+
+	auditLogSink := strings.TrimSpace(os.Getenv("AUDIT_LOG_SINK"))
+	auditRedactPrompts := strings.ToLower(os.Getenv("AUDIT_REDACT_PROMPTS")) == "true"
+	if auditLogSink != "" {
+		LogInfof("Audit logging enabled. Sink: %s (prompt redaction: %t)", auditLogSink, auditRedactPrompts)
+	}
+
+	var maxInlineBytes int64
+	if v := strings.TrimSpace(os.Getenv("MCP_MAX_INLINE_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			maxInlineBytes = n
+			LogInfof("Inline outputs larger than %s will be spilled to GCS.", FormatBytes(n))
+		} else {
+			LogWarnf("Invalid MCP_MAX_INLINE_BYTES value %q, inline output spilling disabled", v)
+		}
+	}
+
+	promptPrefix := strings.TrimSpace(os.Getenv("PROMPT_PREFIX"))
+	promptSuffix := strings.TrimSpace(os.Getenv("PROMPT_SUFFIX"))
+	if promptPrefix != "" || promptSuffix != "" {
+		LogInfof("Prompt affixes enabled. Prefix: %q, Suffix: %q", promptPrefix, promptSuffix)
+	}
+
+	var responseCacheSize int
+	responseCacheTTL := DefaultResponseCacheTTL
+	if v := strings.TrimSpace(os.Getenv("GENERATION_CACHE_SIZE")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			responseCacheSize = n
+		} else {
+			LogWarnf("Invalid GENERATION_CACHE_SIZE value %q, response cache disabled", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("GENERATION_CACHE_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			responseCacheTTL = d
+		} else {
+			LogWarnf("Invalid GENERATION_CACHE_TTL value %q, using default of %v", v, DefaultResponseCacheTTL)
+		}
+	}
+	if responseCacheSize > 0 {
+		LogInfof("Response cache enabled for seeded requests: up to %d entries for %v each.", responseCacheSize, responseCacheTTL)
+	}
+
+	enabledTools := parseToolList(os.Getenv("MCP_ENABLED_TOOLS"))
+	disabledTools := parseToolList(os.Getenv("MCP_DISABLED_TOOLS"))
+	if len(enabledTools) > 0 {
+		LogInfof("Only the following tools will be registered: %s", strings.Join(enabledTools, ", "))
+	}
+	if len(disabledTools) > 0 {
+		LogInfof("The following tools will not be registered: %s", strings.Join(disabledTools, ", "))
+	}
+
+	var outputRetention time.Duration
+	var outputRetentionDirs []string
+	outputRetentionInterval := DefaultOutputRetentionInterval
+	outputRetentionDryRun := strings.ToLower(os.Getenv("OUTPUT_RETENTION_DRY_RUN")) == "true"
+	if v := strings.TrimSpace(os.Getenv("OUTPUT_RETENTION")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			outputRetention = d
+		} else {
+			LogWarnf("Invalid OUTPUT_RETENTION value %q, output cleanup disabled", v)
+		}
+	}
+	for _, dir := range strings.Split(os.Getenv("OUTPUT_RETENTION_DIRS"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			outputRetentionDirs = append(outputRetentionDirs, dir)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("OUTPUT_RETENTION_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			outputRetentionInterval = d
+		} else {
+			LogWarnf("Invalid OUTPUT_RETENTION_INTERVAL value %q, using default of %v", v, DefaultOutputRetentionInterval)
+		}
+	}
+	if outputRetention > 0 && len(outputRetentionDirs) == 0 {
+		LogWarnf("OUTPUT_RETENTION is set but OUTPUT_RETENTION_DIRS is empty, output cleanup disabled")
+	}
+
+	circuitBreakerThreshold := DefaultCircuitBreakerThreshold
+	circuitBreakerCooldown := DefaultCircuitBreakerCooldown
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER_THRESHOLD")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			circuitBreakerThreshold = n
+		} else {
+			LogWarnf("Invalid CIRCUIT_BREAKER_THRESHOLD value %q, using default of %d", v, DefaultCircuitBreakerThreshold)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER_COOLDOWN")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			circuitBreakerCooldown = d
+		} else {
+			LogWarnf("Invalid CIRCUIT_BREAKER_COOLDOWN value %q, using default of %v", v, DefaultCircuitBreakerCooldown)
+		}
 	}
 
-	return &Config{
+	var ttsDefaultEncoding string
+	if v := strings.TrimSpace(os.Getenv("TTS_DEFAULT_ENCODING")); v != "" {
+		if encoding, err := ParseTTSEncoding(v, TTSEncodings); err == nil {
+			ttsDefaultEncoding = encoding
+			LogInfof("TTS requests without an audio encoding will use %s.", encoding)
+		} else {
+			LogWarnf("Invalid TTS_DEFAULT_ENCODING value %q, using %s: %v", v, DefaultTTSEncoding, err)
+		}
+	}
+
+	maxInputImages := DefaultMaxInputImages
+	if v := strings.TrimSpace(os.Getenv("MAX_INPUT_IMAGES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxInputImages = n
+		} else {
+			LogWarnf("Invalid MAX_INPUT_IMAGES value %q, using default of %d", v, DefaultMaxInputImages)
+		}
+	}
+	var maxInputImageBytes int64 = DefaultMaxInputImageBytes
+	if v := strings.TrimSpace(os.Getenv("MAX_INPUT_IMAGE_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			maxInputImageBytes = n
+		} else {
+			LogWarnf("Invalid MAX_INPUT_IMAGE_BYTES value %q, using default of %s", v, FormatBytes(DefaultMaxInputImageBytes))
+		}
+	}
+	var maxInputTextBytes int64 = DefaultMaxInputTextBytes
+	if v := strings.TrimSpace(os.Getenv("MAX_INPUT_TEXT_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			maxInputTextBytes = n
+		} else {
+			LogWarnf("Invalid MAX_INPUT_TEXT_BYTES value %q, using default of %s", v, FormatBytes(DefaultMaxInputTextBytes))
+		}
+	}
+	inputTextLocalDir := strings.TrimSpace(os.Getenv("INPUT_TEXT_LOCAL_DIR"))
+
+	modelDefinitionsFile := strings.TrimSpace(os.Getenv("MODEL_DEFINITIONS_FILE"))
+	if modelDefinitionsFile != "" {
+		if err := LoadModelDefinitions(modelDefinitionsFile); err != nil {
+			LogWarnf("Invalid MODEL_DEFINITIONS_FILE %q, using the built-in models only: %v", modelDefinitionsFile, err)
+		} else {
+			LogInfof("Loaded model definitions from %s.", modelDefinitionsFile)
+		}
+	}
+
+	logToolCalls := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_TOOL_CALLS"))) != "false"
+
+	mp4Faststart := strings.ToLower(strings.TrimSpace(os.Getenv("MP4_FASTSTART"))) != "false"
+	ttsMetadataTags := strings.ToLower(strings.TrimSpace(os.Getenv("TTS_METADATA_TAGS"))) == "true"
+	if !mp4Faststart {
+		LogInfof("MP4_FASTSTART is false: MP4 outputs are written without faststart.")
+	}
+
+	storageBackend := strings.ToLower(strings.TrimSpace(GetEnv("STORAGE_BACKEND", StorageBackendGCS)))
+	if storageBackend != StorageBackendGCS && storageBackend != StorageBackendLocal {
+		LogWarnf("Invalid STORAGE_BACKEND value %q, using %s", storageBackend, StorageBackendGCS)
+		storageBackend = StorageBackendGCS
+	}
+	storageLocalRoot := strings.TrimSpace(os.Getenv("STORAGE_LOCAL_ROOT"))
+
+	veoOutputPathTemplate := strings.TrimSpace(GetEnv("VEO_OUTPUT_PATH_TEMPLATE", DefaultVeoOutputPathTemplate))
+	if err := ValidateOutputPathTemplate(veoOutputPathTemplate); err != nil {
+		LogWarnf("Invalid VEO_OUTPUT_PATH_TEMPLATE value %q, using %s: %v", veoOutputPathTemplate, DefaultVeoOutputPathTemplate, err)
+		veoOutputPathTemplate = DefaultVeoOutputPathTemplate
+	}
+
+	var imagenImageSizePreference string
+	if v := strings.TrimSpace(os.Getenv("IMAGEN_IMAGE_SIZE_PREFERENCE")); v != "" {
+		if preference, err := ParseImageSizePreference(v); err == nil {
+			imagenImageSizePreference = preference
+			LogInfof("Imagen requests without an image size will use the %s supported size.", preference)
+		} else {
+			LogWarnf("Invalid IMAGEN_IMAGE_SIZE_PREFERENCE value %q, using %s: %v", v, DefaultImageSizePreference, err)
+		}
+	}
+
+	var overrideBuckets, overrideLocations []string
+	for _, bucket := range strings.Split(os.Getenv("CONFIG_OVERRIDE_BUCKETS"), ",") {
+		if bucket = normalizeBucketName(bucket); bucket != "" {
+			overrideBuckets = append(overrideBuckets, bucket)
+		}
+	}
+	for _, location := range strings.Split(os.Getenv("CONFIG_OVERRIDE_LOCATIONS"), ",") {
+		if location = strings.ToLower(strings.TrimSpace(location)); location != "" {
+			overrideLocations = append(overrideLocations, location)
+		}
+	}
+	if len(overrideBuckets) > 0 {
+		LogInfof("Requests may override the bucket with: %s", strings.Join(overrideBuckets, ", "))
+	}
+	if len(overrideLocations) > 0 {
+		LogInfof("Requests may override the location with: %s", strings.Join(overrideLocations, ", "))
+	}
+
+	cfg := &Config{
 		ProjectID:                   projectID,
 		Location:                    location,
+		LocationEnvVar:              locationEnvVar,
 		GenmediaBucket:              genmediaBucket,
 		ApiEndpoint:                 os.Getenv("VERTEX_API_ENDPOINT"), // Use os.Getenv for optional value
 		AllowUnsafeModels:           allowUnsafe,
 		EnableOptionalHeaderCapture: enableCapture,
+		AuditLogSink:                auditLogSink,
+		AuditRedactPrompts:          auditRedactPrompts,
+		MaxInlineBytes:              maxInlineBytes,
+		PromptPrefix:                promptPrefix,
+		PromptSuffix:                promptSuffix,
+		ResponseCacheSize:           responseCacheSize,
+		ResponseCacheTTL:            responseCacheTTL,
+		LogLevel:                    logLevel,
+		LogFormat:                   logFormat,
+		EnabledTools:                enabledTools,
+		DisabledTools:               disabledTools,
+		GenAIBackend:                genAIBackend,
+		GeminiAPIKey:                geminiAPIKey,
+		OutputRetention:             outputRetention,
+		OutputRetentionDirs:         outputRetentionDirs,
+		OutputRetentionInterval:     outputRetentionInterval,
+		OutputRetentionDryRun:       outputRetentionDryRun,
+		CircuitBreakerThreshold:     circuitBreakerThreshold,
+		CircuitBreakerCooldown:      circuitBreakerCooldown,
+		TTSDefaultEncoding:          ttsDefaultEncoding,
+		MaxInputImages:              maxInputImages,
+		MaxInputImageBytes:          maxInputImageBytes,
+		MaxInputTextBytes:           maxInputTextBytes,
+		InputTextLocalDir:           inputTextLocalDir,
+		ModelDefinitionsFile:        modelDefinitionsFile,
+		LogToolCalls:                logToolCalls,
+		ImagenImageSizePreference:   imagenImageSizePreference,
+		OverrideBuckets:             overrideBuckets,
+		OverrideLocations:           overrideLocations,
+		MP4Faststart:                mp4Faststart,
+		TTSMetadataTags:             ttsMetadataTags,
+		StorageBackend:              storageBackend,
+		StorageLocalRoot:            storageLocalRoot,
+		VeoOutputPathTemplate:       veoOutputPathTemplate,
+	}
+
+	if err := cfg.Validate(); err != nil {
+		msg := "Invalid configuration:\n  " + strings.ReplaceAll(err.Error(), "\n", "\n  ")
+		if strings.ToLower(os.Getenv("CONFIG_STRICT_VALIDATION")) == "true" {
+			LogFatalf("%s", msg)
+		}
+		LogWarnf("%s\nSet CONFIG_STRICT_VALIDATION=true to refuse to start with an invalid configuration.", msg)
+	}
+	return cfg
+}
+
+// ToolEnabled reports whether the named tool should be registered, based on the
+// MCP_ENABLED_TOOLS and MCP_DISABLED_TOOLS lists. A tool in both lists is disabled.
+func (c *Config) ToolEnabled(name string) bool {
+	if c == nil {
+		return true
+	}
+	if slices.Contains(c.DisabledTools, name) {
+		return false
+	}
+	return len(c.EnabledTools) == 0 || slices.Contains(c.EnabledTools, name)
+}
+
+// parseToolList splits a comma-separated list of tool names, dropping empty entries.
+func parseToolList(value string) []string {
+	var tools []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tools = append(tools, name)
+		}
 	}
+	return tools
 }
 
 // GetGCSDownloadTimeout returns the timeout duration for GCS download operations.
@@ -115,7 +442,7 @@ func GetGCSDownloadTimeout() time.Duration {
 		if d, err := time.ParseDuration(v); err == nil {
 			return d
 		}
-		log.Printf("Invalid GCS_DOWNLOAD_TIMEOUT value %q, using default of 5m", v)
+		LogWarnf("Invalid GCS_DOWNLOAD_TIMEOUT value %q, using default of 5m", v)
 	}
 	return 5 * time.Minute
 }
@@ -128,9 +455,9 @@ func GetEnv(key, fallback string) string {
 		return value
 	}
 	if fallback != "" {
-		log.Printf("Environment variable %s not set or empty, using fallback: %s", key, fallback)
+		LogDebugf("Environment variable %s not set or empty, using fallback: %s", key, fallback)
 	} else {
-		log.Printf("Environment variable %s not set or empty, using empty fallback.", key)
+		LogDebugf("Environment variable %s not set or empty, using empty fallback.", key)
 	}
 	return fallback
 }
@@ -0,0 +1,94 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Tool parameters that override the server configuration for one request. Overrides are only
+// accepted for the values listed in CONFIG_OVERRIDE_BUCKETS and CONFIG_OVERRIDE_LOCATIONS.
+const (
+	BucketOverrideParam   = "genmedia_bucket"
+	LocationOverrideParam = "vertex_location"
+)
+
+// ConfigOverrides are the request-level values that replace those of the global Config.
+// Empty values keep the global value.
+type ConfigOverrides struct {
+	GenmediaBucket string
+	Location       string
+}
+
+// ParseConfigOverrides reads the BucketOverrideParam and LocationOverrideParam tool parameters.
+func ParseConfigOverrides(args map[string]interface{}) ConfigOverrides {
+	bucket, _ := args[BucketOverrideParam].(string)
+	location, _ := args[LocationOverrideParam].(string)
+	return ConfigOverrides{
+		GenmediaBucket: normalizeBucketName(bucket),
+		Location:       strings.ToLower(strings.TrimSpace(location)),
+	}
+}
+
+// normalizeBucketName strips the gs:// prefix and trailing slashes of a bucket name.
+func normalizeBucketName(bucket string) string {
+	return strings.TrimRight(strings.TrimPrefix(strings.TrimSpace(bucket), "gs://"), "/")
+}
+
+// MergeConfig returns the effective configuration of a request: a copy of c with the overrides
+// applied. An override must be listed in CONFIG_OVERRIDE_BUCKETS or CONFIG_OVERRIDE_LOCATIONS,
+// unless it equals the global value. If any value is overridden, the effective values are
+// logged. c itself is never modified.
+func (c *Config) MergeConfig(overrides ConfigOverrides) (*Config, error) {
+	effective := *c
+	var changed []string
+	if bucket := overrides.GenmediaBucket; bucket != "" && bucket != c.GenmediaBucket {
+		if strings.Contains(bucket, "/") {
+			return nil, fmt.Errorf("%s '%s' must be a bucket name without a path", BucketOverrideParam, bucket)
+		}
+		if !slices.Contains(c.OverrideBuckets, bucket) {
+			return nil, fmt.Errorf("%s '%s' is not allowed. %s", BucketOverrideParam, bucket, allowedOverrides(c.OverrideBuckets, "CONFIG_OVERRIDE_BUCKETS"))
+		}
+		effective.GenmediaBucket = bucket
+		changed = append(changed, "bucket")
+	}
+	if location := overrides.Location; location != "" && location != c.Location {
+		if !slices.Contains(c.OverrideLocations, location) {
+			return nil, fmt.Errorf("%s '%s' is not allowed. %s", LocationOverrideParam, location, allowedOverrides(c.OverrideLocations, "CONFIG_OVERRIDE_LOCATIONS"))
+		}
+		effective.Location = location
+		changed = append(changed, "location")
+	}
+	if len(changed) > 0 {
+		LogInfof("Request overrides %s. Effective configuration: location=%s, bucket=%s", strings.Join(changed, " and "), effective.Location, effective.GenmediaBucket)
+	}
+	return &effective, nil
+}
+
+// allowedOverrides describes the values allowed by an override allowlist for an error message.
+func allowedOverrides(allowed []string, envVar string) string {
+	if len(allowed) == 0 {
+		return fmt.Sprintf("Overrides are disabled on this server (%s is not set).", envVar)
+	}
+	return fmt.Sprintf("Allowed values are: %s", strings.Join(allowed, ", "))
+}
+
+// effectiveConfigContextKey is the context key of a request's effective configuration.
+type effectiveConfigContextKey struct{}
+
+// WithEffectiveConfig returns a context carrying the effective configuration of a request, for
+// code that runs deep inside a handler.
+func WithEffectiveConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, effectiveConfigContextKey{}, cfg)
+}
+
+// EffectiveConfig returns the effective configuration carried by ctx, or fallback if there is none.
+func EffectiveConfig(ctx context.Context, fallback *Config) *Config {
+	if cfg, ok := ctx.Value(effectiveConfigContextKey{}).(*Config); ok && cfg != nil {
+		return cfg
+	}
+	return fallback
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigOverrides(t *testing.T) {
+	got := ParseConfigOverrides(map[string]interface{}{BucketOverrideParam: " gs://team-a/ ", LocationOverrideParam: "US-East4"})
+	want := ConfigOverrides{GenmediaBucket: "team-a", Location: "us-east4"}
+	if got != want {
+		t.Errorf("ParseConfigOverrides() = %+v, want %+v", got, want)
+	}
+	if got := ParseConfigOverrides(map[string]interface{}{}); got != (ConfigOverrides{}) {
+		t.Errorf("ParseConfigOverrides() without parameters = %+v, want no overrides", got)
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	global := &Config{
+		Location:          "us-central1",
+		GenmediaBucket:    "shared",
+		OverrideBuckets:   []string{"team-a", "team-b"},
+		OverrideLocations: []string{"us-east4"},
+	}
+	tests := []struct {
+		name         string
+		overrides    ConfigOverrides
+		wantBucket   string
+		wantLocation string
+		errContains  string
+	}{
+		{name: "no overrides", wantBucket: "shared", wantLocation: "us-central1"},
+		{name: "allowed bucket", overrides: ConfigOverrides{GenmediaBucket: "team-a"}, wantBucket: "team-a", wantLocation: "us-central1"},
+		{name: "allowed location", overrides: ConfigOverrides{Location: "us-east4"}, wantBucket: "shared", wantLocation: "us-east4"},
+		{name: "global values", overrides: ConfigOverrides{GenmediaBucket: "shared", Location: "us-central1"}, wantBucket: "shared", wantLocation: "us-central1"},
+		{name: "bucket not allowed", overrides: ConfigOverrides{GenmediaBucket: "other"}, errContains: "Allowed values are: team-a, team-b"},
+		{name: "bucket with path", overrides: ConfigOverrides{GenmediaBucket: "team-a/out"}, errContains: "without a path"},
+		{name: "location not allowed", overrides: ConfigOverrides{Location: "europe-west4"}, errContains: "vertex_location 'europe-west4' is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := global.MergeConfig(tt.overrides)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.GenmediaBucket != tt.wantBucket || got.Location != tt.wantLocation {
+				t.Errorf("MergeConfig() = bucket %q, location %q, want %q, %q", got.GenmediaBucket, got.Location, tt.wantBucket, tt.wantLocation)
+			}
+		})
+	}
+	if global.GenmediaBucket != "shared" || global.Location != "us-central1" {
+		t.Errorf("MergeConfig() modified the global config: %+v", global)
+	}
+
+	_, err := (&Config{GenmediaBucket: "shared"}).MergeConfig(ConfigOverrides{GenmediaBucket: "team-a"})
+	if err == nil || !strings.Contains(err.Error(), "CONFIG_OVERRIDE_BUCKETS is not set") {
+		t.Errorf("expected overrides to be disabled without an allowlist, got %v", err)
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	global, effective := &Config{Location: "us-central1"}, &Config{Location: "us-east4"}
+	if got := EffectiveConfig(context.Background(), global); got != global {
+		t.Errorf("EffectiveConfig() without a request config = %+v, want the fallback", got)
+	}
+	if got := EffectiveConfig(WithEffectiveConfig(context.Background(), effective), global); got != effective {
+		t.Errorf("EffectiveConfig() = %+v, want the request config", got)
+	}
+}
@@ -95,6 +95,20 @@ func TestLoadConfig(t *testing.T) {
 		_ = os.Unsetenv("ALLOW_UNSAFE_MODELS")
 	})
 
+	t.Run("with LOG_TOOL_CALLS", func(t *testing.T) {
+		_ = os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+		_ = os.Unsetenv("LOG_TOOL_CALLS")
+		if cfg := LoadConfig("test-server"); !cfg.LogToolCalls {
+			t.Errorf("expected LogToolCalls to default to true")
+		}
+
+		_ = os.Setenv("LOG_TOOL_CALLS", "False")
+		if cfg := LoadConfig("test-server"); cfg.LogToolCalls {
+			t.Errorf("expected LogToolCalls to be false")
+		}
+		_ = os.Unsetenv("LOG_TOOL_CALLS")
+	})
+
 	t.Run("with some env vars missing", func(t *testing.T) {
 		_ = os.Unsetenv("LOCATION")
 		_ = os.Unsetenv("GENMEDIA_BUCKET")
@@ -148,3 +162,29 @@ func TestGetGCSDownloadTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestToolEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  string
+		disabled string
+		tool     string
+		expected bool
+	}{
+		{"no_lists", "", "", "gemini_audio_tts", true},
+		{"disabled", "", "gemini_audio_tts, list_gemini_voices", "gemini_audio_tts", false},
+		{"other_tool_disabled", "", "gemini_audio_tts", "gemini_image_generation", true},
+		{"enabled_only", "gemini_image_generation", "", "gemini_audio_tts", false},
+		{"in_enabled_list", "gemini_image_generation,gemini_audio_tts", "", "gemini_audio_tts", true},
+		{"disabled_wins", "gemini_audio_tts", "gemini_audio_tts", "gemini_audio_tts", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{EnabledTools: parseToolList(tt.enabled), DisabledTools: parseToolList(tt.disabled)}
+			if got := cfg.ToolEnabled(tt.tool); got != tt.expected {
+				t.Errorf("ToolEnabled(%q) = %v, want %v (enabled: %q, disabled: %q)", tt.tool, got, tt.expected, tt.enabled, tt.disabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// KnownLocations are the Google Cloud locations accepted by Validate: the Vertex AI regions and
+// the "global", "us", and "eu" multi-regions used by some APIs (e.g., CHIRP3_LOCATION=eu).
+var KnownLocations = []string{
+	"global", "us", "eu",
+	"us-central1", "us-east1", "us-east4", "us-east5", "us-south1", "us-west1", "us-west2", "us-west3", "us-west4",
+	"northamerica-northeast1", "northamerica-northeast2", "southamerica-east1", "southamerica-west1",
+	"europe-central2", "europe-north1", "europe-southwest1", "europe-west1", "europe-west2", "europe-west3",
+	"europe-west4", "europe-west6", "europe-west8", "europe-west9", "europe-west12",
+	"asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3", "asia-south1",
+	"asia-southeast1", "asia-southeast2", "australia-southeast1", "australia-southeast2",
+	"me-central1", "me-central2", "me-west1", "africa-south1",
+}
+
+// bucketNamePattern matches the characters allowed in a GCS bucket name, which must start and
+// end with a lowercase letter or digit.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*[a-z0-9]$`)
+
+// ValidateBucketName checks a GCS bucket name against the Cloud Storage naming rules. A "gs://"
+// prefix and an object path after the bucket (e.g., "my-bucket/outputs") are allowed.
+func ValidateBucketName(bucket string) error {
+	name := strings.TrimPrefix(bucket, "gs://")
+	name, _, _ = strings.Cut(name, "/")
+	switch {
+	case len(name) < 3 || len(name) > 222:
+		return fmt.Errorf("bucket name %q must be between 3 and 222 characters", name)
+	case !bucketNamePattern.MatchString(name):
+		return fmt.Errorf("bucket name %q may only contain lowercase letters, digits, dashes, underscores, and dots, and must start and end with a letter or digit", name)
+	case !strings.Contains(name, ".") && len(name) > 63:
+		return fmt.Errorf("bucket name %q must be at most 63 characters unless it contains dots", name)
+	case net.ParseIP(name) != nil:
+		return fmt.Errorf("bucket name %q must not be an IP address", name)
+	case strings.HasPrefix(name, "goog") || strings.Contains(name, "google") || strings.Contains(name, "g00gle"):
+		return fmt.Errorf("bucket name %q must not start with \"goog\" or contain \"google\"", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("bucket name %q must not contain consecutive dots", name)
+	}
+	for _, component := range strings.Split(name, ".") {
+		if len(component) > 63 {
+			return fmt.Errorf("bucket name %q has a dot-separated component longer than 63 characters", name)
+		}
+	}
+	return nil
+}
+
+// APIEndpointURL returns VERTEX_API_ENDPOINT as a URL, adding the https scheme to a bare host
+// (e.g., "us-central1-aiplatform.googleapis.com"). It returns "" if the endpoint is not set.
+func (c *Config) APIEndpointURL() string {
+	if c.ApiEndpoint == "" || strings.Contains(c.ApiEndpoint, "://") {
+		return c.ApiEndpoint
+	}
+	return "https://" + c.ApiEndpoint
+}
+
+// APIEndpointHost returns the host, and port if any, of VERTEX_API_ENDPOINT, which may be set as
+// a URL or as a bare host. It returns "" if the endpoint is not set.
+func (c *Config) APIEndpointHost() string {
+	if c.ApiEndpoint == "" {
+		return ""
+	}
+	u, err := url.Parse(c.APIEndpointURL())
+	if err != nil {
+		return c.ApiEndpoint
+	}
+	return u.Host
+}
+
+// validateAPIEndpoint checks that VERTEX_API_ENDPOINT is an absolute http or https URL, or a bare
+// host with an optional port.
+func validateAPIEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if !strings.Contains(endpoint, "://") {
+		u, err = url.Parse("https://" + endpoint)
+		if err == nil && strings.Trim(u.Path, "/") != "" {
+			err = errors.New("a bare host must not have a path")
+		}
+	}
+	if err == nil && ((u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "") {
+		err = errors.New("missing host or unsupported scheme")
+	}
+	if err != nil {
+		return fmt.Errorf("VERTEX_API_ENDPOINT %q must be an http or https URL or a bare host (e.g., https://us-central1-aiplatform.googleapis.com/ or us-central1-aiplatform.googleapis.com): %w", endpoint, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration for values that would only fail later, when a tool is
+// called: the GENMEDIA_BUCKET name, the location, the VERTEX_API_ENDPOINT, and the root of the
+// local storage backend. It returns all problems found, joined into one error, or nil if there
+// are none.
+func (c *Config) Validate() error {
+	var problems []error
+	if c.GenmediaBucket != "" {
+		if err := ValidateBucketName(c.GenmediaBucket); err != nil {
+			problems = append(problems, fmt.Errorf("GENMEDIA_BUCKET: %w", err))
+		}
+	}
+	if c.Location != "" && !slices.Contains(KnownLocations, c.Location) {
+		envVar := c.LocationEnvVar
+		if envVar == "" {
+			envVar = "LOCATION"
+		}
+		problems = append(problems, fmt.Errorf("%s: location %q is not a known Google Cloud location (e.g., us-central1, europe-west4, global)", envVar, c.Location))
+	}
+	if c.ApiEndpoint != "" {
+		if err := validateAPIEndpoint(c.ApiEndpoint); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.StorageBackend == StorageBackendLocal && c.StorageLocalRoot == "" {
+		problems = append(problems, fmt.Errorf("STORAGE_LOCAL_ROOT must be set when STORAGE_BACKEND is %s", StorageBackendLocal))
+	}
+	return errors.Join(problems...)
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBucketName(t *testing.T) {
+	tests := []struct {
+		name    string
+		bucket  string
+		wantErr bool
+	}{
+		{name: "simple", bucket: "my-bucket"},
+		{name: "with scheme and path", bucket: "gs://my_bucket/outputs/veo"},
+		{name: "dotted", bucket: "media.example.com"},
+		{name: "too short", bucket: "ab", wantErr: true},
+		{name: "uppercase", bucket: "My-Bucket", wantErr: true},
+		{name: "trailing dash", bucket: "my-bucket-", wantErr: true},
+		{name: "too long without dots", bucket: strings.Repeat("a", 64), wantErr: true},
+		{name: "ip address", bucket: "192.168.1.1", wantErr: true},
+		{name: "google prefix", bucket: "goog-media", wantErr: true},
+		{name: "contains google", bucket: "my-google-bucket", wantErr: true},
+		{name: "consecutive dots", bucket: "media..example", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateBucketName(tt.bucket); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{GenmediaBucket: "my-bucket", Location: "us-central1", ApiEndpoint: "https://us-central1-aiplatform.googleapis.com/"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+
+	invalid := Config{GenmediaBucket: "My_Bucket!", Location: "us-centrall", LocationEnvVar: "VEO_LOCATION", ApiEndpoint: "ftp://aiplatform.googleapis.com", StorageBackend: StorageBackendLocal}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected an error, but got nil")
+	}
+	for _, want := range []string{"GENMEDIA_BUCKET", "VEO_LOCATION", "us-centrall", "VERTEX_API_ENDPOINT", "STORAGE_LOCAL_ROOT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, but got %v", want, err)
+		}
+	}
+}
+
+func TestValidateAPIEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+		wantURL  string
+		wantHost string
+	}{
+		{endpoint: "https://us-central1-aiplatform.googleapis.com/", wantURL: "https://us-central1-aiplatform.googleapis.com/", wantHost: "us-central1-aiplatform.googleapis.com"},
+		{endpoint: "http://localhost:8080", wantURL: "http://localhost:8080", wantHost: "localhost:8080"},
+		{endpoint: "aiplatform.googleapis.com", wantURL: "https://aiplatform.googleapis.com", wantHost: "aiplatform.googleapis.com"},
+		{endpoint: "localhost:8080", wantURL: "https://localhost:8080", wantHost: "localhost:8080"},
+		{endpoint: "ftp://aiplatform.googleapis.com", wantErr: true},
+		{endpoint: "https://", wantErr: true},
+		{endpoint: "aiplatform.googleapis.com/v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			err := validateAPIEndpoint(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			c := Config{ApiEndpoint: tt.endpoint}
+			if got := c.APIEndpointURL(); got != tt.wantURL {
+				t.Errorf("expected URL %q, but got %q", tt.wantURL, got)
+			}
+			if got := c.APIEndpointHost(); got != tt.wantHost {
+				t.Errorf("expected host %q, but got %q", tt.wantHost, got)
+			}
+		})
+	}
+}
+
+func TestConfigValidateDefaultLocationEnvVar(t *testing.T) {
+	err := (&Config{Location: "mars-north1"}).Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "LOCATION: ") {
+		t.Errorf("expected the error to name LOCATION, but got %v", err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -38,7 +37,7 @@ func PrepareInputFile(ctx context.Context, fileURI, purpose string, gcpProjectID
 		}
 		localPath = filepath.Join(tempDir, base)
 
-		log.Printf("Downloading GCS file %s to temporary path %s for %s", fileURI, localPath, purpose)
+		LogInfof("Downloading GCS file %s to temporary path %s for %s", fileURI, localPath, purpose)
 
 		gcsErr := DownloadFromGCS(ctx, fileURI, localPath)
 		if gcsErr != nil {
@@ -47,7 +46,7 @@ func PrepareInputFile(ctx context.Context, fileURI, purpose string, gcpProjectID
 		}
 
 		cleanupFunc = func() {
-			log.Printf("Cleaning up temporary directory for GCS download: %s", tempDir)
+			LogDebugf("Cleaning up temporary directory for GCS download: %s", tempDir)
 			_ = os.RemoveAll(tempDir)
 		}
 		return localPath, cleanupFunc, nil
@@ -56,7 +55,7 @@ func PrepareInputFile(ctx context.Context, fileURI, purpose string, gcpProjectID
 	if _, statErr := os.Stat(fileURI); os.IsNotExist(statErr) {
 		return "", cleanupFunc, fmt.Errorf("local input file %s does not exist for %s", fileURI, purpose)
 	}
-	log.Printf("Using local input file %s for %s", fileURI, purpose)
+	LogInfof("Using local input file %s for %s", fileURI, purpose)
 	return fileURI, cleanupFunc, nil
 }
 
@@ -81,19 +80,19 @@ func HandleOutputPreparation(desiredOutputFilename, defaultExt string) (tempLoca
 		if currentExt == "" {
 			finalOutputFilename = finalOutputFilename + "." + defaultExt
 		} else if strings.ToLower(currentExt) != "."+strings.ToLower(defaultExt) {
-			log.Printf("Warning: output_file_name '%s' has extension '%s', but expected '%s'. Using original extension.", desiredOutputFilename, currentExt, defaultExt)
+			LogWarnf("Warning: output_file_name '%s' has extension '%s', but expected '%s'. Using original extension.", desiredOutputFilename, currentExt, defaultExt)
 		}
 	}
 
 	tempLocalOutputFile = filepath.Join(tempDir, finalOutputFilename)
 
 	cleanupFunc = func() {
-		log.Printf("Cleaning up temporary output directory: %s", tempDir)
+		LogDebugf("Cleaning up temporary output directory: %s", tempDir)
 		_ = os.RemoveAll(tempDir)
 	}
 
-	log.Printf("FFMpeg will write temporary output to: %s", tempLocalOutputFile)
-	log.Printf("Final output filename will be: %s", finalOutputFilename)
+	LogDebugf("FFMpeg will write temporary output to: %s", tempLocalOutputFile)
+	LogDebugf("Final output filename will be: %s", finalOutputFilename)
 	return tempLocalOutputFile, finalOutputFilename, cleanupFunc, nil
 }
 
@@ -108,10 +107,10 @@ func ProcessOutputAfterFFmpeg(ctx context.Context, ffmpegOutputActualPath, final
 			return "", "", fmt.Errorf("failed to create specified output local directory %s: %w", outputLocalDir, errMkdir)
 		}
 		destLocalPath := filepath.Join(outputLocalDir, finalOutputFilename)
-		log.Printf("Moving FFMpeg output from %s to %s", currentLocalPath, destLocalPath)
+		LogInfof("Moving FFMpeg output from %s to %s", currentLocalPath, destLocalPath)
 		if errRename := os.Rename(currentLocalPath, destLocalPath); errRename != nil {
 			// If rename fails (e.g. different devices), try copy then remove original
-			log.Printf("Rename failed (%v), attempting copy and remove for %s to %s", errRename, currentLocalPath, destLocalPath)
+			LogWarnf("Rename failed (%v), attempting copy and remove for %s to %s", errRename, currentLocalPath, destLocalPath)
 			inputBytes, readErr := os.ReadFile(currentLocalPath)
 			if readErr != nil {
 				return "", "", fmt.Errorf("failed to read source for copy %s: %w", currentLocalPath, readErr)
@@ -120,16 +119,16 @@ func ProcessOutputAfterFFmpeg(ctx context.Context, ffmpegOutputActualPath, final
 				return "", "", fmt.Errorf("failed to write destination for copy %s: %w", destLocalPath, writeErr)
 			}
 			if removeErr := os.Remove(currentLocalPath); removeErr != nil {
-				log.Printf("Warning: failed to remove original file %s after copy: %v", currentLocalPath, removeErr)
+				LogWarnf("Warning: failed to remove original file %s after copy: %v", currentLocalPath, removeErr)
 				// Not returning error here as the file is copied, but log it.
 			}
 		}
 		currentLocalPath = destLocalPath
 		finalLocalPath = currentLocalPath
-		log.Printf("Output saved to local directory: %s", finalLocalPath)
+		LogInfof("Output saved to local directory: %s", finalLocalPath)
 	} else {
 		finalLocalPath = ffmpegOutputActualPath
-		log.Printf("Output generated at temporary location: %s (will be cleaned up if not moved or uploaded)", finalLocalPath)
+		LogInfof("Output generated at temporary location: %s (will be cleaned up if not moved or uploaded)", finalLocalPath)
 	}
 
 	if outputGCSBucket != "" {
@@ -140,7 +139,7 @@ func ProcessOutputAfterFFmpeg(ctx context.Context, ffmpegOutputActualPath, final
 			return finalLocalPath, "", fmt.Errorf("ffmpeg output file %s not found for GCS upload", currentLocalPath)
 		}
 
-		log.Printf("Uploading %s to GCS bucket %s as object %s", currentLocalPath, outputGCSBucket, finalOutputFilename)
+		LogInfof("Uploading %s to GCS bucket %s as object %s", currentLocalPath, outputGCSBucket, finalOutputFilename)
 
 		fileData, readErr := os.ReadFile(currentLocalPath)
 		if readErr != nil {
@@ -154,7 +153,7 @@ func ProcessOutputAfterFFmpeg(ctx context.Context, ffmpegOutputActualPath, final
 			return finalLocalPath, "", fmt.Errorf("failed to upload to GCS (gs://%s/%s): %w", outputGCSBucket, finalOutputFilename, errUpload)
 		}
 		finalGCSPath = fmt.Sprintf("gs://%s/%s", outputGCSBucket, finalOutputFilename)
-		log.Printf("Output uploaded to GCS: %s", finalGCSPath)
+		LogInfof("Output uploaded to GCS: %s", finalGCSPath)
 	}
 	return finalLocalPath, finalGCSPath, nil
 }
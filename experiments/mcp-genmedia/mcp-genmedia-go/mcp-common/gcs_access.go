@@ -0,0 +1,198 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"cloud.google.com/go/storage"
+	"github.com/teris-io/shortid"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
+)
+
+// gcsAccessProbePrefix is the folder in which the write probe object is created (and deleted).
+const gcsAccessProbePrefix = "mcp_access_check/"
+
+// tokenInfoURL is the endpoint that reports the email of an access token.
+var tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// impersonationURLPattern extracts the target service account from an impersonation URL.
+var impersonationURLPattern = regexp.MustCompile(`serviceAccounts/([^/:]+):generateAccessToken`)
+
+// GCSAccessCheck is the outcome of one probe of a GCS access report.
+type GCSAccessCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// GCSAccessReport describes what the server's credentials can do with a bucket.
+type GCSAccessReport struct {
+	Bucket         string           `json:"bucket"`
+	Identity       string           `json:"identity,omitempty"` // Service account or user email, if it could be determined.
+	CredentialType string           `json:"credential_type,omitempty"`
+	Read           bool             `json:"read"`
+	Write          bool             `json:"write"`
+	Sign           bool             `json:"sign"`
+	Checks         []GCSAccessCheck `json:"checks"`
+}
+
+func (r *GCSAccessReport) addCheck(name string, err error, detail string) bool {
+	check := GCSAccessCheck{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+	return err == nil
+}
+
+// identityFromCredentialsJSON returns the email and type of a credentials file, if present.
+// Service account keys carry the email directly; impersonated credentials carry it in the
+// impersonation URL. User credentials have no email, which is then looked up from the token.
+func identityFromCredentialsJSON(data []byte) (email, credType string) {
+	var creds struct {
+		Type                           string `json:"type"`
+		ClientEmail                    string `json:"client_email"`
+		ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &creds) != nil {
+		return "", ""
+	}
+	email = creds.ClientEmail
+	if email == "" {
+		if m := impersonationURLPattern.FindStringSubmatch(creds.ServiceAccountImpersonationURL); m != nil {
+			email, _ = url.PathUnescape(m[1])
+		}
+	}
+	return email, creds.Type
+}
+
+// credentialIdentity determines the email of the Application Default Credentials.
+func credentialIdentity(ctx context.Context) (email, credType string, err error) {
+	creds, err := google.FindDefaultCredentials(ctx, storage.ScopeFullControl)
+	if err != nil {
+		return "", "", fmt.Errorf("no Application Default Credentials found: %w", err)
+	}
+	email, credType = identityFromCredentialsJSON(creds.JSON)
+	if email != "" {
+		return email, credType, nil
+	}
+	if len(creds.JSON) == 0 && metadata.OnGCE() {
+		email, err = metadata.EmailWithContext(ctx, "default")
+		return email, "compute_metadata", err
+	}
+
+	// Fall back to asking the token info endpoint about the access token.
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return "", credType, fmt.Errorf("failed to get an access token: %w", err)
+	}
+	email, err = tokenInfoEmail(ctx, token.AccessToken)
+	return email, credType, err
+}
+
+// tokenInfoEmail asks the token info endpoint for the email of an access token.
+func tokenInfoEmail(ctx context.Context, accessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?access_token="+url.QueryEscape(accessToken), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token info lookup failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token info lookup failed with status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var info struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil || info.Email == "" {
+		return "", errors.New("the credentials do not expose an email address")
+	}
+	return info.Email, nil
+}
+
+// CheckGCSAccess probes what the server's credentials can do with a bucket: list objects
+// (read), create, read back, and delete a small probe object (write), and sign a URL (sign).
+// Each probe is recorded as a check, so a failure does not stop the remaining probes.
+func CheckGCSAccess(ctx context.Context, bucketName string) *GCSAccessReport {
+	report := &GCSAccessReport{Bucket: bucketName}
+
+	email, credType, err := credentialIdentity(ctx)
+	report.Identity, report.CredentialType = email, credType
+	report.addCheck("identity", err, email)
+
+	client, err := storage.NewClient(ctx)
+	if !report.addCheck("storage_client", err, "") {
+		return report
+	}
+	defer func() { _ = client.Close() }()
+	bucket := client.Bucket(bucketName)
+
+	attrs, err := bucket.Attrs(ctx)
+	if err == nil {
+		report.addCheck("bucket_metadata", nil, fmt.Sprintf("location %s, storage class %s", attrs.Location, attrs.StorageClass))
+	} else {
+		// Reading bucket metadata needs storage.buckets.get, which object-level roles lack.
+		report.addCheck("bucket_metadata", err, "")
+	}
+
+	_, err = bucket.Objects(ctx, &storage.Query{Prefix: gcsAccessProbePrefix}).Next()
+	if errors.Is(err, iterator.Done) {
+		err = nil
+	}
+	report.Read = report.addCheck("list_objects", err, "")
+
+	uid, _ := shortid.Generate()
+	objectName := fmt.Sprintf("%s%s-%s.txt", gcsAccessProbePrefix, time.Now().UTC().Format("20060102T150405"), uid)
+	probe := []byte("mcp-genmedia access check\n")
+	// The bucket is probed in Cloud Storage, whatever STORAGE_BACKEND, as that is where the
+	// Vertex AI APIs write.
+	err = GCSStorage{}.Upload(ctx, bucketName, objectName, "text/plain", probe)
+	report.Write = report.addCheck("write_object", err, "gs://"+bucketName+"/"+objectName)
+	if report.Write {
+		err := readProbeObject(ctx, bucket.Object(objectName), probe)
+		report.Read = report.addCheck("read_object", err, "") && report.Read
+		if err := bucket.Object(objectName).Delete(ctx); err != nil {
+			LogWarnf("Warning: failed to delete access check object gs://%s/%s: %v", bucketName, objectName, err)
+			report.addCheck("delete_object", err, "")
+		} else {
+			report.addCheck("delete_object", nil, "")
+		}
+	}
+
+	_, err = GCSStorage{}.SignURL(ctx, "gs://"+bucketName+"/"+objectName, 15*time.Minute)
+	report.Sign = report.addCheck("sign_url", err, "")
+	return report
+}
+
+// readProbeObject reads an object back and checks that it has the expected content.
+func readProbeObject(ctx context.Context, obj *storage.ObjectHandle, want []byte) error {
+	rc, err := obj.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("the probe object was read back with different content")
+	}
+	return nil
+}
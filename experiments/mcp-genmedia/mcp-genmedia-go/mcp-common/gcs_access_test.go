@@ -0,0 +1,78 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdentityFromCredentialsJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantEmail string
+		wantType  string
+	}{
+		{"service account key", `{"type": "service_account", "client_email": "genmedia@my-project.iam.gserviceaccount.com"}`, "genmedia@my-project.iam.gserviceaccount.com", "service_account"},
+		{
+			"impersonated service account",
+			`{"type": "impersonated_service_account", "service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/runner@my-project.iam.gserviceaccount.com:generateAccessToken"}`,
+			"runner@my-project.iam.gserviceaccount.com", "impersonated_service_account",
+		},
+		{"user credentials", `{"type": "authorized_user", "client_id": "123"}`, "", "authorized_user"},
+		{"empty", ``, "", ""},
+		{"invalid JSON", `{`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, credType := identityFromCredentialsJSON([]byte(tt.data))
+			if email != tt.wantEmail || credType != tt.wantType {
+				t.Errorf("expected (%q, %q), but got (%q, %q)", tt.wantEmail, tt.wantType, email, credType)
+			}
+		})
+	}
+}
+
+func TestTokenInfoEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		want        string
+		errContains string
+	}{
+		{name: "email", status: http.StatusOK, body: `{"email": "someone@example.com"}`, want: "someone@example.com"},
+		{name: "no email", status: http.StatusOK, body: `{"scope": "cloud-platform"}`, errContains: "do not expose an email address"},
+		{name: "invalid token", status: http.StatusBadRequest, body: `{"error_description": "Invalid Value", "email": "stale@example.com"}`, errContains: "status 400 Bad Request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("access_token") != "token" {
+					t.Errorf("expected the access token to be sent, but got %q", r.URL.RawQuery)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			saved := tokenInfoURL
+			t.Cleanup(func() { tokenInfoURL = saved })
+			tokenInfoURL = srv.URL
+
+			got, err := tokenInfoEmail(t.Context(), "token")
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,22 +20,21 @@ import (
 // It parses the GCS URI, creates a GCS client, and then reads the object's contents,
 // writing them to a new local file. It also creates the destination directory if it doesn't exist.
 func DownloadFromGCS(ctx context.Context, gcsURI, localDestPath string) error {
-	bucketName, objectName, err := ParseGCSPath(gcsURI)
-	if err != nil {
-		return err
-	}
+	return downloadToFile(ctx, DefaultStorage, gcsURI, localDestPath)
+}
 
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("storage.NewClient: %w", err)
-	}
-	defer func() { _ = client.Close() }()
+// DownloadGeneratedFromGCS is DownloadFromGCS for an object that a Vertex AI API wrote to GCS
+// itself, such as a Veo video. It always reads Cloud Storage, whatever STORAGE_BACKEND.
+func DownloadGeneratedFromGCS(ctx context.Context, gcsURI, localDestPath string) error {
+	return downloadToFile(ctx, GCSStorage{}, gcsURI, localDestPath)
+}
 
+func downloadToFile(ctx context.Context, store Storage, gcsURI, localDestPath string) error {
 	gcsOpCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
-	rc, err := client.Bucket(bucketName).Object(objectName).NewReader(gcsOpCtx)
+	rc, err := store.Download(gcsOpCtx, gcsURI)
 	if err != nil {
-		return fmt.Errorf("Object(%q).NewReader: %w", objectName, err)
+		return err
 	}
 	defer func() { _ = rc.Close() }()
 
@@ -53,46 +52,40 @@ func DownloadFromGCS(ctx context.Context, gcsURI, localDestPath string) error {
 	if _, err := io.Copy(f, rc); err != nil {
 		return fmt.Errorf("io.Copy: %w", err)
 	}
-	log.Printf("Successfully downloaded %s to %s", gcsURI, localDestPath)
+	LogInfof("Successfully downloaded %s to %s", gcsURI, localDestPath)
 	return nil
 }
 
 func DownloadFromGCSAsBytes(ctx context.Context, gcsURI string) ([]byte, error) {
-	bucketName, objectName, err := ParseGCSPath(gcsURI)
-	if err != nil {
+	if _, _, err := ParseGCSPath(gcsURI); err != nil {
 		return nil, err
 	}
 
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("storage.NewClient: %w", err)
-	}
-	defer func() { _ = client.Close() }()
-
-	var rc *storage.Reader
-	var lastErr error
+	var rc io.ReadCloser
 	var cancel context.CancelFunc
 	timeout := GetGCSDownloadTimeout()
-	// Retry loop to handle eventual consistency of GCS.
-	for i := 0; i < 5; i++ {
-		var gcsOpCtx context.Context
-		var loopCancel context.CancelFunc
-		gcsOpCtx, loopCancel = context.WithTimeout(ctx, timeout)
-		rc, lastErr = client.Bucket(bucketName).Object(objectName).NewReader(gcsOpCtx)
-		if lastErr == nil {
-			cancel = loopCancel
-			break // Success — don't cancel yet, rc needs the context to stream data
-		}
-		loopCancel()
-		if !errors.Is(lastErr, storage.ErrObjectNotExist) {
-			return nil, fmt.Errorf("Object(%q).NewReader: %w", objectName, lastErr) // Return non-transient errors immediately
-		}
-		log.Printf("Object %s not found, retrying in 3 seconds... (attempt %d/5)", gcsURI, i+1)
-		time.Sleep(3 * time.Second)
+	// Retry to handle eventual consistency of GCS; other errors are returned immediately.
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   3 * time.Second,
+		MaxDelay:    3 * time.Second,
+		IsRetryable: func(err error) bool {
+			return errors.Is(err, storage.ErrObjectNotExist) || errors.Is(err, fs.ErrNotExist)
+		},
 	}
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("Object(%q).NewReader timed out after retries: %w", objectName, lastErr)
+	err := Retry(ctx, policy, func(ctx context.Context) error {
+		gcsOpCtx, opCancel := context.WithTimeout(ctx, timeout)
+		reader, err := DefaultStorage.Download(gcsOpCtx, gcsURI)
+		if err != nil {
+			opCancel()
+			return err
+		}
+		// Success — don't cancel yet, rc needs the context to stream data
+		rc, cancel = reader, opCancel
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer cancel()
 	defer func() { _ = rc.Close() }()
@@ -104,11 +97,65 @@ func DownloadFromGCSAsBytes(ctx context.Context, gcsURI string) ([]byte, error)
 	return data, nil
 }
 
-// UploadToGCS uploads data to a specified GCS bucket and object.
-// It takes the data as a byte slice and infers the content type from the object name's extension
-// if it's not explicitly provided. This is useful for ensuring that GCS objects have the correct
-// metadata, which is important for serving them correctly.
+// objectContentTypes maps file extensions to the content types set on uploaded GCS objects, so
+// that browsers can play or display them, e.g. through signed URLs.
+var objectContentTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".json": "application/json",
+	".txt":  "text/plain",
+	".srt":  "application/x-subrip",
+	".vtt":  "text/vtt",
+}
+
+// ContentTypeForObject infers the content type of a GCS object from the extension of its name.
+// It returns an empty string for unknown extensions.
+func ContentTypeForObject(objectName string) string {
+	return objectContentTypes[strings.ToLower(filepath.Ext(objectName))]
+}
+
+// GetGCSCacheControl returns the Cache-Control metadata set on the GCS objects written by the
+// servers, from the GCS_CACHE_CONTROL environment variable (e.g. "private, max-age=86400").
+// If it is not set, objects get the Cloud Storage default.
+func GetGCSCacheControl() string {
+	return strings.TrimSpace(os.Getenv("GCS_CACHE_CONTROL"))
+}
+
+// UploadToGCS uploads data to a specified GCS bucket and object of DefaultStorage. See
+// GCSStorage.Upload for the metadata set on GCS objects.
 func UploadToGCS(ctx context.Context, bucketName, objectName, contentType string, data []byte) error {
+	return DefaultStorage.Upload(ctx, bucketName, objectName, contentType, data)
+}
+
+// UploadGeneratedToGCS is UploadToGCS for an object derived from the output of a Vertex AI API
+// and stored next to it, such as a re-encoded Veo video. Like the output, it always goes to
+// Cloud Storage, whatever STORAGE_BACKEND.
+func UploadGeneratedToGCS(ctx context.Context, bucketName, objectName, contentType string, data []byte) error {
+	return GCSStorage{}.Upload(ctx, bucketName, objectName, contentType, data)
+}
+
+// GCSStorage is the Cloud Storage backend.
+type GCSStorage struct{}
+
+// Upload takes the data as a byte slice and infers the content type from the object name's
+// extension if it's not explicitly provided. This is useful for ensuring that GCS objects have
+// the correct metadata, which is important for serving them correctly. GCS_CACHE_CONTROL, if
+// set, is applied as the object's Cache-Control.
+func (GCSStorage) Upload(ctx context.Context, bucketName, objectName, contentType string, data []byte) error {
 	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return fmt.Errorf("storage.NewClient: %w", err)
@@ -120,34 +167,18 @@ func UploadToGCS(ctx context.Context, bucketName, objectName, contentType string
 
 	finalContentType := contentType
 	if finalContentType == "" {
-		ext := strings.ToLower(filepath.Ext(objectName))
-		switch ext {
-		case ".mp3":
-			finalContentType = "audio/mpeg"
-		case ".wav":
-			finalContentType = "audio/wav"
-		case ".mp4":
-			finalContentType = "video/mp4"
-		case ".mov":
-			finalContentType = "video/quicktime"
-		case ".mkv":
-			finalContentType = "video/x-matroska"
-		case ".webm":
-			finalContentType = "video/webm"
-		case ".png":
-			finalContentType = "image/png"
-		case ".jpg", ".jpeg":
-			finalContentType = "image/jpeg"
-		case ".gif":
-			finalContentType = "image/gif"
-		default:
-			log.Printf("uploadToGCS: Could not infer ContentType for extension '%s' of object '%s'. Uploading without explicit ContentType.", ext, objectName)
+		finalContentType = ContentTypeForObject(objectName)
+		if finalContentType == "" {
+			LogWarnf("uploadToGCS: Could not infer ContentType for extension '%s' of object '%s'. Uploading without explicit ContentType.", filepath.Ext(objectName), objectName)
 		}
 	}
 
 	if finalContentType != "" {
 		wc.ContentType = finalContentType
-		log.Printf("uploadToGCS: Setting ContentType to '%s' for object '%s'", finalContentType, objectName)
+		LogDebugf("uploadToGCS: Setting ContentType to '%s' for object '%s'", finalContentType, objectName)
+	}
+	if cacheControl := GetGCSCacheControl(); cacheControl != "" {
+		wc.CacheControl = cacheControl
 	}
 
 	if _, err := wc.Write(data); err != nil {
@@ -160,6 +191,113 @@ func UploadToGCS(ctx context.Context, bucketName, objectName, contentType string
 	return nil
 }
 
+// gcsObjectReader closes the storage client along with the object reader.
+type gcsObjectReader struct {
+	*storage.Reader
+	client *storage.Client
+}
+
+func (r gcsObjectReader) Close() error {
+	err := r.Reader.Close()
+	_ = r.client.Close()
+	return err
+}
+
+// Download opens the object for reading. A missing object is reported as storage.ErrObjectNotExist.
+func (GCSStorage) Download(ctx context.Context, gcsURI string) (io.ReadCloser, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	rc, err := client.Bucket(bucketName).Object(objectName).NewReader(ctx)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("Object(%q).NewReader: %w", objectName, err)
+	}
+	return gcsObjectReader{Reader: rc, client: client}, nil
+}
+
+// SignURL returns a V4 signed GET URL of the object. The credentials must be able to sign,
+// e.g. a service account key or a service account with the Token Creator role on itself.
+func (GCSStorage) SignURL(ctx context.Context, gcsURI string, expires time.Duration) (string, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return "", err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return "", fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+	signedURL, err := client.Bucket(bucketName).SignedURL(objectName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "GET",
+		Expires: time.Now().Add(expires),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign a URL for %s: %w", gcsURI, err)
+	}
+	return signedURL, nil
+}
+
+// SetGCSObjectMetadata sets the content type and GCS_CACHE_CONTROL on an existing object, such
+// as one written directly by a Vertex AI API, which may not carry the metadata browsers need.
+// If contentType is empty, it is inferred from the object name. Like the outputs it is meant
+// for, the object is always in Cloud Storage, whatever STORAGE_BACKEND.
+func SetGCSObjectMetadata(ctx context.Context, gcsURI, contentType string) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = ContentTypeForObject(objectName)
+	}
+	update := storage.ObjectAttrsToUpdate{}
+	if contentType != "" {
+		update.ContentType = contentType
+	}
+	if cacheControl := GetGCSCacheControl(); cacheControl != "" {
+		update.CacheControl = cacheControl
+	}
+	if update.ContentType == nil && update.CacheControl == nil {
+		return nil
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if _, err := client.Bucket(bucketName).Object(objectName).Update(ctx, update); err != nil {
+		return fmt.Errorf("failed to update metadata of %s: %w", gcsURI, err)
+	}
+	return nil
+}
+
+// DeleteFromGCS deletes an object from Cloud Storage, whatever STORAGE_BACKEND, such as one
+// staged there for a Google Cloud API to read.
+func DeleteFromGCS(ctx context.Context, gcsURI string) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := client.Bucket(bucketName).Object(objectName).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", gcsURI, err)
+	}
+	return nil
+}
+
 // ParseGCSPath extracts the bucket and object names from a GCS URI.
 // It validates that the URI has the correct format (gs://bucket/object)
 // and returns the two components. This is a helper function to make working
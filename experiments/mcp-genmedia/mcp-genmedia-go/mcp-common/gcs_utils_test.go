@@ -38,6 +38,29 @@ func TestParseGCSPath(t *testing.T) {
 	}
 }
 
+func TestContentTypeForObject(t *testing.T) {
+	testCases := []struct {
+		objectName string
+		expected   string
+	}{
+		{"videos/veo-1.mp4", "video/mp4"},
+		{"images/IMAGE.PNG", "image/png"},
+		{"speech.m4a", "audio/mp4"},
+		{"speech.ogg", "audio/ogg"},
+		{"image.webp", "image/webp"},
+		{"archive.tar.gz", ""},
+		{"no-extension", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.objectName, func(t *testing.T) {
+			if got := ContentTypeForObject(tc.objectName); got != tc.expected {
+				t.Errorf("expected content type '%s', but got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestDownloadFromGCS(t *testing.T) {
 	// This is a basic integration test that requires a running GCS emulator.
 	// You can start one with: gcloud beta emulators gcs start --project=test-project
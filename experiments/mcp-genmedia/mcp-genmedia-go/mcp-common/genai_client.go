@@ -0,0 +1,149 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"google.golang.org/genai"
+)
+
+const (
+	// GenAIBackendVertex selects Vertex AI, authenticated with Application Default Credentials.
+	GenAIBackendVertex = "vertex"
+	// GenAIBackendGemini selects the Gemini API, authenticated with an API key.
+	GenAIBackendGemini = "gemini"
+)
+
+const (
+	// clientRetryInitialBackoff is the wait before the first retry of a failed client creation.
+	clientRetryInitialBackoff = time.Second
+	// clientRetryMaxBackoff caps the wait between client creation attempts.
+	clientRetryMaxBackoff = 10 * time.Second
+)
+
+// dmiProductNamePath holds the hardware product name, "Google Compute Engine" on Google Cloud VMs.
+var dmiProductNamePath = "/sys/class/dmi/id/product_name"
+
+// ParseGenAIBackend parses the GENAI_BACKEND setting. An empty value selects Vertex AI.
+func ParseGenAIBackend(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", GenAIBackendVertex, "vertexai", "vertex_ai":
+		return GenAIBackendVertex, nil
+	case GenAIBackendGemini, "gemini_api", "geminiapi":
+		return GenAIBackendGemini, nil
+	default:
+		return "", fmt.Errorf("invalid GENAI_BACKEND value %q: must be %q or %q", value, GenAIBackendVertex, GenAIBackendGemini)
+	}
+}
+
+// NewGenAIClientConfig returns the genai client configuration for the configured backend. For
+// Vertex AI it uses the project, the given location, and VERTEX_API_ENDPOINT if set; for the
+// Gemini API it uses the API key, and the location is ignored.
+func (c *Config) NewGenAIClientConfig(location string) *genai.ClientConfig {
+	if c.GenAIBackend == GenAIBackendGemini {
+		return &genai.ClientConfig{
+			Backend: genai.BackendGeminiAPI,
+			APIKey:  c.GeminiAPIKey,
+		}
+	}
+
+	clientConfig := &genai.ClientConfig{
+		Backend:  genai.BackendVertexAI,
+		Project:  c.ProjectID,
+		Location: location,
+	}
+	if c.ApiEndpoint != "" {
+		LogInfof("Using custom Vertex AI endpoint: %s", c.APIEndpointURL())
+		clientConfig.HTTPOptions.BaseURL = c.APIEndpointURL()
+	}
+	return clientConfig
+}
+
+// IsTransientClientError reports whether an error creating an API client is likely to go away on
+// retry: timeouts, failed HTTP requests and other network errors, and 5xx or 429 responses from
+// the token endpoint or the metadata server. Configuration errors and missing or rejected
+// credentials are permanent. Not finding default credentials is treated as transient on Google
+// Cloud runtimes, where it usually means that the metadata server did not answer in time; the
+// credentials libraries report it without an error type, so it is recognized by its message.
+func IsTransientClientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.Response != nil {
+		return isTransientStatus(retrieveErr.Response.StatusCode)
+	}
+	var metadataErr *metadata.Error
+	if errors.As(err, &metadataErr) {
+		return isTransientStatus(metadataErr.Code)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if strings.Contains(strings.ToLower(err.Error()), "could not find default credentials") {
+		return runningOnGoogleCloud()
+	}
+	return false
+}
+
+// isTransientStatus reports whether an HTTP status code is worth retrying.
+func isTransientStatus(code int) bool {
+	return code >= 500 || code == 429
+}
+
+// runningOnGoogleCloud reports whether the server appears to run on Google Cloud (Compute Engine,
+// GKE, Cloud Run, Cloud Functions, or App Engine), where credentials come from the metadata server.
+func runningOnGoogleCloud() bool {
+	for _, key := range []string{"GCE_METADATA_HOST", "K_SERVICE", "CLOUD_RUN_JOB", "FUNCTION_TARGET", "GAE_SERVICE"} {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	productName, err := os.ReadFile(dmiProductNamePath)
+	return err == nil && strings.Contains(string(productName), "Google")
+}
+
+// NewGenAIClientWithRetry creates a GenAI client with create, retrying transient failures with
+// exponential backoff until ctx is done, so that a network or metadata server delay at startup
+// does not stop the server. Permanent errors, such as bad credentials, are returned immediately.
+// Use IsTransientClientError on the returned error to tell the two apart.
+func NewGenAIClientWithRetry(ctx context.Context, create func(ctx context.Context) (*genai.Client, error)) (*genai.Client, error) {
+	backoff := clientRetryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		client, err := create(ctx)
+		if err == nil {
+			return client, nil
+		}
+		if !IsTransientClientError(err) {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		LogWarnf("Creating the GenAI client failed with a transient error (attempt %d): %v. Retrying in %v.", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, clientRetryMaxBackoff)
+	}
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+
+	"google.golang.org/genai"
+)
+
+func TestParseGenAIBackend(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: GenAIBackendVertex},
+		{value: "vertex", want: GenAIBackendVertex},
+		{value: " VertexAI ", want: GenAIBackendVertex},
+		{value: "gemini", want: GenAIBackendGemini},
+		{value: "GEMINI_API", want: GenAIBackendGemini},
+		{value: "openai", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGenAIBackend(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGenAIBackend(%q): expected error %t, but got %v", tt.value, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseGenAIBackend(%q): expected %q, but got %q", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestNewGenAIClientConfig(t *testing.T) {
+	vertex := &Config{ProjectID: "my-project", GenAIBackend: GenAIBackendVertex, ApiEndpoint: "https://example.com", GeminiAPIKey: "unused"}
+	got := vertex.NewGenAIClientConfig("us-east1")
+	if got.Backend != genai.BackendVertexAI || got.Project != "my-project" || got.Location != "us-east1" || got.APIKey != "" {
+		t.Errorf("expected a Vertex AI config for my-project in us-east1, but got %+v", got)
+	}
+	if got.HTTPOptions.BaseURL != "https://example.com" {
+		t.Errorf("expected the custom endpoint, but got %q", got.HTTPOptions.BaseURL)
+	}
+
+	gemini := &Config{GenAIBackend: GenAIBackendGemini, GeminiAPIKey: "key"}
+	got = gemini.NewGenAIClientConfig("us-east1")
+	if got.Backend != genai.BackendGeminiAPI || got.APIKey != "key" || got.Project != "" || got.Location != "" {
+		t.Errorf("expected a Gemini API config with only the API key, but got %+v", got)
+	}
+}
+
+func TestIsTransientClientError(t *testing.T) {
+	originalPath := dmiProductNamePath
+	t.Cleanup(func() { dmiProductNamePath = originalPath })
+	dmiProductNamePath = filepath.Join(t.TempDir(), "product_name")
+	for _, key := range []string{"GCE_METADATA_HOST", "K_SERVICE", "CLOUD_RUN_JOB", "FUNCTION_TARGET", "GAE_SERVICE"} {
+		t.Setenv(key, "")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "deadline exceeded", err: fmt.Errorf("token: %w", context.DeadlineExceeded), want: true},
+		{name: "metadata server 503", err: fmt.Errorf("token: %w", &metadata.Error{Code: http.StatusServiceUnavailable}), want: true},
+		{name: "metadata server 404", err: &metadata.Error{Code: http.StatusNotFound}, want: false},
+		{name: "failed request", err: &url.Error{Op: "Get", URL: "http://169.254.169.254", Err: errors.New("connection refused")}, want: true},
+		{name: "dial error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "message mentioning a timeout", err: errors.New("invalid timeout setting"), want: false},
+		{name: "token endpoint 503", err: &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}}, want: true},
+		{name: "token endpoint 401", err: &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusUnauthorized}}, want: false},
+		{name: "missing credentials off Google Cloud", err: errors.New("credentials: could not find default credentials"), want: false},
+		{name: "invalid config", err: errors.New("project/location or API key must be set"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientClientError(tt.err); got != tt.want {
+				t.Errorf("expected %t, but got %t", tt.want, got)
+			}
+		})
+	}
+
+	t.Setenv("K_SERVICE", "mcp-imagen-go")
+	if !IsTransientClientError(errors.New("credentials: could not find default credentials")) {
+		t.Errorf("expected missing credentials on Cloud Run to be transient")
+	}
+}
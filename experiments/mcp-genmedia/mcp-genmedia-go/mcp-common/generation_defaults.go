@@ -0,0 +1,189 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// GenerationDefaultsToolName is the name of the tool registered by AddGenerationDefaultsTool.
+const GenerationDefaultsToolName = "get_generation_defaults"
+
+// Model families accepted by AddGenerationDefaultsTool.
+const (
+	ModelFamilyVeo    = "veo"
+	ModelFamilyImagen = "imagen"
+)
+
+// VeoGenerationDefaults are the values a Veo tool uses for parameters that are not given.
+type VeoGenerationDefaults struct {
+	DurationSeconds int32  `json:"duration_seconds"`
+	AspectRatio     string `json:"aspect_ratio"`
+	NumVideos       int32  `json:"num_videos"`
+	GenerateAudio   bool   `json:"generate_audio"`
+}
+
+// VeoGenerationProfile is the capability profile of a Veo model: its defaults and the values
+// it accepts.
+type VeoGenerationProfile struct {
+	Model                  string                `json:"model"`
+	Aliases                []string              `json:"aliases"`
+	Defaults               VeoGenerationDefaults `json:"defaults"`
+	SupportedDurations     []int32               `json:"supported_durations_seconds"`
+	SupportedAspectRatios  []string              `json:"supported_aspect_ratios"`
+	MaxVideos              int32                 `json:"max_videos"`
+	SupportsGenerateAudio  bool                  `json:"supports_generate_audio"`
+	SupportsFirstLast      bool                  `json:"supports_first_last_frame"`
+	SupportsReferenceImage bool                  `json:"supports_reference_images"`
+	SupportsExtend         bool                  `json:"supports_extend"`
+	SupportedFPS           []int32               `json:"supported_fps,omitempty"`
+}
+
+// ImagenGenerationDefaults are the values the Imagen tool uses for parameters that are not given.
+// ImageSize is empty for models that do not accept an image size.
+type ImagenGenerationDefaults struct {
+	AspectRatio string `json:"aspect_ratio"`
+	NumImages   int32  `json:"num_images"`
+	ImageSize   string `json:"image_size,omitempty"`
+}
+
+// ImagenGenerationProfile is the capability profile of an Imagen model: its defaults and the
+// values it accepts.
+type ImagenGenerationProfile struct {
+	Model                 string                   `json:"model"`
+	Aliases               []string                 `json:"aliases"`
+	Defaults              ImagenGenerationDefaults `json:"defaults"`
+	SupportedAspectRatios []string                 `json:"supported_aspect_ratios"`
+	SupportedImageSizes   []string                 `json:"supported_image_sizes"`
+	MaxImages             int32                    `json:"max_images"`
+}
+
+// generationDefaultsResult is the result of the get_generation_defaults tool.
+type generationDefaultsResult struct {
+	Models []any `json:"models"`
+}
+
+// NewVeoGenerationProfile returns the capability profile of a Veo model.
+func NewVeoGenerationProfile(info VeoModelInfo) VeoGenerationProfile {
+	return VeoGenerationProfile{
+		Model:   info.CanonicalName,
+		Aliases: nonNilStrings(info.Aliases),
+		Defaults: VeoGenerationDefaults{
+			DurationSeconds: info.DefaultDuration,
+			AspectRatio:     info.DefaultAspectRatio(),
+			NumVideos:       1,
+			GenerateAudio:   info.SupportsGenerateAudio,
+		},
+		SupportedDurations:     info.SupportedDurations,
+		SupportedAspectRatios:  nonNilStrings(info.SupportedAspectRatios),
+		MaxVideos:              info.MaxVideos,
+		SupportsGenerateAudio:  info.SupportsGenerateAudio,
+		SupportsFirstLast:      info.SupportsFirstLast,
+		SupportsReferenceImage: info.SupportsReferenceImage,
+		SupportsExtend:         info.SupportsExtend,
+		SupportedFPS:           info.SupportedFPS,
+	}
+}
+
+// NewImagenGenerationProfile returns the capability profile of an Imagen model. Its default
+// image size is the one the Imagen tool picks for the given image size preference.
+func NewImagenGenerationProfile(info ImagenModelInfo, sizePreference string) ImagenGenerationProfile {
+	defaults := ImagenGenerationDefaults{AspectRatio: "1:1", NumImages: 1}
+	defaults.ImageSize = info.DefaultImageSize(defaults.AspectRatio, sizePreference)
+	return ImagenGenerationProfile{
+		Model:                 info.CanonicalName,
+		Aliases:               nonNilStrings(info.Aliases),
+		Defaults:              defaults,
+		SupportedAspectRatios: nonNilStrings(info.SupportedAspectRatios),
+		SupportedImageSizes:   nonNilStrings(info.SupportedImageSizes),
+		MaxImages:             info.MaxImages,
+	}
+}
+
+// nonNilStrings returns values, or an empty slice if it is nil, so that it is encoded as [].
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// generationProfiles returns the capability profiles of the models of a family, sorted by name.
+// If model is not empty, only the profile of that model (resolved by alias) is returned.
+func generationProfiles(cfg *Config, family, model string) ([]any, error) {
+	allowUnsafe := cfg != nil && cfg.AllowUnsafeModels
+	sizePreference, _ := cfg.ResolveImageSizePreference("")
+	var profiles []any
+	switch family {
+	case ModelFamilyVeo:
+		if model != "" {
+			info, found := ResolveVeoModel(model, allowUnsafe)
+			if !found {
+				return nil, fmt.Errorf("model '%s' is not a valid or supported Veo model name", model)
+			}
+			return []any{NewVeoGenerationProfile(info)}, nil
+		}
+		for _, name := range sortedKeys(SupportedVeoModels) {
+			profiles = append(profiles, NewVeoGenerationProfile(SupportedVeoModels[name]))
+		}
+	case ModelFamilyImagen:
+		if model != "" {
+			info, found := ResolveImagenModel(model, allowUnsafe)
+			if !found {
+				return nil, fmt.Errorf("model '%s' is not a valid or supported Imagen model name", model)
+			}
+			return []any{NewImagenGenerationProfile(info, sizePreference)}, nil
+		}
+		for _, name := range sortedKeys(SupportedImagenModels) {
+			profiles = append(profiles, NewImagenGenerationProfile(SupportedImagenModels[name], sizePreference))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported model family '%s'", family)
+	}
+	return profiles, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddGenerationDefaultsTool registers the 'get_generation_defaults' tool, which returns the
+// capability profile of the models of a family (ModelFamilyVeo or ModelFamilyImagen): the
+// defaults the server applies and the values each model accepts, so that clients can
+// pre-populate and constrain their inputs.
+func AddGenerationDefaultsTool(s *server.MCPServer, cfg *Config, family string) {
+	tool := mcp.NewTool(GenerationDefaultsToolName,
+		mcp.WithDescription("Returns the default and allowed parameter values of a model, such as durations, aspect ratios, and the maximum number of outputs, for building client forms."),
+		mcp.WithString("model",
+			mcp.Description("Optional. A model name or alias. If omitted, the profiles of all supported models are returned."),
+		),
+	)
+	AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return generationDefaultsHandler(cfg, family, request)
+	})
+}
+
+func generationDefaultsHandler(cfg *Config, family string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	profiles, err := generationProfiles(cfg, family, request.GetString("model", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := generationDefaultsResult{Models: profiles}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal model profiles: %v", err)), nil
+	}
+	return mcp.NewToolResultStructured(result, string(data)), nil
+}
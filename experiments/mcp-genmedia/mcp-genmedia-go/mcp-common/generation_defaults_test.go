@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestGenerationDefaultsTool(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		family  string
+		model   string
+		wantErr bool
+		check   func(t *testing.T, models []any)
+	}{
+		{
+			name:   "veo alias",
+			family: ModelFamilyVeo,
+			model:  "Veo 3.0",
+			check: func(t *testing.T, models []any) {
+				profile, ok := models[0].(VeoGenerationProfile)
+				if len(models) != 1 || !ok {
+					t.Fatalf("expected one VeoGenerationProfile, but got %v", models)
+				}
+				if profile.Model != "veo-3.0-generate-001" {
+					t.Errorf("expected veo-3.0-generate-001, but got %s", profile.Model)
+				}
+				want := VeoGenerationDefaults{DurationSeconds: 8, AspectRatio: "16:9", NumVideos: 1, GenerateAudio: true}
+				if profile.Defaults != want {
+					t.Errorf("expected defaults %+v, but got %+v", want, profile.Defaults)
+				}
+				if profile.MaxVideos != 2 || len(profile.SupportedDurations) != 3 {
+					t.Errorf("expected 2 videos and 3 durations, but got %d and %v", profile.MaxVideos, profile.SupportedDurations)
+				}
+				if !slices.Equal(profile.SupportedFPS, []int32{24}) {
+					t.Errorf("expected 24 fps, but got %v", profile.SupportedFPS)
+				}
+			},
+		},
+		{
+			name:   "imagen without image sizes",
+			family: ModelFamilyImagen,
+			model:  "Imagen 3",
+			check: func(t *testing.T, models []any) {
+				profile := models[0].(ImagenGenerationProfile)
+				if profile.Defaults.ImageSize != "" || len(profile.SupportedImageSizes) != 0 {
+					t.Errorf("expected no image size, but got %+v", profile)
+				}
+			},
+		},
+		{
+			name:   "imagen size preference",
+			cfg:    &Config{ImagenImageSizePreference: ImageSizePreferenceLargest},
+			family: ModelFamilyImagen,
+			model:  "imagen-4.0-generate-001",
+			check: func(t *testing.T, models []any) {
+				if size := models[0].(ImagenGenerationProfile).Defaults.ImageSize; size != "2K" {
+					t.Errorf("expected the largest image size 2K, but got %q", size)
+				}
+			},
+		},
+		{
+			name:   "all imagen models",
+			family: ModelFamilyImagen,
+			check: func(t *testing.T, models []any) {
+				if len(models) != len(SupportedImagenModels) {
+					t.Fatalf("expected %d models, but got %d", len(SupportedImagenModels), len(models))
+				}
+				if first := models[0].(ImagenGenerationProfile).Model; first != "imagen-3.0-fast-generate-001" {
+					t.Errorf("expected models sorted by name, but got %s first", first)
+				}
+			},
+		},
+		{name: "unknown model", family: ModelFamilyVeo, model: "veo-9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "1.0.0")
+			cfg := tt.cfg
+			if cfg == nil {
+				cfg = &Config{}
+			}
+			AddGenerationDefaultsTool(s, cfg, tt.family)
+			request := mcp.CallToolRequest{}
+			request.Params.Name = GenerationDefaultsToolName
+			request.Params.Arguments = map[string]any{"model": tt.model}
+			result, err := s.GetTool(GenerationDefaultsToolName).Handler(context.Background(), request)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if result.IsError != tt.wantErr {
+				t.Fatalf("expected IsError %t, but got %t", tt.wantErr, result.IsError)
+			}
+			if tt.check != nil {
+				tt.check(t, result.StructuredContent.(generationDefaultsResult).Models)
+			}
+		})
+	}
+}
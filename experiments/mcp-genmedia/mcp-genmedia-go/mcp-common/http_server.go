@@ -0,0 +1,89 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultHTTPReadHeaderTimeout = 10 * time.Second
+	defaultHTTPReadTimeout       = 60 * time.Second
+	defaultHTTPIdleTimeout       = 120 * time.Second
+	defaultHTTPMaxBodyBytes      = 32 << 20 // 32 MiB
+)
+
+// HTTPServerSettings holds the limits applied to the streamable HTTP transport.
+type HTTPServerSettings struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout is disabled (0) by default because tool calls such as video
+	// generation can legitimately stream a response for many minutes.
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	MaxBodyBytes int64
+}
+
+// GetHTTPServerSettings reads the HTTP transport limits from the environment.
+// MCP_HTTP_READ_HEADER_TIMEOUT, MCP_HTTP_READ_TIMEOUT, MCP_HTTP_WRITE_TIMEOUT and
+// MCP_HTTP_IDLE_TIMEOUT accept Go duration strings; MCP_HTTP_MAX_BODY_BYTES accepts
+// a positive integer. Unset or invalid values fall back to the defaults.
+func GetHTTPServerSettings() HTTPServerSettings {
+	settings := HTTPServerSettings{
+		ReadHeaderTimeout: defaultHTTPReadHeaderTimeout,
+		ReadTimeout:       defaultHTTPReadTimeout,
+		IdleTimeout:       defaultHTTPIdleTimeout,
+		MaxBodyBytes:      defaultHTTPMaxBodyBytes,
+	}
+	if d := parseDurationEnv("MCP_HTTP_READ_HEADER_TIMEOUT"); d > 0 {
+		settings.ReadHeaderTimeout = d
+	}
+	if d := parseDurationEnv("MCP_HTTP_READ_TIMEOUT"); d > 0 {
+		settings.ReadTimeout = d
+	}
+	if d := parseDurationEnv("MCP_HTTP_WRITE_TIMEOUT"); d > 0 {
+		settings.WriteTimeout = d
+	}
+	if d := parseDurationEnv("MCP_HTTP_IDLE_TIMEOUT"); d > 0 {
+		settings.IdleTimeout = d
+	}
+	if n := parsePositiveIntEnv("MCP_HTTP_MAX_BODY_BYTES"); n > 0 {
+		settings.MaxBodyBytes = int64(n)
+	}
+	return settings
+}
+
+// NewHTTPServer returns an http.Server for the given address with the configured
+// timeouts applied and the handler wrapped in a request body size limit.
+func NewHTTPServer(addr string, handler http.Handler) *http.Server {
+	settings := GetHTTPServerSettings()
+	LogInfof("HTTP server limits: ReadHeaderTimeout=%v, ReadTimeout=%v, WriteTimeout=%v, IdleTimeout=%v, MaxBodyBytes=%d",
+		settings.ReadHeaderTimeout, settings.ReadTimeout, settings.WriteTimeout, settings.IdleTimeout, settings.MaxBodyBytes)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           LimitRequestBody(handler, settings.MaxBodyBytes),
+		ReadHeaderTimeout: settings.ReadHeaderTimeout,
+		ReadTimeout:       settings.ReadTimeout,
+		WriteTimeout:      settings.WriteTimeout,
+		IdleTimeout:       settings.IdleTimeout,
+	}
+}
+
+// LimitRequestBody wraps a handler so that request bodies larger than maxBytes are rejected.
+// Requests that declare an oversized Content-Length are refused up front with 413; bodies
+// without a declared length are cut off by http.MaxBytesReader once the limit is reached.
+func LimitRequestBody(next http.Handler, maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			http.Error(w, "request body too large (limit "+strconv.FormatInt(maxBytes, 10)+" bytes)", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetHTTPServerSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		for _, k := range []string{"MCP_HTTP_READ_HEADER_TIMEOUT", "MCP_HTTP_READ_TIMEOUT", "MCP_HTTP_WRITE_TIMEOUT", "MCP_HTTP_IDLE_TIMEOUT", "MCP_HTTP_MAX_BODY_BYTES"} {
+			t.Setenv(k, "")
+		}
+		s := GetHTTPServerSettings()
+		if s.ReadTimeout != defaultHTTPReadTimeout {
+			t.Errorf("expected ReadTimeout to be %v, but got %v", defaultHTTPReadTimeout, s.ReadTimeout)
+		}
+		if s.WriteTimeout != 0 {
+			t.Errorf("expected WriteTimeout to be disabled, but got %v", s.WriteTimeout)
+		}
+		if s.MaxBodyBytes != defaultHTTPMaxBodyBytes {
+			t.Errorf("expected MaxBodyBytes to be %d, but got %d", defaultHTTPMaxBodyBytes, s.MaxBodyBytes)
+		}
+	})
+
+	t.Run("env overrides", func(t *testing.T) {
+		t.Setenv("MCP_HTTP_READ_TIMEOUT", "5s")
+		t.Setenv("MCP_HTTP_WRITE_TIMEOUT", "15m")
+		t.Setenv("MCP_HTTP_IDLE_TIMEOUT", "invalid")
+		t.Setenv("MCP_HTTP_MAX_BODY_BYTES", "1024")
+		s := GetHTTPServerSettings()
+		if s.ReadTimeout != 5*time.Second {
+			t.Errorf("expected ReadTimeout to be 5s, but got %v", s.ReadTimeout)
+		}
+		if s.WriteTimeout != 15*time.Minute {
+			t.Errorf("expected WriteTimeout to be 15m, but got %v", s.WriteTimeout)
+		}
+		if s.IdleTimeout != defaultHTTPIdleTimeout {
+			t.Errorf("expected invalid IdleTimeout to fall back to %v, but got %v", defaultHTTPIdleTimeout, s.IdleTimeout)
+		}
+		if s.MaxBodyBytes != 1024 {
+			t.Errorf("expected MaxBodyBytes to be 1024, but got %d", s.MaxBodyBytes)
+		}
+	})
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := LimitRequestBody(echo, 16)
+
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		wantStatus    int
+	}{
+		{"within limit", "small body", false, http.StatusOK},
+		{"declared length over limit", strings.Repeat("x", 17), false, http.StatusRequestEntityTooLarge},
+		{"streamed body over limit", strings.Repeat("x", 17), true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Image size preferences accepted by IMAGEN_IMAGE_SIZE_PREFERENCE and the Imagen tools.
+const (
+	ImageSizePreferenceSmallest = "smallest"
+	ImageSizePreferenceLargest  = "largest"
+)
+
+// DefaultImageSizePreference is used when neither the request nor IMAGEN_IMAGE_SIZE_PREFERENCE
+// sets a preference. It keeps the previous behavior of generating 1K images.
+const DefaultImageSizePreference = ImageSizePreferenceSmallest
+
+// ParseImageSizePreference normalizes an image size preference (case-insensitively). An empty
+// value is returned as is.
+func ParseImageSizePreference(value string) (string, error) {
+	preference := strings.ToLower(strings.TrimSpace(value))
+	switch preference {
+	case "", ImageSizePreferenceSmallest, ImageSizePreferenceLargest:
+		return preference, nil
+	}
+	return "", fmt.Errorf("unsupported image size preference '%s'. Supported preferences are: %s, %s", value, ImageSizePreferenceSmallest, ImageSizePreferenceLargest)
+}
+
+// ResolveImageSizePreference returns the image size preference of a request: the requested
+// preference if set, otherwise IMAGEN_IMAGE_SIZE_PREFERENCE, otherwise DefaultImageSizePreference.
+func (c *Config) ResolveImageSizePreference(requested string) (string, error) {
+	preference, err := ParseImageSizePreference(requested)
+	if err != nil || preference != "" {
+		return preference, err
+	}
+	if c != nil && c.ImagenImageSizePreference != "" {
+		return c.ImagenImageSizePreference, nil
+	}
+	return DefaultImageSizePreference, nil
+}
+
+// DefaultImageSize returns the image size to request when a generation sets an aspect ratio
+// but no image size: of the model's supported sizes, the smallest for ImageSizePreferenceSmallest
+// or the largest for ImageSizePreferenceLargest. A size names the length of the image's longest
+// side, so the aspect ratio does not change which size is smallest; it only has to be supported.
+// It returns "" if the model does not accept an image size or does not support the aspect ratio.
+func (info ImagenModelInfo) DefaultImageSize(aspectRatio, preference string) string {
+	if !slices.Contains(info.SupportedAspectRatios, aspectRatio) {
+		return ""
+	}
+	best, bestK := "", 0
+	for _, size := range info.SupportedImageSizes {
+		k, ok := imageSizeK(size)
+		if !ok {
+			continue
+		}
+		if best == "" || (preference == ImageSizePreferenceLargest && k > bestK) || (preference != ImageSizePreferenceLargest && k < bestK) {
+			best, bestK = size, k
+		}
+	}
+	return best
+}
+
+// imageSizeK returns the number of K of an image size such as "1K".
+func imageSizeK(size string) (int, bool) {
+	k, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(size), "K"))
+	if err != nil || k <= 0 {
+		return 0, false
+	}
+	return k, true
+}
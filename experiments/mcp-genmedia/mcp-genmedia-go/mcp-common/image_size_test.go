@@ -0,0 +1,56 @@
+package common
+
+import "testing"
+
+func TestDefaultImageSize(t *testing.T) {
+	imagen4 := SupportedImagenModels["imagen-4.0-generate-001"]
+	imagen3 := SupportedImagenModels["imagen-3.0-generate-002"]
+	tests := []struct {
+		name        string
+		info        ImagenModelInfo
+		aspectRatio string
+		preference  string
+		want        string
+	}{
+		{"portrait smallest", imagen4, "9:16", ImageSizePreferenceSmallest, "1K"},
+		{"portrait largest", imagen4, "9:16", ImageSizePreferenceLargest, "2K"},
+		{"square without preference", imagen4, "1:1", "", "1K"},
+		{"sizes not supported", imagen3, "9:16", ImageSizePreferenceLargest, ""},
+		{"aspect ratio not supported", imagen4, "21:9", ImageSizePreferenceSmallest, ""},
+		{"unordered sizes", ImagenModelInfo{SupportedAspectRatios: []string{"16:9"}, SupportedImageSizes: []string{"2K", "1K", "bogus"}}, "16:9", ImageSizePreferenceSmallest, "1K"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.DefaultImageSize(tt.aspectRatio, tt.preference); got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveImageSizePreference(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *Config
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{"requested wins", &Config{ImagenImageSizePreference: ImageSizePreferenceLargest}, "Smallest", ImageSizePreferenceSmallest, false},
+		{"configured default", &Config{ImagenImageSizePreference: ImageSizePreferenceLargest}, "", ImageSizePreferenceLargest, false},
+		{"no default configured", &Config{}, "", DefaultImageSizePreference, false},
+		{"nil config", nil, " ", DefaultImageSizePreference, false},
+		{"unsupported request", &Config{}, "medium", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.ResolveImageSizePreference(tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
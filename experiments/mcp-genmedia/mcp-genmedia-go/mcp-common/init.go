@@ -2,23 +2,28 @@ package common
 
 import (
 	"context"
-	"log"
 )
 
 // Init loads the configuration and initializes OpenTelemetry.
 // It returns the loaded config and a cleanup function that should be deferred in main().
 func Init(serviceName, version string) (*Config, func()) {
 	cfg := LoadConfig(serviceName)
+	LogProxyConfiguration(cfg)
 
 	tp, err := InitTracerProvider(serviceName, version)
 	if err != nil {
-		log.Fatalf("failed to initialize tracer provider: %v", err)
+		LogFatalf("failed to initialize tracer provider: %v", err)
 	}
 
+	stopJanitor := StartOutputJanitor(cfg)
+	DefaultStorage = cfg.NewStorage()
+	GenAIBreaker = NewCircuitBreaker("GenAI API", cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+
 	cleanup := func() {
+		stopJanitor()
 		if tp != nil {
 			if err := tp.Shutdown(context.Background()); err != nil {
-				log.Printf("Error shutting down tracer provider: %v", err)
+				LogErrorf("Error shutting down tracer provider: %v", err)
 			}
 		}
 	}
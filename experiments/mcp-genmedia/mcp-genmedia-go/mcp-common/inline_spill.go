@@ -0,0 +1,36 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/teris-io/shortid"
+)
+
+// SpillInlineOutput uploads an output that would otherwise be returned inline to
+// gs://<GENMEDIA_BUCKET>/inline_spill/<service>/ and returns its gs:// URI. It returns
+// an empty string when the output is within MCP_MAX_INLINE_BYTES, when no bucket is
+// configured, or when the upload fails, in which case callers return the data inline.
+func SpillInlineOutput(ctx context.Context, cfg *Config, serviceName, fileName, contentType string, data []byte) string {
+	if cfg == nil || cfg.MaxInlineBytes <= 0 || int64(len(data)) <= cfg.MaxInlineBytes {
+		return ""
+	}
+	if cfg.GenmediaBucket == "" {
+		LogWarnf("Warning: output of %s exceeds MCP_MAX_INLINE_BYTES but GENMEDIA_BUCKET is not set; returning it inline.", FormatBytes(int64(len(data))))
+		return ""
+	}
+
+	uid, _ := shortid.Generate()
+	objectName := path.Join("inline_spill", serviceName, fmt.Sprintf("%s-%s-%s", time.Now().UTC().Format("20060102T150405"), uid, path.Base(fileName)))
+	if err := UploadToGCS(ctx, cfg.GenmediaBucket, objectName, contentType, data); err != nil {
+		LogWarnf("Warning: failed to spill %s output to GCS, returning it inline: %v", FormatBytes(int64(len(data))), err)
+		return ""
+	}
+	gcsURI := fmt.Sprintf("gs://%s/%s", cfg.GenmediaBucket, objectName)
+	LogInfof("Output of %s exceeds the inline limit of %s; spilled to %s", FormatBytes(int64(len(data))), FormatBytes(cfg.MaxInlineBytes), gcsURI)
+	return gcsURI
+}
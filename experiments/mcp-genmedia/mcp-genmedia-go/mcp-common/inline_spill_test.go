@@ -0,0 +1,26 @@
+package common
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSpillInlineOutputKeepsDataInline(t *testing.T) {
+	data := make([]byte, 1024)
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"nil config", nil},
+		{"spilling disabled", &Config{GenmediaBucket: "bucket"}},
+		{"within limit", &Config{GenmediaBucket: "bucket", MaxInlineBytes: 2048}},
+		{"over limit without bucket", &Config{MaxInlineBytes: 512}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SpillInlineOutput(context.Background(), tt.cfg, "mcp-test-go", "out.wav", "audio/wav", data); got != "" {
+				t.Errorf("expected output to stay inline, but got URI %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import "fmt"
+
+const (
+	// DefaultMaxInputImages is the default number of input images a generation request may
+	// include, the most that the Gemini 3 image models accept.
+	DefaultMaxInputImages = 14
+	// DefaultMaxInputImageBytes is the default total size of the input images sent inline with a
+	// generation request, below the 20 MB limit on inline request data.
+	DefaultMaxInputImageBytes = 20 << 20
+)
+
+// InputImageLimitError reports that the input images of a request exceed MAX_INPUT_IMAGES or
+// MAX_INPUT_IMAGE_BYTES.
+type InputImageLimitError struct {
+	Limit  string // The environment variable that sets the exceeded limit.
+	Actual int64
+	Max    int64
+}
+
+func (e *InputImageLimitError) Error() string {
+	if e.Limit == "MAX_INPUT_IMAGE_BYTES" {
+		return fmt.Sprintf("the input images total %s, more than the %s allowed (%s); pass large images as gs:// URIs instead of local files", FormatBytes(e.Actual), FormatBytes(e.Max), e.Limit)
+	}
+	return fmt.Sprintf("too many input images: %d were given, but at most %d are allowed (%s)", e.Actual, e.Max, e.Limit)
+}
+
+// CheckInputImageCount returns an *InputImageLimitError if count exceeds MAX_INPUT_IMAGES. A
+// limit of 0 disables the check.
+func (c *Config) CheckInputImageCount(count int) error {
+	if c == nil || c.MaxInputImages <= 0 || count <= c.MaxInputImages {
+		return nil
+	}
+	return &InputImageLimitError{Limit: "MAX_INPUT_IMAGES", Actual: int64(count), Max: int64(c.MaxInputImages)}
+}
+
+// CheckInputImageBytes returns an *InputImageLimitError if the total size of the input images
+// sent inline exceeds MAX_INPUT_IMAGE_BYTES. Images referenced by GCS URI are not sent inline and
+// do not count. A limit of 0 disables the check.
+func (c *Config) CheckInputImageBytes(total int64) error {
+	if c == nil || c.MaxInputImageBytes <= 0 || total <= c.MaxInputImageBytes {
+		return nil
+	}
+	return &InputImageLimitError{Limit: "MAX_INPUT_IMAGE_BYTES", Actual: total, Max: c.MaxInputImageBytes}
+}
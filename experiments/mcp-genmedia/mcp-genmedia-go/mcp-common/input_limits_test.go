@@ -0,0 +1,38 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckInputImageLimits(t *testing.T) {
+	cfg := &Config{MaxInputImages: 3, MaxInputImageBytes: 1000}
+	tests := []struct {
+		name      string
+		cfg       *Config
+		count     int
+		bytes     int64
+		wantLimit string
+	}{
+		{"within limits", cfg, 3, 1000, ""},
+		{"too many images", cfg, 4, 10, "MAX_INPUT_IMAGES"},
+		{"too large", cfg, 1, 1001, "MAX_INPUT_IMAGE_BYTES"},
+		{"checks disabled", &Config{}, 100, 1 << 30, ""},
+		{"nil config", nil, 100, 1 << 30, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Join(tt.cfg.CheckInputImageCount(tt.count), tt.cfg.CheckInputImageBytes(tt.bytes))
+			var limitErr *InputImageLimitError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+				t.Errorf("expected a %s limit error, but got %v", tt.wantLimit, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextURIParam is the tool parameter naming a text file, a local path or gs:// URI, whose
+// contents are used instead of the inline 'text' parameter.
+const TextURIParam = "text_uri"
+
+// DefaultMaxInputTextBytes is the default size limit of the text read from a TextURIParam.
+const DefaultMaxInputTextBytes = 1 << 20
+
+// InputText returns the text of a request: the 'text' parameter, or the contents of the file
+// named by TextURIParam. Exactly one of them must be given; whitespace-only text counts as not
+// given. Files larger than MAX_INPUT_TEXT_BYTES are rejected before they are read in full.
+// Local paths are restricted to INPUT_TEXT_LOCAL_DIR if it is set; otherwise any file the
+// server can read may be named.
+func (c *Config) InputText(ctx context.Context, args map[string]interface{}) (string, error) {
+	text, _ := args["text"].(string)
+	uri, _ := args[TextURIParam].(string)
+	uri = strings.TrimSpace(uri)
+	switch {
+	case strings.TrimSpace(text) != "" && uri != "":
+		return "", fmt.Errorf("only one of 'text' and '%s' may be provided", TextURIParam)
+	case uri == "":
+		if strings.TrimSpace(text) == "" {
+			return "", fmt.Errorf("either 'text' or '%s' must be provided, and the text must not be empty", TextURIParam)
+		}
+		return text, nil
+	}
+
+	var maxBytes int64
+	if c != nil {
+		maxBytes = c.MaxInputTextBytes
+		if c.InputTextLocalDir != "" && !strings.HasPrefix(uri, "gs://") {
+			if err := checkPathWithin(uri, c.InputTextLocalDir); err != nil {
+				return "", err
+			}
+		}
+	}
+	data, err := readTextFile(ctx, uri, maxBytes)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%s %s is not UTF-8 text", TextURIParam, uri)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("%s %s is empty", TextURIParam, uri)
+	}
+	return string(data), nil
+}
+
+// checkPathWithin returns an error unless the local file at path, with symbolic links resolved,
+// is inside dir (INPUT_TEXT_LOCAL_DIR).
+func checkPathWithin(path, dir string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("INPUT_TEXT_LOCAL_DIR %s is not accessible: %w", dir, err)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return fmt.Errorf("INPUT_TEXT_LOCAL_DIR %s is not accessible: %w", dir, err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s %s is outside the directory allowed for local text files (INPUT_TEXT_LOCAL_DIR)", TextURIParam, path)
+	}
+	return nil
+}
+
+// readTextFile reads the local file or GCS object at uri, failing if it is larger than maxBytes
+// (unless maxBytes is 0).
+func readTextFile(ctx context.Context, uri string, maxBytes int64) ([]byte, error) {
+	var (
+		r    io.Reader
+		size int64
+	)
+	if strings.HasPrefix(uri, "gs://") {
+		gcsOpCtx, cancel := context.WithTimeout(ctx, GetGCSDownloadTimeout())
+		defer cancel()
+		rc, err := DefaultStorage.Download(gcsOpCtx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		defer func() { _ = rc.Close() }()
+		r = rc
+	} else {
+		f, err := os.Open(uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		defer func() { _ = f.Close() }()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		r, size = f, info.Size()
+	}
+
+	if maxBytes > 0 {
+		if size > maxBytes {
+			return nil, inputTextTooLarge(uri, size, maxBytes)
+		}
+		// Objects, whose size is not known up front, and files that grew are checked while reading.
+		r = io.LimitReader(r, maxBytes+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, inputTextTooLarge(uri, int64(len(data)), maxBytes)
+	}
+	return data, nil
+}
+
+func inputTextTooLarge(uri string, size, maxBytes int64) error {
+	return fmt.Errorf("%s %s is %s, more than the %s allowed (MAX_INPUT_TEXT_BYTES)", TextURIParam, uri, FormatBytes(size), FormatBytes(maxBytes))
+}
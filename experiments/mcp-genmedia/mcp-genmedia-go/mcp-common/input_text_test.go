@@ -0,0 +1,76 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputText(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	script := writeFile("script.txt", "Chapter one.\nIt was a dark and stormy night.")
+	large := writeFile("large.txt", strings.Repeat("a", 101))
+	empty := writeFile("empty.txt", " \n")
+	binary := writeFile("binary.txt", "\xff\xfe\x00")
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{MaxInputTextBytes: 100}
+	restricted := &Config{InputTextLocalDir: dir}
+	tests := []struct {
+		name        string
+		cfg         *Config
+		args        map[string]interface{}
+		want        string
+		errContains string
+	}{
+		{name: "inline text", cfg: cfg, args: map[string]interface{}{"text": "Hello"}, want: "Hello"},
+		{name: "text file", cfg: cfg, args: map[string]interface{}{TextURIParam: script}, want: "Chapter one.\nIt was a dark and stormy night."},
+		{name: "both", cfg: cfg, args: map[string]interface{}{"text": "Hello", TextURIParam: script}, errContains: "only one of 'text' and 'text_uri'"},
+		{name: "neither", cfg: cfg, args: map[string]interface{}{}, errContains: "either 'text' or 'text_uri' must be provided"},
+		{name: "blank text", cfg: cfg, args: map[string]interface{}{"text": "  "}, errContains: "must not be empty"},
+		{name: "blank text with a file", cfg: cfg, args: map[string]interface{}{"text": " \n", TextURIParam: script}, want: "Chapter one.\nIt was a dark and stormy night."},
+		{name: "file in the allowed directory", cfg: restricted, args: map[string]interface{}{TextURIParam: script}, want: "Chapter one.\nIt was a dark and stormy night."},
+		{name: "file outside the allowed directory", cfg: restricted, args: map[string]interface{}{TextURIParam: outside}, errContains: "outside the directory allowed"},
+		{name: "relative path escaping the allowed directory", cfg: restricted, args: map[string]interface{}{TextURIParam: filepath.Join(dir, "..", filepath.Base(filepath.Dir(outside)), "secret.txt")}, errContains: "outside the directory allowed"},
+		{name: "symlink out of the allowed directory", cfg: restricted, args: map[string]interface{}{TextURIParam: link}, errContains: "outside the directory allowed"},
+		{name: "too large", cfg: cfg, args: map[string]interface{}{TextURIParam: large}, errContains: "more than the 100 B allowed (MAX_INPUT_TEXT_BYTES)"},
+		{name: "no limit", cfg: &Config{}, args: map[string]interface{}{TextURIParam: large}, want: strings.Repeat("a", 101)},
+		{name: "empty file", cfg: cfg, args: map[string]interface{}{TextURIParam: empty}, errContains: "is empty"},
+		{name: "not text", cfg: cfg, args: map[string]interface{}{TextURIParam: binary}, errContains: "is not UTF-8 text"},
+		{name: "missing file", cfg: cfg, args: map[string]interface{}{TextURIParam: filepath.Join(dir, "missing.txt")}, errContains: "failed to read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.InputText(t.Context(), tt.args)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
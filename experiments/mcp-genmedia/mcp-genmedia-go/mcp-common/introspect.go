@@ -0,0 +1,58 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// IntrospectToolName is the name of the tool registered by AddIntrospectTool.
+const IntrospectToolName = "introspect"
+
+// introspectResult is the result of the introspect tool. Each tool is serialized as in the MCP
+// tools/list response, including its full JSON input schema.
+type introspectResult struct {
+	Tools []mcp.Tool `json:"tools"`
+}
+
+// AddIntrospectTool registers the 'introspect' tool, which returns the JSON schema of every tool
+// registered on the server so that clients can generate UIs dynamically. The tool list is read at
+// call time, so it reflects tools registered after this call and those disabled by configuration.
+func AddIntrospectTool(s *server.MCPServer, cfg *Config) {
+	tool := mcp.NewTool(IntrospectToolName,
+		mcp.WithDescription("Returns the full JSON schema of the parameters of every tool on this server, for building client UIs dynamically."),
+		mcp.WithString("tool_name",
+			mcp.Description("Optional. Only return the schema of this tool."),
+		),
+	)
+	AddTool(s, cfg, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return introspectHandler(s, request)
+	})
+}
+
+func introspectHandler(s *server.MCPServer, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	toolName := request.GetString("tool_name", "")
+
+	result := introspectResult{Tools: []mcp.Tool{}}
+	for name, serverTool := range s.ListTools() {
+		if toolName == "" || name == toolName {
+			result.Tools = append(result.Tools, serverTool.Tool)
+		}
+	}
+	if toolName != "" && len(result.Tools) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Tool '%s' is not registered on this server.", toolName)), nil
+	}
+	sort.Slice(result.Tools, func(i, j int) bool { return result.Tools[i].Name < result.Tools[j].Name })
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal tool schemas: %v", err)), nil
+	}
+	return mcp.NewToolResultStructured(result, string(data)), nil
+}
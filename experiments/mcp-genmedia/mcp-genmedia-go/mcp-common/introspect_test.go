@@ -0,0 +1,77 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestIntrospectTool(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	noop := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(""), nil
+	}
+	AddIntrospectTool(s, &Config{})
+	AddTool(s, &Config{}, mcp.NewTool("generate",
+		mcp.WithString("prompt", mcp.Required(), mcp.Description("The prompt.")),
+		mcp.WithNumber("count", mcp.DefaultNumber(1)),
+	), noop)
+	AddTool(s, &Config{}, mcp.NewTool("edit"), noop)
+
+	call := func(args map[string]any) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = IntrospectToolName
+		request.Params.Arguments = args
+		result, err := s.GetTool(IntrospectToolName).Handler(context.Background(), request)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		return result
+	}
+
+	result := call(nil)
+	structured, ok := result.StructuredContent.(introspectResult)
+	if !ok {
+		t.Fatalf("expected introspectResult structured content, but got %T", result.StructuredContent)
+	}
+	var names []string
+	for _, tool := range structured.Tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "edit,generate,introspect" {
+		t.Errorf("expected the tools sorted by name, but got %s", got)
+	}
+
+	text := result.Content[0].(mcp.TextContent).Text
+	var decoded struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		t.Fatalf("expected JSON text content, but got: %v", err)
+	}
+	generate := decoded.Tools[1].InputSchema
+	if generate.Properties["prompt"]["type"] != "string" || generate.Properties["count"]["default"] != float64(1) {
+		t.Errorf("expected the parameter schemas of generate, but got %v", generate.Properties)
+	}
+	if len(generate.Required) != 1 || generate.Required[0] != "prompt" {
+		t.Errorf("expected prompt to be required, but got %v", generate.Required)
+	}
+
+	filtered := call(map[string]any{"tool_name": "edit"}).StructuredContent.(introspectResult)
+	if len(filtered.Tools) != 1 || filtered.Tools[0].Name != "edit" {
+		t.Errorf("expected only the edit tool, but got %v", filtered.Tools)
+	}
+	if result := call(map[string]any{"tool_name": "missing"}); !result.IsError {
+		t.Errorf("expected an error for an unknown tool")
+	}
+}
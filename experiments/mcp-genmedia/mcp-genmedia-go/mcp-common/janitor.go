@@ -0,0 +1,92 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultOutputRetentionInterval is how often the output janitor runs if OUTPUT_RETENTION_INTERVAL is not set.
+const DefaultOutputRetentionInterval = 15 * time.Minute
+
+// outputJanitor removes files older than the retention period from the managed output
+// directories, so that long-running servers do not fill their disks with saved outputs.
+type outputJanitor struct {
+	dirs      []string
+	retention time.Duration
+	dryRun    bool
+	now       func() time.Time
+}
+
+// StartOutputJanitor starts a background janitor for the directories in OUTPUT_RETENTION_DIRS if
+// OUTPUT_RETENTION is set. It sweeps once at startup and then every OutputRetentionInterval. The
+// returned function stops the janitor.
+func StartOutputJanitor(cfg *Config) (stop func()) {
+	if cfg.OutputRetention <= 0 || len(cfg.OutputRetentionDirs) == 0 {
+		return func() {}
+	}
+	j := &outputJanitor{dirs: cfg.OutputRetentionDirs, retention: cfg.OutputRetention, dryRun: cfg.OutputRetentionDryRun, now: time.Now}
+	LogInfof("Output janitor enabled: removing files older than %v from %v every %v (dry run: %t)", j.retention, j.dirs, cfg.OutputRetentionInterval, j.dryRun)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cfg.OutputRetentionInterval)
+		defer ticker.Stop()
+		for {
+			j.sweep()
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// sweep removes, or in dry-run mode only logs, the regular files under the managed directories
+// that were last modified before the retention period. Directories and symlinks are left alone.
+// It returns the paths that were (or would have been) removed.
+func (j *outputJanitor) sweep() []string {
+	cutoff := j.now().Add(-j.retention)
+	var expired []string
+	for _, dir := range j.dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == dir {
+					return filepath.SkipDir
+				}
+				LogWarnf("Output janitor: skipping %s: %v", path, err)
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || !info.ModTime().Before(cutoff) {
+				return nil
+			}
+			if j.dryRun {
+				LogInfof("Output janitor (dry run): would remove %s (modified %s)", path, info.ModTime().Format(time.RFC3339))
+				expired = append(expired, path)
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				LogWarnf("Output janitor: failed to remove %s: %v", path, err)
+				return nil
+			}
+			expired = append(expired, path)
+			return nil
+		})
+		if err != nil {
+			LogWarnf("Output janitor: failed to scan %s: %v", dir, err)
+		}
+	}
+	if len(expired) > 0 && !j.dryRun {
+		LogInfof("Output janitor: removed %d file(s) older than %v", len(expired), j.retention)
+	}
+	return expired
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOutputJanitorSweep(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	files := map[string]time.Duration{
+		"old.wav":        48 * time.Hour,
+		"nested/old.mp4": 25 * time.Hour,
+		"new.wav":        time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantExpired := []string{filepath.Join(dir, "nested/old.mp4"), filepath.Join(dir, "old.wav")}
+
+	j := &outputJanitor{dirs: []string{dir, filepath.Join(dir, "missing")}, retention: 24 * time.Hour, dryRun: true, now: func() time.Time { return now }}
+	if got := j.sweep(); !slices.Equal(got, wantExpired) {
+		t.Errorf("expected a dry run to report %v, but got %v", wantExpired, got)
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected a dry run to keep %s, but got: %v", name, err)
+		}
+	}
+
+	j.dryRun = false
+	if got := j.sweep(); !slices.Equal(got, wantExpired) {
+		t.Errorf("expected %v to be removed, but got %v", wantExpired, got)
+	}
+	for name, age := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if expired := age > j.retention; expired != os.IsNotExist(err) {
+			t.Errorf("expected %s removed to be %t, but got stat error %v", name, expired, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested")); err != nil {
+		t.Errorf("expected directories to be kept, but got: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// LogFormatText keeps the standard log package output, filtered by level.
+	LogFormatText = "text"
+	// LogFormatJSON writes one JSON object per log line.
+	LogFormatJSON = "json"
+)
+
+// logLinePrefix matches the date/time and file:line prefixes added by the log package.
+var logLinePrefix = regexp.MustCompile(`^(?:\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? )?(?:(\S+\.go:\d+): )?`)
+
+// ParseLogLevel parses a LOG_LEVEL value: debug, info, warn (or warning), or error.
+func ParseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unsupported log level %q", value)
+}
+
+// slogLevelPrefix matches the level that slog's default handler writes in front of each
+// message when it logs through the log package, as it does with the text format.
+var slogLevelPrefix = regexp.MustCompile(`^(DEBUG|INFO|WARN|ERROR) `)
+
+var (
+	// logLevel is the minimum level of the messages of LogDebugf, LogInfof, LogWarnf, and
+	// LogErrorf, and of the log package. It is set by ConfigureLogging.
+	logLevel slog.LevelVar
+	// logOutput is where the log helpers write with the text format.
+	logOutput io.Writer = os.Stderr
+	// logHandler receives the messages of the log helpers with the JSON format. It is nil with
+	// the text format.
+	logHandler slog.Handler
+)
+
+// LogDebugf logs a debug message, formatted like fmt.Sprintf.
+func LogDebugf(format string, args ...any) { logAt(slog.LevelDebug, format, args...) }
+
+// LogInfof logs an informational message, formatted like fmt.Sprintf.
+func LogInfof(format string, args ...any) { logAt(slog.LevelInfo, format, args...) }
+
+// LogWarnf logs a warning, formatted like fmt.Sprintf.
+func LogWarnf(format string, args ...any) { logAt(slog.LevelWarn, format, args...) }
+
+// LogErrorf logs an error, formatted like fmt.Sprintf.
+func LogErrorf(format string, args ...any) { logAt(slog.LevelError, format, args...) }
+
+// LogFatalf logs an error, formatted like fmt.Sprintf, and exits with status 1.
+func LogFatalf(format string, args ...any) {
+	logAt(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+// logAt logs a message of the given level if LOG_LEVEL enables it: with the text format as the
+// log package would, with the flags and prefix of its standard logger, and with the JSON format
+// as a record of the slog handler. It must be called directly by the exported helpers, so that
+// the source of the message is their caller.
+func logAt(level slog.Level, format string, args ...any) {
+	if level < logLevel.Level() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if logHandler == nil {
+		// Skip logAt and the helper.
+		_ = log.New(logOutput, log.Prefix(), log.Flags()).Output(3, msg)
+		return
+	}
+	record := slog.NewRecord(time.Now(), level, msg, 0)
+	var pcs [1]uintptr
+	// Skip runtime.Callers, logAt, and the helper.
+	if runtime.Callers(3, pcs[:]) == 1 {
+		frame, _ := runtime.CallersFrames(pcs[:]).Next()
+		record.AddAttrs(slog.String("source", fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)))
+	}
+	_ = logHandler.Handle(context.Background(), record)
+}
+
+// levelWriter is installed as the output of the log package, which the servers' dependencies
+// and slog's default handler write to. Lines of slog's default handler keep their level; other
+// lines are informational. Lines below LOG_LEVEL are dropped, and the others are either passed
+// through unchanged (text format) or re-emitted as records of the slog handler (JSON format).
+type levelWriter struct {
+	out     io.Writer
+	level   slog.Level
+	handler slog.Handler // Nil for the text format.
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	prefix := logLinePrefix.FindStringSubmatch(line)
+	msg := line[len(prefix[0]):]
+	level := slog.LevelInfo
+	if m := slogLevelPrefix.FindStringSubmatch(msg); m != nil {
+		_ = level.UnmarshalText([]byte(m[1]))
+	}
+	if level < w.level {
+		return len(p), nil
+	}
+	if w.handler == nil {
+		return w.out.Write(p)
+	}
+	record := slog.NewRecord(time.Now(), level, msg, 0)
+	if prefix[1] != "" {
+		record.AddAttrs(slog.String("source", prefix[1]))
+	}
+	if err := w.handler.Handle(context.Background(), record); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newLevelWriter returns the log package output for the given level and format.
+func newLevelWriter(out io.Writer, level slog.Level, format string) *levelWriter {
+	w := &levelWriter{out: out, level: level}
+	if format == LogFormatJSON {
+		w.handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})
+	}
+	return w
+}
+
+// ConfigureLogging applies LOG_LEVEL and LOG_FORMAT to the log and slog packages. Logs are
+// written to stderr, as stdout carries the MCP messages of the stdio transport.
+// With the JSON format, slog is set up to write JSON as well.
+func ConfigureLogging(level slog.Level, format string) {
+	w := newLevelWriter(os.Stderr, level, format)
+	logLevel.Set(level)
+	logOutput, logHandler = w.out, w.handler
+	if w.handler != nil {
+		// SetDefault redirects the log package to slog, so the log output is set afterwards.
+		slog.SetDefault(slog.New(w.handler))
+		log.SetFlags(log.Lshortfile)
+	} else {
+		slog.SetLogLoggerLevel(level)
+	}
+	log.SetOutput(w)
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"Warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogLevel(%q): expected error %t, but got %v", tt.value, tt.wantErr, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLogLevel(%q): expected %v, but got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+// setLogHelpers points the log helpers at the given output for the duration of the test.
+func setLogHelpers(t *testing.T, out io.Writer, level slog.Level, format string) {
+	t.Helper()
+	oldLevel, oldOutput, oldHandler := logLevel.Level(), logOutput, logHandler
+	t.Cleanup(func() {
+		logLevel.Set(oldLevel)
+		logOutput, logHandler = oldOutput, oldHandler
+	})
+	w := newLevelWriter(out, level, format)
+	logLevel.Set(level)
+	logOutput, logHandler = w.out, w.handler
+}
+
+func TestLogHelpersText(t *testing.T) {
+	var buf bytes.Buffer
+	setLogHelpers(t, &buf, slog.LevelWarn, LogFormatText)
+	LogInfof("Handling %s request", "veo_t2v")
+	LogWarnf("Slow response after %ds", 30)
+
+	out := buf.String()
+	if strings.Contains(out, "Handling veo_t2v request") {
+		t.Errorf("expected info message to be filtered, but got %q", out)
+	}
+	if !strings.Contains(out, "Slow response after 30s") {
+		t.Errorf("expected the warning, but got %q", out)
+	}
+}
+
+func TestLogHelpersJSON(t *testing.T) {
+	var buf bytes.Buffer
+	setLogHelpers(t, &buf, slog.LevelDebug, LogFormatJSON)
+	LogDebugf("Polled operation %s: not done yet.", "op-1")
+	LogErrorf("Error writing file %s", "out.wav")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, but got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry["level"] != "DEBUG" {
+		t.Errorf("expected a DEBUG entry, but got %q", lines[0])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("expected a JSON line, but got %q: %v", lines[1], err)
+	}
+	if entry["level"] != "ERROR" || entry["msg"] != "Error writing file out.wav" {
+		t.Errorf("expected an ERROR entry with the message, but got %v", entry)
+	}
+	if source, _ := entry["source"].(string); !strings.HasPrefix(source, "logging_test.go:") {
+		t.Errorf("expected the source file, but got %v", entry["source"])
+	}
+}
+
+func TestLevelWriterText(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(newLevelWriter(&buf, slog.LevelWarn, LogFormatText), "", log.LstdFlags|log.Lshortfile)
+	logger.Print("Handling request")
+	logger.Print("WARN slow response")
+
+	out := buf.String()
+	if strings.Contains(out, "Handling request") {
+		t.Errorf("expected info message to be filtered, but got %q", out)
+	}
+	if !strings.Contains(out, "logging_test.go") || !strings.Contains(out, "WARN slow response") {
+		t.Errorf("expected warning with the log prefix, but got %q", out)
+	}
+}
+
+func TestLevelWriterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(newLevelWriter(&buf, slog.LevelInfo, LogFormatJSON), "", log.Lshortfile)
+	logger.Print("DEBUG request payload")
+	logger.Print("Error writing file out.wav")
+	logger.Print("ERROR upload failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, but got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("expected a JSON line, but got %q: %v", lines[0], err)
+	}
+	if entry["level"] != "INFO" || entry["msg"] != "Error writing file out.wav" {
+		t.Errorf("expected an INFO entry with the message, but got %v", entry)
+	}
+	if source, _ := entry["source"].(string); !strings.HasPrefix(source, "logging_test.go:") {
+		t.Errorf("expected the source file, but got %v", entry["source"])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry["level"] != "ERROR" {
+		t.Errorf("expected an ERROR entry, but got %q", lines[1])
+	}
+}
@@ -0,0 +1,207 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// modelDefinitions is the shape of the file named by MODEL_DEFINITIONS_FILE. Each family maps
+// canonical model names to model info objects with the same fields as the built-in definitions,
+// e.g. {"veo": {"veo-4.0-generate-001": {"Aliases": ["Veo 4"], "SupportedDurations": [4, 8], ...}}}.
+type modelDefinitions struct {
+	Imagen      map[string]json.RawMessage `json:"imagen"`
+	GeminiImage map[string]json.RawMessage `json:"gemini_image"`
+	Veo         map[string]json.RawMessage `json:"veo"`
+	Lyria       map[string]json.RawMessage `json:"lyria"`
+}
+
+// LoadModelDefinitions merges the model definitions in the JSON file at path over the built-in
+// supported models, so that new models can be registered without rebuilding the servers. A
+// definition for a built-in model only overrides the fields it sets; a new model must set all
+// required fields. The file is validated as a whole, and nothing is changed if it is invalid.
+func LoadModelDefinitions(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var defs modelDefinitions
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&defs); err != nil {
+		return fmt.Errorf("invalid model definitions (the top-level keys are imagen, gemini_image, veo, and lyria): %w", err)
+	}
+
+	imagen, errImagen := mergeModelDefinitions("imagen", SupportedImagenModels, defs.Imagen, validateImagenModel)
+	geminiImage, errGemini := mergeModelDefinitions("gemini_image", SupportedGeminiImageModels, defs.GeminiImage, validateGeminiImageModel)
+	veo, errVeo := mergeModelDefinitions("veo", SupportedVeoModels, defs.Veo, validateVeoModel)
+	lyria, errLyria := mergeModelDefinitions("lyria", SupportedLyriaModels, defs.Lyria, validateLyriaModel)
+	if err := errors.Join(errImagen, errGemini, errVeo, errLyria); err != nil {
+		return err
+	}
+
+	imagenAliases, errImagen := buildAliasMap("imagen", imagen, func(m ImagenModelInfo) []string { return m.Aliases })
+	geminiImageAliases, errGemini := buildAliasMap("gemini_image", geminiImage, func(m GeminiImageModelInfo) []string { return m.Aliases })
+	veoAliases, errVeo := buildAliasMap("veo", veo, func(m VeoModelInfo) []string { return m.Aliases })
+	_, errLyria = buildAliasMap("lyria", lyria, func(m LyriaModelInfo) []string { return m.Aliases })
+	if err := errors.Join(errImagen, errGemini, errVeo, errLyria); err != nil {
+		return err
+	}
+
+	SupportedImagenModels, imagenAliasMap = imagen, imagenAliases
+	SupportedGeminiImageModels, geminiImageAliasMap = geminiImage, geminiImageAliases
+	SupportedVeoModels, veoAliasMap = veo, veoAliases
+	SupportedLyriaModels = lyria
+	return nil
+}
+
+// mergeModelDefinitions returns a copy of builtin with the definitions decoded over it. Each
+// definition is decoded onto a deep copy of the built-in info for that name, if any, so that a
+// rejected definition leaves the built-in slices untouched, and then validated; an empty
+// CanonicalName is set to the name the definition is keyed by.
+func mergeModelDefinitions[T interface{ clone() T }](family string, builtin map[string]T, defs map[string]json.RawMessage, validate func(name string, info *T) error) (map[string]T, error) {
+	merged := maps.Clone(builtin)
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		info := merged[name].clone()
+		decoder := json.NewDecoder(bytes.NewReader(defs[name]))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&info); err != nil {
+			errs = append(errs, fmt.Errorf("%s model %q: %w", family, name, err))
+			continue
+		}
+		if err := validate(name, &info); err != nil {
+			errs = append(errs, fmt.Errorf("%s model %q: %w", family, name, err))
+			continue
+		}
+		merged[name] = info
+	}
+	return merged, errors.Join(errs...)
+}
+
+// clone returns a copy of the model info that shares no slices with it.
+func (m ImagenModelInfo) clone() ImagenModelInfo {
+	m.Aliases = slices.Clone(m.Aliases)
+	m.SupportedAspectRatios = slices.Clone(m.SupportedAspectRatios)
+	m.SupportedImageSizes = slices.Clone(m.SupportedImageSizes)
+	return m
+}
+
+// clone returns a copy of the model info that shares no slices with it.
+func (m GeminiImageModelInfo) clone() GeminiImageModelInfo {
+	m.Aliases = slices.Clone(m.Aliases)
+	m.SupportedAspectRatios = slices.Clone(m.SupportedAspectRatios)
+	return m
+}
+
+// clone returns a copy of the model info that shares no slices with it.
+func (m VeoModelInfo) clone() VeoModelInfo {
+	m.Aliases = slices.Clone(m.Aliases)
+	m.SupportedDurations = slices.Clone(m.SupportedDurations)
+	m.SupportedAspectRatios = slices.Clone(m.SupportedAspectRatios)
+	m.SupportedFPS = slices.Clone(m.SupportedFPS)
+	return m
+}
+
+// clone returns a copy of the model info that shares no slices with it.
+func (m LyriaModelInfo) clone() LyriaModelInfo {
+	m.Aliases = slices.Clone(m.Aliases)
+	return m
+}
+
+// buildAliasMap maps the lowercased canonical names and aliases of the models to their canonical
+// names. A name or alias that would resolve to more than one model is an error, since the model
+// it resolves to would depend on map iteration order.
+func buildAliasMap[T any](family string, models map[string]T, aliases func(T) []string) (map[string]string, error) {
+	aliasMap := make(map[string]string)
+	var errs []error
+	add := func(name, canonicalName string) {
+		key := strings.ToLower(name)
+		if other, ok := aliasMap[key]; ok && other != canonicalName {
+			errs = append(errs, fmt.Errorf("%s model %q: name or alias %q is already used by %q", family, canonicalName, name, other))
+			return
+		}
+		aliasMap[key] = canonicalName
+	}
+	canonicalNames := slices.Sorted(maps.Keys(models))
+	for _, canonicalName := range canonicalNames {
+		add(canonicalName, canonicalName)
+	}
+	for _, canonicalName := range canonicalNames {
+		for _, alias := range aliases(models[canonicalName]) {
+			add(alias, canonicalName)
+		}
+	}
+	return aliasMap, errors.Join(errs...)
+}
+
+// checkCanonicalName defaults the canonical name of a model definition to the name it is keyed by,
+// and rejects a definition whose canonical name differs from it.
+func checkCanonicalName(name string, canonicalName *string) error {
+	if *canonicalName == "" {
+		*canonicalName = name
+	}
+	if *canonicalName != name {
+		return fmt.Errorf("CanonicalName %q does not match the model name", *canonicalName)
+	}
+	return nil
+}
+
+func validateImagenModel(name string, info *ImagenModelInfo) error {
+	if err := checkCanonicalName(name, &info.CanonicalName); err != nil {
+		return err
+	}
+	if info.MaxImages < 1 {
+		return fmt.Errorf("MaxImages must be at least 1, got %d", info.MaxImages)
+	}
+	if len(info.SupportedAspectRatios) == 0 {
+		return fmt.Errorf("SupportedAspectRatios must not be empty")
+	}
+	return nil
+}
+
+func validateGeminiImageModel(name string, info *GeminiImageModelInfo) error {
+	if err := checkCanonicalName(name, &info.CanonicalName); err != nil {
+		return err
+	}
+	if len(info.SupportedAspectRatios) == 0 {
+		return fmt.Errorf("SupportedAspectRatios must not be empty")
+	}
+	return nil
+}
+
+func validateVeoModel(name string, info *VeoModelInfo) error {
+	if err := checkCanonicalName(name, &info.CanonicalName); err != nil {
+		return err
+	}
+	if len(info.SupportedDurations) == 0 {
+		return fmt.Errorf("SupportedDurations must not be empty")
+	}
+	if !info.SupportsDuration(info.DefaultDuration) {
+		return fmt.Errorf("DefaultDuration %d is not one of the SupportedDurations %v", info.DefaultDuration, info.SupportedDurations)
+	}
+	if info.MaxVideos < 1 {
+		return fmt.Errorf("MaxVideos must be at least 1, got %d", info.MaxVideos)
+	}
+	if len(info.SupportedAspectRatios) == 0 {
+		return fmt.Errorf("SupportedAspectRatios must not be empty")
+	}
+	return nil
+}
+
+func validateLyriaModel(name string, info *LyriaModelInfo) error {
+	if err := checkCanonicalName(name, &info.CanonicalName); err != nil {
+		return err
+	}
+	if info.EndpointType != "prediction" && info.EndpointType != "interactions" {
+		return fmt.Errorf("EndpointType must be prediction or interactions, got %q", info.EndpointType)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// restoreModels restores the built-in model maps after a test that loads model definitions.
+func restoreModels(t *testing.T) {
+	imagen, imagenAliases := maps.Clone(SupportedImagenModels), maps.Clone(imagenAliasMap)
+	geminiImage, geminiImageAliases := maps.Clone(SupportedGeminiImageModels), maps.Clone(geminiImageAliasMap)
+	veo, veoAliases := maps.Clone(SupportedVeoModels), maps.Clone(veoAliasMap)
+	lyria := maps.Clone(SupportedLyriaModels)
+	t.Cleanup(func() {
+		SupportedImagenModels, imagenAliasMap = imagen, imagenAliases
+		SupportedGeminiImageModels, geminiImageAliasMap = geminiImage, geminiImageAliases
+		SupportedVeoModels, veoAliasMap = veo, veoAliases
+		SupportedLyriaModels = lyria
+	})
+}
+
+func writeModelDefinitions(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write model definitions: %v", err)
+	}
+	return path
+}
+
+func TestLoadModelDefinitions(t *testing.T) {
+	restoreModels(t)
+	path := writeModelDefinitions(t, `{
+		"veo": {
+			"veo-9.0-generate-001": {
+				"Aliases": ["Veo 9"],
+				"DefaultDuration": 8,
+				"SupportedDurations": [4, 8],
+				"MaxVideos": 2,
+				"SupportedAspectRatios": ["16:9"],
+				"SupportsGenerateAudio": true
+			},
+			"veo-2.0-generate-001": {"MaxVideos": 1}
+		}
+	}`)
+	if err := LoadModelDefinitions(path); err != nil {
+		t.Fatalf("LoadModelDefinitions() error = %v", err)
+	}
+
+	info, ok := ResolveVeoModel("veo 9", false)
+	if !ok {
+		t.Fatalf("expected the new model to resolve by alias")
+	}
+	if info.CanonicalName != "veo-9.0-generate-001" || info.MaxVideos != 2 || !info.SupportsGenerateAudio {
+		t.Errorf("unexpected model info: %+v", info)
+	}
+	if !strings.Contains(BuildVeoModelDescription(), "veo-9.0-generate-001") {
+		t.Errorf("expected the new model to be listed in the model description")
+	}
+
+	overridden := SupportedVeoModels["veo-2.0-generate-001"]
+	if overridden.MaxVideos != 1 {
+		t.Errorf("expected MaxVideos to be overridden to 1, got %d", overridden.MaxVideos)
+	}
+	if overridden.DefaultDuration != 8 || len(overridden.Aliases) == 0 {
+		t.Errorf("expected the fields not set in the file to be kept, got %+v", overridden)
+	}
+	if _, ok := ResolveVeoModel("Veo 2", false); !ok {
+		t.Errorf("expected the built-in aliases to keep resolving")
+	}
+}
+
+func TestLoadModelDefinitionsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"malformed JSON", `{"veo": `, "invalid model definitions"},
+		{"unknown family", `{"sora": {}}`, "invalid model definitions"},
+		{"unknown field", `{"lyria": {"lyria-002": {"Tempo": 120}}}`, `lyria model "lyria-002"`},
+		{"missing required field", `{"veo": {"veo-9.0-generate-001": {"MaxVideos": 1, "SupportedAspectRatios": ["16:9"]}}}`, "SupportedDurations must not be empty"},
+		{"mismatched canonical name", `{"imagen": {"imagen-9": {"CanonicalName": "imagen-8", "MaxImages": 1, "SupportedAspectRatios": ["1:1"]}}}`, "does not match the model name"},
+		{"bad endpoint type", `{"lyria": {"lyria-9": {"EndpointType": "stream"}}}`, "EndpointType must be prediction or interactions"},
+		{"alias used by another model", `{"veo": {"veo-2.0-generate-001": {"Aliases": ["Veo 3.1"]}}}`, `alias "Veo 3.1" is already used`},
+		{"alias matching another canonical name", `{"imagen": {"imagen-4.0-generate-001": {"Aliases": ["IMAGEN-3.0-GENERATE-002"]}}}`, `is already used by "imagen-3.0-generate-002"`},
+		{"alias used by a new model", `{"lyria": {"lyria-9": {"EndpointType": "interactions", "Aliases": ["Lyria 2"]}}}`, `lyria model "lyria-9"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreModels(t)
+			before := len(SupportedVeoModels)
+			err := LoadModelDefinitions(writeModelDefinitions(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadModelDefinitions() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if len(SupportedVeoModels) != before {
+				t.Errorf("expected the models to be unchanged after an invalid file")
+			}
+		})
+	}
+}
+
+func TestBuiltinModelNamesAreUnique(t *testing.T) {
+	_, errImagen := buildAliasMap("imagen", SupportedImagenModels, func(m ImagenModelInfo) []string { return m.Aliases })
+	_, errGemini := buildAliasMap("gemini_image", SupportedGeminiImageModels, func(m GeminiImageModelInfo) []string { return m.Aliases })
+	_, errVeo := buildAliasMap("veo", SupportedVeoModels, func(m VeoModelInfo) []string { return m.Aliases })
+	_, errLyria := buildAliasMap("lyria", SupportedLyriaModels, func(m LyriaModelInfo) []string { return m.Aliases })
+	if err := errors.Join(errImagen, errGemini, errVeo, errLyria); err != nil {
+		t.Errorf("expected the built-in model names and aliases to be unique, but got %v", err)
+	}
+}
+
+func TestLoadModelDefinitionsMissingFile(t *testing.T) {
+	if err := LoadModelDefinitions(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestLoadModelDefinitionsInvalidKeepsBuiltins(t *testing.T) {
+	restoreModels(t)
+	want := SupportedVeoModels["veo-2.0-generate-001"].clone()
+	// DefaultDuration 8 is not one of the new durations, so the definition is rejected after
+	// the durations have been decoded.
+	path := writeModelDefinitions(t, `{"veo": {"veo-2.0-generate-001": {"SupportedDurations": [4], "Aliases": ["Veo Two"]}}}`)
+	if err := LoadModelDefinitions(path); err == nil {
+		t.Fatalf("expected an error for a default duration that is not supported")
+	}
+	if got := SupportedVeoModels["veo-2.0-generate-001"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the built-in model to be unchanged after an invalid file, got %+v, want %+v", got, want)
+	}
+}
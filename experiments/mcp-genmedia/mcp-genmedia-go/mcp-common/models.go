@@ -18,6 +18,7 @@ package common
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -110,7 +111,46 @@ func ResolveImagenModel(modelInput string, allowUnsafe bool) (ImagenModelInfo, b
 	return ImagenModelInfo{}, false
 }
 
-// BuildImagenModelDescription generates a formatted string for the tool description.
+// sortedStrings returns a sorted copy of values, so that a description does not depend on the
+// order in which a model's values were defined.
+func sortedStrings(values []string) []string {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted
+}
+
+// sortedAspectRatios returns a copy of the W:H aspect ratios sorted from the tallest to the
+// widest. Ties and ratios that cannot be parsed are ordered by name, the latter last.
+func sortedAspectRatios(ratios []string) []string {
+	value := func(ratio string) (float64, bool) {
+		var w, h float64
+		if _, err := fmt.Sscanf(ratio, "%g:%g", &w, &h); err != nil || w <= 0 || h <= 0 {
+			return 0, false
+		}
+		return w / h, true
+	}
+	sorted := slices.Clone(ratios)
+	slices.SortFunc(sorted, func(a, b string) int {
+		va, okA := value(a)
+		vb, okB := value(b)
+		switch {
+		case okA != okB:
+			if okA {
+				return -1
+			}
+			return 1
+		case va < vb:
+			return -1
+		case va > vb:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return sorted
+}
+
+// BuildImagenModelDescription generates a formatted string for the tool description. Models and
+// their values are sorted, so the description is the same on every call.
 func BuildImagenModelDescription() string {
 	var sb strings.Builder
 	sb.WriteString("Model for image generation. Can be a full model ID or a common name. Supported models:\n")
@@ -122,13 +162,13 @@ func BuildImagenModelDescription() string {
 
 	for _, name := range sortedNames {
 		info := SupportedImagenModels[name]
-		baseInfo := fmt.Sprintf("- *%s* (Max Images: %d, Ratios: %s)", info.CanonicalName, info.MaxImages, strings.Join(info.SupportedAspectRatios, ", "))
+		baseInfo := fmt.Sprintf("- *%s* (Max Images: %d, Ratios: %s)", info.CanonicalName, info.MaxImages, strings.Join(sortedAspectRatios(info.SupportedAspectRatios), ", "))
 		sb.WriteString(baseInfo)
 		if len(info.SupportedImageSizes) > 0 {
-			fmt.Fprintf(&sb, " (Sizes: %s)", strings.Join(info.SupportedImageSizes, ", "))
+			fmt.Fprintf(&sb, " (Sizes: %s)", strings.Join(sortedStrings(info.SupportedImageSizes), ", "))
 		}
 		if len(info.Aliases) > 0 {
-			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(info.Aliases, "*, *"))
+			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(sortedStrings(info.Aliases), "*, *"))
 		}
 		sb.WriteString("\n")
 	}
@@ -215,9 +255,9 @@ func BuildGeminiImageModelDescription() string {
 
 	for _, name := range sortedNames {
 		info := SupportedGeminiImageModels[name]
-		fmt.Fprintf(&sb, "- *%s* (Ratios: %s)", info.CanonicalName, strings.Join(info.SupportedAspectRatios, ", "))
+		fmt.Fprintf(&sb, "- *%s* (Ratios: %s)", info.CanonicalName, strings.Join(sortedAspectRatios(info.SupportedAspectRatios), ", "))
 		if len(info.Aliases) > 0 {
-			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(info.Aliases, "*, *"))
+			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(sortedStrings(info.Aliases), "*, *"))
 		}
 		if info.Description != "" {
 			fmt.Fprintf(&sb, " - %s", info.Description)
@@ -241,6 +281,7 @@ type VeoModelInfo struct {
 	SupportsFirstLast      bool
 	SupportsReferenceImage bool
 	SupportsExtend         bool
+	SupportedFPS           []int32 // Frame rates accepted by the fps setting; empty if the model does not accept one.
 }
 
 // SupportedVeoModels is the single source of truth for all supported Veo models.
@@ -288,6 +329,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      false,
 		SupportsReferenceImage: false,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.0-fast-generate-001": {
 		CanonicalName:          "veo-3.0-fast-generate-001",
@@ -299,6 +341,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      false,
 		SupportsReferenceImage: false,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.1-generate-001": {
 		CanonicalName:          "veo-3.1-generate-001",
@@ -310,6 +353,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      true,
 		SupportsReferenceImage: false,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.1-fast-generate-001": {
 		CanonicalName:          "veo-3.1-fast-generate-001",
@@ -321,6 +365,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      true,
 		SupportsReferenceImage: false,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.1-generate-preview": {
 		CanonicalName:          "veo-3.1-generate-preview",
@@ -332,6 +377,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      true,
 		SupportsReferenceImage: true,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.1-fast-generate-preview": {
 		CanonicalName:          "veo-3.1-fast-generate-preview",
@@ -343,6 +389,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsGenerateAudio:  true,
 		SupportsFirstLast:      true,
 		SupportsReferenceImage: true,
+		SupportedFPS:           []int32{24},
 	},
 	"veo-3.1-lite-generate-001": {
 		CanonicalName:          "veo-3.1-lite-generate-001",
@@ -355,6 +402,7 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 		SupportsFirstLast:      true,
 		SupportsReferenceImage: false,
 		SupportsExtend:         true,
+		SupportedFPS:           []int32{24},
 	},
 }
 
@@ -388,13 +436,93 @@ func ResolveVeoModel(modelInput string, allowUnsafe bool) (VeoModelInfo, bool) {
 			SupportsFirstLast:      true,
 			SupportsReferenceImage: true,
 			SupportsExtend:         true,
+			SupportedFPS:           []int32{24, 30},
 		}, true
 	}
 
 	return VeoModelInfo{}, false
 }
 
-// BuildVeoModelDescription generates a formatted string for the tool description.
+// SupportsAspectRatio reports whether the model accepts the given aspect ratio.
+func (m VeoModelInfo) SupportsAspectRatio(ratio string) bool {
+	return slices.Contains(m.SupportedAspectRatios, ratio)
+}
+
+// SupportsDuration reports whether the model accepts the given duration in seconds.
+func (m VeoModelInfo) SupportsDuration(durationSecs int32) bool {
+	return slices.Contains(m.SupportedDurations, durationSecs)
+}
+
+// SupportsFPS reports whether the model accepts the given frame rate.
+func (m VeoModelInfo) SupportsFPS(fps int32) bool {
+	return slices.Contains(m.SupportedFPS, fps)
+}
+
+// DefaultAspectRatio returns the model's preferred aspect ratio, falling back to 16:9.
+func (m VeoModelInfo) DefaultAspectRatio() string {
+	if len(m.SupportedAspectRatios) > 0 {
+		return m.SupportedAspectRatios[0]
+	}
+	return "16:9"
+}
+
+// lookupVeoModel resolves a canonical Veo model name or alias, returning an error if it is unknown.
+// Unknown model names are accepted with permissive capabilities if ALLOW_UNSAFE_MODELS is set.
+func (c *Config) lookupVeoModel(model string) (VeoModelInfo, error) {
+	info, found := ResolveVeoModel(model, c != nil && c.AllowUnsafeModels)
+	if !found {
+		return VeoModelInfo{}, fmt.Errorf("model '%s' is not a valid or supported Veo model name", model)
+	}
+	return info, nil
+}
+
+// ModelSupportsAspectRatio reports whether the named Veo model accepts the given aspect ratio.
+func (c *Config) ModelSupportsAspectRatio(model, ratio string) (bool, error) {
+	info, err := c.lookupVeoModel(model)
+	if err != nil {
+		return false, err
+	}
+	return info.SupportsAspectRatio(ratio), nil
+}
+
+// ModelSupportsDuration reports whether the named Veo model accepts the given duration in seconds.
+func (c *Config) ModelSupportsDuration(model string, durationSecs int32) (bool, error) {
+	info, err := c.lookupVeoModel(model)
+	if err != nil {
+		return false, err
+	}
+	return info.SupportsDuration(durationSecs), nil
+}
+
+// ModelSupportsFPS reports whether the named Veo model accepts the given frame rate.
+func (c *Config) ModelSupportsFPS(model string, fps int32) (bool, error) {
+	info, err := c.lookupVeoModel(model)
+	if err != nil {
+		return false, err
+	}
+	return info.SupportsFPS(fps), nil
+}
+
+// ModelSupportsAudio reports whether the named Veo model can generate audio.
+func (c *Config) ModelSupportsAudio(model string) (bool, error) {
+	info, err := c.lookupVeoModel(model)
+	if err != nil {
+		return false, err
+	}
+	return info.SupportsGenerateAudio, nil
+}
+
+// MaxVideosFor returns the maximum number of videos the named Veo model can generate per request.
+func (c *Config) MaxVideosFor(model string) (int32, error) {
+	info, err := c.lookupVeoModel(model)
+	if err != nil {
+		return 0, err
+	}
+	return info.MaxVideos, nil
+}
+
+// BuildVeoModelDescription generates a formatted string for the tool description. Like
+// BuildImagenModelDescription, it sorts the models and their values.
 func BuildVeoModelDescription() string {
 	var sb strings.Builder
 	sb.WriteString("Model for video generation. Can be a full model ID or a common name. Supported models:\n")
@@ -406,14 +534,16 @@ func BuildVeoModelDescription() string {
 
 	for _, name := range sortedNames {
 		info := SupportedVeoModels[name]
-		durationsStr := make([]string, len(info.SupportedDurations))
-		for i, d := range info.SupportedDurations {
+		durations := slices.Clone(info.SupportedDurations)
+		slices.Sort(durations)
+		durationsStr := make([]string, len(durations))
+		for i, d := range durations {
 			durationsStr[i] = fmt.Sprintf("%d", d)
 		}
 		fmt.Fprintf(&sb, "- *%s* (Durations: [%s]s, Max Videos: %d, Ratios: %s)",
-			info.CanonicalName, strings.Join(durationsStr, ", "), info.MaxVideos, strings.Join(info.SupportedAspectRatios, ", "))
+			info.CanonicalName, strings.Join(durationsStr, ", "), info.MaxVideos, strings.Join(sortedAspectRatios(info.SupportedAspectRatios), ", "))
 		if len(info.Aliases) > 0 {
-			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(info.Aliases, "*, *"))
+			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(sortedStrings(info.Aliases), "*, *"))
 		}
 		sb.WriteString("\n")
 	}
@@ -494,7 +624,7 @@ func BuildLyriaModelDescription() string {
 		info := SupportedLyriaModels[k]
 		fmt.Fprintf(&sb, "- *%s*", info.CanonicalName)
 		if len(info.Aliases) > 0 {
-			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(info.Aliases, "*, *"))
+			fmt.Fprintf(&sb, " Aliases: *%s*", strings.Join(sortedStrings(info.Aliases), "*, *"))
 		}
 		sb.WriteString("\n")
 	}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestModelDescriptionsGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		build  func() string
+	}{
+		{"imagen_models.golden", BuildImagenModelDescription},
+		{"gemini_image_models.golden", BuildGeminiImageModelDescription},
+		{"veo_models.golden", BuildVeoModelDescription},
+		{"lyria_models.golden", BuildLyriaModelDescription},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got := tt.build()
+			for range 10 {
+				if again := tt.build(); again != got {
+					t.Fatalf("repeated calls produced different descriptions:\n%s\nand\n%s", got, again)
+				}
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *updateGolden {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatalf("failed to update %s: %v", path, err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s (run 'go test -run TestModelDescriptionsGolden -update' to create it): %v", path, err)
+			}
+			if got != string(want) {
+				t.Errorf("description does not match %s (run with -update if the change is intended):\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestSortedAspectRatios(t *testing.T) {
+	got := sortedAspectRatios([]string{"16:9", "1:1", "9:16", "auto", "21:9", "3:4", "4:3", "2:3", "3:2"})
+	want := []string{"9:16", "2:3", "3:4", "1:1", "4:3", "3:2", "16:9", "21:9", "auto"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
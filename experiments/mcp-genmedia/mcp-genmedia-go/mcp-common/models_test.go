@@ -0,0 +1,116 @@
+package common
+
+import "testing"
+
+func TestModelSupportsAspectRatio(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		model   string
+		ratio   string
+		want    bool
+		wantErr bool
+	}{
+		{"veo-2.0-generate-001", "16:9", true, false},
+		{"Veo 2", "9:16", true, false},
+		{"veo-2.0-generate-001", "1:1", false, false},
+		{"not-a-model", "16:9", false, true},
+	}
+	for _, tt := range tests {
+		got, err := cfg.ModelSupportsAspectRatio(tt.model, tt.ratio)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ModelSupportsAspectRatio(%q, %q) error = %v, wantErr %v", tt.model, tt.ratio, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ModelSupportsAspectRatio(%q, %q) = %v, want %v", tt.model, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestModelSupportsDuration(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		model   string
+		dur     int32
+		want    bool
+		wantErr bool
+	}{
+		{"veo-2.0-generate-001", 8, true, false},
+		{"veo-2.0-generate-001", 4, false, false},
+		{"not-a-model", 8, false, true},
+	}
+	for _, tt := range tests {
+		got, err := cfg.ModelSupportsDuration(tt.model, tt.dur)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ModelSupportsDuration(%q, %d) error = %v, wantErr %v", tt.model, tt.dur, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ModelSupportsDuration(%q, %d) = %v, want %v", tt.model, tt.dur, got, tt.want)
+		}
+	}
+}
+
+func TestModelSupportsAudio(t *testing.T) {
+	cfg := &Config{}
+	for name, info := range SupportedVeoModels {
+		got, err := cfg.ModelSupportsAudio(name)
+		if err != nil {
+			t.Errorf("ModelSupportsAudio(%q) returned unexpected error: %v", name, err)
+		}
+		if got != info.SupportsGenerateAudio {
+			t.Errorf("ModelSupportsAudio(%q) = %v, want %v", name, got, info.SupportsGenerateAudio)
+		}
+	}
+	if _, err := cfg.ModelSupportsAudio("not-a-model"); err == nil {
+		t.Errorf("expected an error for an unknown model, but got nil")
+	}
+}
+
+func TestMaxVideosFor(t *testing.T) {
+	cfg := &Config{}
+	got, err := cfg.MaxVideosFor("Veo 2")
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if got != 4 {
+		t.Errorf("expected 4 max videos, but got %d", got)
+	}
+	if _, err := cfg.MaxVideosFor("not-a-model"); err == nil {
+		t.Errorf("expected an error for an unknown model, but got nil")
+	}
+}
+
+func TestModelSupportsFPS(t *testing.T) {
+	cfg := &Config{}
+	if got, err := cfg.ModelSupportsFPS("veo-3.0-generate-001", 24); err != nil || !got {
+		t.Errorf("expected veo-3.0-generate-001 to support 24 fps, but got %v (err: %v)", got, err)
+	}
+	if got, err := cfg.ModelSupportsFPS("veo-2.0-generate-001", 24); err != nil || got {
+		t.Errorf("expected veo-2.0-generate-001 not to support an fps, but got %v (err: %v)", got, err)
+	}
+}
+
+func TestModelCapabilitiesAllowUnsafeModels(t *testing.T) {
+	if _, err := (&Config{}).ModelSupportsAspectRatio("veo-99-experimental", "16:9"); err == nil {
+		t.Errorf("expected an error for an unknown model without ALLOW_UNSAFE_MODELS")
+	}
+	cfg := &Config{AllowUnsafeModels: true}
+	if got, err := cfg.ModelSupportsAspectRatio("veo-99-experimental", "1:1"); err != nil || !got {
+		t.Errorf("expected an unknown model to be accepted with ALLOW_UNSAFE_MODELS, but got %v (err: %v)", got, err)
+	}
+	if got, err := cfg.MaxVideosFor("veo-99-experimental"); err != nil || got < 1 {
+		t.Errorf("expected a maximum number of videos for an unknown model, but got %d (err: %v)", got, err)
+	}
+	// Known models keep their own capabilities.
+	if got, err := cfg.ModelSupportsAudio("Veo 2"); err != nil || got {
+		t.Errorf("expected Veo 2 not to generate audio, but got %v (err: %v)", got, err)
+	}
+}
+
+func TestVeoModelInfoDefaultAspectRatio(t *testing.T) {
+	if got := (VeoModelInfo{SupportedAspectRatios: []string{"9:16", "16:9"}}).DefaultAspectRatio(); got != "9:16" {
+		t.Errorf("expected 9:16, but got %s", got)
+	}
+	if got := (VeoModelInfo{}).DefaultAspectRatio(); got != "16:9" {
+		t.Errorf("expected fallback 16:9, but got %s", got)
+	}
+}
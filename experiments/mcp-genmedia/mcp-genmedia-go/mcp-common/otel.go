@@ -4,7 +4,6 @@ package common
 
 import (
 	"context"
-	"log"
 	"os"
 
 	"go.opentelemetry.io/otel"
@@ -21,7 +20,7 @@ import (
 // for distributed tracing of requests as they flow through the system.
 func InitTracerProvider(serviceName, serviceVersion string) (*sdktrace.TracerProvider, error) {
 	if os.Getenv("OTEL_ENABLED") != "true" {
-		log.Println("OpenTelemetry tracing is disabled. Set OTEL_ENABLED=true to enable.")
+		LogInfof("OpenTelemetry tracing is disabled. Set OTEL_ENABLED=true to enable.")
 		return nil, nil // Return nil to indicate that tracing is disabled.
 	}
 	ctx := context.Background()
@@ -42,14 +41,14 @@ func InitTracerProvider(serviceName, serviceVersion string) (*sdktrace.TracerPro
 
 	// Check for the standard environment variable to enable insecure mode.
 	if os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true" {
-		log.Println("WARNING: Using insecure connection for OTLP exporter")
+		LogWarnf("WARNING: Using insecure connection for OTLP exporter")
 		opts = append(opts, otlptracegrpc.WithInsecure())
 	}
 	// --- End of Recommended Logic ---
 
 	exporter, err := otlptracegrpc.New(ctx, opts...)
 	if err != nil {
-		log.Fatalf("failed to create OTLP trace exporter: %v", err)
+		LogFatalf("failed to create OTLP trace exporter: %v", err)
 	}
 
 	// Create a new tracer provider.
@@ -66,7 +65,7 @@ func InitTracerProvider(serviceName, serviceVersion string) (*sdktrace.TracerPro
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 
-	log.Printf("Tracer provider initialized for service: %s, version: %s", serviceName, serviceVersion)
+	LogInfof("Tracer provider initialized for service: %s, version: %s", serviceName, serviceVersion)
 
 	return tp, nil
 }
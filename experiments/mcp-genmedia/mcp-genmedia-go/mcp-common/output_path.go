@@ -0,0 +1,62 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultVeoOutputPathTemplate is the path under GENMEDIA_BUCKET where Veo saves the videos of
+// requests without a bucket, unless VEO_OUTPUT_PATH_TEMPLATE is set.
+const DefaultVeoOutputPathTemplate = "veo_outputs/"
+
+// OutputPathTokens are the tokens of an output path template and what they are replaced with.
+// Dates are in UTC.
+var OutputPathTokens = map[string]string{
+	"{date}":       "the date of the request, e.g. 2025-01-31",
+	"{month}":      "the month of the request, e.g. 2025-01",
+	"{year}":       "the year of the request, e.g. 2025",
+	"{model}":      "the canonical name of the model, e.g. veo-3.0-generate-001",
+	"{request_id}": "a short ID that is unique to the request",
+}
+
+var outputPathTokenPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateOutputPathTemplate checks that an output path template only uses OutputPathTokens and
+// stays inside the bucket.
+func ValidateOutputPathTemplate(template string) error {
+	for _, token := range outputPathTokenPattern.FindAllString(template, -1) {
+		if _, ok := OutputPathTokens[token]; !ok {
+			return fmt.Errorf("unknown token %s (supported: {date}, {month}, {year}, {model}, {request_id})", token)
+		}
+	}
+	for _, segment := range strings.Split(template, "/") {
+		if segment == ".." {
+			return fmt.Errorf("the path must not contain '..'")
+		}
+	}
+	return nil
+}
+
+// ExpandOutputPathTemplate replaces the OutputPathTokens of an output path template with the
+// values of a request, and returns the resulting object prefix: a clean path ending in "/", or
+// "" for the root of the bucket.
+func ExpandOutputPathTemplate(template, model, requestID string, now time.Time) string {
+	now = now.UTC()
+	expanded := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{month}", now.Format("2006-01"),
+		"{year}", now.Format("2006"),
+		"{model}", model,
+		"{request_id}", requestID,
+	).Replace(template)
+	expanded = strings.Trim(path.Clean("/"+expanded), "/")
+	if expanded == "" {
+		return ""
+	}
+	return expanded + "/"
+}
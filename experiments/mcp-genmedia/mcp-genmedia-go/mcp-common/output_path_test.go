@@ -0,0 +1,48 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandOutputPathTemplate(t *testing.T) {
+	now := time.Date(2025, time.January, 31, 23, 30, 0, 0, time.FixedZone("PST", -8*60*60))
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: DefaultVeoOutputPathTemplate, want: "veo_outputs/"},
+		{template: "veo_outputs/{month}/{model}", want: "veo_outputs/2025-02/veo-3.0-generate-001/"},
+		{template: "/teams/{year}/{date}/{request_id}/", want: "teams/2025/2025-02-01/abc123/"},
+		{template: "videos//{model}-{date}", want: "videos/veo-3.0-generate-001-2025-02-01/"},
+		{template: "/", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if got := ExpandOutputPathTemplate(tt.template, "veo-3.0-generate-001", "abc123", now); got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateOutputPathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: DefaultVeoOutputPathTemplate},
+		{template: "veo_outputs/{date}/{month}/{year}/{model}/{request_id}/"},
+		{template: "veo_outputs/{user}/", wantErr: true},
+		{template: "veo_outputs/../other/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if err := ValidateOutputPathTemplate(tt.template); (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// RawPromptParam is the name of the per-request tool parameter that opts out of the
+// configured prompt prefix and suffix.
+const RawPromptParam = "raw_prompt"
+
+// EnhancePromptParam is the name of the tool parameter that asks the model to rewrite the
+// prompt before generating.
+const EnhancePromptParam = "enhance_prompt"
+
+// MaxSystemInstructionLength is the maximum length, in characters, of a system instruction.
+const MaxSystemInstructionLength = 8000
+
+// ApplyPromptAffixes returns the prompt with the configured PROMPT_PREFIX prepended and
+// PROMPT_SUFFIX appended, so that a house style can be applied to every generation without
+// clients repeating it. Empty prompts and requests with raw set are returned unchanged.
+func ApplyPromptAffixes(cfg *Config, prompt string, raw bool) string {
+	if cfg == nil || (cfg.PromptPrefix == "" && cfg.PromptSuffix == "") || strings.TrimSpace(prompt) == "" {
+		return prompt
+	}
+	if raw {
+		LogInfof("Prompt affixes skipped by request (%s=true).", RawPromptParam)
+		return prompt
+	}
+
+	parts := make([]string, 0, 3)
+	for _, part := range []string{cfg.PromptPrefix, strings.TrimSpace(prompt), cfg.PromptSuffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	effective := strings.Join(parts, " ")
+	LogInfof("Effective prompt: %s", loggablePrompt(cfg, effective))
+	return effective
+}
+
+// loggablePrompt returns the prompt quoted for a log line, or only its length if
+// AUDIT_REDACT_PROMPTS is set.
+func loggablePrompt(cfg *Config, prompt string) string {
+	if cfg != nil && cfg.AuditRedactPrompts {
+		return fmt.Sprintf("[redacted, %d characters]", utf8.RuneCountInString(prompt))
+	}
+	return fmt.Sprintf("%q", prompt)
+}
+
+// DescribePromptEnhancement reports the prompt that was sent and the rewritten prompts returned by
+// the API, so that users can see what was actually generated from. Identical rewrites (e.g. one per
+// image) are reported once. It returns an empty string if enhancement was not requested and no
+// rewritten prompt was returned.
+func DescribePromptEnhancement(prompt string, enhanced []string, requested bool) string {
+	var rewrites []string
+	for _, e := range enhanced {
+		if e = strings.TrimSpace(e); e != "" && !slices.Contains(rewrites, e) {
+			rewrites = append(rewrites, e)
+		}
+	}
+	switch {
+	case len(rewrites) == 1:
+		return fmt.Sprintf("Original prompt: %q. Enhanced prompt: %q.", prompt, rewrites[0])
+	case len(rewrites) > 1:
+		quoted := make([]string, len(rewrites))
+		for i, r := range rewrites {
+			quoted[i] = fmt.Sprintf("%q", r)
+		}
+		return fmt.Sprintf("Original prompt: %q. Enhanced prompts: %s.", prompt, strings.Join(quoted, "; "))
+	case requested:
+		return fmt.Sprintf("Prompt enhancement was on for the prompt %q, but the API did not return the rewritten prompt.", prompt)
+	}
+	return ""
+}
+
+// ParseSystemInstruction validates the 'system_instruction' tool argument and returns it as
+// content for GenerateContentConfig.SystemInstruction. It returns nil if the argument is absent
+// or blank.
+func ParseSystemInstruction(arg interface{}) (*genai.Content, error) {
+	if arg == nil {
+		return nil, nil
+	}
+	instruction, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("system_instruction must be a string")
+	}
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return nil, nil
+	}
+	if n := utf8.RuneCountInString(instruction); n > MaxSystemInstructionLength {
+		return nil, fmt.Errorf("system_instruction is %d characters long; the maximum is %d", n, MaxSystemInstructionLength)
+	}
+	return genai.NewContentFromText(instruction, genai.RoleUser), nil
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyPromptAffixes(t *testing.T) {
+	styled := &Config{PromptPrefix: "Cinematic.", PromptSuffix: "Shot on 35mm film."}
+	tests := []struct {
+		name   string
+		cfg    *Config
+		prompt string
+		raw    bool
+		want   string
+	}{
+		{"prefix and suffix", styled, "A cat on a sofa", false, "Cinematic. A cat on a sofa Shot on 35mm film."},
+		{"suffix only", &Config{PromptSuffix: "In watercolor."}, " A boat ", false, "A boat In watercolor."},
+		{"raw opt-out", styled, "A cat on a sofa", true, "A cat on a sofa"},
+		{"empty prompt unchanged", styled, "", false, ""},
+		{"no affixes configured", &Config{}, "A cat on a sofa", false, "A cat on a sofa"},
+		{"nil config", nil, "A cat on a sofa", false, "A cat on a sofa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyPromptAffixes(tt.cfg, tt.prompt, tt.raw); got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoggablePrompt(t *testing.T) {
+	if got, want := loggablePrompt(&Config{}, "A cat"), `"A cat"`; got != want {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+	if got, want := loggablePrompt(&Config{AuditRedactPrompts: true}, "A café"), "[redacted, 6 characters]"; got != want {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+}
+
+func TestDescribePromptEnhancement(t *testing.T) {
+	tests := []struct {
+		name      string
+		enhanced  []string
+		requested bool
+		want      string
+	}{
+		{"not requested", nil, false, ""},
+		{"one rewrite", []string{"A fluffy cat on a velvet sofa, soft light"}, true, `Original prompt: "A cat". Enhanced prompt: "A fluffy cat on a velvet sofa, soft light".`},
+		{"identical rewrites reported once", []string{"A fluffy cat", " A fluffy cat ", ""}, true, `Original prompt: "A cat". Enhanced prompt: "A fluffy cat".`},
+		{"distinct rewrites", []string{"A fluffy cat", "A sleepy cat"}, false, `Original prompt: "A cat". Enhanced prompts: "A fluffy cat"; "A sleepy cat".`},
+		{"requested but not returned", nil, true, `Prompt enhancement was on for the prompt "A cat", but the API did not return the rewritten prompt.`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribePromptEnhancement("A cat", tt.enhanced, tt.requested); got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseSystemInstruction(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "absent", arg: nil},
+		{name: "blank", arg: "   "},
+		{name: "trimmed", arg: "  Answer in haiku.  ", want: "Answer in haiku."},
+		{name: "maximum length", arg: strings.Repeat("é", MaxSystemInstructionLength), want: strings.Repeat("é", MaxSystemInstructionLength)},
+		{name: "too long", arg: strings.Repeat("a", MaxSystemInstructionLength+1), wantErr: true},
+		{name: "not a string", arg: 42, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSystemInstruction(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %t, but got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("%s: expected no instruction, but got %v", tt.name, got)
+			}
+			continue
+		}
+		if got == nil || len(got.Parts) != 1 || got.Parts[0].Text != tt.want {
+			t.Errorf("%s: expected a single text part %q, but got %v", tt.name, tt.want, got)
+		}
+	}
+}
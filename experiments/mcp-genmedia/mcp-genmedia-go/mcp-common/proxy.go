@@ -0,0 +1,68 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// The GenAI, GCS, and Text-to-Speech clients all route through the standard HTTPS_PROXY,
+// HTTP_PROXY, and NO_PROXY environment variables: the HTTP clients use http.DefaultTransport
+// (or a clone of it), which reads them via http.ProxyFromEnvironment, and gRPC reads them
+// itself. No extra client configuration is needed, so we only report the routing at startup.
+
+// proxyTargets returns the endpoints whose proxy routing is reported at startup.
+func proxyTargets(cfg *Config) []string {
+	vertex := fmt.Sprintf("https://%s-aiplatform.googleapis.com", cfg.Location)
+	if cfg.Location == "global" {
+		vertex = "https://aiplatform.googleapis.com"
+	}
+	if cfg.ApiEndpoint != "" {
+		vertex = cfg.APIEndpointURL()
+	}
+	return []string{vertex, "https://storage.googleapis.com"}
+}
+
+// describeProxyRouting returns one line per target describing whether, and through which proxy,
+// requests to it are sent. Proxy credentials are redacted.
+func describeProxyRouting(proxyConfig *httpproxy.Config, targets []string) ([]string, error) {
+	proxyFunc := proxyConfig.ProxyFunc()
+	var lines []string
+	for _, target := range targets {
+		targetURL, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", target, err)
+		}
+		proxyURL, err := proxyFunc(targetURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy configuration: %w", err)
+		}
+		if proxyURL == nil {
+			lines = append(lines, fmt.Sprintf("%s: direct", targetURL.Host))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: via proxy %s", targetURL.Host, proxyURL.Redacted()))
+		}
+	}
+	return lines, nil
+}
+
+// LogProxyConfiguration logs how outbound Vertex AI and GCS requests are routed when a proxy
+// is configured in the environment.
+func LogProxyConfiguration(cfg *Config) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxyConfig.HTTPSProxy == "" && proxyConfig.HTTPProxy == "" {
+		return
+	}
+	lines, err := describeProxyRouting(proxyConfig, proxyTargets(cfg))
+	if err != nil {
+		LogWarnf("Warning: %v", err)
+		return
+	}
+	LogInfof("Outbound proxy configured (NO_PROXY: %q).", proxyConfig.NoProxy)
+	for _, line := range lines {
+		LogInfof("Proxy routing: %s", line)
+	}
+}
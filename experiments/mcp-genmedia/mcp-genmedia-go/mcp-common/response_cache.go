@@ -0,0 +1,108 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultResponseCacheTTL is how long cached generation responses are kept when GENERATION_CACHE_TTL is not set.
+const DefaultResponseCacheTTL = time.Hour
+
+// ResponseCache is an in-memory LRU cache of generation responses with a per-entry TTL.
+// It is used to answer repeated deterministic requests without spending quota.
+// A nil *ResponseCache is valid and caches nothing.
+type ResponseCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // Front is the most recently used entry.
+	entries map[string]*list.Element
+}
+
+type responseCacheEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// NewResponseCache returns a cache holding at most size entries for ttl each.
+// It returns nil, which disables caching, if size is not positive.
+func NewResponseCache(size int, ttl time.Duration) *ResponseCache {
+	if size <= 0 {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = DefaultResponseCacheTTL
+	}
+	return &ResponseCache{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns the cached value for key if it is present and has not expired.
+func (c *ResponseCache) Get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*responseCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Put stores value under key, evicting the least recently used entry if the cache is full.
+func (c *ResponseCache) Put(key string, value any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*responseCacheEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&responseCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache, including any that have expired but not yet been evicted.
+func (c *ResponseCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// ResponseCacheKey derives a cache key from the parts of a request that determine its output.
+func ResponseCacheKey(parts ...any) string {
+	data, err := json.Marshal(parts)
+	if err != nil {
+		// Every caller passes plain values; fall back to a key that never matches.
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
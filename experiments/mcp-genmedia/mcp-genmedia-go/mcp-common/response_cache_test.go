@@ -0,0 +1,54 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := NewResponseCache(2, time.Hour)
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a cache hit for 'a' with value 1, but got %v (hit: %t)", v, ok)
+	}
+
+	// "b" is now the least recently used entry and is evicted.
+	cache.Put("c", 3)
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("expected 'b' to be evicted, but it was still cached")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, but got %d", cache.Len())
+	}
+
+	expiring := NewResponseCache(1, time.Millisecond)
+	expiring.Put("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := expiring.Get("a"); ok {
+		t.Errorf("expected 'a' to have expired, but it was still cached")
+	}
+
+	disabled := NewResponseCache(0, time.Hour)
+	disabled.Put("a", 1)
+	if _, ok := disabled.Get("a"); ok {
+		t.Errorf("expected a disabled cache to never hit")
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []any
+		same bool
+	}{
+		{name: "identical", a: []any{"model", "prompt", int32(7)}, b: []any{"model", "prompt", int32(7)}, same: true},
+		{name: "different seed", a: []any{"model", "prompt", int32(7)}, b: []any{"model", "prompt", int32(8)}, same: false},
+		{name: "parts are not concatenated", a: []any{"ab", "c"}, b: []any{"a", "bc"}, same: false},
+	}
+	for _, tt := range tests {
+		if got := ResponseCacheKey(tt.a...) == ResponseCacheKey(tt.b...); got != tt.same {
+			t.Errorf("%s: expected keys equal to be %t, but got %t", tt.name, tt.same, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures the exponential backoff used by Retry.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values < 1 are treated as 1.
+	MaxAttempts int
+	// BaseDelay is the delay before the second attempt. It doubles for each subsequent attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0.0-1.0) of each delay that is randomized to avoid synchronized retries.
+	Jitter float64
+	// IsRetryable decides whether an error should be retried. If nil, all errors are retried.
+	IsRetryable func(error) bool
+}
+
+// DefaultRetryPolicy returns a policy suitable for transient Google Cloud API errors:
+// 5 attempts, starting at 1s and capped at 30s, with 20% jitter. Context cancellation
+// and deadline errors are never retried.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   1 * time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		IsRetryable: IsRetryableError,
+	}
+}
+
+// IsRetryableError reports whether err is worth retrying. Context cancellation and
+// deadline errors are not, since retrying cannot succeed once the context is done.
+func IsRetryableError(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, the policy's attempts
+// are exhausted, or ctx is done. Delays between attempts grow exponentially from BaseDelay.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	maxAttempts := max(policy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		lastErr = fn(ctx)
+		if lastErr == nil {
+			return nil
+		}
+		if policy.IsRetryable != nil && !policy.IsRetryable(lastErr) {
+			return lastErr
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := policy.backoff(attempt)
+		LogWarnf("Attempt %d/%d failed: %v. Retrying in %v...", attempt, maxAttempts, lastErr, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry aborted after %d attempt(s): %w (last error: %w)", attempt, ctx.Err(), lastErr)
+		case <-timer.C:
+		}
+	}
+	return fmt.Errorf("giving up after %d attempt(s): %w", maxAttempts, lastErr)
+}
+
+// backoff returns the delay to wait after the given (1-based) failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 && delay > 0 {
+		jitter := min(p.Jitter, 1.0)
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+	fastPolicy := RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		IsRetryable: func(err error) bool { return errors.Is(err, errTransient) },
+	}
+
+	tests := []struct {
+		name         string
+		failures     []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{"succeeds_first_try", nil, nil, 1},
+		{"succeeds_after_transient_errors", []error{errTransient, errTransient}, nil, 3},
+		{"stops_on_non_retryable_error", []error{errTransient, errFatal}, errFatal, 2},
+		{"gives_up_after_max_attempts", []error{errTransient, errTransient, errTransient, errTransient, errTransient}, errTransient, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Retry(context.Background(), fastPolicy, func(ctx context.Context) error {
+				attempts++
+				if attempts <= len(tt.failures) {
+					return tt.failures[attempts-1]
+				}
+				return nil
+			})
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, but got: %v", tt.wantErr, err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, but got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+
+	t.Run("aborts_when_context_is_canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}
+		attempts := 0
+		err := Retry(ctx, policy, func(ctx context.Context) error {
+			attempts++
+			cancel()
+			return errTransient
+		})
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errTransient) {
+			t.Errorf("expected context.Canceled wrapping the last error, but got: %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("expected 1 attempt, but got %d", attempts)
+		}
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{50, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(2)
+		if got < time.Second || got > 2*time.Second {
+			t.Fatalf("backoff(2) with 50%% jitter = %v, want between 1s and 2s", got)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	if IsRetryableError(nil) {
+		t.Errorf("expected nil error to be non-retryable")
+	}
+	if IsRetryableError(context.Canceled) || IsRetryableError(context.DeadlineExceeded) {
+		t.Errorf("expected context errors to be non-retryable")
+	}
+	if !IsRetryableError(errors.New("unavailable")) {
+		t.Errorf("expected generic errors to be retryable")
+	}
+}
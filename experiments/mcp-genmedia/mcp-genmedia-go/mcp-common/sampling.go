@@ -0,0 +1,44 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// MaxTemperature is the highest sampling temperature accepted by the Gemini models.
+	MaxTemperature = 2.0
+	// MaxTopK is the highest top-k value accepted by the 'top_k' tool parameters.
+	MaxTopK = 100
+)
+
+// ApplySamplingParams validates the optional 'temperature', 'top_p', and 'top_k' tool arguments
+// and sets them on the GenerateContentConfig. Parameters that are absent keep the model's default
+// sampling.
+func ApplySamplingParams(args map[string]interface{}, config *genai.GenerateContentConfig) error {
+	if v, ok := args["temperature"]; ok && v != nil {
+		temperature, ok := v.(float64)
+		if !ok || temperature < 0 || temperature > MaxTemperature {
+			return fmt.Errorf("temperature must be a number between 0 and %g", MaxTemperature)
+		}
+		config.Temperature = genai.Ptr(float32(temperature))
+	}
+	if v, ok := args["top_p"]; ok && v != nil {
+		topP, ok := v.(float64)
+		if !ok || topP < 0 || topP > 1 {
+			return fmt.Errorf("top_p must be a number between 0 and 1")
+		}
+		config.TopP = genai.Ptr(float32(topP))
+	}
+	if v, ok := args["top_k"]; ok && v != nil {
+		topK, ok := v.(float64)
+		if !ok || topK < 1 || topK > MaxTopK || topK != float64(int(topK)) {
+			return fmt.Errorf("top_k must be a whole number between 1 and %d", MaxTopK)
+		}
+		config.TopK = genai.Ptr(float32(topK))
+	}
+	return nil
+}
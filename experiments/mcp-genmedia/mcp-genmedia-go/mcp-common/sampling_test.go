@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestApplySamplingParams(t *testing.T) {
+	tests := []struct {
+		name                   string
+		args                   map[string]interface{}
+		wantTemp, wantP, wantK *float32
+		wantErr                bool
+	}{
+		{name: "absent keeps defaults", args: map[string]interface{}{}},
+		{name: "all set", args: map[string]interface{}{"temperature": 0.2, "top_p": 0.9, "top_k": 40.0}, wantTemp: genai.Ptr(float32(0.2)), wantP: genai.Ptr(float32(0.9)), wantK: genai.Ptr(float32(40))},
+		{name: "zero temperature", args: map[string]interface{}{"temperature": 0.0}, wantTemp: genai.Ptr(float32(0))},
+		{name: "temperature too high", args: map[string]interface{}{"temperature": 2.5}, wantErr: true},
+		{name: "top_p out of range", args: map[string]interface{}{"top_p": 1.1}, wantErr: true},
+		{name: "fractional top_k", args: map[string]interface{}{"top_k": 2.5}, wantErr: true},
+		{name: "top_k below one", args: map[string]interface{}{"top_k": 0.0}, wantErr: true},
+		{name: "not a number", args: map[string]interface{}{"temperature": "hot"}, wantErr: true},
+	}
+	equal := func(a, b *float32) bool { return (a == nil && b == nil) || (a != nil && b != nil && *a == *b) }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &genai.GenerateContentConfig{}
+			err := ApplySamplingParams(tt.args, config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equal(config.Temperature, tt.wantTemp) || !equal(config.TopP, tt.wantP) || !equal(config.TopK, tt.wantK) {
+				t.Errorf("expected temperature %v, top_p %v, top_k %v, but got %v, %v, %v", tt.wantTemp, tt.wantP, tt.wantK, config.Temperature, config.TopP, config.TopK)
+			}
+		})
+	}
+}
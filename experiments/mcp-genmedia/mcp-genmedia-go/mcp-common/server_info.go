@@ -0,0 +1,101 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Build metadata, injected at build time with -ldflags, for example:
+//
+//	go build -ldflags "-X github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common.GitCommit=$(git rev-parse --short HEAD) -X github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// If they are not set, the VCS information that the Go toolchain embeds in the binary is used.
+var (
+	GitCommit string
+	BuildTime string
+)
+
+// ServerInfoResourceURI is the URI of the resource registered by AddServerInfoResource.
+const ServerInfoResourceURI = "server://info"
+
+// changelogURL is where the release notes of the servers are published.
+const changelogURL = "https://github.com/GoogleCloudPlatform/vertex-ai-creative-studio/blob/main/experiments/mcp-genmedia/mcp-genmedia-go/CHANGELOG.md"
+
+// SupportedTransports are the transports every server accepts with the -transport flag.
+var SupportedTransports = []string{"stdio", "sse", "http"}
+
+// ServerInfo is the content of the server://info resource.
+type ServerInfo struct {
+	Service             string   `json:"service"`
+	Version             string   `json:"version"`
+	GitCommit           string   `json:"git_commit"`
+	BuildTime           string   `json:"build_time"`
+	GoVersion           string   `json:"go_version"`
+	Transport           string   `json:"transport"`
+	SupportedTransports []string `json:"supported_transports"`
+	ChangelogURL        string   `json:"changelog_url"`
+}
+
+// NewServerInfo returns the build metadata of the running server. Values that are neither
+// injected nor embedded by the toolchain are reported as "unknown".
+func NewServerInfo(serviceName, version, transport string) ServerInfo {
+	info := ServerInfo{
+		Service:             serviceName,
+		Version:             version,
+		GitCommit:           GitCommit,
+		BuildTime:           BuildTime,
+		GoVersion:           runtime.Version(),
+		Transport:           transport,
+		SupportedTransports: SupportedTransports,
+		ChangelogURL:        changelogURL,
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// AddServerInfoResource registers the 'server://info' resource, which returns the service name,
+// version, and build metadata of the running server, so that clients can check what they are
+// talking to.
+func AddServerInfoResource(s *server.MCPServer, serviceName, version, transport string) {
+	s.AddResource(mcp.NewResource(
+		ServerInfoResourceURI,
+		"Server Info",
+		mcp.WithResourceDescription("The service name, version, git commit, build time, and supported transports of this server."),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		jsonData, err := json.MarshalIndent(NewServerInfo(serviceName, version, transport), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal server info: %w", err)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      ServerInfoResourceURI,
+				MIMEType: "application/json",
+				Text:     string(jsonData),
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestNewServerInfoUsesInjectedBuildMetadata(t *testing.T) {
+	origCommit, origTime := GitCommit, BuildTime
+	defer func() { GitCommit, BuildTime = origCommit, origTime }()
+	GitCommit, BuildTime = "abc1234", "2025-06-01T12:00:00Z"
+
+	info := NewServerInfo("mcp-test-go", "1.2.3", "http")
+	if info.Service != "mcp-test-go" || info.Version != "1.2.3" || info.Transport != "http" {
+		t.Errorf("expected service mcp-test-go 1.2.3 over http, but got %+v", info)
+	}
+	if info.GitCommit != "abc1234" || info.BuildTime != "2025-06-01T12:00:00Z" {
+		t.Errorf("expected injected build metadata, but got commit %q and build time %q", info.GitCommit, info.BuildTime)
+	}
+	if len(info.SupportedTransports) != 3 {
+		t.Errorf("expected 3 supported transports, but got %v", info.SupportedTransports)
+	}
+}
+
+func TestServerInfoResource(t *testing.T) {
+	s := server.NewMCPServer("test", "1.2.3")
+	AddServerInfoResource(s, "mcp-test-go", "1.2.3", "stdio")
+
+	raw := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"server://info"}}`))
+	resp, ok := raw.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("expected a JSON-RPC response, but got %T: %+v", raw, raw)
+	}
+	result, ok := resp.Result.(mcp.ReadResourceResult)
+	if !ok || len(result.Contents) != 1 {
+		t.Fatalf("expected one resource content, but got %+v", resp.Result)
+	}
+	text, ok := result.Contents[0].(mcp.TextResourceContents)
+	if !ok {
+		t.Fatalf("expected text resource contents, but got %T", result.Contents[0])
+	}
+	var info ServerInfo
+	if err := json.Unmarshal([]byte(text.Text), &info); err != nil {
+		t.Fatalf("expected JSON server info, but got error %v", err)
+	}
+	if info.Version != "1.2.3" || info.GitCommit == "" || info.BuildTime == "" {
+		t.Errorf("expected version and build metadata, but got %+v", info)
+	}
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseClarityPreset(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "not requested", args: map[string]interface{}{"clarity_preset": "strong"}, want: ""},
+		{name: "disabled", args: map[string]interface{}{"clarity_enhance": false}, want: ""},
+		{name: "default preset", args: map[string]interface{}{"clarity_enhance": true}, want: DefaultClarityPreset},
+		{name: "explicit preset", args: map[string]interface{}{"clarity_enhance": true, "clarity_preset": " Strong "}, want: "strong"},
+		{name: "unknown preset", args: map[string]interface{}{"clarity_enhance": true, "clarity_preset": "loud"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClarityPreset(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClarityPresetsUseCompandAndEqualizer(t *testing.T) {
+	for _, name := range ClarityPresetNames() {
+		filter := clarityPresets[name]
+		if !strings.HasPrefix(filter, "compand=") || !strings.Contains(filter, ",equalizer=") {
+			t.Errorf("expected preset %s to chain compand and equalizer, but got %q", name, filter)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Storage backends accepted by STORAGE_BACKEND.
+const (
+	StorageBackendGCS   = "gcs"
+	StorageBackendLocal = "local"
+)
+
+// Storage stores the objects that the servers upload and download, named by gs://bucket/object
+// URIs whatever the backend.
+type Storage interface {
+	// Upload writes data to the object. If contentType is empty, it is inferred from the object
+	// name where the backend records content types.
+	Upload(ctx context.Context, bucketName, objectName, contentType string, data []byte) error
+	// Download opens the object at uri for reading. The caller must close the reader.
+	Download(ctx context.Context, uri string) (io.ReadCloser, error)
+	// SignURL returns a URL through which the object at uri can be read until it expires.
+	SignURL(ctx context.Context, uri string, expires time.Duration) (string, error)
+}
+
+// DefaultStorage is the backend used by UploadToGCS, DownloadFromGCS, and
+// DownloadFromGCSAsBytes. Init replaces it with the backend selected by STORAGE_BACKEND.
+// Objects that the Vertex AI APIs write to GCS themselves are not in it; they are read with
+// DownloadGeneratedFromGCS, which always uses GCSStorage.
+var DefaultStorage Storage = GCSStorage{}
+
+// NewStorage returns the backend selected by STORAGE_BACKEND: Cloud Storage, or the local
+// filesystem under STORAGE_LOCAL_ROOT.
+func (c *Config) NewStorage() Storage {
+	if c != nil && c.StorageBackend == StorageBackendLocal {
+		if c.StorageLocalRoot == "" {
+			LogWarnf("STORAGE_BACKEND is %s but STORAGE_LOCAL_ROOT is not set, using %s", StorageBackendLocal, StorageBackendGCS)
+			return GCSStorage{}
+		}
+		LogInfof("Storing gs:// objects under the local directory %s", c.StorageLocalRoot)
+		return LocalStorage{Root: c.StorageLocalRoot}
+	}
+	return GCSStorage{}
+}
+
+// LocalStorage stores the object gs://bucket/object as the file Root/bucket/object, for running
+// the servers without Cloud Storage.
+type LocalStorage struct {
+	Root string
+}
+
+// path returns the file of the object at uri, which must stay inside Root.
+func (s LocalStorage) path(uri string) (string, error) {
+	bucketName, objectName, err := ParseGCSPath(uri)
+	if err != nil {
+		return "", err
+	}
+	return s.objectPath(bucketName, objectName)
+}
+
+func (s LocalStorage) objectPath(bucketName, objectName string) (string, error) {
+	root := filepath.Clean(s.Root)
+	path := filepath.Join(root, bucketName, filepath.FromSlash(objectName))
+	// filepath.Rel rather than a prefix check, which fails for a root of "/".
+	if rel, err := filepath.Rel(root, path); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("object gs://%s/%s is outside the storage root", bucketName, objectName)
+	}
+	return path, nil
+}
+
+// Upload writes data to the object's file, creating its directory. Content types are not
+// recorded.
+func (s LocalStorage) Upload(ctx context.Context, bucketName, objectName, contentType string, data []byte) error {
+	path, err := s.objectPath(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+// Download opens the object's file. A missing object is reported as fs.ErrNotExist.
+func (s LocalStorage) Download(ctx context.Context, uri string) (io.ReadCloser, error) {
+	path, err := s.path(uri)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// SignURL returns the file:// URL of the object's file. Local files do not expire.
+func (s LocalStorage) SignURL(ctx context.Context, uri string, expires time.Duration) (string, error) {
+	path, err := s.path(uri)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String(), nil
+}
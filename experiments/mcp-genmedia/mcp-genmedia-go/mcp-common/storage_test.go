@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useStorage makes s the DefaultStorage for the duration of the test.
+func useStorage(t *testing.T, s Storage) {
+	previous := DefaultStorage
+	DefaultStorage = s
+	t.Cleanup(func() { DefaultStorage = previous })
+}
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	useStorage(t, LocalStorage{Root: root})
+	ctx := t.Context()
+
+	if err := UploadToGCS(ctx, "my-bucket", "audio/speech.wav", "", []byte("RIFF")); err != nil {
+		t.Fatalf("UploadToGCS() failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "my-bucket", "audio", "speech.wav")); err != nil || string(data) != "RIFF" {
+		t.Fatalf("expected the object under the root, got %q (err: %v)", data, err)
+	}
+
+	data, err := DownloadFromGCSAsBytes(ctx, "gs://my-bucket/audio/speech.wav")
+	if err != nil || string(data) != "RIFF" {
+		t.Errorf("DownloadFromGCSAsBytes() = %q, %v, want RIFF", data, err)
+	}
+	localPath := filepath.Join(t.TempDir(), "downloaded.wav")
+	if err := DownloadFromGCS(ctx, "gs://my-bucket/audio/speech.wav", localPath); err != nil {
+		t.Fatalf("DownloadFromGCS() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(localPath); string(data) != "RIFF" {
+		t.Errorf("expected the downloaded file to contain RIFF, got %q", data)
+	}
+
+	signedURL, err := DefaultStorage.SignURL(ctx, "gs://my-bucket/audio/speech.wav", time.Hour)
+	if err != nil || !strings.HasPrefix(signedURL, "file://") || !strings.HasSuffix(signedURL, "/my-bucket/audio/speech.wav") {
+		t.Errorf("SignURL() = %q, %v, want a file:// URL of the object", signedURL, err)
+	}
+
+	if _, err := DefaultStorage.Download(ctx, "gs://my-bucket/missing.wav"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing object, got %v", err)
+	}
+	if _, err := DefaultStorage.SignURL(ctx, "gs://my-bucket/missing.wav", time.Hour); err == nil {
+		t.Error("expected an error signing a missing object")
+	}
+	if err := UploadToGCS(ctx, "my-bucket", "../../escape.txt", "", []byte("x")); err == nil || !strings.Contains(err.Error(), "outside the storage root") {
+		t.Errorf("expected an object outside the root to be rejected, got %v", err)
+	}
+}
+
+func TestLocalStorageObjectPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		bucket  string
+		object  string
+		want    string
+		wantErr bool
+	}{
+		{name: "object", root: "/data", bucket: "my-bucket", object: "audio/speech.wav", want: "/data/my-bucket/audio/speech.wav"},
+		{name: "filesystem root", root: "/", bucket: "my-bucket", object: "speech.wav", want: "/my-bucket/speech.wav"},
+		{name: "unclean root", root: "/data/", bucket: "my-bucket", object: "speech.wav", want: "/data/my-bucket/speech.wav"},
+		{name: "escape", root: "/data", bucket: "my-bucket", object: "../../etc/passwd", wantErr: true},
+		{name: "sibling with the root as prefix", root: "/data", bucket: "..", object: "data2/x", wantErr: true},
+		{name: "the root itself", root: "/data", bucket: "", object: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LocalStorage{Root: tt.root}.objectPath(tt.bucket, tt.object)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, but got %v", tt.wantErr, err)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want Storage
+	}{
+		{name: "default", cfg: &Config{}, want: GCSStorage{}},
+		{name: "gcs", cfg: &Config{StorageBackend: StorageBackendGCS}, want: GCSStorage{}},
+		{name: "local", cfg: &Config{StorageBackend: StorageBackendLocal, StorageLocalRoot: "/data"}, want: LocalStorage{Root: "/data"}},
+		{name: "local without root", cfg: &Config{StorageBackend: StorageBackendLocal}, want: GCSStorage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.NewStorage(); got != tt.want {
+				t.Errorf("expected %#v, but got %#v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxLoggedToolErrorLength caps the length, in characters, of the error message in a tool call summary.
+const maxLoggedToolErrorLength = 200
+
+// AddTool registers a tool with the server unless it has been disabled through
+// MCP_ENABLED_TOOLS or MCP_DISABLED_TOOLS, in which case it is skipped entirely
+// and does not appear in the server's tool list. Unless LOG_TOOL_CALLS is false,
+// the handler is wrapped with WithToolCallLogging.
+func AddTool(s *server.MCPServer, cfg *Config, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if !cfg.ToolEnabled(tool.Name) {
+		LogInfof("Tool %s is disabled by configuration and will not be registered.", tool.Name)
+		return
+	}
+	if cfg.LogToolCalls {
+		handler = WithToolCallLogging(tool.Name, handler)
+	}
+	s.AddTool(tool, handler)
+}
+
+// WithToolCallLogging wraps a tool handler so that every call emits one summary log record
+// when it completes, with the tool name, duration, status ("ok" or "error"), the size of the
+// returned content and, for failed calls, the error message. Calls that fail with an error
+// result are logged as warnings.
+func WithToolCallLogging(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+		slog.LogAttrs(ctx, toolCallLevel(result, err), "Tool call completed", toolCallAttrs(name, time.Since(start), result, err)...)
+		return result, err
+	}
+}
+
+// toolCallLevel returns the level of a tool call summary: warn for failed calls, info otherwise.
+func toolCallLevel(result *mcp.CallToolResult, err error) slog.Level {
+	if err != nil || (result != nil && result.IsError) {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// toolCallAttrs returns the attributes of a tool call summary.
+func toolCallAttrs(name string, elapsed time.Duration, result *mcp.CallToolResult, err error) []slog.Attr {
+	status := "ok"
+	errMsg := ""
+	switch {
+	case err != nil:
+		status, errMsg = "error", err.Error()
+	case result != nil && result.IsError:
+		status, errMsg = "error", resultText(result)
+	}
+	attrs := []slog.Attr{
+		slog.String("tool", name),
+		slog.Int64("duration_ms", elapsed.Milliseconds()),
+		slog.String("status", status),
+		slog.Int("output_bytes", resultSize(result)),
+	}
+	if errMsg != "" {
+		if utf8.RuneCountInString(errMsg) > maxLoggedToolErrorLength {
+			errMsg = string([]rune(errMsg)[:maxLoggedToolErrorLength]) + "..."
+		}
+		attrs = append(attrs, slog.String("error", errMsg))
+	}
+	return attrs
+}
+
+// resultText returns the text of the first text content of a result.
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// resultSize returns the size in bytes of the content of a result: the text, and the base64
+// data of inline images, audio, and resources.
+func resultSize(result *mcp.CallToolResult) int {
+	if result == nil {
+		return 0
+	}
+	size := 0
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			size += len(text.Text)
+		} else if image, ok := mcp.AsImageContent(content); ok {
+			size += len(image.Data)
+		} else if audio, ok := mcp.AsAudioContent(content); ok {
+			size += len(audio.Data)
+		} else if resource, ok := mcp.AsEmbeddedResource(content); ok {
+			if blob, ok := mcp.AsBlobResourceContents(resource.Resource); ok {
+				size += len(blob.Blob)
+			} else if text, ok := mcp.AsTextResourceContents(resource.Resource); ok {
+				size += len(text.Text)
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestWithToolCallLogging(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	tests := []struct {
+		name       string
+		result     *mcp.CallToolResult
+		err        error
+		wantLevel  string
+		wantStatus string
+		wantBytes  float64
+		wantError  string
+	}{
+		{
+			name:       "success",
+			result:     &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent("done"), mcp.NewImageContent("aGVsbG8=", "image/png")}},
+			wantLevel:  "INFO",
+			wantStatus: "ok",
+			wantBytes:  12,
+		},
+		{
+			name:       "error result",
+			result:     mcp.NewToolResultError("prompt is required"),
+			wantLevel:  "WARN",
+			wantStatus: "error",
+			wantBytes:  18,
+			wantError:  "prompt is required",
+		},
+		{
+			name:       "handler error",
+			err:        errors.New("connection reset"),
+			wantLevel:  "WARN",
+			wantStatus: "error",
+			wantError:  "connection reset",
+		},
+		{
+			name:       "long error message",
+			result:     mcp.NewToolResultError(strings.Repeat("x", 300)),
+			wantLevel:  "WARN",
+			wantStatus: "error",
+			wantBytes:  300,
+			wantError:  strings.Repeat("x", maxLoggedToolErrorLength) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+			handler := WithToolCallLogging("test_tool", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return tt.result, tt.err
+			})
+			result, err := handler(context.Background(), mcp.CallToolRequest{})
+			if result != tt.result || err != tt.err {
+				t.Errorf("expected the handler's result and error to be returned unchanged")
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("expected one summary log line, but got %d: %q", len(lines), buf.String())
+			}
+			var record map[string]any
+			if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+				t.Fatalf("failed to parse the log record: %v", err)
+			}
+			if record["level"] != tt.wantLevel || record["tool"] != "test_tool" || record["status"] != tt.wantStatus || record["output_bytes"] != tt.wantBytes {
+				t.Errorf("unexpected log record: %v", record)
+			}
+			if _, ok := record["duration_ms"].(float64); !ok {
+				t.Errorf("expected a duration_ms attribute, but got %v", record)
+			}
+			if got, _ := record["error"].(string); got != tt.wantError {
+				t.Errorf("expected error %q, but got %q", tt.wantError, got)
+			}
+		})
+	}
+}
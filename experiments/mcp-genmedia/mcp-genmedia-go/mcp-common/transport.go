@@ -0,0 +1,110 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// GenAITransportSettings holds optional HTTP transport tuning for GenAI clients.
+// Zero values mean "not set", in which case the SDK defaults are used.
+type GenAITransportSettings struct {
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	RequestTimeout      time.Duration
+}
+
+// GetGenAITransportSettings reads the GenAI HTTP transport settings from the environment.
+// GENAI_HTTP_IDLE_CONN_TIMEOUT and GENAI_HTTP_REQUEST_TIMEOUT accept Go duration strings
+// (e.g. "90s", "5m"); GENAI_HTTP_MAX_IDLE_CONNS and GENAI_HTTP_MAX_IDLE_CONNS_PER_HOST
+// accept positive integers. Invalid values are logged and ignored.
+func GetGenAITransportSettings() GenAITransportSettings {
+	return GenAITransportSettings{
+		IdleConnTimeout:     parseDurationEnv("GENAI_HTTP_IDLE_CONN_TIMEOUT"),
+		MaxIdleConns:        parsePositiveIntEnv("GENAI_HTTP_MAX_IDLE_CONNS"),
+		MaxIdleConnsPerHost: parsePositiveIntEnv("GENAI_HTTP_MAX_IDLE_CONNS_PER_HOST"),
+		RequestTimeout:      parseDurationEnv("GENAI_HTTP_REQUEST_TIMEOUT"),
+	}
+}
+
+// hasTransportTuning reports whether any connection-pool setting requires a custom HTTP client.
+func (s GenAITransportSettings) hasTransportTuning() bool {
+	return s.IdleConnTimeout > 0 || s.MaxIdleConns > 0 || s.MaxIdleConnsPerHost > 0
+}
+
+// newHTTPClient builds an HTTP client whose transport is a clone of the default
+// transport with the configured connection-pool settings applied.
+func (s GenAITransportSettings) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = s.IdleConnTimeout
+	}
+	if s.MaxIdleConns > 0 {
+		transport.MaxIdleConns = s.MaxIdleConns
+	}
+	if s.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	}
+	return &http.Client{Transport: transport}
+}
+
+// ApplyGenAITransportSettings applies the GENAI_HTTP_* environment settings to a genai.ClientConfig.
+// The request timeout is set on HTTPOptions. If connection-pool settings are present, a custom
+// HTTP client is installed and, for the Vertex AI backend, Application Default Credentials are
+// attached to it, since the SDK only creates an authenticated client when HTTPClient is nil.
+func ApplyGenAITransportSettings(clientConfig *genai.ClientConfig) error {
+	settings := GetGenAITransportSettings()
+
+	if settings.RequestTimeout > 0 {
+		timeout := settings.RequestTimeout
+		clientConfig.HTTPOptions.Timeout = &timeout
+		LogInfof("GenAI request timeout set to %v", timeout)
+	}
+
+	if !settings.hasTransportTuning() || clientConfig.HTTPClient != nil {
+		return nil
+	}
+
+	clientConfig.HTTPClient = settings.newHTTPClient()
+	LogInfof("GenAI HTTP transport tuned: IdleConnTimeout=%v, MaxIdleConns=%d, MaxIdleConnsPerHost=%d",
+		settings.IdleConnTimeout, settings.MaxIdleConns, settings.MaxIdleConnsPerHost)
+
+	if clientConfig.Backend == genai.BackendVertexAI && clientConfig.APIKey == "" && clientConfig.Credentials == nil {
+		return clientConfig.UseDefaultCredentials()
+	}
+	return nil
+}
+
+// parseDurationEnv parses a Go duration from the given environment variable, returning 0 if unset or invalid.
+func parseDurationEnv(key string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		LogWarnf("Invalid %s value %q, ignoring", key, v)
+		return 0
+	}
+	return d
+}
+
+// parsePositiveIntEnv parses a positive integer from the given environment variable, returning 0 if unset or invalid.
+func parsePositiveIntEnv(key string) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		LogWarnf("Invalid %s value %q, ignoring", key, v)
+		return 0
+	}
+	return n
+}
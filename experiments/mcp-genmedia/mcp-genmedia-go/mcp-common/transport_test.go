@@ -0,0 +1,72 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestApplyGenAITransportSettings(t *testing.T) {
+	t.Run("no env vars leaves the SDK defaults", func(t *testing.T) {
+		t.Setenv("GENAI_HTTP_IDLE_CONN_TIMEOUT", "")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS", "")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS_PER_HOST", "")
+		t.Setenv("GENAI_HTTP_REQUEST_TIMEOUT", "")
+
+		cc := &genai.ClientConfig{Backend: genai.BackendGeminiAPI, APIKey: "test-key"}
+		if err := ApplyGenAITransportSettings(cc); err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		if cc.HTTPClient != nil {
+			t.Errorf("expected HTTPClient to be nil, but got %v", cc.HTTPClient)
+		}
+		if cc.HTTPOptions.Timeout != nil {
+			t.Errorf("expected Timeout to be nil, but got %v", *cc.HTTPOptions.Timeout)
+		}
+	})
+
+	t.Run("env vars tune the transport", func(t *testing.T) {
+		t.Setenv("GENAI_HTTP_IDLE_CONN_TIMEOUT", "45s")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS", "200")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS_PER_HOST", "50")
+		t.Setenv("GENAI_HTTP_REQUEST_TIMEOUT", "10m")
+
+		cc := &genai.ClientConfig{Backend: genai.BackendGeminiAPI, APIKey: "test-key"}
+		if err := ApplyGenAITransportSettings(cc); err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		if cc.HTTPOptions.Timeout == nil || *cc.HTTPOptions.Timeout != 10*time.Minute {
+			t.Errorf("expected Timeout to be 10m, but got %v", cc.HTTPOptions.Timeout)
+		}
+		if cc.HTTPClient == nil {
+			t.Fatal("expected HTTPClient to be set")
+		}
+		transport, ok := cc.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("expected *http.Transport, but got %T", cc.HTTPClient.Transport)
+		}
+		if transport.IdleConnTimeout != 45*time.Second {
+			t.Errorf("expected IdleConnTimeout to be 45s, but got %v", transport.IdleConnTimeout)
+		}
+		if transport.MaxIdleConns != 200 {
+			t.Errorf("expected MaxIdleConns to be 200, but got %d", transport.MaxIdleConns)
+		}
+		if transport.MaxIdleConnsPerHost != 50 {
+			t.Errorf("expected MaxIdleConnsPerHost to be 50, but got %d", transport.MaxIdleConnsPerHost)
+		}
+	})
+
+	t.Run("invalid values are ignored", func(t *testing.T) {
+		t.Setenv("GENAI_HTTP_IDLE_CONN_TIMEOUT", "soon")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS", "-1")
+		t.Setenv("GENAI_HTTP_MAX_IDLE_CONNS_PER_HOST", "many")
+		t.Setenv("GENAI_HTTP_REQUEST_TIMEOUT", "0s")
+
+		settings := GetGenAITransportSettings()
+		if settings != (GenAITransportSettings{}) {
+			t.Errorf("expected empty settings, but got %+v", settings)
+		}
+	})
+}
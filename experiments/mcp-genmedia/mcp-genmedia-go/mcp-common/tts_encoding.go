@@ -0,0 +1,49 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultTTSEncoding is the output encoding of the TTS tools when neither the request nor
+// TTS_DEFAULT_ENCODING sets one.
+const DefaultTTSEncoding = "LINEAR16"
+
+// TTSEncodings are the Text-to-Speech audio encodings accepted by TTS_DEFAULT_ENCODING. Each
+// TTS server supports a subset of them.
+var TTSEncodings = []string{"LINEAR16", "MP3", "OGG_OPUS", "MULAW", "ALAW", "PCM", "M4A"}
+
+// ttsEncodingAliases maps common spellings to TTS encodings.
+var ttsEncodingAliases = map[string]string{
+	"WAV":  "LINEAR16",
+	"OGG":  "OGG_OPUS",
+	"OPUS": "OGG_OPUS",
+}
+
+// ParseTTSEncoding normalizes a TTS encoding name or alias (case-insensitively, e.g. "mp3" or
+// "wav") and checks it against the supported encodings.
+func ParseTTSEncoding(value string, supported []string) (string, error) {
+	encoding := strings.ToUpper(strings.TrimSpace(value))
+	if alias, ok := ttsEncodingAliases[encoding]; ok {
+		encoding = alias
+	}
+	if !slices.Contains(supported, encoding) {
+		return "", fmt.Errorf("unsupported audio encoding '%s'. Supported encodings are: %s", value, strings.Join(supported, ", "))
+	}
+	return encoding, nil
+}
+
+// ResolveTTSEncoding returns the output encoding of a TTS request: the requested encoding if
+// set, otherwise TTS_DEFAULT_ENCODING if the server supports it, otherwise DefaultTTSEncoding.
+func (c *Config) ResolveTTSEncoding(requested string, supported []string) (string, error) {
+	if strings.TrimSpace(requested) != "" {
+		return ParseTTSEncoding(requested, supported)
+	}
+	if c != nil && c.TTSDefaultEncoding != "" && slices.Contains(supported, c.TTSDefaultEncoding) {
+		return c.TTSDefaultEncoding, nil
+	}
+	return DefaultTTSEncoding, nil
+}
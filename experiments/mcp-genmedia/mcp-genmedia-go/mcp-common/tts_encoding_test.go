@@ -0,0 +1,34 @@
+package common
+
+import "testing"
+
+func TestResolveTTSEncoding(t *testing.T) {
+	chirpEncodings := []string{"LINEAR16", "MP3", "OGG_OPUS"}
+	tests := []struct {
+		name      string
+		cfg       *Config
+		requested string
+		supported []string
+		want      string
+		wantErr   bool
+	}{
+		{"requested wins", &Config{TTSDefaultEncoding: "MP3"}, "OGG_OPUS", TTSEncodings, "OGG_OPUS", false},
+		{"requested alias", nil, "wav", TTSEncodings, "LINEAR16", false},
+		{"configured default", &Config{TTSDefaultEncoding: "MP3"}, "", TTSEncodings, "MP3", false},
+		{"configured default unsupported by server", &Config{TTSDefaultEncoding: "M4A"}, "", chirpEncodings, DefaultTTSEncoding, false},
+		{"no default configured", &Config{}, "", chirpEncodings, DefaultTTSEncoding, false},
+		{"nil config", nil, "", chirpEncodings, DefaultTTSEncoding, false},
+		{"unsupported request", &Config{}, "MULAW", chirpEncodings, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.ResolveTTSEncoding(tt.requested, tt.supported)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
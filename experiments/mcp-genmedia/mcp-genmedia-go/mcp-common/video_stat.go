@@ -0,0 +1,160 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// VideoStat is the size and duration of a video file.
+type VideoStat struct {
+	SizeBytes int64
+	Duration  time.Duration // 0 if the duration could not be read from the file.
+}
+
+// String describes the video for a tool result, e.g. "4718592 bytes (4.5 MB), 8.0s".
+func (s VideoStat) String() string {
+	description := fmt.Sprintf("%d bytes (%s)", s.SizeBytes, FormatBytes(s.SizeBytes))
+	if s.Duration > 0 {
+		description += fmt.Sprintf(", %.1fs", s.Duration.Seconds())
+	}
+	return description
+}
+
+// StatVideo returns the size and duration of the MP4 video at uri, a gs:// URI or a local
+// path. Only the headers of the video are read, so GCS objects are not downloaded. If the size
+// is known but the duration cannot be read, the stat is returned along with the error. A gs://
+// URI names a Veo output, so it is always read from Cloud Storage, whatever STORAGE_BACKEND.
+func StatVideo(ctx context.Context, uri string) (VideoStat, error) {
+	if strings.HasPrefix(uri, "gs://") {
+		return statGCSVideo(ctx, uri)
+	}
+	f, err := os.Open(uri)
+	if err != nil {
+		return VideoStat{}, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return VideoStat{}, err
+	}
+	stat := VideoStat{SizeBytes: info.Size()}
+	stat.Duration, err = MP4Duration(f, info.Size())
+	return stat, err
+}
+
+func statGCSVideo(ctx context.Context, gcsURI string) (VideoStat, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return VideoStat{}, err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return VideoStat{}, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	obj := client.Bucket(bucketName).Object(objectName)
+	attrs, err := obj.Attrs(gcsOpCtx)
+	if err != nil {
+		return VideoStat{}, fmt.Errorf("Object(%q).Attrs: %w", objectName, err)
+	}
+	stat := VideoStat{SizeBytes: attrs.Size}
+	stat.Duration, err = MP4Duration(gcsReaderAt{ctx: gcsOpCtx, obj: obj}, attrs.Size)
+	return stat, err
+}
+
+// gcsReaderAt reads ranges of a GCS object.
+type gcsReaderAt struct {
+	ctx context.Context
+	obj *storage.ObjectHandle
+}
+
+func (r gcsReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	rc, err := r.obj.NewRangeReader(r.ctx, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadFull(rc, p)
+}
+
+// MP4Duration reads the duration of an MP4 (or QuickTime) file of the given size from the
+// movie header ('mvhd') box inside its 'moov' box. Only the box headers are read, wherever the
+// 'moov' box is in the file.
+func MP4Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	moovStart, moovSize, err := findMP4Box(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhdStart, mvhdSize, err := findMP4Box(r, moovStart, moovStart+moovSize, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	// The full box header (version and flags) is followed by the creation and modification
+	// times, the timescale and the duration, which are 64-bit in version 1 and 32-bit otherwise.
+	header := make([]byte, 32)
+	if mvhdSize < 24 {
+		return 0, fmt.Errorf("mvhd box is too small")
+	}
+	if _, err := r.ReadAt(header[:min(int64(len(header)), mvhdSize)], mvhdStart); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("failed to read the mvhd box: %w", err)
+	}
+	var timescale uint32
+	var duration uint64
+	if header[0] == 1 {
+		if mvhdSize < 32 {
+			return 0, fmt.Errorf("mvhd box is too small")
+		}
+		timescale = binary.BigEndian.Uint32(header[20:24])
+		duration = binary.BigEndian.Uint64(header[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(header[12:16])
+		duration = uint64(binary.BigEndian.Uint32(header[16:20]))
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("mvhd box has a timescale of 0")
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// findMP4Box returns the offset and size of the contents of the first box of the given type
+// between start and end.
+func findMP4Box(r io.ReaderAt, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return 0, 0, fmt.Errorf("failed to read box header at %d: %w", offset, err)
+		}
+		boxSize, headerSize := int64(binary.BigEndian.Uint32(header[:4])), int64(8)
+		switch boxSize {
+		case 0: // The box extends to the end.
+			boxSize = end - offset
+		case 1: // A 64-bit size follows the type.
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, fmt.Errorf("failed to read box size at %d: %w", offset, err)
+			}
+			boxSize, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		if boxSize < headerSize || offset+boxSize > end {
+			return 0, 0, fmt.Errorf("invalid %q box size %d at %d", string(header[4:8]), boxSize, offset)
+		}
+		if string(header[4:8]) == boxType {
+			return offset + headerSize, boxSize - headerSize, nil
+		}
+		offset += boxSize
+	}
+	return 0, 0, fmt.Errorf("no %s box found", boxType)
+}
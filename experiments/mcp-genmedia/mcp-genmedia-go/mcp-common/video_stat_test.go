@@ -0,0 +1,91 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mp4Box returns a box of the given type around the contents.
+func mp4Box(boxType string, contents ...[]byte) []byte {
+	body := bytes.Join(contents, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, boxType...), body...)
+}
+
+// mvhdV0 returns the contents of a version 0 mvhd box.
+func mvhdV0(timescale, duration uint32) []byte {
+	contents := make([]byte, 100)
+	binary.BigEndian.PutUint32(contents[12:16], timescale)
+	binary.BigEndian.PutUint32(contents[16:20], duration)
+	return contents
+}
+
+// mvhdV1 returns the contents of a version 1 mvhd box.
+func mvhdV1(timescale uint32, duration uint64) []byte {
+	contents := make([]byte, 112)
+	contents[0] = 1
+	binary.BigEndian.PutUint32(contents[20:24], timescale)
+	binary.BigEndian.PutUint64(contents[24:32], duration)
+	return contents
+}
+
+func TestMP4Duration(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41"))
+	mdat := mp4Box("mdat", make([]byte, 1000))
+	tests := []struct {
+		name    string
+		file    []byte
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "moov at the end", file: bytes.Join([][]byte{ftyp, mdat, mp4Box("moov", mp4Box("mvhd", mvhdV0(1000, 8000)))}, nil), want: 8 * time.Second},
+		{name: "faststart", file: bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvhd", mvhdV0(600, 3300))), mdat}, nil), want: 5500 * time.Millisecond},
+		{name: "version 1", file: bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvhd", mvhdV1(90000, 720000)))}, nil), want: 8 * time.Second},
+		{name: "mvhd after other boxes", file: bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("udta"), mp4Box("mvhd", mvhdV0(1, 4)))}, nil), want: 4 * time.Second},
+		{name: "no moov", file: bytes.Join([][]byte{ftyp, mdat}, nil), wantErr: true},
+		{name: "zero timescale", file: mp4Box("moov", mp4Box("mvhd", mvhdV0(0, 10))), wantErr: true},
+		{name: "truncated", file: bytes.Join([][]byte{ftyp, mdat[:500]}, nil), wantErr: true},
+		{name: "not an mp4", file: []byte("this is not a video"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MP4Duration(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got a duration of %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStatVideo(t *testing.T) {
+	file := bytes.Join([][]byte{mp4Box("ftyp"), mp4Box("moov", mp4Box("mvhd", mvhdV0(1000, 6000)))}, nil)
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := StatVideo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := VideoStat{SizeBytes: int64(len(file)), Duration: 6 * time.Second}
+	if got != want {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+	if got, want := (VideoStat{SizeBytes: 4718592, Duration: 8 * time.Second}).String(), "4718592 bytes (4.5 MB), 8.0s"; got != want {
+		t.Errorf("expected %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// videoCodecs maps the codec names accepted by the 'output_codec' parameter to FFMpeg encoders.
+var videoCodecs = map[string]string{
+	"h264": "libx264",
+	"h265": "libx265",
+	"vp9":  "libvpx-vp9",
+}
+
+// videoCodecAliases maps common spellings to codec names.
+var videoCodecAliases = map[string]string{
+	"avc":  "h264",
+	"hevc": "h265",
+}
+
+// videoContainers lists the containers accepted by the 'output_container' parameter, with the
+// codecs each can hold and the audio encoder used with it.
+var videoContainers = map[string]struct {
+	codecs     []string
+	audioCodec string
+	mimeType   string
+}{
+	"mp4":  {codecs: []string{"h264", "h265", "vp9"}, audioCodec: "aac", mimeType: "video/mp4"},
+	"mov":  {codecs: []string{"h264", "h265"}, audioCodec: "aac", mimeType: "video/quicktime"},
+	"mkv":  {codecs: []string{"h264", "h265", "vp9"}, audioCodec: "aac", mimeType: "video/x-matroska"},
+	"webm": {codecs: []string{"vp9"}, audioCodec: "libopus", mimeType: "video/webm"},
+}
+
+// bitratePattern matches FFMpeg bitrates such as "5M", "2500k", or "800000".
+var bitratePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmM]?$`)
+
+// VideoTranscodeOptions describes how a generated video is re-encoded. Codec and Container
+// are always set; an empty Bitrate leaves the encoder's default quality-based rate control.
+type VideoTranscodeOptions struct {
+	Codec     string
+	Container string
+	Bitrate   string
+}
+
+// Extension returns the file extension of the output container, including the dot.
+func (o VideoTranscodeOptions) Extension() string {
+	return "." + o.Container
+}
+
+// MIMEType returns the MIME type of the output container.
+func (o VideoTranscodeOptions) MIMEType() string {
+	return videoContainers[o.Container].mimeType
+}
+
+// String describes the options, e.g. "h265/mp4 at 5M".
+func (o VideoTranscodeOptions) String() string {
+	if o.Bitrate == "" {
+		return o.Codec + "/" + o.Container
+	}
+	return fmt.Sprintf("%s/%s at %s", o.Codec, o.Container, o.Bitrate)
+}
+
+// ParseVideoTranscodeOptions reads the optional 'output_codec', 'output_container', and
+// 'output_bitrate' tool parameters. It returns nil if none is set, meaning the video is kept as
+// generated. Unset values default to H.264 in an MP4 container (WebM for VP9).
+func ParseVideoTranscodeOptions(args map[string]interface{}) (*VideoTranscodeOptions, error) {
+	codec, _ := args["output_codec"].(string)
+	container, _ := args["output_container"].(string)
+	bitrate, _ := args["output_bitrate"].(string)
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	container = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(container), "."))
+	bitrate = strings.TrimSpace(bitrate)
+	if codec == "" && container == "" && bitrate == "" {
+		return nil, nil
+	}
+
+	if alias, ok := videoCodecAliases[codec]; ok {
+		codec = alias
+	}
+	if codec == "" {
+		codec = "h264"
+		if container == "webm" {
+			codec = "vp9"
+		}
+	}
+	if _, ok := videoCodecs[codec]; !ok {
+		return nil, fmt.Errorf("unsupported output_codec '%s'. Supported codecs are: h264, h265, vp9", codec)
+	}
+	if container == "" {
+		container = "mp4"
+		if codec == "vp9" {
+			container = "webm"
+		}
+	}
+	target, ok := videoContainers[container]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output_container '%s'. Supported containers are: mkv, mov, mp4, webm", container)
+	}
+	if !slices.Contains(target.codecs, codec) {
+		return nil, fmt.Errorf("output_container '%s' does not support codec '%s'. Supported codecs for %s are: %s", container, codec, container, strings.Join(target.codecs, ", "))
+	}
+	if bitrate != "" && !bitratePattern.MatchString(bitrate) {
+		return nil, fmt.Errorf("output_bitrate '%s' is invalid. Use a number of bits per second with an optional k or M suffix, e.g. '5M' or '2500k'", bitrate)
+	}
+	return &VideoTranscodeOptions{Codec: codec, Container: container, Bitrate: bitrate}, nil
+}
+
+// videoTranscodeArgs returns the FFMpeg arguments that encode with the given options.
+func videoTranscodeArgs(opts VideoTranscodeOptions) []string {
+	args := []string{"-c:v", videoCodecs[opts.Codec], "-pix_fmt", "yuv420p"}
+	if opts.Bitrate != "" {
+		args = append(args, "-b:v", opts.Bitrate)
+	}
+	if opts.Codec == "h265" && (opts.Container == "mp4" || opts.Container == "mov") {
+		args = append(args, "-tag:v", "hvc1") // Needed for HEVC playback on Apple devices.
+	}
+	args = append(args, "-c:a", videoContainers[opts.Container].audioCodec)
+	if opts.Container == "mp4" || opts.Container == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	return args
+}
+
+// TranscodeVideoFile re-encodes the video at inputPath to outputPath with the given options.
+// ffmpeg is looked up in MCP_CUSTOM_PATH if set, otherwise in PATH.
+func TranscodeVideoFile(ctx context.Context, inputPath, outputPath string, opts VideoTranscodeOptions) error {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", inputPath}, videoTranscodeArgs(opts)...)
+	args = append(args, outputPath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed to transcode to %s: %w: %s", opts, err, GetTail(string(output), 5))
+	}
+	return nil
+}
+
+// faststartExtensions lists the MP4-family containers whose index (the moov atom) can be moved
+// to the front of the file with '-movflags +faststart'.
+var faststartExtensions = []string{".mp4", ".m4v", ".m4a", ".mov"}
+
+// SupportsFaststart reports whether the file at path is in a container that supports faststart.
+func SupportsFaststart(path string) bool {
+	return slices.Contains(faststartExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// WithFaststart adds '-movflags +faststart' to FFMpeg arguments whose output, the last argument,
+// is an MP4-family file, so that the file can start playing before it is fully downloaded.
+// Arguments that already set -movflags are returned unchanged.
+func WithFaststart(args []string) []string {
+	if len(args) == 0 || !SupportsFaststart(args[len(args)-1]) || slices.Contains(args, "-movflags") {
+		return args
+	}
+	withFlags := make([]string, 0, len(args)+2)
+	withFlags = append(withFlags, args[:len(args)-1]...)
+	return append(withFlags, "-movflags", "+faststart", args[len(args)-1])
+}
+
+// FaststartVideoFile remuxes the MP4-family file at path in place, without re-encoding, so that
+// its index is at the front of the file. ffmpeg is looked up in MCP_CUSTOM_PATH if set,
+// otherwise in PATH.
+func FaststartVideoFile(ctx context.Context, path string) error {
+	if !SupportsFaststart(path) {
+		return fmt.Errorf("faststart is not supported for %s files", filepath.Ext(path))
+	}
+	tempPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".faststart" + filepath.Ext(path)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error", "-y", "-i", path,
+		"-map", "0", "-c", "copy", "-movflags", "+faststart", tempPath)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("ffmpeg failed to apply faststart: %w: %s", err, GetTail(string(output), 5))
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVideoTranscodeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    *VideoTranscodeOptions
+		wantErr string
+	}{
+		{name: "not requested", args: map[string]interface{}{}, want: nil},
+		{name: "h265 at 5M", args: map[string]interface{}{"output_codec": "h265", "output_bitrate": "5M"}, want: &VideoTranscodeOptions{Codec: "h265", Container: "mp4", Bitrate: "5M"}},
+		{name: "alias and dotted container", args: map[string]interface{}{"output_codec": "HEVC", "output_container": ".mov"}, want: &VideoTranscodeOptions{Codec: "h265", Container: "mov"}},
+		{name: "bitrate only defaults to h264", args: map[string]interface{}{"output_bitrate": "2500k"}, want: &VideoTranscodeOptions{Codec: "h264", Container: "mp4", Bitrate: "2500k"}},
+		{name: "vp9 defaults to webm", args: map[string]interface{}{"output_codec": "vp9"}, want: &VideoTranscodeOptions{Codec: "vp9", Container: "webm"}},
+		{name: "webm defaults to vp9", args: map[string]interface{}{"output_container": "webm"}, want: &VideoTranscodeOptions{Codec: "vp9", Container: "webm"}},
+		{name: "unsupported codec", args: map[string]interface{}{"output_codec": "av1"}, wantErr: "unsupported output_codec"},
+		{name: "unsupported container", args: map[string]interface{}{"output_container": "avi"}, wantErr: "unsupported output_container"},
+		{name: "codec not allowed in container", args: map[string]interface{}{"output_codec": "h264", "output_container": "webm"}, wantErr: "does not support codec"},
+		{name: "invalid bitrate", args: map[string]interface{}{"output_bitrate": "fast"}, wantErr: "output_bitrate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVideoTranscodeOptions(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, but got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVideoTranscodeArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts VideoTranscodeOptions
+		want []string
+	}{
+		{"h265 mp4 with bitrate", VideoTranscodeOptions{Codec: "h265", Container: "mp4", Bitrate: "5M"},
+			[]string{"-c:v", "libx265", "-pix_fmt", "yuv420p", "-b:v", "5M", "-tag:v", "hvc1", "-c:a", "aac", "-movflags", "+faststart"}},
+		{"h264 mkv", VideoTranscodeOptions{Codec: "h264", Container: "mkv"},
+			[]string{"-c:v", "libx264", "-pix_fmt", "yuv420p", "-c:a", "aac"}},
+		{"vp9 webm", VideoTranscodeOptions{Codec: "vp9", Container: "webm", Bitrate: "2M"},
+			[]string{"-c:v", "libvpx-vp9", "-pix_fmt", "yuv420p", "-b:v", "2M", "-c:a", "libopus"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := videoTranscodeArgs(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithFaststart(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"mp4 output", []string{"-y", "-i", "in.mp4", "-c", "copy", "out.mp4"},
+			[]string{"-y", "-i", "in.mp4", "-c", "copy", "-movflags", "+faststart", "out.mp4"}},
+		{"uppercase mov output", []string{"-i", "in.webm", "OUT.MOV"},
+			[]string{"-i", "in.webm", "-movflags", "+faststart", "OUT.MOV"}},
+		{"already set", []string{"-i", "in.mp4", "-movflags", "+faststart", "out.mp4"},
+			[]string{"-i", "in.mp4", "-movflags", "+faststart", "out.mp4"}},
+		{"webm output", []string{"-i", "in.mp4", "out.webm"}, []string{"-i", "in.mp4", "out.webm"}},
+		{"gif output", []string{"-i", "in.mp4", "out.gif"}, []string{"-i", "in.mp4", "out.gif"}},
+		{"no arguments", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithFaststart(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import "time"
+
+// Voice previews use a short phrase and a tight timeout so they return quickly.
+const (
+	// DefaultVoicePreviewText is the phrase a voice preview speaks when no text is given.
+	DefaultVoicePreviewText = "Hi there! This is a quick preview of my voice."
+	// MaxVoicePreviewTextLength is the longest text, in characters, a voice preview accepts.
+	MaxVoicePreviewTextLength = 200
+	// VoicePreviewTimeout bounds the synthesis of a voice preview.
+	VoicePreviewTimeout = 15 * time.Second
+)
@@ -18,10 +18,11 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -33,6 +34,43 @@ import (
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 )
 
+// inlineImageWarnBytes is the decoded size above which an inline image is flagged as large.
+// Inline results are base64-encoded, so their payload is roughly a third larger than this.
+const inlineImageWarnBytes = 4 << 20 // 4 MiB
+
+// defaultResponseModalities lets the model return commentary text along with the images.
+var defaultResponseModalities = []string{"IMAGE", "TEXT"}
+
+// parseResponseModalities validates the 'response_modalities' argument, which may be a list of
+// "IMAGE" and "TEXT" in any case. An absent or empty list selects defaultResponseModalities.
+func parseResponseModalities(arg interface{}) ([]string, error) {
+	if arg == nil {
+		return defaultResponseModalities, nil
+	}
+	items, ok := arg.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("response_modalities must be a list of strings")
+	}
+	var modalities []string
+	for _, item := range items {
+		modality, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("response_modalities must be a list of strings")
+		}
+		modality = strings.ToUpper(strings.TrimSpace(modality))
+		if modality != "IMAGE" && modality != "TEXT" {
+			return nil, fmt.Errorf("unsupported response modality '%s'. Supported values are: IMAGE, TEXT", modality)
+		}
+		if !slices.Contains(modalities, modality) {
+			modalities = append(modalities, modality)
+		}
+	}
+	if len(modalities) == 0 {
+		return defaultResponseModalities, nil
+	}
+	return modalities, nil
+}
+
 func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(ctx, "gemini_generate_content")
@@ -49,6 +87,17 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 		aspectRatio = strings.TrimSpace(ar)
 	}
 
+	responseModalities, err := parseResponseModalities(request.GetArguments()["response_modalities"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	includeText := slices.Contains(responseModalities, "TEXT")
+
+	systemInstruction, err := common.ParseSystemInstruction(request.GetArguments()["system_instruction"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	modelArg, _ := request.GetArguments()["model"].(string)
 	model := "gemini-3.1-flash-image"
 	if modelArg != "" {
@@ -64,16 +113,36 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 		outputDir = strings.TrimSpace(dir)
 	}
 
+	gcsOutputPrefix := ""
+	if uri, ok := request.GetArguments()["gcs_bucket_uri"].(string); ok && strings.TrimSpace(uri) != "" {
+		gcsOutputPrefix = strings.TrimSuffix(common.EnsureGCSPathPrefix(strings.TrimSpace(uri)), "/")
+	}
+	// With no output target, generated images are returned inline as base64 image content.
+	returnInline := outputDir == "" && gcsOutputPrefix == ""
+
 	// --- Construct Gemini Request ---
 	var parts []*genai.Part
 	parts = append(parts, genai.NewPartFromText(prompt))
 
 	if imageArgs, ok := request.GetArguments()["images"].([]interface{}); ok {
+		if err := appConfig.CheckInputImageCount(len(imageArgs)); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var inlineImageBytes int64
 		for _, imgArg := range imageArgs {
 			if imgPath, ok := imgArg.(string); ok {
 				if strings.HasPrefix(imgPath, "gs://") {
 					parts = append(parts, genai.NewPartFromURI(imgPath, ""))
 				} else {
+					// The size is checked before reading, so that oversized files are never loaded.
+					info, err := os.Stat(imgPath)
+					if err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("failed to read image file %s: %v", imgPath, err)), nil
+					}
+					inlineImageBytes += info.Size()
+					if err := appConfig.CheckInputImageBytes(inlineImageBytes); err != nil {
+						return mcp.NewToolResultError(err.Error()), nil
+					}
 					imgData, err := os.ReadFile(imgPath)
 					if err != nil {
 						return mcp.NewToolResultError(fmt.Sprintf("failed to read image file %s: %v", imgPath, err)), nil
@@ -87,35 +156,57 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 	span.SetAttributes(
 		attribute.String("prompt", prompt),
 		attribute.String("model", model),
+		attribute.StringSlice("response_modalities", responseModalities),
+		attribute.Bool("system_instruction", systemInstruction != nil),
 		attribute.String("output_directory", outputDir),
+		attribute.String("gcs_bucket_uri", gcsOutputPrefix),
 	)
 
 	// --- API Call ---
-	log.Printf("Calling GenerateContent with Model: %s, Prompt: \"%s\"", model, prompt)
+	common.LogInfof("Calling GenerateContent with Model: %s, Prompt: \"%s\"", model, prompt)
 	startTime := time.Now()
 
 	config := &genai.GenerateContentConfig{
-		ResponseModalities: []string{"IMAGE", "TEXT"},
+		ResponseModalities: responseModalities,
+		SystemInstruction:  systemInstruction,
 		ImageConfig: &genai.ImageConfig{
 			AspectRatio: aspectRatio,
 		},
 	}
+	if err := common.ApplySamplingParams(request.GetArguments(), config); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	contents := &genai.Content{Parts: parts, Role: "USER"}
 
-	resp, err := client.Models.GenerateContent(ctx, model, []*genai.Content{contents}, config)
+	var resp *genai.GenerateContentResponse
+	err = common.GenAIBreaker.Execute(ctx, func(ctx context.Context) error {
+		var callErr error
+		resp, callErr = client.Models.GenerateContent(ctx, model, []*genai.Content{contents}, config)
+		return callErr
+	})
 
 	apiCallDuration := time.Since(startTime)
-	log.Printf("GenerateContent call took: %v", apiCallDuration)
+	common.LogInfof("GenerateContent call took: %v", apiCallDuration)
 	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
 
 	if err != nil {
 		span.RecordError(err)
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "gemini_image_generation",
+			Prompt:     prompt,
+			Model:      model,
+			Parameters: request.GetArguments(),
+			Error:      err.Error(),
+		})
 		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini API: %v", err)), nil
 	}
 
 	// --- Process Response ---
 	var responseText strings.Builder
 	var savedFiles []string
+	var inlineImages []mcp.Content
+	var largeInlineImages int
 
 	// Check for optional Sherlog header
 	if resp.SDKHTTPResponse != nil && resp.SDKHTTPResponse.Headers != nil {
@@ -126,38 +217,171 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 	gentime := time.Now().Format("20060102150405")
 
 	for _, candidate := range resp.Candidates {
+		if candidate.Content == nil {
+			continue // Blocked candidates have no content; see describeResponseFeedback.
+		}
 		for n, part := range candidate.Content.Parts {
-			if part.Text != "" {
+			// Text is dropped if only images were requested, in case the model returns some anyway.
+			if part.Text != "" && includeText {
 				responseText.WriteString(part.Text)
 			}
 			if part.InlineData != nil {
-				log.Printf("part %d mime-type: %s", n, part.InlineData.MIMEType)
+				mimeType := part.InlineData.MIMEType
+				if mimeType == "" {
+					mimeType = "image/png"
+				}
+				common.LogDebugf("part %d mime-type: %s", n, mimeType)
+				fileName := fmt.Sprintf("gemini_%s_%d%s", gentime, n, extensionForMimeType(mimeType))
 
 				if outputDir != "" {
 					if err := os.MkdirAll(outputDir, 0755); err != nil {
 						return mcp.NewToolResultError(fmt.Sprintf("failed to create output directory: %v", err)), nil
 					}
-					fileName := fmt.Sprintf("gemini_%s_%d.png", gentime, n)
 					filePath := filepath.Join(outputDir, fileName)
 					if err := os.WriteFile(filePath, part.InlineData.Data, 0644); err != nil {
 						return mcp.NewToolResultError(fmt.Sprintf("failed to write image file: %v", err)), nil
 					}
 					savedFiles = append(savedFiles, filePath)
-				} else {
-					// If no output dir, should we return base64? For now, we just log.
-					log.Println("Received image data but no output_directory was specified. Image not saved.")
+				}
+				if gcsOutputPrefix != "" {
+					gcsURI := gcsOutputPrefix + "/" + fileName
+					bucketName, objectName, err := common.ParseGCSPath(gcsURI)
+					if err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("invalid gcs_bucket_uri: %v", err)), nil
+					}
+					if err := common.UploadToGCS(ctx, bucketName, objectName, mimeType, part.InlineData.Data); err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("failed to upload image to GCS: %v", err)), nil
+					}
+					savedFiles = append(savedFiles, gcsURI)
+				}
+				if returnInline {
+					size := len(part.InlineData.Data)
+					if size > inlineImageWarnBytes {
+						largeInlineImages++
+						common.LogWarnf("Warning: returning large inline image (%s). Consider setting output_directory or gcs_bucket_uri.", common.FormatBytes(int64(size)))
+					}
+					inlineImages = append(inlineImages, inlineOrSpillContent(ctx, part.InlineData.Data, fileName, mimeType))
 				}
 			}
 		}
 	}
 
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "gemini_image_generation",
+		Prompt:     prompt,
+		Model:      model,
+		Parameters: request.GetArguments(),
+		OutputURIs: savedFiles,
+	})
+
 	// --- Format Final Result ---
 	finalMessage := responseText.String()
+	noImages := slices.Contains(responseModalities, "IMAGE") && len(inlineImages) == 0 && len(savedFiles) == 0
+	if feedback := describeResponseFeedback(resp, noImages); feedback != "" {
+		common.LogInfof("Gemini response feedback: %s", feedback)
+		span.SetAttributes(attribute.String("response_feedback", feedback))
+		finalMessage += "\n\n" + feedback
+	}
 	if len(savedFiles) > 0 {
 		finalMessage += fmt.Sprintf("\n\nGenerated and saved %d image(s): %s", len(savedFiles), strings.Join(savedFiles, ", "))
 	}
+	if len(inlineImages) > 0 {
+		finalMessage += fmt.Sprintf("\n\nGenerated %d image(s), returned in this response.", len(inlineImages))
+		if largeInlineImages > 0 {
+			finalMessage += fmt.Sprintf(" Warning: %d image(s) exceed %s; set output_directory or gcs_bucket_uri to avoid large inline payloads.", largeInlineImages, common.FormatBytes(inlineImageWarnBytes))
+		}
+	}
+
+	content := []mcp.Content{mcp.TextContent{Type: "text", Text: strings.TrimSpace(finalMessage)}}
+	content = append(content, inlineImages...)
+	return &mcp.CallToolResult{Content: content}, nil
+}
+
+// inlineOrSpillContent returns generated media as base64 image or audio content. If the data
+// exceeds MCP_MAX_INLINE_BYTES and GENMEDIA_BUCKET is set, it is uploaded to GCS instead and a
+// text item with the gs:// URI is returned.
+func inlineOrSpillContent(ctx context.Context, data []byte, fileName, mimeType string) mcp.Content {
+	if gcsURI := common.SpillInlineOutput(ctx, appConfig, serviceName, fileName, mimeType, data); gcsURI != "" {
+		return mcp.TextContent{Type: "text", Text: fmt.Sprintf("Output (%s) exceeds the inline size limit and was saved to %s", common.FormatBytes(int64(len(data))), gcsURI)}
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if strings.HasPrefix(mimeType, "audio/") {
+		return mcp.AudioContent{Type: "audio", Data: encoded, MIMEType: mimeType}
+	}
+	return mcp.ImageContent{Type: "image", Data: encoded, MIMEType: mimeType}
+}
 
-	return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: strings.TrimSpace(finalMessage)}}}, nil
+// flaggedSafetyProbabilities are the harm probabilities reported with a blocked or cut-short response.
+var flaggedSafetyProbabilities = []genai.HarmProbability{genai.HarmProbabilityMedium, genai.HarmProbabilityHigh}
+
+// describeResponseFeedback explains a response that was blocked, produced no images (noImages),
+// or was cut short: the block reason of the prompt, the finish reason of every candidate that
+// did not stop normally, and the safety categories that were blocked or rated medium or high.
+// It returns "" if nothing went wrong.
+func describeResponseFeedback(resp *genai.GenerateContentResponse, noImages bool) string {
+	var parts []string
+	if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		msg := fmt.Sprintf("The prompt was blocked (block reason: %s).", fb.BlockReason)
+		if fb.BlockReasonMessage != "" {
+			msg += " " + fb.BlockReasonMessage
+		}
+		if categories := flaggedSafetyCategories(fb.SafetyRatings); categories != "" {
+			msg += " Safety categories: " + categories + "."
+		}
+		parts = append(parts, msg)
+	}
+	for i, candidate := range resp.Candidates {
+		if candidate.FinishReason == "" || (candidate.FinishReason == genai.FinishReasonStop && !noImages) {
+			continue
+		}
+		msg := fmt.Sprintf("Candidate %d finished with reason %s.", i, candidate.FinishReason)
+		if candidate.FinishMessage != "" {
+			msg += " " + candidate.FinishMessage
+		}
+		if categories := flaggedSafetyCategories(candidate.SafetyRatings); categories != "" {
+			msg += " Safety categories: " + categories + "."
+		}
+		parts = append(parts, msg)
+	}
+	if noImages {
+		parts = append([]string{"No image was generated."}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// flaggedSafetyCategories lists the categories of the ratings that were blocked or rated with a
+// flagged probability, e.g. "HARM_CATEGORY_DANGEROUS_CONTENT (HIGH, blocked)".
+func flaggedSafetyCategories(ratings []*genai.SafetyRating) string {
+	var flagged []string
+	for _, rating := range ratings {
+		if rating == nil || (!rating.Blocked && !slices.Contains(flaggedSafetyProbabilities, rating.Probability)) {
+			continue
+		}
+		detail := string(rating.Probability)
+		if rating.Blocked {
+			if detail != "" {
+				detail += ", "
+			}
+			detail += "blocked"
+		}
+		flagged = append(flagged, fmt.Sprintf("%s (%s)", rating.Category, detail))
+	}
+	return strings.Join(flagged, ", ")
+}
+
+// extensionForMimeType returns the file extension for a generated image MIME type, defaulting to .png.
+func extensionForMimeType(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
 }
 
 func inferMimeType(path string) string {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseResponseModalities(t *testing.T) {
+	tests := []struct {
+		name        string
+		arg         interface{}
+		want        []string
+		errContains string
+	}{
+		{"absent", nil, []string{"IMAGE", "TEXT"}, ""},
+		{"empty", []interface{}{}, []string{"IMAGE", "TEXT"}, ""},
+		{"image only", []interface{}{"IMAGE"}, []string{"IMAGE"}, ""},
+		{"case and duplicates", []interface{}{"text", " Image ", "TEXT"}, []string{"TEXT", "IMAGE"}, ""},
+		{"unsupported", []interface{}{"AUDIO"}, nil, "unsupported response modality 'AUDIO'"},
+		{"not a string", []interface{}{42}, nil, "must be a list of strings"},
+		{"not a list", "IMAGE", nil, "must be a list of strings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponseModalities(tt.arg)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDescribeResponseFeedback(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *genai.GenerateContentResponse
+		noImages bool
+		want     string
+	}{
+		{
+			name: "images generated",
+			resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}}},
+			want: "",
+		},
+		{
+			name: "prompt blocked",
+			resp: &genai.GenerateContentResponse{PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+				BlockReason: genai.BlockedReasonSafety,
+				SafetyRatings: []*genai.SafetyRating{
+					{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh, Blocked: true},
+					{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+				},
+			}},
+			noImages: true,
+			want:     "No image was generated. The prompt was blocked (block reason: SAFETY). Safety categories: HARM_CATEGORY_DANGEROUS_CONTENT (HIGH, blocked).",
+		},
+		{
+			name: "candidate cut short",
+			resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+				FinishReason:  genai.FinishReasonImageSafety,
+				FinishMessage: "Unable to show the generated image.",
+				SafetyRatings: []*genai.SafetyRating{{Category: genai.HarmCategorySexuallyExplicit, Probability: genai.HarmProbabilityMedium}},
+			}}},
+			noImages: true,
+			want:     "No image was generated. Candidate 0 finished with reason IMAGE_SAFETY. Unable to show the generated image. Safety categories: HARM_CATEGORY_SEXUALLY_EXPLICIT (MEDIUM).",
+		},
+		{
+			name:     "stopped without an image",
+			resp:     &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}}},
+			noImages: true,
+			want:     "No image was generated. Candidate 0 finished with reason STOP.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeResponseFeedback(tt.resp, tt.noImages); got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
@@ -50,10 +51,10 @@ func init() {
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse, or http)")
 	flag.IntVar(&port, "p", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
 	flag.IntVar(&port, "port", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
-	flag.Parse()
 }
 
 func main() {
+	flag.Parse() // Ensure flags are parsed before use
 
 	var cleanup func()
 	appConfig, cleanup = common.Init(serviceName, version)
@@ -61,34 +62,34 @@ func main() {
 
 	// Override default location for Gemini models if not explicitly set
 	if os.Getenv("LOCATION") == "" {
-		log.Printf("LOCATION environment variable not set. Defaulting to 'global' for mcp-gemini-go.")
+		common.LogInfof("LOCATION environment variable not set. Defaulting to 'global' for mcp-gemini-go.")
 		appConfig.Location = "global"
 	}
 	var err error
 
-	log.Printf("Initializing global GenAI client...")
+	common.LogInfof("Initializing global GenAI client...")
 	clientCtx, clientCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer clientCancel()
 
-	clientConfig := &genai.ClientConfig{
-		Backend:  genai.BackendVertexAI,
-		Project:  appConfig.ProjectID,
-		Location: appConfig.Location,
-	}
-	if appConfig.ApiEndpoint != "" {
-		log.Printf("Using custom Vertex AI endpoint: %s", appConfig.ApiEndpoint)
-		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
+	clientConfig := appConfig.NewGenAIClientConfig(appConfig.Location)
+
+	if err := common.ApplyGenAITransportSettings(clientConfig); err != nil {
+		common.LogWarnf("Warning: Failed to apply GenAI transport settings: %v", err)
 	}
 
 	if err := common.InjectCaptureHeaders(clientCtx, appConfig, clientConfig); err != nil {
-		log.Printf("Warning: Failed to inject capture headers: %v", err)
+		common.LogWarnf("Warning: Failed to inject capture headers: %v", err)
 	}
 
-	genAIClient, err = genai.NewClient(clientCtx, clientConfig)
-	if err != nil {
-		log.Printf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
+	genAIClient, err = common.NewGenAIClientWithRetry(clientCtx, func(ctx context.Context) (*genai.Client, error) {
+		return genai.NewClient(ctx, clientConfig)
+	})
+	if err != nil && !common.IsTransientClientError(err) {
+		common.LogFatalf("Error creating global GenAI client: %v. Check the credentials and GENAI_BACKEND, PROJECT_ID and GEMINI_API_KEY settings.", err)
+	} else if err != nil {
+		common.LogWarnf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
 	} else {
-		log.Printf("Global GenAI client initialized successfully.")
+		common.LogInfof("Global GenAI client initialized successfully.")
 	}
 
 	s := server.NewMCPServer("Gemini", version, server.WithResourceCapabilities(true, false))
@@ -98,27 +99,50 @@ func main() {
 		mcp.WithString("prompt", mcp.Required(), mcp.Description("The text prompt for content generation.")),
 		mcp.WithString("model", mcp.DefaultString("gemini-3.1-flash-image"), mcp.Description(common.BuildGeminiImageModelDescription())),
 		mcp.WithString("aspect_ratio", mcp.DefaultString("1:1"), mcp.Description("Aspect ratio of the generated images. Note: supported aspect ratios are model-dependent.")),
-		mcp.WithArray("images", mcp.Description("Optional. A list of local file paths or GCS URIs for input images."), mcp.Items(map[string]any{"type": "string"})),
-		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save generated image(s) to.")),
-		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store generated images (e.g., your-bucket/outputs/).")),
+		mcp.WithArray("images", mcp.Description("Optional. A list of local file paths or GCS URIs for input images. The number of images and the total size of local files are limited by MAX_INPUT_IMAGES and MAX_INPUT_IMAGE_BYTES (by default 14 images and 20 MB); pass large images as GCS URIs."), mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithString("system_instruction", mcp.Description(fmt.Sprintf("Optional. A system instruction that steers the model's behavior separately from the prompt, such as tone, style, or constraints (up to %d characters).", common.MaxSystemInstructionLength))),
+		mcp.WithNumber("temperature", mcp.Min(0), mcp.Max(common.MaxTemperature), mcp.Description("Optional. Sampling temperature, from 0 to 2. Lower values make the output more deterministic and focused, higher values more varied. Defaults to the model's default.")),
+		mcp.WithNumber("top_p", mcp.Min(0), mcp.Max(1), mcp.Description("Optional. Nucleus sampling, from 0 to 1: only the most likely tokens whose probabilities add up to top_p are considered. Defaults to the model's default.")),
+		mcp.WithNumber("top_k", mcp.Min(1), mcp.Max(common.MaxTopK), mcp.Description(fmt.Sprintf("Optional. Only the top_k most likely tokens are considered at each step, from 1 to %d. Defaults to the model's default.", common.MaxTopK))),
+		mcp.WithArray("response_modalities", mcp.Description("Optional. The kinds of output the model may return: ['IMAGE'] for images only, without commentary text, or ['TEXT', 'IMAGE'] for both. Defaults to both."), mcp.Items(map[string]any{"type": "string", "enum": []string{"IMAGE", "TEXT"}})),
+		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save generated image(s) to. If neither this nor gcs_bucket_uri is set, images are returned inline as base64.")),
+		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store generated images (e.g., your-bucket/outputs/). If neither this nor output_directory is set, images are returned inline as base64.")),
 	)
 
 	handlerWithClient := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return geminiGenerateContentHandler(genAIClient, ctx, request)
 	}
-	s.AddTool(tool, handlerWithClient)
+	common.AddTool(s, appConfig, tool, handlerWithClient)
+
+	expandPromptTool := mcp.NewTool("expand_prompt",
+		mcp.WithDescription("Expands a short image idea (e.g. 'a cat') into a detailed image generation prompt describing the setting, composition, lighting, colors, and style, using a Gemini text model. Optionally generates images from the expanded prompt with gemini_image_generation."),
+		mcp.WithString("prompt", mcp.Required(), mcp.Description(fmt.Sprintf("The short prompt to expand (up to %d characters).", maxExpandPromptLength))),
+		mcp.WithString("style", mcp.Description(fmt.Sprintf("Optional. Style guidance for the expanded prompt, e.g. 'watercolor illustration' or 'moody cinematic photography' (up to %d characters).", maxPromptStyleLength))),
+		mcp.WithString("model", mcp.DefaultString(defaultPromptExpansionModel), mcp.Description("Optional. The Gemini text model that expands the prompt.")),
+		mcp.WithNumber("temperature", mcp.Min(0), mcp.Max(common.MaxTemperature), mcp.Description("Optional. Sampling temperature of the expansion, from 0 to 2. Higher values give more varied prompts. Defaults to the model's default.")),
+		mcp.WithBoolean("generate", mcp.DefaultBool(false), mcp.Description("Optional. If true, also generates images from the expanded prompt, as gemini_image_generation does with the parameters below.")),
+		mcp.WithString("image_model", mcp.DefaultString("gemini-3.1-flash-image"), mcp.Description("Optional. Used with generate. "+common.BuildGeminiImageModelDescription())),
+		mcp.WithString("aspect_ratio", mcp.DefaultString("1:1"), mcp.Description("Optional. Used with generate. Aspect ratio of the generated images.")),
+		mcp.WithString("output_directory", mcp.Description("Optional. Used with generate. Local directory to save generated image(s) to. If neither this nor gcs_bucket_uri is set, images are returned inline as base64.")),
+		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. Used with generate. GCS URI prefix to store generated images (e.g., your-bucket/outputs/).")),
+	)
+	common.AddTool(s, appConfig, expandPromptTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return expandPromptHandler(genAIClient, ctx, request)
+	})
 
 	// --- Register Gemini TTS Tools ---
 	listVoicesTool := mcp.NewTool("list_gemini_voices",
 		mcp.WithDescription("Lists the available single-speaker voices for use with the Gemini-TTS models."),
 	)
-	s.AddTool(listVoicesTool, listGeminiVoicesHandler)
+	common.AddTool(s, appConfig, listVoicesTool, listGeminiVoicesHandler)
 
 	ttsTool := mcp.NewTool("gemini_audio_tts",
 		mcp.WithDescription("Synthesizes speech from text using Gemini models, allowing for granular control over style, pace, tone, and emotional expression through natural-language prompts."),
 		mcp.WithString("text",
-			mcp.Required(),
-			mcp.Description("The text to synthesize (up to 800 characters)."),
+			mcp.Description("The text to synthesize (up to 800 characters). Either 'text' or 'text_uri' is required."),
+		),
+		mcp.WithString(common.TextURIParam,
+			mcp.Description("Optional. A local path or gs:// URI of a UTF-8 text file of up to 800 characters to synthesize instead of 'text'."),
 		),
 		mcp.WithString("prompt",
 			mcp.Description("Stylistic instructions on how to synthesize the content. You can adapt delivery, adopt specific accents, and produce a range of tones and expressions."),
@@ -135,7 +159,7 @@ func main() {
 		),
 		mcp.WithString("language_code",
 			mcp.DefaultString("en-US"),
-			mcp.Description("Optional. The language code to use for the synthesis. Defaults to en-US."),
+			mcp.Description("Optional. The language to use for the synthesis, as a BCP-47 code (e.g. 'pt-BR') or a descriptive name (e.g. 'Portuguese (Brazil)'). Defaults to en-US."),
 		),
 		mcp.WithString("output_filename_prefix",
 			mcp.DefaultString("gemini_tts_audio"),
@@ -145,12 +169,46 @@ func main() {
 			mcp.Description("Optional. If provided, specifies a local directory to save the generated audio file to. If not provided, audio data is returned in the response."),
 		),
 		mcp.WithString("audio_encoding",
-			mcp.DefaultString("LINEAR16"),
-			mcp.Description("The format of the audio byte stream. Supported values: LINEAR16, MP3, OGG_OPUS, MULAW, ALAW, PCM, M4A."),
+			mcp.Description("Optional. The format of the audio byte stream. Supported values: LINEAR16, MP3, OGG_OPUS, MULAW, ALAW, PCM, M4A. Defaults to TTS_DEFAULT_ENCODING, or LINEAR16."),
 			mcp.Enum("LINEAR16", "MP3", "OGG_OPUS", "MULAW", "ALAW", "PCM", "M4A"),
 		),
+		mcp.WithArray("additional_encodings",
+			mcp.Description(fmt.Sprintf("Optional. Extra formats to transcode the audio to (e.g., ['mp3'] with the default LINEAR16 encoding to get both a .wav and an .mp3). Each is saved next to the primary file, or returned inline if no output_directory is given. Supported: %s. Not available with the raw MULAW, ALAW, and PCM encodings. Requires ffmpeg on the server.", strings.Join(common.AudioFormatNames(), ", "))),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("clarity_enhance",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, post-processes the speech with light dynamic-range compression and EQ for clearer, more consistent-volume playback in noisy environments. Available with the LINEAR16, MP3, and OGG_OPUS encodings. Requires ffmpeg on the server."),
+		),
+		mcp.WithString("clarity_preset",
+			mcp.DefaultString(common.DefaultClarityPreset),
+			mcp.Enum(common.ClarityPresetNames()...),
+			mcp.Description("Optional. Used with clarity_enhance. How strongly to process the speech: 'light', 'standard', or 'strong'."),
+		),
+	)
+	common.AddTool(s, appConfig, ttsTool, geminiAudioTTSHandler)
+
+	previewVoiceTool := mcp.NewTool("gemini_preview_voice",
+		mcp.WithDescription("Synthesizes a short sample phrase with a Gemini-TTS voice and returns the audio inline, so a voice can be auditioned before use."),
+		mcp.WithString("voice_name",
+			mcp.Required(),
+			mcp.Description("The voice to preview. Use 'list_gemini_voices' to see available voices."),
+			mcp.Enum(availableGeminiVoices...),
+		),
+		mcp.WithString("text",
+			mcp.Description(fmt.Sprintf("Optional. A short sample phrase of at most %d characters. Defaults to a fixed preview phrase.", common.MaxVoicePreviewTextLength)),
+		),
+		mcp.WithString("model_name",
+			mcp.DefaultString(defaultGeminiTTSModel),
+			mcp.Description("The model to use."),
+			mcp.Enum("gemini-3.1-flash-tts-preview", "gemini-2.5-flash-tts", "gemini-2.5-pro-tts", "gemini-2.5-flash-lite-preview-tts"),
+		),
+		mcp.WithString("language_code",
+			mcp.DefaultString("en-US"),
+			mcp.Description("Optional. The language to use for the synthesis, as a BCP-47 code (e.g. 'pt-BR') or a descriptive name (e.g. 'Portuguese (Brazil)'). Defaults to en-US."),
+		),
 	)
-	s.AddTool(ttsTool, geminiAudioTTSHandler)
+	common.AddTool(s, appConfig, previewVoiceTool, geminiPreviewVoiceHandler)
 	// --- End of TTS Tools ---
 
 	// --- Register Gemini Resources ---
@@ -162,6 +220,9 @@ func main() {
 	), geminiLanguageCodesHandler)
 	// --- End of Gemini Resources ---
 
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
+
 	switch transport {
 	case "sse":
 		ssePort := 8081 // Default SSE port
@@ -170,10 +231,10 @@ func main() {
 		} else if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -182,17 +243,19 @@ func main() {
 		} else if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
-		http.Handle("/mcp", server.NewStreamableHTTPServer(s))
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", httpPort), nil); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", server.NewStreamableHTTPServer(s))
+		httpServer := common.NewHTTPServer(fmt.Sprintf(":%d", httpPort), mux)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 }
@@ -0,0 +1,172 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Gemini models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+const (
+	// defaultPromptExpansionModel is the text model that expands prompts.
+	defaultPromptExpansionModel = "gemini-2.5-flash"
+	// maxExpandPromptLength and maxPromptStyleLength, in characters, keep expansion requests short.
+	maxExpandPromptLength = 2000
+	maxPromptStyleLength  = 500
+)
+
+// promptExpansionInstruction is the system instruction that asks the model for an image
+// generation prompt rather than a conversational answer.
+const promptExpansionInstruction = `You rewrite short image ideas into detailed prompts for an image generation model.
+Keep the subject and intent of the idea, and add concrete visual detail: the setting, composition and framing, lighting, colors, mood, materials and textures, and the medium or photographic style.
+Write one paragraph of at most 120 words in plain descriptive language. Do not add text or lettering to the image unless the idea asks for it.
+Reply with the prompt only, without a title, quotes, explanations, or alternatives.`
+
+// buildPromptExpansionInstruction returns the system instruction for an expansion, adding the
+// style guidance, if any.
+func buildPromptExpansionInstruction(style string) string {
+	if style = strings.TrimSpace(style); style == "" {
+		return promptExpansionInstruction
+	}
+	return promptExpansionInstruction + "\nFollow this style guidance: " + style
+}
+
+// cleanExpandedPrompt removes the decoration that models sometimes add around the prompt
+// despite the instruction, such as a "Prompt:" label or surrounding quotes.
+func cleanExpandedPrompt(text string) string {
+	text = strings.TrimSpace(text)
+	for _, label := range []string{"**Prompt:**", "Prompt:"} {
+		if len(text) >= len(label) && strings.EqualFold(text[:len(label)], label) {
+			text = strings.TrimSpace(text[len(label):])
+		}
+	}
+	for _, quote := range []string{`"`, "“"} {
+		closing := quote
+		if quote == "“" {
+			closing = "”"
+		}
+		if strings.HasPrefix(text, quote) && strings.HasSuffix(text, closing) && len(text) > len(quote)+len(closing) {
+			text = strings.TrimSpace(text[len(quote) : len(text)-len(closing)])
+		}
+	}
+	return text
+}
+
+// expandPromptHandler expands a short prompt into a detailed image generation prompt with a
+// Gemini text model. If 'generate' is set, the expanded prompt is passed on to the image
+// generation handler, and its result follows the expanded prompt.
+func expandPromptHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "expand_prompt")
+	defer span.End()
+
+	args := request.GetArguments()
+	prompt, _ := args["prompt"].(string)
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return mcp.NewToolResultError("prompt must be a non-empty string and is required"), nil
+	}
+	if n := utf8.RuneCountInString(prompt); n > maxExpandPromptLength {
+		return mcp.NewToolResultError(fmt.Sprintf("prompt is %d characters long; the maximum is %d", n, maxExpandPromptLength)), nil
+	}
+	style, _ := args["style"].(string)
+	style = strings.TrimSpace(style)
+	if n := utf8.RuneCountInString(style); n > maxPromptStyleLength {
+		return mcp.NewToolResultError(fmt.Sprintf("style is %d characters long; the maximum is %d", n, maxPromptStyleLength)), nil
+	}
+	model := defaultPromptExpansionModel
+	if m, ok := args["model"].(string); ok && strings.TrimSpace(m) != "" {
+		model = strings.TrimSpace(m)
+	}
+	generate, _ := args["generate"].(bool)
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(buildPromptExpansionInstruction(style), genai.RoleUser),
+	}
+	if temperature, ok := args["temperature"]; ok {
+		if err := common.ApplySamplingParams(map[string]interface{}{"temperature": temperature}, config); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	span.SetAttributes(
+		attribute.String("prompt", prompt),
+		attribute.String("style", style),
+		attribute.String("model", model),
+		attribute.Bool("generate", generate),
+	)
+
+	common.LogInfof("Expanding prompt with Model: %s, Prompt: %q", model, prompt)
+	startTime := time.Now()
+	var resp *genai.GenerateContentResponse
+	err := common.GenAIBreaker.Execute(ctx, func(ctx context.Context) error {
+		var callErr error
+		resp, callErr = client.Models.GenerateContent(ctx, model, genai.Text(prompt), config)
+		return callErr
+	})
+	apiCallDuration := time.Since(startTime)
+	common.LogInfof("GenerateContent call took: %v", apiCallDuration)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini API: %v", err)), nil
+	}
+
+	expanded := cleanExpandedPrompt(resp.Text())
+	if expanded == "" {
+		return mcp.NewToolResultError("Gemini returned no expanded prompt; try again or rephrase the prompt."), nil
+	}
+	common.LogInfof("Expanded prompt: %q", expanded)
+	message := fmt.Sprintf("Original prompt: %q.\n\nExpanded prompt:\n%s", prompt, expanded)
+	if !generate {
+		return mcp.NewToolResultText(message), nil
+	}
+
+	// The remaining arguments are the image generation parameters.
+	generationRequest := request
+	generationArgs := maps.Clone(args)
+	for _, name := range []string{"style", "model", "generate", "temperature"} {
+		delete(generationArgs, name)
+	}
+	generationArgs["prompt"] = expanded
+	if imageModel, ok := args["image_model"].(string); ok {
+		generationArgs["model"] = imageModel
+	}
+	delete(generationArgs, "image_model")
+	generationRequest.Params.Arguments = generationArgs
+
+	result, err := geminiGenerateContentHandler(client, ctx, generationRequest)
+	if err != nil || result == nil {
+		return result, err
+	}
+	if result.IsError {
+		message += "\n\nImage generation failed:"
+	}
+	result.Content = append([]mcp.Content{mcp.TextContent{Type: "text", Text: message}}, result.Content...)
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildPromptExpansionInstruction(t *testing.T) {
+	if got := buildPromptExpansionInstruction("  "); got != promptExpansionInstruction {
+		t.Errorf("expected the base instruction without style guidance, but got %q", got)
+	}
+	got := buildPromptExpansionInstruction(" watercolor illustration ")
+	if !strings.HasPrefix(got, promptExpansionInstruction) || !strings.HasSuffix(got, "Follow this style guidance: watercolor illustration") {
+		t.Errorf("expected the style guidance to be appended, but got %q", got)
+	}
+}
+
+func TestCleanExpandedPrompt(t *testing.T) {
+	tests := map[string]string{
+		"A fluffy ginger cat asleep on a sunlit windowsill.": "A fluffy ginger cat asleep on a sunlit windowsill.",
+		"  Prompt: A fluffy ginger cat.\n":                   "A fluffy ginger cat.",
+		"**Prompt:** A fluffy ginger cat.":                   "A fluffy ginger cat.",
+		`"A fluffy ginger cat."`:                             "A fluffy ginger cat.",
+		"prompt: “A fluffy ginger cat.”":                     "A fluffy ginger cat.",
+		`A cat saying "hello" to a "dog"`:                    `A cat saying "hello" to a "dog"`,
+		"":                                                   "",
+	}
+	for input, want := range tests {
+		if got := cleanExpandedPrompt(input); got != want {
+			t.Errorf("cleanExpandedPrompt(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -6,15 +6,19 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
 	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -171,7 +175,7 @@ func geminiLanguageCodesHandler(ctx context.Context, request mcp.ReadResourceReq
 // listGeminiVoicesHandler handles the 'list_gemini_voices' tool request.
 // It returns a hardcoded list of available Gemini TTS voices.
 func listGeminiVoicesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Println("Handling list_gemini_voices request.")
+	common.LogInfof("Handling list_gemini_voices request.")
 
 	voiceListJSON, err := json.MarshalIndent(availableGeminiVoices, "", "  ")
 	if err != nil {
@@ -198,6 +202,10 @@ var audioEncodingToFileExtension = map[string]string{
 	"M4A":      ".m4a",
 }
 
+// rawAudioEncodings are the encodings without a container, which cannot be transcoded
+// to additional encodings because the sample format is not self-describing.
+var rawAudioEncodings = []string{"MULAW", "ALAW", "PCM"}
+
 var audioEncodingToMIMEType = map[string]string{
 	"LINEAR16": "audio/wav",
 	"MP3":      "audio/mpeg",
@@ -208,14 +216,59 @@ var audioEncodingToMIMEType = map[string]string{
 	"M4A":      "audio/mp4",
 }
 
+// bcp47Pattern matches strings shaped like a BCP-47 language tag, e.g. "en", "pt-BR", or "es-419".
+var bcp47Pattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,4})*$`)
+
+// resolveGeminiLanguageCode resolves the language_code parameter to a BCP-47 code. It accepts
+// a code (e.g. "pt-BR") or a descriptive name from geminiLanguageCodeMap (e.g. "Portuguese (Brazil)"),
+// case-insensitively. Partial names are accepted when they match exactly one language; otherwise
+// the error lists the matching choices. Unknown code-shaped values are passed through to the API.
+func resolveGeminiLanguageCode(input string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(input))
+	if normalized == "" {
+		return "en-US", nil
+	}
+	if code, ok := geminiLanguageCodeMap[normalized]; ok {
+		return code, nil
+	}
+	for _, code := range geminiLanguageCodeMap {
+		if strings.ToLower(code) == normalized {
+			return code, nil
+		}
+	}
+	if bcp47Pattern.MatchString(normalized) {
+		common.LogWarnf("language_code '%s' is not in the list of known Gemini-TTS languages; passing it through.", input)
+		return strings.TrimSpace(input), nil
+	}
+
+	var matches []string
+	for name := range geminiLanguageCodeMap {
+		if strings.Contains(name, normalized) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unsupported language_code '%s'. See the 'gemini://language_codes' resource for supported languages", input)
+	case 1:
+		return geminiLanguageCodeMap[matches[0]], nil
+	}
+	sort.Strings(matches)
+	choices := make([]string, len(matches))
+	for i, name := range matches {
+		choices[i] = fmt.Sprintf("%s [%s]", name, geminiLanguageCodeMap[name])
+	}
+	return "", fmt.Errorf("language_code '%s' is ambiguous. Please be more specific by choosing one of the following: %s", input, strings.Join(choices, ", "))
+}
+
 // geminiAudioTTSHandler handles the 'gemini_audio_tts' tool request.
 func geminiAudioTTSHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Printf("Handling gemini_audio_tts request with arguments: %v", request.GetArguments())
+	common.LogInfof("Handling gemini_audio_tts request with arguments: %v", request.GetArguments())
 
 	// --- 1. Parse and Validate Arguments ---
-	text, ok := request.GetArguments()["text"].(string)
-	if !ok || strings.TrimSpace(text) == "" {
-		return mcp.NewToolResultError("text parameter must be a non-empty string and is required"), nil
+	text, err := appConfig.InputText(ctx, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 	if len(text) > 800 {
 		return mcp.NewToolResultError("text parameter cannot exceed 800 characters"), nil
@@ -244,14 +297,32 @@ func geminiAudioTTSHandler(ctx context.Context, request mcp.CallToolRequest) (*m
 		return mcp.NewToolResultError(fmt.Sprintf("invalid voice_name '%s'. Use 'list_gemini_voices' to see available voices", voiceName)), nil
 	}
 
-	languageCode, _ := request.GetArguments()["language_code"].(string)
-	if languageCode == "" {
-		languageCode = "en-US"
+	languageArg, _ := request.GetArguments()["language_code"].(string)
+	languageCode, err := resolveGeminiLanguageCode(languageArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	audioEncodingArg, _ := request.GetArguments()["audio_encoding"].(string)
+	audioEncoding, err := appConfig.ResolveTTSEncoding(audioEncodingArg, common.TTSEncodings)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	audioEncoding, _ := request.GetArguments()["audio_encoding"].(string)
-	if audioEncoding == "" {
-		audioEncoding = "LINEAR16"
+	additionalFormats, err := common.ParseAudioEncodings(request.GetArguments()["additional_encodings"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(additionalFormats) > 0 && slices.Contains(rawAudioEncodings, audioEncoding) {
+		return mcp.NewToolResultError(fmt.Sprintf("additional_encodings cannot be used with the raw %s audio_encoding", audioEncoding)), nil
+	}
+	clarityPreset, err := common.ParseClarityPreset(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	encodingFormat, encodingFormatOK := common.LookupAudioFormat(audioEncoding)
+	if clarityPreset != "" && !encodingFormatOK {
+		return mcp.NewToolResultError(fmt.Sprintf("clarity_enhance cannot be used with the %s audio_encoding", audioEncoding)), nil
 	}
 
 	outputDir, _ := request.GetArguments()["output_directory"].(string)
@@ -263,12 +334,34 @@ func geminiAudioTTSHandler(ctx context.Context, request mcp.CallToolRequest) (*m
 	// --- 2. Call the TTS API ---
 	audioBytes, err := callGeminiTTSAPI(ctx, text, prompt, voiceName, modelName, audioEncoding, languageCode)
 	if err != nil {
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "gemini_audio_tts",
+			Prompt:     text,
+			Model:      modelName,
+			Parameters: request.GetArguments(),
+			Error:      err.Error(),
+		})
 		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini TTS API: %v", err)), nil
 	}
 
 	// --- 3. Process the Audio Response ---
 	var contentItems []mcp.Content
 	var fileSaveMessage string
+	var clarityMessage string
+	if clarityPreset != "" {
+		enhanced, err := common.EnhanceSpeechClarity(ctx, audioBytes, clarityPreset, encodingFormat)
+		if err != nil {
+			// Enhancement is best-effort; fall back to the unprocessed audio.
+			common.LogWarnf("Warning: %v", err)
+			clarityMessage = "Clarity enhancement was skipped: " + err.Error() + ". "
+		} else {
+			audioBytes = enhanced
+			clarityMessage = fmt.Sprintf("Applied the '%s' clarity enhancement. ", clarityPreset)
+		}
+	}
+	var outputURIs []string
+	var savedPath string // Path of the saved file, from which additional encodings are transcoded.
 
 	fileExtension, ok := audioEncodingToFileExtension[audioEncoding]
 	if !ok {
@@ -282,35 +375,103 @@ func geminiAudioTTSHandler(ctx context.Context, request mcp.CallToolRequest) (*m
 	if outputDir != "" {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
 			fileSaveMessage = fmt.Sprintf("Error creating directory %s: %v. Audio data will be returned in response instead.", outputDir, err)
-			log.Print(fileSaveMessage)
+			common.LogWarnf("%s", fileSaveMessage)
 			// Fallback to returning data in response
-			base64AudioData := base64.StdEncoding.EncodeToString(audioBytes)
-			contentItems = append(contentItems, mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: mimeType})
+			contentItems = append(contentItems, inlineOrSpillContent(ctx, audioBytes, filenamePrefix+fileExtension, mimeType))
 		} else {
 			filename := fmt.Sprintf("%s-%s-%s%s", filenamePrefix, voiceName, time.Now().Format(timeFormatForTTSFilename), fileExtension)
 			savedFilename := filepath.Join(outputDir, filename)
 			if err := os.WriteFile(savedFilename, audioBytes, 0644); err != nil {
 				fileSaveMessage = fmt.Sprintf("Error writing audio file %s: %v. Audio data will be returned in response instead.", savedFilename, err)
-				log.Print(fileSaveMessage)
-				base64AudioData := base64.StdEncoding.EncodeToString(audioBytes)
-				contentItems = append(contentItems, mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: mimeType})
+				common.LogWarnf("%s", fileSaveMessage)
+				contentItems = append(contentItems, inlineOrSpillContent(ctx, audioBytes, filenamePrefix+fileExtension, mimeType))
 			} else {
 				fileSaveMessage = fmt.Sprintf("Audio saved to: %s (%d bytes).", savedFilename, len(audioBytes))
-				log.Print(fileSaveMessage)
+				outputURIs = append(outputURIs, savedFilename)
+				savedPath = savedFilename
+				common.LogInfof("%s", fileSaveMessage)
 			}
 		}
 	} else {
-		base64AudioData := base64.StdEncoding.EncodeToString(audioBytes)
-		contentItems = append(contentItems, mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: mimeType})
+		contentItems = append(contentItems, inlineOrSpillContent(ctx, audioBytes, filenamePrefix+fileExtension, mimeType))
 		fileSaveMessage = "Audio data is included in the response."
 	}
 
-	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s. %s", voiceName, fileSaveMessage)
+	if len(additionalFormats) > 0 {
+		inline := func(encoded []byte, format common.AudioFormat) mcp.Content {
+			return inlineOrSpillContent(ctx, encoded, filenamePrefix+format.Extension, format.MIMEType)
+		}
+		encodedItems, encodingMessages, encodedPaths := common.WriteAdditionalEncodings(ctx, savedPath, audioBytes, encodingFormat, additionalFormats, inline)
+		contentItems = append(contentItems, encodedItems...)
+		if len(encodingMessages) > 0 {
+			fileSaveMessage += " " + strings.Join(encodingMessages, " ")
+		}
+		outputURIs = append(outputURIs, encodedPaths...)
+	}
+	if tagMessage := appConfig.TagGeneratedAudioFiles(ctx, outputURIs, modelName, text); tagMessage != "" {
+		fileSaveMessage += " " + tagMessage
+	}
+
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "gemini_audio_tts",
+		Prompt:     text,
+		Model:      modelName,
+		Parameters: request.GetArguments(),
+		OutputURIs: outputURIs,
+	})
+
+	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s. %s%s", voiceName, clarityMessage, fileSaveMessage)
 	contentItems = append([]mcp.Content{mcp.TextContent{Type: "text", Text: resultText}}, contentItems...)
 
 	return &mcp.CallToolResult{Content: contentItems}, nil
 }
 
+// geminiPreviewVoiceHandler handles the 'gemini_preview_voice' tool request. It synthesizes a
+// short sample phrase with the requested voice under a tight timeout and returns the audio inline.
+func geminiPreviewVoiceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.LogInfof("Handling gemini_preview_voice request with arguments: %v", request.GetArguments())
+
+	voiceName, _ := request.GetArguments()["voice_name"].(string)
+	if !slices.Contains(availableGeminiVoices, voiceName) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid voice_name '%s'. Use 'list_gemini_voices' to see available voices", voiceName)), nil
+	}
+
+	text, _ := request.GetArguments()["text"].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = common.DefaultVoicePreviewText
+	}
+	if len([]rune(text)) > common.MaxVoicePreviewTextLength {
+		return mcp.NewToolResultError(fmt.Sprintf("text parameter cannot exceed %d characters for a voice preview", common.MaxVoicePreviewTextLength)), nil
+	}
+
+	modelName, _ := request.GetArguments()["model_name"].(string)
+	if modelName == "" {
+		modelName = defaultGeminiTTSModel
+	}
+	languageArg, _ := request.GetArguments()["language_code"].(string)
+	languageCode, err := resolveGeminiLanguageCode(languageArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	previewCtx, cancel := context.WithTimeout(ctx, common.VoicePreviewTimeout)
+	defer cancel()
+	audioBytes, err := callGeminiTTSAPI(previewCtx, text, "", voiceName, modelName, "LINEAR16", languageCode)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return mcp.NewToolResultError(fmt.Sprintf("voice preview timed out after %v", common.VoicePreviewTimeout)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini TTS API: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.TextContent{Type: "text", Text: fmt.Sprintf("Preview of voice %s: %q", voiceName, text)},
+		mcp.AudioContent{Type: "audio", Data: base64.StdEncoding.EncodeToString(audioBytes), MIMEType: "audio/wav"},
+	}}, nil
+}
+
 // --- API Helper Function ---
 
 func callGeminiTTSAPI(ctx context.Context, text, stylePrompt, voiceName, modelName, audioEncoding, languageCode string) ([]byte, error) {
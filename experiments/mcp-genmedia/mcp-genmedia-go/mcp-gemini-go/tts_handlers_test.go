@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveGeminiLanguageCode(t *testing.T) {
+	tests := []struct {
+		input       string
+		want        string
+		errContains string
+	}{
+		{"", "en-US", ""},
+		{"pt-BR", "pt-BR", ""},
+		{"PT-br", "pt-BR", ""},
+		{"portuguese (brazil)", "pt-BR", ""},
+		{"Portuguese (Brazil)", "pt-BR", ""},
+		{"thai", "th-TH", ""},
+		{"sw-TZ", "sw-TZ", ""},
+		{"portuguese", "", "ambiguous"},
+		{"klingon", "", "unsupported language_code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := resolveGeminiLanguageCode(tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveGeminiLanguageCodeListsChoices(t *testing.T) {
+	_, err := resolveGeminiLanguageCode("portuguese")
+	if err == nil {
+		t.Fatal("expected an ambiguity error, but got nil")
+	}
+	for _, choice := range []string{"portuguese (brazil) [pt-BR]", "portuguese (portugal) [pt-PT]"} {
+		if !strings.Contains(err.Error(), choice) {
+			t.Errorf("expected error to list %q, but got: %v", choice, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Imagen models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+const (
+	// maxBatchPrompts caps the number of prompts in a single imagen_batch call.
+	maxBatchPrompts = 20
+	// defaultBatchConcurrency is the number of prompts generated at the same time.
+	defaultBatchConcurrency = 4
+	// maxBatchConcurrency caps the 'concurrency' parameter of imagen_batch.
+	maxBatchConcurrency = 8
+)
+
+// batchSharedParams are the imagen_t2i parameters that apply to every prompt of a batch.
+var batchSharedParams = []string{"model", "num_images", "aspect_ratio", "image_size", "image_size_preference", "gcs_bucket_uri", "output_directory", common.RawPromptParam, common.EnhancePromptParam}
+
+// batchOverrideParams are the imagen_t2i parameters that a prompt object may override.
+var batchOverrideParams = []string{"model", "num_images", "aspect_ratio", "image_size", "seed"}
+
+// imagenBatchEntry is the result of one prompt of a batch.
+type imagenBatchEntry struct {
+	Index      int      `json:"index"`
+	Prompt     string   `json:"prompt"`
+	Status     string   `json:"status"` // "ok" or "error".
+	GCSURIs    []string `json:"gcsUris,omitempty"`
+	LocalFiles []string `json:"localFiles,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// imagenBatchResult is the structured content of the imagen_batch result.
+type imagenBatchResult struct {
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []imagenBatchEntry `json:"results"`
+}
+
+// addImagenBatchTool registers the 'imagen_batch' tool.
+func addImagenBatchTool(s *server.MCPServer) {
+	tool := mcp.NewTool("imagen_batch",
+		mcp.WithDescription(fmt.Sprintf("Generates images for a list of prompts in one call, one imagen_t2i generation per prompt, run concurrently. Returns a result for every prompt, in order, with its GCS URIs and local files or its error; a failed prompt does not stop the others. At most %d prompts.", maxBatchPrompts)),
+		mcp.WithArray("prompts",
+			mcp.Required(),
+			mcp.Description("The prompts. Each item is either a string or an object with 'prompt' and optional 'model', 'num_images', 'aspect_ratio', 'image_size', and 'seed' fields that override the shared parameters for that prompt."),
+			mcp.Items(map[string]any{
+				"anyOf": []map[string]any{
+					{"type": "string"},
+					{
+						"type": "object",
+						"properties": map[string]any{
+							"prompt":       map[string]any{"type": "string"},
+							"model":        map[string]any{"type": "string"},
+							"num_images":   map[string]any{"type": "number"},
+							"aspect_ratio": map[string]any{"type": "string"},
+							"image_size":   map[string]any{"type": "string"},
+							"seed":         map[string]any{"type": "number"},
+						},
+						"required": []string{"prompt"},
+					},
+				},
+			}),
+		),
+		mcp.WithString("model",
+			mcp.DefaultString("imagen-4.0-fast-generate-001"),
+			mcp.Description(common.BuildImagenModelDescription()),
+		),
+		mcp.WithNumber("num_images",
+			mcp.DefaultNumber(1),
+			mcp.Min(1),
+			mcp.Max(4),
+			mcp.Description("Number of images to generate per prompt (1-4). Note: the maximum is model-dependent."),
+		),
+		mcp.WithString("aspect_ratio",
+			mcp.DefaultString("1:1"),
+			mcp.Description("Aspect ratio of the generated images (e.g., \"1:1\", \"16:9\", \"9:16\")."),
+		),
+		mcp.WithString("image_size",
+			mcp.Description("Optional. The size of the largest dimension of the generated images, 1K or 2K (not supported for Imagen 3 models)."),
+		),
+		mcp.WithString("image_size_preference",
+			mcp.Enum(common.ImageSizePreferenceSmallest, common.ImageSizePreferenceLargest),
+			mcp.Description("Optional. Which supported size to use when image_size is omitted: 'smallest' or 'largest'."),
+		),
+		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store the generated images (e.g., your-bucket/outputs/ or gs://your-bucket/outputs/).")),
+		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save the generated images to. The images of each prompt are saved in a 'prompt-NN' subdirectory.")),
+		mcp.WithBoolean(common.RawPromptParam, mcp.Description("Optional. If true, the server-configured PROMPT_PREFIX/PROMPT_SUFFIX are not applied to the prompts.")),
+		mcp.WithBoolean(common.EnhancePromptParam, mcp.Description("Optional. If true, the model rewrites each prompt into a more detailed one before generating. Each prompt's result reports the original and the enhanced prompt.")),
+		mcp.WithNumber("concurrency",
+			mcp.DefaultNumber(defaultBatchConcurrency),
+			mcp.Min(1),
+			mcp.Max(maxBatchConcurrency),
+			mcp.Description(fmt.Sprintf("Optional. How many prompts to generate at the same time (1-%d).", maxBatchConcurrency)),
+		),
+	)
+	common.AddTool(s, appConfig, tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imagenBatchHandler(genAIClient, ctx, request)
+	})
+}
+
+// parseBatchPrompts turns the 'prompts' parameter into the imagen_t2i arguments of each prompt:
+// the shared parameters, the prompt, and the prompt's overrides.
+func parseBatchPrompts(args map[string]interface{}) ([]map[string]interface{}, error) {
+	items, ok := args["prompts"].([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("prompts must be a non-empty array")
+	}
+	if len(items) > maxBatchPrompts {
+		return nil, fmt.Errorf("at most %d prompts are supported per batch, got %d", maxBatchPrompts, len(items))
+	}
+
+	outputDir, _ := args["output_directory"].(string)
+	outputDir = strings.TrimSpace(outputDir)
+
+	var batch []map[string]interface{}
+	for i, item := range items {
+		promptArgs := make(map[string]interface{})
+		for _, name := range batchSharedParams {
+			if v, ok := args[name]; ok {
+				promptArgs[name] = v
+			}
+		}
+		switch v := item.(type) {
+		case string:
+			promptArgs["prompt"] = v
+		case map[string]interface{}:
+			promptArgs["prompt"], _ = v["prompt"].(string)
+			for _, name := range batchOverrideParams {
+				if override, ok := v[name]; ok {
+					promptArgs[name] = override
+				}
+			}
+		default:
+			return nil, fmt.Errorf("prompts[%d] must be a string or an object with a 'prompt' field", i)
+		}
+		if prompt, _ := promptArgs["prompt"].(string); strings.TrimSpace(prompt) == "" {
+			return nil, fmt.Errorf("prompts[%d] has an empty prompt", i)
+		}
+		if outputDir != "" {
+			// Files are named by model and time, so concurrent prompts need their own directories.
+			promptArgs["output_directory"] = filepath.Join(outputDir, fmt.Sprintf("prompt-%02d", i+1))
+		}
+		batch = append(batch, promptArgs)
+	}
+	return batch, nil
+}
+
+// imagenBatchHandler is the handler for the 'imagen_batch' tool. Each prompt is generated by
+// imagenGenerationHandler, with at most 'concurrency' generations running at the same time.
+func imagenBatchHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "imagen_batch")
+	defer span.End()
+
+	batch, err := parseBatchPrompts(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	concurrency := defaultBatchConcurrency
+	if v, ok := request.GetArguments()["concurrency"].(float64); ok {
+		concurrency = min(max(int(v), 1), maxBatchConcurrency)
+	}
+	span.SetAttributes(
+		attribute.Int("num_prompts", len(batch)),
+		attribute.Int("concurrency", concurrency),
+	)
+	common.LogInfof("Handling imagen_batch request: %d prompt(s), concurrency %d", len(batch), concurrency)
+
+	result, images := generateBatch(ctx, batch, concurrency, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imagenGenerationHandler(client, ctx, request)
+	})
+	span.SetAttributes(attribute.Int("failed", result.Failed))
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal batch results: %v", err)), nil
+	}
+	text := fmt.Sprintf("Generated images for %d of %d prompt(s).\n%s", result.Succeeded, len(result.Results), data)
+	toolResult := mcp.NewToolResultStructured(result, text)
+	// Images are only returned inline when no GCS or local output was requested.
+	for _, promptImages := range images {
+		toolResult.Content = append(toolResult.Content, promptImages...)
+	}
+	return toolResult, nil
+}
+
+// generateBatch runs generate for the arguments of each prompt, at most concurrency at a time,
+// and collects a result entry and the inline images of every prompt, in order.
+func generateBatch(ctx context.Context, batch []map[string]interface{}, concurrency int, generate func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) (imagenBatchResult, [][]mcp.Content) {
+	entries := make([]imagenBatchEntry, len(batch))
+	images := make([][]mcp.Content, len(batch))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, promptArgs := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			prompt, _ := promptArgs["prompt"].(string)
+			entries[i] = imagenBatchEntry{Index: i, Prompt: prompt}
+			result, err := generate(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: promptArgs}})
+			if err != nil {
+				entries[i].Status = "error"
+				entries[i].Error = err.Error()
+				return
+			}
+			var texts []string
+			for _, content := range result.Content {
+				switch c := content.(type) {
+				case mcp.TextContent:
+					texts = append(texts, c.Text)
+				case mcp.ImageContent:
+					images[i] = append(images[i], c)
+				}
+			}
+			// imagenGenerationHandler reports failures as text, without structured output.
+			output, ok := result.StructuredContent.(ImagenOutput)
+			if !ok {
+				entries[i].Status = "error"
+				entries[i].Error = strings.TrimSpace(strings.Join(texts, " "))
+				return
+			}
+			entries[i].Status = "ok"
+			entries[i].GCSURIs = output.GCSURIs
+			entries[i].LocalFiles = output.LocalFiles
+			entries[i].Message = output.Message
+		}()
+	}
+	wg.Wait()
+
+	result := imagenBatchResult{Results: entries}
+	for _, entry := range entries {
+		if entry.Status == "ok" {
+			result.Succeeded++
+		} else {
+			result.Failed++
+			common.LogWarnf("imagen_batch: prompt %d failed: %s", entry.Index, entry.Error)
+		}
+	}
+	return result, images
+}
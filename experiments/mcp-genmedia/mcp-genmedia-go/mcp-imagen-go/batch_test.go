@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseBatchPrompts(t *testing.T) {
+	tooMany := make([]interface{}, maxBatchPrompts+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("prompt %d", i)
+	}
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    []map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "shared parameters",
+			args: map[string]interface{}{"prompts": []interface{}{"a cat", "a dog"}, "model": "imagen-4.0-generate-001", "num_images": 2.0, "concurrency": 2.0},
+			want: []map[string]interface{}{
+				{"prompt": "a cat", "model": "imagen-4.0-generate-001", "num_images": 2.0},
+				{"prompt": "a dog", "model": "imagen-4.0-generate-001", "num_images": 2.0},
+			},
+		},
+		{
+			name: "per-prompt overrides",
+			args: map[string]interface{}{
+				"prompts": []interface{}{
+					"a cat",
+					map[string]interface{}{"prompt": "a dog", "model": "imagen-3.0-generate-002", "aspect_ratio": "16:9", "seed": 7.0, "gcs_bucket_uri": "ignored"},
+				},
+				"model":        "imagen-4.0-generate-001",
+				"aspect_ratio": "1:1",
+			},
+			want: []map[string]interface{}{
+				{"prompt": "a cat", "model": "imagen-4.0-generate-001", "aspect_ratio": "1:1"},
+				{"prompt": "a dog", "model": "imagen-3.0-generate-002", "aspect_ratio": "16:9", "seed": 7.0},
+			},
+		},
+		{
+			name: "per-prompt output directories",
+			args: map[string]interface{}{"prompts": []interface{}{"a cat", "a dog"}, "output_directory": " /tmp/out "},
+			want: []map[string]interface{}{
+				{"prompt": "a cat", "output_directory": filepath.Join("/tmp/out", "prompt-01")},
+				{"prompt": "a dog", "output_directory": filepath.Join("/tmp/out", "prompt-02")},
+			},
+		},
+		{
+			name:    "empty prompt",
+			args:    map[string]interface{}{"prompts": []interface{}{"a cat", "  "}},
+			wantErr: "prompts[1] has an empty prompt",
+		},
+		{
+			name:    "object without a prompt",
+			args:    map[string]interface{}{"prompts": []interface{}{map[string]interface{}{"model": "imagen-4.0-generate-001"}}},
+			wantErr: "prompts[0] has an empty prompt",
+		},
+		{
+			name:    "invalid item",
+			args:    map[string]interface{}{"prompts": []interface{}{"a cat", 3.0}},
+			wantErr: "prompts[1] must be a string or an object",
+		},
+		{
+			name:    "no prompts",
+			args:    map[string]interface{}{"prompts": []interface{}{}},
+			wantErr: "prompts must be a non-empty array",
+		},
+		{
+			name:    "too many prompts",
+			args:    map[string]interface{}{"prompts": tooMany},
+			wantErr: fmt.Sprintf("at most %d prompts are supported per batch, got %d", maxBatchPrompts, maxBatchPrompts+1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBatchPrompts(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateBatchPartialFailure(t *testing.T) {
+	batch := []map[string]interface{}{
+		{"prompt": "a cat"},
+		{"prompt": "a dog"},
+		{"prompt": "a bird"},
+		{"prompt": "a fish"},
+	}
+	var running, maxRunning atomic.Int32
+	generate := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		switch prompt := request.GetArguments()["prompt"].(string); prompt {
+		case "a dog":
+			return mcp.NewToolResultError("quota exceeded"), nil
+		case "a bird":
+			return nil, errors.New("connection reset")
+		default:
+			result := mcp.NewToolResultImage("Generated "+prompt, "ZGF0YQ==", "image/png")
+			result.StructuredContent = ImagenOutput{GCSURIs: []string{"gs://bucket/" + prompt + ".png"}, Message: "Generated " + prompt}
+			return result, nil
+		}
+	}
+
+	result, images := generateBatch(context.Background(), batch, 2, generate)
+
+	if result.Succeeded != 2 || result.Failed != 2 {
+		t.Errorf("expected 2 succeeded and 2 failed, but got %d and %d", result.Succeeded, result.Failed)
+	}
+	want := []imagenBatchEntry{
+		{Index: 0, Prompt: "a cat", Status: "ok", GCSURIs: []string{"gs://bucket/a cat.png"}, Message: "Generated a cat"},
+		{Index: 1, Prompt: "a dog", Status: "error", Error: "quota exceeded"},
+		{Index: 2, Prompt: "a bird", Status: "error", Error: "connection reset"},
+		{Index: 3, Prompt: "a fish", Status: "ok", GCSURIs: []string{"gs://bucket/a fish.png"}, Message: "Generated a fish"},
+	}
+	if !reflect.DeepEqual(result.Results, want) {
+		t.Errorf("expected results %+v, but got %+v", want, result.Results)
+	}
+	for i, wantImages := range []int{1, 0, 0, 1} {
+		if len(images[i]) != wantImages {
+			t.Errorf("prompt %d: expected %d inline image(s), but got %d", i, wantImages, len(images[i]))
+		}
+	}
+	if got := maxRunning.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent generations, but got %d", got)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -45,7 +44,7 @@ func registerImagenEditingTools(s *server.MCPServer, client *genai.Client, appCo
 	})
 
 	// Inpainting Insert Tool
-	s.AddTool(mcp.NewTool("imagen_edit_inpainting_insert",
+	common.AddTool(s, appConfig, mcp.NewTool("imagen_edit_inpainting_insert",
 		mcp.WithDescription("Adds content to a masked area of an image."),
 		mcp.WithString("prompt", mcp.Required(), mcp.Description("A description of the content to add.")),
 		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit.")),
@@ -57,7 +56,7 @@ func registerImagenEditingTools(s *server.MCPServer, client *genai.Client, appCo
 	})
 
 	// Inpainting Remove Tool
-	s.AddTool(mcp.NewTool("imagen_edit_inpainting_remove",
+	common.AddTool(s, appConfig, mcp.NewTool("imagen_edit_inpainting_remove",
 		mcp.WithDescription("Removes content from a masked area of an image."),
 		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit.")),
 		mcp.WithString("mask_mode", mcp.Required(), mcp.Description("The masking mode to use (e.g., MASK_MODE_FOREGROUND, MASK_MODE_SEMANTIC).")),
@@ -213,23 +212,38 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 
 	// Call the EditImage method
 	referenceImagesJSON, _ := json.MarshalIndent(referenceImages, "", "  ")
-	log.Printf("Calling EditImage with referenceImages:\n%s", string(referenceImagesJSON))
+	common.LogDebugf("Calling EditImage with referenceImages:\n%s", string(referenceImagesJSON))
 	editConfigJSON, _ := json.MarshalIndent(editConfig, "", "  ")
-	log.Printf("Calling EditImage with editConfig:\n%s", string(editConfigJSON))
+	common.LogDebugf("Calling EditImage with editConfig:\n%s", string(editConfigJSON))
 
-	response, err := client.Models.EditImage(
-		ctx,
-		"imagen-3.0-capability-001",
-		prompt,
-		referenceImages,
-		editConfig,
-	)
+	const editModel = "imagen-3.0-capability-001"
+	var response *genai.EditImageResponse
+	err = common.GenAIBreaker.Execute(ctx, func(ctx context.Context) error {
+		var callErr error
+		response, callErr = client.Models.EditImage(
+			ctx,
+			editModel,
+			prompt,
+			referenceImages,
+			editConfig,
+		)
+		return callErr
+	})
 	if err != nil {
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       request.Params.Name,
+			Prompt:     prompt,
+			Model:      editModel,
+			Parameters: args,
+			Error:      err.Error(),
+		})
 		return mcp.NewToolResultError(fmt.Sprintf("error editing image: %v", err)), nil
 	}
 
 	// Process the response
 	var statusText string
+	var outputURIs []string
 
 	if len(response.GeneratedImages) > 0 {
 		genImg := response.GeneratedImages[0]
@@ -239,12 +253,26 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 			filename := fmt.Sprintf("edited-image-%d.png", time.Now().UnixNano())
 			// Now, upload the image to GCS.
 			if err := common.UploadToGCS(ctx, appConfig.GenmediaBucket, filename, "image/png", genImg.Image.ImageBytes); err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("error uploading edited image to GCS: %v", err)), nil
+				errMsg := fmt.Sprintf("error uploading edited image to GCS: %v", err)
+				common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+					Service:    serviceName,
+					Tool:       request.Params.Name,
+					Prompt:     prompt,
+					Model:      editModel,
+					Parameters: args,
+					Error:      errMsg,
+				})
+				return mcp.NewToolResultError(errMsg), nil
 			}
 			gcsURI := fmt.Sprintf("gs://%s/%s", appConfig.GenmediaBucket, filename)
+			outputURIs = append(outputURIs, gcsURI)
 			statusText = fmt.Sprintf("Image edited successfully. Edited image URI: %s", gcsURI)
 		} else if genImg.Image != nil && genImg.Image.GCSURI != "" {
 			// The image is already in GCS.
+			if err := common.SetGCSObjectMetadata(ctx, genImg.Image.GCSURI, genImg.Image.MIMEType); err != nil {
+				common.LogWarnf("Warning: could not set the metadata of edited image %s: %v", genImg.Image.GCSURI, err)
+			}
+			outputURIs = append(outputURIs, genImg.Image.GCSURI)
 			statusText = fmt.Sprintf("Image edited successfully. Edited image URI: %s", genImg.Image.GCSURI)
 		} else {
 			statusText = "Image editing did not produce any images."
@@ -262,5 +290,14 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 	}
 	resultText += statusText
 
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       request.Params.Name,
+		Prompt:     prompt,
+		Model:      editModel,
+		Parameters: args,
+		OutputURIs: outputURIs,
+	})
+
 	return mcp.NewToolResultText(resultText), nil
 }
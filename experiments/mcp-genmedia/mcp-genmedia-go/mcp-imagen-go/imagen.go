@@ -27,6 +27,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -43,6 +44,7 @@ import (
 var (
 	appConfig   *common.Config
 	genAIClient *genai.Client // Global GenAI client
+	imageCache  *common.ResponseCache // Cache of seeded generation responses; nil when disabled.
 	transport   string
 	port        int
 )
@@ -58,40 +60,41 @@ func init() {
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse, or http)")
 	flag.IntVar(&port, "p", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
 	flag.IntVar(&port, "port", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
-	flag.Parse()
 }
 
 // main is the entry point for the mcp-imagen-go service.
 func main() {
+	flag.Parse() // Ensure flags are parsed before use
 
 	var cleanup func()
 	appConfig, cleanup = common.Init(serviceName, version)
 	defer cleanup()
+	imageCache = common.NewResponseCache(appConfig.ResponseCacheSize, appConfig.ResponseCacheTTL)
 	var err error
 
-	log.Printf("Initializing global GenAI client...")
+	common.LogInfof("Initializing global GenAI client...")
 	clientCtx, clientCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer clientCancel()
 
-	clientConfig := &genai.ClientConfig{
-		Backend:  genai.BackendVertexAI,
-		Project:  appConfig.ProjectID,
-		Location: appConfig.Location,
-	}
-	if appConfig.ApiEndpoint != "" {
-		log.Printf("Using custom Vertex AI endpoint: %s", appConfig.ApiEndpoint)
-		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
+	clientConfig := appConfig.NewGenAIClientConfig(appConfig.Location)
+
+	if err := common.ApplyGenAITransportSettings(clientConfig); err != nil {
+		common.LogWarnf("Warning: Failed to apply GenAI transport settings: %v", err)
 	}
 
 	if err := common.InjectCaptureHeaders(clientCtx, appConfig, clientConfig); err != nil {
-		log.Printf("Warning: Failed to inject capture headers: %v", err)
+		common.LogWarnf("Warning: Failed to inject capture headers: %v", err)
 	}
 
-	genAIClient, err = genai.NewClient(clientCtx, clientConfig)
-	if err != nil {
-		log.Printf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
+	genAIClient, err = common.NewGenAIClientWithRetry(clientCtx, func(ctx context.Context) (*genai.Client, error) {
+		return genai.NewClient(ctx, clientConfig)
+	})
+	if err != nil && !common.IsTransientClientError(err) {
+		common.LogFatalf("Error creating global GenAI client: %v. Check the credentials and GENAI_BACKEND, PROJECT_ID and GEMINI_API_KEY settings.", err)
+	} else if err != nil {
+		common.LogWarnf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
 	} else {
-		log.Printf("Global GenAI client initialized successfully.")
+		common.LogInfof("Global GenAI client initialized successfully.")
 	}
 
 	s := server.NewMCPServer("Imagen", version, server.WithResourceCapabilities(true, true))
@@ -132,20 +135,27 @@ func main() {
 		),
 		mcp.WithString("aspect_ratio",
 			mcp.DefaultString("1:1"),
-			mcp.Description("Aspect ratio of the generated images (e.g., \"1:1\", \"16:9\", \"9:16\")."),
+			mcp.Description("Aspect ratio of the generated images (e.g., \"1:1\", \"16:9\", \"9:16\"). Forms such as \"16x9\", \"16/9\", \"1.77\", or \"landscape\" are normalized to W:H."),
 		),
 		mcp.WithString("image_size",
-			mcp.DefaultString("1K"),
-			mcp.Description("Optional. The size of the largest dimension of the generated image. Supported sizes are 1K and 2K (not supported for Imagen 3 models)."),
+			mcp.Description("Optional. The size of the largest dimension of the generated image. Supported sizes are 1K and 2K (not supported for Imagen 3 models). If omitted, a supported size is chosen for the aspect ratio according to image_size_preference."),
+		),
+		mcp.WithString("image_size_preference",
+			mcp.Enum(common.ImageSizePreferenceSmallest, common.ImageSizePreferenceLargest),
+			mcp.Description("Optional. Which supported size to use when image_size is omitted: 'smallest' or 'largest'. Defaults to IMAGEN_IMAGE_SIZE_PREFERENCE, or 'smallest'."),
 		),
 		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store the generated images (e.g., your-bucket/outputs/ or gs://your-bucket/outputs/).")),
 		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save the generated image(s) to.")),
+		mcp.WithBoolean(common.RawPromptParam, mcp.Description("Optional. If true, the server-configured PROMPT_PREFIX/PROMPT_SUFFIX are not applied to the prompt.")),
+		mcp.WithBoolean(common.EnhancePromptParam, mcp.Description("Optional. If true, the model rewrites the prompt into a more detailed one before generating. The result reports the original and the enhanced prompt.")),
+		mcp.WithNumber("seed", mcp.Description("Optional. Random seed for deterministic generation. Setting a seed disables the SynthID watermark. Seeded requests are answered from the response cache when GENERATION_CACHE_SIZE is set.")),
 	)
 
 	handlerWithClient := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return imagenGenerationHandler(genAIClient, ctx, request)
 	}
-	s.AddTool(tool, handlerWithClient)
+	common.AddTool(s, appConfig, tool, handlerWithClient)
+	addImagenBatchTool(s)
 
 	s.AddPrompt(mcp.NewPrompt("generate-image",
 		mcp.WithPromptDescription("Generates an image from a text prompt."),
@@ -192,6 +202,10 @@ func main() {
 		), nil
 	})
 
+	common.AddGenerationDefaultsTool(s, appConfig, common.ModelFamilyImagen)
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
+
 	switch transport {
 	case "sse":
 		ssePort := 8081 // Default SSE port
@@ -200,10 +214,10 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting Imagen MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
+		common.LogInfof("Starting Imagen MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -212,7 +226,7 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting Imagen MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
+		common.LogInfof("Starting Imagen MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
 		mcpHTTPHandler := server.NewStreamableHTTPServer(s) // Base path /mcp
 		c := cors.New(cors.Options{
 			AllowedOrigins:   []string{"*"},
@@ -224,25 +238,30 @@ func main() {
 		})
 		handlerWithCORS := c.Handler(mcpHTTPHandler)
 		listenAddr := fmt.Sprintf(":%d", httpPort)
-		if err := http.ListenAndServe(listenAddr, handlerWithCORS); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		httpServer := common.NewHTTPServer(listenAddr, handlerWithCORS)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting Imagen MCP Server (Version: %s, Transport: stdio)", version)
+		common.LogInfof("Starting Imagen MCP Server (Version: %s, Transport: stdio)", version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 
-	log.Println("Imagen Server has stopped.")
+	common.LogInfof("Imagen Server has stopped.")
 }
 
+// ImagenOutput is the structured content of a successful imagen_t2i result.
 type ImagenOutput struct {
-	GCSURIs   []string `json:"gcsUris"`
-	HTTPSURLs []string `json:"httpsURLs"`
-	Message   string   `json:"message"`
+	GCSURIs         []string `json:"gcsUris"`
+	HTTPSURLs       []string `json:"httpsURLs"`
+	LocalFiles      []string `json:"localFiles,omitempty"`
+	Prompt          string   `json:"prompt,omitempty"`
+	EnhancedPrompts []string `json:"enhancedPrompts,omitempty"`
+	Message         string   `json:"message"`
 }
 
 func contains(s []string, e string) bool {
@@ -263,10 +282,13 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 	if !ok {
 		return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: prompt must be a string and is required"}}}, nil
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
+	enhancePrompt, _ := request.GetArguments()[common.EnhancePromptParam].(bool)
 
 	modelInput, ok := request.GetArguments()["model"].(string)
 	if !ok || modelInput == "" {
-		log.Printf("Model not provided or empty, using default: imagen-4.0-fast-generate-001")
+		common.LogInfof("Model not provided or empty, using default: imagen-4.0-fast-generate-001")
 		modelInput = "imagen-4.0-fast-generate-001"
 	}
 
@@ -282,7 +304,7 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		if numImagesFloat, okFloat := numImagesArg.(float64); okFloat {
 			numberOfImages = int32(numImagesFloat)
 		} else {
-			log.Printf("Warning: num_images was not a float64, received %T. Using default.", numImagesArg)
+			common.LogWarnf("Warning: num_images was not a float64, received %T. Using default.", numImagesArg)
 		}
 	}
 
@@ -290,18 +312,24 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		numberOfImages = 1
 	}
 	if numberOfImages > modelDetails.MaxImages {
-		log.Printf("Warning: Requested %d images, but model %s only supports up to %d. Adjusting to max.", numberOfImages, model, modelDetails.MaxImages)
+		common.LogWarnf("Warning: Requested %d images, but model %s only supports up to %d. Adjusting to max.", numberOfImages, model, modelDetails.MaxImages)
 		numberOfImages = modelDetails.MaxImages
 	}
 
-	aspectRatio, ok := request.GetArguments()["aspect_ratio"].(string)
-	if !ok || aspectRatio == "" {
-		log.Printf("Aspect ratio not provided or empty, using default: 1:1")
+	aspectRatio, _ := request.GetArguments()["aspect_ratio"].(string)
+	if normalized, err := common.NormalizeAspectRatio(aspectRatio, modelDetails.SupportedAspectRatios); err != nil {
+		common.LogWarnf("Warning: %v. Falling back to '1:1'.", err)
+		aspectRatio = "1:1"
+	} else {
+		aspectRatio = normalized
+	}
+	if aspectRatio == "" {
+		common.LogInfof("Aspect ratio not provided or empty, using default: 1:1")
 		aspectRatio = "1:1"
 	}
 
 	if !contains(modelDetails.SupportedAspectRatios, aspectRatio) {
-		log.Printf("Warning: Requested aspect ratio '%s' is not supported by model %s. Supported ratios are: %v. Falling back to '1:1'.", aspectRatio, model, modelDetails.SupportedAspectRatios)
+		common.LogWarnf("Warning: Requested aspect ratio '%s' is not supported by model %s. Supported ratios are: %v. Falling back to '1:1'.", aspectRatio, model, modelDetails.SupportedAspectRatios)
 		aspectRatio = "1:1" // Fallback to a safe default
 	}
 
@@ -309,12 +337,22 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 	var finalImageSize string
 	if imageSize != "" {
 		if len(modelDetails.SupportedImageSizes) == 0 {
-			log.Printf("Warning: image_size parameter ('%s') provided, but model %s does not support it. The parameter will be ignored.", imageSize, model)
+			common.LogWarnf("Warning: image_size parameter ('%s') provided, but model %s does not support it. The parameter will be ignored.", imageSize, model)
 		} else if !contains(modelDetails.SupportedImageSizes, imageSize) {
-			log.Printf("Warning: Requested image size '%s' is not supported by model %s. Supported sizes are: %v. The parameter will be ignored.", imageSize, model, modelDetails.SupportedImageSizes)
+			common.LogWarnf("Warning: Requested image size '%s' is not supported by model %s. Supported sizes are: %v. The parameter will be ignored.", imageSize, model, modelDetails.SupportedImageSizes)
 		} else {
 			finalImageSize = imageSize
 		}
+	}
+	if finalImageSize == "" && len(modelDetails.SupportedImageSizes) > 0 {
+		sizePreference, _ := request.GetArguments()["image_size_preference"].(string)
+		preference, err := appConfig.ResolveImageSizePreference(sizePreference)
+		if err != nil {
+			common.LogWarnf("Warning: %v. The parameter will be ignored.", err)
+			preference, _ = appConfig.ResolveImageSizePreference("")
+		}
+		finalImageSize = modelDetails.DefaultImageSize(aspectRatio, preference)
+		common.LogInfof("Image size not provided, using the %s size supported by model %s for aspect ratio %s: %s", preference, model, aspectRatio, finalImageSize)
 	} // ... rest of handler ...
 	gcsOutputURI := ""
 	gcsBucketUriParam, _ := request.GetArguments()["gcs_bucket_uri"].(string)
@@ -324,18 +362,24 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		gcsOutputURI = gcsBucketUriParam
 		if !strings.HasPrefix(gcsOutputURI, "gs://") {
 			gcsOutputURI = "gs://" + gcsOutputURI
-			log.Printf("gcs_bucket_uri did not start with 'gs://', prepended. New URI: %s", gcsOutputURI)
+			common.LogInfof("gcs_bucket_uri did not start with 'gs://', prepended. New URI: %s", gcsOutputURI)
 		}
 	} else if appConfig.GenmediaBucket != "" {
 		gcsOutputURI = fmt.Sprintf("gs://%s/imagen_outputs/", appConfig.GenmediaBucket)
-		log.Printf("Handler imagen_t2i: 'gcs_bucket_uri' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsOutputURI)
+		common.LogInfof("Handler imagen_t2i: 'gcs_bucket_uri' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsOutputURI)
 	} else {
-		log.Printf("Handler imagen_t2i: 'gcs_bucket_uri' parameter and GENMEDIA_BUCKET env var are both empty. No GCS output will be saved.")
+		common.LogWarnf("Handler imagen_t2i: 'gcs_bucket_uri' parameter and GENMEDIA_BUCKET env var are both empty. No GCS output will be saved.")
 	}
 
 	if gcsOutputURI != "" && !strings.HasSuffix(gcsOutputURI, "/") {
 		gcsOutputURI += "/"
-		log.Printf("Appended '/' to gcsOutputURI for directory structure. New URI: %s", gcsOutputURI)
+		common.LogInfof("Appended '/' to gcsOutputURI for directory structure. New URI: %s", gcsOutputURI)
+	}
+
+	var seed *int32
+	if seedArg, ok := request.GetArguments()["seed"].(float64); ok {
+		seedValue := int32(seedArg)
+		seed = &seedValue
 	}
 
 	outputDir := ""
@@ -357,10 +401,10 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 	select {
 	case <-ctx.Done():
 		errMsg := fmt.Sprintf("Request processing canceled early: %v", ctx.Err())
-		log.Printf("Incoming context for prompt \"%s\" was already canceled: %v", prompt, ctx.Err())
+		common.LogWarnf("Incoming context for prompt \"%s\" was already canceled: %v", prompt, ctx.Err())
 		return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: errMsg}}}, nil
 	default:
-		log.Printf("Handling imagen request: Prompt=\"%s\", Model=%s, NumImages=%d, AspectRatio=%s, ImageSize=%s, GCSOutputURI='%s', OutputDirectory='%s'",
+		common.LogInfof("Handling imagen request: Prompt=\"%s\", Model=%s, NumImages=%d, AspectRatio=%s, ImageSize=%s, GCSOutputURI='%s', OutputDirectory='%s'",
 			prompt, model, numberOfImages, aspectRatio, finalImageSize, gcsOutputURI, outputDir)
 	}
 
@@ -369,24 +413,49 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		AspectRatio:    aspectRatio,
 		ImageSize:      finalImageSize,
 		OutputGCSURI:   gcsOutputURI,
+		EnhancePrompt:  enhancePrompt,
+	}
+	if seed != nil {
+		// The API only honors a seed when watermarking is off.
+		config.Seed = seed
+		config.AddWatermark = false
 	}
 
 	apiCallCtx, apiCallCancel := context.WithTimeout(ctx, 3*time.Minute)
 	defer apiCallCancel()
 
-	log.Printf("Calling GenerateImages with Model: %s, Prompt: \"%s\". API call timeout: 3m", model, prompt)
-	startTime := time.Now()
+	// Only seeded requests are deterministic, so only they are cached.
+	var cacheKey string
+	if seed != nil && imageCache != nil {
+		cacheKey = common.ResponseCacheKey(model, prompt, *seed, aspectRatio, finalImageSize, numberOfImages, config.SafetyFilterLevel, config.PersonGeneration, gcsOutputURI, enhancePrompt)
+	}
 
-	response, err := client.Models.GenerateImages(
-		apiCallCtx,
-		model,
-		prompt,
-		config,
-	)
+	startTime := time.Now()
+	var response *genai.GenerateImagesResponse
+	var err error
+	cacheHit := false
+	if cached, ok := imageCache.Get(cacheKey); cacheKey != "" && ok {
+		response = cached.(*genai.GenerateImagesResponse)
+		cacheHit = true
+		common.LogInfof("Image cache hit for Model: %s, Prompt: \"%s\", Seed: %d", model, prompt, *seed)
+	} else {
+		common.LogInfof("Calling GenerateImages with Model: %s, Prompt: \"%s\". API call timeout: 3m", model, prompt)
+		err = common.GenAIBreaker.Execute(apiCallCtx, func(ctx context.Context) error {
+			var callErr error
+			response, callErr = client.Models.GenerateImages(ctx, model, prompt, config)
+			return callErr
+		})
+		if err == nil && cacheKey != "" && response != nil && len(response.GeneratedImages) > 0 {
+			imageCache.Put(cacheKey, response)
+		}
+	}
 
 	apiCallDuration := time.Since(startTime)
-	log.Printf("GenerateImages call took: %v", apiCallDuration)
-	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
+	common.LogInfof("GenerateImages call took: %v", apiCallDuration)
+	span.SetAttributes(
+		attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())),
+		attribute.Bool("cache_hit", cacheHit),
+	)
 
 	var contentItems []mcp.Content
 
@@ -400,37 +469,49 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 	if err != nil {
 		errorMessage := fmt.Sprintf("error generating images: %v", err.Error())
 		if errors.Is(err, context.DeadlineExceeded) && apiCallCtx.Err() == context.DeadlineExceeded {
-			log.Printf("GenerateImages failed due to API call timeout (3 minutes): %v", err)
+			common.LogErrorf("GenerateImages failed due to API call timeout (3 minutes): %v", err)
 			errorMessage = "image generation timed out"
 		} else if errors.Is(err, context.Canceled) {
-			log.Printf("GenerateImages failed due to context cancellation: %v", err)
+			common.LogErrorf("GenerateImages failed due to context cancellation: %v", err)
 			errorMessage = "image generation was canceled"
 		} else {
-			log.Printf("Error generating images (API call failed): %v", err)
+			common.LogErrorf("Error generating images (API call failed): %v", err)
 		}
 		span.RecordError(err)
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "imagen_t2i",
+			Prompt:     prompt,
+			Model:      model,
+			Parameters: request.GetArguments(),
+			Error:      errorMessage,
+		})
 		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: errorMessage})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 
 	if response == nil || len(response.GeneratedImages) == 0 {
 		noImageText := fmt.Sprintf("Sorry, I couldn't generate any images for the prompt \"%s\".", prompt)
-		log.Print(noImageText)
+		common.LogWarnf("%s", noImageText)
 		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: noImageText})
 		return &mcp.CallToolResult{Content: contentItems}, nil
 	}
 
-	log.Printf("Successfully received %d image metadata/references from API.", len(response.GeneratedImages))
+	common.LogInfof("Successfully received %d image metadata/references from API.", len(response.GeneratedImages))
 
 	var savedLocalFilenames []string
 	var failedLocalSaveReasons []string
+	var enhancedPrompts []string
 	var gcsSavedURIs []string
 	var totalSizeBytesGenerated int64 = 0
 	imagesWithDataOrURI := 0
 	returnImageDataInResponse := gcsOutputURI == "" && !attemptLocalSave
-	log.Printf("Will return image data in response: %t", returnImageDataInResponse)
+	common.LogInfof("Will return image data in response: %t", returnImageDataInResponse)
 
 	for n, genImg := range response.GeneratedImages {
+		if genImg.EnhancedPrompt != "" {
+			enhancedPrompts = append(enhancedPrompts, genImg.EnhancedPrompt)
+		}
 		var imageData []byte
 		imageMimeType := "image/png"
 		imageSourceIsGCS := false
@@ -441,10 +522,13 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 			imagesWithDataOrURI++
 			imageSourceIsGCS = true
 			gcsSavedURIs = append(gcsSavedURIs, currentImageGCSURI)
-			log.Printf("Image %d available at GCS URI (from API response): %s", n, currentImageGCSURI)
+			common.LogInfof("Image %d available at GCS URI (from API response): %s", n, currentImageGCSURI)
 			if genImg.Image.MIMEType != "" {
 				imageMimeType = genImg.Image.MIMEType
 			}
+			if err := common.SetGCSObjectMetadata(ctx, currentImageGCSURI, imageMimeType); err != nil {
+				common.LogWarnf("Warning: could not set the metadata of image %d at %s: %v", n, currentImageGCSURI, err)
+			}
 		} else if genImg.Image != nil && genImg.Image.ImageBytes != nil && len(genImg.Image.ImageBytes) > 0 {
 			imagesWithDataOrURI++
 			imageData = genImg.Image.ImageBytes
@@ -452,9 +536,9 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 			if genImg.Image.MIMEType != "" {
 				imageMimeType = genImg.Image.MIMEType
 			}
-			log.Printf("Image %d received as bytes from API (Size: %s, MIME: %s)", n, common.FormatBytes(int64(len(imageData))), imageMimeType)
+			common.LogInfof("Image %d received as bytes from API (Size: %s, MIME: %s)", n, common.FormatBytes(int64(len(imageData))), imageMimeType)
 		} else {
-			log.Printf("Generated image %d (model: %s) from API had no GCS URI and no direct image data.", n, model)
+			common.LogWarnf("Generated image %d (model: %s) from API had no GCS URI and no direct image data.", n, model)
 			continue
 		}
 
@@ -472,33 +556,33 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 			actualSavePath = filepath.Clean(actualSavePath)
 
 			if imageSourceIsGCS {
-				log.Printf("Attempting to download image %d from GCS URI %s to %s", n, currentImageGCSURI, actualSavePath)
+				common.LogInfof("Attempting to download image %d from GCS URI %s to %s", n, currentImageGCSURI, actualSavePath)
 				downloadCtx, downloadCancel := context.WithTimeout(ctx, 2*time.Minute)
-				err := common.DownloadFromGCS(downloadCtx, currentImageGCSURI, actualSavePath)
+				err := common.DownloadGeneratedFromGCS(downloadCtx, currentImageGCSURI, actualSavePath)
 				downloadCancel()
 				if err != nil {
-					log.Print(err)
+					common.LogErrorf("%v", err)
 					failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
 				} else {
-					log.Printf("Successfully downloaded and saved image %d to %s", n, actualSavePath)
+					common.LogInfof("Successfully downloaded and saved image %d to %s", n, actualSavePath)
 					savedLocalFilenames = append(savedLocalFilenames, actualSavePath)
 					fileInfo, statErr := os.Stat(actualSavePath)
 					if statErr == nil {
 						totalSizeBytesGenerated += fileInfo.Size()
 					} else {
-						log.Printf("Could not get file info for downloaded file %s: %v", actualSavePath, statErr)
+						common.LogWarnf("Could not get file info for downloaded file %s: %v", actualSavePath, statErr)
 					}
 				}
 			} else if len(imageData) > 0 {
 				if err := os.MkdirAll(outputDir, 0755); err != nil {
-					log.Print(err)
+					common.LogErrorf("%v", err)
 					failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
 				} else {
 					if err := os.WriteFile(actualSavePath, imageData, 0644); err != nil {
-						log.Print(err)
+						common.LogErrorf("%v", err)
 						failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
 					} else {
-						log.Printf("Saved image %s (Size: %s)", actualSavePath, common.FormatBytes(int64(len(imageData))))
+						common.LogInfof("Saved image %s (Size: %s)", actualSavePath, common.FormatBytes(int64(len(imageData))))
 						savedLocalFilenames = append(savedLocalFilenames, actualSavePath)
 					}
 				}
@@ -519,12 +603,12 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 	var resultText string
 	var saveMessageParts []string
 
+	httpURIs := make([]string, len(gcsSavedURIs))
+	for i, gcsUri := range gcsSavedURIs {
+		httpURIs[i] = strings.Replace(gcsUri, "gs://", "https://storage.mtls.cloud.google.com/", 1)
+	}
 	if gcsOutputURI != "" {
 		if len(gcsSavedURIs) > 0 {
-			httpURIs := make([]string, len(gcsSavedURIs))
-			for i, gcsUri := range gcsSavedURIs {
-				httpURIs[i] = strings.Replace(gcsUri, "gs://", "https://storage.mtls.cloud.google.com/", 1)
-			}
 			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Images saved to GCS: %s. HTTPS URLs: %s.", strings.Join(gcsSavedURIs, ", "), strings.Join(httpURIs, ", ")))
 		} else if imagesWithDataOrURI > 0 && len(gcsSavedURIs) == 0 {
 			saveMessageParts = append(saveMessageParts, fmt.Sprintf("GCS output was requested to '%s', but API did not return GCS URIs for the generated images.", config.OutputGCSURI))
@@ -547,6 +631,12 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		}
 	}
 
+	if enhancement := common.DescribePromptEnhancement(prompt, enhancedPrompts, enhancePrompt); enhancement != "" {
+		saveMessageParts = append(saveMessageParts, enhancement)
+	}
+	if cacheHit {
+		saveMessageParts = append(saveMessageParts, "Returned a cached result for this seeded request.")
+	}
 	if !returnImageDataInResponse {
 		saveMessageParts = append(saveMessageParts, "Image data is not included in this MCP response because a GCS URI or local output directory was specified.")
 	} else if returnImageDataInResponse && imagesWithDataOrURI > 0 {
@@ -582,10 +672,30 @@ func imagenGenerationHandler(client *genai.Client, ctx context.Context, request
 		Text: strings.TrimSpace(resultText),
 	}
 
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "imagen_t2i",
+		Prompt:     prompt,
+		Model:      model,
+		Parameters: request.GetArguments(),
+		OutputURIs: slices.Concat(gcsSavedURIs, savedLocalFilenames),
+	})
+
 	finalContentItems := []mcp.Content{textItem}
 	if returnImageDataInResponse {
 		finalContentItems = append(finalContentItems, contentItems...)
 	}
 
-	return &mcp.CallToolResult{Content: finalContentItems}, nil
+	result := &mcp.CallToolResult{Content: finalContentItems}
+	if imagesWithDataOrURI > 0 {
+		result.StructuredContent = ImagenOutput{
+			GCSURIs:         gcsSavedURIs,
+			HTTPSURLs:       httpURIs,
+			LocalFiles:      savedLocalFilenames,
+			Prompt:          prompt,
+			EnhancedPrompts: enhancedPrompts,
+			Message:         textItem.Text,
+		}
+	}
+	return result, nil
 }
@@ -7,9 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
@@ -17,7 +17,7 @@ import (
 // generateAudioWithInteractions uses the experimental Interactions API to generate audio
 // for newer Lyria models like lyria-3-pro-preview and lyria-3-clip-preview.
 func generateAudioWithInteractions(ctx context.Context, modelID string, prompt string) ([]byte, string, error) {
-	log.Printf("Using Interactions API for model: %s", modelID)
+	common.LogInfof("Using Interactions API for model: %s", modelID)
 
 	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
 	if err != nil {
@@ -27,7 +27,7 @@ func generateAudioWithInteractions(ctx context.Context, modelID string, prompt s
 
 	endpoint := "aiplatform.googleapis.com"
 	if appConfig.ApiEndpoint != "" {
-		endpoint = appConfig.ApiEndpoint
+		endpoint = appConfig.APIEndpointHost()
 	}
 
 	// Lyria 3 preview models via the Interactions API currently only support the global location ("Cardolan").
@@ -85,21 +85,21 @@ func main() {
 	defer cleanup()
 	var err error
 
-	log.Println("Initializing global AI Platform Prediction client...")
+	common.LogInfof("Initializing global AI Platform Prediction client...")
 	regionalEndpoint := fmt.Sprintf("%s-aiplatform.googleapis.com:443", appConfig.Location)
 	predictionClient, err = aiplatform.NewPredictionClient(context.Background(), option.WithEndpoint(regionalEndpoint))
 	if err != nil {
-		log.Printf("Warning: Failed to create global AI Platform Prediction client: %v. Deferring to runtime.", err)
+		common.LogWarnf("Warning: Failed to create global AI Platform Prediction client: %v. Deferring to runtime.", err)
 	}
 	defer func() {
 		if predictionClient != nil {
-			log.Println("Closing global AI Platform Prediction client.")
+			common.LogInfof("Closing global AI Platform Prediction client.")
 			if err := predictionClient.Close(); err != nil {
-				log.Printf("Error closing global AI Platform Prediction client: %v", err)
+				common.LogWarnf("Error closing global AI Platform Prediction client: %v", err)
 			}
 		}
 	}()
-	log.Println("Global AI Platform Prediction client initialized successfully.")
+	common.LogInfof("Global AI Platform Prediction client initialized successfully.")
 
 	s := server.NewMCPServer(
 		"Lyria", // Standardized name
@@ -139,7 +139,7 @@ func main() {
 	}
 
 	lyriaTool := mcp.NewTool("lyria_generate_music", lyriaToolParams...)
-	s.AddTool(lyriaTool, lyriaGenerateMusicHandler)
+	common.AddTool(s, appConfig, lyriaTool, lyriaGenerateMusicHandler)
 
 	s.AddPrompt(mcp.NewPrompt("generate-music",
 		mcp.WithPromptDescription("Generates music from a text prompt."),
@@ -184,6 +184,9 @@ func main() {
 		), nil
 	})
 
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
+
 	switch transport {
 	case "sse":
 		ssePort := 8081 // Default SSE port
@@ -192,10 +195,10 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting Lyria MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
+		common.LogInfof("Starting Lyria MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -204,7 +207,7 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting Lyria MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
+		common.LogInfof("Starting Lyria MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
 		mcpHTTPHandler := server.NewStreamableHTTPServer(s) // Base path /mcp
 		c := cors.New(cors.Options{
 			AllowedOrigins:   []string{"*"},
@@ -216,19 +219,20 @@ func main() {
 		})
 		handlerWithCORS := c.Handler(mcpHTTPHandler)
 		listenAddr := fmt.Sprintf(":%d", httpPort)
-		if err := http.ListenAndServe(listenAddr, handlerWithCORS); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		httpServer := common.NewHTTPServer(listenAddr, handlerWithCORS)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting Lyria MCP Server (Version: %s, Transport: stdio)", version)
+		common.LogInfof("Starting Lyria MCP Server (Version: %s, Transport: stdio)", version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 
-	log.Println("Lyria Server has stopped.")
+	common.LogInfof("Lyria Server has stopped.")
 }
 
 // lyriaGenerateMusicHandler is the handler for the 'lyria_generate_music' tool.
@@ -256,7 +260,7 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 		gcsBucketParam = userProvidedBucket
 	} else if appConfig.GenmediaBucket != "" {
 		gcsBucketParam = appConfig.GenmediaBucket
-		log.Printf("Handler lyria_generate_music: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", gcsBucketParam)
+		common.LogInfof("Handler lyria_generate_music: 'output_gcs_bucket' parameter not provided, using default from GENMEDIA_BUCKET: %s", gcsBucketParam)
 	}
 
 	if gcsBucketParam != "" { // Only trim prefix if bucket is actually set
@@ -301,7 +305,7 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 		if sc > 0 {
 			sampleCount = sc
 		} else {
-			log.Printf("Warning: sample_count was <= 0 (%.0f), using default %d.", scValFloat, defaultSampleCount)
+			common.LogWarnf("Warning: sample_count was <= 0 (%.0f), using default %d.", scValFloat, defaultSampleCount)
 			sampleCount = uint32(defaultSampleCount)
 		}
 	}
@@ -319,19 +323,19 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 		span.SetAttributes(attribute.Int("seed", int(*seed)))
 	}
 
-	log.Printf("Handling Lyria request: Prompt='%s', NegativePrompt='%s', ModelID='%s', Seed=%v, SampleCount=%d, GCSBucket='%s', FileName='%s', LocalDir='%s'",
+	common.LogInfof("Handling Lyria request: Prompt='%s', NegativePrompt='%s', ModelID='%s', Seed=%v, SampleCount=%d, GCSBucket='%s', FileName='%s', LocalDir='%s'",
 		prompt, negativePrompt, modelID, seed, sampleCount, gcsBucketParam, fileNameParam, localDirectoryPathParameter)
 
 	baseFilename := fileNameParam
 	if baseFilename == "" {
 		uid, errGen := shortid.Generate()
 		if errGen != nil {
-			log.Printf("Error generating shortid for filename: %v. Using default fallback.", errGen)
+			common.LogWarnf("Error generating shortid for filename: %v. Using default fallback.", errGen)
 			baseFilename = "lyria_output_default.wav"
 		} else {
 			baseFilename = fmt.Sprintf("lyria_output_%s.wav", uid)
 		}
-		log.Printf("Generated Base Filename: %s", baseFilename)
+		common.LogInfof("Generated Base Filename: %s", baseFilename)
 	}
 	baseFilename = strings.TrimPrefix(baseFilename, "/")
 
@@ -342,7 +346,15 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 
 	if err != nil {
 		span.RecordError(err)
-		log.Printf("Error in invokeLyriaAndUpload after %v: %v", duration, err)
+		common.LogErrorf("Error in invokeLyriaAndUpload after %v: %v", duration, err)
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "lyria_generate_music",
+			Prompt:     prompt,
+			Model:      modelID,
+			Parameters: params,
+			Error:      err.Error(),
+		})
 		errMsg := fmt.Sprintf("Music generation failed after %v: %v", duration, err)
 		if gcsBucketParam != "" {
 			errMsg = fmt.Sprintf("Music generation or GCS upload/processing failed after %v: %v", duration, err)
@@ -351,7 +363,7 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 	}
 
 	if base64AudioData == "" {
-		log.Printf("invokeLyriaAndUpload returned no error but base64AudioData is empty after %v.", duration)
+		common.LogErrorf("invokeLyriaAndUpload returned no error but base64AudioData is empty after %v.", duration)
 		return mcp.NewToolResultError(fmt.Sprintf("Music generation resulted in empty audio data after %v.", duration)), nil
 	}
 
@@ -360,25 +372,41 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 		audioBytes, decodeErr := base64.StdEncoding.DecodeString(base64AudioData)
 		if decodeErr != nil {
 			localSaveMessage = fmt.Sprintf("Failed to decode audio for local save: %v.", decodeErr)
-			log.Printf("Error decoding audio for local save (dir: %s): %v", localDirectoryPathParameter, decodeErr)
+			common.LogErrorf("Error decoding audio for local save (dir: %s): %v", localDirectoryPathParameter, decodeErr)
 		} else {
 			if errMkdir := os.MkdirAll(localDirectoryPathParameter, 0755); errMkdir != nil {
 				localSaveMessage = fmt.Sprintf("Failed to create local directory %s: %v.", localDirectoryPathParameter, errMkdir)
-				log.Printf("Error creating local directory %s: %v", localDirectoryPathParameter, errMkdir)
+				common.LogErrorf("Error creating local directory %s: %v", localDirectoryPathParameter, errMkdir)
 			} else {
 				fullLocalPath := filepath.Join(localDirectoryPathParameter, baseFilename)
 				errWrite := os.WriteFile(fullLocalPath, audioBytes, 0644)
 				if errWrite != nil {
 					localSaveMessage = fmt.Sprintf("Failed to save audio locally to %s: %v.", fullLocalPath, errWrite)
-					log.Printf("Error saving audio locally to %s: %v", fullLocalPath, errWrite)
+					common.LogErrorf("Error saving audio locally to %s: %v", fullLocalPath, errWrite)
 				} else {
 					localSaveMessage = fmt.Sprintf("Successfully saved audio locally to %s.", fullLocalPath)
-					log.Printf("Successfully saved audio locally to %s.", fullLocalPath)
+					common.LogInfof("Successfully saved audio locally to %s.", fullLocalPath)
 				}
 			}
 		}
 	}
 
+	var auditOutputURIs []string
+	if gcsBucketParam != "" && gcsUploadedObjectName != "" {
+		auditOutputURIs = append(auditOutputURIs, fmt.Sprintf("gs://%s/%s", gcsBucketParam, gcsUploadedObjectName))
+	}
+	if localDirectoryPathParameter != "" && strings.HasPrefix(localSaveMessage, "Successfully") {
+		auditOutputURIs = append(auditOutputURIs, filepath.Join(localDirectoryPathParameter, baseFilename))
+	}
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "lyria_generate_music",
+		Prompt:     prompt,
+		Model:      modelID,
+		Parameters: params,
+		OutputURIs: auditOutputURIs,
+	})
+
 	var resultContents []mcp.Content
 	var messageText string
 	var finalMessageParts []string
@@ -390,10 +418,10 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 		if gcsUploadedObjectName != "" {
 			fullGCSPath := fmt.Sprintf("gs://%s/%s", gcsBucketParam, gcsUploadedObjectName)
 			finalMessageParts = append(finalMessageParts, fmt.Sprintf("Uploaded to GCS: %s.", fullGCSPath))
-			log.Printf("GCS specified. Success. Path: %s.", fullGCSPath)
+			common.LogInfof("GCS specified. Success. Path: %s.", fullGCSPath)
 		} else {
 			finalMessageParts = append(finalMessageParts, fmt.Sprintf("GCS upload was specified (bucket: %s) but object name was not confirmed for upload.", gcsBucketParam))
-			log.Printf("GCS specified but no object name confirmed from upload. Bucket: %s.", gcsBucketParam)
+			common.LogWarnf("GCS specified but no object name confirmed from upload. Bucket: %s.", gcsBucketParam)
 		}
 	}
 
@@ -411,11 +439,11 @@ func lyriaGenerateMusicHandler(ctx context.Context, request mcp.CallToolRequest)
 
 	// Only include AudioContent if NEITHER GCS nor local path was specified
 	if gcsBucketParam == "" && localDirectoryPathParameter == "" {
-		log.Printf("Neither GCS nor local path specified. Returning audio data directly. Length: %d", len(base64AudioData))
+		common.LogInfof("Neither GCS nor local path specified. Returning audio data directly. Length: %d", len(base64AudioData))
 		audioContent := mcp.AudioContent{Type: "audio", Data: base64AudioData, MIMEType: audioMIMEType}
 		resultContents = append(resultContents, audioContent)
 	} else {
-		log.Printf("GCS or local path specified. Audio data NOT returned directly.")
+		common.LogInfof("GCS or local path specified. Audio data NOT returned directly.")
 	}
 
 	return &mcp.CallToolResult{
@@ -441,7 +469,7 @@ func invokeLyriaAndUpload(client *aiplatform.PredictionClient, ctx context.Conte
 	// 1. GENERATE AUDIO DATA
 	if modelInfo.EndpointType == "interactions" {
 		// --- V3 INTERACTIONS API ROUTE ---
-		log.Printf("Routing request to Interactions API for model: %s", modelID)
+		common.LogInfof("Routing request to Interactions API for model: %s", modelID)
 		audioBytes, sherlogLink, err = generateAudioWithInteractions(ctx, modelID, prompt)
 		if err != nil {
 			return "", "", "", fmt.Errorf("interactions API failed: %w", err)
@@ -453,7 +481,7 @@ func invokeLyriaAndUpload(client *aiplatform.PredictionClient, ctx context.Conte
 		// --- V2 PREDICTION API ROUTE ---
 		lyriaEndpointPath := fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s",
 			appConfig.ProjectID, appConfig.Location, modelID)
-		log.Printf("Using Lyria Prediction Endpoint Path: %s", lyriaEndpointPath)
+		common.LogInfof("Using Lyria Prediction Endpoint Path: %s", lyriaEndpointPath)
 
 		instanceData := map[string]interface{}{
 			"prompt":       prompt,
@@ -477,7 +505,7 @@ func invokeLyriaAndUpload(client *aiplatform.PredictionClient, ctx context.Conte
 			Instances: instances,
 		}
 
-		log.Printf("Sending Predict request to Lyria model %s. Instance data: %+v", modelID, instanceData)
+		common.LogDebugf("Sending Predict request to Lyria model %s. Instance data: %+v", modelID, instanceData)
 
 		resp, errPredict := client.Predict(ctx, predictRequest)
 		if errPredict != nil {
@@ -522,7 +550,7 @@ func invokeLyriaAndUpload(client *aiplatform.PredictionClient, ctx context.Conte
 		}
 	}
 
-	log.Printf("Received audio data (decoded length: %d bytes) from Lyria.", len(audioBytes))
+	common.LogInfof("Received audio data (decoded length: %d bytes) from Lyria.", len(audioBytes))
 
 	// 2. OPTIONAL GCS UPLOAD
 	if gcsBucket != "" {
@@ -534,10 +562,10 @@ func invokeLyriaAndUpload(client *aiplatform.PredictionClient, ctx context.Conte
 		if uploadErr != nil {
 			return "", extractedB64Audio, sherlogLink, fmt.Errorf("failed to upload audio to GCS (bucket: %s, object: %s): %w", gcsBucket, gcsObjectNameForUpload, uploadErr)
 		}
-		log.Printf("Successfully uploaded audio sample to gs://%s/%s", gcsBucket, gcsObjectNameForUpload)
+		common.LogInfof("Successfully uploaded audio sample to gs://%s/%s", gcsBucket, gcsObjectNameForUpload)
 		return gcsObjectNameForUpload, extractedB64Audio, sherlogLink, nil
 	}
 
-	log.Println("GCS bucket not provided, skipping upload.")
+	common.LogInfof("GCS bucket not provided, skipping upload.")
 	return "", extractedB64Audio, sherlogLink, nil
 }
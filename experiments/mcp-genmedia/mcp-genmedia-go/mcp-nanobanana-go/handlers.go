@@ -19,7 +19,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -64,6 +63,11 @@ func nanobananaGenerateContentHandler(client *genai.Client, ctx context.Context,
 		outputDir = strings.TrimSpace(dir)
 	}
 
+	systemInstruction, err := common.ParseSystemInstruction(request.GetArguments()["system_instruction"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// --- Construct Gemini Request ---
 	var parts []*genai.Part
 	parts = append(parts, genai.NewPartFromText(prompt))
@@ -88,28 +92,46 @@ func nanobananaGenerateContentHandler(client *genai.Client, ctx context.Context,
 		attribute.String("prompt", prompt),
 		attribute.String("model", model),
 		attribute.String("output_directory", outputDir),
+		attribute.Bool("system_instruction", systemInstruction != nil),
 	)
 
 	// --- API Call ---
-	log.Printf("Calling GenerateContent with Model: %s, Prompt: \"%s\"", model, prompt)
+	common.LogInfof("Calling GenerateContent with Model: %s, Prompt: \"%s\"", model, prompt)
 	startTime := time.Now()
 
 	config := &genai.GenerateContentConfig{
 		ResponseModalities: []string{"IMAGE", "TEXT"},
+		SystemInstruction:  systemInstruction,
 		ImageConfig: &genai.ImageConfig{
 			AspectRatio: aspectRatio,
 		},
 	}
+	if err := common.ApplySamplingParams(request.GetArguments(), config); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	contents := &genai.Content{Parts: parts, Role: "USER"}
 
-	resp, err := client.Models.GenerateContent(ctx, model, []*genai.Content{contents}, config)
+	var resp *genai.GenerateContentResponse
+	err = common.GenAIBreaker.Execute(ctx, func(ctx context.Context) error {
+		var callErr error
+		resp, callErr = client.Models.GenerateContent(ctx, model, []*genai.Content{contents}, config)
+		return callErr
+	})
 
 	apiCallDuration := time.Since(startTime)
-	log.Printf("GenerateContent call took: %v", apiCallDuration)
+	common.LogInfof("GenerateContent call took: %v", apiCallDuration)
 	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
 
 	if err != nil {
 		span.RecordError(err)
+		common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+			Service:    serviceName,
+			Tool:       "nanobanana_image_generation",
+			Prompt:     prompt,
+			Model:      model,
+			Parameters: request.GetArguments(),
+			Error:      err.Error(),
+		})
 		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini API: %v", err)), nil
 	}
 
@@ -131,7 +153,7 @@ func nanobananaGenerateContentHandler(client *genai.Client, ctx context.Context,
 				responseText.WriteString(part.Text)
 			}
 			if part.InlineData != nil {
-				log.Printf("part %d mime-type: %s", n, part.InlineData.MIMEType)
+				common.LogDebugf("part %d mime-type: %s", n, part.InlineData.MIMEType)
 
 				if outputDir != "" {
 					if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -145,12 +167,21 @@ func nanobananaGenerateContentHandler(client *genai.Client, ctx context.Context,
 					savedFiles = append(savedFiles, filePath)
 				} else {
 					// If no output dir, should we return base64? For now, we just log.
-					log.Println("Received image data but no output_directory was specified. Image not saved.")
+					common.LogWarnf("Received image data but no output_directory was specified. Image not saved.")
 				}
 			}
 		}
 	}
 
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "nanobanana_image_generation",
+		Prompt:     prompt,
+		Model:      model,
+		Parameters: request.GetArguments(),
+		OutputURIs: savedFiles,
+	})
+
 	// --- Format Final Result ---
 	finalMessage := responseText.String()
 	if len(savedFiles) > 0 {
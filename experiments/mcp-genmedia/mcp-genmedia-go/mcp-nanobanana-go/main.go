@@ -61,34 +61,34 @@ func main() {
 
 	// Override default location for Gemini models if not explicitly set
 	if os.Getenv("LOCATION") == "" {
-		log.Printf("LOCATION environment variable not set. Defaulting to 'global' for mcp-nanobanana-go.")
+		common.LogInfof("LOCATION environment variable not set. Defaulting to 'global' for mcp-nanobanana-go.")
 		appConfig.Location = "global"
 	}
 	var err error
 
-	log.Printf("Initializing global GenAI client...")
+	common.LogInfof("Initializing global GenAI client...")
 	clientCtx, clientCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer clientCancel()
 
-	clientConfig := &genai.ClientConfig{
-		Backend:  genai.BackendVertexAI,
-		Project:  appConfig.ProjectID,
-		Location: appConfig.Location,
-	}
-	if appConfig.ApiEndpoint != "" {
-		log.Printf("Using custom Vertex AI endpoint: %s", appConfig.ApiEndpoint)
-		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
+	clientConfig := appConfig.NewGenAIClientConfig(appConfig.Location)
+
+	if err := common.ApplyGenAITransportSettings(clientConfig); err != nil {
+		common.LogWarnf("Warning: Failed to apply GenAI transport settings: %v", err)
 	}
 
 	if err := common.InjectCaptureHeaders(clientCtx, appConfig, clientConfig); err != nil {
-		log.Printf("Warning: Failed to inject capture headers: %v", err)
+		common.LogWarnf("Warning: Failed to inject capture headers: %v", err)
 	}
 
-	genAIClient, err = genai.NewClient(clientCtx, clientConfig)
-	if err != nil {
-		log.Printf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
+	genAIClient, err = common.NewGenAIClientWithRetry(clientCtx, func(ctx context.Context) (*genai.Client, error) {
+		return genai.NewClient(ctx, clientConfig)
+	})
+	if err != nil && !common.IsTransientClientError(err) {
+		common.LogFatalf("Error creating global GenAI client: %v. Check the credentials and GENAI_BACKEND, PROJECT_ID and GEMINI_API_KEY settings.", err)
+	} else if err != nil {
+		common.LogWarnf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
 	} else {
-		log.Printf("Global GenAI client initialized successfully.")
+		common.LogInfof("Global GenAI client initialized successfully.")
 	}
 
 	s := server.NewMCPServer("Gemini", version, server.WithResourceCapabilities(true, false))
@@ -99,6 +99,10 @@ func main() {
 		mcp.WithString("model", mcp.DefaultString("gemini-3.1-flash-image"), mcp.Description(common.BuildGeminiImageModelDescription())),
 		mcp.WithString("aspect_ratio", mcp.DefaultString("1:1"), mcp.Description("Aspect ratio of the generated images. Note: supported aspect ratios are model-dependent.")),
 		mcp.WithArray("images", mcp.Description("Optional. A list of local file paths or GCS URIs for input media (images, videos, or PDFs)."), mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithString("system_instruction", mcp.Description(fmt.Sprintf("Optional. A system instruction that steers the model's behavior separately from the prompt, such as tone, style, or constraints (up to %d characters).", common.MaxSystemInstructionLength))),
+		mcp.WithNumber("temperature", mcp.Min(0), mcp.Max(common.MaxTemperature), mcp.Description("Optional. Sampling temperature, from 0 to 2. Lower values make the output more deterministic and focused, higher values more varied. Defaults to the model's default.")),
+		mcp.WithNumber("top_p", mcp.Min(0), mcp.Max(1), mcp.Description("Optional. Nucleus sampling, from 0 to 1: only the most likely tokens whose probabilities add up to top_p are considered. Defaults to the model's default.")),
+		mcp.WithNumber("top_k", mcp.Min(1), mcp.Max(common.MaxTopK), mcp.Description(fmt.Sprintf("Optional. Only the top_k most likely tokens are considered at each step, from 1 to %d. Defaults to the model's default.", common.MaxTopK))),
 		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save generated image(s) to.")),
 		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store generated images (e.g., your-bucket/outputs/).")),
 	)
@@ -106,7 +110,10 @@ func main() {
 	handlerWithClient := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return nanobananaGenerateContentHandler(genAIClient, ctx, request)
 	}
-	s.AddTool(tool, handlerWithClient)
+	common.AddTool(s, appConfig, tool, handlerWithClient)
+
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
 
 	switch transport {
 	case "sse":
@@ -116,10 +123,10 @@ func main() {
 		} else if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: sse, Port: %d)", serviceName, version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -128,17 +135,19 @@ func main() {
 		} else if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
-		http.Handle("/mcp", server.NewStreamableHTTPServer(s))
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", httpPort), nil); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: http, Port: %d)", serviceName, version, httpPort)
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", server.NewStreamableHTTPServer(s))
+		httpServer := common.NewHTTPServer(fmt.Sprintf(":%d", httpPort), mux)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
+		common.LogInfof("Starting %s MCP Server (Version: %s, Transport: stdio)", serviceName, version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 }
@@ -19,9 +19,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"go.opentelemetry.io/otel"
@@ -39,8 +39,31 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 	if !ok || strings.TrimSpace(prompt) == "" {
 		return mcp.NewToolResultError("prompt must be a non-empty string and is required for text-to-video"), nil
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
 
-	gcsBucket, outputDir, model, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), appConfig, false)
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), cfg, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	model := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(request.GetArguments(), veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	post, err := parsePostProcessOptions(request.GetArguments(), outputDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	compressionQuality, err := parseCompressionQuality(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fps, err := parseVeoFPS(request.GetArguments(), modelDetails)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -65,10 +88,10 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming t2v context for prompt \"%s\" was already canceled: %v", prompt, ctx.Err())
+		common.LogWarnf("Incoming t2v context for prompt \"%s\" was already canceled: %v", prompt, ctx.Err())
 		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo t2v request: Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t, PersonGen=%s", prompt, gcsBucket, outputDir, model, numberOfVideos, finalAspectRatio, durationSecs, generateAudio, personGeneration)
+		common.LogInfof("Handling Veo t2v request: Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t, PersonGen=%s", prompt, gcsBucket, outputDir, model, numberOfVideos, finalAspectRatio, durationSecs, generateAudio, personGeneration)
 	}
 
 	config := &genai.GenerateVideosConfig{
@@ -77,7 +100,10 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 		OutputGCSURI:     gcsBucket,
 		DurationSeconds:  &durationSecs,
 		PersonGeneration: personGeneration,
+		FPS:              fps,
 	}
+	config.EnhancePrompt, _ = request.GetArguments()[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
 
 	if generateAudio {
 		config.GenerateAudio = &generateAudio
@@ -86,7 +112,10 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 	source := &genai.GenerateVideosSource{
 		Prompt: prompt,
 	}
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, model, source, config, "t2v")
+	if async, _ := request.GetArguments()["async"].(bool); async {
+		return startVideoGeneration(client, ctx, model, source, config, "t2v", polling, post), nil
+	}
+	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, model, source, config, "t2v", polling, post)
 }
 
 // veoImageToVideoHandler is the handler for the 'veo_i2v' tool.
@@ -107,25 +136,48 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 	if mt, ok := request.GetArguments()["mime_type"].(string); ok && strings.TrimSpace(mt) != "" {
 		mimeType = strings.ToLower(strings.TrimSpace(mt))
 		if mimeType != "image/jpeg" && mimeType != "image/png" {
-			log.Printf("Unsupported MIME type provided: %s. Only 'image/jpeg' and 'image/png' are supported.", mimeType)
+			common.LogWarnf("Unsupported MIME type provided: %s. Only 'image/jpeg' and 'image/png' are supported.", mimeType)
 			return mcp.NewToolResultError(fmt.Sprintf("Unsupported MIME type '%s'. Please use 'image/jpeg' or 'image/png'.", mimeType)), nil
 		}
-		log.Printf("Using provided and validated MIME type: %s", mimeType)
+		common.LogInfof("Using provided and validated MIME type: %s", mimeType)
 	} else {
 		mimeType = inferMimeTypeFromURI(imageURI)
 		if mimeType == "" {
-			log.Printf("Could not infer a supported MIME type (image/jpeg or image/png) from image_uri: %s. Please provide a 'mime_type' parameter.", imageURI)
+			common.LogWarnf("Could not infer a supported MIME type (image/jpeg or image/png) from image_uri: %s. Please provide a 'mime_type' parameter.", imageURI)
 			return mcp.NewToolResultError(fmt.Sprintf("MIME type for image '%s' could not be inferred or is not supported. Please specify 'mime_type' as 'image/jpeg' or 'image/png'.", imageURI)), nil
 		}
-		log.Printf("Inferred MIME type: %s for image_uri: %s", mimeType, imageURI)
+		common.LogInfof("Inferred MIME type: %s for image_uri: %s", mimeType, imageURI)
 	}
 
 	prompt := ""
 	if promptArg, ok := request.GetArguments()["prompt"].(string); ok {
 		prompt = strings.TrimSpace(promptArg)
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), appConfig, false)
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), cfg, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelName := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(request.GetArguments(), veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	post, err := parsePostProcessOptions(request.GetArguments(), outputDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	compressionQuality, err := parseCompressionQuality(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fps, err := parseVeoFPS(request.GetArguments(), modelDetails)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -152,10 +204,10 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming i2v context for image_uri \"%s\" was already canceled: %v", imageURI, ctx.Err())
+		common.LogWarnf("Incoming i2v context for image_uri \"%s\" was already canceled: %v", imageURI, ctx.Err())
 		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo i2v request: ImageURI=\"%s\", MimeType=\"%s\", Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t, PersonGen=%s", imageURI, mimeType, prompt, gcsBucket, outputDir, modelName, numberOfVideos, finalAspectRatio, durationSecs, generateAudio, personGeneration)
+		common.LogInfof("Handling Veo i2v request: ImageURI=\"%s\", MimeType=\"%s\", Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t, PersonGen=%s", imageURI, mimeType, prompt, gcsBucket, outputDir, modelName, numberOfVideos, finalAspectRatio, durationSecs, generateAudio, personGeneration)
 	}
 
 	inputImage := &genai.Image{
@@ -169,7 +221,10 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 		OutputGCSURI:     gcsBucket,
 		DurationSeconds:  &durationSecs,
 		PersonGeneration: personGeneration,
+		FPS:              fps,
 	}
+	config.EnhancePrompt, _ = request.GetArguments()[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
 
 	if generateAudio {
 		config.GenerateAudio = &generateAudio
@@ -180,5 +235,8 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 		Image:  inputImage,
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, source, config, "i2v")
+	if async, _ := request.GetArguments()["async"].(bool); async {
+		return startVideoGeneration(client, ctx, modelName, source, config, "i2v", polling, post), nil
+	}
+	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, modelName, source, config, "i2v", polling, post)
 }
@@ -17,7 +17,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
@@ -48,13 +47,35 @@ func veoFirstLastToVideoHandler(client *genai.Client, ctx context.Context, reque
 	if promptArg, ok := request.GetArguments()["prompt"].(string); ok {
 		prompt = strings.TrimSpace(promptArg)
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), appConfig, false)
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), cfg, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelName := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(request.GetArguments(), veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	post, err := parsePostProcessOptions(request.GetArguments(), outputDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	compressionQuality, err := parseCompressionQuality(request.GetArguments())
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	modelDetails := common.SupportedVeoModels[modelName]
+	fps, err := parseVeoFPS(request.GetArguments(), modelDetails)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if !modelDetails.SupportsFirstLast {
 		return mcp.NewToolResultError(fmt.Sprintf("Model %s does not support first-last video generation.", modelName)), nil
 	}
@@ -101,10 +122,10 @@ func veoFirstLastToVideoHandler(client *genai.Client, ctx context.Context, reque
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming first_last_to_video context was already canceled: %v", ctx.Err())
+		common.LogWarnf("Incoming first_last_to_video context was already canceled: %v", ctx.Err())
 		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo first_last_to_video request: FirstImageURI=\"%s\", LastImageURI=\"%s\", Prompt=\"%s\", Model=%s, PersonGen=%s", firstImageURI, lastImageURI, prompt, modelName, personGeneration)
+		common.LogInfof("Handling Veo first_last_to_video request: FirstImageURI=\"%s\", LastImageURI=\"%s\", Prompt=\"%s\", Model=%s, PersonGen=%s", firstImageURI, lastImageURI, prompt, modelName, personGeneration)
 	}
 
 	inputImage := &genai.Image{
@@ -118,11 +139,14 @@ func veoFirstLastToVideoHandler(client *genai.Client, ctx context.Context, reque
 		OutputGCSURI:     gcsBucket,
 		DurationSeconds:  &durationSecs,
 		PersonGeneration: personGeneration,
+		FPS:              fps,
 		LastFrame: &genai.Image{
 			GCSURI:   lastImageURI,
 			MIMEType: lastMimeType,
 		},
 	}
+	config.EnhancePrompt, _ = request.GetArguments()[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
 
 	if generateAudio {
 		config.GenerateAudio = &generateAudio
@@ -133,7 +157,10 @@ func veoFirstLastToVideoHandler(client *genai.Client, ctx context.Context, reque
 		Image:  inputImage,
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, source, config, "first_last_to_video")
+	if async, _ := request.GetArguments()["async"].(bool); async {
+		return startVideoGeneration(client, ctx, modelName, source, config, "first_last_to_video", polling, post), nil
+	}
+	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, modelName, source, config, "first_last_to_video", polling, post)
 }
 
 // veoReferenceToVideoHandler is the handler for the 'veo_reference_to_video' tool.
@@ -146,6 +173,8 @@ func veoReferenceToVideoHandler(client *genai.Client, ctx context.Context, reque
 	if !ok || strings.TrimSpace(prompt) == "" {
 		return mcp.NewToolResultError("prompt must be a non-empty string and is required for reference-to-video"), nil
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
 
 	referenceImageURIsRaw, ok := request.GetArguments()["reference_image_uris"].([]interface{})
 	if !ok || len(referenceImageURIsRaw) == 0 {
@@ -202,12 +231,32 @@ func veoReferenceToVideoHandler(client *genai.Client, ctx context.Context, reque
 		})
 	}
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), appConfig, false)
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), cfg, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelName := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(request.GetArguments(), veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	post, err := parsePostProcessOptions(request.GetArguments(), outputDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	compressionQuality, err := parseCompressionQuality(request.GetArguments())
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	modelDetails := common.SupportedVeoModels[modelName]
+	fps, err := parseVeoFPS(request.GetArguments(), modelDetails)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if !modelDetails.SupportsReferenceImage {
 		return mcp.NewToolResultError(fmt.Sprintf("Model %s does not support reference image to video generation.", modelName)), nil
 	}
@@ -227,10 +276,10 @@ func veoReferenceToVideoHandler(client *genai.Client, ctx context.Context, reque
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming reference_to_video context was already canceled: %v", ctx.Err())
+		common.LogWarnf("Incoming reference_to_video context was already canceled: %v", ctx.Err())
 		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo reference_to_video request: Prompt=\"%s\", Model=%s, NumRefImages=%d, PersonGen=%s", prompt, modelName, len(referenceImages), personGeneration)
+		common.LogInfof("Handling Veo reference_to_video request: Prompt=\"%s\", Model=%s, NumRefImages=%d, PersonGen=%s", prompt, modelName, len(referenceImages), personGeneration)
 	}
 
 	config := &genai.GenerateVideosConfig{
@@ -240,7 +289,10 @@ func veoReferenceToVideoHandler(client *genai.Client, ctx context.Context, reque
 		DurationSeconds:  &durationSecs,
 		ReferenceImages:  referenceImages,
 		PersonGeneration: personGeneration,
+		FPS:              fps,
 	}
+	config.EnhancePrompt, _ = request.GetArguments()[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
 
 	if generateAudio {
 		config.GenerateAudio = &generateAudio
@@ -250,7 +302,10 @@ func veoReferenceToVideoHandler(client *genai.Client, ctx context.Context, reque
 		Prompt: prompt,
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, source, config, "reference_to_video")
+	if async, _ := request.GetArguments()["async"].(bool); async {
+		return startVideoGeneration(client, ctx, modelName, source, config, "reference_to_video", polling, post), nil
+	}
+	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, modelName, source, config, "reference_to_video", polling, post)
 }
 
 // veoExtendVideoHandler is the handler for the 'veo_extend_video' tool.
@@ -276,13 +331,35 @@ func veoExtendVideoHandler(client *genai.Client, ctx context.Context, request mc
 	if promptArg, ok := request.GetArguments()["prompt"].(string); ok {
 		prompt = strings.TrimSpace(promptArg)
 	}
+	rawPrompt, _ := request.GetArguments()[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), appConfig, true)
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, err := parseCommonVideoParams(request.GetArguments(), cfg, true)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelName := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(request.GetArguments(), veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	post, err := parsePostProcessOptions(request.GetArguments(), outputDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	compressionQuality, err := parseCompressionQuality(request.GetArguments())
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	modelDetails := common.SupportedVeoModels[modelName]
+	fps, err := parseVeoFPS(request.GetArguments(), modelDetails)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if !modelDetails.SupportsExtend {
 		return mcp.NewToolResultError(fmt.Sprintf("Model %s does not support video extension.", modelName)), nil
 	}
@@ -309,10 +386,10 @@ func veoExtendVideoHandler(client *genai.Client, ctx context.Context, request mc
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming extend context for video_uri \"%s\" was already canceled: %v", videoURI, ctx.Err())
+		common.LogWarnf("Incoming extend context for video_uri \"%s\" was already canceled: %v", videoURI, ctx.Err())
 		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo extend_video request: VideoURI=\"%s\", Prompt=\"%s\", Model=%s", videoURI, prompt, modelName)
+		common.LogInfof("Handling Veo extend_video request: VideoURI=\"%s\", Prompt=\"%s\", Model=%s", videoURI, prompt, modelName)
 	}
 
 	inputVideo := &genai.Video{
@@ -326,7 +403,10 @@ func veoExtendVideoHandler(client *genai.Client, ctx context.Context, request mc
 		OutputGCSURI:     gcsBucket,
 		DurationSeconds:  &durationSecs,
 		PersonGeneration: personGeneration,
+		FPS:              fps,
 	}
+	config.EnhancePrompt, _ = request.GetArguments()[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
 
 	if generateAudio {
 		config.GenerateAudio = &generateAudio
@@ -337,5 +417,8 @@ func veoExtendVideoHandler(client *genai.Client, ctx context.Context, request mc
 		Video:  inputVideo,
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, source, config, "extend_video")
+	if async, _ := request.GetArguments()["async"].(bool); async {
+		return startVideoGeneration(client, ctx, modelName, source, config, "extend_video", polling, post), nil
+	}
+	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, modelName, source, config, "extend_video", polling, post)
 }
@@ -0,0 +1,322 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+const (
+	// veoExtensionSeconds is the length of the clip produced by each Veo extension.
+	veoExtensionSeconds = 7
+	// maxLongVideoSeconds caps the total duration of a chained video.
+	maxLongVideoSeconds = 60
+)
+
+// planVideoChain returns the number of extensions needed after an initial clip of initialSecs
+// to reach targetSecs, or an error if the target is out of range.
+func planVideoChain(targetSecs, initialSecs int32) (int, error) {
+	if targetSecs <= 0 || targetSecs > maxLongVideoSeconds {
+		return 0, fmt.Errorf("target_duration must be between 1 and %d seconds, got %d", maxLongVideoSeconds, targetSecs)
+	}
+	if targetSecs <= initialSecs {
+		return 0, nil
+	}
+	remaining := targetSecs - initialSecs
+	return int((remaining + veoExtensionSeconds - 1) / veoExtensionSeconds), nil
+}
+
+// firstVideoURI returns the GCS URI of the first video of a completed operation, or "" if there is none.
+func firstVideoURI(operation *genai.GenerateVideosOperation) string {
+	if operation == nil || operation.Response == nil {
+		return ""
+	}
+	for _, video := range operation.Response.GeneratedVideos {
+		if video.Video != nil && video.Video.URI != "" {
+			return video.Video.URI
+		}
+	}
+	return ""
+}
+
+// buildConcatList returns an FFMpeg concat demuxer list for the given files.
+func buildConcatList(paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(p, "'", `'\''`))
+	}
+	return b.String()
+}
+
+// stitchVideos joins the segments, which share the same encoding, into outputPath without
+// re-encoding and cuts the result to maxSecs. ffmpeg is looked up in MCP_CUSTOM_PATH if set.
+func stitchVideos(ctx context.Context, segmentPaths []string, outputPath string, maxSecs int32) error {
+	listPath := filepath.Join(filepath.Dir(outputPath), "segments.txt")
+	if err := os.WriteFile(listPath, []byte(buildConcatList(segmentPaths)), 0644); err != nil {
+		return fmt.Errorf("failed to write concat list: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listPath,
+		"-c", "copy", "-t", fmt.Sprintf("%d", maxSecs), "-movflags", "+faststart", outputPath)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed to stitch the segments: %w: %s", err, common.GetTail(string(output), 5))
+	}
+	return nil
+}
+
+// veoGenerateLongVideoHandler is the handler for the 'veo_generate_long_video' tool. It generates an
+// initial clip from the prompt, extends it repeatedly (each extension continues from the previous
+// segment) until the target duration is reached, and stitches the segments into a single video.
+func veoGenerateLongVideoHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_generate_long_video")
+	defer span.End()
+
+	args := request.GetArguments()
+	prompt, ok := args["prompt"].(string)
+	if !ok || strings.TrimSpace(prompt) == "" {
+		return mcp.NewToolResultError("prompt must be a non-empty string and is required for long video generation"), nil
+	}
+	rawPrompt, _ := args[common.RawPromptParam].(bool)
+	prompt = common.ApplyPromptAffixes(appConfig, prompt, rawPrompt)
+	extensionPrompt := prompt
+	if p, ok := args["extension_prompt"].(string); ok && strings.TrimSpace(p) != "" {
+		extensionPrompt = common.ApplyPromptAffixes(appConfig, strings.TrimSpace(p), rawPrompt)
+	}
+
+	targetArg, ok := args["target_duration"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("target_duration is required"), nil
+	}
+	targetSecs := int32(targetArg)
+
+	ctx, cfg, client, err := resolveRequestConfig(ctx, client, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	gcsBucket, outputDir, modelDetails, aspectRatio, _, initialSecs, generateAudio, personGeneration, err := parseCommonVideoParams(args, cfg, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelName := modelDetails.CanonicalName
+	polling, err := parseVeoPolling(args, veoPollingDefaults)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	transcode, err := common.ParseVideoTranscodeOptions(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	verify, _ := args["verify_output"].(bool)
+	compressionQuality, err := parseCompressionQuality(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if gcsBucket == "" {
+		return mcp.NewToolResultError("a GCS bucket is required for long video generation, since each extension reads the previous segment from GCS. Set the 'bucket' parameter or GENMEDIA_BUCKET"), nil
+	}
+	fps, err := parseVeoFPS(args, modelDetails)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	numExtensions, err := planVideoChain(targetSecs, initialSecs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if numExtensions > 0 && !modelDetails.SupportsExtend {
+		return mcp.NewToolResultError(fmt.Sprintf("Model %s does not support video extension, so it can only generate up to %ds.", modelName, initialSecs)), nil
+	}
+
+	span.SetAttributes(
+		attribute.String("prompt", prompt),
+		attribute.String("gcs_bucket", gcsBucket),
+		attribute.String("output_dir", outputDir),
+		attribute.String("model", modelName),
+		attribute.String("aspect_ratio", aspectRatio),
+		attribute.Int("target_duration_secs", int(targetSecs)),
+		attribute.Int("num_extensions", numExtensions),
+	)
+	common.LogInfof("Handling Veo generate_long_video request: Prompt=\"%s\", Model=%s, Target=%ds, Initial=%ds, Extensions=%d", prompt, modelName, targetSecs, initialSecs, numExtensions)
+
+	mcpServer := server.ServerFromContext(ctx)
+	var progressToken mcp.ProgressToken
+	if request.Params.Meta != nil {
+		progressToken = request.Params.Meta.ProgressToken
+	}
+
+	startTime := time.Now()
+	config := &genai.GenerateVideosConfig{
+		NumberOfVideos:   1,
+		AspectRatio:      aspectRatio,
+		OutputGCSURI:     gcsBucket,
+		DurationSeconds:  &initialSecs,
+		PersonGeneration: personGeneration,
+		FPS:              fps,
+	}
+	config.EnhancePrompt, _ = args[common.EnhancePromptParam].(bool)
+	config.CompressionQuality = compressionQuality
+	if generateAudio {
+		config.GenerateAudio = &generateAudio
+	}
+	source := &genai.GenerateVideosSource{Prompt: prompt}
+
+	var segmentURIs []string
+	for step := 0; step <= numExtensions; step++ {
+		if step > 0 {
+			extensionSecs := int32(veoExtensionSeconds)
+			extensionConfig := *config
+			extensionConfig.DurationSeconds = &extensionSecs
+			config = &extensionConfig
+			source = &genai.GenerateVideosSource{
+				Prompt: extensionPrompt,
+				Video:  &genai.Video{URI: segmentURIs[len(segmentURIs)-1], MIMEType: "video/mp4"},
+			}
+		}
+		common.LogInfof("generate_long_video: generating segment %d of %d", step+1, numExtensions+1)
+		operation, _, _, errResult := waitForGeneratedVideos(client, ctx, mcpServer, progressToken, modelName, source, config, "generate_long_video", polling)
+		if errResult != nil {
+			if step == 0 {
+				return errResult, nil
+			}
+			reason := "unknown error"
+			if len(errResult.Content) > 0 {
+				if text, ok := errResult.Content[0].(mcp.TextContent); ok {
+					reason = text.Text
+				}
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("segment %d of %d failed: %s. Completed segments: %s", step+1, numExtensions+1, reason, strings.Join(segmentURIs, ", "))), nil
+		}
+		uri := firstVideoURI(operation)
+		if uri == "" {
+			msg := fmt.Sprintf("segment %d of %d produced no video.", step+1, numExtensions+1)
+			if filtered := describeFilteredVideos(operation.Response, personGeneration); filtered != "" {
+				msg += " " + filtered
+			}
+			return mcp.NewToolResultError(msg), nil
+		}
+		segmentURIs = append(segmentURIs, uri)
+	}
+
+	tempDir, err := os.MkdirTemp("", "veo_long_video_")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create temp dir: %v", err)), nil
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	var segmentPaths []string
+	for i, uri := range segmentURIs {
+		localPath := filepath.Join(tempDir, fmt.Sprintf("segment_%03d.mp4", i))
+		if err := common.DownloadGeneratedFromGCS(ctx, uri, localPath); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to download segment %s: %v. Segments: %s", uri, err, strings.Join(segmentURIs, ", "))), nil
+		}
+		segmentPaths = append(segmentPaths, localPath)
+	}
+
+	fileName := fmt.Sprintf("veo-long-%s-%s.mp4", modelName, time.Now().Format("20060102-150405"))
+	stitchedPath := filepath.Join(tempDir, fileName)
+	if err := stitchVideos(ctx, segmentPaths, stitchedPath, targetSecs); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("%v. Segments: %s", err, strings.Join(segmentURIs, ", "))), nil
+	}
+	var verifyMessage string
+	if verify {
+		expect := videoExpectation{DurationSeconds: float64(targetSecs), AspectRatio: aspectRatio}
+		if fps != nil {
+			expect.FPS = float64(*fps)
+		}
+		mismatches, err := verifyGeneratedVideo(ctx, "", stitchedPath, expect)
+		if err != nil {
+			common.LogWarnf("Warning: could not verify the stitched video: %v", err)
+			verifyMessage = fmt.Sprintf("Could not verify the output: %v.", err)
+		} else if len(mismatches) > 0 {
+			common.LogWarnf("Warning: the stitched video does not match the request: %s", strings.Join(mismatches, ", "))
+			verifyMessage = fmt.Sprintf("Warning: the output does not match the request: its %s.", strings.Join(mismatches, " and "))
+		}
+	}
+	mimeType := "video/mp4"
+	if transcode != nil {
+		transcodedName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "_" + transcode.Codec + transcode.Extension()
+		transcodedPath := filepath.Join(tempDir, transcodedName)
+		if err := common.TranscodeVideoFile(ctx, stitchedPath, transcodedPath, *transcode); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%v. Segments: %s", err, strings.Join(segmentURIs, ", "))), nil
+		}
+		fileName, stitchedPath, mimeType = transcodedName, transcodedPath, transcode.MIMEType()
+	}
+	stitched, err := os.ReadFile(stitchedPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read stitched video: %v", err)), nil
+	}
+
+	var outputURIs []string
+	var saveMessageParts []string
+	bucketName, objectName, err := common.ParseGCSPath(segmentURIs[len(segmentURIs)-1])
+	if err == nil {
+		objectName = path.Join(path.Dir(objectName), fileName)
+		if err := common.UploadGeneratedToGCS(ctx, bucketName, objectName, mimeType, stitched); err != nil {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Failed to upload the stitched video to GCS: %v.", err))
+		} else {
+			gcsURI := fmt.Sprintf("gs://%s/%s", bucketName, objectName)
+			outputURIs = append(outputURIs, gcsURI)
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Stitched video saved to GCS: %s.", gcsURI))
+		}
+	}
+	if outputDir != "" {
+		localPath := filepath.Clean(filepath.Join(outputDir, fileName))
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Error creating directory %s: %v.", outputDir, err))
+		} else if err := os.WriteFile(localPath, stitched, 0644); err != nil {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Error writing %s: %v.", localPath, err))
+		} else {
+			outputURIs = append(outputURIs, localPath)
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Stitched video downloaded locally to: %s.", localPath))
+		}
+	}
+	stat, err := common.StatVideo(ctx, stitchedPath)
+	if err != nil {
+		common.LogWarnf("Warning: could not read the duration of the stitched video: %v", err)
+	}
+	if stat.SizeBytes > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Output size: %s.", stat))
+	}
+	auditVideoGeneration(ctx, "generate_long_video", modelName, &genai.GenerateVideosSource{Prompt: prompt}, config, outputURIs, "")
+	if enhancement := common.DescribePromptEnhancement(prompt, nil, config.EnhancePrompt); enhancement != "" {
+		saveMessageParts = append(saveMessageParts, enhancement)
+	}
+	if verifyMessage != "" {
+		saveMessageParts = append(saveMessageParts, verifyMessage)
+	}
+
+	resultText := fmt.Sprintf("Generated a ~%ds video from %d segment(s) (an initial %ds clip and %d extension(s) of %ds) using model %s. This took about %s. Segments: %s. %s",
+		targetSecs, len(segmentURIs), initialSecs, numExtensions, veoExtensionSeconds, modelName,
+		time.Since(startTime).Round(time.Second), strings.Join(segmentURIs, ", "), strings.Join(saveMessageParts, " "))
+	return mcp.NewToolResultText(strings.TrimSpace(resultText)), nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestPlanVideoChain(t *testing.T) {
+	tests := []struct {
+		target, initial int32
+		want            int
+		wantErr         bool
+	}{
+		{24, 8, 3, false}, // 8 + 3*7 = 29s, cut to 24s.
+		{22, 8, 2, false},
+		{8, 8, 0, false},
+		{5, 8, 0, false},
+		{60, 8, 8, false},
+		{61, 8, 0, true},
+		{0, 8, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := planVideoChain(tt.target, tt.initial)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("planVideoChain(%d, %d): expected error %t, but got %v", tt.target, tt.initial, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("planVideoChain(%d, %d): expected %d extensions, but got %d", tt.target, tt.initial, tt.want, got)
+		}
+	}
+}
+
+func TestBuildConcatList(t *testing.T) {
+	got := buildConcatList([]string{"/tmp/a.mp4", "/tmp/it's.mp4"})
+	want := "file '/tmp/a.mp4'\nfile '/tmp/it'\\''s.mp4'\n"
+	if got != want {
+		t.Errorf("expected %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// parseVeoOperationName returns the location and model of a GenerateVideos operation from its
+// name, e.g. "projects/p/locations/us-central1/publishers/google/models/veo-3.0-generate-001/operations/123".
+// Names of the Gemini API backend ("models/veo-3.0-generate-001/operations/123") have no location.
+func parseVeoOperationName(name string) (location, model string, err error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(name), "/"), "/")
+	if len(parts) < 4 || parts[len(parts)-2] != "operations" || parts[len(parts)-1] == "" {
+		return "", "", fmt.Errorf("'%s' is not a Veo operation name (expected '.../models/<model>/operations/<id>')", name)
+	}
+	for i := 0; i+1 < len(parts)-2; i++ {
+		switch parts[i] {
+		case "locations":
+			location = parts[i+1]
+		case "models":
+			model = parts[i+1]
+		}
+	}
+	if model == "" {
+		return "", "", fmt.Errorf("'%s' is not a Veo operation name (expected '.../models/<model>/operations/<id>')", name)
+	}
+	return location, model, nil
+}
+
+// describeOperationProgress describes the state of a running operation from its metadata, e.g.
+// "state: RUNNING, 40% complete", or returns "" if the metadata reports neither.
+func describeOperationProgress(metadata map[string]any) string {
+	var parts []string
+	if state, ok := metadata["state"].(string); ok && state != "" {
+		parts = append(parts, "state: "+state)
+	}
+	if p, ok := metadata["progress_percent"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("%d%% complete", int(p)))
+	} else if p, ok := metadata["progressPercent"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("%d%% complete", int(p)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// startVideoGeneration starts a GenerateVideos operation (failing over to VEO_FALLBACK_LOCATIONS
+// on capacity errors) without waiting for it, and returns its name for 'poll_veo_operation'.
+// Options that process the finished videos are rejected, as nothing is left to apply them.
+func startVideoGeneration(
+	client *genai.Client,
+	ctx context.Context,
+	modelName string,
+	source *genai.GenerateVideosSource,
+	config *genai.GenerateVideosConfig,
+	callType string,
+	polling veoPolling,
+	post postProcessOptions,
+) *mcp.CallToolResult {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "startVideoGeneration")
+	defer span.End()
+
+	switch {
+	case post.OutputDir != "" || post.Faststart:
+		return mcp.NewToolResultError("'output_directory' and 'faststart' cannot be used with 'async'; pass them to poll_veo_operation instead")
+	case post.Transcode != nil:
+		return mcp.NewToolResultError("re-encoding the output (output_codec, output_container, output_bitrate) is not supported with 'async'")
+	case post.Verify:
+		return mcp.NewToolResultError("'verify_output' is not supported with 'async'")
+	}
+
+	// Only the initial call is bounded by the timeout; the operation runs on after it returns.
+	startCtx, cancel := context.WithTimeout(ctx, polling.Timeout)
+	defer cancel()
+	operation, _, location, err := generateVideosWithFailover(startCtx, client, modelName, source, config, callType)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && startCtx.Err() == context.DeadlineExceeded {
+			common.LogErrorf("GenerateVideos (%s) failed: initial call timed out: %v", callType, err)
+			errMsg := fmt.Sprintf("video generation (%s) initiation timed out", callType)
+			auditVideoGeneration(ctx, callType, modelName, source, config, nil, errMsg)
+			return mcp.NewToolResultError(errMsg)
+		}
+		common.LogErrorf("Error initiating GenerateVideos (%s): %v", callType, err)
+		auditVideoGeneration(ctx, callType, modelName, source, config, nil, err.Error())
+		return mcp.NewToolResultError(fmt.Sprintf("error starting video generation (%s): %v", callType, err))
+	}
+	common.LogInfof("GenerateVideos operation (%s) started without waiting in location %s. Operation Name: %s", callType, location, operation.Name)
+	span.SetAttributes(attribute.String("location", location), attribute.String("operation_name", operation.Name))
+
+	return mcp.NewToolResultText(fmt.Sprintf("Started video generation (%s) with model %s in region %s. Operation name: %s. The videos will be saved to %s. Call poll_veo_operation with this operation_name to check its status and get the videos when it is done.",
+		callType, modelName, location, operation.Name, config.OutputGCSURI))
+}
+
+// pollVeoOperationHandler is the handler for the 'poll_veo_operation' tool. It checks the status
+// of an operation started with 'async' once, and summarizes the videos if it is done.
+func pollVeoOperationHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "poll_veo_operation")
+	defer span.End()
+
+	operationName, _ := request.GetArguments()["operation_name"].(string)
+	operationName = strings.TrimSpace(operationName)
+	location, modelName, err := parseVeoOperationName(operationName)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	outputDir, _ := request.GetArguments()["output_directory"].(string)
+	faststart, _ := request.GetArguments()["faststart"].(bool)
+
+	span.SetAttributes(
+		attribute.String("operation_name", operationName),
+		attribute.String("location", location),
+		attribute.String("model", modelName),
+		attribute.String("output_dir", outputDir),
+	)
+
+	// The operation must be polled in the location that accepted it.
+	if location == "" {
+		location = common.EffectiveConfig(ctx, appConfig).Location
+	} else if location != common.EffectiveConfig(ctx, appConfig).Location {
+		if client, err = clientForLocation(ctx, location); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	var operation *genai.GenerateVideosOperation
+	err = common.Retry(ctx, veoPollRetryPolicy, func(pollCtx context.Context) error {
+		var err error
+		operation, err = client.Operations.GetVideosOperation(pollCtx, &genai.GenerateVideosOperation{Name: operationName}, nil)
+		return err
+	})
+	if err != nil {
+		common.LogErrorf("Error polling GenerateVideos operation %s: %v", operationName, err)
+		return mcp.NewToolResultError(fmt.Sprintf("could not get the status of operation %s: %v", operationName, err)), nil
+	}
+
+	if !operation.Done {
+		message := fmt.Sprintf("Operation %s is still running", operationName)
+		if progress := describeOperationProgress(operation.Metadata); progress != "" {
+			message += " (" + progress + ")"
+		}
+		common.LogDebugf("Polled GenerateVideos operation %s: not done yet.", operationName)
+		return mcp.NewToolResultText(message + ". Poll again later."), nil
+	}
+	common.LogInfof("Polled GenerateVideos operation %s: done.", operationName)
+
+	// The request that started the operation is not known here, so its source and settings are empty.
+	config := &genai.GenerateVideosConfig{}
+	if errResult := operationErrorResult(ctx, operation, modelName, nil, config, "poll_operation"); errResult != nil {
+		return errResult, nil
+	}
+	return summarizeGeneratedVideos(ctx, operation, location, 0, modelName, nil, config, "poll_operation", postProcessOptions{OutputDir: outputDir, Faststart: faststart}), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseVeoOperationName(t *testing.T) {
+	tests := []struct {
+		name         string
+		wantLocation string
+		wantModel    string
+		wantErr      bool
+	}{
+		{name: "projects/p/locations/us-east4/publishers/google/models/veo-3.0-generate-001/operations/123", wantLocation: "us-east4", wantModel: "veo-3.0-generate-001"},
+		{name: " models/veo-2.0-generate-001/operations/abc ", wantModel: "veo-2.0-generate-001"},
+		{name: "projects/p/locations/us-central1/operations/123", wantErr: true},
+		{name: "projects/p/locations/us-central1/publishers/google/models/veo-3.0-generate-001", wantErr: true},
+		{name: "models/veo-3.0-generate-001/operations/", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		location, model, err := parseVeoOperationName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseVeoOperationName(%q): expected error: %v, but got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if location != tt.wantLocation || model != tt.wantModel {
+			t.Errorf("parseVeoOperationName(%q): expected (%q, %q), but got (%q, %q)", tt.name, tt.wantLocation, tt.wantModel, location, model)
+		}
+	}
+}
+
+func TestDescribeOperationProgress(t *testing.T) {
+	tests := []struct {
+		metadata map[string]any
+		want     string
+	}{
+		{nil, ""},
+		{map[string]any{"state": "RUNNING", "progress_percent": 40.0}, "state: RUNNING, 40% complete"},
+		{map[string]any{"progressPercent": 75.5}, "75% complete"},
+		{map[string]any{"state": ""}, ""},
+	}
+	for _, tt := range tests {
+		if got := describeOperationProgress(tt.metadata); got != tt.want {
+			t.Errorf("describeOperationProgress(%v): expected %q, but got %q", tt.metadata, tt.want, got)
+		}
+	}
+}
+
+func TestPollVeoOperationHandlerRejectsUnknownLocation(t *testing.T) {
+	setLocationConfig(t, "us-central1", nil, nil)
+	names := []string{
+		"projects/p/locations/attacker.example#/publishers/google/models/veo-3.0-generate-001/operations/1",
+		"projects/p/locations/asia-east1/publishers/google/models/veo-3.0-generate-001/operations/1",
+	}
+	for _, name := range names {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{"operation_name": name}
+		// No client is created or used for a rejected location.
+		result, err := pollVeoOperationHandler(nil, context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: expected no error, but got %v", name, err)
+		}
+		if !result.IsError {
+			t.Errorf("%s: expected an error result, but got success", name)
+			continue
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "location") {
+			t.Errorf("%s: expected an error about the location, but got %q", name, text)
+		}
+	}
+	if len(regionalClients) != 0 {
+		t.Errorf("expected no regional clients to be created, but got %d", len(regionalClients))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+const (
+	defaultVeoPollInterval     = 15 * time.Second
+	defaultVeoOperationTimeout = 5 * time.Minute
+	// minVeoPollInterval keeps clients from polling the operations API in a tight loop.
+	minVeoPollInterval = 1 * time.Second
+	// maxVeoOperationTimeout caps how long a single tool call may wait for a render.
+	maxVeoOperationTimeout = 2 * time.Hour
+)
+
+// veoPolling controls how a GenerateVideos operation is polled: how often its status is checked
+// and how long to wait for it overall, including the initial call.
+type veoPolling struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// veoPollingDefaults is the polling used when a request does not override it, read from
+// VEO_POLL_INTERVAL and VEO_OPERATION_TIMEOUT.
+var veoPollingDefaults = veoPolling{Interval: defaultVeoPollInterval, Timeout: defaultVeoOperationTimeout}
+
+// parseVeoPollingEnv returns the polling defaults from the VEO_POLL_INTERVAL and
+// VEO_OPERATION_TIMEOUT values, which are Go duration strings. Empty or invalid values fall
+// back to the built-in defaults.
+func parseVeoPollingEnv(intervalValue, timeoutValue string) veoPolling {
+	polling := veoPolling{Interval: defaultVeoPollInterval, Timeout: defaultVeoOperationTimeout}
+	if v := strings.TrimSpace(intervalValue); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= minVeoPollInterval {
+			polling.Interval = d
+		} else {
+			common.LogWarnf("Invalid VEO_POLL_INTERVAL value %q (minimum %v), using default of %v", v, minVeoPollInterval, defaultVeoPollInterval)
+		}
+	}
+	if v := strings.TrimSpace(timeoutValue); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 && d <= maxVeoOperationTimeout {
+			polling.Timeout = d
+		} else {
+			common.LogWarnf("Invalid VEO_OPERATION_TIMEOUT value %q (maximum %v), using default of %v", v, maxVeoOperationTimeout, defaultVeoOperationTimeout)
+		}
+	}
+	return polling
+}
+
+// parseVeoPolling reads the optional 'poll_interval_seconds' and 'timeout_seconds' parameters,
+// falling back to defaults for any that are not given.
+func parseVeoPolling(args map[string]interface{}, defaults veoPolling) (veoPolling, error) {
+	polling := defaults
+	if v, ok := args["poll_interval_seconds"].(float64); ok {
+		interval := time.Duration(v * float64(time.Second))
+		if interval < minVeoPollInterval {
+			return veoPolling{}, fmt.Errorf("poll_interval_seconds must be at least %v, got %v", minVeoPollInterval.Seconds(), v)
+		}
+		polling.Interval = interval
+	}
+	if v, ok := args["timeout_seconds"].(float64); ok {
+		timeout := time.Duration(v * float64(time.Second))
+		if timeout <= 0 || timeout > maxVeoOperationTimeout {
+			return veoPolling{}, fmt.Errorf("timeout_seconds must be greater than 0 and at most %v, got %v", maxVeoOperationTimeout.Seconds(), v)
+		}
+		polling.Timeout = timeout
+	}
+	return polling, nil
+}
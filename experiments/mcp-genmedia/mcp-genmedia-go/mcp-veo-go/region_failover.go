@@ -0,0 +1,200 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+var (
+	// veoFallbackLocations is the ordered list of locations tried when the primary location
+	// is out of capacity, read from VEO_FALLBACK_LOCATIONS.
+	veoFallbackLocations []string
+
+	// regionalClients caches the GenAI clients created for fallback and override locations.
+	regionalClients   = map[string]*genai.Client{}
+	regionalClientsMu sync.Mutex
+
+	// locationBreakers holds a GenAI circuit breaker per location, so that an outage in one
+	// location does not fail fast the requests sent to the others.
+	locationBreakers   = map[string]*common.CircuitBreaker{}
+	locationBreakersMu sync.Mutex
+
+	// locationPattern matches a Google Cloud location name, e.g. "us-central1". The location
+	// becomes part of the API host name, so nothing else may pass.
+	locationPattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+)
+
+// parseLocationList parses a comma-separated list of locations, dropping empty entries,
+// duplicates, and the primary location.
+func parseLocationList(value, primary string) []string {
+	var locations []string
+	seen := map[string]bool{primary: true}
+	for _, loc := range strings.Split(value, ",") {
+		loc = strings.TrimSpace(loc)
+		if loc == "" || seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		locations = append(locations, loc)
+	}
+	return locations
+}
+
+// newVeoClient creates a GenAI client for the given location using the server's backend,
+// project, endpoint, and transport settings.
+func newVeoClient(ctx context.Context, location string) (*genai.Client, error) {
+	clientConfig := appConfig.NewGenAIClientConfig(location)
+
+	if err := common.ApplyGenAITransportSettings(clientConfig); err != nil {
+		common.LogWarnf("Warning: Failed to apply GenAI transport settings: %v", err)
+	}
+
+	return genai.NewClient(ctx, clientConfig)
+}
+
+// clientForLocation returns a cached client for a location other than the primary one (a
+// fallback location or a request's location override), creating it on first use.
+func clientForLocation(ctx context.Context, location string) (*genai.Client, error) {
+	if err := checkVeoLocation(location); err != nil {
+		return nil, err
+	}
+	regionalClientsMu.Lock()
+	defer regionalClientsMu.Unlock()
+
+	if client, ok := regionalClients[location]; ok {
+		return client, nil
+	}
+	client, err := newVeoClient(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("creating GenAI client for location %s: %w", location, err)
+	}
+	regionalClients[location] = client
+	common.LogInfof("GenAI client for location %s initialized successfully.", location)
+	return client, nil
+}
+
+// checkVeoLocation returns an error unless location is a well-formed location this server is
+// configured to use: the primary location, one of CONFIG_OVERRIDE_LOCATIONS, or one of
+// VEO_FALLBACK_LOCATIONS. This keeps a caller-supplied location (e.g., from an operation name)
+// from redirecting requests and credentials to another host, and bounds the client cache.
+func checkVeoLocation(location string) error {
+	if !locationPattern.MatchString(location) {
+		return fmt.Errorf("location '%s' is not a valid Google Cloud location", location)
+	}
+	if location != appConfig.Location && !slices.Contains(appConfig.OverrideLocations, location) && !slices.Contains(veoFallbackLocations, location) {
+		return fmt.Errorf("location '%s' is not configured on this server; allowed locations are the primary location (%s), CONFIG_OVERRIDE_LOCATIONS, and VEO_FALLBACK_LOCATIONS", location, appConfig.Location)
+	}
+	return nil
+}
+
+// isCapacityError reports whether err is a quota or capacity error (HTTP 429 / RESOURCE_EXHAUSTED)
+// that may succeed in a different location.
+func isCapacityError(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || strings.Contains(apiErr.Status, "RESOURCE_EXHAUSTED")
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return apiErrPtr.Code == http.StatusTooManyRequests || strings.Contains(apiErrPtr.Status, "RESOURCE_EXHAUSTED")
+	}
+	return false
+}
+
+// breakerForLocation returns the circuit breaker for a location, created on first use from the
+// CIRCUIT_BREAKER_* settings. It is nil (disabled) if the threshold is not positive.
+func breakerForLocation(location string) *common.CircuitBreaker {
+	locationBreakersMu.Lock()
+	defer locationBreakersMu.Unlock()
+
+	breaker, ok := locationBreakers[location]
+	if !ok {
+		breaker = common.NewCircuitBreaker(fmt.Sprintf("GenAI API (%s)", location), appConfig.CircuitBreakerThreshold, appConfig.CircuitBreakerCooldown)
+		locationBreakers[location] = breaker
+	}
+	return breaker
+}
+
+// generateVideosFromSource starts a GenerateVideos operation through the circuit breaker of the
+// client's location.
+func generateVideosFromSource(ctx context.Context, client *genai.Client, location, modelName string, source *genai.GenerateVideosSource, config *genai.GenerateVideosConfig) (*genai.GenerateVideosOperation, error) {
+	var operation *genai.GenerateVideosOperation
+	err := breakerForLocation(location).Execute(ctx, func(ctx context.Context) error {
+		var callErr error
+		operation, callErr = client.Models.GenerateVideosFromSource(ctx, modelName, source, config)
+		return callErr
+	})
+	return operation, err
+}
+
+// generateVideosWithFailover starts a GenerateVideos operation with the primary client and, if the
+// primary location reports a capacity error, tries each of veoFallbackLocations in order.
+// It returns the operation along with the client and location that accepted it; the operation
+// must be polled with that same client.
+func generateVideosWithFailover(ctx context.Context, client *genai.Client, modelName string, source *genai.GenerateVideosSource, config *genai.GenerateVideosConfig, callType string) (*genai.GenerateVideosOperation, *genai.Client, string, error) {
+	primary := common.EffectiveConfig(ctx, appConfig).Location
+	location := primary
+	operation, err := generateVideosFromSource(ctx, client, location, modelName, source, config)
+	if err == nil || !isCapacityError(err) || len(veoFallbackLocations) == 0 {
+		return operation, client, location, err
+	}
+
+	for _, fallback := range veoFallbackLocations {
+		if fallback == primary {
+			continue
+		}
+		common.LogWarnf("GenerateVideos (%s) in location %s hit a capacity error: %v. Trying fallback location %s.", callType, location, err, fallback)
+		fallbackClient, clientErr := clientForLocation(ctx, fallback)
+		if clientErr != nil {
+			common.LogWarnf("Skipping fallback location %s: %v", fallback, clientErr)
+			continue
+		}
+		location = fallback
+		operation, err = generateVideosFromSource(ctx, fallbackClient, location, modelName, source, config)
+		if err == nil || !isCapacityError(err) {
+			return operation, fallbackClient, location, err
+		}
+	}
+	return nil, nil, location, fmt.Errorf("all locations are out of capacity (last tried %s): %w", location, err)
+}
+
+// resolveRequestConfig applies the request's configuration overrides (see common.MergeConfig). It
+// returns a context carrying the effective configuration, the configuration itself, and the
+// client for its location, which is client unless the location is overridden.
+func resolveRequestConfig(ctx context.Context, client *genai.Client, args map[string]interface{}) (context.Context, *common.Config, *genai.Client, error) {
+	cfg, err := appConfig.MergeConfig(common.ParseConfigOverrides(args))
+	if err != nil {
+		return ctx, nil, nil, err
+	}
+	if cfg.Location != appConfig.Location {
+		if client, err = clientForLocation(ctx, cfg.Location); err != nil {
+			return ctx, nil, nil, err
+		}
+	}
+	return common.WithEffectiveConfig(ctx, cfg), cfg, client, nil
+}
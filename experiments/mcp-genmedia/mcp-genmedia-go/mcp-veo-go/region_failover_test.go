@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestParseLocationList(t *testing.T) {
+	tests := []struct {
+		value   string
+		primary string
+		want    []string
+	}{
+		{"", "us-central1", nil},
+		{"us-east4, europe-west4", "us-central1", []string{"us-east4", "europe-west4"}},
+		{"us-central1,us-east4,,us-east4", "us-central1", []string{"us-east4"}},
+	}
+	for _, tt := range tests {
+		if got := parseLocationList(tt.value, tt.primary); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLocationList(%q, %q): expected %v, but got %v", tt.value, tt.primary, tt.want, got)
+		}
+	}
+}
+
+func TestIsCapacityError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"429", genai.APIError{Code: 429, Status: "429 Too Many Requests"}, true},
+		{"wrapped resource exhausted", fmt.Errorf("start: %w", genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}), true},
+		{"pointer", &genai.APIError{Code: 429}, true},
+		{"invalid argument", genai.APIError{Code: 400, Status: "INVALID_ARGUMENT"}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCapacityError(tt.err); got != tt.want {
+				t.Errorf("expected %t, but got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+// setLocationConfig configures the primary, override, and fallback locations for a test.
+func setLocationConfig(t *testing.T, primary string, overrides, fallbacks []string) {
+	savedConfig, savedFallbacks := appConfig, veoFallbackLocations
+	t.Cleanup(func() { appConfig, veoFallbackLocations = savedConfig, savedFallbacks })
+	appConfig = &common.Config{Location: primary, OverrideLocations: overrides}
+	veoFallbackLocations = fallbacks
+}
+
+func TestCheckVeoLocation(t *testing.T) {
+	setLocationConfig(t, "us-central1", []string{"europe-west4"}, []string{"us-east4"})
+	tests := []struct {
+		location string
+		wantErr  bool
+	}{
+		{"us-central1", false},
+		{"europe-west4", false},
+		{"us-east4", false},
+		{"asia-east1", true},
+		{"attacker.example#", true},
+		{"us-central1.attacker.example", true},
+		{"US-CENTRAL1", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if err := checkVeoLocation(tt.location); (err != nil) != tt.wantErr {
+			t.Errorf("checkVeoLocation(%q): expected error: %v, but got %v", tt.location, tt.wantErr, err)
+		}
+	}
+}
+
+func TestBreakerForLocation(t *testing.T) {
+	setLocationConfig(t, "us-central1", nil, []string{"us-east4"})
+	appConfig.CircuitBreakerThreshold = 2
+	savedBreakers := locationBreakers
+	t.Cleanup(func() { locationBreakers = savedBreakers })
+	locationBreakers = map[string]*common.CircuitBreaker{}
+
+	primary := breakerForLocation("us-central1")
+	if primary == nil {
+		t.Fatalf("expected a breaker for the primary location")
+	}
+	if breakerForLocation("us-central1") != primary {
+		t.Errorf("expected the same breaker for the same location")
+	}
+	if breakerForLocation("us-east4") == primary {
+		t.Errorf("expected a separate breaker for each location")
+	}
+}
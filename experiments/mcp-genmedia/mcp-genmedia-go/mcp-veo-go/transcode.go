@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+// transcodedVideo is a generated video after re-encoding with the requested output options.
+type transcodedVideo struct {
+	GCSURI          string
+	LocalPath       string // Set if the video was saved to the output directory.
+	OriginalBytes   int64
+	TranscodedBytes int64
+}
+
+// describe summarizes the transcoded video for the tool result.
+func (t transcodedVideo) describe() string {
+	location := t.GCSURI
+	if t.LocalPath != "" {
+		location += " (local: " + t.LocalPath + ")"
+	}
+	return fmt.Sprintf("%s, %s -> %s", location, common.FormatBytes(t.OriginalBytes), common.FormatBytes(t.TranscodedBytes))
+}
+
+// transcodeGeneratedVideo downloads the video at gcsURI, re-encodes it with opts, and uploads the
+// result next to the original, which is kept. If outputDir is set, the re-encoded video is also
+// saved there as localName with the extension of the output container.
+func transcodeGeneratedVideo(ctx context.Context, gcsURI, outputDir, localName string, opts common.VideoTranscodeOptions) (transcodedVideo, error) {
+	bucketName, objectName, err := common.ParseGCSPath(gcsURI)
+	if err != nil {
+		return transcodedVideo{}, err
+	}
+	tempDir, err := os.MkdirTemp("", "veo_transcode_")
+	if err != nil {
+		return transcodedVideo{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	originalPath := filepath.Join(tempDir, "original.mp4")
+	if err := common.DownloadGeneratedFromGCS(ctx, gcsURI, originalPath); err != nil {
+		return transcodedVideo{}, fmt.Errorf("failed to download %s: %w", gcsURI, err)
+	}
+	transcodedPath := filepath.Join(tempDir, "transcoded"+opts.Extension())
+	if err := common.TranscodeVideoFile(ctx, originalPath, transcodedPath, opts); err != nil {
+		return transcodedVideo{}, err
+	}
+
+	var result transcodedVideo
+	if info, err := os.Stat(originalPath); err == nil {
+		result.OriginalBytes = info.Size()
+	}
+	transcoded, err := os.ReadFile(transcodedPath)
+	if err != nil {
+		return transcodedVideo{}, fmt.Errorf("failed to read transcoded video: %w", err)
+	}
+	result.TranscodedBytes = int64(len(transcoded))
+
+	// e.g. sample_0.mp4 -> sample_0_h265.mp4, next to the original.
+	base := strings.TrimSuffix(path.Base(objectName), path.Ext(objectName))
+	transcodedObject := path.Join(path.Dir(objectName), fmt.Sprintf("%s_%s%s", base, opts.Codec, opts.Extension()))
+	if err := common.UploadGeneratedToGCS(ctx, bucketName, transcodedObject, opts.MIMEType(), transcoded); err != nil {
+		return transcodedVideo{}, fmt.Errorf("failed to upload transcoded video: %w", err)
+	}
+	result.GCSURI = fmt.Sprintf("gs://%s/%s", bucketName, transcodedObject)
+
+	if outputDir != "" {
+		localPath := filepath.Clean(filepath.Join(outputDir, strings.TrimSuffix(localName, filepath.Ext(localName))+opts.Extension()))
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return result, fmt.Errorf("error creating directory %s: %w", outputDir, err)
+		}
+		if err := os.WriteFile(localPath, transcoded, 0644); err != nil {
+			return result, fmt.Errorf("error writing %s: %w", localPath, err)
+		}
+		result.LocalPath = localPath
+	}
+	return result, nil
+}
@@ -18,11 +18,14 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/teris-io/shortid"
+	"google.golang.org/genai"
 )
 
 // inferMimeTypeFromURI attempts to determine the MIME type of a file based on its extension.
@@ -39,7 +42,8 @@ func inferMimeTypeFromURI(uri string) string {
 }
 
 // parseCommonVideoParams extracts and validates video generation parameters from the request arguments.
-func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Config, isExtend bool) (string, string, string, string, int32, int32, bool, string, error) {
+// It returns the resolved model so that callers can check further capabilities without resolving it again.
+func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Config, isExtend bool) (string, string, common.VeoModelInfo, string, int32, int32, bool, string, error) {
 	// Model
 	modelInput, ok := args["model"].(string)
 	if !ok || modelInput == "" {
@@ -47,18 +51,22 @@ func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Confi
 	}
 	modelInfo, found := common.ResolveVeoModel(modelInput, appConfig.AllowUnsafeModels)
 	if !found {
-		return "", "", "", "", 0, 0, false, "", fmt.Errorf("model '%s' is not a valid or supported model name", modelInput)
+		return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", fmt.Errorf("model '%s' is not a valid or supported model name", modelInput)
 	}
 	model := modelInfo.CanonicalName
-	modelDetails := modelInfo
 
 	// GCS Bucket
 	gcsBucket, _ := args["bucket"].(string)
 	if gcsBucket != "" {
 		gcsBucket = common.EnsureGCSPathPrefix(gcsBucket)
 	} else if appConfig.GenmediaBucket != "" {
-		gcsBucket = fmt.Sprintf("gs://%s/veo_outputs/", appConfig.GenmediaBucket)
-		log.Printf("Handler: 'bucket' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsBucket)
+		outputPathTemplate := appConfig.VeoOutputPathTemplate
+		if outputPathTemplate == "" {
+			outputPathTemplate = common.DefaultVeoOutputPathTemplate
+		}
+		requestID, _ := shortid.Generate()
+		gcsBucket = fmt.Sprintf("gs://%s/%s", appConfig.GenmediaBucket, common.ExpandOutputPathTemplate(outputPathTemplate, model, requestID, time.Now()))
+		common.LogInfof("Handler: 'bucket' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsBucket)
 	}
 
 	// Output Directory
@@ -72,9 +80,10 @@ func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Confi
 	if numberOfVideos < 1 {
 		numberOfVideos = 1
 	}
-	if numberOfVideos > modelDetails.MaxVideos {
-		log.Printf("Warning: Requested %d videos, but model %s only supports up to %d. Adjusting to max.", numberOfVideos, model, modelDetails.MaxVideos)
-		numberOfVideos = modelDetails.MaxVideos
+	maxVideos := modelInfo.MaxVideos
+	if numberOfVideos > maxVideos {
+		common.LogWarnf("Warning: Requested %d videos, but model %s only supports up to %d. Adjusting to max.", numberOfVideos, model, maxVideos)
+		numberOfVideos = maxVideos
 	}
 
 	// Duration
@@ -82,67 +91,110 @@ func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Confi
 	if isExtend {
 		durationSecs = 7
 	} else {
-		durationSecs = modelDetails.DefaultDuration
+		durationSecs = modelInfo.DefaultDuration
 		if durationArg, ok := args["duration"].(float64); ok {
 			durationSecs = int32(durationArg)
 		}
-		validDuration := false
-		for _, d := range modelDetails.SupportedDurations {
-			if d == durationSecs {
-				validDuration = true
-				break
-			}
-		}
-		if !validDuration {
+		if !modelInfo.SupportsDuration(durationSecs) {
 			// Create a string representation of the supported durations for the error message
-			durationsStr := make([]string, len(modelDetails.SupportedDurations))
-			for i, d := range modelDetails.SupportedDurations {
+			durationsStr := make([]string, len(modelInfo.SupportedDurations))
+			for i, d := range modelInfo.SupportedDurations {
 				durationsStr[i] = fmt.Sprintf("%d", d)
 			}
-			return "", "", "", "", 0, 0, false, "", fmt.Errorf("duration '%d' is not supported by model %s. Supported durations are: [%s]", durationSecs, model, strings.Join(durationsStr, ", "))
+			return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", fmt.Errorf("duration '%d' is not supported by model %s. Supported durations are: [%s]", durationSecs, model, strings.Join(durationsStr, ", "))
 		}
 	}
 
 	// Aspect Ratio
 	finalAspectRatio, _ := args["aspect_ratio"].(string)
-	if finalAspectRatio == "" {
-		if len(modelDetails.SupportedAspectRatios) > 0 {
-			finalAspectRatio = modelDetails.SupportedAspectRatios[0]
-		} else {
-			finalAspectRatio = "16:9"
-		}
+	finalAspectRatio, err := common.NormalizeAspectRatio(finalAspectRatio, modelInfo.SupportedAspectRatios)
+	if err != nil {
+		return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", err
 	}
-	validRatio := false
-	for _, r := range modelDetails.SupportedAspectRatios {
-		if r == finalAspectRatio {
-			validRatio = true
-			break
-		}
+	if finalAspectRatio == "" {
+		finalAspectRatio = modelInfo.DefaultAspectRatio()
 	}
-	if !validRatio {
-		return "", "", "", "", 0, 0, false, "", fmt.Errorf("aspect ratio '%s' is not supported by model %s", finalAspectRatio, model)
+	if !modelInfo.SupportsAspectRatio(finalAspectRatio) {
+		return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", fmt.Errorf("aspect ratio '%s' is not supported by model %s", finalAspectRatio, model)
 	}
 
-	// Generate Audio
-	generateAudio := true // Default to true as per user request
+	// Generate Audio: defaults to on for models that can generate audio and off otherwise,
+	// so that leaving it unset never fails on a Veo 2 model.
+	supportsAudio := modelInfo.SupportsGenerateAudio
+	generateAudio := supportsAudio
 	if genAudioArg, ok := args["generate_audio"].(bool); ok {
 		generateAudio = genAudioArg
 	}
 
-	if generateAudio && !modelDetails.SupportsGenerateAudio {
-		return "", "", "", "", 0, 0, false, "", fmt.Errorf("generate_audio is set to true, but is not supported by model %s", model)
+	if generateAudio && !supportsAudio {
+		return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", fmt.Errorf("generate_audio is set to true, but is not supported by model %s", model)
 	}
 	
 	// Person Generation
+	personGeneration, err := parsePersonGeneration(args)
+	if err != nil {
+		return "", "", common.VeoModelInfo{}, "", 0, 0, false, "", err
+	}
+
+	return gcsBucket, outputDir, modelInfo, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, nil
+}
+
+// personGenerationOptions lists the supported values of the 'person_generation' parameter.
+var personGenerationOptions = []string{"dont_allow", "allow_adult"}
+
+// parsePersonGeneration reads and validates the 'person_generation' parameter, defaulting to 'allow_adult'.
+func parsePersonGeneration(args map[string]interface{}) (string, error) {
 	personGeneration, _ := args["person_generation"].(string)
+	personGeneration = strings.ToLower(strings.TrimSpace(personGeneration))
 	if personGeneration == "" {
-		personGeneration = "allow_adult"
+		return "allow_adult", nil
 	}
-	
-	validPersonGeneration := personGeneration == "dont_allow" || personGeneration == "allow_adult"
-	if !validPersonGeneration {
-		return "", "", "", "", 0, 0, false, "", fmt.Errorf("person_generation '%s' is invalid. Supported values are 'dont_allow', 'allow_adult'", personGeneration)
+	if !slices.Contains(personGenerationOptions, personGeneration) {
+		return "", fmt.Errorf("person_generation '%s' is invalid. Supported values are '%s'", personGeneration, strings.Join(personGenerationOptions, "', '"))
+	}
+	return personGeneration, nil
+}
+
+// compressionQualityOptions maps the values of the 'compression_quality' parameter to the API's.
+var compressionQualityOptions = map[string]genai.VideoCompressionQuality{
+	"optimized": genai.VideoCompressionQualityOptimized,
+	"lossless":  genai.VideoCompressionQualityLossless,
+}
+
+// parseCompressionQuality reads and validates the optional 'compression_quality' parameter. It
+// returns "" if the parameter is not set, leaving the choice to the API.
+func parseCompressionQuality(args map[string]interface{}) (genai.VideoCompressionQuality, error) {
+	value, _ := args["compression_quality"].(string)
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
 	}
+	quality, ok := compressionQualityOptions[value]
+	if !ok {
+		return "", fmt.Errorf("compression_quality '%s' is invalid. Supported values are 'optimized', 'lossless'", value)
+	}
+	return quality, nil
+}
 
-	return gcsBucket, outputDir, model, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, personGeneration, nil
+// parseVeoFPS reads the optional 'fps' parameter. It returns nil if the parameter is not set, or
+// if the model does not accept a frame rate, in which case the parameter is ignored with a warning.
+// A frame rate the model does not support is an error.
+func parseVeoFPS(args map[string]interface{}, modelInfo common.VeoModelInfo) (*int32, error) {
+	fpsArg, ok := args["fps"].(float64)
+	if !ok {
+		return nil, nil
+	}
+	fps := int32(fpsArg)
+	if len(modelInfo.SupportedFPS) == 0 {
+		common.LogWarnf("Warning: fps parameter (%d) provided, but model %s does not support it. The parameter will be ignored.", fps, modelInfo.CanonicalName)
+		return nil, nil
+	}
+	if float64(fps) != fpsArg || !modelInfo.SupportsFPS(fps) {
+		fpsStr := make([]string, len(modelInfo.SupportedFPS))
+		for i, f := range modelInfo.SupportedFPS {
+			fpsStr[i] = fmt.Sprintf("%d", f)
+		}
+		return nil, fmt.Errorf("fps '%g' is not supported by model %s. Supported frame rates are: [%s]", fpsArg, modelInfo.CanonicalName, strings.Join(fpsStr, ", "))
+	}
+	return &fps, nil
 }
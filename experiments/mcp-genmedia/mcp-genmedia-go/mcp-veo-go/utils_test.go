@@ -0,0 +1,303 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestParseCommonVideoParams(t *testing.T) {
+	cfg := &common.Config{}
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		wantRatio   string
+		wantVideos  int32
+		wantDur     int32
+		wantAudio   bool
+		errContains string
+	}{
+		{
+			name:       "defaults",
+			args:       map[string]interface{}{"generate_audio": false},
+			wantRatio:  "16:9",
+			wantVideos: 1,
+			wantDur:    8,
+		},
+		{
+			name:       "num_videos clamped to model max",
+			args:       map[string]interface{}{"num_videos": float64(10), "generate_audio": false},
+			wantRatio:  "16:9",
+			wantVideos: 4,
+			wantDur:    8,
+		},
+		{
+			name:        "unsupported duration",
+			args:        map[string]interface{}{"duration": float64(3), "generate_audio": false},
+			errContains: "duration '3' is not supported",
+		},
+		{
+			name:        "unsupported aspect ratio",
+			args:        map[string]interface{}{"aspect_ratio": "1:1", "generate_audio": false},
+			errContains: "aspect ratio '1:1' is not supported",
+		},
+		{
+			name:       "aspect ratio alias forms normalized",
+			args:       map[string]interface{}{"aspect_ratio": "9x16", "generate_audio": false},
+			wantRatio:  "9:16",
+			wantVideos: 1,
+			wantDur:    8,
+		},
+		{
+			name:       "decimal aspect ratio normalized",
+			args:       map[string]interface{}{"aspect_ratio": "1.77", "generate_audio": false},
+			wantRatio:  "16:9",
+			wantVideos: 1,
+			wantDur:    8,
+		},
+		{
+			name:       "audio defaults off for veo 2",
+			args:       map[string]interface{}{},
+			wantRatio:  "16:9",
+			wantVideos: 1,
+			wantDur:    8,
+		},
+		{
+			name:        "audio not supported",
+			args:        map[string]interface{}{"generate_audio": true},
+			errContains: "generate_audio is set to true",
+		},
+		{
+			name:       "audio defaults on for veo 3",
+			args:       map[string]interface{}{"model": "veo-3.1-fast-generate-001"},
+			wantRatio:  "16:9",
+			wantVideos: 1,
+			wantDur:    8,
+			wantAudio:  true,
+		},
+		{
+			name:        "unknown model",
+			args:        map[string]interface{}{"model": "not-a-model"},
+			errContains: "not a valid or supported model name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, ratio, videos, dur, audio, _, err := parseCommonVideoParams(tt.args, cfg, false)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, but got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if ratio != tt.wantRatio {
+				t.Errorf("expected aspect ratio %s, but got %s", tt.wantRatio, ratio)
+			}
+			if videos != tt.wantVideos {
+				t.Errorf("expected %d videos, but got %d", tt.wantVideos, videos)
+			}
+			if dur != tt.wantDur {
+				t.Errorf("expected duration %d, but got %d", tt.wantDur, dur)
+			}
+			if audio != tt.wantAudio {
+				t.Errorf("expected generate_audio %t, but got %t", tt.wantAudio, audio)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsDefaultBucket(t *testing.T) {
+	month := time.Now().UTC().Format("2006-01")
+	tests := []struct {
+		name string
+		cfg  *common.Config
+		args map[string]interface{}
+		want string
+	}{
+		{name: "default template", cfg: &common.Config{GenmediaBucket: "my-bucket"}, want: "gs://my-bucket/veo_outputs/"},
+		{name: "configured template", cfg: &common.Config{GenmediaBucket: "my-bucket", VeoOutputPathTemplate: "veo_outputs/{month}/{model}/"}, want: "gs://my-bucket/veo_outputs/" + month + "/veo-2.0-generate-001/"},
+		{name: "request bucket", cfg: &common.Config{GenmediaBucket: "my-bucket", VeoOutputPathTemplate: "veo_outputs/{month}/"}, args: map[string]interface{}{"bucket": "other-bucket/videos"}, want: "gs://other-bucket/videos"},
+		{name: "no bucket", cfg: &common.Config{VeoOutputPathTemplate: "veo_outputs/{month}/"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"generate_audio": false}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			gcsBucket, _, _, _, _, _, _, _, err := parseCommonVideoParams(args, tt.cfg, false)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if gcsBucket != tt.want {
+				t.Errorf("expected bucket %q, but got %q", tt.want, gcsBucket)
+			}
+		})
+	}
+}
+
+func TestParsePersonGeneration(t *testing.T) {
+	tests := []struct {
+		value       interface{}
+		want        string
+		errContains string
+	}{
+		{value: nil, want: "allow_adult"},
+		{value: "dont_allow", want: "dont_allow"},
+		{value: " ALLOW_ADULT ", want: "allow_adult"},
+		{value: "allow_everyone", errContains: "person_generation 'allow_everyone' is invalid"},
+	}
+
+	for _, tt := range tests {
+		args := map[string]interface{}{}
+		if tt.value != nil {
+			args["person_generation"] = tt.value
+		}
+		got, err := parsePersonGeneration(args)
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, but got: %v", tt.errContains, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expected %q for %v, but got %q (err: %v)", tt.want, tt.value, got, err)
+		}
+	}
+}
+
+func TestParseCompressionQuality(t *testing.T) {
+	tests := []struct {
+		value       interface{}
+		want        genai.VideoCompressionQuality
+		errContains string
+	}{
+		{value: nil, want: ""},
+		{value: "optimized", want: genai.VideoCompressionQualityOptimized},
+		{value: " Lossless ", want: genai.VideoCompressionQualityLossless},
+		{value: "best", errContains: "compression_quality 'best' is invalid"},
+	}
+
+	for _, tt := range tests {
+		args := map[string]interface{}{}
+		if tt.value != nil {
+			args["compression_quality"] = tt.value
+		}
+		got, err := parseCompressionQuality(args)
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, but got: %v", tt.errContains, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expected %q for %v, but got %q (err: %v)", tt.want, tt.value, got, err)
+		}
+	}
+}
+
+func TestParseVeoPolling(t *testing.T) {
+	defaults := veoPolling{Interval: 15 * time.Second, Timeout: 5 * time.Minute}
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		want        veoPolling
+		errContains string
+	}{
+		{name: "defaults", args: map[string]interface{}{}, want: defaults},
+		{name: "overrides", args: map[string]interface{}{"poll_interval_seconds": 30.0, "timeout_seconds": 1200.0}, want: veoPolling{Interval: 30 * time.Second, Timeout: 20 * time.Minute}},
+		{name: "interval too short", args: map[string]interface{}{"poll_interval_seconds": 0.5}, errContains: "poll_interval_seconds"},
+		{name: "timeout too long", args: map[string]interface{}{"timeout_seconds": 10000.0}, errContains: "timeout_seconds"},
+		{name: "negative timeout", args: map[string]interface{}{"timeout_seconds": -1.0}, errContains: "timeout_seconds"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseVeoPolling(tt.args, defaults)
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("%s: expected error containing %q, but got: %v", tt.name, tt.errContains, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %+v, but got %+v (err: %v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestParseVeoPollingEnv(t *testing.T) {
+	if got := parseVeoPollingEnv("", ""); got.Interval != defaultVeoPollInterval || got.Timeout != defaultVeoOperationTimeout {
+		t.Errorf("expected built-in defaults, but got %+v", got)
+	}
+	if got := parseVeoPollingEnv("30s", "45m"); got.Interval != 30*time.Second || got.Timeout != 45*time.Minute {
+		t.Errorf("expected 30s/45m, but got %+v", got)
+	}
+	if got := parseVeoPollingEnv("10ms", "forever"); got.Interval != defaultVeoPollInterval || got.Timeout != defaultVeoOperationTimeout {
+		t.Errorf("expected invalid values to fall back to defaults, but got %+v", got)
+	}
+}
+
+func TestParseVeoFPS(t *testing.T) {
+	veo3 := common.SupportedVeoModels["veo-3.1-generate-001"]
+	veo2 := common.SupportedVeoModels["veo-2.0-generate-001"]
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		model       common.VeoModelInfo
+		want        int32 // 0 means unset.
+		errContains string
+	}{
+		{name: "unset", args: map[string]interface{}{}, model: veo3},
+		{name: "supported", args: map[string]interface{}{"fps": float64(24)}, model: veo3, want: 24},
+		{name: "unsupported rate", args: map[string]interface{}{"fps": float64(60)}, model: veo3, errContains: "fps '60' is not supported"},
+		{name: "fractional rate", args: map[string]interface{}{"fps": 23.976}, model: veo3, errContains: "Supported frame rates are: [24]"},
+		{name: "ignored by model without fps", args: map[string]interface{}{"fps": float64(24)}, model: veo2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVeoFPS(tt.args, tt.model)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == nil) != (tt.want == 0) || (got != nil && *got != tt.want) {
+				t.Errorf("parseVeoFPS() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePostProcessOptions(t *testing.T) {
+	got, err := parsePostProcessOptions(map[string]interface{}{"verify_output": true, "faststart": true}, "/tmp/out")
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if got.OutputDir != "/tmp/out" || got.Transcode != nil || !got.Verify || !got.Faststart {
+		t.Errorf("expected the output directory, verify and faststart without transcoding, but got %+v", got)
+	}
+
+	got, err = parsePostProcessOptions(map[string]interface{}{"output_container": "webm"}, "")
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if got.Transcode == nil || got.Transcode.Codec != "vp9" || got.Verify || got.Faststart {
+		t.Errorf("expected a vp9 transcode only, but got %+v", got)
+	}
+
+	if _, err := parsePostProcessOptions(map[string]interface{}{"output_codec": "mpeg2"}, ""); err == nil || !strings.Contains(err.Error(), "unsupported output_codec") {
+		t.Errorf("expected an unsupported codec error, but got: %v", err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -52,7 +53,6 @@ func init() {
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse, or http)")
 	flag.IntVar(&port, "p", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
 	flag.IntVar(&port, "port", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
-	flag.Parse()
 }
 
 // main is the entry point for the mcp-veo-go service.
@@ -60,6 +60,8 @@ func init() {
 // It then creates an MCP server, registers the 'veo_t2v' and 'veo_i2v' tools,
 // and starts listening for requests on the configured transport.
 func main() {
+	flag.Parse() // Ensure flags are parsed before use
+
 	var err error
 
 	// Initialize OpenTelemetry
@@ -67,26 +69,27 @@ func main() {
 	appConfig, cleanup = common.Init(serviceName, version)
 	defer cleanup()
 
-	log.Printf("Initializing global GenAI client...")
+	common.LogInfof("Initializing global GenAI client...")
 	clientCtx, clientCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer clientCancel()
 
-	clientConfig := &genai.ClientConfig{
-		Backend:  genai.BackendVertexAI,
-		Project:  appConfig.ProjectID,
-		Location: appConfig.Location,
+	genAIClient, err = common.NewGenAIClientWithRetry(clientCtx, func(ctx context.Context) (*genai.Client, error) {
+		return newVeoClient(ctx, appConfig.Location)
+	})
+	if err != nil && !common.IsTransientClientError(err) {
+		common.LogFatalf("Error creating global GenAI client: %v. Check the credentials and GENAI_BACKEND, PROJECT_ID and GEMINI_API_KEY settings.", err)
+	} else if err != nil {
+		common.LogWarnf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
+	} else {
+		common.LogInfof("Global GenAI client initialized successfully.")
 	}
 
-	if appConfig.ApiEndpoint != "" {
-		log.Printf("Using custom Vertex AI endpoint: %s", appConfig.ApiEndpoint)
-		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
-	}
+	veoPollingDefaults = parseVeoPollingEnv(os.Getenv("VEO_POLL_INTERVAL"), os.Getenv("VEO_OPERATION_TIMEOUT"))
+	common.LogInfof("Veo operations are polled every %v with a timeout of %v", veoPollingDefaults.Interval, veoPollingDefaults.Timeout)
 
-	genAIClient, err = genai.NewClient(clientCtx, clientConfig)
-	if err != nil {
-		log.Printf("Warning: Error creating global GenAI client: %v. Deferring initialization to runtime.", err)
-	} else {
-		log.Printf("Global GenAI client initialized successfully.")
+	veoFallbackLocations = parseLocationList(os.Getenv("VEO_FALLBACK_LOCATIONS"), appConfig.Location)
+	if len(veoFallbackLocations) > 0 {
+		common.LogInfof("Fallback locations for capacity errors: %s", strings.Join(veoFallbackLocations, ", "))
 	}
 
 	s := server.NewMCPServer(
@@ -110,21 +113,68 @@ func main() {
 			mcp.Description("Number of videos to generate. Note: the maximum is model-dependent."),
 		),
 		mcp.WithString("aspect_ratio",
-			mcp.Description("Aspect ratio of the generated videos. Note: supported aspect ratios are model-dependent."),
+			mcp.Description("Aspect ratio of the generated videos, e.g. \"16:9\". Forms such as \"16x9\", \"16/9\", \"1.77\", or \"portrait\" are normalized to W:H. Note: supported aspect ratios are model-dependent."),
 		),
 		mcp.WithNumber("duration",
 			mcp.Description("Duration of the generated video in seconds. Note: the supported duration range is model-dependent."),
 		),
 		mcp.WithBoolean("generate_audio",
-			mcp.DefaultBool(true),
-			mcp.Description("Optional. Generate audio for the video. Only supported by Veo 3 models. Defaults to true."),
+			mcp.Description("Optional. Generate audio for the video. Only supported by Veo 3 models. Defaults to true for models that support audio and false otherwise."),
 		),
 		mcp.WithString("person_generation",
 			mcp.DefaultString("allow_adult"),
-			mcp.Description("Whether to allow generating videos with people. Supported values: 'dont_allow', 'allow_adult'."),
+			mcp.Enum(personGenerationOptions...),
+			mcp.Description("Whether to allow generating videos with people. Supported values: 'dont_allow', 'allow_adult'. If videos are filtered by this setting, the result reports how many and why."),
+		),
+		mcp.WithBoolean(common.RawPromptParam,
+			mcp.Description("Optional. If true, the server-configured PROMPT_PREFIX/PROMPT_SUFFIX are not applied to the prompt."),
+		),
+		mcp.WithBoolean(common.EnhancePromptParam,
+			mcp.Description("Optional. If true, the model rewrites the prompt into a more detailed one before generating. The result repeats the prompt that was sent; the Veo API does not return the rewritten prompt."),
+		),
+		mcp.WithNumber("poll_interval_seconds",
+			mcp.Description("Optional. How often to check the status of the generation, in seconds. Defaults to VEO_POLL_INTERVAL, or 15."),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description(fmt.Sprintf("Optional. How long to wait for the generation to finish, in seconds (at most %d). Increase it for long or high-resolution renders. Defaults to VEO_OPERATION_TIMEOUT, or 300.", int(maxVeoOperationTimeout.Seconds()))),
+		),
+		mcp.WithString("output_codec",
+			mcp.Enum("h264", "h265", "vp9"),
+			mcp.Description("Optional. Re-encode the generated video with this codec after generation, e.g. 'h265' for smaller files. The original is kept in GCS and the re-encoded video is saved next to it. Defaults to h264 if output_container or output_bitrate is set."),
+		),
+		mcp.WithString("output_container",
+			mcp.Enum("mp4", "mov", "mkv", "webm"),
+			mcp.Description("Optional. Container of the re-encoded video. 'webm' requires 'vp9'. Defaults to mp4 (webm for vp9)."),
+		),
+		mcp.WithString("output_bitrate",
+			mcp.Description("Optional. Target video bitrate of the re-encoded video, e.g. '5M' or '2500k'. If omitted, the encoder's default quality is used."),
+		),
+		mcp.WithNumber("fps",
+			mcp.Description("Optional. Frame rate of the generated video, e.g. 24. Note: supported frame rates are model-dependent; the parameter is ignored for models that do not accept one."),
+		),
+		mcp.WithBoolean("verify_output",
+			mcp.Description("Optional. If true, the generated video is checked with ffprobe after generation, and the result warns if its duration, aspect ratio, or frame rate does not match the request. Requires ffprobe on the server."),
+		),
+		mcp.WithBoolean("faststart",
+			mcp.Description("Optional. If true, videos downloaded to 'output_directory' are remuxed with their index at the front, so they start playing in a browser before they are fully downloaded. Requires ffmpeg on the server. Re-encoded MP4 and MOV videos always use faststart."),
+		),
+		mcp.WithString("compression_quality",
+			mcp.Enum("optimized", "lossless"),
+			mcp.Description("Optional. Compression of the generated video: 'optimized' for smaller files, or 'lossless' for the highest quality at a much larger size. If omitted, the API's default is used. The result reports the size and duration of each video."),
+		),
+		mcp.WithString(common.BucketOverrideParam,
+			mcp.Description("Optional. Bucket used instead of GENMEDIA_BUCKET for this request when 'bucket' is not given. Must be one of the buckets listed in CONFIG_OVERRIDE_BUCKETS."),
+		),
+		mcp.WithString(common.LocationOverrideParam,
+			mcp.Description("Optional. Google Cloud location in which to generate, instead of the server's location. Must be one of the locations listed in CONFIG_OVERRIDE_LOCATIONS."),
 		),
 	}
 
+	// The long video tool chains several operations, so it cannot hand one back to the client.
+	asyncParam := mcp.WithBoolean("async",
+		mcp.Description("Optional. If true, the tool returns as soon as generation has started, with the name of the long-running operation instead of the videos. Pass that name to poll_veo_operation to check its status and get the videos when it is done. Cannot be combined with 'output_directory', 'faststart', 'verify_output' or re-encoding; give 'output_directory' and 'faststart' to poll_veo_operation instead."),
+	)
+
 	var textToVideoToolParams []mcp.ToolOption
 	textToVideoToolParams = append(textToVideoToolParams,
 		mcp.WithDescription("Generate a video from a text prompt using Veo. Video is saved to GCS and optionally downloaded locally."),
@@ -134,11 +184,12 @@ func main() {
 		),
 	)
 	textToVideoToolParams = append(textToVideoToolParams, commonVideoParams...)
+	textToVideoToolParams = append(textToVideoToolParams, asyncParam)
 
 	textToVideoTool := mcp.NewTool("veo_t2v",
 		textToVideoToolParams...,
 	)
-	s.AddTool(textToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, textToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoTextToVideoHandler(genAIClient, ctx, request)
 	})
 
@@ -157,11 +208,12 @@ func main() {
 		),
 	)
 	imageToVideoToolParams = append(imageToVideoToolParams, commonVideoParams...)
+	imageToVideoToolParams = append(imageToVideoToolParams, asyncParam)
 
 	imageToVideoTool := mcp.NewTool("veo_i2v",
 		imageToVideoToolParams...,
 	)
-	s.AddTool(imageToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, imageToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoImageToVideoHandler(genAIClient, ctx, request)
 	})
 
@@ -187,11 +239,12 @@ func main() {
 		),
 	)
 	firstLastToVideoToolParams = append(firstLastToVideoToolParams, commonVideoParams...)
+	firstLastToVideoToolParams = append(firstLastToVideoToolParams, asyncParam)
 
 	firstLastToVideoTool := mcp.NewTool("veo_first_last_to_video",
 		firstLastToVideoToolParams...,
 	)
-	s.AddTool(firstLastToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, firstLastToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoFirstLastToVideoHandler(genAIClient, ctx, request)
 	})
 
@@ -213,11 +266,12 @@ func main() {
 		),
 	)
 	referenceToVideoToolParams = append(referenceToVideoToolParams, commonVideoParams...)
+	referenceToVideoToolParams = append(referenceToVideoToolParams, asyncParam)
 
 	referenceToVideoTool := mcp.NewTool("veo_reference_to_video",
 		referenceToVideoToolParams...,
 	)
-	s.AddTool(referenceToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, referenceToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoReferenceToVideoHandler(genAIClient, ctx, request)
 	})
 
@@ -225,7 +279,7 @@ func main() {
 	ingredientsToVideoTool := mcp.NewTool("veo_ingredients_to_video",
 		referenceToVideoToolParams...,
 	)
-	s.AddTool(ingredientsToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, ingredientsToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoReferenceToVideoHandler(genAIClient, ctx, request)
 	})
 
@@ -257,25 +311,111 @@ func main() {
 			mcp.Description("Number of videos to generate. Note: the maximum is model-dependent."),
 		),
 		mcp.WithString("aspect_ratio",
-			mcp.Description("Aspect ratio of the generated videos. Note: supported aspect ratios are model-dependent."),
+			mcp.Description("Aspect ratio of the generated videos, e.g. \"16:9\". Forms such as \"16x9\", \"16/9\", \"1.77\", or \"portrait\" are normalized to W:H. Note: supported aspect ratios are model-dependent."),
 		),
 		mcp.WithBoolean("generate_audio",
-			mcp.DefaultBool(true),
-			mcp.Description("Optional. Generate audio for the video. Only supported by Veo 3 models. Defaults to true."),
+			mcp.Description("Optional. Generate audio for the video. Only supported by Veo 3 models. Defaults to true for models that support audio and false otherwise."),
 		),
 		mcp.WithString("person_generation",
 			mcp.DefaultString("allow_adult"),
-			mcp.Description("Whether to allow generating videos with people. Supported values: 'dont_allow', 'allow_adult'."),
+			mcp.Enum(personGenerationOptions...),
+			mcp.Description("Whether to allow generating videos with people. Supported values: 'dont_allow', 'allow_adult'. If videos are filtered by this setting, the result reports how many and why."),
+		),
+		mcp.WithBoolean(common.RawPromptParam,
+			mcp.Description("Optional. If true, the server-configured PROMPT_PREFIX/PROMPT_SUFFIX are not applied to the prompt."),
+		),
+		mcp.WithBoolean(common.EnhancePromptParam,
+			mcp.Description("Optional. If true, the model rewrites the prompt into a more detailed one before generating. The result repeats the prompt that was sent; the Veo API does not return the rewritten prompt."),
+		),
+		mcp.WithNumber("poll_interval_seconds",
+			mcp.Description("Optional. How often to check the status of the generation, in seconds. Defaults to VEO_POLL_INTERVAL, or 15."),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description(fmt.Sprintf("Optional. How long to wait for the generation to finish, in seconds (at most %d). Increase it for long or high-resolution renders. Defaults to VEO_OPERATION_TIMEOUT, or 300.", int(maxVeoOperationTimeout.Seconds()))),
+		),
+		mcp.WithString("output_codec",
+			mcp.Enum("h264", "h265", "vp9"),
+			mcp.Description("Optional. Re-encode the generated video with this codec after generation, e.g. 'h265' for smaller files. The original is kept in GCS and the re-encoded video is saved next to it. Defaults to h264 if output_container or output_bitrate is set."),
+		),
+		mcp.WithString("output_container",
+			mcp.Enum("mp4", "mov", "mkv", "webm"),
+			mcp.Description("Optional. Container of the re-encoded video. 'webm' requires 'vp9'. Defaults to mp4 (webm for vp9)."),
+		),
+		mcp.WithString("output_bitrate",
+			mcp.Description("Optional. Target video bitrate of the re-encoded video, e.g. '5M' or '2500k'. If omitted, the encoder's default quality is used."),
+		),
+		mcp.WithNumber("fps",
+			mcp.Description("Optional. Frame rate of the generated video, e.g. 24. Note: supported frame rates are model-dependent; the parameter is ignored for models that do not accept one."),
+		),
+		mcp.WithBoolean("verify_output",
+			mcp.Description("Optional. If true, the generated video is checked with ffprobe after generation, and the result warns if its duration, aspect ratio, or frame rate does not match the request. Requires ffprobe on the server."),
+		),
+		mcp.WithBoolean("faststart",
+			mcp.Description("Optional. If true, videos downloaded to 'output_directory' are remuxed with their index at the front, so they start playing in a browser before they are fully downloaded. Requires ffmpeg on the server. Re-encoded MP4 and MOV videos always use faststart."),
+		),
+		mcp.WithString("compression_quality",
+			mcp.Enum("optimized", "lossless"),
+			mcp.Description("Optional. Compression of the generated video: 'optimized' for smaller files, or 'lossless' for the highest quality at a much larger size. If omitted, the API's default is used. The result reports the size and duration of each video."),
+		),
+		mcp.WithString(common.BucketOverrideParam,
+			mcp.Description("Optional. Bucket used instead of GENMEDIA_BUCKET for this request when 'bucket' is not given. Must be one of the buckets listed in CONFIG_OVERRIDE_BUCKETS."),
+		),
+		mcp.WithString(common.LocationOverrideParam,
+			mcp.Description("Optional. Google Cloud location in which to generate, instead of the server's location. Must be one of the locations listed in CONFIG_OVERRIDE_LOCATIONS."),
 		),
 	)
 
+	extendVideoToolParams = append(extendVideoToolParams, asyncParam)
+
 	extendVideoTool := mcp.NewTool("veo_extend_video",
 		extendVideoToolParams...,
 	)
-	s.AddTool(extendVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	common.AddTool(s, appConfig, extendVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoExtendVideoHandler(genAIClient, ctx, request)
 	})
 
+	var longVideoToolParams []mcp.ToolOption
+	longVideoToolParams = append(longVideoToolParams,
+		mcp.WithDescription(fmt.Sprintf("Generate a video longer than a single Veo generation allows. An initial clip is generated from the prompt and extended repeatedly by %ds (each extension continuing from the previous segment) until the target duration is reached; 'duration' sets the length of the initial clip and 'num_videos' is ignored. The segments are stitched into one MP4, saved to GCS next to the segments and optionally downloaded locally. Requires a model that supports extension and ffmpeg on the server.", veoExtensionSeconds)),
+		mcp.WithString("prompt",
+			mcp.Required(),
+			mcp.Description("Text prompt for the initial clip. Also used for the extensions unless 'extension_prompt' is given."),
+		),
+		mcp.WithNumber("target_duration",
+			mcp.Required(),
+			mcp.Max(maxLongVideoSeconds),
+			mcp.Description(fmt.Sprintf("Total duration of the stitched video in seconds (at most %d).", maxLongVideoSeconds)),
+		),
+		mcp.WithString("extension_prompt",
+			mcp.Description("Optional. Text prompt used for each extension, e.g. to describe how the scene continues."),
+		),
+	)
+	longVideoToolParams = append(longVideoToolParams, commonVideoParams...)
+
+	longVideoTool := mcp.NewTool("veo_generate_long_video",
+		longVideoToolParams...,
+	)
+	common.AddTool(s, appConfig, longVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoGenerateLongVideoHandler(genAIClient, ctx, request)
+	})
+
+	pollOperationTool := mcp.NewTool("poll_veo_operation",
+		mcp.WithDescription("Check the status of a video generation started with 'async' set to true. Returns the progress while the operation is running, and the generated videos (saved to GCS and optionally downloaded locally) once it is done."),
+		mcp.WithString("operation_name",
+			mcp.Required(),
+			mcp.Description("Name of the long-running operation returned by the Veo tool, e.g. projects/my-project/locations/us-central1/publishers/google/models/veo-3.0-generate-001/operations/1234."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Optional. If provided and the operation is done, specifies a local directory to download the generated video(s) to. Filenames will be generated automatically."),
+		),
+		mcp.WithBoolean("faststart",
+			mcp.Description("Optional. If true, videos downloaded to 'output_directory' are remuxed with their index at the front, so they start playing in a browser before they are fully downloaded. Requires ffmpeg on the server."),
+		),
+	)
+	common.AddTool(s, appConfig, pollOperationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return pollVeoOperationHandler(genAIClient, ctx, request)
+	})
+
 	s.AddPrompt(mcp.NewPrompt("generate-video",
 		mcp.WithPromptDescription("Generates a video from a text prompt."),
 		mcp.WithArgument("prompt", mcp.ArgumentDescription("The text prompt to generate a video from."), mcp.RequiredArgument()),
@@ -321,6 +461,10 @@ func main() {
 		), nil
 	})
 
+	common.AddGenerationDefaultsTool(s, appConfig, common.ModelFamilyVeo)
+	common.AddIntrospectTool(s, appConfig)
+	common.AddServerInfoResource(s, serviceName, version, transport)
+
 	switch transport {
 	case "sse":
 		ssePort := 8081 // Default SSE port
@@ -329,10 +473,10 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			ssePort = p
 		}
-		log.Printf("Starting Veo MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
+		common.LogInfof("Starting Veo MCP Server (Version: %s, Transport: sse, Port: %d)", version, ssePort)
 		sseServer := server.NewSSEServer(s, server.WithBaseURL(fmt.Sprintf("http://localhost:%d", ssePort)))
 		if err := sseServer.Start(fmt.Sprintf(":%d", ssePort)); err != nil {
-			log.Fatalf("SSE Server error: %v", err)
+			common.LogFatalf("SSE Server error: %v", err)
 		}
 	case "http":
 		httpPort := 8080 // Default HTTP port
@@ -341,7 +485,7 @@ func main() {
 		} else if p, err := strconv.Atoi(common.GetEnv("PORT", "")); err == nil {
 			httpPort = p
 		}
-		log.Printf("Starting Veo MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
+		common.LogInfof("Starting Veo MCP Server (Version: %s, Transport: http, Port: %d)", version, httpPort)
 		mcpHTTPHandler := server.NewStreamableHTTPServer(s) // Base path /mcp
 		c := cors.New(cors.Options{
 			AllowedOrigins:   []string{"*"},
@@ -353,17 +497,18 @@ func main() {
 		})
 		handlerWithCORS := c.Handler(mcpHTTPHandler)
 		listenAddr := fmt.Sprintf(":%d", httpPort)
-		if err := http.ListenAndServe(listenAddr, handlerWithCORS); err != nil {
-			log.Fatalf("HTTP Server error: %v", err)
+		httpServer := common.NewHTTPServer(listenAddr, handlerWithCORS)
+		if err := httpServer.ListenAndServe(); err != nil {
+			common.LogFatalf("HTTP Server error: %v", err)
 		}
 	case "stdio":
-		log.Printf("Starting Veo MCP Server (Version: %s, Transport: stdio)", version)
+		common.LogInfof("Starting Veo MCP Server (Version: %s, Transport: stdio)", version)
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("STDIO Server error: %v", err)
+			common.LogFatalf("STDIO Server error: %v", err)
 		}
 	default:
-		log.Fatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
+		common.LogFatalf("Unsupported transport type: %s. Please use 'stdio', 'sse', or 'http'.", transport)
 	}
 
-	log.Println("Veo Server has stopped.")
+	common.LogInfof("Veo Server has stopped.")
 }
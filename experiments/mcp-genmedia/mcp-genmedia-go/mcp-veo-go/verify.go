@@ -0,0 +1,164 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+// verifyDurationTolerance is how far, in seconds, a generated video's duration may be from the
+// requested duration before it is reported. Container durations are rarely exact.
+const verifyDurationTolerance = 0.5
+
+// verifyFPSTolerance is how far a generated video's frame rate may be from the requested one
+// before it is reported, so that e.g. 23.976 matches 24.
+const verifyFPSTolerance = 0.1
+
+// verifyAspectRatioTolerance is the relative difference between a generated video's aspect ratio
+// and the requested one above which it is reported, allowing for rounded resolutions.
+const verifyAspectRatioTolerance = 0.02
+
+// videoExpectation is what the request asked for. Zero values are not checked.
+type videoExpectation struct {
+	DurationSeconds float64
+	AspectRatio     string // W:H, e.g. "16:9".
+	FPS             float64
+}
+
+// videoProbe is what ffprobe reports about a video.
+type videoProbe struct {
+	Width           int
+	Height          int
+	DurationSeconds float64
+	FPS             float64 // 0 if ffprobe did not report a frame rate.
+}
+
+// expectationFromConfig returns what a generation with the given config should produce. The
+// duration of an extension depends on the input video, so it is only checked for new videos.
+func expectationFromConfig(source *genai.GenerateVideosSource, config *genai.GenerateVideosConfig) videoExpectation {
+	expect := videoExpectation{AspectRatio: config.AspectRatio}
+	if config.DurationSeconds != nil && (source == nil || source.Video == nil) {
+		expect.DurationSeconds = float64(*config.DurationSeconds)
+	}
+	if config.FPS != nil {
+		expect.FPS = float64(*config.FPS)
+	}
+	return expect
+}
+
+// parseVideoProbe reads the output of 'ffprobe -show_entries
+// stream=width,height,r_frame_rate:format=duration -of json' for the first video stream.
+func parseVideoProbe(output []byte) (videoProbe, error) {
+	var parsed struct {
+		Streams []struct {
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			FrameRate string `json:"r_frame_rate"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return videoProbe{}, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	if len(parsed.Streams) == 0 || parsed.Streams[0].Width <= 0 || parsed.Streams[0].Height <= 0 {
+		return videoProbe{}, fmt.Errorf("ffprobe found no video stream")
+	}
+	probe := videoProbe{Width: parsed.Streams[0].Width, Height: parsed.Streams[0].Height}
+	if parsed.Format.Duration != "" {
+		duration, err := strconv.ParseFloat(parsed.Format.Duration, 64)
+		if err != nil {
+			return videoProbe{}, fmt.Errorf("ffprobe reported an invalid duration '%s'", parsed.Format.Duration)
+		}
+		probe.DurationSeconds = duration
+	}
+	var num, den float64
+	if _, err := fmt.Sscanf(parsed.Streams[0].FrameRate, "%g/%g", &num, &den); err == nil && num > 0 && den > 0 {
+		probe.FPS = num / den
+	}
+	return probe, nil
+}
+
+// mismatches describes how a probed video differs from the expectation, or returns nil if it
+// matches within tolerance.
+func (e videoExpectation) mismatches(probe videoProbe) []string {
+	var found []string
+	if e.DurationSeconds > 0 && probe.DurationSeconds > 0 && math.Abs(probe.DurationSeconds-e.DurationSeconds) > verifyDurationTolerance {
+		found = append(found, fmt.Sprintf("duration is %.1fs instead of %gs", probe.DurationSeconds, e.DurationSeconds))
+	}
+	if e.FPS > 0 && probe.FPS > 0 && math.Abs(probe.FPS-e.FPS) > verifyFPSTolerance {
+		found = append(found, fmt.Sprintf("frame rate is %.3gfps instead of %gfps", probe.FPS, e.FPS))
+	}
+	if e.AspectRatio != "" {
+		var w, h float64
+		if _, err := fmt.Sscanf(e.AspectRatio, "%g:%g", &w, &h); err == nil && w > 0 && h > 0 {
+			want := w / h
+			got := float64(probe.Width) / float64(probe.Height)
+			if math.Abs(got-want)/want > verifyAspectRatioTolerance {
+				found = append(found, fmt.Sprintf("dimensions are %dx%d, which is not %s", probe.Width, probe.Height, e.AspectRatio))
+			}
+		}
+	}
+	return found
+}
+
+// probeVideoFile runs ffprobe on the video at path. ffprobe is looked up in MCP_CUSTOM_PATH if
+// set, otherwise in PATH.
+func probeVideoFile(ctx context.Context, path string) (videoProbe, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height,r_frame_rate:format=duration", "-of", "json", path)
+	if customPath := os.Getenv("MCP_CUSTOM_PATH"); customPath != "" {
+		cmd.Env = append(os.Environ(), "PATH="+customPath)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return videoProbe{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseVideoProbe(output)
+}
+
+// verifyGeneratedVideo checks the video at localPath, or if it is empty the video at gcsURI, against
+// the expectation, and returns the mismatches found.
+func verifyGeneratedVideo(ctx context.Context, gcsURI, localPath string, expect videoExpectation) ([]string, error) {
+	if localPath == "" {
+		tempDir, err := os.MkdirTemp("", "veo_verify_")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		localPath = filepath.Join(tempDir, "video.mp4")
+		if err := common.DownloadGeneratedFromGCS(ctx, gcsURI, localPath); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", gcsURI, err)
+		}
+	}
+	probe, err := probeVideoFile(ctx, localPath)
+	if err != nil {
+		return nil, err
+	}
+	return expect.mismatches(probe), nil
+}
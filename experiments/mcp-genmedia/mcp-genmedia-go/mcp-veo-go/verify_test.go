@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseVideoProbe(t *testing.T) {
+	output := `{"programs": [], "streams": [{"width": 1280, "height": 720, "r_frame_rate": "24/1"}], "format": {"duration": "8.000000"}}`
+	got, err := parseVideoProbe([]byte(output))
+	if err != nil {
+		t.Fatalf("parseVideoProbe() error = %v", err)
+	}
+	want := videoProbe{Width: 1280, Height: 720, DurationSeconds: 8, FPS: 24}
+	if got != want {
+		t.Errorf("parseVideoProbe() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseVideoProbe([]byte(`{"streams": [], "format": {"duration": "8.0"}}`)); err == nil {
+		t.Error("expected an error for a file without a video stream")
+	}
+}
+
+func TestVideoExpectationMismatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect videoExpectation
+		probe  videoProbe
+		want   []string
+	}{
+		{"match", videoExpectation{8, "16:9", 24}, videoProbe{1280, 720, 8.02, 24}, nil},
+		{"rounded resolution and NTSC rate", videoExpectation{8, "9:16", 24}, videoProbe{720, 1278, 8, 23.976}, nil},
+		{"unchecked", videoExpectation{}, videoProbe{1024, 1024, 5, 30}, nil},
+		{"wrong dimensions", videoExpectation{8, "16:9", 0}, videoProbe{720, 1280, 8, 24}, []string{"dimensions are 720x1280, which is not 16:9"}},
+		{"wrong duration", videoExpectation{8, "16:9", 0}, videoProbe{1920, 1080, 6, 24}, []string{"duration is 6.0s instead of 8s"}},
+		{"wrong frame rate", videoExpectation{8, "16:9", 24}, videoProbe{1920, 1080, 8, 30}, []string{"frame rate is 30fps instead of 24fps"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expect.mismatches(tt.probe); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mismatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectationFromConfig(t *testing.T) {
+	secs := int32(8)
+	config := &genai.GenerateVideosConfig{AspectRatio: "16:9", DurationSeconds: &secs}
+
+	got := expectationFromConfig(&genai.GenerateVideosSource{Prompt: "a cat"}, config)
+	if got.DurationSeconds != 8 || got.AspectRatio != "16:9" {
+		t.Errorf("expectationFromConfig() = %+v, want 8s at 16:9", got)
+	}
+
+	// Extensions are longer than the requested duration, so only the aspect ratio is checked.
+	got = expectationFromConfig(&genai.GenerateVideosSource{Video: &genai.Video{URI: "gs://b/in.mp4"}}, config)
+	if got.DurationSeconds != 0 || got.AspectRatio != "16:9" {
+		t.Errorf("expectationFromConfig() for an extension = %+v, want no duration at 16:9", got)
+	}
+}
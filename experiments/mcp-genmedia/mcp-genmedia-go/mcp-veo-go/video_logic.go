@@ -21,8 +21,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,35 +30,311 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/genai"
 )
 
+// veoPollRetryPolicy governs retries of a single GetVideosOperation poll. If the retries are
+// exhausted, the polling loop notifies the client and tries again on the next polling cycle.
+var veoPollRetryPolicy = common.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    8 * time.Second,
+	Jitter:      0.2,
+	IsRetryable: common.IsRetryableError,
+}
+
+// postProcessOptions controls how the videos of a finished GenerateVideos operation are
+// processed before they are summarized.
+type postProcessOptions struct {
+	OutputDir string                        // Local directory to download the videos to; empty leaves them in GCS.
+	Transcode *common.VideoTranscodeOptions // Re-encoding of each video, or nil to keep it as generated.
+	Verify    bool                          // Check each video against the request with ffprobe.
+	Faststart bool                          // Remux downloaded videos for faststart web playback.
+}
+
+// parsePostProcessOptions reads the 'output_codec', 'output_container', 'output_bitrate',
+// 'verify_output' and 'faststart' parameters. outputDir is the parsed 'output_directory'.
+func parsePostProcessOptions(args map[string]interface{}, outputDir string) (postProcessOptions, error) {
+	transcode, err := common.ParseVideoTranscodeOptions(args)
+	if err != nil {
+		return postProcessOptions{}, err
+	}
+	verify, _ := args["verify_output"].(bool)
+	faststart, _ := args["faststart"].(bool)
+	return postProcessOptions{OutputDir: outputDir, Transcode: transcode, Verify: verify, Faststart: faststart}, nil
+}
+
 // callGenerateVideosAPI orchestrates the entire video generation process.
 // It initiates the video generation operation, polls for its completion, and handles
 // progress notifications. Once the video is generated, it can download the file
-// to a local directory if requested. It returns a summary of the operation's outcome.
+// to a local directory if requested, remuxing it for faststart web playback if asked to,
+// and verify it against the request with ffprobe.
+// It returns a summary of the operation's outcome.
 func callGenerateVideosAPI(
 	client *genai.Client,
 	parentCtx context.Context, // Renamed from ctx to avoid conflict with operationCtx
 	mcpServer *server.MCPServer,
 	progressToken mcp.ProgressToken,
-	outputDir string,
 	modelName string,
 	source *genai.GenerateVideosSource,
 	config *genai.GenerateVideosConfig,
 	callType string,
+	polling veoPolling,
+	post postProcessOptions,
 ) (*mcp.CallToolResult, error) {
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(parentCtx, "callGenerateVideosAPI")
 	defer span.End()
 
-	attemptLocalDownload := outputDir != ""
+	if post.OutputDir != "" {
+		common.LogInfof("GenerateVideos (%s): will attempt to download to local directory: '%s'", callType, post.OutputDir)
+	}
+
+	operation, location, operationDuration, errResult := waitForGeneratedVideos(client, ctx, mcpServer, progressToken, modelName, source, config, callType, polling)
+	if errResult != nil {
+		return errResult, nil
+	}
+	span.SetAttributes(attribute.String("location", location))
+	return summarizeGeneratedVideos(ctx, operation, location, operationDuration, modelName, source, config, callType, post), nil
+}
+
+// summarizeGeneratedVideos processes the videos of a completed GenerateVideos operation: it
+// downloads them to post.OutputDir if one is given, applies faststart, re-encodes and verifies
+// them if requested, and returns a summary of the outcome. operationDuration is 0 if it is unknown.
+func summarizeGeneratedVideos(
+	ctx context.Context,
+	operation *genai.GenerateVideosOperation,
+	location string,
+	operationDuration time.Duration,
+	modelName string,
+	source *genai.GenerateVideosSource,
+	config *genai.GenerateVideosConfig,
+	callType string,
+	post postProcessOptions,
+) *mcp.CallToolResult {
+	ctx, span := otel.Tracer(serviceName).Start(ctx, "summarizeGeneratedVideos")
+	defer span.End()
+
+	attemptLocalDownload := post.OutputDir != ""
+	filteredMessage := describeFilteredVideos(operation.Response, config.PersonGeneration)
+	if filteredMessage != "" {
+		common.LogInfof("Operation %s (%s): %s", operation.Name, callType, filteredMessage)
+		span.SetAttributes(attribute.Int("rai_media_filtered_count", int(operation.Response.RAIMediaFilteredCount)))
+	}
+
+	if operation.Response == nil || len(operation.Response.GeneratedVideos) == 0 {
+		common.LogWarnf("No videos generated (%s) by operation %s, despite successful completion.", callType, operation.Name)
+		return mcp.NewToolResultText(strings.TrimSpace(fmt.Sprintf("Sorry, I couldn't generate any videos (%s) for your request (operation completed but no videos found). %s", callType, filteredMessage)))
+	}
+
+	common.LogInfof("Successfully generated %d videos (%s) by operation %s.", len(operation.Response.GeneratedVideos), callType, operation.Name)
+
+	var gcsVideoURIs []string
+	var downloadedLocalFiles []string
+	var downloadErrors []string
+	var transcodedVideos []transcodedVideo
+	var verifyWarnings, verifyErrors []string
+	var outputStats []string
+	expectation := expectationFromConfig(source, config)
+
+	for i, generatedVideo := range operation.Response.GeneratedVideos {
+		videoGCSURI := ""
+		if generatedVideo.Video != nil && generatedVideo.Video.URI != "" {
+			videoGCSURI = generatedVideo.Video.URI
+		}
+
+		if videoGCSURI == "" {
+			common.LogWarnf("Generated video %d (%s) (model: %s, operation: %s) had no retrievable GCS URI.", i, callType, modelName, operation.Name)
+			continue
+		}
+		gcsVideoURIs = append(gcsVideoURIs, videoGCSURI)
+		common.LogInfof("Video %d (%s) generated by operation %s is available at GCS URI: %s", i, callType, operation.Name, videoGCSURI)
+		if err := common.SetGCSObjectMetadata(ctx, videoGCSURI, "video/mp4"); err != nil {
+			common.LogWarnf("Warning: could not set the metadata of video %d at %s: %v", i, videoGCSURI, err)
+		}
+
+		verifyPath := ""
+		// Construct a descriptive filename similar to Imagen
+		localFilename := fmt.Sprintf("veo-%s-%s-%d.mp4", modelName, time.Now().Format("20060102-150405"), i)
+
+		if post.Transcode != nil {
+			// The re-encoded video replaces the original in the output directory.
+			common.LogInfof("Transcoding video %d from %s to %s", i, videoGCSURI, post.Transcode)
+			transcoded, err := transcodeGeneratedVideo(ctx, videoGCSURI, post.OutputDir, localFilename, *post.Transcode)
+			if err != nil {
+				errMsg := fmt.Sprintf("Error transcoding video %d from %s to %s: %v", i, videoGCSURI, post.Transcode, err)
+				common.LogErrorf("%s", errMsg)
+				downloadErrors = append(downloadErrors, errMsg)
+			}
+			if transcoded.GCSURI != "" {
+				transcodedVideos = append(transcodedVideos, transcoded)
+			}
+			if transcoded.LocalPath != "" {
+				downloadedLocalFiles = append(downloadedLocalFiles, transcoded.LocalPath)
+			}
+		} else if attemptLocalDownload {
+			localFilepath := filepath.Join(post.OutputDir, localFilename)
+			localFilepath = filepath.Clean(localFilepath)
+
+			common.LogInfof("Attempting to download video %d from GCS URI %s to %s", i, videoGCSURI, localFilepath)
+			downloadErr := common.DownloadGeneratedFromGCS(ctx, videoGCSURI, localFilepath)
+			if downloadErr != nil {
+				errMsg := fmt.Sprintf("Error downloading video %d from %s to %s: %v", i, videoGCSURI, localFilepath, downloadErr)
+				common.LogErrorf("%s", errMsg)
+				downloadErrors = append(downloadErrors, errMsg)
+			} else {
+				common.LogInfof("Successfully downloaded and saved video %d to %s", i, localFilepath)
+				downloadedLocalFiles = append(downloadedLocalFiles, localFilepath)
+				verifyPath = localFilepath
+				if post.Faststart {
+					// The download is kept without faststart if ffmpeg is not available.
+					if err := common.FaststartVideoFile(ctx, localFilepath); err != nil {
+						errMsg := fmt.Sprintf("Could not apply faststart to %s: %v", localFilepath, err)
+						common.LogErrorf("%s", errMsg)
+						downloadErrors = append(downloadErrors, errMsg)
+					}
+				}
+			}
+		}
+
+		// The size and duration are read from the downloaded file if there is one, so that they
+		// include faststart, and otherwise from the headers of the GCS object.
+		statURI := videoGCSURI
+		if verifyPath != "" {
+			statURI = verifyPath
+		}
+		stat, err := common.StatVideo(ctx, statURI)
+		if err != nil {
+			common.LogWarnf("Warning: could not read the size and duration of video %d at %s: %v", i, statURI, err)
+		}
+		if stat.SizeBytes > 0 {
+			outputStats = append(outputStats, fmt.Sprintf("%s: %s", statURI, stat))
+		}
+
+		if post.Verify {
+			// The video as generated is checked, not the re-encoded one. Mismatches are reported
+			// as warnings; the video is still returned.
+			mismatches, err := verifyGeneratedVideo(ctx, videoGCSURI, verifyPath, expectation)
+			if err != nil {
+				common.LogWarnf("Warning: could not verify video %d at %s: %v", i, videoGCSURI, err)
+				verifyErrors = append(verifyErrors, fmt.Sprintf("%s: %v", videoGCSURI, err))
+			} else if len(mismatches) > 0 {
+				common.LogWarnf("Warning: video %d at %s does not match the request: %s", i, videoGCSURI, strings.Join(mismatches, ", "))
+				verifyWarnings = append(verifyWarnings, fmt.Sprintf("%s: %s", videoGCSURI, strings.Join(mismatches, " and ")))
+			}
+		}
+	}
+
+	outputURIs := slices.Concat(gcsVideoURIs, downloadedLocalFiles)
+	for _, transcoded := range transcodedVideos {
+		outputURIs = append(outputURIs, transcoded.GCSURI)
+	}
+	auditVideoGeneration(ctx, callType, modelName, source, config, outputURIs, "")
+
+	var resultText string
+	var saveMessageParts []string
+
+	if len(gcsVideoURIs) > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Videos saved to GCS: %s.", strings.Join(gcsVideoURIs, ", ")))
+	}
+	if len(transcodedVideos) > 0 {
+		var descriptions []string
+		for _, transcoded := range transcodedVideos {
+			descriptions = append(descriptions, transcoded.describe())
+		}
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Transcoded to %s: %s.", post.Transcode, strings.Join(descriptions, "; ")))
+	}
+	if len(outputStats) > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Output size: %s.", strings.Join(outputStats, "; ")))
+	}
+	if post.Transcode != nil && len(downloadErrors) > 0 && !attemptLocalDownload {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Transcoding issues: %s.", strings.Join(downloadErrors, "; ")))
+	}
 
+	if attemptLocalDownload {
+		if len(downloadedLocalFiles) > 0 { // Only mention post.OutputDir if downloads were attempted and successful
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Successfully downloaded locally to '%s': %s.", post.OutputDir, strings.Join(downloadedLocalFiles, ", ")))
+		} else if post.OutputDir != "" { // If post.OutputDir was specified but no files downloaded (all errors or no videos)
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to download videos to local directory '%s'.", post.OutputDir))
+		}
+		if len(downloadErrors) > 0 {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Local download/save issues: %s.", strings.Join(downloadErrors, "; ")))
+		}
+	}
+
+	if len(gcsVideoURIs) > 0 {
+		resultText = fmt.Sprintf("Generated %d video(s) using model %s in region %s. ",
+			len(gcsVideoURIs),
+			modelName,
+			location,
+		)
+		if operationDuration > 0 {
+			resultText += fmt.Sprintf("This took about %s. ", operationDuration.Round(time.Second))
+		}
+		resultText += strings.Join(saveMessageParts, " ")
+	} else if operation.Error == nil {
+		resultText = fmt.Sprintf("Processed request (%s) for model %s (took %s), but no video URIs were found in the completed operation %s. No specific error reported by the operation.",
+			callType,
+			modelName,
+			operationDuration.Round(time.Second),
+			operation.Name,
+		)
+		if len(downloadErrors) > 0 { // If there were download errors even with no GCS URIs (shouldn't happen but good to cover)
+			resultText += " " + strings.Join(saveMessageParts, " ")
+		}
+	} else {
+		// This case should ideally be caught by the operation.Error check earlier.
+		// If we reach here, it implies operation.Error was non-nil but didn't lead to an early return.
+		resultText = fmt.Sprintf("Video generation request (%s) for model %s (took %s) did not yield videos and encountered an issue with operation %s.",
+			callType,
+			modelName,
+			operationDuration.Round(time.Second),
+			operation.Name,
+		)
+		if len(downloadErrors) > 0 {
+			resultText += " " + strings.Join(saveMessageParts, " ")
+		}
+	}
+
+	if source != nil {
+		if enhancement := common.DescribePromptEnhancement(source.Prompt, nil, config.EnhancePrompt); enhancement != "" {
+			resultText += " " + enhancement
+		}
+	}
+	if filteredMessage != "" {
+		resultText += " " + filteredMessage
+	}
+	if len(verifyWarnings) > 0 {
+		resultText += fmt.Sprintf(" Warning: the output does not match the request: %s.", strings.Join(verifyWarnings, "; "))
+	}
+	if len(verifyErrors) > 0 {
+		resultText += fmt.Sprintf(" Could not verify the output: %s.", strings.Join(verifyErrors, "; "))
+	}
+
+	return mcp.NewToolResultText(strings.TrimSpace(resultText))
+}
+
+// waitForGeneratedVideos starts a GenerateVideos operation (failing over to VEO_FALLBACK_LOCATIONS on
+// capacity errors), polls it to completion while sending progress notifications, and returns the
+// completed operation, the location that served it, and how long it took. If the operation could
+// not be started, was canceled, timed out, or failed, a tool error result is returned instead.
+func waitForGeneratedVideos(
+	client *genai.Client,
+	ctx context.Context,
+	mcpServer *server.MCPServer,
+	progressToken mcp.ProgressToken,
+	modelName string,
+	source *genai.GenerateVideosSource,
+	config *genai.GenerateVideosConfig,
+	callType string,
+	polling veoPolling,
+) (*genai.GenerateVideosOperation, string, time.Duration, *mcp.CallToolResult) {
 	// Context for the entire GenerateVideos operation, including polling.
 	// We derive the operation context from the parent context to ensure that if the
 	// client disconnects or the parent request is canceled, we propagate the
 	// cancellation to the long-running GenAI operation.
-	operationCtx, operationCancel := context.WithTimeout(ctx, 5*time.Minute) // Timeout for the entire GenAI operation + polling
+	operationCtx, operationCancel := context.WithTimeout(ctx, polling.Timeout) // Timeout for the entire GenAI operation + polling
 	defer operationCancel()
 
 	logMsg := fmt.Sprintf("Initiating GenerateVideos (%s) with Model: %s", callType, modelName)
@@ -76,25 +352,26 @@ func callGenerateVideosAPI(
 	if config.DurationSeconds != nil {
 		logMsg += fmt.Sprintf(", Duration: %ds", *config.DurationSeconds)
 	}
-	logMsg += fmt.Sprintf(", OutputGCS: %s. Operation timeout: %v", config.OutputGCSURI, 5*time.Minute)
-	if attemptLocalDownload {
-		logMsg += fmt.Sprintf(". Will attempt to download to local directory: '%s'", outputDir)
-	}
-	log.Print(logMsg)
+	logMsg += fmt.Sprintf(", OutputGCS: %s. Operation timeout: %v, polling interval: %v", config.OutputGCSURI, polling.Timeout, polling.Interval)
+	common.LogInfof("%s", logMsg)
 
 	startTime := time.Now()
 
-	// Use operationCtx for the initial call to GenerateVideos
-	operation, err := client.Models.GenerateVideosFromSource(operationCtx, modelName, source, config)
+	// Use operationCtx for the initial call to GenerateVideos. On capacity errors the call fails over
+	// to VEO_FALLBACK_LOCATIONS, and the client that accepted the operation is used for polling.
+	operation, client, location, err := generateVideosWithFailover(operationCtx, client, modelName, source, config, callType)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) && operationCtx.Err() == context.DeadlineExceeded {
-			log.Printf("GenerateVideos (%s) failed: initial call timed out: %v", callType, err)
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) initiation timed out", callType)), nil
+			common.LogErrorf("GenerateVideos (%s) failed: initial call timed out: %v", callType, err)
+			errMsg := fmt.Sprintf("video generation (%s) initiation timed out", callType)
+			auditVideoGeneration(ctx, callType, modelName, source, config, nil, errMsg)
+			return nil, "", 0, mcp.NewToolResultError(errMsg)
 		}
-		log.Printf("Error initiating GenerateVideos (%s): %v", callType, err)
-		return mcp.NewToolResultError(fmt.Sprintf("error starting video generation (%s): %v", callType, err)), nil
+		common.LogErrorf("Error initiating GenerateVideos (%s): %v", callType, err)
+		auditVideoGeneration(ctx, callType, modelName, source, config, nil, err.Error())
+		return nil, "", 0, mcp.NewToolResultError(fmt.Sprintf("error starting video generation (%s): %v", callType, err))
 	}
-	log.Printf("GenerateVideos operation (%s) initiated successfully. Operation Name: %s", callType, operation.Name)
+	common.LogInfof("GenerateVideos operation (%s) initiated successfully in location %s. Operation Name: %s", callType, location, operation.Name)
 
 	if progressToken != nil && mcpServer != nil {
 		if err := mcpServer.SendNotificationToClient(
@@ -106,26 +383,28 @@ func callGenerateVideosAPI(
 				"status":        "initiated", // Add a status field
 			},
 		); err != nil {
-			log.Printf("Warning: Failed to send 'initiated' progress notification: %v", err)
+			common.LogWarnf("Warning: Failed to send 'initiated' progress notification: %v", err)
 		}
 	}
 
 	pollingStartTime := time.Now()
-	pollingInterval := 15 * time.Second
+	pollingInterval := polling.Interval
 	pollingAttempt := 0
 
 	for !operation.Done {
 		select {
 		case <-ctx.Done(): // Check if the original MCP request was canceled
-			log.Printf("Parent context for GenerateVideos (%s) polling canceled: %v. Stopping polling and GenAI operation.", callType, ctx.Err())
+			common.LogWarnf("Parent context for GenerateVideos (%s) polling canceled: %v. Stopping polling and GenAI operation.", callType, ctx.Err())
 			operationCancel() // Attempt to cancel the GenAI operation
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) was canceled by the client: %v", callType, ctx.Err())), nil
+			return nil, "", 0, mcp.NewToolResultError(fmt.Sprintf("video generation (%s) was canceled by the client: %v", callType, ctx.Err()))
 		case <-operationCtx.Done(): // Check if the GenAI operation itself timed out or was canceled
-			log.Printf("Polling loop for GenerateVideos (%s) canceled/timed out by operationCtx: %v", callType, operationCtx.Err())
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) timed out while waiting for completion", callType)), nil
+			common.LogWarnf("Polling loop for GenerateVideos (%s) canceled/timed out by operationCtx: %v", callType, operationCtx.Err())
+			errMsg := fmt.Sprintf("video generation (%s) timed out after %v while waiting for completion of operation %s. Increase 'timeout_seconds' (or VEO_OPERATION_TIMEOUT) for long renders.", callType, polling.Timeout, operation.Name)
+			auditVideoGeneration(ctx, callType, modelName, source, config, nil, errMsg)
+			return nil, "", 0, mcp.NewToolResultError(errMsg)
 		case <-time.After(pollingInterval): // Time to poll
 			pollingAttempt++
-			log.Printf("Polling GenerateVideos operation (%s): %s (Attempt: %d, Elapsed: %v)", callType, operation.Name, pollingAttempt, time.Since(pollingStartTime).Round(time.Second))
+			common.LogDebugf("Polling GenerateVideos operation (%s): %s (Attempt: %d, Elapsed: %v, Remaining: %v)", callType, operation.Name, pollingAttempt, time.Since(pollingStartTime).Round(time.Second), (polling.Timeout - time.Since(startTime)).Round(time.Second))
 
 			// Send a proactive heartbeat notification BEFORE making the potentially slow network call.
 			// This resets the client's inactivity timer.
@@ -139,18 +418,26 @@ func callGenerateVideosAPI(
 						"status":        "polling",
 					},
 				); err != nil {
-					log.Printf("Warning: Failed to send 'polling' progress notification: %v", err)
+					common.LogWarnf("Warning: Failed to send 'polling' progress notification: %v", err)
 				}
 			}
 
 			var getOpOpts genai.GetOperationConfig
-			// Use operationCtx for the GetVideosOperation call, as it's part of the GenAI operation lifecycle
-			updatedOp, getErr := client.Operations.GetVideosOperation(operationCtx, operation, &getOpOpts)
+			var updatedOp *genai.GenerateVideosOperation
+			// Use operationCtx for the GetVideosOperation call, as it's part of the GenAI operation lifecycle.
+			// Transient errors are retried with backoff before falling back to the next polling cycle.
+			getErr := common.Retry(operationCtx, veoPollRetryPolicy, func(pollCtx context.Context) error {
+				var err error
+				updatedOp, err = client.Operations.GetVideosOperation(pollCtx, operation, &getOpOpts)
+				return err
+			})
 			if getErr != nil {
-				log.Printf("Error polling GenerateVideos operation (%s) %s: %v", callType, operation.Name, getErr)
+				common.LogErrorf("Error polling GenerateVideos operation (%s) %s: %v", callType, operation.Name, getErr)
 				// If operationCtx is done, it means the GenAI operation itself was canceled or timed out.
 				if errors.Is(getErr, context.Canceled) || errors.Is(getErr, context.DeadlineExceeded) {
-					return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) polling was canceled or timed out during GetOperation", callType)), nil
+					errMsg := fmt.Sprintf("video generation (%s) polling was canceled or timed out during GetOperation", callType)
+					auditVideoGeneration(ctx, callType, modelName, source, config, nil, errMsg)
+					return nil, "", 0, mcp.NewToolResultError(errMsg)
 				}
 				// For other errors, notify and continue (could be transient)
 				if progressToken != nil && mcpServer != nil {
@@ -163,7 +450,7 @@ func callGenerateVideosAPI(
 							"status":        "polling_issue",
 						},
 					); err != nil {
-						log.Printf("Warning: Failed to send 'polling_issue' progress notification: %v", err)
+						common.LogWarnf("Warning: Failed to send 'polling_issue' progress notification: %v", err)
 					}
 				}
 				continue // Continue polling
@@ -197,14 +484,14 @@ func callGenerateVideosAPI(
 					payload["total"] = 100
 				}
 				if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", payload); err != nil {
-					log.Printf("Warning: Failed to send 'processing' progress notification: %v", err)
+					common.LogWarnf("Warning: Failed to send 'processing' progress notification: %v", err)
 				}
 			}
 		}
 	}
 
 	operationDuration := time.Since(startTime)
-	log.Printf("GenerateVideos operation (%s) %s completed. Total duration: %v", callType, operation.Name, operationDuration.Round(time.Second))
+	common.LogInfof("GenerateVideos operation (%s) %s completed. Total duration: %v", callType, operation.Name, operationDuration.Round(time.Second))
 
 	if progressToken != nil && mcpServer != nil {
 		finalStatus := "completed_successfully"
@@ -224,10 +511,19 @@ func callGenerateVideosAPI(
 				"total":         100,
 			},
 		); err != nil {
-			log.Printf("Warning: Failed to send final progress notification: %v", err)
+			common.LogWarnf("Warning: Failed to send final progress notification: %v", err)
 		}
 	}
 
+	if errResult := operationErrorResult(ctx, operation, modelName, source, config, callType); errResult != nil {
+		return nil, "", 0, errResult
+	}
+	return operation, location, operationDuration, nil
+}
+
+// operationErrorResult returns a tool error result describing the error of a completed
+// GenerateVideos operation, after auditing it, or nil if the operation succeeded.
+func operationErrorResult(ctx context.Context, operation *genai.GenerateVideosOperation, modelName string, source *genai.GenerateVideosSource, config *genai.GenerateVideosConfig, callType string) *mcp.CallToolResult {
 	if operation.Error != nil {
 		var errMessage string
 		var errCode int32
@@ -253,101 +549,72 @@ func callGenerateVideosAPI(
 				errMessage = string(errorBytes)
 			}
 		}
-		log.Printf("GenerateVideos operation (%s) %s failed with error: %s (Code: %d, FullError: %v)", callType, operation.Name, errMessage, errCode, operation.Error)
-		return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) failed: %s (code: %d)", callType, errMessage, errCode)), nil
+		common.LogErrorf("GenerateVideos operation (%s) %s failed with error: %s (Code: %d, FullError: %v)", callType, operation.Name, errMessage, errCode, operation.Error)
+		auditVideoGeneration(ctx, callType, modelName, source, config, nil, errMessage)
+		return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) failed: %s (code: %d)", callType, errMessage, errCode))
 	}
+	return nil
+}
 
-	if operation.Response == nil || len(operation.Response.GeneratedVideos) == 0 {
-		log.Printf("No videos generated (%s) by operation %s, despite successful completion.", callType, operation.Name)
-		return mcp.NewToolResultText(fmt.Sprintf("Sorry, I couldn't generate any videos (%s) for your request (operation completed but no videos found).", callType)), nil
+// describeFilteredVideos reports how many videos the service withheld under its safety settings,
+// including the person_generation setting in effect if it is known, or returns "" if none were
+// filtered.
+func describeFilteredVideos(response *genai.GenerateVideosResponse, personGeneration string) string {
+	if response == nil || response.RAIMediaFilteredCount == 0 {
+		return ""
 	}
-
-	log.Printf("Successfully generated %d videos (%s) by operation %s.", len(operation.Response.GeneratedVideos), callType, operation.Name)
-
-	var gcsVideoURIs []string
-	var downloadedLocalFiles []string
-	var downloadErrors []string
-
-	for i, generatedVideo := range operation.Response.GeneratedVideos {
-		videoGCSURI := ""
-		if generatedVideo.Video != nil && generatedVideo.Video.URI != "" {
-			videoGCSURI = generatedVideo.Video.URI
-		}
-
-		if videoGCSURI == "" {
-			log.Printf("Generated video %d (%s) (model: %s, operation: %s) had no retrievable GCS URI.", i, callType, modelName, operation.Name)
-			continue
-		}
-		gcsVideoURIs = append(gcsVideoURIs, videoGCSURI)
-		log.Printf("Video %d (%s) generated by operation %s is available at GCS URI: %s", i, callType, operation.Name, videoGCSURI)
-
-		if attemptLocalDownload {
-			// Construct a descriptive filename similar to Imagen
-			localFilename := fmt.Sprintf("veo-%s-%s-%d.mp4", modelName, time.Now().Format("20060102-150405"), i)
-			localFilepath := filepath.Join(outputDir, localFilename)
-			localFilepath = filepath.Clean(localFilepath)
-
-			log.Printf("Attempting to download video %d from GCS URI %s to %s", i, videoGCSURI, localFilepath)
-			downloadErr := common.DownloadFromGCS(ctx, videoGCSURI, localFilepath)
-			if downloadErr != nil {
-				errMsg := fmt.Sprintf("Error downloading video %d from %s to %s: %v", i, videoGCSURI, localFilepath, downloadErr)
-				log.Print(errMsg)
-				downloadErrors = append(downloadErrors, errMsg)
-			} else {
-				log.Printf("Successfully downloaded and saved video %d to %s", i, localFilepath)
-				downloadedLocalFiles = append(downloadedLocalFiles, localFilepath)
-			}
-		}
+	msg := fmt.Sprintf("Generation was restricted: %d video(s) were filtered by safety settings", response.RAIMediaFilteredCount)
+	if personGeneration != "" {
+		msg += fmt.Sprintf(" (person_generation: %s)", personGeneration)
 	}
-
-	var resultText string
-	var saveMessageParts []string
-
-	if len(gcsVideoURIs) > 0 {
-		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Videos saved to GCS: %s.", strings.Join(gcsVideoURIs, ", ")))
+	msg += "."
+	if len(response.RAIMediaFilteredReasons) > 0 {
+		msg += fmt.Sprintf(" Reasons: %s.", strings.Join(response.RAIMediaFilteredReasons, "; "))
 	}
+	return msg
+}
 
-	if attemptLocalDownload {
-		if len(downloadedLocalFiles) > 0 { // Only mention outputDir if downloads were attempted and successful
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Successfully downloaded locally to '%s': %s.", outputDir, strings.Join(downloadedLocalFiles, ", ")))
-		} else if outputDir != "" { // If outputDir was specified but no files downloaded (all errors or no videos)
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to download videos to local directory '%s'.", outputDir))
+// auditVideoGeneration writes an audit record for a Veo generation request.
+// The tool name is derived from the call type (e.g. "t2v" -> "veo_t2v").
+func auditVideoGeneration(ctx context.Context, callType, modelName string, source *genai.GenerateVideosSource, config *genai.GenerateVideosConfig, outputURIs []string, errMsg string) {
+	params := map[string]interface{}{}
+	var prompt string
+	if source != nil {
+		prompt = source.Prompt
+		params["prompt"] = source.Prompt
+		if source.Image != nil && source.Image.GCSURI != "" {
+			params["image_uri"] = source.Image.GCSURI
 		}
-		if len(downloadErrors) > 0 {
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Local download/save issues: %s.", strings.Join(downloadErrors, "; ")))
+		if source.Video != nil && source.Video.URI != "" {
+			params["video_uri"] = source.Video.URI
 		}
 	}
-
-	if len(gcsVideoURIs) > 0 {
-		resultText = fmt.Sprintf("Generated %d video(s) using model %s. This took about %s. %s",
-			len(gcsVideoURIs),
-			modelName,
-			operationDuration.Round(time.Second),
-			strings.Join(saveMessageParts, " "),
-		)
-	} else if operation.Error == nil {
-		resultText = fmt.Sprintf("Processed request (%s) for model %s (took %s), but no video URIs were found in the completed operation %s. No specific error reported by the operation.",
-			callType,
-			modelName,
-			operationDuration.Round(time.Second),
-			operation.Name,
-		)
-		if len(downloadErrors) > 0 { // If there were download errors even with no GCS URIs (shouldn't happen but good to cover)
-			resultText += " " + strings.Join(saveMessageParts, " ")
+	if config != nil {
+		params["output_gcs_uri"] = config.OutputGCSURI
+		params["aspect_ratio"] = config.AspectRatio
+		params["number_of_videos"] = config.NumberOfVideos
+		params["person_generation"] = config.PersonGeneration
+		if config.DurationSeconds != nil {
+			params["duration_seconds"] = *config.DurationSeconds
 		}
-	} else {
-		// This case should ideally be caught by the operation.Error check earlier.
-		// If we reach here, it implies operation.Error was non-nil but didn't lead to an early return.
-		resultText = fmt.Sprintf("Video generation request (%s) for model %s (took %s) did not yield videos and encountered an issue with operation %s.",
-			callType,
-			modelName,
-			operationDuration.Round(time.Second),
-			operation.Name,
-		)
-		if len(downloadErrors) > 0 {
-			resultText += " " + strings.Join(saveMessageParts, " ")
+		if config.GenerateAudio != nil {
+			params["generate_audio"] = *config.GenerateAudio
+		}
+		if config.LastFrame != nil && config.LastFrame.GCSURI != "" {
+			params["last_frame_uri"] = config.LastFrame.GCSURI
+		}
+		if len(config.ReferenceImages) > 0 {
+			params["reference_images"] = len(config.ReferenceImages)
 		}
 	}
 
-	return mcp.NewToolResultText(strings.TrimSpace(resultText)), nil
+	common.WriteAuditRecord(ctx, appConfig, common.AuditRecord{
+		Service:    serviceName,
+		Tool:       "veo_" + callType,
+		Prompt:     prompt,
+		Model:      modelName,
+		Parameters: params,
+		OutputURIs: outputURIs,
+		Error:      errMsg,
+	})
 }
@@ -14,6 +14,8 @@
  * limitations under the License.
  */
 
+import { responseError } from './veo';
+
 export interface AnalyzeResponse {
   context: string;
 }
@@ -28,8 +30,7 @@ export async function analyzeVideo(videoUri: string): Promise<AnalyzeResponse> {
   });
 
   if (!response.ok) {
-    const errorText = await response.text();
-    throw new Error(`Analysis failed: ${response.status} ${errorText}`);
+    throw await responseError('Analysis failed', response);
   }
 
   return response.json();
@@ -14,9 +14,40 @@
  * limitations under the License.
  */
 
+export interface VeoVideo {
+  videoUri: string;
+  sourceUri: string;
+}
+
 export interface VeoResponse {
   videoUri: string;
   sourceUri: string;
+  videos: VeoVideo[];
+}
+
+/** Structured error returned when a Veo operation or Gemini analysis fails, e.g. with code 'safety_blocked'. */
+export interface VeoOperationError {
+  error: string;
+  rpcCode?: number;
+  message: string;
+  supportCodes?: string[];
+  filteredReasons?: string[];
+  finishReason?: string;
+}
+
+/** Builds an Error from a failed response, using the structured message when there is one. */
+export async function responseError(prefix: string, response: Response): Promise<Error> {
+  const errorText = await response.text();
+  try {
+    const opError = JSON.parse(errorText) as VeoOperationError;
+    if (opError.error && opError.message) {
+      const codes = opError.supportCodes?.length ? ` (support codes: ${opError.supportCodes.join(', ')})` : '';
+      return new Error(`${prefix}: ${opError.error}: ${opError.message}${codes}`);
+    }
+  } catch {
+    // Not JSON; fall through to the raw text.
+  }
+  return new Error(`${prefix}: ${response.status} ${errorText}`);
 }
 
 export interface GenerateOptions {
@@ -29,6 +60,7 @@ export interface GenerateOptions {
   lastFrameMimeType?: string;
   refImageUris?: string[];
   refImageTypes?: string[];
+  numVideos?: number;
 }
 
 export async function generateVideo(options: GenerateOptions): Promise<VeoResponse> {
@@ -41,8 +73,7 @@ export async function generateVideo(options: GenerateOptions): Promise<VeoRespon
   });
 
   if (!response.ok) {
-    const errorText = await response.text();
-    throw new Error(`Generation failed: ${response.status} ${errorText}`);
+    throw await responseError('Generation failed', response);
   }
 
   return response.json();
@@ -62,9 +93,81 @@ export async function extendVideo(videoUri: string, prompt: string, model?: stri
   });
 
   if (!response.ok) {
-    const errorText = await response.text();
-    throw new Error(`Extension failed: ${response.status} ${errorText}`);
+    throw await responseError('Extension failed', response);
+  }
+
+  return response.json();
+}
+/** Returned by the generate and extend endpoints for requests made with `async: true`. */
+export interface AsyncOperation {
+  operationId: string;
+  eventsUrl: string;
+}
+
+/** Data of the `progress` events of an operation's event stream. */
+export interface OperationProgress {
+  operationId: string;
+  status: string;
+  elapsedSeconds: number;
+  progressPercent?: number;
+}
+
+/** Starts a generation without waiting for it; follow it with watchOperation. */
+export async function startGenerateVideo(options: GenerateOptions): Promise<AsyncOperation> {
+  const response = await fetch('/api/veo/generate', {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ ...options, async: true }),
+  });
+
+  if (!response.ok) {
+    throw await responseError('Generation failed', response);
   }
 
   return response.json();
-}
\ No newline at end of file
+}
+
+/**
+ * Follows an operation started with `async: true` through its server-sent event stream, and
+ * resolves with the generated videos, or rejects with the operation's error.
+ */
+export function watchOperation(operation: AsyncOperation, onProgress?: (progress: OperationProgress) => void): Promise<VeoResponse> {
+  return new Promise((resolve, reject) => {
+    const source = new EventSource(operation.eventsUrl);
+    source.addEventListener('progress', (event) => {
+      onProgress?.(JSON.parse((event as MessageEvent).data) as OperationProgress);
+    });
+    source.addEventListener('result', (event) => {
+      source.close();
+      resolve(JSON.parse((event as MessageEvent).data) as VeoResponse);
+    });
+    source.addEventListener('canceled', () => {
+      source.close();
+      reject(new Error('Generation canceled'));
+    });
+    source.addEventListener('failed', (event) => {
+      source.close();
+      const opError = JSON.parse((event as MessageEvent).data) as VeoOperationError;
+      reject(new Error(`Generation failed: ${opError.error}: ${opError.message}`));
+    });
+    source.onerror = () => {
+      // EventSource reconnects on its own while the server still knows the operation.
+      if (source.readyState === EventSource.CLOSED) {
+        reject(new Error('Lost the connection to the operation event stream'));
+      }
+    };
+  });
+}
+
+/** Cancels an operation started with `async: true`; watchOperation then rejects. */
+export async function cancelOperation(operation: AsyncOperation): Promise<OperationProgress> {
+  const response = await fetch(`/api/veo/operations/${operation.operationId}/cancel`, { method: 'POST' });
+
+  if (!response.ok) {
+    throw await responseError('Cancel failed', response);
+  }
+
+  return response.json();
+}
@@ -16,19 +16,38 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// SafetyThresholds are the accepted values of ANALYZE_SAFETY_THRESHOLD, from the strictest to
+// the most permissive.
+var SafetyThresholds = []string{"BLOCK_LOW_AND_ABOVE", "BLOCK_MEDIUM_AND_ABOVE", "BLOCK_ONLY_HIGH", "BLOCK_NONE", "OFF"}
+
 type Config struct {
-	ProjectID          string
-	Port               string
-	GeminiModel        string
-	VeoModel           string
-	VeoBucket          string
-	Location           string
-	GeminiLocation     string
-	RateLimitPerMinute int
+	ProjectID             string
+	Port                  string
+	GeminiModel           string
+	VeoModel              string
+	VeoBucket             string
+	Location              string
+	GeminiLocation        string
+	RateLimitPerMinute    int
+	AnalyzeCacheWindow    time.Duration
+	MaxConcurrentVeo      int           // Veo generations that run at once.
+	VeoQueueSize          int           // Veo generations that may wait for a free slot before requests are rejected.
+	MaxConcurrentAnalyses int           // Gemini analyses that run at once.
+	AnalyzeQueueSize      int           // Gemini analyses that may wait for a free slot before requests are rejected.
+	AnalyzeTimeout        time.Duration // How long a Gemini analysis may take before it is abandoned.
+	AnalyzeMaxTokens      int32         // Output token cap of a Gemini analysis; 0 uses the model's default.
+	AnalyzeSafety         string        // Block threshold of the analysis safety settings, e.g. BLOCK_ONLY_HIGH; empty uses the API's defaults.
+	AllowedInputBuckets   []string      // Buckets that gs:// inputs may reference; "*" allows any bucket.
+	DeniedInputBuckets    []string      // Buckets that gs:// inputs may never reference, even if allowed.
+	RequestSigningSecret  string        // Shared secret of the HMAC signatures API requests must carry; empty disables signing.
 }
 
 func Load() *Config {
@@ -75,14 +94,104 @@ func Load() *Config {
 		}
 	}
 
+	// Repeated analyses of the same video within this window reuse one Gemini call. 0 disables it.
+	analyzeCacheWindow := 60 * time.Second
+	if v := os.Getenv("ANALYZE_CACHE_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			analyzeCacheWindow = d
+		} else {
+			slog.Warn("Ignoring invalid ANALYZE_CACHE_WINDOW", "value", v, "default", analyzeCacheWindow)
+		}
+	}
+
+	maxConcurrentVeo := 4
+	if v, err := strconv.Atoi(os.Getenv("VEO_MAX_CONCURRENT")); err == nil && v > 0 {
+		maxConcurrentVeo = v
+	}
+
+	veoQueueSize := 8
+	if v, err := strconv.Atoi(os.Getenv("VEO_QUEUE_SIZE")); err == nil && v >= 0 {
+		veoQueueSize = v
+	}
+
+	maxConcurrentAnalyses := 4
+	if v, err := strconv.Atoi(os.Getenv("ANALYZE_MAX_CONCURRENT")); err == nil && v > 0 {
+		maxConcurrentAnalyses = v
+	}
+
+	analyzeQueueSize := 4
+	if v, err := strconv.Atoi(os.Getenv("ANALYZE_QUEUE_SIZE")); err == nil && v >= 0 {
+		analyzeQueueSize = v
+	}
+
+	analyzeTimeout := 2 * time.Minute
+	if v := os.Getenv("ANALYZE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			analyzeTimeout = d
+		}
+	}
+
+	analyzeMaxTokens := int32(0)
+	if v, err := strconv.ParseInt(os.Getenv("ANALYZE_MAX_OUTPUT_TOKENS"), 10, 32); err == nil && v > 0 {
+		analyzeMaxTokens = int32(v)
+	}
+
+	analyzeSafety := strings.ToUpper(strings.TrimSpace(os.Getenv("ANALYZE_SAFETY_THRESHOLD")))
+	if analyzeSafety != "" && !slices.Contains(SafetyThresholds, analyzeSafety) {
+		slog.Warn("Ignoring invalid ANALYZE_SAFETY_THRESHOLD", "value", analyzeSafety, "allowed", strings.Join(SafetyThresholds, ", "))
+		analyzeSafety = ""
+	}
+
+	// Clients may only reference their own uploads and generated videos unless more buckets are allowed.
+	allowedInputBuckets := parseBucketList(os.Getenv("ALLOWED_INPUT_BUCKETS"))
+	if len(allowedInputBuckets) == 0 {
+		allowedInputBuckets = []string{veoBucket}
+	}
+	deniedInputBuckets := parseBucketList(os.Getenv("DENIED_INPUT_BUCKETS"))
+
+	// API requests must carry a recent HMAC signature made with this secret, if set.
+	requestSigningSecret := os.Getenv("REQUEST_SIGNING_SECRET")
+
 	return &Config{
-		ProjectID:          projectID,
-		Port:               port,
-		GeminiModel:        geminiModel,
-		VeoModel:           veoModel,
-		VeoBucket:          veoBucket,
-		Location:           location,
-		GeminiLocation:     geminiLocation,
-		RateLimitPerMinute: rateLimit,
+		ProjectID:             projectID,
+		Port:                  port,
+		GeminiModel:           geminiModel,
+		VeoModel:              veoModel,
+		VeoBucket:             veoBucket,
+		Location:              location,
+		GeminiLocation:        geminiLocation,
+		RateLimitPerMinute:    rateLimit,
+		AnalyzeCacheWindow:    analyzeCacheWindow,
+		MaxConcurrentVeo:      maxConcurrentVeo,
+		VeoQueueSize:          veoQueueSize,
+		MaxConcurrentAnalyses: maxConcurrentAnalyses,
+		AnalyzeQueueSize:      analyzeQueueSize,
+		AnalyzeTimeout:        analyzeTimeout,
+		AnalyzeMaxTokens:      analyzeMaxTokens,
+		AnalyzeSafety:         analyzeSafety,
+		AllowedInputBuckets:   allowedInputBuckets,
+		DeniedInputBuckets:    deniedInputBuckets,
+		RequestSigningSecret:  requestSigningSecret,
+	}
+}
+
+// parseBucketList splits a comma-separated list of bucket names, dropping any gs:// prefix.
+func parseBucketList(value string) []string {
+	var buckets []string
+	for _, bucket := range strings.Split(value, ",") {
+		bucket = strings.Trim(strings.TrimPrefix(strings.TrimSpace(bucket), "gs://"), "/")
+		if bucket != "" {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// InputBucketAllowed reports whether gs:// inputs may reference the bucket: it must not be
+// denied, and must be allowed by name or by "*".
+func (c *Config) InputBucketAllowed(bucket string) bool {
+	if slices.Contains(c.DeniedInputBuckets, bucket) {
+		return false
 	}
+	return slices.Contains(c.AllowedInputBuckets, "*") || slices.Contains(c.AllowedInputBuckets, bucket)
 }
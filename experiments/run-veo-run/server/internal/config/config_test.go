@@ -0,0 +1,135 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadAnalyzeLimits(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantConcurrent int
+		wantQueueSize  int
+		wantTimeout    time.Duration
+	}{
+		{"defaults", nil, 4, 4, 2 * time.Minute},
+		{"configured", map[string]string{"ANALYZE_MAX_CONCURRENT": "2", "ANALYZE_QUEUE_SIZE": "0", "ANALYZE_TIMEOUT": "45s"}, 2, 0, 45 * time.Second},
+		{"invalid values keep the defaults", map[string]string{"ANALYZE_MAX_CONCURRENT": "0", "ANALYZE_QUEUE_SIZE": "-1", "ANALYZE_TIMEOUT": "soon"}, 4, 4, 2 * time.Minute},
+		{"non-positive timeout", map[string]string{"ANALYZE_TIMEOUT": "0s"}, 4, 4, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"ANALYZE_MAX_CONCURRENT", "ANALYZE_QUEUE_SIZE", "ANALYZE_TIMEOUT"} {
+				t.Setenv(key, tt.env[key])
+			}
+			c := Load()
+			if c.MaxConcurrentAnalyses != tt.wantConcurrent {
+				t.Errorf("expected %d concurrent analyses, but got %d", tt.wantConcurrent, c.MaxConcurrentAnalyses)
+			}
+			if c.AnalyzeQueueSize != tt.wantQueueSize {
+				t.Errorf("expected an analysis queue of %d, but got %d", tt.wantQueueSize, c.AnalyzeQueueSize)
+			}
+			if c.AnalyzeTimeout != tt.wantTimeout {
+				t.Errorf("expected an analysis timeout of %v, but got %v", tt.wantTimeout, c.AnalyzeTimeout)
+			}
+		})
+	}
+}
+
+func TestInputBucketAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		bucket  string
+		want    bool
+	}{
+		{"allowed by name", []string{"uploads", "outputs"}, nil, "outputs", true},
+		{"not allowed", []string{"uploads"}, nil, "other", false},
+		{"nothing allowed", nil, nil, "uploads", false},
+		{"wildcard", []string{"*"}, nil, "any-bucket", true},
+		{"denied overrides wildcard", []string{"*"}, []string{"secrets"}, "secrets", false},
+		{"denied overrides name", []string{"uploads"}, []string{"uploads"}, "uploads", false},
+		{"wildcard with another denied", []string{"*"}, []string{"secrets"}, "uploads", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{AllowedInputBuckets: tt.allowed, DeniedInputBuckets: tt.denied}
+			if got := c.InputBucketAllowed(tt.bucket); got != tt.want {
+				t.Errorf("InputBucketAllowed(%q): expected %v, but got %v", tt.bucket, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseBucketList(t *testing.T) {
+	tests := map[string][]string{
+		"":                          nil,
+		"uploads":                   {"uploads"},
+		" gs://uploads/ , outputs,": {"uploads", "outputs"},
+		"*":                         {"*"},
+	}
+	for value, want := range tests {
+		if got := parseBucketList(value); !slices.Equal(got, want) {
+			t.Errorf("parseBucketList(%q): expected %v, but got %v", value, want, got)
+		}
+	}
+}
+
+func TestLoadInputBuckets(t *testing.T) {
+	t.Setenv("VEO_BUCKET", "veo-assets")
+	t.Setenv("ALLOWED_INPUT_BUCKETS", "")
+	t.Setenv("DENIED_INPUT_BUCKETS", "gs://secrets")
+	c := Load()
+	if !slices.Equal(c.AllowedInputBuckets, []string{"veo-assets"}) {
+		t.Errorf("expected only VEO_BUCKET to be allowed by default, but got %v", c.AllowedInputBuckets)
+	}
+	if !slices.Equal(c.DeniedInputBuckets, []string{"secrets"}) {
+		t.Errorf("expected the secrets bucket to be denied, but got %v", c.DeniedInputBuckets)
+	}
+}
+
+func TestLoadAnalyzeGenerationSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxTokens     string
+		safety        string
+		wantMaxTokens int32
+		wantSafety    string
+	}{
+		{"defaults", "", "", 0, ""},
+		{"configured", "2048", "BLOCK_ONLY_HIGH", 2048, "BLOCK_ONLY_HIGH"},
+		{"lowercase threshold", "", " block_none ", 0, "BLOCK_NONE"},
+		{"invalid values are ignored", "-5", "BLOCK_EVERYTHING", 0, ""},
+		{"token cap above int32", "4294967296", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ANALYZE_MAX_OUTPUT_TOKENS", tt.maxTokens)
+			t.Setenv("ANALYZE_SAFETY_THRESHOLD", tt.safety)
+			c := Load()
+			if c.AnalyzeMaxTokens != tt.wantMaxTokens {
+				t.Errorf("expected a token cap of %d, but got %d", tt.wantMaxTokens, c.AnalyzeMaxTokens)
+			}
+			if c.AnalyzeSafety != tt.wantSafety {
+				t.Errorf("expected safety threshold %q, but got %q", tt.wantSafety, c.AnalyzeSafety)
+			}
+		})
+	}
+}
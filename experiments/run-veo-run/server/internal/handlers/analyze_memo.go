@@ -0,0 +1,90 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// analysisMemo deduplicates video analyses. Concurrent requests for the same key share a single
+// Gemini call, and a successful result is reused for the rest of the window. Failures are not
+// remembered, so the next request retries.
+type analysisMemo struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*analysisEntry
+	now     func() time.Time
+}
+
+type analysisEntry struct {
+	done      chan struct{} // Closed when the analysis finishes.
+	result    string
+	err       error
+	expiresAt time.Time // Zero while the analysis is in flight.
+}
+
+func newAnalysisMemo(window time.Duration) *analysisMemo {
+	return &analysisMemo{window: window, entries: make(map[string]*analysisEntry), now: time.Now}
+}
+
+// Do returns the result for key, calling analyze only if no analysis for key is in flight or
+// remembered. shared reports whether the result came from another request's call. analyze runs
+// detached from ctx so that the request that started it cannot cancel it for the others; ctx
+// only bounds how long this caller waits.
+func (m *analysisMemo) Do(ctx context.Context, key string, analyze func(context.Context) (string, error)) (result string, shared bool, err error) {
+	if m == nil || m.window <= 0 {
+		result, err = analyze(ctx)
+		return result, false, err
+	}
+
+	m.mu.Lock()
+	now := m.now()
+	for k, e := range m.entries {
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &analysisEntry{done: make(chan struct{})}
+		m.entries[key] = entry
+		go m.run(context.WithoutCancel(ctx), key, entry, analyze)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.result, ok, entry.err
+	case <-ctx.Done():
+		return "", ok, ctx.Err()
+	}
+}
+
+// run performs the analysis for entry and records its outcome.
+func (m *analysisMemo) run(ctx context.Context, key string, entry *analysisEntry, analyze func(context.Context) (string, error)) {
+	result, err := analyze(ctx)
+
+	m.mu.Lock()
+	entry.result, entry.err = result, err
+	if err != nil {
+		delete(m.entries, key)
+	} else {
+		entry.expiresAt = m.now().Add(m.window)
+	}
+	m.mu.Unlock()
+	close(entry.done)
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingAnalyzer is a fake Gemini call that counts its calls. Calls block until release is
+// closed, if it is set.
+type countingAnalyzer struct {
+	calls   atomic.Int32
+	release chan struct{}
+	result  string
+	err     error
+}
+
+func (a *countingAnalyzer) analyze(ctx context.Context) (string, error) {
+	a.calls.Add(1)
+	if a.release != nil {
+		<-a.release
+	}
+	return a.result, a.err
+}
+
+// fakeClock is a settable clock for analysisMemo.now.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestMemo(window time.Duration) (*analysisMemo, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	m := newAnalysisMemo(window)
+	m.now = clock.now
+	return m, clock
+}
+
+func TestAnalysisMemoSharesInFlightCall(t *testing.T) {
+	m, _ := newTestMemo(time.Minute)
+	a := &countingAnalyzer{release: make(chan struct{}), result: "a cat"}
+
+	const callers = 3
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	shared := make([]bool, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], shared[i], _ = m.Do(context.Background(), "video", a.analyze)
+		}()
+	}
+	// Let every caller join the in-flight call before it finishes.
+	for {
+		m.mu.Lock()
+		_, started := m.entries["video"]
+		m.mu.Unlock()
+		if started && a.calls.Load() == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(a.release)
+	wg.Wait()
+
+	if got := a.calls.Load(); got != 1 {
+		t.Errorf("expected 1 Gemini call, but got %d", got)
+	}
+	sharedCount := 0
+	for i := range callers {
+		if results[i] != "a cat" {
+			t.Errorf("caller %d: expected result %q, but got %q", i, "a cat", results[i])
+		}
+		if shared[i] {
+			sharedCount++
+		}
+	}
+	if sharedCount != callers-1 {
+		t.Errorf("expected %d shared results, but got %d", callers-1, sharedCount)
+	}
+}
+
+func TestAnalysisMemoRapidAnalysesMakeOneCall(t *testing.T) {
+	m, clock := newTestMemo(time.Minute)
+	a := &countingAnalyzer{result: "a cat"}
+
+	for i := range 3 {
+		result, shared, err := m.Do(context.Background(), "video", a.analyze)
+		if err != nil || result != "a cat" {
+			t.Fatalf("analysis %d: expected %q, but got %q, %v", i, "a cat", result, err)
+		}
+		if shared != (i > 0) {
+			t.Errorf("analysis %d: expected shared %v, but got %v", i, i > 0, shared)
+		}
+		clock.advance(time.Second)
+	}
+	if got := a.calls.Load(); got != 1 {
+		t.Errorf("expected three rapid analyses to make 1 Gemini call, but got %d", got)
+	}
+
+	// Another video is analyzed separately.
+	if _, _, err := m.Do(context.Background(), "other", a.analyze); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if got := a.calls.Load(); got != 2 {
+		t.Errorf("expected 2 Gemini calls after analyzing another video, but got %d", got)
+	}
+}
+
+func TestAnalysisMemoDoesNotCacheFailures(t *testing.T) {
+	m, _ := newTestMemo(time.Minute)
+	a := &countingAnalyzer{err: errors.New("quota exceeded")}
+
+	if _, _, err := m.Do(context.Background(), "video", a.analyze); err == nil {
+		t.Fatal("expected the first analysis to fail")
+	}
+	a.err, a.result = nil, "a cat"
+	result, shared, err := m.Do(context.Background(), "video", a.analyze)
+	if err != nil || result != "a cat" || shared {
+		t.Errorf("expected a fresh result %q, but got %q (shared: %v), %v", "a cat", result, shared, err)
+	}
+	if got := a.calls.Load(); got != 2 {
+		t.Errorf("expected the failure to be retried with 2 Gemini calls, but got %d", got)
+	}
+}
+
+func TestAnalysisMemoWindowExpiry(t *testing.T) {
+	m, clock := newTestMemo(time.Minute)
+	a := &countingAnalyzer{result: "a cat"}
+
+	m.Do(context.Background(), "video", a.analyze)
+	clock.advance(time.Minute)
+	m.Do(context.Background(), "video", a.analyze)
+	if got := a.calls.Load(); got != 1 {
+		t.Errorf("expected the result to be reused at the end of the window, but got %d calls", got)
+	}
+
+	clock.advance(time.Nanosecond)
+	if _, shared, _ := m.Do(context.Background(), "video", a.analyze); shared {
+		t.Error("expected a fresh result after the window expired")
+	}
+	if got := a.calls.Load(); got != 2 {
+		t.Errorf("expected 2 Gemini calls after the window expired, but got %d", got)
+	}
+}
+
+func TestAnalysisMemoDisabled(t *testing.T) {
+	m, _ := newTestMemo(0)
+	a := &countingAnalyzer{result: "a cat"}
+	for range 3 {
+		m.Do(context.Background(), "video", a.analyze)
+	}
+	if got := a.calls.Load(); got != 3 {
+		t.Errorf("expected every analysis to call Gemini with a zero window, but got %d calls", got)
+	}
+}
+
+func TestAnalysisMemoCallerCancellation(t *testing.T) {
+	m, _ := newTestMemo(time.Minute)
+	a := &countingAnalyzer{release: make(chan struct{}), result: "a cat"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := m.Do(ctx, "video", a.analyze); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, but got %v", err)
+	}
+
+	// The analysis runs on for the other callers.
+	close(a.release)
+	result, _, err := m.Do(context.Background(), "video", a.analyze)
+	if err != nil || result != "a cat" {
+		t.Errorf("expected %q, but got %q, %v", "a cat", result, err)
+	}
+	if got := a.calls.Load(); got != 1 {
+		t.Errorf("expected 1 Gemini call, but got %d", got)
+	}
+}
@@ -15,7 +15,8 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -38,18 +39,57 @@ func (h *Handler) HandleAnalyzeVideo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req AnalyzeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if verr := decodeJSONBody(r, &req); verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
 
 	if req.VideoURI == "" {
-		http.Error(w, "videoUri is required", http.StatusBadRequest)
+		writeValidationError(w, fieldError("videoUri", "is required"))
+		return
+	}
+	if verr := validateGCSURI("videoUri", req.VideoURI); verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	if verr := h.checkInputBuckets(map[string]string{"videoUri": req.VideoURI}); verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
 
 	slog.Info("Analyzing video context", "uri", req.VideoURI, "model", h.Config.GeminiModel)
 
+	// Frontends can fire several analyses of the same clip in quick succession; share one Gemini call.
+	key := h.Config.GeminiModel + "|" + req.VideoURI
+	partText, shared, err := h.analyses.Do(r.Context(), key, func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, h.Config.AnalyzeTimeout)
+		defer cancel()
+		result, err := h.analyzeVideo(ctx, req.VideoURI)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", &OperationError{Code: OpErrDeadlineExceeded, Message: fmt.Sprintf("the analysis did not finish within %v", h.Config.AnalyzeTimeout)}
+		}
+		return result, err
+	})
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		writeOperationError(w, opErr)
+		return
+	}
+	if err != nil {
+		slog.Error("Gemini analysis failed", "error", err)
+		http.Error(w, fmt.Sprintf("Analysis failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if shared {
+		slog.Info("Reusing recent analysis", "uri", req.VideoURI, "model", h.Config.GeminiModel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(partText))
+}
+
+// analyzeVideo asks Gemini for a continuity summary of the video and returns its raw JSON text.
+func (h *Handler) analyzeVideo(ctx context.Context, videoURI string) (string, error) {
 	// Construct Prompt
 	prompt := `Analyze this video clip to ensure visual continuity for a generative video extension. 
 Provide a concise, comma-separated descriptive summary including:
@@ -68,7 +108,7 @@ Return the result in this JSON format: { "context": "description string" }`
 				{Text: prompt},
 				{
 					FileData: &genai.FileData{
-						FileURI:  req.VideoURI,
+						FileURI:  videoURI,
 						MIMEType: "video/mp4",
 					},
 				},
@@ -76,51 +116,71 @@ Return the result in this JSON format: { "context": "description string" }`
 		},
 	}
 
-	slog.Info("Sending request to Gemini", "file_uri", req.VideoURI)
+	slog.Info("Sending request to Gemini", "file_uri", videoURI)
 
-	resp, err := h.GenAI.Models.GenerateContent(r.Context(), h.Config.GeminiModel,
-		contents,
-		&genai.GenerateContentConfig{
-			ResponseMIMEType: "application/json",
-		},
-	)
+	resp, err := h.GenAI.Models.GenerateContent(ctx, h.Config.GeminiModel, contents, h.analyzeConfig())
 	if err != nil {
-		slog.Error("Gemini analysis failed", "error", err)
-		http.Error(w, fmt.Sprintf("Analysis failed: %v", err), http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
-	if resp.UsageMetadata != nil {
-		slog.Info("Gemini Usage", 
+	if resp != nil && resp.UsageMetadata != nil {
+		slog.Info("Gemini Usage",
 			"prompt_tokens", resp.UsageMetadata.PromptTokenCount,
 			"candidate_tokens", resp.UsageMetadata.CandidatesTokenCount,
 			"total_tokens", resp.UsageMetadata.TotalTokenCount,
 		)
 	}
 
-	// Parse Response
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		http.Error(w, "No content generated", http.StatusInternalServerError)
-		return
+	// Parse Response. A blocked response has no content, or no candidates at all.
+	if opErr := emptyContentError(resp); opErr != nil {
+		return "", opErr
 	}
 
 	// The SDK returns parts. We expect Text.
 	// Note: In v1.39.0, parts might be specific types.
 	// Checking the Part type handling.
-	
+
 	// Assuming text response for now based on standard usage.
 	// We just stream the raw JSON back to the client or parse/validate it.
 	// Let's forward the raw text for simplicity if it validates as JSON.
-	
+
 	var partText string
 	for _, part := range resp.Candidates[0].Content.Parts {
 		if part.Text != "" {
 			partText += part.Text
 		}
 	}
+	if resp.Candidates[0].FinishReason == genai.FinishReasonMaxTokens {
+		// The JSON is likely incomplete. Raising ANALYZE_MAX_OUTPUT_TOKENS lets the analysis finish.
+		slog.Warn("Analysis was truncated by the output token cap", "uri", videoURI, "max_output_tokens", h.Config.AnalyzeMaxTokens, "result_length", len(partText))
+	}
 
 	slog.Info("Analysis complete", "result", partText)
+	return partText, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(partText))
+// analyzeSafetyCategories are the harm categories that ANALYZE_SAFETY_THRESHOLD applies to.
+var analyzeSafetyCategories = []genai.HarmCategory{
+	genai.HarmCategoryHarassment,
+	genai.HarmCategoryHateSpeech,
+	genai.HarmCategorySexuallyExplicit,
+	genai.HarmCategoryDangerousContent,
+}
+
+// analyzeConfig returns the generation config of a video analysis, with the output token cap
+// and safety threshold of the server config if they are set.
+func (h *Handler) analyzeConfig() *genai.GenerateContentConfig {
+	cfg := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		MaxOutputTokens:  h.Config.AnalyzeMaxTokens,
+	}
+	if h.Config.AnalyzeSafety != "" {
+		for _, category := range analyzeSafetyCategories {
+			cfg.SafetySettings = append(cfg.SafetySettings, &genai.SafetySetting{
+				Category:  category,
+				Threshold: genai.HarmBlockThreshold(h.Config.AnalyzeSafety),
+			})
+		}
+	}
+	return cfg
 }
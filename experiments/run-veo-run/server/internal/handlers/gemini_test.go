@@ -0,0 +1,56 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/run-veo-run/server/internal/config"
+	"google.golang.org/genai"
+)
+
+func TestAnalyzeConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxTokens     int32
+		safety        string
+		wantSettings  int
+		wantThreshold genai.HarmBlockThreshold
+	}{
+		{name: "defaults"},
+		{name: "token cap", maxTokens: 1024},
+		{name: "safety threshold", safety: "BLOCK_ONLY_HIGH", wantSettings: len(analyzeSafetyCategories), wantThreshold: genai.HarmBlockThresholdBlockOnlyHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{Config: &config.Config{AnalyzeMaxTokens: tt.maxTokens, AnalyzeSafety: tt.safety}}
+			cfg := h.analyzeConfig()
+			if cfg.ResponseMIMEType != "application/json" {
+				t.Errorf("expected a JSON response, but got %q", cfg.ResponseMIMEType)
+			}
+			if cfg.MaxOutputTokens != tt.maxTokens {
+				t.Errorf("expected a token cap of %d, but got %d", tt.maxTokens, cfg.MaxOutputTokens)
+			}
+			if len(cfg.SafetySettings) != tt.wantSettings {
+				t.Fatalf("expected %d safety settings, but got %d", tt.wantSettings, len(cfg.SafetySettings))
+			}
+			for _, setting := range cfg.SafetySettings {
+				if setting.Threshold != tt.wantThreshold {
+					t.Errorf("expected threshold %s for %s, but got %s", tt.wantThreshold, setting.Category, setting.Threshold)
+				}
+			}
+		})
+	}
+}
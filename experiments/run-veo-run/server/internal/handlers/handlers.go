@@ -28,6 +28,10 @@ type Handler struct {
 	Config     *config.Config
 	AuthClient *auth.Client
 	GenAI      *genai.Client
+
+	analyses   *analysisMemo
+	uploads    *uploadIndex
+	operations *operationTracker
 }
 
 func New(cfg *config.Config, authClient *auth.Client, genaiClient *genai.Client) *Handler {
@@ -35,6 +39,9 @@ func New(cfg *config.Config, authClient *auth.Client, genaiClient *genai.Client)
 		Config:     cfg,
 		AuthClient: authClient,
 		GenAI:      genaiClient,
+		analyses:   newAnalysisMemo(cfg.AnalyzeCacheWindow),
+		uploads:    newUploadIndex(),
+		operations: newOperationTracker(opRetention),
 	}
 }
 
@@ -0,0 +1,202 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Operation error categories reported in OperationError.Code.
+const (
+	OpErrSafetyBlocked    = "safety_blocked"
+	OpErrInvalidRequest   = "invalid_request"
+	OpErrQuotaExceeded    = "quota_exceeded"
+	OpErrPermissionDenied = "permission_denied"
+	OpErrDeadlineExceeded = "deadline_exceeded"
+	OpErrInternal         = "internal"
+)
+
+// google.rpc.Code values returned in the error of a failed long-running operation.
+const (
+	rpcInvalidArgument   = 3
+	rpcDeadlineExceeded  = 4
+	rpcPermissionDenied  = 7
+	rpcResourceExhausted = 8
+)
+
+// safetyMessageMarkers identify the messages Veo returns when a prompt or input is rejected by
+// its safety filters, which otherwise share the INVALID_ARGUMENT code with malformed requests.
+var safetyMessageMarkers = []string{"usage guidelines", "responsible ai", "safety", "prohibited"}
+
+// supportCodePattern matches the eight-digit support codes Veo attaches to safety rejections.
+var supportCodePattern = regexp.MustCompile(`\b\d{8}\b`)
+
+// OperationError is the structured error of a failed Veo operation. Code is a machine-readable
+// category, RPCCode the google.rpc.Code reported by the service, and Details the raw status
+// details. For safety rejections, SupportCodes and FilteredReasons explain what was blocked.
+// Empty Gemini responses report why generation stopped in FinishReason.
+type OperationError struct {
+	Code            string   `json:"error"`
+	RPCCode         int      `json:"rpcCode,omitempty"`
+	Message         string   `json:"message"`
+	SupportCodes    []string `json:"supportCodes,omitempty"`
+	FilteredReasons []string `json:"filteredReasons,omitempty"`
+	FinishReason    string   `json:"finishReason,omitempty"`
+	Details         []any    `json:"details,omitempty"`
+}
+
+func (e *OperationError) Error() string {
+	if e.RPCCode != 0 {
+		return fmt.Sprintf("%s (code %d): %s", e.Code, e.RPCCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// HTTPStatus returns the status code a handler responds with for this error.
+func (e *OperationError) HTTPStatus() int {
+	switch e.Code {
+	case OpErrSafetyBlocked:
+		return http.StatusUnprocessableEntity
+	case OpErrInvalidRequest:
+		return http.StatusBadRequest
+	case OpErrQuotaExceeded:
+		return http.StatusTooManyRequests
+	case OpErrPermissionDenied:
+		return http.StatusForbidden
+	case OpErrDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// parseOperationError converts the google.rpc.Status of a failed operation into an
+// OperationError. Numbers in the status are float64, as decoded from JSON.
+func parseOperationError(status map[string]any) *OperationError {
+	opErr := &OperationError{Code: OpErrInternal}
+	if code, ok := status["code"].(float64); ok {
+		opErr.RPCCode = int(code)
+	}
+	opErr.Message, _ = status["message"].(string)
+	if opErr.Message == "" {
+		raw, _ := json.Marshal(status)
+		opErr.Message = string(raw)
+	}
+	opErr.Details, _ = status["details"].([]any)
+
+	switch opErr.RPCCode {
+	case rpcInvalidArgument:
+		opErr.Code = OpErrInvalidRequest
+		if isSafetyMessage(opErr.Message) {
+			opErr.Code = OpErrSafetyBlocked
+			opErr.SupportCodes = supportCodePattern.FindAllString(opErr.Message, -1)
+		}
+	case rpcDeadlineExceeded:
+		opErr.Code = OpErrDeadlineExceeded
+	case rpcPermissionDenied:
+		opErr.Code = OpErrPermissionDenied
+	case rpcResourceExhausted:
+		opErr.Code = OpErrQuotaExceeded
+	}
+	return opErr
+}
+
+func isSafetyMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range safetyMessageMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// filteredVideosError returns a safety_blocked error if the operation succeeded but Veo withheld
+// every video under its Responsible AI filters, and nil otherwise.
+func filteredVideosError(resp *genai.GenerateVideosResponse) *OperationError {
+	if resp == nil || len(resp.GeneratedVideos) > 0 || resp.RAIMediaFilteredCount == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("%d video(s) were blocked by safety filters", resp.RAIMediaFilteredCount)
+	if len(resp.RAIMediaFilteredReasons) > 0 {
+		message += ": " + strings.Join(resp.RAIMediaFilteredReasons, " ")
+	}
+	return &OperationError{
+		Code:            OpErrSafetyBlocked,
+		Message:         message,
+		SupportCodes:    supportCodePattern.FindAllString(strings.Join(resp.RAIMediaFilteredReasons, " "), -1),
+		FilteredReasons: resp.RAIMediaFilteredReasons,
+	}
+}
+
+// blockedFinishReasons are the Gemini finish reasons that mean the response was withheld by
+// safety or policy filters rather than cut short.
+var blockedFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonRecitation:        true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonSPII:              true,
+	genai.FinishReasonImageSafety:       true,
+}
+
+// emptyContentError returns an error explaining why a Gemini response has no content parts, or
+// nil if its first candidate has content. A blocked prompt or a candidate stopped by a safety
+// filter is reported as safety_blocked, with the block or finish reason.
+func emptyContentError(resp *genai.GenerateContentResponse) *OperationError {
+	if resp != nil && resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		message := fmt.Sprintf("the request was blocked (%s)", resp.PromptFeedback.BlockReason)
+		if resp.PromptFeedback.BlockReasonMessage != "" {
+			message += ": " + resp.PromptFeedback.BlockReasonMessage
+		}
+		return &OperationError{Code: OpErrSafetyBlocked, Message: message, FilteredReasons: []string{string(resp.PromptFeedback.BlockReason)}}
+	}
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0] == nil {
+		return &OperationError{Code: OpErrInternal, Message: "Gemini returned no candidates"}
+	}
+	candidate := resp.Candidates[0]
+	if candidate.Content != nil && len(candidate.Content.Parts) > 0 {
+		return nil
+	}
+	opErr := &OperationError{
+		Code:         OpErrInternal,
+		Message:      fmt.Sprintf("Gemini returned an empty response (finish reason %s)", candidate.FinishReason),
+		FinishReason: string(candidate.FinishReason),
+	}
+	if blockedFinishReasons[candidate.FinishReason] {
+		opErr.Code = OpErrSafetyBlocked
+		opErr.Message = fmt.Sprintf("the response was blocked (finish reason %s)", candidate.FinishReason)
+	}
+	if candidate.FinishMessage != "" {
+		opErr.Message += ": " + candidate.FinishMessage
+	}
+	return opErr
+}
+
+// writeOperationError responds with the operation error as JSON, with a status code matching
+// its category.
+func writeOperationError(w http.ResponseWriter, opErr *OperationError) {
+	slog.Warn("Generation failed", "code", opErr.Code, "rpcCode", opErr.RPCCode, "message", opErr.Message)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(opErr.HTTPStatus())
+	json.NewEncoder(w).Encode(opErr)
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseOperationError(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           map[string]any
+		wantCode         string
+		wantRPCCode      int
+		wantSupportCodes []string
+		wantHTTPStatus   int
+	}{
+		{
+			name:           "invalid argument",
+			status:         map[string]any{"code": 3.0, "message": "image is too large"},
+			wantCode:       OpErrInvalidRequest,
+			wantRPCCode:    3,
+			wantHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			name:             "safety rejection",
+			status:           map[string]any{"code": 3.0, "message": "The prompt could not be submitted. It violates Vertex AI's usage guidelines. Support codes: 58061214, 17301594"},
+			wantCode:         OpErrSafetyBlocked,
+			wantRPCCode:      3,
+			wantSupportCodes: []string{"58061214", "17301594"},
+			wantHTTPStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "deadline exceeded",
+			status:         map[string]any{"code": 4.0, "message": "timed out"},
+			wantCode:       OpErrDeadlineExceeded,
+			wantRPCCode:    4,
+			wantHTTPStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "permission denied",
+			status:         map[string]any{"code": 7.0, "message": "denied"},
+			wantCode:       OpErrPermissionDenied,
+			wantRPCCode:    7,
+			wantHTTPStatus: http.StatusForbidden,
+		},
+		{
+			name:           "quota exceeded",
+			status:         map[string]any{"code": 8.0, "message": "quota"},
+			wantCode:       OpErrQuotaExceeded,
+			wantRPCCode:    8,
+			wantHTTPStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "unknown code",
+			status:         map[string]any{"code": 13.0, "message": "internal"},
+			wantCode:       OpErrInternal,
+			wantRPCCode:    13,
+			wantHTTPStatus: http.StatusBadGateway,
+		},
+		{
+			name:           "no code",
+			status:         map[string]any{},
+			wantCode:       OpErrInternal,
+			wantHTTPStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opErr := parseOperationError(tt.status)
+			if opErr.Code != tt.wantCode {
+				t.Errorf("expected code %q, but got %q", tt.wantCode, opErr.Code)
+			}
+			if opErr.RPCCode != tt.wantRPCCode {
+				t.Errorf("expected rpc code %d, but got %d", tt.wantRPCCode, opErr.RPCCode)
+			}
+			if !slices.Equal(opErr.SupportCodes, tt.wantSupportCodes) {
+				t.Errorf("expected support codes %v, but got %v", tt.wantSupportCodes, opErr.SupportCodes)
+			}
+			if got := opErr.HTTPStatus(); got != tt.wantHTTPStatus {
+				t.Errorf("expected HTTP status %d, but got %d", tt.wantHTTPStatus, got)
+			}
+			if opErr.Message == "" {
+				t.Error("expected a message, but got none")
+			}
+		})
+	}
+}
+
+func TestFilteredVideosError(t *testing.T) {
+	tests := []struct {
+		name             string
+		resp             *genai.GenerateVideosResponse
+		wantErr          bool
+		wantSupportCodes []string
+	}{
+		{name: "nil response"},
+		{name: "videos returned", resp: &genai.GenerateVideosResponse{GeneratedVideos: []*genai.GeneratedVideo{{}}, RAIMediaFilteredCount: 1}},
+		{name: "no videos and none filtered", resp: &genai.GenerateVideosResponse{}},
+		{
+			name:    "all videos filtered",
+			resp:    &genai.GenerateVideosResponse{RAIMediaFilteredCount: 2},
+			wantErr: true,
+		},
+		{
+			name: "filtered with reasons",
+			resp: &genai.GenerateVideosResponse{
+				RAIMediaFilteredCount:   1,
+				RAIMediaFilteredReasons: []string{"The video could not be generated. Support codes: 29310472"},
+			},
+			wantErr:          true,
+			wantSupportCodes: []string{"29310472"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opErr := filteredVideosError(tt.resp)
+			if (opErr != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, opErr)
+			}
+			if opErr == nil {
+				return
+			}
+			if opErr.Code != OpErrSafetyBlocked {
+				t.Errorf("expected code %q, but got %q", OpErrSafetyBlocked, opErr.Code)
+			}
+			if !slices.Equal(opErr.SupportCodes, tt.wantSupportCodes) {
+				t.Errorf("expected support codes %v, but got %v", tt.wantSupportCodes, opErr.SupportCodes)
+			}
+			if !slices.Equal(opErr.FilteredReasons, tt.resp.RAIMediaFilteredReasons) {
+				t.Errorf("expected filtered reasons %v, but got %v", tt.resp.RAIMediaFilteredReasons, opErr.FilteredReasons)
+			}
+		})
+	}
+}
+
+func TestEmptyContentError(t *testing.T) {
+	withText := &genai.Candidate{Content: &genai.Content{Parts: []*genai.Part{{Text: "a cat"}}}}
+	tests := []struct {
+		name             string
+		resp             *genai.GenerateContentResponse
+		wantCode         string
+		wantFinishReason string
+		wantReasons      []string
+	}{
+		{name: "content", resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{withText}}},
+		{name: "nil response", wantCode: OpErrInternal},
+		{name: "no candidates", resp: &genai.GenerateContentResponse{}, wantCode: OpErrInternal},
+		{
+			name: "blocked prompt",
+			resp: &genai.GenerateContentResponse{
+				PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety, BlockReasonMessage: "unsafe"},
+			},
+			wantCode:    OpErrSafetyBlocked,
+			wantReasons: []string{string(genai.BlockedReasonSafety)},
+		},
+		{
+			name:             "safety finish reason",
+			resp:             &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonSafety}}},
+			wantCode:         OpErrSafetyBlocked,
+			wantFinishReason: string(genai.FinishReasonSafety),
+		},
+		{
+			name:             "token limit",
+			resp:             &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonMaxTokens, Content: &genai.Content{}}}},
+			wantCode:         OpErrInternal,
+			wantFinishReason: string(genai.FinishReasonMaxTokens),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opErr := emptyContentError(tt.resp)
+			if tt.wantCode == "" {
+				if opErr != nil {
+					t.Errorf("expected no error, but got %v", opErr)
+				}
+				return
+			}
+			if opErr == nil {
+				t.Fatal("expected an error, but got nil")
+			}
+			if opErr.Code != tt.wantCode {
+				t.Errorf("expected code %q, but got %q", tt.wantCode, opErr.Code)
+			}
+			if opErr.FinishReason != tt.wantFinishReason {
+				t.Errorf("expected finish reason %q, but got %q", tt.wantFinishReason, opErr.FinishReason)
+			}
+			if !slices.Equal(opErr.FilteredReasons, tt.wantReasons) {
+				t.Errorf("expected filtered reasons %v, but got %v", tt.wantReasons, opErr.FilteredReasons)
+			}
+		})
+	}
+}
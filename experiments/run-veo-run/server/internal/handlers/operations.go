@@ -0,0 +1,324 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/run-veo-run/server/internal/security"
+	"google.golang.org/genai"
+)
+
+// Server-sent event names of an operation's event stream.
+const (
+	opEventProgress = "progress" // The operation is still running.
+	opEventResult   = "result"   // The operation finished; the data is a VeoResponse.
+	opEventFailed   = "failed"   // The operation failed; the data is an OperationError.
+	opEventCanceled = "canceled" // The operation was canceled; the data is an OperationProgress.
+)
+
+// opRetention is how long a finished operation's result stays available to new subscribers. It
+// is shorter than the lifetime of the signed URLs in the result.
+const opRetention = 10 * time.Minute
+
+// opEventsHeartbeat is how often an idle event stream sends a comment, so that proxies do not
+// close the connection while Veo is working.
+const opEventsHeartbeat = 15 * time.Second
+
+// AsyncOperation is the response to a generation or extension started with "async": true.
+type AsyncOperation struct {
+	OperationID string `json:"operationId"`
+	EventsURL   string `json:"eventsUrl"` // Server-sent event stream of the operation's progress.
+}
+
+// OperationProgress is the data of a progress event.
+type OperationProgress struct {
+	OperationID     string  `json:"operationId"`
+	Status          string  `json:"status"`
+	ElapsedSeconds  float64 `json:"elapsedSeconds"`
+	ProgressPercent float64 `json:"progressPercent,omitempty"` // Only set if Veo reports it.
+}
+
+// operationEvent is one server-sent event.
+type operationEvent struct {
+	name string
+	data []byte
+}
+
+// trackedOperation is a Veo operation polled in the background. Subscribers are notified of
+// every new event through a channel with a buffer of one, and read the latest event.
+type trackedOperation struct {
+	cancel      context.CancelFunc // Stops the background polling.
+	startedAt   time.Time
+	last        *operationEvent
+	done        bool
+	finishedAt  time.Time
+	subscribers map[chan struct{}]struct{}
+}
+
+// operationTracker holds the operations started with "async": true, so that clients can follow
+// them through HandleOperationEvents. Finished operations are kept for the retention period, so
+// that a client that subscribes late still receives the result.
+type operationTracker struct {
+	mu        sync.Mutex
+	retention time.Duration
+	ops       map[string]*trackedOperation
+}
+
+func newOperationTracker(retention time.Duration) *operationTracker {
+	return &operationTracker{retention: retention, ops: make(map[string]*trackedOperation)}
+}
+
+// add registers a new operation, polled in the background until cancel is called, and returns
+// its ID.
+func (t *operationTracker) add(cancel context.CancelFunc) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for k, op := range t.ops {
+		if op.done && now.Sub(op.finishedAt) > t.retention {
+			delete(t.ops, k)
+		}
+	}
+	t.ops[id] = &trackedOperation{cancel: cancel, startedAt: now, subscribers: make(map[chan struct{}]struct{})}
+	return id
+}
+
+// publish records the latest event of an operation and notifies its subscribers. A final event
+// marks the operation as done.
+func (t *operationTracker) publish(id string, event operationEvent, final bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.ops[id]
+	if !ok || op.done {
+		return
+	}
+	op.last = &event
+	if final {
+		op.done = true
+		op.finishedAt = time.Now()
+	}
+	op.notifyLocked()
+}
+
+// notifyLocked notifies the subscribers of an operation of a new event. The tracker's lock must
+// be held.
+func (op *trackedOperation) notifyLocked() {
+	for ch := range op.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // A notification is already pending; the subscriber reads the latest event.
+		}
+	}
+}
+
+// cancel stops the background polling of an operation and publishes a final canceled event.
+// found is false if the operation is unknown, and canceled is false if it had already finished.
+func (t *operationTracker) cancel(id string) (progress OperationProgress, found, canceled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.ops[id]
+	if !ok {
+		return OperationProgress{}, false, false
+	}
+	if op.done {
+		return OperationProgress{}, true, false
+	}
+	op.cancel()
+
+	progress = OperationProgress{OperationID: id, Status: "canceled", ElapsedSeconds: time.Since(op.startedAt).Seconds()}
+	data, _ := json.Marshal(progress)
+	op.last = &operationEvent{name: opEventCanceled, data: data}
+	op.done = true
+	op.finishedAt = time.Now()
+	op.notifyLocked()
+	return progress, true, true
+}
+
+// subscribe returns a channel that is notified of new events of an operation, and a function
+// that cancels the subscription. ok is false if the operation is unknown.
+func (t *operationTracker) subscribe(id string) (notify chan struct{}, cancel func(), ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.ops[id]
+	if !ok {
+		return nil, nil, false
+	}
+	notify = make(chan struct{}, 1)
+	if op.last != nil {
+		notify <- struct{}{}
+	}
+	op.subscribers[notify] = struct{}{}
+	return notify, func() {
+		t.mu.Lock()
+		delete(op.subscribers, notify)
+		t.mu.Unlock()
+	}, true
+}
+
+// latest returns the latest event of an operation and whether it is final.
+func (t *operationTracker) latest(id string) (*operationEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.ops[id]
+	if !ok {
+		return nil, true
+	}
+	return op.last, op.done
+}
+
+// startAsync polls op in the background, publishing progress events and, when it finishes, a
+// result or error event, and responds with 202 and the operation's ID and event stream URL.
+// The request's generation queue slot is held until polling ends or the operation is canceled.
+func (h *Handler) startAsync(w http.ResponseWriter, r *http.Request, op *genai.GenerateVideosOperation) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	id := h.operations.add(cancel)
+	release := security.DetachSlot(r.Context())
+	started := time.Now()
+
+	publishJSON := func(name string, v any, final bool) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			slog.Error("Failed to encode operation event", "operation_id", id, "event", name, "error", err)
+			return
+		}
+		h.operations.publish(id, operationEvent{name: name, data: data}, final)
+	}
+	publishJSON(opEventProgress, OperationProgress{OperationID: id, Status: "running"}, false)
+
+	go func() {
+		defer release()
+		defer cancel()
+		resp, err := h.waitForOperation(ctx, op, func(latest *genai.GenerateVideosOperation) {
+			progress := OperationProgress{OperationID: id, Status: "running", ElapsedSeconds: time.Since(started).Seconds()}
+			if percent, ok := latest.Metadata["progressPercent"].(float64); ok {
+				progress.ProgressPercent = percent
+			}
+			publishJSON(opEventProgress, progress, false)
+		})
+		if err == nil {
+			if opErr := filteredVideosError(resp); opErr != nil {
+				err = opErr
+			} else if len(resp.GeneratedVideos) == 0 {
+				err = &OperationError{Code: OpErrInternal, Message: "no video generated"}
+			}
+		}
+		if errors.Is(err, context.Canceled) {
+			slog.Info("Background generation canceled", "operation_id", id)
+			return
+		}
+		if err != nil {
+			var opErr *OperationError
+			if !errors.As(err, &opErr) {
+				opErr = &OperationError{Code: OpErrInternal, Message: err.Error()}
+				if errors.Is(err, context.DeadlineExceeded) {
+					opErr.Code = OpErrDeadlineExceeded
+				}
+			}
+			slog.Warn("Background generation failed", "operation_id", id, "code", opErr.Code, "message", opErr.Message)
+			publishJSON(opEventFailed, opErr, true)
+			return
+		}
+		slog.Info("Background generation complete", "operation_id", id, "count", len(resp.GeneratedVideos))
+		publishJSON(opEventResult, h.buildVeoResponse(ctx, resp.GeneratedVideos), true)
+	}()
+
+	slog.Info("Polling video operation in the background", "op", op.Name, "operation_id", id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(AsyncOperation{
+		OperationID: id,
+		EventsURL:   fmt.Sprintf("/api/veo/operations/%s/events", id),
+	})
+}
+
+// HandleOperationEvents streams the progress of an operation started with "async": true as
+// server-sent events, for browsers' EventSource: the latest event right away, then every new
+// one, until a final result or error event.
+func (h *Handler) HandleOperationEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	notify, cancel, ok := h.operations.subscribe(id)
+	if !ok {
+		http.Error(w, "Unknown operation", http.StatusNotFound)
+		return
+	}
+	defer cancel()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(opEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-notify:
+			event, done := h.operations.latest(id)
+			if event != nil {
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+				flusher.Flush()
+			}
+			if done {
+				return
+			}
+		}
+	}
+}
+
+// HandleCancelOperation cancels an operation started with "async": true: its background polling
+// stops, its generation queue slot is released, and its event stream ends with a canceled event.
+// The Veo API has no call to cancel a generation, so Veo may still finish the videos, but they
+// are not returned.
+func (h *Handler) HandleCancelOperation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	progress, found, canceled := h.operations.cancel(id)
+	if !found {
+		http.Error(w, "Unknown operation", http.StatusNotFound)
+		return
+	}
+	if !canceled {
+		http.Error(w, "Operation already finished", http.StatusConflict)
+		return
+	}
+	slog.Info("Canceled background generation", "operation_id", id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
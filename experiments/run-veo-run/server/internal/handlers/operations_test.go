@@ -0,0 +1,238 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/run-veo-run/server/internal/security"
+)
+
+func TestOperationTrackerPublishAndSubscribe(t *testing.T) {
+	tr := newOperationTracker(time.Minute)
+	id := tr.add(func() {})
+
+	if _, _, ok := tr.subscribe("unknown"); ok {
+		t.Error("expected subscribing to an unknown operation to fail")
+	}
+	notify, unsubscribe, ok := tr.subscribe(id)
+	if !ok {
+		t.Fatal("expected subscribing to a tracked operation to succeed")
+	}
+	defer unsubscribe()
+	select {
+	case <-notify:
+		t.Error("expected no notification before the first event")
+	default:
+	}
+
+	// Several events before the subscriber reads leave one pending notification and the latest event.
+	tr.publish(id, operationEvent{name: opEventProgress, data: []byte(`1`)}, false)
+	tr.publish(id, operationEvent{name: opEventProgress, data: []byte(`2`)}, false)
+	<-notify
+	select {
+	case <-notify:
+		t.Error("expected notifications to coalesce")
+	default:
+	}
+	event, done := tr.latest(id)
+	if event == nil || string(event.data) != "2" || done {
+		t.Errorf("expected the latest running event 2, but got %+v (done: %v)", event, done)
+	}
+
+	tr.publish(id, operationEvent{name: opEventResult, data: []byte(`{}`)}, true)
+	<-notify
+	if event, done := tr.latest(id); event.name != opEventResult || !done {
+		t.Errorf("expected a final result event, but got %+v (done: %v)", event, done)
+	}
+
+	// Events after the final one are ignored.
+	tr.publish(id, operationEvent{name: opEventProgress}, false)
+	if event, _ := tr.latest(id); event.name != opEventResult {
+		t.Errorf("expected the result to stay final, but got %q", event.name)
+	}
+}
+
+func TestOperationTrackerLateSubscriber(t *testing.T) {
+	tr := newOperationTracker(time.Minute)
+	id := tr.add(func() {})
+	tr.publish(id, operationEvent{name: opEventResult}, true)
+
+	// A subscriber that arrives after the result is notified right away.
+	notify, unsubscribe, ok := tr.subscribe(id)
+	if !ok {
+		t.Fatal("expected a finished operation to be kept for the retention period")
+	}
+	defer unsubscribe()
+	select {
+	case <-notify:
+	default:
+		t.Error("expected a pending notification for the existing event")
+	}
+}
+
+func TestOperationTrackerCancel(t *testing.T) {
+	tr := newOperationTracker(time.Minute)
+	canceled := 0
+	id := tr.add(func() { canceled++ })
+
+	if _, found, _ := tr.cancel("unknown"); found {
+		t.Error("expected canceling an unknown operation to report it as not found")
+	}
+	progress, found, ok := tr.cancel(id)
+	if !found || !ok {
+		t.Fatalf("expected the operation to be canceled, but got found: %v, canceled: %v", found, ok)
+	}
+	if progress.OperationID != id || progress.Status != "canceled" {
+		t.Errorf("expected canceled progress for %s, but got %+v", id, progress)
+	}
+	if canceled != 1 {
+		t.Errorf("expected the polling to be stopped once, but got %d calls", canceled)
+	}
+	if event, done := tr.latest(id); event.name != opEventCanceled || !done {
+		t.Errorf("expected a final canceled event, but got %+v (done: %v)", event, done)
+	}
+
+	// A finished operation cannot be canceled again.
+	if _, found, ok := tr.cancel(id); !found || ok {
+		t.Errorf("expected found: true, canceled: false, but got %v, %v", found, ok)
+	}
+	if canceled != 1 {
+		t.Errorf("expected the polling to be stopped once, but got %d calls", canceled)
+	}
+}
+
+func TestOperationTrackerRetention(t *testing.T) {
+	tr := newOperationTracker(time.Millisecond)
+	finished := tr.add(func() {})
+	running := tr.add(func() {})
+	tr.publish(finished, operationEvent{name: opEventResult}, true)
+	time.Sleep(5 * time.Millisecond)
+
+	// Adding an operation drops finished ones past the retention period, but not running ones.
+	tr.add(func() {})
+	if _, _, ok := tr.subscribe(finished); ok {
+		t.Error("expected the finished operation to be dropped after the retention period")
+	}
+	if _, _, ok := tr.subscribe(running); !ok {
+		t.Error("expected the running operation to be kept")
+	}
+}
+
+func TestHandleOperationEvents(t *testing.T) {
+	h := &Handler{operations: newOperationTracker(time.Minute)}
+	finished := h.operations.add(func() {})
+	h.operations.publish(finished, operationEvent{name: opEventProgress, data: []byte(`{"status":"running"}`)}, false)
+	h.operations.publish(finished, operationEvent{name: opEventResult, data: []byte(`{"videos":[]}`)}, true)
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{"unknown operation", "unknown", http.StatusNotFound, "Unknown operation"},
+		{"finished operation", finished, http.StatusOK, "event: result\ndata: {\"videos\":[]}\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/veo/operations/"+tt.id+"/events", nil)
+			req.SetPathValue("id", tt.id)
+			rec := httptest.NewRecorder()
+			h.HandleOperationEvents(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected the response to contain %q, but got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleCancelOperation(t *testing.T) {
+	h := &Handler{operations: newOperationTracker(time.Minute)}
+	queue := security.NewGenerationQueue("generation", 1, 0, time.Second)
+
+	// Start an operation the way startAsync does: the queue slot is held by background work
+	// that ends when the operation is canceled.
+	var id string
+	queue.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := security.DetachSlot(r.Context())
+		id = h.operations.add(cancel)
+		go func() {
+			<-ctx.Done()
+			release()
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+	if _, active := queue.Depth(); active != 1 {
+		t.Fatalf("expected the operation to hold a queue slot, but %d are active", active)
+	}
+
+	cancelRequest := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/veo/operations/"+id+"/cancel", nil)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h.HandleCancelOperation(rec, req)
+		return rec
+	}
+
+	rec := cancelRequest(id)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, but got %d", http.StatusOK, rec.Code)
+	}
+	var progress OperationProgress
+	if err := json.NewDecoder(rec.Body).Decode(&progress); err != nil {
+		t.Fatalf("could not decode the response: %v", err)
+	}
+	if progress.OperationID != id || progress.Status != "canceled" {
+		t.Errorf("expected canceled progress for %s, but got %+v", id, progress)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, active := queue.Depth(); active == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the queue slot to be released after the cancel")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{"already canceled", id, http.StatusConflict},
+		{"unknown operation", "unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := cancelRequest(tt.id); rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -15,25 +15,102 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"cloud.google.com/go/storage"
-	"github.com/google/uuid"
+	"google.golang.org/api/googleapi"
 )
 
 type UploadResponse struct {
 	URI       string `json:"uri"`       // gs:// URI
 	SignedURI string `json:"signedUri"` // HTTPS URL for preview
+	Duplicate bool   `json:"duplicate"` // True if identical content was already uploaded
 }
 
 const MaxUploadSize = 50 << 20 // 50 MB
 
+// MaxRequestSize bounds the JSON body of the API requests other than uploads.
+const MaxRequestSize = 1 << 20 // 1 MB
+
+// maxUploadIndexEntries bounds the number of uploaded objects remembered by uploadIndex.
+const maxUploadIndexEntries = 1024
+
+// uploadIndex remembers the objects this instance has uploaded, so that re-uploads of the same
+// content skip the existence check in GCS. Uploads are named by their content hash, so objects
+// uploaded by other instances or before a restart are still found through that check.
+type uploadIndex struct {
+	mu      sync.Mutex
+	objects map[string]bool
+	order   []string // Insertion order, for evicting the oldest entries.
+}
+
+func newUploadIndex() *uploadIndex {
+	return &uploadIndex{objects: make(map[string]bool)}
+}
+
+func (i *uploadIndex) contains(object string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.objects[object]
+}
+
+func (i *uploadIndex) add(object string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.objects[object] {
+		return
+	}
+	if len(i.order) >= maxUploadIndexEntries {
+		delete(i.objects, i.order[0])
+		i.order = i.order[1:]
+	}
+	i.objects[object] = true
+	i.order = append(i.order, object)
+}
+
+// contentTypeAliases maps non-standard declared types to the types reported by http.DetectContentType.
+var contentTypeAliases = map[string]string{
+	"image/jpg":   "image/jpeg",
+	"image/pjpeg": "image/jpeg",
+	"image/x-png": "image/png",
+}
+
+// reconcileContentType compares the client-declared content type with the type detected from the
+// file's contents and returns the detected type. A missing or generic declared type is replaced by
+// the detected one; any other disagreement is an error, as the file may be mislabeled or spoofed.
+func reconcileContentType(declared, detected string) (string, error) {
+	detected, _, _ = strings.Cut(detected, ";")
+	detected = strings.TrimSpace(detected)
+	declared, _, _ = strings.Cut(declared, ";")
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	if alias, ok := contentTypeAliases[declared]; ok {
+		declared = alias
+	}
+	if declared == "" || declared == "application/octet-stream" || declared == detected {
+		return detected, nil
+	}
+	return "", fmt.Errorf("File content (%s) does not match declared type %s", detected, declared)
+}
+
+// uploadObjectName returns the content-addressed object name for an upload.
+func uploadObjectName(content io.Reader, ext string) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("uploads/%s%s", hex.EncodeToString(hash.Sum(nil)), ext), nil
+}
+
 func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -54,8 +131,24 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Validate content type
-	contentType := header.Header.Get("Content-Type")
+	// Validate content type against the file's magic bytes rather than trusting the client
+	sniffed := make([]byte, 512)
+	n, err := io.ReadFull(file, sniffed)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		http.Error(w, "Invalid file", http.StatusBadRequest)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		slog.Error("Failed to rewind uploaded file", "error", err)
+		http.Error(w, "Upload failed", http.StatusInternalServerError)
+		return
+	}
+	contentType, err := reconcileContentType(header.Header.Get("Content-Type"), http.DetectContentType(sniffed[:n]))
+	if err != nil {
+		slog.Warn("Rejected upload with mismatched content type", "filename", header.Filename, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if strings.HasPrefix(contentType, "video/") && contentType != "video/mp4" {
 		http.Error(w, "Only video/mp4 is supported", http.StatusBadRequest)
 		return
@@ -70,12 +163,18 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	if ext == "" {
 		ext = ".png" // default
 	}
-	filename := fmt.Sprintf("uploads/%s%s", uuid.New().String(), ext)
-	
+	filename, err := uploadObjectName(file, ext)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		slog.Error("Failed to hash uploaded file", "error", err)
+		http.Error(w, "Upload failed", http.StatusInternalServerError)
+		return
+	}
+
 	ctx := r.Context()
 	bucketName := h.Config.VeoBucket
-	
-	slog.Info("Uploading file", "filename", filename, "bucket", bucketName)
 
 	client, err := storage.NewClient(ctx)
 	if err != nil {
@@ -84,20 +183,42 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer client.Close()
+	obj := client.Bucket(bucketName).Object(filename)
 
-	wc := client.Bucket(bucketName).Object(filename).NewWriter(ctx)
-	wc.ContentType = contentType
-	
-	if _, err := io.Copy(wc, file); err != nil {
-		slog.Error("Failed to write file to GCS", "error", err)
-		http.Error(w, "Upload failed", http.StatusInternalServerError)
-		return
+	duplicate := h.uploads.contains(filename)
+	if !duplicate {
+		if _, err := obj.Attrs(ctx); err == nil {
+			duplicate = true
+		} else if !errors.Is(err, storage.ErrObjectNotExist) {
+			slog.Warn("Failed to check for an existing upload, uploading anyway", "object", filename, "error", err)
+		}
 	}
-	if err := wc.Close(); err != nil {
-		slog.Error("Failed to close GCS writer", "error", err)
-		http.Error(w, "Upload failed", http.StatusInternalServerError)
-		return
+
+	if duplicate {
+		slog.Info("Reusing existing upload with identical content", "filename", filename, "bucket", bucketName)
+	} else {
+		slog.Info("Uploading file", "filename", filename, "bucket", bucketName)
+
+		// The precondition keeps concurrent uploads of the same content from rewriting the object.
+		wc := obj.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+		wc.ContentType = contentType
+
+		if _, err := io.Copy(wc, file); err != nil {
+			slog.Error("Failed to write file to GCS", "error", err)
+			http.Error(w, "Upload failed", http.StatusInternalServerError)
+			return
+		}
+		if err := wc.Close(); err != nil {
+			var apiErr *googleapi.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != http.StatusPreconditionFailed {
+				slog.Error("Failed to close GCS writer", "error", err)
+				http.Error(w, "Upload failed", http.StatusInternalServerError)
+				return
+			}
+			duplicate = true // Another request stored the same content first.
+		}
 	}
+	h.uploads.add(filename)
 
 	gcsURI := fmt.Sprintf("gs://%s/%s", bucketName, filename)
 	
@@ -112,5 +233,6 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(UploadResponse{
 		URI:       gcsURI,
 		SignedURI: signedURI,
+		Duplicate: duplicate,
 	})
 }
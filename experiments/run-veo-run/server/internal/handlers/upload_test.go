@@ -0,0 +1,160 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestUploadIndex(t *testing.T) {
+	i := newUploadIndex()
+	if i.contains("uploads/a.png") {
+		t.Error("expected an empty index to contain nothing")
+	}
+	i.add("uploads/a.png")
+	i.add("uploads/a.png")
+	if !i.contains("uploads/a.png") {
+		t.Error("expected the index to contain an added object")
+	}
+	if len(i.order) != 1 {
+		t.Errorf("expected a repeated add to be recorded once, but got %d entries", len(i.order))
+	}
+}
+
+func TestUploadIndexEvictsOldest(t *testing.T) {
+	i := newUploadIndex()
+	for n := range maxUploadIndexEntries + 1 {
+		i.add(fmt.Sprintf("uploads/%d.png", n))
+	}
+	if i.contains("uploads/0.png") {
+		t.Error("expected the oldest entry to be evicted")
+	}
+	if !i.contains("uploads/1.png") || !i.contains(fmt.Sprintf("uploads/%d.png", maxUploadIndexEntries)) {
+		t.Error("expected the newer entries to be kept")
+	}
+	if len(i.objects) != maxUploadIndexEntries || len(i.order) != maxUploadIndexEntries {
+		t.Errorf("expected %d entries, but got %d objects and %d in order", maxUploadIndexEntries, len(i.objects), len(i.order))
+	}
+}
+
+func TestUploadObjectName(t *testing.T) {
+	tests := []struct {
+		content string
+		ext     string
+		want    string
+	}{
+		{"", ".png", "uploads/e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.png"},
+		{"hello", ".mp4", "uploads/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824.mp4"},
+		{"hello", "", "uploads/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+	}
+	for _, tt := range tests {
+		got, err := uploadObjectName(strings.NewReader(tt.content), tt.ext)
+		if err != nil {
+			t.Errorf("uploadObjectName(%q, %q): expected no error, but got %v", tt.content, tt.ext, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("uploadObjectName(%q, %q): expected %q, but got %q", tt.content, tt.ext, tt.want, got)
+		}
+	}
+
+	readErr := errors.New("read failed")
+	if _, err := uploadObjectName(iotest.ErrReader(readErr), ".png"); !errors.Is(err, readErr) {
+		t.Errorf("expected the read error, but got %v", err)
+	}
+}
+
+func TestReconcileContentType(t *testing.T) {
+	tests := []struct {
+		declared string
+		detected string
+		want     string
+		wantErr  bool
+	}{
+		{"image/png", "image/png", "image/png", false},
+		{"", "image/png", "image/png", false},
+		{"application/octet-stream", "video/mp4", "video/mp4", false},
+		{" IMAGE/PNG ", "image/png", "image/png", false},
+		{"image/jpg", "image/jpeg", "image/jpeg", false},
+		{"image/pjpeg", "image/jpeg", "image/jpeg", false},
+		{"image/x-png", "image/png", "image/png", false},
+		{"text/plain; charset=utf-8", "text/plain; charset=utf-8", "text/plain", false},
+		{"image/png", "video/mp4", "", true},
+		{"video/mp4", "text/html; charset=utf-8", "", true},
+	}
+	for _, tt := range tests {
+		got, err := reconcileContentType(tt.declared, tt.detected)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("reconcileContentType(%q, %q): expected error: %v, but got %v", tt.declared, tt.detected, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("reconcileContentType(%q, %q): expected %q, but got %q", tt.declared, tt.detected, tt.want, got)
+		}
+	}
+}
+
+func TestHandleUploadRejectsContentType(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	webm := "\x1a\x45\xdf\xa3"
+	tests := []struct {
+		name        string
+		declared    string
+		content     string
+		wantMessage string
+	}{
+		{"mislabeled image", "video/mp4", png, "does not match declared type video/mp4"},
+		{"unsupported video", "video/webm", webm, "Only video/mp4 is supported"},
+		{"html", "", "<html><body>hi</body></html>", "Only images and MP4 videos are supported"},
+	}
+	h := &Handler{uploads: newUploadIndex()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			part := textproto.MIMEHeader{}
+			part.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+			if tt.declared != "" {
+				part.Set("Content-Type", tt.declared)
+			}
+			fw, err := mw.CreatePart(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write([]byte(tt.content))
+			mw.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/upload", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			rec := httptest.NewRecorder()
+			h.HandleUpload(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, but got %d", http.StatusBadRequest, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("expected the response to contain %q, but got %q", tt.wantMessage, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes why a request body was rejected. Field is the JSON path of the
+// offending field (e.g. "refImageUris[1]"), or empty if the body as a whole is invalid.
+// Code is a machine-readable category: invalid_body, unknown_field, invalid_field, or
+// bucket_not_allowed.
+type ValidationError struct {
+	Code    string `json:"error"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// codeBucketNotAllowed is the ValidationError code of a gs:// URI outside the allowed buckets.
+const codeBucketNotAllowed = "bucket_not_allowed"
+
+// HTTPStatus returns the status code a handler responds with for this error: 403 for a
+// disallowed bucket, 400 otherwise.
+func (e *ValidationError) HTTPStatus() int {
+	if e.Code == codeBucketNotAllowed {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// writeValidationError responds with the validation error as JSON, with a status code matching
+// its category.
+func writeValidationError(w http.ResponseWriter, verr *ValidationError) {
+	slog.Warn("Rejected invalid request", "code", verr.Code, "field", verr.Field, "message", verr.Message)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(verr.HTTPStatus())
+	json.NewEncoder(w).Encode(verr)
+}
+
+// fieldError returns a ValidationError for a single invalid field.
+func fieldError(field, format string, args ...any) *ValidationError {
+	return &ValidationError{Code: "invalid_field", Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// decodeJSONBody strictly decodes a single JSON object from the request body into dst.
+// Unknown fields, type mismatches, and trailing data are reported as a ValidationError
+// naming the offending field where possible.
+func decodeJSONBody(r *http.Request, dst any) *ValidationError {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.Is(err, io.EOF):
+			return &ValidationError{Code: "invalid_body", Message: "request body must not be empty"}
+		case errors.As(err, &syntaxErr):
+			return &ValidationError{Code: "invalid_body", Message: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &ValidationError{Code: "invalid_body", Message: "malformed JSON: unexpected end of body"}
+		case errors.As(err, &typeErr):
+			return fieldError(typeErr.Field, "must be of type %s, got %s", typeErr.Type, typeErr.Value)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			// encoding/json has no typed error for unknown fields.
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return &ValidationError{Code: "unknown_field", Field: field, Message: fmt.Sprintf("unknown field %q", field)}
+		default:
+			return &ValidationError{Code: "invalid_body", Message: err.Error()}
+		}
+	}
+	if dec.More() {
+		return &ValidationError{Code: "invalid_body", Message: "request body must contain a single JSON object"}
+	}
+	return nil
+}
+
+// validateGCSURI checks that a field holds a gs://bucket/object URI.
+func validateGCSURI(field, uri string) *ValidationError {
+	if !strings.HasPrefix(uri, "gs://") || !strings.Contains(strings.TrimPrefix(uri, "gs://"), "/") {
+		return fieldError(field, "must be a GCS URI of the form gs://bucket/object, got %q", uri)
+	}
+	return nil
+}
+
+// checkInputBuckets checks that every gs:// URI, keyed by its field, is in a bucket that
+// ALLOWED_INPUT_BUCKETS and DENIED_INPUT_BUCKETS permit. The URIs must already be validated.
+func (h *Handler) checkInputBuckets(uris map[string]string) *ValidationError {
+	fields := make([]string, 0, len(uris))
+	for field := range uris {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		uri := uris[field]
+		if uri == "" {
+			continue
+		}
+		bucket, _, _ := strings.Cut(strings.TrimPrefix(uri, "gs://"), "/")
+		if !h.Config.InputBucketAllowed(bucket) {
+			return &ValidationError{Code: codeBucketNotAllowed, Field: field, Message: fmt.Sprintf("bucket %q is not allowed", bucket)}
+		}
+	}
+	return nil
+}
+
+// inputURIs returns the gs:// URIs of a Veo request, keyed by field.
+func (req *VeoRequest) inputURIs() map[string]string {
+	uris := map[string]string{
+		"videoUri":     req.VideoURI,
+		"imageUri":     req.ImageURI,
+		"lastFrameUri": req.LastFrameURI,
+	}
+	for i, uri := range req.RefImageURIs {
+		uris[fmt.Sprintf("refImageUris[%d]", i)] = uri
+	}
+	return uris
+}
+
+// validateGenerate checks the fields of a text/image/reference-to-video request for the model.
+func (req *VeoRequest) validateGenerate(model string) *ValidationError {
+	if req.NumVideos != nil {
+		if maxVideos := maxVideosFor(model); *req.NumVideos < 1 || *req.NumVideos > maxVideos {
+			return fieldError("numVideos", "must be between 1 and %d for model %s, got %d", maxVideos, model, *req.NumVideos)
+		}
+	}
+	if req.AspectRatio != "" && req.AspectRatio != "16:9" && req.AspectRatio != "9:16" {
+		return fieldError("aspectRatio", "must be \"16:9\" or \"9:16\", got %q", req.AspectRatio)
+	}
+	if req.ImageURI != "" {
+		if verr := validateGCSURI("imageUri", req.ImageURI); verr != nil {
+			return verr
+		}
+	}
+	if req.LastFrameURI != "" {
+		if verr := validateGCSURI("lastFrameUri", req.LastFrameURI); verr != nil {
+			return verr
+		}
+	}
+	for i, uri := range req.RefImageURIs {
+		if verr := validateGCSURI(fmt.Sprintf("refImageUris[%d]", i), uri); verr != nil {
+			return verr
+		}
+	}
+	if len(req.RefImageTypes) > len(req.RefImageURIs) {
+		return fieldError("refImageTypes", "has %d entries but refImageUris has only %d", len(req.RefImageTypes), len(req.RefImageURIs))
+	}
+	for i, refType := range req.RefImageTypes {
+		if refType != "ASSET" && refType != "STYLE" {
+			return fieldError(fmt.Sprintf("refImageTypes[%d]", i), "must be \"ASSET\" or \"STYLE\", got %q", refType)
+		}
+	}
+	return nil
+}
+
+// validateExtend checks the fields of a video extension request.
+func (req *VeoRequest) validateExtend() *ValidationError {
+	if req.VideoURI == "" {
+		return fieldError("videoUri", "is required for extension")
+	}
+	return validateGCSURI("videoUri", req.VideoURI)
+}
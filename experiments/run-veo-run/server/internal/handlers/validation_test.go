@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/run-veo-run/server/internal/config"
+)
+
+func TestValidateGenerateNumVideos(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	tests := []struct {
+		name      string
+		model     string
+		numVideos *int
+		wantErr   bool
+	}{
+		{"unset", "veo-3.0-generate-001", nil, false},
+		{"one", "veo-3.0-generate-001", intPtr(1), false},
+		{"model maximum", "veo-3.1-generate-001", intPtr(4), false},
+		{"zero", "veo-3.1-generate-001", intPtr(0), true},
+		{"negative", "veo-3.1-generate-001", intPtr(-1), true},
+		{"above the model maximum", "veo-3.0-generate-001", intPtr(3), true},
+		{"unknown model uses the default maximum", "veo-9.0-generate-001", intPtr(defaultMaxVideos), false},
+		{"above the default maximum", "veo-9.0-generate-001", intPtr(defaultMaxVideos + 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &VeoRequest{Prompt: "a cat", NumVideos: tt.numVideos}
+			verr := req.validateGenerate(tt.model)
+			if (verr != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, but got %v", tt.wantErr, verr)
+			}
+			if verr != nil && verr.Field != "numVideos" {
+				t.Errorf("expected field numVideos, but got %q", verr.Field)
+			}
+		})
+	}
+}
+
+func TestHandleGenerateVideoRejectsNumVideos(t *testing.T) {
+	h := &Handler{Config: &config.Config{VeoModel: "veo-3.0-generate-001"}}
+	tests := []struct {
+		body      string
+		wantLimit string
+	}{
+		{`{"prompt":"a cat","numVideos":0}`, "between 1 and 2"},
+		{`{"prompt":"a cat","numVideos":3}`, "between 1 and 2"},
+		{`{"prompt":"a cat","model":"veo-3.1-generate-001","numVideos":5}`, "between 1 and 4"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleGenerateVideo(rec, httptest.NewRequest(http.MethodPost, "/api/veo/generate", strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, but got %d", tt.body, http.StatusBadRequest, rec.Code)
+			continue
+		}
+		var verr ValidationError
+		if err := json.NewDecoder(rec.Body).Decode(&verr); err != nil {
+			t.Fatalf("%s: could not decode the response: %v", tt.body, err)
+		}
+		if verr.Field != "numVideos" || !strings.Contains(verr.Message, tt.wantLimit) {
+			t.Errorf("%s: expected a numVideos error mentioning %q, but got %+v", tt.body, tt.wantLimit, verr)
+		}
+	}
+}
+
+func TestCheckInputBuckets(t *testing.T) {
+	h := &Handler{Config: &config.Config{AllowedInputBuckets: []string{"uploads"}, DeniedInputBuckets: []string{"secrets"}}}
+	tests := []struct {
+		name      string
+		uris      map[string]string
+		wantField string
+	}{
+		{"allowed", map[string]string{"imageUri": "gs://uploads/a.png"}, ""},
+		{"empty fields are skipped", map[string]string{"imageUri": "", "videoUri": "gs://uploads/a.mp4"}, ""},
+		{"not allowed", map[string]string{"imageUri": "gs://other/a.png"}, "imageUri"},
+		{"denied", map[string]string{"videoUri": "gs://secrets/a.mp4"}, "videoUri"},
+		{"first field in order", map[string]string{"videoUri": "gs://other/a.mp4", "imageUri": "gs://other/a.png"}, "imageUri"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verr := h.checkInputBuckets(tt.uris)
+			if tt.wantField == "" {
+				if verr != nil {
+					t.Errorf("expected no error, but got %+v", verr)
+				}
+				return
+			}
+			if verr == nil {
+				t.Fatal("expected an error, but got nil")
+			}
+			if verr.Field != tt.wantField || verr.Code != codeBucketNotAllowed {
+				t.Errorf("expected a %s error for %s, but got %+v", codeBucketNotAllowed, tt.wantField, verr)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -39,13 +40,44 @@ type VeoRequest struct {
 	LastFrameMimeType string   `json:"lastFrameMimeType,omitempty"` //
 	RefImageURIs      []string `json:"refImageUris,omitempty"`      // Ingredient assets
 	RefImageTypes     []string `json:"refImageTypes,omitempty"`     // e.g. "ASSET"
+	NumVideos         *int     `json:"numVideos,omitempty"`         // Number of candidates (1 to the model's maximum)
+	Async             bool     `json:"async,omitempty"`             // Respond with 202 and follow progress through the event stream
 }
 
-type VeoResponse struct {
+// veoMaxVideos is the maximum number of videos each Veo model generates per request.
+var veoMaxVideos = map[string]int{
+	"veo-2.0-generate-001":          4,
+	"veo-3.0-generate-001":          2,
+	"veo-3.0-fast-generate-001":     2,
+	"veo-3.1-generate-001":          4,
+	"veo-3.1-fast-generate-001":     4,
+	"veo-3.1-generate-preview":      4,
+	"veo-3.1-fast-generate-preview": 4,
+	"veo-3.1-lite-generate-001":     4,
+}
+
+// defaultMaxVideos is the limit for models missing from veoMaxVideos.
+const defaultMaxVideos = 4
+
+// maxVideosFor returns the maximum number of videos the model generates per request.
+func maxVideosFor(model string) int {
+	if limit, ok := veoMaxVideos[model]; ok {
+		return limit
+	}
+	return defaultMaxVideos
+}
+
+type VeoVideo struct {
 	VideoURI  string `json:"videoUri"`  // Signed URL for playback
 	SourceURI string `json:"sourceUri"` // Original gs:// URI (for extension)
 }
 
+type VeoResponse struct {
+	VideoURI  string     `json:"videoUri"`  // First video: signed URL for playback
+	SourceURI string     `json:"sourceUri"` // First video: original gs:// URI (for extension)
+	Videos    []VeoVideo `json:"videos"`    // All generated videos
+}
+
 // HandleGenerateVideo handles text-to-video requests
 func (h *Handler) HandleGenerateVideo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -54,17 +86,29 @@ func (h *Handler) HandleGenerateVideo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req VeoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if verr := decodeJSONBody(r, &req); verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
-
 	model := req.Model
 	if model == "" {
 		model = h.Config.VeoModel
 	}
+	if verr := req.validateGenerate(model); verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	if verr := h.checkInputBuckets(req.inputURIs()); verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
 
-	slog.Info("Generating video", "prompt", req.Prompt, "model", model, "aspect_ratio", req.AspectRatio, "image_uri", req.ImageURI, "last_frame", req.LastFrameURI, "ref_images", len(req.RefImageURIs))
+	var numVideos int32 // 0 leaves the number to the API default
+	if req.NumVideos != nil {
+		numVideos = int32(*req.NumVideos)
+	}
+
+	slog.Info("Generating video", "prompt", req.Prompt, "model", model, "aspect_ratio", req.AspectRatio, "image_uri", req.ImageURI, "last_frame", req.LastFrameURI, "ref_images", len(req.RefImageURIs), "num_videos", numVideos)
 
 	source := &genai.GenerateVideosSource{
 		Prompt: req.Prompt,
@@ -91,6 +135,8 @@ func (h *Handler) HandleGenerateVideo(w http.ResponseWriter, r *http.Request) {
 		cfg.AspectRatio = req.AspectRatio
 	}
 
+	cfg.NumberOfVideos = numVideos
+
 	if req.LastFrameURI != "" {
 				mimeType := req.LastFrameMimeType
 				if mimeType == "" {
@@ -137,33 +183,36 @@ func (h *Handler) HandleGenerateVideo(w http.ResponseWriter, r *http.Request) {
 
 	slog.Info("Video generation started", "op", op.Name)
 
-	resp, err := h.waitForOperation(r.Context(), op)
+	if req.Async {
+		h.startAsync(w, r, op)
+		return
+	}
+
+	resp, err := h.waitForOperation(r.Context(), op, nil)
 	if err != nil {
+		var opErr *OperationError
+		if errors.As(err, &opErr) {
+			writeOperationError(w, opErr)
+			return
+		}
 		slog.Error("Video generation failed during wait", "error", err)
 		http.Error(w, fmt.Sprintf("Generation failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if opErr := filteredVideosError(resp); opErr != nil {
+		writeOperationError(w, opErr)
+		return
+	}
 	if len(resp.GeneratedVideos) == 0 {
 		http.Error(w, "No video generated", http.StatusInternalServerError)
 		return
 	}
 
-	videoGS := resp.GeneratedVideos[0].Video.URI
-	slog.Info("Video generation complete", "uri", videoGS)
-
-	signedURL, err := h.signURL(r.Context(), videoGS)
-	if err != nil {
-		slog.Warn("Failed to sign URL (playback might fail locally without SA impersonation)", "error", err)
-		// Fallback: Use the original GS URI, though it won't play in standard browsers
-		signedURL = videoGS
-	}
+	slog.Info("Video generation complete", "count", len(resp.GeneratedVideos))
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(VeoResponse{
-		VideoURI:  signedURL,
-		SourceURI: videoGS,
-	})
+	json.NewEncoder(w).Encode(h.buildVeoResponse(r.Context(), resp.GeneratedVideos))
 }
 
 // HandleExtendVideo handles video-to-video extension
@@ -174,13 +223,16 @@ func (h *Handler) HandleExtendVideo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req VeoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if verr := decodeJSONBody(r, &req); verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
-
-	if req.VideoURI == "" {
-		http.Error(w, "videoUri is required for extension", http.StatusBadRequest)
+	if verr := req.validateExtend(); verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	if verr := h.checkInputBuckets(req.inputURIs()); verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
 
@@ -215,36 +267,69 @@ func (h *Handler) HandleExtendVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.waitForOperation(r.Context(), op)
+	if req.Async {
+		h.startAsync(w, r, op)
+		return
+	}
+
+	resp, err := h.waitForOperation(r.Context(), op, nil)
 	if err != nil {
+		var opErr *OperationError
+		if errors.As(err, &opErr) {
+			writeOperationError(w, opErr)
+			return
+		}
 		slog.Error("Video extension failed during wait", "error", err)
 		http.Error(w, fmt.Sprintf("Extension failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if opErr := filteredVideosError(resp); opErr != nil {
+		writeOperationError(w, opErr)
+		return
+	}
 	if len(resp.GeneratedVideos) == 0 {
 		http.Error(w, "No video extended", http.StatusInternalServerError)
 		return
 	}
 
-	videoGS := resp.GeneratedVideos[0].Video.URI
-	slog.Info("Video extension complete", "uri", videoGS)
-
-	signedURL, err := h.signURL(r.Context(), videoGS)
-	if err != nil {
-		slog.Warn("Failed to sign URL (playback might fail locally without SA impersonation)", "error", err)
-		// Fallback: Use the original GS URI, though it won't play in standard browsers
-		signedURL = videoGS
-	}
+	slog.Info("Video extension complete", "uri", resp.GeneratedVideos[0].Video.URI)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(VeoResponse{
-		VideoURI:  signedURL,
-		SourceURI: videoGS,
-	})
+	json.NewEncoder(w).Encode(h.buildVeoResponse(r.Context(), resp.GeneratedVideos))
 }
 
-func (h *Handler) waitForOperation(ctx context.Context, op *genai.GenerateVideosOperation) (*genai.GenerateVideosResponse, error) {
+// buildVeoResponse signs every generated video for playback. The first video is
+// also exposed at the top level for clients that only handle a single result.
+func (h *Handler) buildVeoResponse(ctx context.Context, generated []*genai.GeneratedVideo) VeoResponse {
+	var out VeoResponse
+	for _, gv := range generated {
+		if gv.Video == nil || gv.Video.URI == "" {
+			continue
+		}
+		videoGS := gv.Video.URI
+
+		signedURL, err := h.signURL(ctx, videoGS)
+		if err != nil {
+			slog.Warn("Failed to sign URL (playback might fail locally without SA impersonation)", "error", err)
+			// Fallback: Use the original GS URI, though it won't play in standard browsers
+			signedURL = videoGS
+		}
+		out.Videos = append(out.Videos, VeoVideo{
+			VideoURI:  signedURL,
+			SourceURI: videoGS,
+		})
+	}
+	if len(out.Videos) > 0 {
+		out.VideoURI = out.Videos[0].VideoURI
+		out.SourceURI = out.Videos[0].SourceURI
+	}
+	return out
+}
+
+// waitForOperation polls op until it finishes. If progress is not nil, it is called with every
+// poll of the still running operation.
+func (h *Handler) waitForOperation(ctx context.Context, op *genai.GenerateVideosOperation, progress func(*genai.GenerateVideosOperation)) (*genai.GenerateVideosResponse, error) {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
@@ -264,10 +349,13 @@ func (h *Handler) waitForOperation(ctx context.Context, op *genai.GenerateVideos
 			}
 			if latestOp.Done {
 				if latestOp.Error != nil {
-					return nil, fmt.Errorf("operation failed: %v", latestOp.Error)
+					return nil, parseOperationError(latestOp.Error)
 				}
 				return latestOp.Response, nil
 			}
+			if progress != nil {
+				progress(latestOp)
+			}
 		}
 	}
 }
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GenerationQueue bounds the number of long-running generation requests. At most workers
+// requests run at once and up to capacity more wait for a free worker; any further request is
+// rejected with 503 and a Retry-After header instead of piling up. Separate queues bound
+// different kinds of requests, e.g. Veo generations and Gemini analyses.
+type GenerationQueue struct {
+	name       string        // Kind of request, e.g. "generation", used in logs and errors.
+	slots      chan struct{} // Holds one token per running request.
+	capacity   int
+	retryAfter time.Duration
+
+	mu      sync.Mutex
+	waiting int
+	active  int
+}
+
+// NewGenerationQueue creates a queue for the named kind of request that runs up to workers
+// requests concurrently and holds up to capacity waiting requests. retryAfter is the delay
+// suggested to rejected clients.
+func NewGenerationQueue(name string, workers, capacity int, retryAfter time.Duration) *GenerationQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &GenerationQueue{
+		name:       name,
+		slots:      make(chan struct{}, workers),
+		capacity:   capacity,
+		retryAfter: retryAfter,
+	}
+}
+
+// Depth returns the number of waiting and running requests.
+func (q *GenerationQueue) Depth() (waiting, active int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiting, q.active
+}
+
+// enqueue admits a request if a worker or a waiting slot is free.
+func (q *GenerationQueue) enqueue() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiting+q.active >= cap(q.slots)+q.capacity {
+		return false
+	}
+	q.waiting++
+	return true
+}
+
+// transition moves a request between states and logs the resulting queue depth.
+func (q *GenerationQueue) transition(event string, waitingDelta, activeDelta int) {
+	q.mu.Lock()
+	q.waiting += waitingDelta
+	q.active += activeDelta
+	waiting, active := q.waiting, q.active
+	q.mu.Unlock()
+	slog.Info("Request queue", "queue", q.name, "event", event, "waiting", waiting, "active", active, "workers", cap(q.slots), "capacity", q.capacity)
+}
+
+func (q *GenerationQueue) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !q.enqueue() {
+			waiting, active := q.Depth()
+			slog.Warn("Request queue full", "queue", q.name, "ip", GetClientIP(r), "waiting", waiting, "active", active)
+			w.Header().Set("Retry-After", strconv.Itoa(int(q.retryAfter.Seconds())))
+			http.Error(w, fmt.Sprintf("Server is busy with other %s requests. Please try again later.", q.name), http.StatusServiceUnavailable)
+			return
+		}
+
+		select {
+		case q.slots <- struct{}{}:
+			q.transition("started", -1, 1)
+		case <-r.Context().Done():
+			q.transition("abandoned", -1, 0)
+			return
+		}
+		slot := &queueSlot{release: func() {
+			<-q.slots
+			q.transition("finished", 0, -1)
+		}}
+		defer func() {
+			if !slot.detached {
+				slot.release()
+			}
+		}()
+		next(w, r.WithContext(context.WithValue(r.Context(), queueSlotKey{}, slot)))
+	}
+}
+
+// queueSlot is the worker slot held by a request admitted by a GenerationQueue.
+type queueSlot struct {
+	release  func()
+	detached bool
+}
+
+type queueSlotKey struct{}
+
+// DetachSlot hands the worker slot of the request that ctx belongs to over to the caller, so
+// that work continuing in the background after the response, such as polling an operation,
+// stays bounded by the queue. The caller must call the returned function once that work is
+// done. If the request holds no slot, the returned function does nothing.
+func DetachSlot(ctx context.Context) (release func()) {
+	slot, ok := ctx.Value(queueSlotKey{}).(*queueSlot)
+	if !ok || slot.detached {
+		return func() {}
+	}
+	slot.detached = true
+	var once sync.Once
+	return func() { once.Do(slot.release) }
+}
@@ -0,0 +1,119 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForDepth waits until the queue holds the given numbers of waiting and running requests.
+func waitForDepth(t *testing.T, q *GenerationQueue, waiting, active int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w, a := q.Depth()
+		if w == waiting && a == active {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected depth (%d waiting, %d active), but got (%d, %d)", waiting, active, w, a)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingHandler returns a handler that blocks until release is closed.
+func blockingHandler(release chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestGenerationQueueRejectsWhenFull(t *testing.T) {
+	q := NewGenerationQueue("generation", 1, 0, 30*time.Second)
+	release := make(chan struct{})
+	handler := q.Middleware(blockingHandler(release))
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+		done <- rec.Code
+	}()
+	waitForDepth(t, q, 0, 1)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, but got %q", got)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected the running request to finish with %d, but got %d", http.StatusOK, code)
+	}
+	waitForDepth(t, q, 0, 0)
+}
+
+func TestGenerationQueueAbandonedWhileWaiting(t *testing.T) {
+	q := NewGenerationQueue("generation", 1, 1, time.Second)
+	release := make(chan struct{})
+	calls := make(chan struct{}, 2)
+	handler := q.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		calls <- struct{}{}
+		blockingHandler(release)(w, r)
+	})
+
+	go handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+	waitForDepth(t, q, 0, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan struct{})
+	go func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil).WithContext(ctx))
+		close(waited)
+	}()
+	waitForDepth(t, q, 1, 1)
+
+	cancel()
+	<-waited
+	waitForDepth(t, q, 0, 1)
+	close(release)
+	waitForDepth(t, q, 0, 0)
+	if len(calls) != 1 {
+		t.Errorf("expected only the running request to reach the handler, but got %d calls", len(calls))
+	}
+}
+
+func TestDetachSlot(t *testing.T) {
+	q := NewGenerationQueue("generation", 1, 0, time.Second)
+	var release func()
+	handler := q.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		release = DetachSlot(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+	// The slot stays held by the background work after the response.
+	waitForDepth(t, q, 0, 1)
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/veo/generate", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while the detached slot is held, but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	release()
+	release() // Releasing twice frees the slot only once.
+	waitForDepth(t, q, 0, 0)
+}
+
+func TestDetachSlotWithoutQueue(t *testing.T) {
+	// A request without a queue slot gets a release function that does nothing.
+	DetachSlot(context.Background())()
+}
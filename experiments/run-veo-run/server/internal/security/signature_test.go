@@ -0,0 +1,178 @@
+package security
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestVerifier(now time.Time) *SignatureVerifier {
+	v := NewSignatureVerifier("secret")
+	v.now = func() time.Time { return now }
+	return v
+}
+
+// signedRequest returns a request signed in the headers at timestamp.
+func signedRequest(v *SignatureVerifier, method, target, body string, timestamp time.Time) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set(SignatureTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	r.Header.Set(SignatureHeader, v.Sign(method, r.URL.Path, timestamp.Unix(), []byte(body)))
+	return r
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+
+	var gotBody string
+	handler := v.Middleware(1024, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	withSignature := func(r *http.Request, signature string) *http.Request {
+		r.Header.Set(SignatureHeader, signature)
+		return r
+	}
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    int
+	}{
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/api/veo/generate", strings.NewReader(`{"prompt":"a cat"}`)), http.StatusUnauthorized},
+		{"signed", signedRequest(v, http.MethodPost, "/api/veo/generate", `{"prompt":"a cat"}`, now), http.StatusOK},
+		{"sha256 prefix", withSignature(signedRequest(v, http.MethodGet, "/api/config", "", now), "sha256="+v.Sign(http.MethodGet, "/api/config", now.Unix(), nil)), http.StatusOK},
+		{"signed within the window", signedRequest(v, http.MethodGet, "/api/config", "", now.Add(-MaxSignatureAge+time.Second)), http.StatusOK},
+		{"stale timestamp", signedRequest(v, http.MethodGet, "/api/config", "", now.Add(-MaxSignatureAge-time.Second)), http.StatusUnauthorized},
+		{"future timestamp", signedRequest(v, http.MethodGet, "/api/config", "", now.Add(MaxSignatureAge+time.Second)), http.StatusUnauthorized},
+		{"wrong signature", withSignature(signedRequest(v, http.MethodGet, "/api/config", "", now), "00ff"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, tt.request)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, but got %d (%s)", tt.want, rec.Code, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+
+	// The body read for verification is restored for the handler.
+	gotBody = ""
+	handler(httptest.NewRecorder(), signedRequest(v, http.MethodPost, "/api/veo/generate", `{"prompt":"a dog"}`, now))
+	if gotBody != `{"prompt":"a dog"}` {
+		t.Errorf("expected the handler to read the original body, but got %q", gotBody)
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestSignatureMiddlewareRejectsBeforeReadingBody(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	handler := v.Middleware(1024, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+	}{
+		{"unsigned", "", ""},
+		{"missing timestamp", "", "00ff"},
+		{"stale timestamp", strconv.FormatInt(now.Add(-MaxSignatureAge-time.Second).Unix(), 10), "00ff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &countingReader{r: strings.NewReader(strings.Repeat("x", 4096))}
+			r := httptest.NewRequest(http.MethodPost, "/api/veo/generate", body)
+			r.Header.Set(SignatureHeader, tt.signature)
+			r.Header.Set(SignatureTimestampHeader, tt.timestamp)
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, but got %d", http.StatusUnauthorized, rec.Code)
+			}
+			if body.read != 0 {
+				t.Errorf("expected the body not to be read, but %d bytes were read", body.read)
+			}
+		})
+	}
+}
+
+func TestSignatureMiddlewareBodyLimit(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	handler := v.Middleware(16, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedRequest(v, http.MethodPost, "/api/veo/generate", `{"prompt":"a cat in a hat"}`, now))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d for a body over the limit, but got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
+
+func TestSignatureCoversMethodAndPath(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	handler := v.Middleware(1024, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	// A signature captured from one empty-body request is not valid for another.
+	captured := signedRequest(v, http.MethodGet, "/api/config", "", now)
+	for _, target := range []struct{ method, path string }{
+		{http.MethodPost, "/api/veo/operations/op-1/cancel"},
+		{http.MethodGet, "/api/veo/operations/op-1/events"},
+	} {
+		r := httptest.NewRequest(target.method, target.path, nil)
+		r.Header.Set(SignatureHeader, captured.Header.Get(SignatureHeader))
+		r.Header.Set(SignatureTimestampHeader, captured.Header.Get(SignatureTimestampHeader))
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected status %d for a signature of another request, but got %d", target.method, target.path, http.StatusUnauthorized, rec.Code)
+		}
+	}
+}
+
+func TestSignatureQueryParams(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	handler := v.Middleware(1024, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	// An EventSource cannot set headers, so it passes the signature in the URL.
+	path := "/api/veo/operations/op-1/events"
+	query := url.Values{
+		SignatureQueryParam:          {v.Sign(http.MethodGet, path, now.Unix(), nil)},
+		SignatureTimestampQueryParam: {strconv.FormatInt(now.Unix(), 10)},
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d for a signature in the query, but got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestSignatureMiddlewareDisabled(t *testing.T) {
+	v := NewSignatureVerifier("")
+	called := false
+	handler := v.Middleware(1024, func(w http.ResponseWriter, r *http.Request) { called = true })
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if !called {
+		t.Error("expected unsigned requests to pass when signing is disabled")
+	}
+}
@@ -69,12 +69,30 @@ func main() {
 	// Rate Limiter
 	rl := security.NewRateLimiter(cfg.RateLimitPerMinute, time.Minute)
 
+	// Generation Queue: bounds the Veo requests that are polling operations at once.
+	queue := security.NewGenerationQueue("generation", cfg.MaxConcurrentVeo, cfg.VeoQueueSize, 30*time.Second)
+
+	// Analysis Queue: bounds the Gemini video analyses that run at once, independently of generations.
+	analyzeQueue := security.NewGenerationQueue("analysis", cfg.MaxConcurrentAnalyses, cfg.AnalyzeQueueSize, 10*time.Second)
+
+	// Request Signing: API requests must carry a recent HMAC signature of their method, path and body, if configured.
+	// Only uploads may have large bodies; the rate limiter runs first so that rejected clients are not read from.
+	signer := security.NewSignatureVerifier(cfg.RequestSigningSecret)
+	if signer.Enabled() {
+		slog.Info("Request signing enabled", "header", security.SignatureHeader, "timestamp_header", security.SignatureTimestampHeader, "max_age", security.MaxSignatureAge)
+	}
+	signed := func(next http.HandlerFunc) http.HandlerFunc {
+		return signer.Middleware(handlers.MaxRequestSize, next)
+	}
+
 	// 6. Setup Routes
-	http.HandleFunc("/api/config", h.HandleConfig)
-	http.HandleFunc("/api/veo/generate", rl.Middleware(h.HandleGenerateVideo))
-	http.HandleFunc("/api/veo/extend", rl.Middleware(h.HandleExtendVideo))
-	http.HandleFunc("/api/gemini/analyze", h.HandleAnalyzeVideo)
-	http.HandleFunc("/api/upload", h.HandleUpload)
+	http.HandleFunc("/api/config", signed(h.HandleConfig))
+	http.HandleFunc("/api/veo/generate", rl.Middleware(signed(queue.Middleware(h.HandleGenerateVideo))))
+	http.HandleFunc("/api/veo/extend", rl.Middleware(signed(queue.Middleware(h.HandleExtendVideo))))
+	http.HandleFunc("GET /api/veo/operations/{id}/events", signed(h.HandleOperationEvents))
+	http.HandleFunc("POST /api/veo/operations/{id}/cancel", signed(h.HandleCancelOperation))
+	http.HandleFunc("/api/gemini/analyze", signed(analyzeQueue.Middleware(h.HandleAnalyzeVideo)))
+	http.HandleFunc("/api/upload", signer.Middleware(handlers.MaxUploadSize, h.HandleUpload))
 	http.Handle("/", http.FileServer(http.Dir("./dist")))
 
 	// 7. Start Server